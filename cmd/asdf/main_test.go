@@ -21,6 +21,10 @@ func TestBatsTests(t *testing.T) {
 	// Run tests with the asdf binary in the temp directory
 
 	// Uncomment these as they are implemented
+	t.Run("complete_command", func(t *testing.T) {
+		runBatsFile(t, dir, "complete_command.bats")
+	})
+
 	t.Run("current_command", func(t *testing.T) {
 		runBatsFile(t, dir, "current_command.bats")
 	})
@@ -0,0 +1,144 @@
+// Package audit records every shim execution in an append-only log in the data
+// directory when the audit_log setting is enabled, so regulated environments
+// can show which toolchain produced which artifact. Each execution is a line of
+// JSON. The log is rotated once it grows past MaxSize, keeping Keep rotated
+// files.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/lock"
+)
+
+const (
+	// Filename is the name of the log in the audit directory. Rotated logs
+	// are named after it with a number appended, .1 being the most recent.
+	Filename = "shims.log"
+	// MaxSize is the size in bytes past which the log is rotated
+	MaxSize = 10 * 1024 * 1024
+	// Keep is the number of rotated logs kept
+	Keep = 5
+)
+
+// Entry is the record of a shim execution
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Tool      string    `json:"tool"`
+	Version   string    `json:"version"`
+	Directory string    `json:"cwd"`
+	// ArgsHash is the hex encoded SHA-256 of the command and its arguments,
+	// each followed by a NUL byte, so the arguments can be matched without
+	// storing secrets passed on the command line
+	ArgsHash string `json:"argv_sha256"`
+}
+
+// NewEntry returns the entry for running the command with the arguments from
+// the directory
+func NewEntry(tool, version, directory, command string, args []string) Entry {
+	hash := sha256.New()
+	for _, arg := range append([]string{command}, args...) {
+		hash.Write([]byte(arg))
+		hash.Write([]byte{0})
+	}
+
+	return Entry{
+		Time:      time.Now().UTC(),
+		Tool:      tool,
+		Version:   version,
+		Directory: directory,
+		ArgsHash:  hex.EncodeToString(hash.Sum(nil)),
+	}
+}
+
+// Path returns the path of the current log
+func Path(dataDir string) string {
+	return filepath.Join(data.AuditDirectory(dataDir), Filename)
+}
+
+// Append adds the entry to the log, rotating it first when it has grown past
+// MaxSize. Shims run concurrently, so the log is locked while it is written.
+func Append(dataDir string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	dir := data.AuditDirectory(dataDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	logLock, err := lock.Acquire(dataDir, lock.AuditLog, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer logLock.Release()
+
+	logPath := Path(dataDir)
+	if info, err := os.Stat(logPath); err == nil && info.Size()+int64(len(line))+1 > MaxSize {
+		if err := rotate(logPath); err != nil {
+			return fmt.Errorf("unable to rotate audit log: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Read returns the entries of the current log, oldest first
+func Read(dataDir string) (entries []Entry, err error) {
+	contents, err := os.ReadFile(Path(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return entries, err
+	}
+
+	for i, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return entries, fmt.Errorf("invalid audit log entry on line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// rotate shifts each rotated log up by one, dropping the oldest, and moves the
+// log to .1
+func rotate(logPath string) error {
+	for i := Keep - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", logPath, i), fmt.Sprintf("%s.%d", logPath, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(logPath, logPath+".1")
+}
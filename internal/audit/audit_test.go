@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntry(t *testing.T) {
+	entry := NewEntry("python", "3.12.1", "/home/user/project", "python", []string{"build.py", "--release"})
+	assert.Equal(t, "python", entry.Tool)
+	assert.Equal(t, "3.12.1", entry.Version)
+	assert.Equal(t, "/home/user/project", entry.Directory)
+	assert.Len(t, entry.ArgsHash, 64)
+
+	t.Run("hash depends on where arguments are split", func(t *testing.T) {
+		other := NewEntry("python", "3.12.1", "/home/user/project", "python", []string{"build.py --release"})
+		assert.NotEqual(t, entry.ArgsHash, other.ArgsHash)
+	})
+}
+
+func TestAppend(t *testing.T) {
+	dataDir := t.TempDir()
+
+	assert.Nil(t, Append(dataDir, NewEntry("python", "3.12.1", "/a", "python", nil)))
+	assert.Nil(t, Append(dataDir, NewEntry("nodejs", "20.11.1", "/b", "node", []string{"index.js"})))
+
+	entries, err := Read(dataDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "python", entries[0].Tool)
+	assert.Equal(t, "nodejs", entries[1].Tool)
+
+	info, err := os.Stat(Path(dataDir))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestRead(t *testing.T) {
+	t.Run("returns no entries when there is no log", func(t *testing.T) {
+		entries, err := Read(t.TempDir())
+		assert.Nil(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("returns error for invalid entry", func(t *testing.T) {
+		dataDir := t.TempDir()
+		assert.Nil(t, os.MkdirAll(filepath.Dir(Path(dataDir)), 0o700))
+		assert.Nil(t, os.WriteFile(Path(dataDir), []byte("{}\nnot json\n"), 0o600))
+
+		_, err := Read(dataDir)
+		assert.ErrorContains(t, err, "line 2")
+	})
+}
+
+func TestRotate(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), Filename)
+	for i := range Keep + 1 {
+		assert.Nil(t, os.WriteFile(logPath, []byte(fmt.Sprint(i)), 0o600))
+		assert.Nil(t, rotate(logPath))
+	}
+
+	assert.NoFileExists(t, logPath)
+	assert.NoFileExists(t, fmt.Sprintf("%s.%d", logPath, Keep+1))
+	for i := 1; i <= Keep; i++ {
+		contents, err := os.ReadFile(fmt.Sprintf("%s.%d", logPath, i))
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprint(Keep+1-i), string(contents))
+	}
+}
@@ -0,0 +1,194 @@
+// Package bake builds standalone data directories holding the plugins,
+// installed versions and shims needed for a set of tool versions, so they can
+// be baked into CI images, and checks live data directories against them.
+package bake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versions"
+	cp "github.com/otiai10/copy"
+)
+
+// ManifestFilename is the name of the manifest written to the root of a baked
+// data directory
+const ManifestFilename = "bake.json"
+
+// Tool is a tool version in a baked data directory and the plugin that
+// installed it
+type Tool struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	PluginURL string `json:"plugin_url,omitempty"`
+	PluginRef string `json:"plugin_ref,omitempty"`
+}
+
+// Manifest describes the contents of a baked data directory
+type Manifest struct {
+	Platform string   `json:"platform"`
+	Tools    []Tool   `json:"tools"`
+	Shims    []string `json:"shims"`
+}
+
+// Bake creates a data directory at output with the plugins of the given tools
+// copied at their current refs, the versions installed and shims generated.
+// Output must not exist or be empty, and versions are installed at that path,
+// so it should be the path the data directory is used at. System and path
+// versions aren't managed by asdf and are left out.
+func Bake(conf config.Config, tools []toolversions.ToolVersions, output string, stdOut, stdErr io.Writer) (manifest Manifest, err error) {
+	entries, err := os.ReadDir(output)
+	if err == nil && len(entries) > 0 {
+		return manifest, fmt.Errorf("output %s is not empty", output)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return manifest, err
+	}
+
+	bakeConf := conf
+	bakeConf.DataDir = output
+	// Versions are installed under output even when a shared install directory
+	// is set, so the settings are loaded before it's cleared
+	if _, err := bakeConf.SharedInstallDir(); err != nil {
+		return manifest, err
+	}
+	bakeConf.Settings.SharedInstallDir = ""
+	manifest = Manifest{Platform: installs.Platform(), Tools: []Tool{}}
+
+	for _, tool := range tools {
+		plugin := plugins.New(conf, tool.Name)
+		if err := plugin.Exists(); err != nil {
+			return manifest, err
+		}
+
+		url, ref := pluginSource(plugin)
+		bakePlugin := plugins.New(bakeConf, tool.Name)
+		if err := cp.Copy(plugin.Dir, bakePlugin.Dir); err != nil {
+			return manifest, fmt.Errorf("unable to copy plugin %s: %w", plugin.Name, err)
+		}
+
+		for _, version := range tool.Versions {
+			if parsed := toolversions.Parse(version); parsed.IsSystem() || parsed.Type == "path" {
+				continue
+			}
+
+			err := versions.InstallOneVersion(bakeConf, bakePlugin, version, false, stdOut, stdErr)
+			if err != nil {
+				return manifest, err
+			}
+
+			manifest.Tools = append(manifest.Tools, Tool{Name: tool.Name, Version: version, PluginURL: url, PluginRef: ref})
+		}
+	}
+
+	manifest.Shims, err = shimNames(bakeConf)
+	if err != nil {
+		return manifest, err
+	}
+
+	return manifest, Write(manifest, filepath.Join(output, ManifestFilename))
+}
+
+// Verify compares a live data directory against a manifest, returning a line
+// for every difference found
+func Verify(conf config.Config, manifest Manifest) (problems []string) {
+	if manifest.Platform != installs.Platform() {
+		problems = append(problems, fmt.Sprintf("baked for %s, running on %s", manifest.Platform, installs.Platform()))
+	}
+
+	for _, tool := range manifest.Tools {
+		plugin := plugins.New(conf, tool.Name)
+		if err := plugin.Exists(); err != nil {
+			problems = append(problems, fmt.Sprintf("plugin %s is not installed", tool.Name))
+			continue
+		}
+
+		if _, ref := pluginSource(plugin); tool.PluginRef != "" && ref != tool.PluginRef {
+			problems = append(problems, fmt.Sprintf("plugin %s is at %s, baked at %s", tool.Name, ref, tool.PluginRef))
+		}
+
+		if !installs.IsInstalled(conf, plugin, toolversions.Parse(tool.Version)) {
+			problems = append(problems, fmt.Sprintf("%s %s is not installed", tool.Name, tool.Version))
+		}
+	}
+
+	for _, shim := range manifest.Shims {
+		if _, err := os.Stat(shims.Path(conf, shim)); err != nil {
+			problems = append(problems, fmt.Sprintf("shim %s is missing", shim))
+		}
+	}
+
+	return problems
+}
+
+// Read reads a manifest file. Template variables in plugin URLs are expanded,
+// so a manifest can point at a mirror set in the environment.
+func Read(path string) (manifest Manifest, err error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return manifest, fmt.Errorf("unable to parse bake manifest %s: %w", path, err)
+	}
+
+	for i, tool := range manifest.Tools {
+		if manifest.Tools[i].PluginURL, err = config.Expand(tool.PluginURL); err != nil {
+			return manifest, fmt.Errorf("invalid plugin_url for %s in bake manifest %s: %w", tool.Name, path, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// Write writes a manifest file
+func Write(manifest Manifest, path string) error {
+	contents, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(contents, '\n'), 0o666)
+}
+
+// pluginSource returns where a plugin was added from and the commit it is at.
+// Plugins that aren't Git repositories, such as local copies, have neither.
+func pluginSource(plugin plugins.Plugin) (url, ref string) {
+	if image, ok := plugin.Image(); ok {
+		return plugins.ImagePrefix + image, ""
+	}
+
+	repo := git.NewRepo(plugin.Dir)
+	ref, _ = repo.Head()
+	url, _ = repo.RemoteURL()
+	return strings.TrimSpace(url), ref
+}
+
+func shimNames(conf config.Config) (names []string, err error) {
+	entries, err := os.ReadDir(shims.Directory(conf))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return names, err
+	}
+
+	names = []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
@@ -0,0 +1,120 @@
+package bake
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestBake(t *testing.T) {
+	conf := generateConfig(t)
+	output := filepath.Join(t.TempDir(), "baked")
+	tools := []toolversions.ToolVersions{{Name: testPluginName, Versions: []string{"1.0.0", "system"}}}
+
+	manifest, err := Bake(conf, tools, output, io.Discard, io.Discard)
+	assert.Nil(t, err)
+
+	bakedConf := conf
+	bakedConf.DataDir = output
+
+	t.Run("installs versions into output", func(t *testing.T) {
+		version := toolversions.Version{Type: "version", Value: "1.0.0"}
+		assert.True(t, installs.IsInstalled(bakedConf, plugins.New(bakedConf, testPluginName), version))
+		assert.False(t, installs.IsInstalled(conf, plugins.New(conf, testPluginName), version))
+	})
+
+	t.Run("writes manifest with plugin refs and shims", func(t *testing.T) {
+		assert.Equal(t, installs.Platform(), manifest.Platform)
+		assert.Len(t, manifest.Tools, 1)
+		assert.Equal(t, "1.0.0", manifest.Tools[0].Version)
+		assert.Len(t, manifest.Tools[0].PluginRef, 40)
+		assert.Contains(t, manifest.Shims, "dummy")
+
+		written, err := Read(filepath.Join(output, ManifestFilename))
+		assert.Nil(t, err)
+		assert.Equal(t, manifest, written)
+	})
+
+	t.Run("returns error when output is not empty", func(t *testing.T) {
+		_, err := Bake(conf, tools, output, io.Discard, io.Discard)
+		assert.ErrorContains(t, err, "is not empty")
+	})
+
+	t.Run("Verify returns no problems for baked data dir", func(t *testing.T) {
+		assert.Empty(t, Verify(bakedConf, manifest))
+	})
+
+	t.Run("Verify returns problems for data dir missing baked versions", func(t *testing.T) {
+		assert.Nil(t, os.Remove(filepath.Join(output, "shims", "dummy")))
+		defer os.WriteFile(filepath.Join(output, "shims", "dummy"), []byte{}, 0o777)
+
+		problems := Verify(conf, manifest)
+		assert.Contains(t, problems, "lua 1.0.0 is not installed")
+
+		problems = Verify(bakedConf, manifest)
+		assert.Equal(t, []string{"shim dummy is missing"}, problems)
+	})
+
+	t.Run("Verify returns problem for other platform", func(t *testing.T) {
+		other := manifest
+		other.Platform = "plan9/mips"
+		assert.Equal(t, []string{"baked for plan9/mips, running on " + installs.Platform()}, Verify(bakedConf, other))
+	})
+}
+
+func TestBakeWithSharedInstallDir(t *testing.T) {
+	conf := generateConfig(t)
+	shared := t.TempDir()
+	conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("shared_install_dir = "+shared+"\n"), 0o666))
+	output := filepath.Join(t.TempDir(), "baked")
+	tools := []toolversions.ToolVersions{{Name: testPluginName, Versions: []string{"1.0.0"}}}
+
+	_, err := Bake(conf, tools, output, io.Discard, io.Discard)
+	assert.Nil(t, err)
+
+	assert.DirExists(t, filepath.Join(output, "installs", testPluginName, "1.0.0"))
+	assert.NoDirExists(t, filepath.Join(shared, "installs", testPluginName))
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ManifestFilename)
+	t.Setenv("ASDF_TEST_MIRROR", "https://mirror.example.com")
+
+	t.Run("expands template variables in plugin URLs", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(path, []byte(`{"tools": [{"name": "lua", "version": "1.0.0", "plugin_url": "${env:ASDF_TEST_MIRROR}/lua.git"}]}`), 0o666))
+
+		manifest, err := Read(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "https://mirror.example.com/lua.git", manifest.Tools[0].PluginURL)
+	})
+
+	t.Run("returns error for undefined variable", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(path, []byte(`{"tools": [{"name": "lua", "version": "1.0.0", "plugin_url": "${env:ASDF_TEST_UNDEFINED}/lua.git"}]}`), 0o666))
+
+		_, err := Read(path)
+		assert.ErrorContains(t, err, "invalid plugin_url for lua in bake manifest")
+	})
+}
+
+func generateConfig(t *testing.T) config.Config {
+	t.Helper()
+	conf, err := config.LoadConfig()
+	assert.Nil(t, err)
+	conf.DataDir = t.TempDir()
+
+	_, err = repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf
+}
@@ -0,0 +1,97 @@
+// Package callbackenv defines the environment variables asdf passes to plugin
+// callbacks that operate on a tool version. These variables are a contract
+// plugins rely on, so they are only assembled here rather than at each call
+// site.
+package callbackenv
+
+import (
+	"path/filepath"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Origin describes where the version a callback is run for was resolved
+type Origin struct {
+	// ProjectDir is the directory the version was resolved for
+	ProjectDir string
+	// VersionSource is the file, or environment variable, that set the version
+	VersionSource string
+}
+
+// OriginOf returns the origin of versions resolved for dir
+func OriginOf(dir string, versions resolve.ToolVersions) Origin {
+	source := versions.Source
+	if versions.Directory != "" && source != "" {
+		source = filepath.Join(versions.Directory, source)
+	}
+
+	return Origin{ProjectDir: dir, VersionSource: source}
+}
+
+// Env is the environment passed to a plugin callback for a tool version
+type Env struct {
+	InstallType    string
+	InstallVersion string
+	InstallPath    string
+	DownloadPath   string
+	Concurrency    string
+	ProvenanceFile string
+	TmpDir         string
+	PatchesDir     string
+	// UninstallReason is why a version is uninstalled, see the Uninstall*
+	// constants
+	UninstallReason string
+	Origin
+}
+
+// Reasons a version is uninstalled, passed to the uninstall callbacks and
+// hooks as ASDF_UNINSTALL_REASON
+const (
+	// UninstallRequested is an uninstall asked for by the user
+	UninstallRequested = "uninstall"
+	// UninstallRebuild is the removal of a ref version whose ref moved,
+	// before it is installed again
+	UninstallRebuild = "rebuild"
+)
+
+// ForVersion returns the environment for an installed tool version
+func ForVersion(conf config.Config, plugin plugins.Plugin, version toolversions.Version) Env {
+	return Env{
+		InstallType:    version.Type,
+		InstallVersion: version.Value,
+		InstallPath:    installs.InstallPath(conf, plugin, version),
+	}
+}
+
+// Map returns the environment variables. The install variables are always
+// set, even when empty, so values inherited from a parent asdf process are
+// cleared. The others are only set for the callbacks they apply to.
+func (e Env) Map() map[string]string {
+	env := map[string]string{
+		"ASDF_INSTALL_TYPE":    e.InstallType,
+		"ASDF_INSTALL_VERSION": e.InstallVersion,
+		"ASDF_INSTALL_PATH":    e.InstallPath,
+	}
+
+	optional := map[string]string{
+		"ASDF_DOWNLOAD_PATH":    e.DownloadPath,
+		"ASDF_CONCURRENCY":      e.Concurrency,
+		"ASDF_PROVENANCE_FILE":  e.ProvenanceFile,
+		"TMPDIR":                e.TmpDir,
+		"ASDF_PATCHES_DIR":      e.PatchesDir,
+		"ASDF_UNINSTALL_REASON": e.UninstallReason,
+		"ASDF_PROJECT_DIR":      e.ProjectDir,
+		"ASDF_VERSION_SOURCE":   e.VersionSource,
+	}
+	for name, value := range optional {
+		if value != "" {
+			env[name] = value
+		}
+	}
+
+	return env
+}
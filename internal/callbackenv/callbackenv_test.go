@@ -0,0 +1,71 @@
+package callbackenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	t.Run("matches golden file when all variables set", func(t *testing.T) {
+		env := Env{
+			InstallType:     "version",
+			InstallVersion:  "1.2.3",
+			InstallPath:     "/data/installs/lua/1.2.3",
+			DownloadPath:    "/data/downloads/lua/1.2.3",
+			Concurrency:     "4",
+			ProvenanceFile:  "/data/downloads/lua/1.2.3/.asdf-provenance",
+			TmpDir:          "/data/tmp/lua-1.2.3-123",
+			PatchesDir:      "/data/tmp/lua-1.2.3-123/patches",
+			UninstallReason: "uninstall",
+			Origin:          Origin{ProjectDir: "/home/user/project", VersionSource: "/home/user/project/.tool-versions"},
+		}
+		assertGolden(t, "all.golden", env.Map())
+	})
+
+	t.Run("matches golden file when only install variables set", func(t *testing.T) {
+		assertGolden(t, "install.golden", Env{InstallType: "ref", InstallVersion: "main"}.Map())
+	})
+}
+
+func TestForVersion(t *testing.T) {
+	conf := config.Config{DataDir: "/data"}
+	plugin := plugins.New(conf, "lua")
+
+	env := ForVersion(conf, plugin, toolversions.Version{Type: "version", Value: "5.4.6"})
+	assert.Equal(t, Env{InstallType: "version", InstallVersion: "5.4.6", InstallPath: "/data/installs/lua/5.4.6"}, env)
+}
+
+func TestOriginOf(t *testing.T) {
+	t.Run("joins directory and file name of version file", func(t *testing.T) {
+		origin := OriginOf("/project/sub", resolve.ToolVersions{Directory: "/project", Source: ".tool-versions"})
+		assert.Equal(t, Origin{ProjectDir: "/project/sub", VersionSource: "/project/.tool-versions"}, origin)
+	})
+
+	t.Run("uses environment variable name as is", func(t *testing.T) {
+		origin := OriginOf("/project", resolve.ToolVersions{Source: "ASDF_LUA_VERSION"})
+		assert.Equal(t, Origin{ProjectDir: "/project", VersionSource: "ASDF_LUA_VERSION"}, origin)
+	})
+}
+
+func assertGolden(t *testing.T, name string, env map[string]string) {
+	t.Helper()
+	var lines []string
+	for key, value := range env {
+		lines = append(lines, fmt.Sprintf("%s=%s\n", key, value))
+	}
+	slices.Sort(lines)
+
+	expected, err := os.ReadFile(filepath.Join("testdata", name))
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), strings.Join(lines, ""))
+}
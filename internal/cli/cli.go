@@ -2,35 +2,67 @@
 package cli
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"maps"
 	"net/mail"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/asdf-vm/asdf/internal/audit"
+	"github.com/asdf-vm/asdf/internal/bake"
+	"github.com/asdf-vm/asdf/internal/callbackenv"
 	"github.com/asdf-vm/asdf/internal/cli/set"
 	"github.com/asdf-vm/asdf/internal/completions"
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/conflicts"
+	"github.com/asdf-vm/asdf/internal/data"
 	"github.com/asdf-vm/asdf/internal/exec"
 	"github.com/asdf-vm/asdf/internal/execenv"
 	"github.com/asdf-vm/asdf/internal/execute"
+	"github.com/asdf-vm/asdf/internal/export"
 	"github.com/asdf-vm/asdf/internal/help"
 	"github.com/asdf-vm/asdf/internal/hook"
 	"github.com/asdf-vm/asdf/internal/info"
 	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/limits"
+	"github.com/asdf-vm/asdf/internal/lint"
+	"github.com/asdf-vm/asdf/internal/lock"
+	"github.com/asdf-vm/asdf/internal/maintain"
+	"github.com/asdf-vm/asdf/internal/messages"
+	"github.com/asdf-vm/asdf/internal/migrate"
+	"github.com/asdf-vm/asdf/internal/notify"
+	"github.com/asdf-vm/asdf/internal/oci"
+	"github.com/asdf-vm/asdf/internal/overrides"
 	"github.com/asdf-vm/asdf/internal/pluginindex"
 	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/profile"
+	"github.com/asdf-vm/asdf/internal/projects"
+	"github.com/asdf-vm/asdf/internal/provenance"
+	"github.com/asdf-vm/asdf/internal/ready"
+	"github.com/asdf-vm/asdf/internal/recovery"
 	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/retool"
+	"github.com/asdf-vm/asdf/internal/revision"
+	"github.com/asdf-vm/asdf/internal/serve"
+	"github.com/asdf-vm/asdf/internal/setup"
 	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/stamp"
 	"github.com/asdf-vm/asdf/internal/toolversions"
 	"github.com/asdf-vm/asdf/internal/versions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
 	"github.com/urfave/cli/v3"
 )
 
@@ -48,6 +80,10 @@ latest asdf binary manually from the asdf website.
 Please visit https://asdf-vm.com/ or https://github.com/asdf-vm/asdf for more
 details.`
 
+// lockTimeout is how long commands wait for a lock held by another asdf
+// process, set from the global --lock-timeout flag
+var lockTimeout time.Duration
+
 // Execute defines the full CLI API and then runs it
 func Execute(version string) {
 	logger := log.New(os.Stderr, "", 0)
@@ -62,7 +98,100 @@ func Execute(version string) {
 		},
 		Usage:     "The multiple runtime version manager",
 		UsageText: usageText,
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:    "lock-timeout",
+				Usage:   "How long to wait for another asdf process to release a lock before failing (default: wait indefinitely)",
+				Sources: cli.EnvVars("ASDF_LOCK_TIMEOUT"),
+			},
+			&cli.StringFlag{
+				Name:    "symlinks",
+				Usage:   "Which paths of a directory reached through a symlink to search for versions (values: logical, physical, both)",
+				Sources: cli.EnvVars("ASDF_SYMLINK_RESOLUTION"),
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			lockTimeout = cmd.Duration("lock-timeout")
+			if cmd.IsSet("symlinks") {
+				mode := cmd.String("symlinks")
+				if !slices.Contains(config.SymlinkResolutionModes, mode) {
+					err := fmt.Errorf("invalid value %q for --symlinks, expected one of %s", mode, strings.Join(config.SymlinkResolutionModes, ", "))
+					logger.Print(err)
+					return ctx, err
+				}
+				// Settings are read from the environment so the mode also
+				// applies to the shims of commands run by asdf
+				os.Setenv("ASDF_SYMLINK_RESOLUTION", mode)
+			}
+			conf, loaded := selectLocale(logger)
+			if loaded && sanitizesEnv(conf, cmd.Args().First()) {
+				sanitizeEnv(logger, conf)
+			}
+			return ctx, nil
+		},
 		Commands: []*cli.Command{
+			{
+				Name: "apply",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the changes the profile makes without making them",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return applyCommand(logger, cmd.Args().Get(0), cmd.Bool("dry-run"))
+				},
+			},
+			{
+				Name: "bake",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Directory to bake the data directory, or with --format oci the image layout, into",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "dir",
+						Usage: "What to produce (values: dir, oci)",
+					},
+					&cli.StringFlag{
+						Name:  "data-dir",
+						Usage: "With --format oci, path the data directory is baked at and placed at in the image",
+					},
+					&cli.StringFlag{
+						Name:  "file",
+						Usage: "Tool versions file to bake, defaults to the one in the current directory",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return bakeCommand(logger, cmd.String("output"), cmd.String("format"), cmd.String("data-dir"), cmd.String("file"))
+				},
+				Commands: []*cli.Command{
+					{
+						Name: "verify",
+						Action: func(_ context.Context, cmd *cli.Command) error {
+							return bakeVerifyCommand(logger, cmd.Args().Get(0))
+						},
+					},
+				},
+			},
+			{
+				Name: "cache",
+				Commands: []*cli.Command{
+					{
+						Name: "clean",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "tmp",
+								Usage: "Remove temporary directories kept from failed installs instead of cached plugin data",
+							},
+						},
+						Action: func(_ context.Context, cmd *cli.Command) error {
+							return cacheCleanCommand(logger, cmd.Bool("tmp"))
+						},
+					},
+				},
+			},
 			{
 				Name: "cmd",
 				Action: func(_ context.Context, cmd *cli.Command) error {
@@ -71,6 +200,14 @@ func Execute(version string) {
 					return extensionCommand(logger, args)
 				},
 			},
+			{
+				Name: "complete",
+				// The words being completed are passed on to the plugin as is
+				SkipFlagParsing: true,
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return completeCommand(logger, cmd.Args().Get(0), cmd.Args().Tail())
+				},
+			},
 			{
 				Name: "completion",
 				Action: func(_ context.Context, cmd *cli.Command) error {
@@ -85,21 +222,69 @@ func Execute(version string) {
 						Name:  "no-header",
 						Usage: "Whether or not to print a header line",
 					},
+					&cli.BoolFlag{
+						Name:  "explain",
+						Usage: "Print every place a version was looked for and why it was used or skipped",
+					},
+					&cli.BoolFlag{
+						Name:  "fast",
+						Usage: "Only answer from the resolution cache within the prompt_budget, for shell prompts",
+					},
 				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					tool := cmd.Args().Get(0)
 
+					if cmd.Bool("explain") {
+						return currentExplainCommand(logger, tool)
+					}
+
 					noHeader := cmd.Bool("no-header")
+					if cmd.Bool("fast") {
+						return currentFastCommand(logger, tool, noHeader)
+					}
+
 					return currentCommand(logger, tool, noHeader)
 				},
 			},
+			{
+				Name: "doctor",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "takeover",
+						Usage: "Print the shell changes needed for asdf to take over the tool from other version managers",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return doctorCommand(logger, cmd.String("takeover"))
+				},
+			},
 			{
 				Name: "env",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Print the environment for all current tools in this format instead of running a command (format: dotenv)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "include",
+						Usage: "Also add man page or shell completion directories of the tools (values: man, completions)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "with",
+						Usage: "Use this version of a tool instead of the current one (format: <tool>=<version>)",
+					},
+				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
+					include := cmd.StringSlice("include")
+					with := cmd.StringSlice("with")
+					if format := cmd.String("format"); format != "" {
+						return envFormatCommand(logger, format, include, with)
+					}
+
 					shimmedCommand := cmd.Args().Get(0)
 					args := cmd.Args().Slice()
 
-					return envCommand(logger, shimmedCommand, args)
+					return envCommand(logger, shimmedCommand, args, include, with)
 				},
 			},
 			{
@@ -112,15 +297,91 @@ func Execute(version string) {
 					command := cmd.Args().Get(0)
 					args := cmd.Args().Slice()
 
-					return execCommand(logger, command, args)
+					if command == "--env-only" {
+						return execEnvOnlyCommand(logger, cmd.Args().Get(1), args[1:])
+					}
+
+					trace := os.Getenv(execenv.TraceVar)
+					var with []string
+				options:
+					for len(args) > 0 {
+						switch option := args[0]; {
+						case option == "--trace":
+							trace = "stderr"
+						case strings.HasPrefix(option, "--trace="):
+							trace = strings.TrimPrefix(option, "--trace=")
+						case option == "--with" && len(args) > 1:
+							with = append(with, args[1])
+							args = args[1:]
+						case strings.HasPrefix(option, "--with="):
+							with = append(with, strings.TrimPrefix(option, "--with="))
+						default:
+							break options
+						}
+						args = args[1:]
+					}
+
+					command = ""
+					if len(args) > 0 {
+						command = args[0]
+					}
+
+					return execCommand(logger, command, args, trace, with)
+				},
+			},
+			{
+				Name: "export",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "The format to export tools and versions in",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return exportCommand(logger, cmd.String("format"))
+				},
+			},
+			{
+				Name: "flags",
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return flagsCommand(logger)
+				},
+			},
+			{
+				Name: "group",
+				Commands: []*cli.Command{
+					{
+						Name: "list",
+						Action: func(_ context.Context, _ *cli.Command) error {
+							return groupListCommand(logger)
+						},
+					},
 				},
 			},
 			{
 				Name: "help",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "List every command rather than the common ones",
+					},
+				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					toolName := cmd.Args().Get(0)
 					toolVersion := cmd.Args().Get(1)
-					return helpCommand(logger, version, toolName, toolVersion)
+					return helpCommand(logger, version, toolName, toolVersion, cmd.Bool("all"))
+				},
+			},
+			{
+				Name: "import",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "from",
+						Usage: "The format of the file to import tools and versions from",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return importCommand(logger, cmd.String("from"), cmd.Args().Get(0))
 				},
 			},
 			{
@@ -128,7 +389,7 @@ func Execute(version string) {
 				Action: func(_ context.Context, _ *cli.Command) error {
 					conf, err := config.LoadConfig()
 					if err != nil {
-						logger.Printf("error loading config: %s", err)
+						logger.Printf(messages.Get(messages.ConfigLoadError), err)
 						return err
 					}
 
@@ -149,11 +410,26 @@ func Execute(version string) {
 						Name:  "keep-download",
 						Usage: "Whether or not to keep download directory after successful install",
 					},
+					&cli.BoolFlag{
+						Name:  "refresh-refs",
+						Usage: "Rebuild installed ref: versions whose branch or tag has moved upstream",
+					},
+					&cli.StringFlag{
+						Name:  "at",
+						Usage: "Install the versions set by the version files at a Git revision of the repository",
+					},
+					&cli.BoolFlag{
+						Name:  "workspace",
+						Usage: "Install the versions of the workspace root and every subproject of its .tool-workspace file",
+					},
 				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					args := cmd.Args()
 					keepDownload := cmd.Bool("keep-download")
-					return installCommand(logger, args.Get(0), args.Get(1), keepDownload)
+					started := time.Now()
+					err := installCommand(logger, args.Get(0), args.Get(1), keepDownload, cmd.Bool("refresh-refs"), cmd.String("at"), cmd.Bool("workspace"))
+					notifyFinished(logger, cmd, started, err)
+					return err
 				},
 			},
 			{
@@ -172,11 +448,104 @@ func Execute(version string) {
 					return latestCommand(logger, all, tool, pattern)
 				},
 			},
+			{
+				Name: "lint",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "The format to print findings in (format: text, json, sarif)",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return lintCommand(logger, cmd.String("format"), cmd.Args().Get(0))
+				},
+			},
 			{
 				Name: "list",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "refresh",
+						Usage: "Ignore cached versions when listing all versions",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					args := cmd.Args()
+					return listCommand(logger, args.Get(0), args.Get(1), args.Get(2), cmd.Bool("refresh"))
+				},
+			},
+			{
+				Name: "lock",
+				Commands: []*cli.Command{
+					{
+						Name: "status",
+						Action: func(_ context.Context, _ *cli.Command) error {
+							return lockStatusCommand(logger)
+						},
+					},
+					{
+						Name: "wait",
+						Action: func(_ context.Context, cmd *cli.Command) error {
+							return lockWaitCommand(logger, cmd.Args().Slice())
+						},
+					},
+				},
+			},
+			{
+				Name: "maintain",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return maintainCommand(logger, cmd.Args().Slice())
+				},
+			},
+			{
+				Name: "recover",
+				Action: func(_ context.Context, _ *cli.Command) error {
+					return recoverCommand(logger)
+				},
+			},
+			{
+				Name: "migrate-data",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "List pending migrations without applying them",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return migrateDataCommand(logger, cmd.Bool("dry-run"))
+				},
+			},
+			{
+				Name: "override",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "The directory the override applies to (default: current directory)",
+					},
+				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					args := cmd.Args()
-					return listCommand(logger, args.Get(0), args.Get(1), args.Get(2))
+					return overrideCommand(logger, cmd.String("dir"), args.First(), args.Tail())
+				},
+				Commands: []*cli.Command{
+					{
+						Name: "list",
+						Action: func(_ context.Context, _ *cli.Command) error {
+							return overrideListCommand(logger)
+						},
+					},
+					{
+						Name: "rm",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "dir",
+								Usage: "The directory the override applies to (default: current directory)",
+							},
+						},
+						Action: func(_ context.Context, cmd *cli.Command) error {
+							return overrideRemoveCommand(logger, cmd.String("dir"), cmd.Args().Get(0))
+						},
+					},
 				},
 			},
 			{
@@ -188,7 +557,7 @@ func Execute(version string) {
 							args := cmd.Args()
 							conf, err := config.LoadConfig()
 							if err != nil {
-								logger.Printf("error loading config: %s", err)
+								logger.Printf(messages.Get(messages.ConfigLoadError), err)
 								return err
 							}
 
@@ -219,6 +588,18 @@ func Execute(version string) {
 							},
 						},
 					},
+					{
+						Name: "capabilities",
+						Action: func(_ context.Context, cmd *cli.Command) error {
+							return pluginCapabilitiesCommand(logger, cmd.Args().Get(0))
+						},
+					},
+					{
+						Name: "gc",
+						Action: func(_ context.Context, cmd *cli.Command) error {
+							return pluginGCCommand(logger, cmd.Args().Get(0))
+						},
+					},
 					{
 						Name: "remove",
 						Action: func(_ context.Context, cmd *cli.Command) error {
@@ -236,7 +617,10 @@ func Execute(version string) {
 						},
 						Action: func(_ context.Context, cmd *cli.Command) error {
 							args := cmd.Args()
-							return pluginUpdateCommand(cmd, logger, args.Get(0), args.Get(1))
+							started := time.Now()
+							err := pluginUpdateCommand(cmd, logger, args.Get(0), args.Get(1))
+							notifyFinished(logger, cmd, started, err)
+							return err
 						},
 					},
 					{
@@ -261,6 +645,33 @@ func Execute(version string) {
 					},
 				},
 			},
+			{
+				Name: "provenance",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					args := cmd.Args()
+					return provenanceCommand(logger, args.Get(0), args.Get(1))
+				},
+			},
+			{
+				Name: "ready",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "How long to wait for tools to become ready (default: check once)",
+					},
+					&cli.BoolFlag{
+						Name:  "install",
+						Usage: "Install missing versions and regenerate shims before waiting",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the status of each tool as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return readyCommand(logger, cmd.Duration("timeout"), cmd.Bool("install"), cmd.Bool("json"))
+				},
+			},
 			{
 				Name: "reshim",
 				Action: func(_ context.Context, cmd *cli.Command) error {
@@ -268,6 +679,58 @@ func Execute(version string) {
 					return reshimCommand(logger, args.Get(0), args.Get(1))
 				},
 			},
+			{
+				Name: "resolve",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "at",
+						Usage: "Resolve versions from the version files at a Git revision of the repository",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return resolveCommand(logger, cmd.Args().Get(0), cmd.String("at"))
+				},
+			},
+			{
+				Name: "retool",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "from",
+						Usage: "The version to move away from, matching every version starting with it (format: <tool>@<version>)",
+					},
+					&cli.StringFlag{
+						Name:  "to",
+						Usage: "The version to move to (format: <tool>@<version>)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "paths",
+						Usage: "The directories to search for version files, defaults to the current directory",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the version files that would change without changing them",
+					},
+					&cli.StringFlag{
+						Name:  "summary",
+						Usage: "Write a Markdown summary of the changed files to this file",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return retoolCommand(logger, cmd.String("from"), cmd.String("to"), cmd.StringSlice("paths"), cmd.Bool("dry-run"), cmd.String("summary"))
+				},
+			},
+			{
+				Name: "serve",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "http",
+						Usage: "Address to serve read-only queries on, e.g. :9123",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return serveCommand(logger, cmd.String("http"))
+				},
+			},
 			{
 				Name: "set",
 				Flags: []cli.Flag{
@@ -287,10 +750,48 @@ func Execute(version string) {
 					home := cmd.Bool("home")
 					parent := cmd.Bool("parent")
 					return set.Main(os.Stdout, os.Stderr, args, home, parent, func() (string, error) {
-						return os.UserHomeDir()
+						homeDir, err := os.UserHomeDir()
+						if err != nil {
+							return "", fmt.Errorf("unable to set version in home directory: %w", err)
+						}
+						return homeDir, nil
 					})
 				},
 			},
+			{
+				Name: "setup",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Answer yes to every question",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return setupCommand(logger, os.Stdin, os.Stdout, cmd.Bool("yes"))
+				},
+			},
+			{
+				Name: "stamp",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: stamp.FormatJSON,
+						Usage: "Format to write the stamp in (values: json, env)",
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "File to write the stamp to instead of STDOUT",
+					},
+					&cli.StringFlag{
+						Name:  "verify",
+						Usage: "Stamp file to compare against the tools resolved for the current directory",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return stampCommand(logger, cmd.String("format"), cmd.String("output"), cmd.String("verify"))
+				},
+			},
 			{
 				Name: "shimversions",
 				Action: func(_ context.Context, cmd *cli.Command) error {
@@ -300,11 +801,17 @@ func Execute(version string) {
 			},
 			{
 				Name: "uninstall",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Uninstall the version even when known projects still use it",
+					},
+				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					tool := cmd.Args().Get(0)
 					version := cmd.Args().Get(1)
 
-					return uninstallCommand(logger, tool, version)
+					return uninstallCommand(logger, tool, version, cmd.Bool("force"))
 				},
 			},
 			{
@@ -314,6 +821,13 @@ func Execute(version string) {
 					return errors.New("command removed")
 				},
 			},
+			{
+				Name: "verify",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					args := cmd.Args()
+					return verifyCommand(logger, args.Get(0), args.Get(1))
+				},
+			},
 			{
 				Name: "where",
 				Action: func(_ context.Context, cmd *cli.Command) error {
@@ -333,12 +847,24 @@ func Execute(version string) {
 			},
 		},
 		CommandNotFound: func(_ context.Context, _ *cli.Command, s string) {
-			logger.Printf("invalid command provided: %s\n\n", s)
-			helpCommand(logger, version, "", "")
+			logger.Printf(messages.Get(messages.InvalidCommand)+"\n\n", s)
+			helpCommand(logger, version, "", "", false)
 			cli.OsExiter(1)
 		},
 	}
 
+	// asdf, asdf --help and asdf help print the same tiered help, commands
+	// with examples add them to the help printed by asdf <command> --help
+	printHelp := cli.HelpPrinter
+	cli.HelpPrinter = func(w io.Writer, templ string, data any) {
+		if data == app {
+			helpCommand(logger, version, "", "", false)
+			return
+		}
+		printHelp(w, templ, data)
+	}
+	addExampleTemplates(app.Commands, nil)
+
 	err := unsetAsdfReservedEnvVars()
 	if err != nil {
 		cli.OsExiter(1)
@@ -363,17 +889,71 @@ Completions are available for: %v`, shell, strings.Join(completions.Names(), ",
 	return nil
 }
 
-// This function is a whole mess and needs to be refactored
-func currentCommand(logger *log.Logger, tool string, noHeader bool) error {
-	conf, err := config.LoadConfig()
-	if err != nil {
-		logger.Printf("error loading config: %s", err)
+// completeCommand prints completions for the arguments of a shimmed command
+// from the complete callback of the plugin providing the current version of
+// it. The last word is the one being completed, only candidates starting with
+// it are printed. Nothing is printed when the command can't be resolved or the
+// plugin has no complete callback, leaving shells to fall back to their
+// default completion.
+func completeCommand(logger *log.Logger, command string, words []string) error {
+	if command == "" {
+		fmt.Println(messages.Get(messages.UsageComplete))
+		return errors.New("must provide command")
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
+	}
+
+	_, plugin, version, found, err := shims.FindExecutable(conf, command, currentDir)
+	if err != nil || !found || !plugin.Supports("complete") {
+		return nil
+	}
+
+	env, err := execEnvironment(conf, plugin, version)
+	if err != nil {
+		return nil
+	}
+
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+	}
+
+	var stdout strings.Builder
+	err = plugin.RunCallback("complete", append([]string{command}, words...), env, &stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range strings.Split(stdout.String(), "\n") {
+		if candidate != "" && strings.HasPrefix(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+
+	return nil
+}
+
+// This function is a whole mess and needs to be refactored
+func currentCommand(logger *log.Logger, tool string, noHeader bool) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
 	currentDir, err := os.Getwd()
 	if err != nil {
-		logger.Printf("unable to get current directory: %s", err)
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
 		return err
 	}
 
@@ -391,15 +971,25 @@ func currentCommand(logger *log.Logger, tool string, noHeader bool) error {
 		}
 
 		if len(allPlugins) < 1 {
-			fmt.Println("No plugins installed")
+			fmt.Println(messages.Get(messages.NoPluginsInstalled))
 			return nil
 		}
 
-		for _, plugin := range allPlugins {
-			toolversion, versionFound, versionInstalled, currentResolvedVersion := getVersionInfo(conf, plugin, currentDir)
-			formatCurrentVersionLine(w, plugin, toolversion, versionFound, versionInstalled, currentResolvedVersion, err)
+		var warnings strings.Builder
+		deprecated := false
+		for _, result := range resolve.All(conf, allPlugins, currentDir) {
+			plugin := result.Plugin
+			toolversion, versionFound, versionInstalled, currentResolvedVersion, systemPath := versionInfo(conf, plugin, currentDir, result.Versions, result.Found)
+			formatCurrentVersionLine(w, plugin, toolversion, versionFound, versionInstalled, currentResolvedVersion, systemPath, err)
+			if versionFound && !checkCurrentDeprecated(conf, plugin, toolversion.Versions[0], &warnings) {
+				deprecated = true
+			}
 		}
 		w.Flush()
+		fmt.Fprint(os.Stderr, warnings.String())
+		if deprecated {
+			return errors.New("deprecated version in use")
+		}
 		return nil
 	}
 
@@ -410,44 +1000,223 @@ func currentCommand(logger *log.Logger, tool string, noHeader bool) error {
 	pluginExists := !ok
 
 	if pluginExists {
-		toolversion, versionFound, versionInstalled, currentResolvedVersion := getVersionInfo(conf, plugin, currentDir)
-		formatCurrentVersionLine(w, plugin, toolversion, versionFound, versionInstalled, currentResolvedVersion, err)
+		toolversion, versionFound, versionInstalled, currentResolvedVersion, systemPath := getVersionInfo(conf, plugin, currentDir)
+		formatCurrentVersionLine(w, plugin, toolversion, versionFound, versionInstalled, currentResolvedVersion, systemPath, err)
 		w.Flush()
 		if !versionFound {
 			os.Exit(126)
 		}
 
+		if !checkCurrentDeprecated(conf, plugin, toolversion.Versions[0], os.Stderr) {
+			return errors.New("deprecated version in use")
+		}
+
 		if !versionInstalled {
 			cli.OsExiter(1)
 		}
 	} else {
-		fmt.Printf("No such plugin: %s\n", tool)
+		fmt.Printf(messages.Get(messages.NoSuchPlugin)+"\n", tool)
+		return err
+	}
+
+	return nil
+}
+
+// currentExplainCommand prints the candidates considered when resolving the
+// current version of the tool, or of every tool when none is given
+func currentExplainCommand(logger *log.Logger, tool string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
+	}
+
+	var toolPlugins []plugins.Plugin
+	if tool == "" {
+		toolPlugins, err = plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.PluginListError), err)
+			return err
+		}
+	} else {
+		plugin := plugins.New(conf, tool)
+		if err := plugin.Exists(); err != nil {
+			fmt.Printf(messages.Get(messages.NoSuchPlugin)+"\n", tool)
+			return err
+		}
+		toolPlugins = []plugins.Plugin{plugin}
+	}
+
+	for i, plugin := range toolPlugins {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		candidates, toolversion, found, err := resolve.Explain(conf, plugin, currentDir)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: %s\n", plugin.Name, err)
+		case found:
+			fmt.Printf("%s: %s\n", plugin.Name, strings.Join(toolversion.Versions, " "))
+		default:
+			fmt.Printf(messages.Get(messages.NoVersionSetForTool)+"\n", plugin.Name)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, candidate := range candidates {
+			status := "skipped"
+			if candidate.Accepted {
+				status = "accepted"
+			}
+			reason := candidate.Reason
+			if len(candidate.Versions) > 0 {
+				reason = fmt.Sprintf("%s (%s)", reason, strings.Join(candidate.Versions, " "))
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", status, candidate.Source, reason)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+// currentFastCommand prints the current version of the tool, or of every tool
+// when none is given, using only the resolution cache so shell prompts don't
+// slow down on every render. Tools the cache can't answer for, and those left
+// once prompt_budget milliseconds have passed, are printed as stale. Versions
+// are never checked for deprecation and the command only fails when asdf
+// can't be loaded.
+func currentFastCommand(logger *log.Logger, tool string, noHeader bool) error {
+	start := time.Now()
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	budget, _ := conf.PromptBudget()
+	deadline := time.After(time.Duration(budget)*time.Millisecond - time.Since(start))
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
 		return err
 	}
 
+	var toolPlugins []plugins.Plugin
+	if tool == "" {
+		toolPlugins, err = plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.PluginListError), err)
+			return err
+		}
+	} else {
+		plugin := plugins.New(conf, tool)
+		if err := plugin.Exists(); err != nil {
+			fmt.Printf(messages.Get(messages.NoSuchPlugin)+"\n", tool)
+			return err
+		}
+		toolPlugins = []plugins.Plugin{plugin}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 16, 0, 1, ' ', 0)
+	if !noHeader {
+		writeHeader(w)
+	}
+	defer w.Flush()
+
+	type result struct {
+		toolversion resolve.ToolVersions
+		found       bool
+		fresh       bool
+		installed   bool
+	}
+
+	for i, plugin := range toolPlugins {
+		results := make(chan result, 1)
+		go func() {
+			toolversion, found, fresh, err := resolve.Cached(conf, plugin, currentDir)
+			installed := found && installs.IsInstalled(conf, plugin, toolversions.Parse(toolversion.Versions[0]))
+			results <- result{toolversion: toolversion, found: found, fresh: fresh && err == nil, installed: installed}
+		}()
+
+		select {
+		case r := <-results:
+			if !r.fresh {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", plugin.Name, "stale", "______", "")
+				continue
+			}
+			formatCurrentVersionLine(w, plugin, r.toolversion, r.found, r.installed, "", "", nil)
+		case <-deadline:
+			for _, skipped := range toolPlugins[i:] {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", skipped.Name, "stale", "______", "")
+			}
+			return nil
+		}
+	}
+
 	return nil
 }
 
-func getVersionInfo(conf config.Config, plugin plugins.Plugin, currentDir string) (resolve.ToolVersions, bool, bool, string) {
+// getVersionInfo resolves the current version of the tool, returning whether
+// it is installed, the installed version matching it when it isn't, and for
+// the system version the path of the executable found on PATH instead
+func getVersionInfo(conf config.Config, plugin plugins.Plugin, currentDir string) (resolve.ToolVersions, bool, bool, string, string) {
 	toolversion, found, _ := resolve.Version(conf, plugin, currentDir)
+	return versionInfo(conf, plugin, currentDir, toolversion, found)
+}
+
+// versionInfo is getVersionInfo for versions already resolved, see resolve.All
+func versionInfo(conf config.Config, plugin plugins.Plugin, currentDir string, toolversion resolve.ToolVersions, found bool) (resolve.ToolVersions, bool, bool, string, string) {
 	installed := false
+	var systemPath string
 	if found {
 		firstVersion := toolversion.Versions[0]
 		version := toolversions.Parse(firstVersion)
+		if version.IsSystem() {
+			systemPath, installed = shims.SystemExecutable(conf, plugin)
+			return toolversion, found, installed, "", systemPath
+		}
 		installed = installs.IsInstalled(conf, plugin, version)
 	}
 	var currentResolvedVersion string
 	if found && !installed {
+		// Match strategies can be set by project asdfrc files
+		if dirConf, err := conf.ForDirectory(currentDir); err == nil {
+			conf = dirConf
+		}
 		currentResolvedVersion = resolve.FindBestMatchingVersion(conf, plugin, toolversion.Versions)
 	}
-	return toolversion, found, installed, currentResolvedVersion
+	return toolversion, found, installed, currentResolvedVersion, systemPath
+}
+
+// checkCurrentDeprecated writes a warning or error to out when the version is
+// deprecated, returning false if the version is not allowed to be used. When
+// the check itself fails a warning is written and the version is allowed.
+func checkCurrentDeprecated(conf config.Config, plugin plugins.Plugin, version string, out io.Writer) bool {
+	err := versions.CheckDeprecated(conf, plugin, version, out)
+	if _, ok := err.(versions.DeprecatedVersionError); ok {
+		fmt.Fprintf(out, "error: %s\n", err)
+		return false
+	}
+	if err != nil {
+		fmt.Fprintf(out, messages.Get(messages.DeprecatedCheckError)+"\n", plugin.Name, version, err)
+	}
+	return true
 }
 
 func writeHeader(w *tabwriter.Writer) {
 	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Name", "Version", "Source", "Installed")
 }
 
-func formatCurrentVersionLine(w *tabwriter.Writer, plugin plugins.Plugin, toolversion resolve.ToolVersions, found bool, installed bool, currentResolvedVersion string, err error) error {
+func formatCurrentVersionLine(w *tabwriter.Writer, plugin plugins.Plugin, toolversion resolve.ToolVersions, found bool, installed bool, currentResolvedVersion, systemPath string, err error) error {
 	if err != nil {
 		return err
 	}
@@ -455,15 +1224,23 @@ func formatCurrentVersionLine(w *tabwriter.Writer, plugin plugins.Plugin, toolve
 	// columns are: name, version, source, installed
 	version := formatVersions(toolversion.Versions, currentResolvedVersion)
 	source := formatSource(toolversion, found)
-	installedStatus := formatInstalled(toolversion, plugin.Name, found, installed)
+	installedStatus := formatInstalled(toolversion, plugin.Name, found, installed, systemPath)
 	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", plugin.Name, version, source, installedStatus)
 	return nil
 }
 
-func formatInstalled(toolversion resolve.ToolVersions, name string, found, installed bool) string {
+func formatInstalled(toolversion resolve.ToolVersions, name string, found, installed bool, systemPath string) string {
 	if !found {
 		return ""
 	}
+	if toolversions.Parse(toolversion.Versions[0]).IsSystem() {
+		if !installed {
+			return "false - Not found on PATH"
+		}
+		if systemPath != "" {
+			return fmt.Sprintf("true - %s", systemPath)
+		}
+	}
 	if !installed {
 		return fmt.Sprintf("false - Run `asdf install %s %s`", name, toolversion.Versions[0])
 	}
@@ -491,14 +1268,85 @@ func formatVersions(versions []string, currentResolvedVersion string) string {
 	}
 }
 
-func envCommand(logger *log.Logger, shimmedCommand string, args []string) error {
+func doctorCommand(logger *log.Logger, takeover string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginListError), err)
+		return err
+	}
+
+	pluginNames := toolPlugins(allPlugins)
+	shimsDir := shims.Directory(conf)
+	found := conflicts.Detect(os.Getenv("PATH"), shimsDir, slices.Sorted(maps.Keys(pluginNames)), os.Getenv)
+
+	if takeover != "" {
+		plugin, err := loadPlugin(logger, conf, takeover)
+		if err != nil {
+			return err
+		}
+
+		tool := plugin.ToolName()
+		if !slices.ContainsFunc(found, func(c conflicts.Conflict) bool { return c.Tool == tool }) {
+			logger.Printf(messages.Get(messages.NoManagersAhead), takeover)
+			return nil
+		}
+
+		return conflicts.WriteTakeover(os.Stdout, tool, shimsDir, found)
+	}
+
+	var problems []string
+	if !slices.Contains(filepath.SplitList(os.Getenv("PATH")), shimsDir) {
+		problems = append(problems, fmt.Sprintf("asdf shims directory %s is not on PATH", shimsDir))
+	}
+
+	for _, conflict := range found {
+		problems = append(problems, fmt.Sprintf("%s, see asdf doctor --takeover %s", conflict, pluginNames[conflict.Tool]))
+	}
+
+	if len(problems) == 0 {
+		fmt.Println(messages.Get(messages.NoProblemsFound))
+		return nil
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	cli.OsExiter(1)
+	return fmt.Errorf("%d problems found", len(problems))
+}
+
+// toolPlugins maps the names of the tools provided by the plugins to the names
+// of the plugins, see plugins.Plugin.ToolName. When plugins in different
+// namespaces provide the same tool the first one is kept.
+func toolPlugins(allPlugins []plugins.Plugin) map[string]string {
+	pluginNames := map[string]string{}
+	for _, plugin := range allPlugins {
+		if _, ok := pluginNames[plugin.ToolName()]; !ok {
+			pluginNames[plugin.ToolName()] = plugin.Name
+		}
+	}
+
+	return pluginNames
+}
+
+func envCommand(logger *log.Logger, shimmedCommand string, args []string, include, with []string) error {
 	command := "env"
 
 	if shimmedCommand == "" {
-		logger.Printf("usage: asdf env <command>")
+		logger.Print(messages.Get(messages.UsageEnv))
 		return fmt.Errorf("usage: asdf env <command>")
 	}
 
+	if err := validateIncludes(logger, include); err != nil {
+		return err
+	}
+
 	if len(args) >= 2 {
 		command = args[1]
 	}
@@ -510,11 +1358,15 @@ func envCommand(logger *log.Logger, shimmedCommand string, args []string) error
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if err := overlayVersions(logger, conf, with); err != nil {
 		return err
 	}
 
-	_, plugin, version, err := getExecutable(logger, conf, shimmedCommand)
+	_, plugin, version, _, err := getExecutable(logger, conf, shimmedCommand, false)
 	if err != nil {
 		return err
 	}
@@ -524,18 +1376,19 @@ func envCommand(logger *log.Logger, shimmedCommand string, args []string) error
 	if err != nil {
 		return err
 	}
-	env := map[string]string{
-		"ASDF_INSTALL_TYPE":    parsedVersion.Type,
-		"ASDF_INSTALL_VERSION": parsedVersion.Value,
-		"ASDF_INSTALL_PATH":    installs.InstallPath(conf, plugin, parsedVersion),
-		"PATH":                 setPath(execPaths),
-	}
+	env := callbackenv.ForVersion(conf, plugin, parsedVersion).Map()
+	env["PATH"] = setPath(execPaths)
+	env[resolve.VariableResolvedVersionName(plugin.Name)] = version
 
-	if parsedVersion.Type != "system" {
+	if !parsedVersion.IsSystem() {
 		env, err = execenv.Generate(plugin, env)
 		if _, ok := err.(plugins.NoCallbackError); !ok && err != nil {
 			return err
 		}
+
+		included := execenv.Environment{}
+		included.Include(env["ASDF_INSTALL_PATH"], include)
+		maps.Copy(env, included.Variables(execute.CurrentEnv()))
 	}
 
 	fname, err := shims.ExecutableOnPath(env["PATH"], command)
@@ -543,7 +1396,7 @@ func envCommand(logger *log.Logger, shimmedCommand string, args []string) error
 		return err
 	}
 
-	finalEnv := execute.MergeWithCurrentEnv(env)
+	finalEnv := execute.MergeWithCurrentEnv(execenv.Record(env, execute.CurrentEnv()))
 	err = exec.Exec(fname, realArgs, finalEnv)
 	if err != nil {
 		fmt.Printf("err %#+v\n", err.Error())
@@ -551,846 +1404,2755 @@ func envCommand(logger *log.Logger, shimmedCommand string, args []string) error
 	return err
 }
 
-func setPath(paths []string) string {
-	return strings.Join(paths, ":") + ":" + os.Getenv("PATH")
-}
+func envFormatCommand(logger *log.Logger, format string, include, with []string) error {
+	if format != "dotenv" {
+		logger.Printf(messages.Get(messages.UnknownEnvFormat), format)
+		return fmt.Errorf("unknown format %s", format)
+	}
 
-func execCommand(logger *log.Logger, command string, args []string) error {
-	if command == "" {
-		logger.Printf("usage: asdf exec <command>")
-		return fmt.Errorf("usage: asdf exec <command>")
+	if err := validateIncludes(logger, include); err != nil {
+		return err
 	}
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	executable, plugin, version, err := getExecutable(logger, conf, command)
-	if err != nil {
+	if err := overlayVersions(logger, conf, with); err != nil {
 		return err
 	}
 
-	if len(args) > 1 {
-		args = args[1:]
-	} else {
-		args = []string{}
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
 	}
 
-	parsedVersion := toolversions.Parse(version)
-	execPaths, err := shims.ExecutablePaths(conf, plugin, parsedVersion)
+	environment, err := execenv.ForDirectory(conf, currentDir, include)
 	if err != nil {
+		logger.Printf(messages.Get(messages.EnvironmentError), err)
 		return err
 	}
-	env := map[string]string{
-		"ASDF_INSTALL_TYPE":    parsedVersion.Type,
-		"ASDF_INSTALL_VERSION": parsedVersion.Value,
-		"ASDF_INSTALL_PATH":    installs.InstallPath(conf, plugin, parsedVersion),
-		"PATH":                 setPath(execPaths),
-	}
 
-	if parsedVersion.Type != "system" {
-		env, err = execenv.Generate(plugin, env)
-		if _, ok := err.(plugins.NoCallbackError); !ok && err != nil {
+	return environment.WriteDotenv(os.Stdout, execute.CurrentEnv())
+}
+
+// overlayVersions sets the versions given with --with, formatted as
+// <tool>=<version>, in the environment variables that take precedence over
+// version files. As they are inherited, commands the executable runs through
+// shims use the same versions.
+func overlayVersions(logger *log.Logger, conf config.Config, with []string) error {
+	for _, overlay := range with {
+		tool, version, ok := strings.Cut(overlay, "=")
+		if !ok || tool == "" || strings.TrimSpace(version) == "" {
+			logger.Printf(messages.Get(messages.InvalidWith), overlay)
+			return fmt.Errorf("invalid --with %s", overlay)
+		}
+
+		if _, err := loadPlugin(logger, conf, tool); err != nil {
 			return err
 		}
-	}
 
-	err = hook.RunWithOutput(conf, fmt.Sprintf("pre_%s_%s", plugin.Name, filepath.Base(executable)), args, os.Stdout, os.Stderr)
-	if err != nil {
-		cli.OsExiter(1)
-		return err
+		if err := os.Setenv(resolve.VariableVersionName(tool), version); err != nil {
+			return err
+		}
 	}
 
-	finalEnv := execute.MergeWithCurrentEnv(env)
-	return exec.Exec(executable, args, finalEnv)
+	return nil
 }
 
-func extensionCommand(logger *log.Logger, args []string) error {
-	if len(args) < 1 {
-		err := errors.New("no plugin name specified")
-		logger.Printf("%s", err.Error())
-		return err
+func validateIncludes(logger *log.Logger, include []string) error {
+	for _, name := range include {
+		if !slices.Contains(execenv.Includes(), name) {
+			logger.Printf(messages.Get(messages.UnknownInclude), name, strings.Join(execenv.Includes(), ", "))
+			return fmt.Errorf("unknown include %s", name)
+		}
+	}
+	return nil
+}
+
+func exportCommand(logger *log.Logger, format string) error {
+	if format == "" {
+		logger.Printf(messages.Get(messages.UsageExport), strings.Join(export.Formats(), "|"))
+		return fmt.Errorf("usage: asdf export --format <format>")
 	}
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	pluginName := args[0]
-	plugin := plugins.New(conf, pluginName)
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
+	}
 
-	err = runExtensionCommand(plugin, args[1:])
-	logger.Printf("error running extension command: %s", err.Error())
+	tools, err := resolvedTools(conf, currentDir)
+	if err != nil {
+		logger.Printf(messages.Get(messages.ResolveToolVersionsError), err)
+		return err
+	}
+
+	err = export.Write(format, tools, os.Stdout, os.Stderr)
+	if err != nil {
+		logger.Printf("%s", err)
+	}
 	return err
 }
 
-func runExtensionCommand(plugin plugins.Plugin, args []string) (err error) {
-	path := ""
-	if len(args) > 0 {
-		path, err = plugin.ExtensionCommandPath(args[0])
+func lintCommand(logger *log.Logger, format, dir string) error {
+	if !slices.Contains(lint.Formats(), format) {
+		logger.Printf(messages.Get(messages.UnknownFormat), format, strings.Join(lint.Formats(), ", "))
+		return fmt.Errorf("unknown format %s", format)
+	}
 
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if dir == "" {
+		dir, err = os.Getwd()
 		if err != nil {
-			path, err = plugin.ExtensionCommandPath("")
-			if err != nil {
-				return err
-			}
-		} else {
-			args = args[1:]
-		}
-	} else {
-		path, err = plugin.ExtensionCommandPath("")
-		if err != nil {
+			logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
 			return err
 		}
 	}
 
-	return exec.Exec(path, args, os.Environ())
-}
-
-func getExecutable(logger *log.Logger, conf config.Config, command string) (executable string, plugin plugins.Plugin, version string, err error) {
-	currentDir, err := os.Getwd()
+	rules, err := conf.LintRules()
 	if err != nil {
-		logger.Printf("unable to get current directory: %s", err)
-		return "", plugins.Plugin{}, "", err
+		logger.Printf(messages.Get(messages.LintRulesError), err)
+		return err
 	}
 
-	executable, plugin, version, found, err := shims.FindExecutable(conf, command, currentDir)
+	findings, err := lint.Run(conf, dir, rules)
 	if err != nil {
+		logger.Printf(messages.Get(messages.LintError), dir, err)
+		return err
+	}
 
-		if _, ok := err.(shims.NoExecutableForPluginError); ok {
-			logger.Printf("No executable %s found for current version. Please select a different version or install %s manually for the current version", command, command)
-			cli.OsExiter(1)
-			return "", plugin, version, err
-		}
-		shimPath := shims.Path(conf, command)
-		toolVersions, _ := shims.GetToolsAndVersionsFromShimFile(shimPath)
+	if err := lint.Write(format, findings, os.Stdout); err != nil {
+		logger.Printf("%s", err)
+		return err
+	}
 
-		if len(toolVersions) > 0 {
-			if anyInstalled(conf, toolVersions) {
-				logger.Printf("No version is set for command %s", command)
-				logger.Printf("Consider adding one of the following versions in your config file at %s/.tool-versions\n", currentDir)
-			} else {
-				logger.Printf("No preset version installed for command %s", command)
-				for _, toolVersion := range toolVersions {
-					for _, version := range toolVersion.Versions {
-						logger.Printf("asdf install %s %s\n", toolVersion.Name, version)
-					}
-				}
+	if len(findings) > 0 {
+		cli.OsExiter(1)
+		return fmt.Errorf("%d findings", len(findings))
+	}
 
-				logger.Printf("or add one of the following versions in your config file at %s/.tool-versions\n", currentDir)
-			}
+	return nil
+}
 
-			for _, toolVersion := range toolVersions {
-				for _, version := range toolVersion.Versions {
-					logger.Printf("%s %s\n", toolVersion.Name, version)
-				}
-			}
-		}
+func importCommand(logger *log.Logger, format, file string) error {
+	if format == "" || file == "" {
+		logger.Printf(messages.Get(messages.UsageImport), strings.Join(export.ImportFormats(), "|"))
+		return fmt.Errorf("usage: asdf import --from <format> <file>")
+	}
 
-		os.Exit(126)
-		return executable, plugins.Plugin{}, "", err
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
 	}
 
-	if !found {
-		logger.Print("executable not found")
-		os.Exit(126)
-		return executable, plugins.Plugin{}, "", fmt.Errorf("executable not found")
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
 	}
 
-	return executable, plugin, version, nil
-}
+	in, err := os.Open(file)
+	if err != nil {
+		logger.Printf(messages.Get(messages.OpenError), file, err)
+		return err
+	}
+	defer in.Close()
 
-func anyInstalled(conf config.Config, toolVersions []toolversions.ToolVersions) bool {
-	for _, toolVersion := range toolVersions {
-		for _, version := range toolVersion.Versions {
-			version := toolversions.Parse(version)
-			plugin := plugins.New(conf, toolVersion.Name)
-			if installs.IsInstalled(conf, plugin, version) {
-				return true
-			}
-		}
+	tools, err := export.Read(format, in)
+	if err != nil {
+		logger.Printf("%s", err)
+		return err
 	}
-	return false
-}
 
-func pluginAddCommand(_ *cli.Command, conf config.Config, logger *log.Logger, pluginName, pluginRepo string) error {
-	if pluginName == "" {
-		// Invalid arguments
-		// Maybe one day switch this to show the generated help
-		// cli.ShowSubcommandHelp(cCtx)
-		return cli.Exit("usage: asdf plugin add <name> [<git-url>]", 1)
+	if len(tools) == 0 {
+		logger.Printf(messages.Get(messages.NoToolsFound), file)
+		return fmt.Errorf("no tools with versions found in %s", file)
+	}
+
+	var toolVersions []toolversions.ToolVersions
+	for _, tool := range tools {
+		toolVersions = append(toolVersions, toolversions.ToolVersions{Name: tool.Name, Versions: []string{tool.Version}})
 	}
 
-	err := plugins.Add(conf, pluginName, pluginRepo, "")
+	filepath := filepath.Join(currentDir, conf.DefaultToolVersionsFilename)
+	err = toolversions.WriteToolVersionsToFile(filepath, toolVersions)
 	if err != nil {
-		logger.Printf("%s", err)
+		logger.Printf(messages.Get(messages.VersionFileWriteError), err)
+	}
+	return err
+}
 
-		var existsErr plugins.PluginAlreadyExists
-		if errors.As(err, &existsErr) {
-			os.Exit(0)
-			return nil
+// resolvedTools returns the first version resolved in dir for every installed
+// plugin that has a version set, along with where it is installed and where it
+// was downloaded from when known.
+func resolvedTools(conf config.Config, dir string) (tools []export.Tool, err error) {
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return tools, err
+	}
+
+	for _, result := range resolve.All(conf, allPlugins, dir) {
+		plugin, toolVersions := result.Plugin, result.Versions
+		if result.Err != nil {
+			return tools, result.Err
 		}
 
-		cli.OsExiter(1)
-		return nil
+		if !result.Found || len(toolVersions.Versions) == 0 {
+			continue
+		}
+
+		tool := export.Tool{Name: plugin.Name, Version: toolVersions.Versions[0]}
+		version := toolversions.Parse(tool.Version)
+		if !version.IsSystem() && installs.IsInstalled(conf, plugin, version) {
+			tool.InstallPath = installs.InstallPath(conf, plugin, version)
+			if record, err := provenance.Read(installs.MetadataPath(conf, plugin, version), plugin.Name, tool.Version); err == nil {
+				tool.SourceURL = record.SourceURL
+				tool.Checksum = record.Checksum
+			}
+		}
+		tools = append(tools, tool)
 	}
 
-	os.Exit(0)
-	return nil
+	return tools, nil
 }
 
-func pluginRemoveCommand(_ *cli.Command, logger *log.Logger, pluginName string) error {
-	if pluginName == "" {
-		logger.Print("No plugin given")
-		cli.OsExiter(1)
-		return nil
+func setPath(paths []string) string {
+	return strings.Join(paths, ":") + ":" + os.Getenv("PATH")
+}
+
+// execCommand runs command with the version set for the current directory.
+// When trace isn't empty the executable, the resolution source and the
+// environment are written to the destination it names before running it, see
+// execenv.TraceVar.
+func execCommand(logger *log.Logger, command string, args []string, trace string, with []string) error {
+	if command == "" {
+		logger.Print(messages.Get(messages.UsageExec))
+		return fmt.Errorf("usage: asdf exec <command>")
 	}
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	err = plugins.Remove(conf, pluginName, os.Stdout, os.Stderr)
-	if err != nil {
-		// Needed to match output of old version
-		logger.Printf("%s", err)
+	if err := overlayVersions(logger, conf, with); err != nil {
+		return err
 	}
 
-	// This feels a little hacky but it works, to re-generate shims we delete them
-	// all and generate them again.
-	err2 := shims.RemoveAll(conf)
-	if err2 != nil {
-		logger.Printf("%s", err2)
-		cli.OsExiter(1)
-		return err2
+	executable, plugin, version, resolved, err := getExecutable(logger, conf, command, true)
+	if err != nil {
+		return err
 	}
 
-	shims.GenerateAll(conf, os.Stdout, os.Stderr)
-	return err
-}
+	warnConflicts(logger, conf, plugin)
+	noticeSubstitution(logger, conf, plugin, version, resolved)
 
-func pluginListCommand(cCtx *cli.Command, logger *log.Logger) error {
-	urls := cCtx.Bool("urls")
-	refs := cCtx.Bool("refs")
+	if len(args) > 1 {
+		args = args[1:]
+	} else {
+		args = []string{}
+	}
 
-	conf, err := config.LoadConfig()
+	if err := auditExec(conf, plugin, version, command, args); err != nil {
+		logger.Printf(messages.Get(messages.AuditLogError), err)
+		return err
+	}
+
+	env, err := execEnvironment(conf, plugin, version)
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
 		return err
 	}
 
-	plugins, err := plugins.List(conf, urls, refs)
+	err = hook.RunWithOutput(conf, fmt.Sprintf("pre_%s_%s", plugin.Name, filepath.Base(executable)), args, os.Stdout, os.Stderr)
 	if err != nil {
-		logger.Printf("error loading plugin list: %s", err)
+		cli.OsExiter(1)
 		return err
 	}
 
-	if len(plugins) == 0 {
-		logger.Println("No plugins installed")
-		return nil
+	if trace != "" {
+		env = traceExec(logger, trace, execenv.NewTrace(command, executable, args, env, execute.CurrentEnv()), plugin, version, resolved, env)
 	}
 
-	// TODO: Add some sort of presenter logic in another file so we
-	// don't clutter up this cmd code with conditional presentation
-	// logic
-	for _, plugin := range plugins {
-		if urls && refs {
-			fmt.Printf("%s\t\t%s\t%s\n", plugin.Name, plugin.URL, plugin.Ref)
-		} else if refs {
-			fmt.Printf("%s\t\t%s\n", plugin.Name, plugin.Ref)
-		} else if urls {
-			fmt.Printf("%s\t\t%s\n", plugin.Name, plugin.URL)
-		} else {
-			fmt.Printf("%s\n", plugin.Name)
-		}
+	// Niceness is set on the calling thread on Linux, the goroutine stays on
+	// it until the executable replaces the process
+	runtime.LockOSThread()
+	if err := applyLimits(conf, plugin); err != nil {
+		logger.Printf(messages.Get(messages.LimitsError), plugin.Name, err)
+		cli.OsExiter(1)
+		return err
 	}
 
-	return nil
+	finalEnv := execute.MergeWithCurrentEnv(execenv.Record(env, execute.CurrentEnv()))
+	return exec.Exec(executable, args, finalEnv)
 }
 
-func pluginListAllCommand(logger *log.Logger) error {
-	conf, err := config.LoadConfig()
+// applyLimits sets the umask, niceness and resource limits set for the tool in
+// the [exec] section of the asdfrc on the asdf process, so they're inherited
+// by the executable it execs
+func applyLimits(conf config.Config, plugin plugins.Plugin) error {
+	toolLimits, err := limits.ForTool(conf, plugin.Name)
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
 		return err
 	}
 
-	disableRepo, err := conf.DisablePluginShortNameRepository()
-	if err != nil {
-		logger.Printf("unable to check config")
-		return err
-	}
-	if disableRepo {
-		logger.Printf("Short-name plugin repository is disabled")
-		cli.OsExiter(1)
-		return nil
+	return toolLimits.Apply()
+}
+
+// warnConflicts warns about other version managers ahead of asdf on PATH for
+// the tool, unless disabled with the conflicting_managers setting
+func warnConflicts(logger *log.Logger, conf config.Config, plugin plugins.Plugin) {
+	if setting, _ := conf.ConflictingManagers(); setting != "warn" {
+		return
 	}
 
-	lastCheckDuration := 0
-	// We don't care about errors here as we can use the default value
-	checkDuration, _ := conf.PluginRepositoryLastCheckDuration()
+	for _, conflict := range conflicts.Detect(os.Getenv("PATH"), shims.Directory(conf), []string{plugin.ToolName()}, os.Getenv) {
+		logger.Printf(messages.Get(messages.TakeoverWarning), conflict, plugin.Name)
+	}
+}
 
-	if !checkDuration.Never {
-		lastCheckDuration = checkDuration.Every
+// auditExec records the execution in the audit log, if enabled with the
+// audit_log setting. An execution that can't be recorded doesn't run.
+func auditExec(conf config.Config, plugin plugins.Plugin, version, command string, args []string) error {
+	enabled, err := conf.AuditLog()
+	if err != nil || !enabled {
+		return err
 	}
 
-	index := pluginindex.Build(conf.DataDir, conf.PluginIndexURL, false, lastCheckDuration)
-	availablePlugins, err := index.Get()
+	currentDir, err := os.Getwd()
 	if err != nil {
-		logger.Printf("error loading plugin index: %s", err)
 		return err
 	}
 
-	installedPlugins, err := plugins.List(conf, true, false)
+	return audit.Append(conf.DataDir, audit.NewEntry(plugin.Name, version, currentDir, command, args))
+}
+
+// selectLocale selects the locale messages are printed in from the asdfrc, or
+// the environment when it isn't set there or the asdfrc can't be loaded, and
+// warns about the keys of the asdfrc whose values can't be expanded. The config
+// is returned with the asdfrc read, so it isn't read again by every command,
+// along with whether it could be loaded.
+func selectLocale(logger *log.Logger) (config.Config, bool) {
+	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading plugin list: %s", err)
-		return err
+		messages.SetLocale("")
+		return conf, false
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 15, 0, 1, ' ', 0)
-	for _, availablePlugin := range availablePlugins {
-		if pluginInstalled(availablePlugin, installedPlugins) {
-			fmt.Fprintf(w, "%s\t\t*%s\n", availablePlugin.Name, availablePlugin.URL)
-		} else {
-			fmt.Fprintf(w, "%s\t\t%s\n", availablePlugin.Name, availablePlugin.URL)
-		}
+	locale, _ := conf.Locale()
+	for _, err := range conf.SettingsErrors() {
+		logger.Printf(messages.Get(messages.Warning), err)
 	}
-	w.Flush()
 
-	return nil
+	messages.SetLocale(locale)
+	return conf, true
 }
 
-func pluginInstalled(plugin pluginindex.Plugin, installedPlugins []plugins.Plugin) bool {
-	for _, installedPlugin := range installedPlugins {
-		if installedPlugin.Name == plugin.Name && installedPlugin.URL == plugin.URL {
-			return true
-		}
+// sanitizesEnv returns true if unusable ASDF_<TOOL>_VERSION variables are
+// removed before the command runs, see sanitizeEnv. Versions set in the
+// environment are how a version not set in any file is installed, so they are
+// left alone for install, and for exec when auto_install may install them.
+func sanitizesEnv(conf config.Config, command string) bool {
+	if sanitize, _ := conf.SanitizeEnv(); !sanitize || command == "install" {
+		return false
 	}
 
-	return false
+	autoInstall, _ := conf.AutoInstall()
+	return command != "exec" || autoInstall == "no"
 }
 
-func infoCommand(conf config.Config, version string) error {
-	return info.Print(conf, version)
-}
+// sanitizeEnv removes unusable ASDF_<TOOL>_VERSION variables from the
+// environment before any versions are resolved, reporting each one, if enabled
+// with the sanitize_env setting
+func sanitizeEnv(logger *log.Logger, conf config.Config) {
 
-func helpCommand(logger *log.Logger, asdfVersion, tool, version string) error {
-	conf, err := config.LoadConfig()
+	ignored, err := resolve.SanitizeEnv(conf)
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
-		return err
+		logger.Printf(messages.Get(messages.SanitizeEnvError), err)
 	}
 
-	if tool != "" {
-		if version != "" {
-			err := help.PrintToolVersion(conf, tool, version)
-			if err != nil {
-				cli.OsExiter(1)
-			}
-			return err
+	for _, variable := range ignored {
+		logger.Printf(messages.Get(messages.SanitizeEnvIgnored), variable)
+	}
+}
+
+// noticeSubstitution prints a notice when the version run isn't one of the
+// versions resolved, because an installed version was substituted for them,
+// if enabled with the substitution_notice setting
+func noticeSubstitution(logger *log.Logger, conf config.Config, plugin plugins.Plugin, version string, resolved resolve.ToolVersions) {
+	if notice, _ := conf.SubstitutionNotice(); !notice || toolversions.Parse(version).Type != "version" {
+		return
+	}
+
+	if len(resolved.Versions) == 0 || slices.Contains(resolved.Versions, version) {
+		return
+	}
+
+	logger.Printf(messages.Get(messages.SubstitutionNotice), plugin.Name, version, strings.Join(resolved.Versions, " "), formatSource(resolved, true))
+}
+
+// traceExec writes the trace to its destination and returns env with the
+// destination set so commands run through shims by the executable are traced
+// too. Failing to write the trace is reported but doesn't stop the command.
+func traceExec(logger *log.Logger, destination string, trace execenv.Trace, plugin plugins.Plugin, version string, resolved resolve.ToolVersions, env map[string]string) map[string]string {
+	if destination != "stderr" {
+		if abs, err := filepath.Abs(destination); err == nil {
+			destination = abs
 		}
+	}
+	env[execenv.TraceVar] = destination
 
-		err := help.PrintTool(conf, tool)
-		if err != nil {
-			cli.OsExiter(1)
+	trace.Tool = plugin.Name
+	trace.Version = version
+	trace.Source = "shim template"
+	if version != "" {
+		trace.Source = "system_fallback setting"
+		if len(resolved.Versions) > 0 {
+			trace.Source = formatSource(resolved, true)
 		}
-		return err
 	}
 
-	allPlugins, err := plugins.List(conf, false, false)
+	out, closeTrace, err := execenv.OpenTrace(destination, os.Stderr)
 	if err != nil {
-		cli.OsExiter(1)
+		logger.Printf(messages.Get(messages.Warning), err)
+		return env
 	}
+	defer closeTrace()
 
-	err = help.Print(asdfVersion, allPlugins)
-	if err != nil {
-		cli.OsExiter(1)
+	if err := trace.Write(out); err != nil {
+		logger.Printf(messages.Get(messages.TraceWriteWarning), err)
 	}
 
-	return err
+	return env
 }
 
-func pluginUpdateCommand(cCtx *cli.Command, logger *log.Logger, pluginName, ref string) error {
-	updateAll := cCtx.Bool("all")
-	if !updateAll && pluginName == "" {
-		return cli.Exit("usage: asdf plugin update {<name> [git-ref] | --all}", 1)
+// execPlan is printed by `asdf exec --env-only` so launchers can run the
+// command themselves
+type execPlan struct {
+	Executable string            `json:"executable"`
+	Args       []string          `json:"args"`
+	Tool       string            `json:"tool"`
+	Version    string            `json:"version"`
+	Env        map[string]string `json:"env"`
+}
+
+func execEnvOnlyCommand(logger *log.Logger, command string, args []string) error {
+	if command == "" {
+		logger.Print(messages.Get(messages.UsageExecEnvOnly))
+		return fmt.Errorf("usage: asdf exec --env-only <command>")
 	}
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	if updateAll {
-		installedPlugins, err := plugins.List(conf, false, false)
-		if err != nil {
-			logger.Printf("failed to get plugin list: %s", err)
-			return err
-		}
+	executable, plugin, version, _, err := getExecutable(logger, conf, command, false)
+	if err != nil {
+		return err
+	}
 
-		for _, plugin := range installedPlugins {
-			updatedToRef, err := plugin.Update(conf, "", os.Stdout, os.Stderr)
-			formatUpdateResult(logger, plugin.Name, updatedToRef, err)
-		}
+	env, err := execEnvironment(conf, plugin, version)
+	if err != nil {
+		return err
+	}
 
-		return nil
+	// Only variables that differ from the current environment are printed
+	for name, value := range env {
+		if current, ok := os.LookupEnv(name); ok && current == value {
+			delete(env, name)
+		}
 	}
 
-	plugin := plugins.New(conf, pluginName)
-	updatedToRef, err := plugin.Update(conf, ref, os.Stdout, os.Stderr)
-	formatUpdateResult(logger, pluginName, updatedToRef, err)
-	return err
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(execPlan{
+		Executable: executable,
+		Args:       append([]string{}, args[1:]...),
+		Tool:       plugin.Name,
+		Version:    version,
+		Env:        env,
+	})
 }
 
-func pluginTestCommand(l *log.Logger, args []string, toolVersion, ref string) {
-	conf, err := config.LoadConfig()
+// execEnvironment returns the variables a command of the version is run with,
+// including those set by the plugin's exec-env callback
+func execEnvironment(conf config.Config, plugin plugins.Plugin, version string) (map[string]string, error) {
+	parsedVersion := toolversions.Parse(version)
+	execPaths, err := shims.ExecutablePaths(conf, plugin, parsedVersion)
 	if err != nil {
-		l.Printf("error loading config: %s", err)
-		cli.OsExiter(1)
-		return
+		return nil, err
 	}
+	env := callbackenv.ForVersion(conf, plugin, parsedVersion).Map()
+	env["PATH"] = setPath(execPaths)
+	env[resolve.VariableResolvedVersionName(plugin.Name)] = version
 
-	if len(args) < 2 {
-		failTest(l, "please provide a plugin name and url")
+	if !parsedVersion.IsSystem() {
+		env, err = execenv.Generate(plugin, env)
+		if _, ok := err.(plugins.NoCallbackError); !ok && err != nil {
+			return nil, err
+		}
 	}
 
-	name := args[0]
-	url := args[1]
-	testName := fmt.Sprintf("asdf-test-%s", name)
+	return env, nil
+}
 
-	// Install plugin
-	err = plugins.Add(conf, testName, url, ref)
+// applyCommand converges the machine to the profile, printing the changes made
+func applyCommand(logger *log.Logger, file string, dryRun bool) error {
+	if file == "" {
+		return cli.Exit("usage: asdf apply <profile> [--dry-run]", 1)
+	}
+
+	conf, err := config.LoadConfig()
 	if err != nil {
-		failTest(l, fmt.Sprintf("%s was not properly installed reason: %s", name, err))
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
 	}
 
-	// Remove plugin
-	var blackhole strings.Builder
-	defer plugins.Remove(conf, testName, &blackhole, &blackhole)
+	desired, err := profile.Load(file)
+	if err != nil {
+		logger.Printf(messages.Get(messages.ProfileLoadError), err)
+		return err
+	}
 
-	// Assert callbacks are present
-	plugin := plugins.New(conf, testName)
-	files, err := os.ReadDir(filepath.Join(plugin.Dir, "bin"))
-	if _, ok := err.(*fs.PathError); ok {
-		failTest(l, "bin/ directory does not exist")
+	if dryRun {
+		changes, err := profile.Plan(conf, desired)
+		if err != nil {
+			logger.Printf(messages.Get(messages.ProfilePlanError), err)
+			return err
+		}
+
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if len(changes) == 0 {
+			fmt.Println(messages.Get(messages.NothingToChange))
+		}
+		return nil
 	}
 
-	callbacks := []string{}
-	for _, file := range files {
-		callbacks = append(callbacks, file.Name())
+	pluginsLock, err := acquireLock(logger, conf, lock.Plugins)
+	if err != nil {
+		return err
 	}
+	defer pluginsLock.Release()
 
-	for _, expectedCallback := range []string{"download", "install", "list-all"} {
-		if !slices.Contains(callbacks, expectedCallback) {
-			failTest(l, fmt.Sprintf("missing callback %s", expectedCallback))
-		}
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
 	}
+	defer installsLock.Release()
 
-	allCallbacks := []string{"download", "install", "list-all", "latest-stable", "help.overview", "help.deps", "help.config", "help.links", "list-bin-paths", "exec-env", "exec-path", "uninstall", "list-legacy-filenames", "parse-legacy-file", "post-plugin-add", "post-plugin-update", "pre-plugin-remove"}
+	applied, err := profile.Apply(conf, desired, os.Stdout, os.Stderr)
+	if err != nil {
+		logger.Printf(messages.Get(messages.ProfileApplyError), err)
+		return err
+	}
 
-	// Assert all callbacks present are executable
-	for _, file := range files {
-		// file is a callback...
-		if slices.Contains(allCallbacks, file.Name()) {
-			// check if it is executable
-			info, _ := file.Info()
-			if !(info.Mode()&0o111 != 0) {
-				failTest(l, fmt.Sprintf("callback lacks executable permission: %s", file.Name()))
-			}
-		}
+	if len(applied) == 0 {
+		fmt.Println(messages.Get(messages.NothingToChange))
 	}
+	return nil
+}
 
-	// Assert has license
-	licensePath := filepath.Join(plugin.Dir, "LICENSE")
-	if _, err := os.Stat(licensePath); errors.Is(err, os.ErrNotExist) {
-		failTest(l, "LICENSE file must be present in the plugin repository")
+// bakeCommand bakes the tool versions set in a tool versions file into a new
+// data directory, and for the oci format packages it as an image layout
+func bakeCommand(logger *log.Logger, output, format, dataDir, file string) error {
+	if output == "" || (format == "oci" && dataDir == "") {
+		return cli.Exit("usage: asdf bake --output <dir> [--format oci --data-dir <dir>] [--file <file>]", 1)
 	}
 
-	bytes, err := os.ReadFile(licensePath)
+	if format != "dir" && format != "oci" {
+		logger.Printf(messages.Get(messages.UnknownBakeFormat), format)
+		return errors.New("bad bake format")
+	}
+
+	conf, err := config.LoadConfig()
 	if err != nil {
-		failTest(l, "LICENSE file must be present in the plugin repository")
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
 	}
 
-	// Validate license file not empty
-	if len(bytes) == 0 {
-		failTest(l, "LICENSE file in the plugin repository must not be empty")
+	if file == "" {
+		file = conf.DefaultToolVersionsFilename
 	}
 
-	// Validate it returns at least one available version
-	var output strings.Builder
-	err = plugin.RunCallback("list-all", []string{}, map[string]string{}, &output, &blackhole)
+	tools, err := toolversions.GetAllToolsAndVersions(file)
 	if err != nil {
-		failTest(l, "Unable to list available versions")
+		logger.Printf(messages.Get(messages.ReadError), file, err)
+		return err
 	}
 
-	allVersions := strings.Fields(output.String())
-	if len(allVersions) < 1 {
-		failTest(l, "list-all did not return any version")
+	bakeDir := output
+	if format == "oci" {
+		bakeDir = dataDir
 	}
 
-	// grab first version returned by list-all callback if no version provided as
-	// a CLI argument
-	if toolVersion == "" {
-		toolVersion = allVersions[0]
+	bakeDir, err = filepath.Abs(bakeDir)
+	if err != nil {
+		return err
 	}
 
-	err = versions.InstallOneVersion(conf, plugin, toolVersion, false, os.Stdout, os.Stderr)
+	manifest, err := bake.Bake(conf, tools, bakeDir, os.Stdout, os.Stderr)
 	if err != nil {
-		failTest(l, "install exited with an error")
+		logger.Printf(messages.Get(messages.BakeError), file, err)
+		return err
 	}
-}
 
-func failTest(logger *log.Logger, msg string) {
-	logger.Printf("FAILED: %s", msg)
-	cli.OsExiter(1)
+	if format == "oci" {
+		if err := oci.WriteLayout(output, bakeDir, bakeDir); err != nil {
+			logger.Printf(messages.Get(messages.ImageLayoutError), err)
+			return err
+		}
+	}
+
+	logger.Printf(messages.Get(messages.Baked), len(manifest.Tools), len(manifest.Shims), manifest.Platform, output)
+	return nil
 }
 
-func formatUpdateResult(logger *log.Logger, pluginName, updatedToRef string, err error) {
+// bakeVerifyCommand compares the current data directory against a bake
+// manifest, exiting with an error if they differ
+func bakeVerifyCommand(logger *log.Logger, manifestPath string) error {
+	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("failed to update %s due to error: %s\n", pluginName, err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
 
-		return
+	if manifestPath == "" {
+		manifestPath = filepath.Join(conf.DataDir, bake.ManifestFilename)
+	}
+
+	manifest, err := bake.Read(manifestPath)
+	if err != nil {
+		logger.Printf("%s", err)
+		return err
+	}
+
+	problems := bake.Verify(conf, manifest)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		cli.OsExiter(1)
+		return errors.New("data directory differs from bake manifest")
 	}
 
-	logger.Printf("updated %s to ref %s\n", pluginName, updatedToRef)
+	return nil
 }
 
-func installCommand(logger *log.Logger, toolName, version string, keepDownload bool) error {
+func cacheCleanCommand(logger *log.Logger, tmp bool) error {
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	dir, err := os.Getwd()
+	dir := data.CacheDirectory(conf.DataDir, "")
+	if tmp {
+		dir = data.TmpDirectory(conf.DataDir)
+	}
+
+	err = os.RemoveAll(dir)
 	if err != nil {
-		return fmt.Errorf("unable to fetch current directory: %w", err)
+		logger.Printf(messages.Get(messages.RemoveError), dir, err)
+		return err
 	}
 
-	if toolName == "" {
-		// Install all versions
-		errs := versions.InstallAll(conf, dir, os.Stdout, os.Stderr)
-		if len(errs) > 0 {
-			for _, err := range errs {
-				// Don't print error if no version set, this just means the current
-				// dir doesn't use a particular plugin that is installed.
-				if _, ok := err.(versions.NoVersionSetError); ok {
-					continue
-				}
+	return nil
+}
 
-				if _, ok := err.(versions.UninstallableVersionError); ok {
-					msg := fmt.Sprintf("skipping %s\n", err.Error())
-					os.Stderr.Write([]byte(msg))
-					continue
-				}
+func setupCommand(logger *log.Logger, in io.Reader, out io.Writer, yes bool) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
 
-				os.Stderr.Write([]byte(err.Error()))
-				os.Stderr.Write([]byte("\n"))
-			}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		logger.Printf(messages.Get(messages.HomeDirectoryError), err)
+		return err
+	}
 
-			filtered := filterInstallErrors(errs)
-			if len(filtered) > 0 {
-				return filtered[0]
-			}
-			return nil
+	reader := bufio.NewReader(in)
+	confirm := func(question string) bool {
+		fmt.Fprintf(out, "%s [Y/n] ", question)
+		if yes {
+			fmt.Fprintln(out, "y")
+			return true
 		}
-	} else {
-		// Install specific version
-		plugin := plugins.New(conf, toolName)
 
-		if version == "" {
-			err = versions.Install(conf, plugin, dir, os.Stdout, os.Stderr)
-			if err != nil {
-				var vaiErr versions.VersionAlreadyInstalledError
-				if errors.As(err, &vaiErr) {
-					logger.Println(err)
-					return nil
-				}
+		answer, _ := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "" || answer == "y" || answer == "yes"
+	}
 
-				if _, ok := err.(versions.NoVersionSetError); ok {
-					logger.Printf("No versions specified for %s in config files or environment", toolName)
-					cli.OsExiter(1)
-				}
+	activatedNow := false
+	shell, err := setup.DetectShell(os.Getenv("SHELL"), home, conf.DataDir)
+	if err != nil {
+		fmt.Fprintf(out, "Shell: %s\n", err)
+	} else {
+		fmt.Fprintf(out, "Shell: %s (%s)\n", shell.Name, shell.RCFile)
+		activated, err := shell.Activated()
+		if err != nil {
+			logger.Printf(messages.Get(messages.ReadError), shell.RCFile, err)
+			return err
+		}
 
-				logger.Printf("error installing version: %v", err)
+		if activated {
+			fmt.Fprintf(out, "asdf is already activated in %s\n", shell.RCFile)
+		} else if confirm(fmt.Sprintf("Add the asdf shims directory to PATH in %s?", shell.RCFile)) {
+			if err := shell.Activate(); err != nil {
+				logger.Printf(messages.Get(messages.WriteError), shell.RCFile, err)
 				return err
 			}
-		} else {
-			parsedVersion := toolversions.ParseFromCliArg(version)
+			fmt.Fprintf(out, "Added activation lines to %s\n", shell.RCFile)
+			activatedNow = true
+		}
+	}
 
-			if parsedVersion.Type == "latest" {
-				err = versions.InstallVersion(conf, plugin, parsedVersion, os.Stdout, os.Stderr)
-			} else {
-				// Adding this here to get tests passing. The other versions.Install*
-				// calls here could have a keepDownload argument added as well. PR
-				// welcome!
-				err = versions.InstallOneVersion(conf, plugin, version, keepDownload, os.Stdout, os.Stderr)
-			}
+	disableRepo, _ := conf.DisablePluginShortNameRepository()
+	if !disableRepo {
+		fmt.Fprintln(out, "Updating plugin index...")
+		index := pluginindex.Build(conf.DataDir, conf.PluginIndexURL, false, 0)
+		if _, err := index.Refresh(); err != nil {
+			fmt.Fprintf(out, "unable to update plugin index: %s\n", err)
+		}
+	}
 
-			if err != nil {
-				var vaiErr versions.VersionAlreadyInstalledError
-				if errors.As(err, &vaiErr) {
-					logger.Println(err)
-					return nil
-				}
+	existing, err := setup.ExistingVersions(home)
+	if err != nil {
+		logger.Printf(messages.Get(messages.OtherManagersError), err)
+		return err
+	}
 
-				logger.Printf("error installing version: %v", err)
-			}
+	toolVersionsFile := filepath.Join(home, conf.DefaultToolVersionsFilename)
+	for _, tool := range existing {
+		question := fmt.Sprintf("Import %s %s from %s into %s?", tool.Name, strings.Join(tool.Versions, " "), tool.Manager, toolVersionsFile)
+		if !confirm(question) {
+			continue
+		}
+
+		if err := toolversions.WriteToolVersionsToFile(toolVersionsFile, []toolversions.ToolVersions{tool.ToolVersions}); err != nil {
+			logger.Printf(messages.Get(messages.WriteError), toolVersionsFile, err)
+			return err
 		}
+		fmt.Fprintf(out, "Run `asdf plugin add %s && asdf install %s` to install it with asdf\n", tool.Name, tool.Name)
+	}
+
+	fmt.Fprintln(out, "Checking installation...")
+	problems := setup.Check(conf.DataDir, os.Getenv("PATH"))
+	if len(problems) == 0 {
+		fmt.Fprintln(out, "Everything looks good")
+		return nil
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(out, "  %s\n", problem)
+	}
+
+	if activatedNow {
+		fmt.Fprintln(out, "Problems with PATH are resolved once your shell is restarted")
+	}
+
+	return nil
+}
+
+func extensionCommand(logger *log.Logger, args []string) error {
+	if len(args) < 1 {
+		err := errors.New("no plugin name specified")
+		logger.Printf("%s", err.Error())
+		return err
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
 	}
 
+	pluginName := args[0]
+	plugin := plugins.New(conf, pluginName)
+
+	err = runExtensionCommand(plugin, args[1:])
+	logger.Printf(messages.Get(messages.ExtensionCommandError), err.Error())
 	return err
 }
 
-func filterInstallErrors(errs []error) []error {
-	var filtered []error
-	for _, err := range errs {
-		var vaiErr versions.VersionAlreadyInstalledError
-		if errors.As(err, &vaiErr) {
-			continue
+func runExtensionCommand(plugin plugins.Plugin, args []string) (err error) {
+	path := ""
+	if len(args) > 0 {
+		path, err = plugin.ExtensionCommandPath(args[0])
+
+		if err != nil {
+			path, err = plugin.ExtensionCommandPath("")
+			if err != nil {
+				return err
+			}
+		} else {
+			args = args[1:]
+		}
+	} else {
+		path, err = plugin.ExtensionCommandPath("")
+		if err != nil {
+			return err
+		}
+	}
+
+	return exec.Exec(path, args, os.Environ())
+}
+
+// getExecutable returns the executable the shim of the command runs, along
+// with the versions resolved for its tool, see shims.FindResolvedExecutable
+func getExecutable(logger *log.Logger, conf config.Config, command string, install bool) (executable string, plugin plugins.Plugin, version string, resolved resolve.ToolVersions, err error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return "", plugins.Plugin{}, "", resolved, err
+	}
+
+	executable, plugin, version, resolved, found, err := shims.FindResolvedExecutable(conf, command, currentDir)
+	if err != nil && install && !resolveFailed(err) {
+		var stdIn io.Reader
+		if isTerminal(os.Stdin) {
+			stdIn = os.Stdin
+		}
+		if autoInstall(logger, conf, command, currentDir, stdIn, os.Stderr) {
+			executable, plugin, version, resolved, found, err = shims.FindResolvedExecutable(conf, command, currentDir)
 		}
+	}
+	if err != nil && resolveFailed(err) {
+		// The reason a resolve hook refused the version is kept
+		logger.Printf(messages.Get(messages.ResolveCommandError), command, err)
+		cli.OsExiter(1)
+		return "", plugin, version, resolved, err
+	}
+	if err != nil {
+
+		if _, ok := err.(shims.NoExecutableForPluginError); ok {
+			logger.Printf(messages.Get(messages.NoExecutableForVersion), command, command)
+			cli.OsExiter(1)
+			return "", plugin, version, resolved, err
+		}
+		shimPath := shims.Path(conf, command)
+		toolVersions, _ := shims.GetToolsAndVersionsFromShimFile(shimPath)
+
+		if len(toolVersions) > 0 {
+			if anyInstalled(conf, toolVersions) {
+				logger.Printf(messages.Get(messages.NoVersionSetForCommand), command)
+				logger.Printf(messages.Get(messages.ConsiderAddingVersion), currentDir)
+			} else {
+				logger.Printf(messages.Get(messages.NoPresetVersionInstalled), command)
+				for _, toolVersion := range toolVersions {
+					for _, version := range toolVersion.Versions {
+						logger.Printf(messages.Get(messages.InstallSuggestion), toolVersion.Name, version)
+					}
+				}
+
+				logger.Printf(messages.Get(messages.OrAddVersion), currentDir)
+			}
+
+			for _, toolVersion := range toolVersions {
+				for _, version := range toolVersion.Versions {
+					logger.Printf("%s %s\n", toolVersion.Name, version)
+				}
+			}
+		}
+
+		os.Exit(126)
+		return executable, plugins.Plugin{}, "", resolved, err
+	}
+
+	if !found {
+		logger.Print(messages.Get(messages.ExecutableNotFound))
+		os.Exit(126)
+		return executable, plugins.Plugin{}, "", resolved, fmt.Errorf("executable not found")
+	}
+
+	return executable, plugin, version, resolved, nil
+}
+
+// resolveFailed returns true if the error finding an executable comes from
+// resolving the versions set, like a resolve hook refusing a version, rather
+// than from no executable being found for them
+func resolveFailed(err error) bool {
+	switch err.(type) {
+	case shims.UnknownCommandError, shims.NoVersionSetError, shims.NoExecutableForPluginError:
+		return false
+	}
+	return true
+}
+
+// autoInstall installs the first version set for each tool providing the
+// command when it isn't installed, as the auto_install setting allows, asking
+// on stdErr and reading the answer from stdIn in prompt mode, which is nil
+// when no terminal is attached. Prompts and the output of the installs go to
+// stdErr, so they don't end up in the output of the command. Nothing is
+// installed by shims run from plugin callbacks, or while another command holds
+// the installs lock, rather than waiting for it. It returns true if a version
+// was installed.
+func autoInstall(logger *log.Logger, conf config.Config, command, currentDir string, stdIn io.Reader, stdErr io.Writer) bool {
+	mode, err := conf.AutoInstall()
+	if err != nil || mode == "no" {
+		return false
+	}
+
+	if mode == "prompt" && stdIn == nil {
+		return false
+	}
+
+	// The command holding the lock is the one running the callback
+	if os.Getenv(lock.TokenEnv(lock.Installs)) != "" {
+		return false
+	}
+
+	toolVersions, err := shims.GetToolsAndVersionsFromShimFile(shims.Path(conf, command))
+	if err != nil {
+		return false
+	}
+
+	installed := false
+	var reader *bufio.Reader
+	if stdIn != nil {
+		reader = bufio.NewReader(stdIn)
+	}
+	for _, toolVersion := range toolVersions {
+		plugin := plugins.New(conf, toolVersion.Name)
+		if plugin.Exists() != nil {
+			continue
+		}
+
+		resolved, found, err := resolve.Version(conf, plugin, currentDir)
+		if err != nil || !found {
+			continue
+		}
+
+		version := resolved.Versions[0]
+		parsed := toolversions.Parse(version)
+		if parsed.Type == "path" || parsed.IsSystem() || versionspec.IsExclusion(version) || installs.IsInstalled(conf, plugin, parsed) {
+			continue
+		}
+
+		if mode == "prompt" {
+			fmt.Fprintf(stdErr, "%s %s is not installed. Install it now? [Y/n] ", plugin.Name, version)
+			answer, _ := reader.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer != "" && answer != "y" && answer != "yes" {
+				continue
+			}
+		}
+
+		installsLock, err := lock.TryAcquire(conf.DataDir, lock.Installs)
+		if err != nil {
+			logger.Printf(messages.Get(messages.AutoInstallSkipped), plugin.Name, version, err)
+			return installed
+		}
+		err = versions.InstallOneVersion(conf, plugin, version, false, stdErr, stdErr)
+		installsLock.Release()
+		if err != nil {
+			logger.Printf(messages.Get(messages.AutoInstallError), plugin.Name, version, err)
+			continue
+		}
+
+		installed = true
+	}
+
+	return installed
+}
+
+// isTerminal returns true if the file is a character device other than the
+// null device, which a shim run from a script may have as STDIN
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+func anyInstalled(conf config.Config, toolVersions []toolversions.ToolVersions) bool {
+	for _, toolVersion := range toolVersions {
+		for _, version := range toolVersion.Versions {
+			version := toolversions.Parse(version)
+			plugin := plugins.New(conf, toolVersion.Name)
+			if installs.IsInstalled(conf, plugin, version) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pluginAddCommand(_ *cli.Command, conf config.Config, logger *log.Logger, pluginName, pluginRepo string) error {
+	if pluginName == "" {
+		// Invalid arguments
+		// Maybe one day switch this to show the generated help
+		// cli.ShowSubcommandHelp(cCtx)
+		return cli.Exit("usage: asdf plugin add <name> [<git-url>]", 1)
+	}
+
+	pluginsLock, err := acquireLock(logger, conf, lock.Plugins)
+	if err != nil {
+		return err
+	}
+	defer pluginsLock.Release()
+
+	err = plugins.Add(conf, pluginName, pluginRepo, "")
+	if err != nil {
+		logger.Printf("%s", err)
+
+		var existsErr plugins.PluginAlreadyExists
+		if errors.As(err, &existsErr) {
+			os.Exit(0)
+			return nil
+		}
+
+		cli.OsExiter(1)
+		return nil
+	}
+
+	os.Exit(0)
+	return nil
+}
+
+// pluginGCCommand cleans up the Git repositories of every plugin, or of the
+// named plugin, printing the space freed for each one
+func pluginGCCommand(logger *log.Logger, pluginName string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	pluginsLock, err := acquireLock(logger, conf, lock.Plugins)
+	if err != nil {
+		return err
+	}
+	defer pluginsLock.Release()
+
+	var selected []plugins.Plugin
+	if pluginName != "" {
+		plugin, err := loadPlugin(logger, conf, pluginName)
+		if err != nil {
+			cli.OsExiter(1)
+			return err
+		}
+		selected = append(selected, plugin)
+	} else {
+		selected, err = plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.PluginListError), err)
+			return err
+		}
+	}
+
+	failed := false
+	for _, action := range maintain.GC(selected) {
+		if action.Result == maintain.ResultFailed {
+			logger.Printf(messages.Get(messages.RepositoryCleanupError), action.Target, action.Detail)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %s\n", action.Target, action.Detail)
+	}
+
+	if failed {
+		cli.OsExiter(1)
+		return errors.New("unable to clean up plugin repositories")
+	}
+	return nil
+}
+
+func pluginRemoveCommand(_ *cli.Command, logger *log.Logger, pluginName string) error {
+	if pluginName == "" {
+		logger.Print(messages.Get(messages.NoPluginGiven))
+		cli.OsExiter(1)
+		return nil
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	pluginsLock, err := acquireLock(logger, conf, lock.Plugins)
+	if err != nil {
+		return err
+	}
+	defer pluginsLock.Release()
+
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
+	}
+	defer installsLock.Release()
+
+	err = plugins.Remove(conf, pluginName, os.Stdout, os.Stderr)
+	if err != nil {
+		// Needed to match output of old version
+		logger.Printf("%s", err)
+	}
+
+	// Regenerating drops the shims of the removed plugin
+	err2 := shims.Regenerate(conf, os.Stdout, os.Stderr)
+	if err2 != nil {
+		logger.Printf("%s", err2)
+		cli.OsExiter(1)
+		return err2
+	}
+
+	return err
+}
+
+func pluginListCommand(cCtx *cli.Command, logger *log.Logger) error {
+	urls := cCtx.Bool("urls")
+	refs := cCtx.Bool("refs")
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	plugins, err := plugins.List(conf, urls, refs)
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginListError), err)
+		return err
+	}
+
+	if len(plugins) == 0 {
+		logger.Println(messages.Get(messages.NoPluginsInstalled))
+		return nil
+	}
+
+	// TODO: Add some sort of presenter logic in another file so we
+	// don't clutter up this cmd code with conditional presentation
+	// logic
+	for _, plugin := range plugins {
+		if urls && refs {
+			fmt.Printf("%s\t\t%s\t%s\n", plugin.Name, plugin.URL, plugin.Ref)
+		} else if refs {
+			fmt.Printf("%s\t\t%s\n", plugin.Name, plugin.Ref)
+		} else if urls {
+			fmt.Printf("%s\t\t%s\n", plugin.Name, plugin.URL)
+		} else {
+			fmt.Printf("%s\n", plugin.Name)
+		}
+	}
+
+	return nil
+}
+
+// pluginCapabilitiesCommand prints which optional callbacks each installed
+// plugin implements, or for a single plugin what asdf falls back to for each
+// callback it doesn't implement
+func pluginCapabilitiesCommand(logger *log.Logger, pluginName string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	capabilities := plugins.Capabilities()
+
+	if pluginName != "" {
+		plugin, err := loadPlugin(logger, conf, pluginName)
+		if err != nil {
+			cli.OsExiter(1)
+			return err
+		}
+
+		for _, capability := range capabilities {
+			if plugin.Supports(capability.Callback) {
+				fmt.Printf(messages.Get(messages.CallbackSupported)+"\n", capability.Callback)
+			} else {
+				fmt.Printf(messages.Get(messages.CallbackMissing)+"\n", capability.Callback, capability.Fallback)
+			}
+		}
+		return nil
+	}
+
+	installed, err := plugins.List(conf, false, false)
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginListError), err)
+		return err
+	}
+
+	if len(installed) == 0 {
+		logger.Println(messages.Get(messages.NoPluginsInstalled))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"PLUGIN"}
+	for _, capability := range capabilities {
+		header = append(header, capability.Callback)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, plugin := range installed {
+		row := []string{plugin.Name}
+		for _, capability := range capabilities {
+			row = append(row, yesNo(plugin.Supports(capability.Callback)))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// explainError replaces a missing callback error with a description of the
+// feature the plugin doesn't support
+func explainError(err error) string {
+	var noCallbackErr plugins.NoCallbackError
+	if errors.As(err, &noCallbackErr) {
+		return noCallbackErr.Explain()
+	}
+	return err.Error()
+}
+
+func pluginListAllCommand(logger *log.Logger) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	disableRepo, err := conf.DisablePluginShortNameRepository()
+	if err != nil {
+		logger.Print(messages.Get(messages.ConfigCheckError))
+		return err
+	}
+	if disableRepo {
+		logger.Print(messages.Get(messages.ShortNameRepositoryDisabled))
+		cli.OsExiter(1)
+		return nil
+	}
+
+	lastCheckDuration := 0
+	// We don't care about errors here as we can use the default value
+	checkDuration, _ := conf.PluginRepositoryLastCheckDuration()
+
+	if !checkDuration.Never {
+		lastCheckDuration = checkDuration.Every
+	}
+
+	index := pluginindex.Build(conf.DataDir, conf.PluginIndexURL, false, lastCheckDuration)
+	availablePlugins, err := index.Get()
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginIndexError), err)
+		return err
+	}
+
+	installedPlugins, err := plugins.List(conf, true, false)
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginListError), err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 15, 0, 1, ' ', 0)
+	for _, availablePlugin := range availablePlugins {
+		if pluginInstalled(availablePlugin, installedPlugins) {
+			fmt.Fprintf(w, "%s\t\t*%s\n", availablePlugin.Name, availablePlugin.URL)
+		} else {
+			fmt.Fprintf(w, "%s\t\t%s\n", availablePlugin.Name, availablePlugin.URL)
+		}
+	}
+	w.Flush()
+
+	return nil
+}
+
+func pluginInstalled(plugin pluginindex.Plugin, installedPlugins []plugins.Plugin) bool {
+	for _, installedPlugin := range installedPlugins {
+		if installedPlugin.Name == plugin.Name && installedPlugin.URL == plugin.URL {
+			return true
+		}
+	}
+
+	return false
+}
+
+func infoCommand(conf config.Config, version string) error {
+	return info.Print(conf, version)
+}
+
+// addExampleTemplates appends the examples of each command that has some to
+// the template of its help
+func addExampleTemplates(commands []*cli.Command, parents []string) {
+	for _, cmd := range commands {
+		words := append(slices.Clone(parents), cmd.Name)
+		if examples, ok := help.Examples(strings.Join(words, " ")); ok {
+			template := cli.CommandHelpTemplate
+			if len(cmd.Commands) > 0 {
+				template = cli.SubcommandHelpTemplate
+			}
+
+			var indented strings.Builder
+			for _, line := range strings.Split(strings.TrimRight(examples, "\n"), "\n") {
+				if line != "" {
+					indented.WriteString("   " + line)
+				}
+				indented.WriteString("\n")
+			}
+
+			// Quoted so the examples are printed as they are rather than
+			// parsed as part of the template
+			cmd.CustomHelpTemplate = template + "\nEXAMPLES:\n{{" + strconv.Quote(indented.String()) + "}}"
+		}
+
+		addExampleTemplates(cmd.Commands, words)
+	}
+}
+
+func helpCommand(logger *log.Logger, asdfVersion, tool, version string, all bool) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	// asdf help <command> shows the examples of an asdf command, unless a
+	// plugin has the same name
+	if command := strings.TrimSpace(tool + " " + version); tool != "" && plugins.New(conf, tool).Exists() != nil {
+		if _, ok := help.Examples(command); ok {
+			return help.WriteExamples(command, os.Stdout)
+		}
+	}
+
+	if tool != "" {
+		if version == "" {
+			// Render the documentation for the version in use in the current
+			// directory, if any, so version specific details are included.
+			version = currentToolVersion(conf, tool)
+		}
+
+		if version != "" {
+			err := help.PrintToolVersion(conf, tool, version)
+			if err != nil {
+				cli.OsExiter(1)
+			}
+			return err
+		}
+
+		err := help.PrintTool(conf, tool)
+		if err != nil {
+			cli.OsExiter(1)
+		}
+		return err
+	}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		cli.OsExiter(1)
+	}
+
+	err = help.Print(asdfVersion, allPlugins, all)
+	if err != nil {
+		cli.OsExiter(1)
+	}
+
+	return err
+}
+
+func currentToolVersion(conf config.Config, tool string) string {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	toolVersions, found, err := resolve.Version(conf, plugins.New(conf, tool), currentDir)
+	if err != nil || !found || len(toolVersions.Versions) == 0 {
+		return ""
+	}
+
+	return toolVersions.Versions[0]
+}
+
+func pluginUpdateCommand(cCtx *cli.Command, logger *log.Logger, pluginName, ref string) error {
+	updateAll := cCtx.Bool("all")
+	if !updateAll && pluginName == "" {
+		return cli.Exit("usage: asdf plugin update {<name> [git-ref] | --all}", 1)
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	pluginsLock, err := acquireLock(logger, conf, lock.Plugins)
+	if err != nil {
+		return err
+	}
+	defer pluginsLock.Release()
+
+	if updateAll {
+		installedPlugins, err := plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.PluginListFailed), err)
+			return err
+		}
+
+		for _, plugin := range installedPlugins {
+			updatedToRef, err := plugin.Update(conf, "", os.Stdout, os.Stderr)
+			formatUpdateResult(logger, plugin.Name, updatedToRef, err)
+		}
+
+		return nil
+	}
+
+	plugin := plugins.New(conf, pluginName)
+	updatedToRef, err := plugin.Update(conf, ref, os.Stdout, os.Stderr)
+	formatUpdateResult(logger, pluginName, updatedToRef, err)
+	return err
+}
+
+func pluginTestCommand(l *log.Logger, args []string, toolVersion, ref string) {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		l.Printf(messages.Get(messages.ConfigLoadError), err)
+		cli.OsExiter(1)
+		return
+	}
+
+	if len(args) < 2 {
+		failTest(l, "please provide a plugin name and url")
+	}
+
+	name := args[0]
+	url := args[1]
+	testName := fmt.Sprintf("asdf-test-%s", name)
+
+	// Install plugin
+	err = plugins.Add(conf, testName, url, ref)
+	if err != nil {
+		failTest(l, fmt.Sprintf("%s was not properly installed reason: %s", name, err))
+	}
+
+	// Remove plugin
+	var blackhole strings.Builder
+	defer plugins.Remove(conf, testName, &blackhole, &blackhole)
+
+	// Assert callbacks are present
+	plugin := plugins.New(conf, testName)
+	files, err := os.ReadDir(filepath.Join(plugin.Dir, "bin"))
+	if _, ok := err.(*fs.PathError); ok {
+		failTest(l, "bin/ directory does not exist")
+	}
+
+	callbacks := []string{}
+	for _, file := range files {
+		callbacks = append(callbacks, file.Name())
+	}
+
+	for _, expectedCallback := range []string{"download", "install", "list-all"} {
+		if !slices.Contains(callbacks, expectedCallback) {
+			failTest(l, fmt.Sprintf("missing callback %s", expectedCallback))
+		}
+	}
+
+	allCallbacks := []string{"download", "install", "list-all", "latest-stable", "resolve-alias", "help.overview", "help.deps", "help.config", "help.links", "list-bin-paths", "exec-env", "exec-path", "uninstall", "list-legacy-filenames", "parse-legacy-file", "post-plugin-add", "post-plugin-update", "pre-plugin-remove"}
+
+	// Assert all callbacks present are executable
+	for _, file := range files {
+		// file is a callback...
+		if slices.Contains(allCallbacks, file.Name()) {
+			// check if it is executable
+			info, _ := file.Info()
+			if !(info.Mode()&0o111 != 0) {
+				failTest(l, fmt.Sprintf("callback lacks executable permission: %s", file.Name()))
+			}
+		}
+	}
+
+	// Assert has license
+	licensePath := filepath.Join(plugin.Dir, "LICENSE")
+	if _, err := os.Stat(licensePath); errors.Is(err, os.ErrNotExist) {
+		failTest(l, "LICENSE file must be present in the plugin repository")
+	}
+
+	bytes, err := os.ReadFile(licensePath)
+	if err != nil {
+		failTest(l, "LICENSE file must be present in the plugin repository")
+	}
+
+	// Validate license file not empty
+	if len(bytes) == 0 {
+		failTest(l, "LICENSE file in the plugin repository must not be empty")
+	}
+
+	// Validate it returns at least one available version
+	var output strings.Builder
+	err = plugin.RunCallback("list-all", []string{}, map[string]string{}, &output, &blackhole)
+	if err != nil {
+		failTest(l, "Unable to list available versions")
+	}
+
+	versionOutput, diagnostics := plugins.SplitVersionOutput(output.String())
+	if len(diagnostics) > 0 {
+		failTest(l, fmt.Sprintf("list-all must only print versions to stdout, diagnostics go to stderr: %q", diagnostics[0]))
+	}
+
+	allVersions := strings.Fields(versionOutput)
+	if len(allVersions) < 1 {
+		failTest(l, "list-all did not return any version")
+	}
+
+	// grab first version returned by list-all callback if no version provided as
+	// a CLI argument
+	if toolVersion == "" {
+		toolVersion = allVersions[0]
+	}
+
+	err = versions.InstallOneVersion(conf, plugin, toolVersion, false, os.Stdout, os.Stderr)
+	if err != nil {
+		failTest(l, "install exited with an error")
+	}
+}
+
+func failTest(logger *log.Logger, msg string) {
+	logger.Printf(messages.Get(messages.Failed), msg)
+	cli.OsExiter(1)
+}
+
+func formatUpdateResult(logger *log.Logger, pluginName, updatedToRef string, err error) {
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginUpdateError), pluginName, err)
+
+		return
+	}
+
+	logger.Printf(messages.Get(messages.PluginUpdated), pluginName, updatedToRef)
+}
+
+func installCommand(logger *log.Logger, toolName, version string, keepDownload, refreshRefs bool, at string, workspace bool) error {
+	if workspace && toolName != "" {
+		return cli.Exit("usage: asdf install --workspace, every tool is installed", 1)
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
+	}
+	defer installsLock.Release()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("unable to fetch current directory: %w", err)
+	}
+
+	if toolName == "" || version == "" {
+		recordProject(logger, conf, dir)
+	}
+
+	if at != "" {
+		snapshot, err := revision.Checkout(conf, dir, at)
+		if err != nil {
+			logger.Printf(messages.Get(messages.VersionFilesError), at, err)
+			cli.OsExiter(1)
+			return err
+		}
+		defer snapshot.Remove()
+
+		dir = snapshot.Path(dir)
+	}
+
+	if refreshRefs {
+		if err := refreshRefsCommand(logger, conf, dir, toolName, version); err != nil {
+			return err
+		}
+	}
+
+	if toolName == "" {
+		dirs := []string{dir}
+		if workspace {
+			var found bool
+			_, dirs, found, err = resolve.Workspace(dir)
+			if err != nil {
+				logger.Printf(messages.Get(messages.WorkspaceError), err)
+				return err
+			}
+			if !found {
+				logger.Printf(messages.Get(messages.WorkspaceNotFound), resolve.WorkspaceFilename, dir)
+				cli.OsExiter(1)
+				return nil
+			}
+		}
+
+		// Install all versions, of every subproject for a workspace
+		var errs []error
+		for _, dir := range dirs {
+			errs = append(errs, versions.InstallAll(conf, dir, os.Stdout, os.Stderr)...)
+		}
+		if len(errs) > 0 {
+			printed := map[string]bool{}
+			for _, err := range errs {
+				// Don't print error if no version set, this just means the current
+				// dir doesn't use a particular plugin that is installed.
+				if _, ok := err.(versions.NoVersionSetError); ok {
+					continue
+				}
+
+				// Subprojects of a workspace often share versions
+				if printed[err.Error()] {
+					continue
+				}
+				printed[err.Error()] = true
+
+				if _, ok := err.(versions.UninstallableVersionError); ok {
+					msg := fmt.Sprintf("skipping %s\n", err.Error())
+					os.Stderr.Write([]byte(msg))
+					continue
+				}
+
+				os.Stderr.Write([]byte(err.Error()))
+				os.Stderr.Write([]byte("\n"))
+			}
+
+			filtered := filterInstallErrors(errs)
+			if len(filtered) > 0 {
+				return filtered[0]
+			}
+			return nil
+		}
+	} else if tools, ok := groupTools(conf, toolName); ok {
+		if version != "" {
+			logger.Printf(messages.Get(messages.GroupVersionsFromConfig), toolName)
+			cli.OsExiter(1)
+			return nil
+		}
+
+		return installGroupCommand(logger, conf, dir, tools)
+	} else {
+		// Install specific version
+		plugin := plugins.New(conf, toolName)
+
+		if version == "" {
+			err = versions.Install(conf, plugin, dir, os.Stdout, os.Stderr)
+			if err != nil {
+				var vaiErr versions.VersionAlreadyInstalledError
+				if errors.As(err, &vaiErr) {
+					logger.Println(err)
+					return nil
+				}
+
+				if _, ok := err.(versions.NoVersionSetError); ok {
+					logger.Printf(messages.Get(messages.NoVersionsSpecified), toolName)
+					cli.OsExiter(1)
+				}
+
+				logger.Printf(messages.Get(messages.InstallVersionError), explainError(err))
+				return err
+			}
+		} else {
+			parsedVersion := toolversions.ParseFromCliArg(version)
+
+			if parsedVersion.Type == "latest" {
+				err = versions.InstallVersion(conf, plugin, parsedVersion, os.Stdout, os.Stderr)
+			} else {
+				// Adding this here to get tests passing. The other versions.Install*
+				// calls here could have a keepDownload argument added as well. PR
+				// welcome!
+				err = versions.InstallOneVersion(conf, plugin, version, keepDownload, os.Stdout, os.Stderr)
+			}
+
+			if err != nil {
+				var vaiErr versions.VersionAlreadyInstalledError
+				if errors.As(err, &vaiErr) {
+					logger.Println(err)
+					return nil
+				}
+
+				logger.Printf(messages.Get(messages.InstallVersionError), explainError(err))
+			}
+		}
+	}
+
+	return err
+}
+
+// refreshRefsCommand rebuilds the ref versions set for dir, or the given
+// version, that have moved upstream. Refs that can't be checked are reported
+// and skipped.
+func refreshRefsCommand(logger *log.Logger, conf config.Config, dir, toolName, version string) error {
+	var toRefresh []plugins.Plugin
+	if toolName == "" {
+		allPlugins, err := plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.ListPluginsError), err)
+			return err
+		}
+		toRefresh = allPlugins
+	} else {
+		toRefresh = []plugins.Plugin{plugins.New(conf, toolName)}
+	}
+
+	for _, plugin := range toRefresh {
+		versionStrs := []string{version}
+		if version == "" {
+			resolved, found, err := resolve.Version(conf, plugin, dir)
+			if err != nil || !found {
+				continue
+			}
+			versionStrs = resolved.Versions
+		}
+
+		for _, versionStr := range versionStrs {
+			_, err := versions.RefreshRef(conf, plugin, versionStr, os.Stdout, os.Stderr)
+			if _, ok := err.(versions.UntrackedRefError); ok {
+				logger.Printf("%s", err)
+				continue
+			}
+
+			if err != nil {
+				logger.Printf(messages.Get(messages.RefreshError), plugin.Name, versionStr, err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// groupTools returns the tools in the group with the given name. Installed
+// plugins take precedence over groups with the same name.
+func groupTools(conf config.Config, name string) ([]string, bool) {
+	if plugins.New(conf, name).Exists() == nil {
+		return nil, false
+	}
+
+	groups, err := conf.Groups()
+	if err != nil {
+		return nil, false
+	}
+
+	tools, ok := groups[name]
+	return tools, ok
+}
+
+func installGroupCommand(logger *log.Logger, conf config.Config, dir string, tools []string) error {
+	var group []plugins.Plugin
+	for _, tool := range tools {
+		group = append(group, plugins.New(conf, tool))
+	}
+
+	resolutions := versions.Resolve(context.Background(), conf, group, dir)
+	ordered, _, err := versions.InstallOrder(conf, group, resolutions)
+	if err != nil {
+		logger.Printf(messages.Get(messages.InstallGroupError), err)
+		return err
+	}
+
+	var firstErr error
+	for _, plugin := range ordered {
+		tool := plugin.Name
+		err := versions.InstallResolved(context.Background(), conf, plugin, dir, resolutions[tool], os.Stdout, os.Stderr)
+		if err == nil {
+			continue
+		}
+
+		var vaiErr versions.VersionAlreadyInstalledError
+		if errors.As(err, &vaiErr) {
+			logger.Println(err)
+			continue
+		}
+
+		if _, ok := err.(versions.NoVersionSetError); ok {
+			logger.Printf(messages.Get(messages.NoVersionsSpecified), tool)
+		} else {
+			logger.Printf(messages.Get(messages.InstallToolError), tool, err)
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func flagsCommand(logger *log.Logger) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 0, 2, ' ', 0)
+	for _, flag := range config.Flags() {
+		value, err := conf.Flag(flag.Name)
+		if err != nil {
+			logger.Printf(messages.Get(messages.FlagLoadError), flag.Name, err)
+			return err
+		}
+
+		if value == flag.Default() {
+			value += " (default)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", flag.Name, value, strings.Join(flag.Values, "|"), flag.Description)
+	}
+
+	return w.Flush()
+}
+
+func groupListCommand(logger *log.Logger) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	groups, err := conf.Groups()
+	if err != nil {
+		logger.Printf(messages.Get(messages.GroupsLoadError), err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 0, 2, ' ', 0)
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		fmt.Fprintf(w, "%s\t%s\n", name, strings.Join(groups[name], " "))
+	}
+
+	return w.Flush()
+}
+
+func filterInstallErrors(errs []error) []error {
+	var filtered []error
+	for _, err := range errs {
+		var vaiErr versions.VersionAlreadyInstalledError
+		if errors.As(err, &vaiErr) {
+			continue
+		}
+
+		if _, ok := err.(versions.NoVersionSetError); !ok {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+func latestCommand(logger *log.Logger, all bool, toolName, pattern string) (err error) {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if !all {
+		err = latestForPlugin(conf, toolName, pattern, false)
+		if err != nil {
+			cli.OsExiter(1)
+		}
+
+		return err
+	}
+
+	plugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		logger.Printf(messages.Get(messages.PluginListError), err)
+		return err
+	}
+
+	var maybeErr error
+	// loop over all plugins and show latest for each one.
+	for _, plugin := range plugins {
+		maybeErr = latestForPlugin(conf, plugin.Name, "", true)
+		if maybeErr != nil {
+			err = maybeErr
+		}
+	}
+
+	if err != nil {
+		cli.OsExiter(1)
+		return maybeErr
+	}
+	return nil
+}
+
+func listCommand(logger *log.Logger, first, second, third string, refresh bool) (err error) {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	// Both listAllCommand and listLocalCommand need to be refactored and extracted
+	// out into another package.
+	if first == "all" {
+		return listAllCommand(logger, conf, second, third, refresh)
+	}
+
+	return listLocalCommand(logger, conf, first, second)
+}
+
+func listAllCommand(logger *log.Logger, conf config.Config, toolName, filter string, refresh bool) error {
+	if toolName == "" {
+		logger.Print(messages.Get(messages.NoPluginGiven))
+		cli.OsExiter(1)
+		return nil
+	}
+
+	plugin, err := loadPlugin(logger, conf, toolName)
+	if err != nil {
+		cli.OsExiter(1)
+		return err
+	}
+
+	var stderr strings.Builder
+	versions, err := versions.ListAll(conf, plugin, refresh, &stderr)
+	if err != nil {
+		var noCallbackErr plugins.NoCallbackError
+		if errors.As(err, &noCallbackErr) {
+			logger.Print(noCallbackErr.Explain())
+			cli.OsExiter(1)
+			return err
+		}
+
+		fmt.Printf(messages.Get(messages.ListAllFailed)+"\n", plugin.Name)
+		// Print to stderr
+		os.Stderr.WriteString(stderr.String())
+
+		cli.OsExiter(1)
+		return err
+	}
+
+	if filter != "" {
+		versions = filterByExactMatch(versions, filter)
+	}
+
+	if len(versions) == 0 {
+		logger.Printf(messages.Get(messages.NoCompatibleVersionsAvailable), plugin.Name, filter)
+		cli.OsExiter(1)
+		return nil
+	}
+
+	for _, version := range versions {
+		fmt.Printf("%s\n", version)
+	}
+
+	return nil
+}
+
+func filterByExactMatch(allVersions []string, pattern string) (versions []string) {
+	for _, version := range allVersions {
+		if strings.HasPrefix(version, pattern) {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
+
+func listLocalCommand(logger *log.Logger, conf config.Config, pluginName, filter string) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
+	}
+
+	if pluginName != "" {
+		plugin, err := loadPlugin(logger, conf, pluginName)
+		if err != nil {
+			cli.OsExiter(1)
+			return err
+		}
+		versions, _ := installs.Installed(conf, plugin)
+
+		if filter != "" {
+			versions = filterByExactMatch(versions, filter)
+		}
+
+		if len(versions) == 0 {
+			if filter == "" {
+				logger.Printf(messages.Get(messages.NoCompatibleVersionsInstalled), plugin.Name)
+			} else {
+				logger.Printf(messages.Get(messages.NoCompatibleVersionsInstalledFilter), plugin.Name, filter)
+			}
+			return nil
+		}
+
+		currentVersions, _, err := resolve.Version(conf, plugin, currentDir)
+		if err != nil {
+			cli.OsExiter(1)
+			return err
+		}
+
+		for _, version := range versions {
+			if slices.Contains(currentVersions.Versions, version) {
+				fmt.Printf(" *%s\n", version)
+			} else {
+				fmt.Printf("  %s\n", version)
+			}
+		}
+		return nil
+	}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		logger.Printf(messages.Get(messages.ListPluginsDueToError), err)
+		return err
+	}
+
+	for _, plugin := range allPlugins {
+		fmt.Printf("%s\n", plugin.Name)
+		versions, _ := installs.Installed(conf, plugin)
+
+		if len(versions) > 0 {
+			currentVersions, _, err := resolve.Version(conf, plugin, currentDir)
+			if err != nil {
+				cli.OsExiter(1)
+				return err
+			}
+			for _, version := range versions {
+				if slices.Contains(currentVersions.Versions, version) {
+					fmt.Printf(" *%s\n", version)
+				} else {
+					fmt.Printf("  %s\n", version)
+				}
+			}
+		} else {
+			fmt.Print("  " + messages.Get(messages.NoVersionsInstalled) + "\n")
+		}
+	}
+
+	return nil
+}
+
+func lockStatusCommand(logger *log.Logger) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	statuses, err := lock.List(conf.DataDir)
+	if err != nil {
+		logger.Printf(messages.Get(messages.LocksReadError), err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 0, 2, ' ', 0)
+	for _, status := range statuses {
+		if status.Held {
+			fmt.Fprintf(w, "%s\theld by %s\n", status.Name, status.Holder)
+		} else if status.Stale {
+			fmt.Fprintf(w, "%s\tfree, pid %d (%s) exited without releasing it, see asdf recover\n", status.Name, status.Holder.PID, status.Holder.Command)
+		} else {
+			fmt.Fprintf(w, "%s\tfree\n", status.Name)
+		}
+	}
+
+	return w.Flush()
+}
+
+func lockWaitCommand(logger *log.Logger, names []string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if len(names) == 0 {
+		names = []string{lock.Installs, lock.Plugins}
+	}
+
+	for _, name := range names {
+		heldLock, err := acquireLock(logger, conf, name)
+		if err != nil {
+			return err
+		}
+
+		if err := heldLock.Release(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notifyFinished notifies the channels of the notify_channels setting that the
+// command started at the given time has finished, logging when they can't be
+// notified
+func notifyFinished(logger *log.Logger, cmd *cli.Command, started time.Time, err error) {
+	conf, confErr := config.LoadConfig()
+	if confErr != nil {
+		return
+	}
+
+	operation := strings.Join(append([]string{"asdf", cmd.FullName()}, cmd.Args().Slice()...), " ")
+	if notifyErr := notify.Finished(conf, operation, started, err, os.Stderr); notifyErr != nil {
+		logger.Printf(messages.Get(messages.NotificationError), notifyErr)
+	}
+}
+
+// acquireLock takes the named lock, logging which process holds it if it has
+// to wait
+func acquireLock(logger *log.Logger, conf config.Config, name string) (*lock.Lock, error) {
+	heldLock, err := lock.Acquire(conf.DataDir, name, lockTimeout, func(holder lock.Holder) {
+		logger.Printf(messages.Get(messages.WaitingForLock), name, holder)
+	})
+	if err != nil {
+		logger.Printf(messages.Get(messages.LockAcquireError), name, err)
+	}
+
+	return heldLock, err
+}
+
+// recoverCommand repairs or removes what interrupted commands left behind in
+// the data directory and prints each action taken
+func recoverCommand(logger *log.Logger) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	report, err := recovery.Run(conf, func(name string) (*lock.Lock, error) {
+		return acquireLock(logger, conf, name)
+	})
+	if err != nil {
+		logger.Printf(messages.Get(messages.RecoverError), err)
+		return err
+	}
+
+	if len(report.Actions) == 0 {
+		fmt.Println(messages.Get(messages.NothingToRecover))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 0, 2, ' ', 0)
+	for _, action := range report.Actions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", action.Check, action.Target, action.Result, action.Detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if report.Failed {
+		return errors.New("recovery failed")
+	}
+
+	return nil
+}
+
+// maintainCommand runs the given maintenance tasks, or those set in the
+// maintain_tasks setting, and prints a JSON report of the actions taken
+func maintainCommand(logger *log.Logger, tasks []string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if len(tasks) == 0 {
+		tasks, err = conf.MaintainTasks()
+		if err != nil {
+			logger.Printf(messages.Get(messages.MaintainTasksError), err)
+			return err
+		}
+	}
+
+	pluginsLock, err := acquireLock(logger, conf, lock.Plugins)
+	if err != nil {
+		return err
+	}
+	defer pluginsLock.Release()
+
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
+	}
+	defer installsLock.Release()
+
+	report, err := maintain.Run(conf, tasks)
+	if err != nil {
+		logger.Printf(messages.Get(messages.MaintenanceError), err)
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if report.Failed {
+		return errors.New("maintenance failed")
+	}
+	return nil
+}
+
+func migrateDataCommand(logger *log.Logger, dryRun bool) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if dryRun {
+		pending, err := migrate.Pending(conf.DataDir)
+		if err != nil {
+			logger.Printf(messages.Get(messages.DataDirectoryStateError), err)
+			return err
+		}
+
+		for _, migration := range pending {
+			fmt.Printf("%d\t%s\n", migration.Version, migration.Description)
+		}
+		return nil
+	}
+
+	applied, err := migrate.Run(conf, os.Stdout, os.Stderr)
+	if err != nil {
+		logger.Printf("%s", err)
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Printf(messages.Get(messages.DataDirectoryUpToDate)+"\n", migrate.Latest())
+	}
+	return nil
+}
+
+func overrideCommand(logger *log.Logger, dir, tool string, versions []string) error {
+	if tool == "" || len(versions) == 0 {
+		logger.Print(messages.Get(messages.ToolAndVersionRequired))
+		return errors.New("bad arguments")
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	if _, err := loadPlugin(logger, conf, tool); err != nil {
+		return err
+	}
+
+	directory, err := overrideDirectory(dir)
+	if err != nil {
+		logger.Printf(messages.Get(messages.OverrideDirectoryError), err)
+		return err
+	}
+
+	if err := overrides.Set(conf.DataDir, directory, tool, versions); err != nil {
+		logger.Printf(messages.Get(messages.OverrideSaveError), err)
+		return err
+	}
+
+	fmt.Printf(messages.Get(messages.Overridden)+"\n", tool, strings.Join(versions, " "), directory)
+	return nil
+}
+
+func overrideListCommand(logger *log.Logger) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	allOverrides, err := overrides.List(conf.DataDir)
+	if err != nil {
+		logger.Printf(messages.Get(messages.OverridesReadError), err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 16, 0, 1, ' ', 0)
+	for _, override := range allOverrides {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", override.Directory, override.Tool, strings.Join(override.Versions, " "))
+	}
+	return w.Flush()
+}
+
+func overrideRemoveCommand(logger *log.Logger, dir, tool string) error {
+	if tool == "" {
+		logger.Print(messages.Get(messages.NoToolSpecified))
+		return errors.New("no tool specified")
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	directory, err := overrideDirectory(dir)
+	if err != nil {
+		logger.Printf(messages.Get(messages.OverrideDirectoryError), err)
+		return err
+	}
+
+	removed, err := overrides.Remove(conf.DataDir, directory, tool)
+	if err != nil {
+		logger.Printf(messages.Get(messages.OverrideRemoveError), err)
+		return err
+	}
+
+	if !removed {
+		logger.Printf(messages.Get(messages.NoOverride), tool, directory)
+		return errors.New("no override found")
+	}
+
+	return nil
+}
+
+// overrideDirectory returns the absolute path of the directory an override
+// applies to, defaulting to the current directory
+func overrideDirectory(dir string) (string, error) {
+	if dir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(dir)
+}
+
+func provenanceCommand(logger *log.Logger, tool, versionStr string) error {
+	if tool == "" {
+		logger.Print(messages.Get(messages.UsageProvenance))
+		return errors.New("no tool specified")
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	plugin, err := loadPlugin(logger, conf, tool)
+	if err != nil {
+		return err
+	}
+
+	if versionStr == "" {
+		versionStr = currentToolVersion(conf, tool)
+	}
+
+	version := toolversions.Parse(versionStr)
+	if version.Value == "" || !installs.Exists(conf, plugin, version) {
+		logger.Print(messages.Get(messages.VersionNotInstalled))
+		return errors.New("Version not installed")
+	}
+
+	record, err := provenance.Read(installs.MetadataPath(conf, plugin, version), plugin.Name, versionStr)
+	if err != nil {
+		logger.Printf("%s", err)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 16, 0, 1, ' ', 0)
+	for _, field := range record.Fields() {
+		if field[1] != "" {
+			fmt.Fprintf(w, "%s\t%s\n", field[0], field[1])
+		}
+	}
+	return w.Flush()
+}
+
+const readyPollInterval = time.Second
+
+// serveCommand serves version resolution, installed versions and install
+// locations over HTTP until it's stopped
+func serveCommand(logger *log.Logger, address string) error {
+	if address == "" {
+		return cli.Exit("usage: asdf serve --http <address>", 1)
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	logger.Printf(messages.Get(messages.Serving), conf.DataDir, address)
+	err = serve.NewServer(conf, address).ListenAndServe()
+	logger.Printf(messages.Get(messages.ServeError), err)
+	return err
+}
+
+func readyCommand(logger *log.Logger, timeout time.Duration, install, jsonOutput bool) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
+	}
+
+	if install {
+		if err := readyInstall(logger, conf, dir); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	status, err := ready.Check(conf, dir)
+	for err == nil && !status.Ready && time.Now().Before(deadline) {
+		time.Sleep(readyPollInterval)
+		status, err = ready.Check(conf, dir)
+	}
+
+	if err != nil {
+		logger.Printf(messages.Get(messages.ToolsCheckError), err)
+		return err
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(status); err != nil {
+			return err
+		}
+	} else {
+		for _, tool := range status.Tools {
+			switch {
+			case !tool.Installed:
+				fmt.Printf(messages.Get(messages.ReadyNotInstalled)+"\n", tool.Name, tool.Version)
+			case !tool.Ready():
+				fmt.Printf(messages.Get(messages.ReadyMissingShims)+"\n", tool.Name, tool.Version, strings.Join(tool.MissingShims, " "))
+			default:
+				fmt.Printf(messages.Get(messages.Ready)+"\n", tool.Name, tool.Version)
+			}
+		}
+	}
+
+	if !status.Ready {
+		cli.OsExiter(1)
+		return errors.New("tools not ready")
+	}
+
+	return nil
+}
+
+// readyInstall installs every version set for dir and regenerates all shims.
+// Install output goes to stderr so stdout only contains the status.
+func readyInstall(logger *log.Logger, conf config.Config, dir string) error {
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
+	}
+	defer installsLock.Release()
+
+	for _, err := range filterInstallErrors(versions.InstallAll(conf, dir, os.Stderr, os.Stderr)) {
+		logger.Printf("%s", err)
+	}
+
+	if err := shims.Regenerate(conf, os.Stderr, os.Stderr); err != nil {
+		logger.Printf(messages.Get(messages.ReshimError), err)
+		return err
+	}
+
+	return nil
+}
+
+func reshimCommand(logger *log.Logger, tool, version string) (err error) {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
+	}
+	defer installsLock.Release()
+
+	var plugin plugins.Plugin
 
-		if _, ok := err.(versions.NoVersionSetError); !ok {
-			filtered = append(filtered, err)
+	if tool != "" {
+		plugin = plugins.New(conf, tool)
+		if err := plugin.Exists(); err != nil {
+			logger.Printf(messages.Get(messages.NoSuchPlugin), plugin.Name)
+			cli.OsExiter(1)
+			return err
 		}
 	}
-	return filtered
+	// if either tool or version are missing just regenerate all shims. This is
+	// fast enough now.
+	if tool == "" || version == "" {
+		return shims.Regenerate(conf, os.Stdout, os.Stderr)
+	}
+
+	// If provided a specific version it could be something special like a path
+	// version so we need to generate it manually
+	return reshimToolVersion(conf, plugin, version, os.Stdout, os.Stderr)
 }
 
-func latestCommand(logger *log.Logger, all bool, toolName, pattern string) (err error) {
+func resolveCommand(logger *log.Logger, tool, at string) error {
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	if !all {
-		err = latestForPlugin(conf, toolName, pattern, false)
+	dir, err := os.Getwd()
+	if err != nil {
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+		return err
+	}
+
+	var snapshot revision.Snapshot
+	if at != "" {
+		snapshot, err = revision.Checkout(conf, dir, at)
 		if err != nil {
+			logger.Printf(messages.Get(messages.VersionFilesError), at, err)
 			cli.OsExiter(1)
+			return err
 		}
+		defer snapshot.Remove()
 
-		return err
+		dir = snapshot.Path(dir)
 	}
 
-	plugins, err := plugins.List(conf, false, false)
-	if err != nil {
-		logger.Printf("error loading plugin list: %s", err)
-		return err
+	var toolPlugins []plugins.Plugin
+	if tool == "" {
+		toolPlugins, err = plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.ListPluginsError), err)
+			return err
+		}
+	} else {
+		plugin := plugins.New(conf, tool)
+		if err := plugin.Exists(); err != nil {
+			logger.Printf("%s", err)
+			cli.OsExiter(1)
+			return err
+		}
+		toolPlugins = []plugins.Plugin{plugin}
 	}
 
-	var maybeErr error
-	// loop over all plugins and show latest for each one.
-	for _, plugin := range plugins {
-		maybeErr = latestForPlugin(conf, plugin.Name, "", true)
-		if maybeErr != nil {
-			err = maybeErr
+	w := tabwriter.NewWriter(os.Stdout, 16, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\n", "Name", "Version", "Source")
+	for _, plugin := range toolPlugins {
+		toolversion, found, err := resolve.Version(conf, plugin, dir)
+		if err != nil {
+			w.Flush()
+			logger.Printf(messages.Get(messages.ResolveError), plugin.Name, err)
+			return err
 		}
-	}
 
-	if err != nil {
-		cli.OsExiter(1)
-		return maybeErr
+		source := formatSource(toolversion, found)
+		if found && at != "" {
+			source = formatRevisionSource(snapshot, toolversion)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", plugin.Name, formatVersions(toolversion.Versions, ""), source)
 	}
-	return nil
+
+	return w.Flush()
 }
 
-func listCommand(logger *log.Logger, first, second, third string) (err error) {
-	conf, err := config.LoadConfig()
+func retoolCommand(logger *log.Logger, from, to string, paths []string, dryRun bool, summary string) error {
+	if from == "" || to == "" {
+		logger.Print(messages.Get(messages.UsageRetool))
+		return fmt.Errorf("usage: asdf retool --from <tool>@<version> --to <tool>@<version>")
+	}
+
+	tool, fromVersion, err := retool.ParseSpec(from)
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf("%s", err)
 		return err
 	}
 
-	// Both listAllCommand and listLocalCommand need to be refactored and extracted
-	// out into another package.
-	if first == "all" {
-		return listAllCommand(logger, conf, second, third)
+	toTool, toVersion, err := retool.ParseSpec(to)
+	if err != nil {
+		logger.Printf("%s", err)
+		return err
 	}
 
-	return listLocalCommand(logger, conf, first, second)
-}
-
-func listAllCommand(logger *log.Logger, conf config.Config, toolName, filter string) error {
-	if toolName == "" {
-		logger.Print("No plugin given")
-		cli.OsExiter(1)
-		return nil
+	if toTool != tool {
+		logger.Printf(messages.Get(messages.RetoolToolMismatch), tool, toTool)
+		return fmt.Errorf("--from and --to must name the same tool")
 	}
 
-	plugin, err := loadPlugin(logger, conf, toolName)
+	conf, err := config.LoadConfig()
 	if err != nil {
-		cli.OsExiter(1)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
-	var stdout strings.Builder
-	var stderr strings.Builder
+	if len(paths) == 0 {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
+			return err
+		}
+		paths = []string{currentDir}
+	}
 
-	err = plugin.RunCallback("list-all", []string{}, map[string]string{}, &stdout, &stderr)
+	changes, err := retool.Run(paths, conf.DefaultToolVersionsFilename, tool, fromVersion, toVersion, !dryRun)
+	for _, change := range changes {
+		fmt.Printf("%s: %s %s -> %s\n", change.Path, tool, strings.Join(change.Before, " "), strings.Join(change.After, " "))
+	}
 	if err != nil {
-		fmt.Printf("Plugin %s's list-all callback script failed with output:\n", plugin.Name)
-		// Print to stderr
-		os.Stderr.WriteString(stderr.String())
-		os.Stderr.WriteString(stdout.String())
-
-		cli.OsExiter(1)
+		logger.Printf(messages.Get(messages.RetoolError), err)
 		return err
 	}
 
-	versions := strings.Split(stdout.String(), " ")
-
-	if filter != "" {
-		versions = filterByExactMatch(versions, filter)
+	if dryRun {
+		fmt.Printf(messages.Get(messages.RetoolWouldChange)+"\n", len(changes))
+	} else {
+		fmt.Printf(messages.Get(messages.RetoolChanged)+"\n", len(changes))
 	}
 
-	if len(versions) == 0 {
-		logger.Printf("No compatible versions available (%s %s)", plugin.Name, filter)
-		cli.OsExiter(1)
-		return nil
-	}
+	if summary != "" {
+		file, err := os.Create(summary)
+		if err != nil {
+			logger.Printf(messages.Get(messages.SummaryWriteError), err)
+			return err
+		}
+		defer file.Close()
 
-	for _, version := range versions {
-		fmt.Printf("%s\n", version)
+		if err := retool.WriteSummary(file, tool, fromVersion, toVersion, changes); err != nil {
+			logger.Printf(messages.Get(messages.SummaryWriteError), err)
+			return err
+		}
 	}
 
 	return nil
 }
 
-func filterByExactMatch(allVersions []string, pattern string) (versions []string) {
-	for _, version := range allVersions {
-		if strings.HasPrefix(version, pattern) {
-			versions = append(versions, version)
-		}
+// stampCommand writes a stamp of the tools resolved for the current directory,
+// or with verify compares the stamp in that file against them, printing every
+// difference and exiting with an error if there are any
+func stampCommand(logger *log.Logger, format, output, verify string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
 	}
 
-	return versions
-}
-
-func listLocalCommand(logger *log.Logger, conf config.Config, pluginName, filter string) error {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		logger.Printf("unable to get current directory: %s", err)
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
 		return err
 	}
 
-	if pluginName != "" {
-		plugin, err := loadPlugin(logger, conf, pluginName)
+	if verify != "" {
+		file, err := os.Open(verify)
 		if err != nil {
-			cli.OsExiter(1)
+			logger.Printf(messages.Get(messages.StampReadError), err)
 			return err
 		}
-		versions, _ := installs.Installed(conf, plugin)
-
-		if filter != "" {
-			versions = filterByExactMatch(versions, filter)
-		}
+		defer file.Close()
 
-		if len(versions) == 0 {
-			if filter == "" {
-				logger.Printf("No compatible versions installed (%s)", plugin.Name)
-			} else {
-				logger.Printf("No compatible versions installed (%s %s)", plugin.Name, filter)
-			}
-			return nil
+		stamped, err := stamp.Read(file)
+		if err != nil {
+			logger.Printf(messages.Get(messages.StampFileReadError), verify, err)
+			return err
 		}
 
-		currentVersions, _, err := resolve.Version(conf, plugin, currentDir)
+		differences, err := stamp.Verify(conf, stamped, currentDir)
 		if err != nil {
-			cli.OsExiter(1)
+			logger.Printf(messages.Get(messages.StampVerifyError), err)
 			return err
 		}
 
-		for _, version := range versions {
-			if slices.Contains(currentVersions.Versions, version) {
-				fmt.Printf(" *%s\n", version)
-			} else {
-				fmt.Printf("  %s\n", version)
-			}
+		for _, difference := range differences {
+			fmt.Println(difference)
+		}
+
+		if len(differences) > 0 {
+			cli.OsExiter(1)
+			return errors.New("environment differs from stamp")
 		}
 		return nil
 	}
 
-	allPlugins, err := plugins.List(conf, false, false)
+	if format != stamp.FormatJSON && format != stamp.FormatEnv {
+		logger.Printf(messages.Get(messages.UnknownStampFormat), format, stamp.FormatJSON, stamp.FormatEnv)
+		return errors.New("bad stamp format")
+	}
+
+	current, err := stamp.New(conf, currentDir)
 	if err != nil {
-		logger.Printf("unable to list plugins due to error: %s", err)
+		logger.Printf(messages.Get(messages.StampError), err)
 		return err
 	}
 
-	for _, plugin := range allPlugins {
-		fmt.Printf("%s\n", plugin.Name)
-		versions, _ := installs.Installed(conf, plugin)
-
-		if len(versions) > 0 {
-			currentVersions, _, err := resolve.Version(conf, plugin, currentDir)
-			if err != nil {
-				cli.OsExiter(1)
-				return err
-			}
-			for _, version := range versions {
-				if slices.Contains(currentVersions.Versions, version) {
-					fmt.Printf(" *%s\n", version)
-				} else {
-					fmt.Printf("  %s\n", version)
-				}
-			}
-		} else {
-			fmt.Print("  No versions installed\n")
+	out := io.Writer(os.Stdout)
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			logger.Printf(messages.Get(messages.StampWriteError), err)
+			return err
 		}
+		defer file.Close()
+		out = file
 	}
 
-	return nil
-}
-
-func reshimCommand(logger *log.Logger, tool, version string) (err error) {
-	conf, err := config.LoadConfig()
-	if err != nil {
-		logger.Printf("error loading config: %s", err)
+	if err := stamp.Write(current, format, out); err != nil {
+		logger.Printf(messages.Get(messages.StampWriteError), err)
 		return err
 	}
 
-	var plugin plugins.Plugin
+	return nil
+}
 
-	if tool != "" {
-		plugin = plugins.New(conf, tool)
-		if err := plugin.Exists(); err != nil {
-			logger.Printf("No such plugin: %s", plugin.Name)
-			cli.OsExiter(1)
-			return err
-		}
+// formatRevisionSource formats the source of versions resolved in a snapshot,
+// showing files committed in the repository as <ref>:<path>
+func formatRevisionSource(snapshot revision.Snapshot, toolversion resolve.ToolVersions) string {
+	if toolversion.Directory == "" {
+		return toolversion.Source
 	}
-	// if either tool or version are missing just regenerate all shims. This is
-	// fast enough now.
-	if tool == "" || version == "" {
-		err = shims.RemoveAll(conf)
-		if err != nil {
-			return err
-		}
 
-		return shims.GenerateAll(conf, os.Stdout, os.Stderr)
+	original := snapshot.Original(filepath.Join(toolversion.Directory, toolversion.Source))
+	rel, err := filepath.Rel(snapshot.Root, original)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return original
 	}
 
-	// If provided a specific version it could be something special like a path
-	// version so we need to generate it manually
-	return reshimToolVersion(conf, plugin, version, os.Stdout, os.Stderr)
+	return fmt.Sprintf("%s:%s", snapshot.Ref, filepath.ToSlash(rel))
 }
 
 func shimVersionsCommand(logger *log.Logger, shimName string) error {
 	if shimName == "" {
-		logger.Printf("usage: asdf shimversions <command>")
+		logger.Print(messages.Get(messages.UsageShimversions))
 		return fmt.Errorf("usage: asdf shimversions <command>")
 	}
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
@@ -1408,24 +4170,24 @@ func shimVersionsCommand(logger *log.Logger, shimName string) error {
 func whichCommand(logger *log.Logger, command string) error {
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
 	currentDir, err := os.Getwd()
 	if err != nil {
-		logger.Printf("unable to get current directory: %s", err)
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
 		return err
 	}
 
 	if command == "" {
-		fmt.Println("usage: asdf which <command>")
+		fmt.Println(messages.Get(messages.UsageWhich))
 		return errors.New("must provide command")
 	}
 
 	path, _, _, _, err := shims.FindExecutable(conf, command, currentDir)
 	if _, ok := err.(shims.UnknownCommandError); ok {
-		logger.Printf("unknown command: %s. Perhaps you have to reshim?", command)
+		logger.Printf(messages.Get(messages.UnknownCommand), command)
 		return errors.New("command not found")
 	}
 
@@ -1435,7 +4197,7 @@ func whichCommand(logger *log.Logger, command string) error {
 	}
 
 	if err != nil {
-		fmt.Printf("unexpected error: %s\n", err.Error())
+		fmt.Printf(messages.Get(messages.UnexpectedError)+"\n", err.Error())
 		return err
 	}
 
@@ -1443,21 +4205,51 @@ func whichCommand(logger *log.Logger, command string) error {
 	return nil
 }
 
-func uninstallCommand(logger *log.Logger, tool, version string) error {
+// recordProject adds the project the directory belongs to to the index of
+// known projects, so the versions it uses are protected from being uninstalled
+func recordProject(logger *log.Logger, conf config.Config, dir string) {
+	if err := projects.Record(conf, dir); err != nil {
+		logger.Printf(messages.Get(messages.ProjectRecordError), dir, err)
+	}
+}
+
+func uninstallCommand(logger *log.Logger, tool, version string, force bool) error {
 	if tool == "" || version == "" {
-		logger.Print("No plugin given")
+		logger.Print(messages.Get(messages.NoPluginGiven))
 		cli.OsExiter(1)
 		return nil
 	}
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		cli.OsExiter(1)
 		return err
 	}
 
+	installsLock, err := acquireLock(logger, conf, lock.Installs)
+	if err != nil {
+		return err
+	}
+	defer installsLock.Release()
+
 	plugin := plugins.New(conf, tool)
+
+	if !force {
+		using, err := projects.Using(conf, plugin, version)
+		if err != nil {
+			logger.Printf(messages.Get(messages.ProjectsCheckError), tool, version, err)
+			cli.OsExiter(1)
+			return err
+		}
+
+		if len(using) > 0 {
+			logger.Printf(messages.Get(messages.ProjectsUsingVersion)+"\n  %s\n"+messages.Get(messages.ForceUninstallHint), tool, version, strings.Join(using, "\n  "))
+			cli.OsExiter(1)
+			return fmt.Errorf("%s %s is used by %d projects", tool, version, len(using))
+		}
+	}
+
 	err = versions.Uninstall(conf, plugin, version, os.Stdout, os.Stderr)
 	if err != nil {
 		logger.Printf("%s", err)
@@ -1465,28 +4257,105 @@ func uninstallCommand(logger *log.Logger, tool, version string) error {
 		return err
 	}
 
-	// This feels a little hacky but it works, to re-generate shims we delete them
-	// all and generate them again.
-	err = shims.RemoveAll(conf)
+	// Regenerating drops the shims of the removed version
+	err = shims.Regenerate(conf, os.Stdout, os.Stderr)
 	if err != nil {
 		logger.Printf("%s", err)
 		cli.OsExiter(1)
 		return err
 	}
 
-	return shims.GenerateAll(conf, os.Stdout, os.Stderr)
+	return nil
+}
+
+func verifyCommand(logger *log.Logger, tool, versionStr string) error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
+		return err
+	}
+
+	var toVerify []plugins.Plugin
+	if tool == "" {
+		toVerify, err = plugins.List(conf, false, false)
+		if err != nil {
+			logger.Printf(messages.Get(messages.PluginListError), err)
+			return err
+		}
+	} else {
+		plugin, err := loadPlugin(logger, conf, tool)
+		if err != nil {
+			return err
+		}
+		toVerify = []plugins.Plugin{plugin}
+	}
+
+	failed := false
+	for _, plugin := range toVerify {
+		installed := []string{versionStr}
+		if versionStr == "" {
+			installed, err = installs.All(conf, plugin)
+			if err != nil {
+				logger.Printf(messages.Get(messages.InstalledVersionsError), plugin.Name, err)
+				return err
+			}
+		}
+
+		for _, installedVersion := range installed {
+			version := toolversions.Parse(installedVersion)
+			if !installs.Exists(conf, plugin, version) {
+				logger.Printf(messages.Get(messages.ToolVersionNotInstalled), plugin.Name, installedVersion)
+				failed = true
+				continue
+			}
+
+			differences, err := installs.Verify(conf, plugin, version)
+			if _, ok := err.(provenance.NoManifestError); ok {
+				fmt.Printf(messages.Get(messages.VerifySkipped)+"\n", plugin.Name, installedVersion, err)
+				continue
+			}
+
+			if err != nil {
+				fmt.Printf("%s %s: %s\n", plugin.Name, installedVersion, err)
+				failed = true
+				continue
+			}
+
+			if differences.Empty() {
+				fmt.Printf(messages.Get(messages.VerifyOk)+"\n", plugin.Name, installedVersion)
+				continue
+			}
+
+			failed = true
+			fmt.Printf(messages.Get(messages.VerifyQuarantined)+"\n", plugin.Name, installedVersion)
+			for _, path := range differences.Modified {
+				fmt.Printf("  "+messages.Get(messages.VerifyModified)+"\n", path)
+			}
+			for _, path := range differences.Missing {
+				fmt.Printf("  "+messages.Get(messages.VerifyMissing)+"\n", path)
+			}
+			for _, path := range differences.Added {
+				fmt.Printf("  "+messages.Get(messages.VerifyAdded)+"\n", path)
+			}
+		}
+	}
+
+	if failed {
+		return errors.New("verification failed")
+	}
+	return nil
 }
 
 func whereCommand(logger *log.Logger, tool, versionStr string) error {
 	conf, err := config.LoadConfig()
 	if err != nil {
-		logger.Printf("error loading config: %s", err)
+		logger.Printf(messages.Get(messages.ConfigLoadError), err)
 		return err
 	}
 
 	currentDir, err := os.Getwd()
 	if err != nil {
-		logger.Printf("unable to get current directory: %s", err)
+		logger.Printf(messages.Get(messages.CurrentDirectoryError), err)
 		return err
 	}
 
@@ -1494,15 +4363,15 @@ func whereCommand(logger *log.Logger, tool, versionStr string) error {
 	err = plugin.Exists()
 	if err != nil {
 		if _, ok := err.(plugins.PluginMissing); ok {
-			logger.Printf("No such plugin: %s", tool)
+			logger.Printf(messages.Get(messages.NoSuchPlugin), tool)
 		}
 		return err
 	}
 
 	version := toolversions.Parse(versionStr)
 
-	if version.Type == "system" {
-		logger.Printf("System version is selected")
+	if version.IsSystem() {
+		logger.Print(messages.Get(messages.SystemVersionSelected))
 		return errors.New("System version is selected")
 	}
 
@@ -1521,7 +4390,11 @@ func whereCommand(logger *log.Logger, tool, versionStr string) error {
 				fmt.Printf("%s", installPath)
 				return nil
 			}
-			currentResolvedVersion := resolve.FindBestMatchingVersion(conf, plugin, versions.Versions)
+			dirConf, err := conf.ForDirectory(currentDir)
+			if err != nil {
+				return err
+			}
+			currentResolvedVersion := resolve.FindBestMatchingVersion(dirConf, plugin, versions.Versions)
 			if currentResolvedVersion != "" {
 				versionStruct = toolversions.Version{Type: "version", Value: currentResolvedVersion}
 				installPath := installs.InstallPath(conf, plugin, versionStruct)
@@ -1536,8 +4409,8 @@ func whereCommand(logger *log.Logger, tool, versionStr string) error {
 		return errors.New(msg)
 	}
 
-	if !installs.IsInstalled(conf, plugin, version) {
-		logger.Printf("Version not installed")
+	if !installs.Exists(conf, plugin, version) {
+		logger.Print(messages.Get(messages.VersionNotInstalled))
 		return errors.New("Version not installed")
 	}
 
@@ -1551,7 +4424,7 @@ func loadPlugin(logger *log.Logger, conf config.Config, pluginName string) (plug
 	plugin := plugins.New(conf, pluginName)
 	err := plugin.Exists()
 	if err != nil {
-		logger.Printf("No such plugin: %s", pluginName)
+		logger.Printf(messages.Get(messages.NoSuchPlugin), pluginName)
 		return plugin, err
 	}
 
@@ -1567,9 +4440,9 @@ func reshimToolVersion(conf config.Config, plugin plugins.Plugin, versionStr str
 func latestForPlugin(conf config.Config, toolName, pattern string, showStatus bool) error {
 	// show single plugin
 	plugin := plugins.New(conf, toolName)
-	latest, err := versions.Latest(plugin, pattern)
+	latest, err := versions.Latest(conf, plugin, pattern)
 	if err != nil && err.Error() != "no latest version found" {
-		fmt.Printf("unable to load latest version: %s\n", err)
+		fmt.Printf(messages.Get(messages.LatestVersionError)+"\n", explainError(err))
 		return err
 	}
 
@@ -1605,5 +4478,27 @@ func unsetAsdfReservedEnvVars() error {
 			return err
 		}
 	}
+
+	if !noInherit(os.Getenv("ASDF_NO_INHERIT")) {
+		return nil
+	}
+
+	// Undo the PATH and exec-env changes made by the asdf exec this process is
+	// nested in, so tools run by another tool resolve as if run directly
+	set, unset := execenv.Restore(execute.CurrentEnv())
+	for name, value := range set {
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	for _, name := range unset {
+		if err := os.Unsetenv(name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func noInherit(value string) bool {
+	return slices.Contains([]string{"1", "yes", "true"}, strings.ToLower(value))
+}
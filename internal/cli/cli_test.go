@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/lock"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versions"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestAutoInstall(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	t.Setenv("HOME", t.TempDir())
+
+	// generateConfig sets up a project setting 1.1.0 with only 1.0.0
+	// installed, so the dummy shim exists but its version is missing
+	generateConfig := func(t *testing.T, mode string) (config.Config, plugins.Plugin, string) {
+		conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("auto_install = "+mode+"\n"), 0o666))
+		_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+		assert.Nil(t, err)
+		plugin := plugins.New(conf, testPluginName)
+		assert.Nil(t, versions.InstallOneVersion(conf, plugin, "1.0.0", false, io.Discard, io.Discard))
+		assert.Nil(t, shims.GenerateAll(conf, io.Discard, io.Discard))
+
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(testPluginName+" 1.1.0\n"), 0o666))
+		return conf, plugin, projectDir
+	}
+
+	t.Run("installs missing version when auto_install is yes", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "yes")
+
+		assert.True(t, autoInstall(logger, conf, "dummy", projectDir, nil, io.Discard))
+		assert.True(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+
+	t.Run("installs missing version when prompt is accepted", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "prompt")
+		var stdErr strings.Builder
+
+		assert.True(t, autoInstall(logger, conf, "dummy", projectDir, strings.NewReader("\n"), &stdErr))
+		assert.Contains(t, stdErr.String(), testPluginName+" 1.1.0 is not installed. Install it now? [Y/n]")
+		assert.True(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+
+	t.Run("installs nothing when prompt is refused", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "prompt")
+		var stdErr strings.Builder
+
+		assert.False(t, autoInstall(logger, conf, "dummy", projectDir, strings.NewReader("n\n"), &stdErr))
+		assert.Contains(t, stdErr.String(), "Install it now? [Y/n]")
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+
+	t.Run("installs nothing in prompt mode without terminal", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "prompt")
+
+		assert.False(t, autoInstall(logger, conf, "dummy", projectDir, nil, io.Discard))
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+
+	t.Run("installs nothing when auto_install is no", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "no")
+
+		assert.False(t, autoInstall(logger, conf, "dummy", projectDir, strings.NewReader("y\n"), io.Discard))
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+
+	t.Run("installs nothing when installs lock is held", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "yes")
+		held, err := lock.Acquire(conf.DataDir, lock.Installs, 0, nil)
+		assert.Nil(t, err)
+		defer held.Release()
+		os.Unsetenv(lock.TokenEnv(lock.Installs))
+
+		assert.False(t, autoInstall(logger, conf, "dummy", projectDir, nil, io.Discard))
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+
+	t.Run("installs nothing when run from plugin callback", func(t *testing.T) {
+		conf, plugin, projectDir := generateConfig(t, "yes")
+		t.Setenv(lock.TokenEnv(lock.Installs), "parent")
+
+		assert.False(t, autoInstall(logger, conf, "dummy", projectDir, nil, io.Discard))
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.1.0")))
+	})
+}
+
+func TestSanitizesEnv(t *testing.T) {
+	conf := func(settings config.Settings) config.Config {
+		settings.Loaded = true
+		return config.Config{Settings: settings}
+	}
+	enabled := config.Settings{SanitizeEnv: true, AutoInstall: "no"}
+
+	assert.True(t, sanitizesEnv(conf(enabled), "exec"))
+	assert.True(t, sanitizesEnv(conf(enabled), "current"))
+	assert.False(t, sanitizesEnv(conf(enabled), "install"))
+	assert.False(t, sanitizesEnv(conf(config.Settings{AutoInstall: "no"}), "exec"))
+	assert.False(t, sanitizesEnv(conf(config.Settings{SanitizeEnv: true, AutoInstall: "yes"}), "exec"))
+	assert.True(t, sanitizesEnv(conf(config.Settings{SanitizeEnv: true, AutoInstall: "prompt"}), "current"))
+}
+
+func TestToolPlugins(t *testing.T) {
+	allPlugins := []plugins.Plugin{{Name: "acme/nodejs"}, {Name: "python"}, {Name: "other/nodejs"}}
+
+	assert.Equal(t, map[string]string{"nodejs": "acme/nodejs", "python": "python"}, toolPlugins(allPlugins))
+}
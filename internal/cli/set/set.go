@@ -11,6 +11,7 @@ import (
 
 	"github.com/asdf-vm/asdf/internal/config"
 	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/projects"
 	"github.com/asdf-vm/asdf/internal/toolversions"
 	"github.com/asdf-vm/asdf/internal/versions"
 )
@@ -34,13 +35,13 @@ func Main(_ io.Writer, stderr io.Writer, args []string, home bool, parent bool,
 		return printError(stderr, fmt.Sprintf("error loading config: %s", err))
 	}
 
+	plugin := plugins.New(conf, args[0])
 	resolvedVersions := []string{}
 
 	for _, version := range args[1:] {
 		parsedVersion := toolversions.ParseFromCliArg(version)
 		if parsedVersion.Type == "latest" {
-			plugin := plugins.New(conf, args[0])
-			resolvedVersion, err := versions.Latest(plugin, parsedVersion.Value)
+			resolvedVersion, err := versions.Latest(conf, plugin, parsedVersion.Value)
 			if err != nil {
 				return fmt.Errorf("unable to resolve latest version for %s", plugin.Name)
 			}
@@ -50,12 +51,12 @@ func Main(_ io.Writer, stderr io.Writer, args []string, home bool, parent bool,
 		resolvedVersions = append(resolvedVersions, version)
 	}
 
-	tv := toolversions.ToolVersions{Name: args[0], Versions: resolvedVersions}
+	tv := toolversions.ToolVersions{Name: plugin.Name, Versions: resolvedVersions}
 
 	if home {
 		homeDir, err := homeFunc()
 		if err != nil {
-			return err
+			return printError(stderr, err.Error())
 		}
 
 		filepath := filepath.Join(homeDir, conf.DefaultToolVersionsFilename)
@@ -80,14 +81,27 @@ func Main(_ io.Writer, stderr io.Writer, args []string, home bool, parent bool,
 
 		err = toolversions.WriteToolVersionsToFile(path, []toolversions.ToolVersions{tv})
 		if err != nil {
-			err = printError(stderr, fmt.Sprintf("error writing version file: %s", err))
+			return printError(stderr, fmt.Sprintf("error writing version file: %s", err))
 		}
-		return err
+		recordProject(conf, stderr, filepath.Dir(path))
+		return nil
 	}
 
 	// Write new file in current dir
 	filepath := filepath.Join(currentDir, conf.DefaultToolVersionsFilename)
-	return toolversions.WriteToolVersionsToFile(filepath, []toolversions.ToolVersions{tv})
+	if err := toolversions.WriteToolVersionsToFile(filepath, []toolversions.ToolVersions{tv}); err != nil {
+		return err
+	}
+	recordProject(conf, stderr, currentDir)
+	return nil
+}
+
+// recordProject adds the directory to the index of known projects. Versions
+// set in the home directory aren't recorded as they apply everywhere.
+func recordProject(conf config.Config, stderr io.Writer, directory string) {
+	if err := projects.Record(conf, directory); err != nil {
+		fmt.Fprintf(stderr, "unable to record project %s: %s\n", directory, err)
+	}
 }
 
 func printError(stderr io.Writer, msg string) error {
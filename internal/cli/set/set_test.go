@@ -6,10 +6,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/asdf-vm/asdf/internal/projects"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestAll(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("ASDF_DATA_DIR", dataDir)
 	homeFunc := func() (string, error) {
 		return "", nil
 	}
@@ -96,6 +99,19 @@ func TestAll(t *testing.T) {
 		assert.Equal(t, "lua 5.2.3\n", string(bytes))
 	})
 
+	t.Run("records directory as a known project", func(t *testing.T) {
+		stdout, stderr := buildOutputs()
+		dir := t.TempDir()
+		assert.Nil(t, os.Chdir(dir))
+
+		err := Main(&stdout, &stderr, []string{"lua", "5.2.3"}, false, false, homeFunc)
+		assert.Nil(t, err)
+
+		directories, err := projects.List(dataDir)
+		assert.Nil(t, err)
+		assert.Contains(t, directories, dir)
+	})
+
 	t.Run("sets version in current directory only once", func(t *testing.T) {
 		stdout, stderr := buildOutputs()
 		dir := t.TempDir()
@@ -145,3 +145,17 @@ _asdf() {
 }
 
 complete -F _asdf asdf
+
+# Complete the arguments of shimmed commands with the complete callback of the
+# plugin providing the current version, falling back to file names
+_asdf_shim() {
+  local IFS=$'\n'
+  # shellcheck disable=SC2207
+  COMPREPLY=($(asdf complete "${COMP_WORDS[@]:0:COMP_CWORD+1}" 2>/dev/null))
+  return 0
+}
+
+# Commands that already have a completion defined keep it
+while IFS= read -r shim; do
+  complete -p "$shim" &>/dev/null || complete -o default -F _asdf_shim "$shim"
+done < <(_asdf_list_shims)
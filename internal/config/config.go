@@ -3,10 +3,14 @@
 package config
 
 import (
+	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -18,6 +22,63 @@ const (
 	configFileDefault                  = "~/.asdfrc"
 	defaultToolVersionsFilenameDefault = ".tool-versions"
 	defaultPluginIndexURL              = "https://github.com/asdf-vm/asdf-plugins.git"
+	deprecatedVersionsDefault          = "warn"
+	conflictingManagersDefault         = "warn"
+	installsBackendDefault             = "directory"
+	resolutionCacheDefault             = "off"
+	hookFailurePolicyDefault           = "auto"
+	hookOutputDefault                  = "inherit"
+	autoInstallDefault                 = "no"
+	remoteFallbackDefault              = "no"
+	listAllCacheDurationDefault        = 60
+	promptBudgetDefault                = 20
+	notifyThresholdDefault             = 60
+	symlinkResolutionDefault           = "logical"
+)
+
+// SymlinkResolutionModes are the values of the symlink_resolution setting. The
+// first is the default.
+var SymlinkResolutionModes = []string{symlinkResolutionDefault, "physical", "both"}
+
+// excludeInstallsValues are the kinds of installs that can be excluded from
+// the installed versions. All of them are excluded by default.
+var excludeInstallsValues = []string{"incomplete", "quarantined", "platform"}
+
+// maintainTasksValues are the tasks `asdf maintain` can run. All of them are
+// run by default. Other values of the maintain_tasks setting are kept, so
+// `asdf maintain` reports them as unknown tasks.
+var maintainTasksValues = []string{"refresh", "prune", "repack", "verify", "tmp"}
+
+// lintRulesValues are the rules `asdf lint` checks. All of them are checked by
+// default.
+var lintRulesValues = []string{"missing-tool-versions", "unknown-plugin", "policy", "deprecated", "legacy-mismatch"}
+
+// notifyChannelValues are the channels notifications of long operations can be
+// sent to. None are used by default.
+var notifyChannelValues = []string{"bell", "desktop", "webhook"}
+
+// matchStrategyValues are the strategies that can be set for a tool in the
+// [match] section to pick the installed version used for the versions set
+var matchStrategyValues = []string{"exact", "ignore-patch", "ignore-minor", "range", "latest"}
+
+// ignoreKeys map the asdfrc keys listing tools, or `*` for all tools, to the
+// match strategy they select, from lowest to highest precedence. They mirror
+// the ASDF_IGNORE_PATCH, ASDF_IGNORE_MINOR and ASDF_IGNORE_VERSION environment
+// variables.
+var ignoreKeys = []struct {
+	key      string
+	strategy string
+}{
+	{key: "ignore_patch", strategy: "ignore-patch"},
+	{key: "ignore_minor", strategy: "ignore-minor"},
+	{key: "ignore_version", strategy: "latest"},
+}
+
+// The spaces before the `=` following a tool name in an ignore list, and those
+// after it when a range follows rather than the next tool, are dropped
+var (
+	ignoreEqualsRegex = regexp.MustCompile(`\s+=`)
+	ignoreRangeRegex  = regexp.MustCompile(`=\s+([<>=!*]|v?[0-9])`)
 )
 
 /* PluginRepoCheckDuration represents the remote plugin repo check duration
@@ -51,6 +112,57 @@ type Settings struct {
 	PluginRepositoryLastCheckDuration PluginRepoCheckDuration
 	DisablePluginShortNameRepository  bool
 	Concurrency                       string
+	SharedInstallDir                  string
+	Locale                            string
+	DeprecatedVersions                string
+	ConflictingManagers               string
+	InstallsBackend                   string
+	ResolutionCache                   string
+	HookFailurePolicy                 string
+	HookOutput                        string
+	AutoInstall                       string
+	RemoteFallback                    string
+	ListAllCacheDuration              int
+	MaintainDownloadRetention         int
+	PromptBudget                      int
+	NotifyThreshold                   int
+	NotifyWebhook                     string
+	SymlinkResolution                 string
+	ToolVersionsPath                  string
+	SystemFallback                    bool
+	SubstitutionNotice                bool
+	SanitizeEnv                       bool
+	LatestRemote                      bool
+	EnvFiles                          bool
+	WorktreeFallback                  bool
+	Launchers                         bool
+	VersionedShims                    bool
+	AuditLog                          bool
+	ExcludeInstalls                   []string
+	MaintainTasks                     []string
+	LintRules                         []string
+	BoundaryMarkers                   []string
+	AllowPrereleases                  []string
+	NotifyChannels                    []string
+	Groups                            map[string][]string
+	VersionFiles                      map[string][]string
+	Patches                           map[string][]string
+	Policies                          map[string][]string
+	DefaultVersions                   map[string][]string
+	ExecLimits                        map[string]map[string]string
+	MatchStrategies                   map[string]string
+	MatchRanges                       map[string]string
+	ToolAliases                       map[string]string
+	// Flags holds the values of the flags set in the asdfrc, see Flags
+	Flags map[string]string
+	// ProjectFiles are the project asdfrc files applied by ForDirectory,
+	// nearest first
+	ProjectFiles []string
+	// KeyErrors holds the errors of the keys that couldn't be loaded, those
+	// whose template variables couldn't be expanded, see Expand, and flags set
+	// to a value they don't accept, by key name, prefixed with the section and
+	// a dot for keys in sections. The keys are left unset.
+	KeyErrors map[string]error
 }
 
 func defaultConfig(dataDir, configFile string) *Config {
@@ -71,6 +183,46 @@ func defaultSettings() *Settings {
 		PluginRepositoryLastCheckDuration: pluginRepoCheckDurationDefault,
 		DisablePluginShortNameRepository:  false,
 		Concurrency:                       getConcurrency("auto"),
+		DeprecatedVersions:                deprecatedVersionsDefault,
+		ConflictingManagers:               conflictingManagersDefault,
+		InstallsBackend:                   installsBackendDefault,
+		ResolutionCache:                   resolutionCacheDefault,
+		HookFailurePolicy:                 hookFailurePolicyDefault,
+		HookOutput:                        hookOutputDefault,
+		AutoInstall:                       autoInstallDefault,
+		RemoteFallback:                    remoteFallbackDefault,
+		ListAllCacheDuration:              listAllCacheDurationDefault,
+		MaintainDownloadRetention:         0,
+		PromptBudget:                      promptBudgetDefault,
+		NotifyThreshold:                   notifyThresholdDefault,
+		NotifyWebhook:                     "",
+		SymlinkResolution:                 getSymlinkResolution(symlinkResolutionDefault),
+		ToolVersionsPath:                  getToolVersionsPath(""),
+		SystemFallback:                    false,
+		SubstitutionNotice:                false,
+		SanitizeEnv:                       false,
+		LatestRemote:                      false,
+		EnvFiles:                          false,
+		WorktreeFallback:                  false,
+		Launchers:                         false,
+		VersionedShims:                    false,
+		AuditLog:                          false,
+		ExcludeInstalls:                   slices.Clone(excludeInstallsValues),
+		MaintainTasks:                     slices.Clone(maintainTasksValues),
+		LintRules:                         slices.Clone(lintRulesValues),
+		BoundaryMarkers:                   []string{},
+		AllowPrereleases:                  []string{},
+		NotifyChannels:                    []string{},
+		Groups:                            map[string][]string{},
+		VersionFiles:                      map[string][]string{},
+		Patches:                           map[string][]string{},
+		Policies:                          map[string][]string{},
+		DefaultVersions:                   map[string][]string{},
+		ExecLimits:                        map[string]map[string]string{},
+		MatchStrategies:                   map[string]string{},
+		MatchRanges:                       map[string]string{},
+		ToolAliases:                       map[string]string{},
+		Flags:                             map[string]string{},
 	}
 }
 
@@ -88,23 +240,65 @@ func newPluginRepoCheckDuration(checkDuration string) PluginRepoCheckDuration {
 	return PluginRepoCheckDuration{Every: every}
 }
 
-// LoadConfig builds the Config struct from environment variables
+// NoDataDirError is returned when HOME is not set and the data directory isn't
+// set by ASDF_DATA_DIR or XDG_DATA_HOME either
+type NoDataDirError struct{}
+
+func (e NoDataDirError) Error() string {
+	return "unable to determine the asdf data directory as HOME is not set, set ASDF_DATA_DIR to the directory asdf should use"
+}
+
+// HomeRequiredError is returned when HOME is not set and a path in an
+// environment variable starts with ~
+type HomeRequiredError struct {
+	variable string
+}
+
+func (e HomeRequiredError) Error() string {
+	return fmt.Sprintf("%s starts with ~ but HOME is not set, use an absolute path instead", e.variable)
+}
+
+// LoadConfig builds the Config struct from environment variables. HOME is
+// optional, as containers and services often run without one. When it isn't
+// set the data directory must be given by ASDF_DATA_DIR or XDG_DATA_HOME, and
+// the asdfrc is only read if ASDF_CONFIG_FILE or XDG_CONFIG_HOME are set.
 func LoadConfig() (Config, error) {
 	config := defaultConfig(dataDirDefault, configFileDefault)
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return Config{}, err
+		homeDir = ""
 	}
 
 	configFile := os.Getenv("ASDF_CONFIG_FILE")
 	if configFile != "" {
 		config.ConfigFile = configFile
+	} else if homeDir == "" {
+		config.ConfigFile = ""
+		if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+			config.ConfigFile = filepath.Join(xdgConfigHome, "asdf", "asdfrc")
+		}
 	}
 
 	dataDir := os.Getenv("ASDF_DATA_DIR")
 	if dataDir != "" {
 		config.DataDir = dataDir
+	} else if homeDir == "" {
+		xdgDataHome := os.Getenv("XDG_DATA_HOME")
+		if xdgDataHome == "" {
+			return Config{}, NoDataDirError{}
+		}
+		config.DataDir = filepath.Join(xdgDataHome, "asdf")
+	}
+
+	if homeDir == "" {
+		if strings.HasPrefix(config.DataDir, "~") {
+			return Config{}, HomeRequiredError{variable: "ASDF_DATA_DIR"}
+		}
+
+		if strings.HasPrefix(config.ConfigFile, "~") {
+			return Config{}, HomeRequiredError{variable: "ASDF_CONFIG_FILE"}
+		}
 	}
 
 	versionFilename := os.Getenv("ASDF_TOOL_VERSIONS_FILENAME")
@@ -121,7 +315,9 @@ func LoadConfig() (Config, error) {
 
 	config.Home = homeDir
 	config.DataDir = normalizePath(homeDir, config.DataDir)
-	config.ConfigFile = normalizePath(homeDir, config.ConfigFile)
+	if config.ConfigFile != "" {
+		config.ConfigFile = normalizePath(homeDir, config.ConfigFile)
+	}
 
 	return *config, nil
 }
@@ -184,6 +380,533 @@ func (c *Config) Concurrency() (string, error) {
 	return c.Settings.Concurrency, nil
 }
 
+// SharedInstallDir returns the path of the shared install directory from the
+// asdfrc file, or an empty string if no shared directory is configured.
+func (c *Config) SharedInstallDir() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.SettingError("shared_install_dir"); err != nil {
+		return "", err
+	}
+
+	return c.Settings.SharedInstallDir, nil
+}
+
+// Locale returns the locale messages are printed in from the asdfrc file, or an
+// empty string if the locale is taken from the environment
+func (c *Config) Locale() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return "", err
+	}
+
+	return c.Settings.Locale, nil
+}
+
+// DeprecatedVersions returns how deprecated tool versions are treated, one of
+// `warn`, `error` or `ignore`
+func (c *Config) DeprecatedVersions() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return deprecatedVersionsDefault, err
+	}
+
+	return c.Settings.DeprecatedVersions, nil
+}
+
+// ConflictingManagers returns whether `asdf exec` warns about other version
+// managers taking precedence over asdf on PATH, one of `warn` or `ignore`
+func (c *Config) ConflictingManagers() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return conflictingManagersDefault, err
+	}
+
+	return c.Settings.ConflictingManagers, nil
+}
+
+// InstallsBackend returns how installed versions are listed, one of
+// `directory`, which reads the install directories, or `index`, which reads an
+// index file kept up to date as versions are installed and uninstalled
+func (c *Config) InstallsBackend() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return installsBackendDefault, err
+	}
+
+	return c.Settings.InstallsBackend, nil
+}
+
+// ResolutionCache returns where the versions found by walking up the directory
+// tree are cached, one of `off`, `memory`, which caches them for the lifetime
+// of the process, or `disk`, which also caches them between processes
+func (c *Config) ResolutionCache() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return resolutionCacheDefault, err
+	}
+
+	return c.Settings.ResolutionCache, nil
+}
+
+// HookFailurePolicy returns what a failing hook does to the command it runs
+// for, one of `auto`, `fail`, `warn` or `ignore`. With `auto`, hooks of plugin
+// add, update and remove are ignored when they fail, as they always were, and
+// other hooks fail the command.
+func (c *Config) HookFailurePolicy() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return hookFailurePolicyDefault, err
+	}
+
+	return c.Settings.HookFailurePolicy, nil
+}
+
+// HookOutput returns where the output of hooks goes, either `inherit`, the
+// output of the command they run for, or `log`, the hook log
+func (c *Config) HookOutput() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return hookOutputDefault, err
+	}
+
+	return c.Settings.HookOutput, nil
+}
+
+// AutoInstall returns whether `asdf exec` installs the version set for a tool
+// when it isn't installed, rather than failing, either `no`, `prompt`, asking
+// first when run from a terminal, or `yes`, installing it without asking
+func (c *Config) AutoInstall() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return autoInstallDefault, err
+	}
+
+	return c.Settings.AutoInstall, nil
+}
+
+// RemoteFallback returns what `asdf install` does with a constraint, like
+// `>=18`, that no installed version satisfies, either `no`, installing it as
+// it is set, `report`, reporting the newest version available satisfying it,
+// or `install`, installing that version
+func (c *Config) RemoteFallback() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return remoteFallbackDefault, err
+	}
+
+	return c.Settings.RemoteFallback, nil
+}
+
+// SymlinkResolution returns which paths of a directory reached through a
+// symlink are searched for versions, one of `logical`, the path as given,
+// `physical`, the path with every symlink resolved, or `both`, the physical
+// path followed by the logical path
+func (c *Config) SymlinkResolution() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return symlinkResolutionDefault, err
+	}
+
+	return c.Settings.SymlinkResolution, nil
+}
+
+// ToolVersionsPath returns the absolute path of the version file, or of the
+// directory whose version files, versions are resolved from instead of the
+// current directory and its parents. It is empty when versions are resolved
+// from the current directory.
+func (c *Config) ToolVersionsPath() (string, error) {
+	err := c.loadSettings()
+	if err != nil || c.Settings.ToolVersionsPath == "" {
+		return "", err
+	}
+
+	return filepath.Abs(normalizePath(c.Home, c.Settings.ToolVersionsPath))
+}
+
+// ListAllCacheDuration returns the number of minutes versions listed by a
+// plugin's list-all callback are cached for. Zero disables caching.
+func (c *Config) ListAllCacheDuration() (int, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return listAllCacheDurationDefault, err
+	}
+
+	return c.Settings.ListAllCacheDuration, nil
+}
+
+// PromptBudget returns the number of milliseconds `asdf current --fast` may
+// take before reporting the versions it hasn't looked up yet as stale
+func (c *Config) PromptBudget() (int, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return promptBudgetDefault, err
+	}
+
+	return c.Settings.PromptBudget, nil
+}
+
+// SystemFallback returns true if shims for tools without a version set should
+// run the next matching executable on PATH rather than failing
+func (c *Config) SystemFallback() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.SystemFallback, nil
+}
+
+// SubstitutionNotice returns true if `asdf exec` should print a notice when it
+// runs an installed version other than the ones set, because of
+// ASDF_IGNORE_PATCH, ASDF_IGNORE_MINOR or ASDF_IGNORE_VERSION
+func (c *Config) SubstitutionNotice() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.SubstitutionNotice, nil
+}
+
+// SanitizeEnv returns true if ASDF_<TOOL>_VERSION variables inherited from the
+// environment should be checked, and ignored when unusable, before versions
+// are resolved
+func (c *Config) SanitizeEnv() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.SanitizeEnv, nil
+}
+
+// LatestRemote returns true if `asdf install` should ask the plugin for the
+// newest version matching `latest` and `latest:<prefix>` versions even when an
+// installed version already matches
+func (c *Config) LatestRemote() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.LatestRemote, nil
+}
+
+// EnvFiles returns true if versions are also looked up in ASDF_<TOOL>_VERSION
+// assignments in the .asdf.env and .env files of the directory and its parents
+func (c *Config) EnvFiles() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.EnvFiles, nil
+}
+
+// WorktreeFallback returns true if versions not set in a linked git worktree
+// are looked up in the root version files of the main worktree
+func (c *Config) WorktreeFallback() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.WorktreeFallback, nil
+}
+
+// Launchers returns whether reshimming also maintains the launchers directory,
+// holding a symlink named after each executable and version of the installed
+// tools
+func (c *Config) Launchers() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.Launchers, nil
+}
+
+// VersionedShims returns whether reshimming also generates shims named
+// <executable>@<version> and <executable>@<major>.<minor> that run a specific
+// version of the tool
+func (c *Config) VersionedShims() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	return c.Settings.VersionedShims, nil
+}
+
+// AuditLog returns whether every shim execution is recorded in the audit log
+func (c *Config) AuditLog() (bool, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return false, err
+	}
+
+	// A value that can't be expanded is an error rather than turning the
+	// audit log off
+	if err := c.SettingError("audit_log"); err != nil {
+		return false, err
+	}
+
+	return c.Settings.AuditLog, nil
+}
+
+// ExcludeInstalls returns the kinds of installs that aren't considered
+// installed, any of `incomplete`, `quarantined` and `platform`
+func (c *Config) ExcludeInstalls() ([]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return slices.Clone(excludeInstallsValues), err
+	}
+
+	return c.Settings.ExcludeInstalls, nil
+}
+
+// NotifyChannels returns the channels notified when a long operation, such as
+// an install, finishes, any of `bell`, `desktop` and `webhook`
+func (c *Config) NotifyChannels() ([]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return c.Settings.NotifyChannels, nil
+}
+
+// NotifyThreshold returns the number of seconds an operation must take before
+// its end is notified
+func (c *Config) NotifyThreshold() (int, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return notifyThresholdDefault, err
+	}
+
+	return c.Settings.NotifyThreshold, nil
+}
+
+// NotifyWebhook returns the URL notifications are posted to by the `webhook`
+// channel
+func (c *Config) NotifyWebhook() (string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return "", err
+	}
+
+	return c.Settings.NotifyWebhook, nil
+}
+
+// MaintainTasks returns the tasks run by `asdf maintain`, any of `refresh`,
+// `prune`, `repack`, `verify` and `tmp`. Unknown tasks are returned as they
+// are set.
+func (c *Config) MaintainTasks() ([]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return slices.Clone(maintainTasksValues), err
+	}
+
+	return c.Settings.MaintainTasks, nil
+}
+
+// MaintainDownloadRetention returns the number of days the prune task of
+// `asdf maintain` keeps downloads of installed versions for. Zero keeps them
+// until the version is uninstalled.
+func (c *Config) MaintainDownloadRetention() (int, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.Settings.MaintainDownloadRetention, nil
+}
+
+// LintRules returns the rules checked by `asdf lint`, any of
+// `missing-tool-versions`, `unknown-plugin`, `policy`, `deprecated` and
+// `legacy-mismatch`
+func (c *Config) LintRules() ([]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return slices.Clone(lintRulesValues), err
+	}
+
+	return c.Settings.LintRules, nil
+}
+
+// BoundaryMarkers returns the names of the files and directories, such as
+// `.git`, marking the directories version files aren't looked up above. No
+// markers are set by default.
+func (c *Config) BoundaryMarkers() ([]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return c.Settings.BoundaryMarkers, nil
+}
+
+// AllowPrereleases returns the names of the tools, or `*` for all tools, whose
+// alpha, beta, release candidate and other pre-release versions may be picked
+// for `latest`, wildcard versions and match strategies. None are by default.
+func (c *Config) AllowPrereleases() ([]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return c.Settings.AllowPrereleases, nil
+}
+
+// Policies returns the version constraints defined in the [policy] section of
+// the asdfrc, mapping each tool name to the constraints its versions must
+// satisfy
+func (c *Config) Policies() (map[string][]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string][]string{}, err
+	}
+
+	return c.Settings.Policies, nil
+}
+
+// DefaultVersions returns the versions defined in the [default_versions]
+// section of the asdfrc, mapping each tool name to the versions used when none
+// are set for the tool
+func (c *Config) DefaultVersions() (map[string][]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string][]string{}, err
+	}
+
+	return c.Settings.DefaultVersions, nil
+}
+
+// ExecLimits returns the process attributes set in the [exec] section of the
+// asdfrc, mapping each tool name to the attributes, such as `umask` or
+// `nofile`, and their values, applied when running the tool's executables
+func (c *Config) ExecLimits() (map[string]map[string]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string]map[string]string{}, err
+	}
+
+	return c.Settings.ExecLimits, nil
+}
+
+// MatchStrategies returns the strategies defined in the [match] section of the
+// asdfrc, mapping each tool name to the name of the strategy used to pick the
+// installed version matching the versions set for it. Tools listed by the
+// ignore_patch, ignore_minor and ignore_version keys are included unless the
+// [match] section sets them. The `*` key holds the strategy of every other
+// tool.
+func (c *Config) MatchStrategies() (map[string]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	return c.Settings.MatchStrategies, nil
+}
+
+// MatchRanges returns the ranges the ignore_patch, ignore_minor and
+// ignore_version keys of the asdfrc limit the strategies they select to, like
+// `20.*` for `nodejs=20.*`, mapping each tool name, or `*`, to its range. Tools
+// listed without a range, or whose strategy is set in the [match] section,
+// aren't included.
+func (c *Config) MatchRanges() (map[string]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	return c.Settings.MatchRanges, nil
+}
+
+// ToolAliases returns the aliases defined in the [tool_aliases] section of the
+// asdfrc, mapping other names a tool is known by, such as `node` or `golang`,
+// to the name of its plugin
+func (c *Config) ToolAliases() (map[string]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	return c.Settings.ToolAliases, nil
+}
+
+// Groups returns the tool groups defined in the [groups] section of the asdfrc,
+// mapping each group name to the names of the tools in it
+func (c *Config) Groups() (map[string][]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string][]string{}, err
+	}
+
+	return c.Settings.Groups, nil
+}
+
+// VersionFiles returns the version files defined in the [version_files]
+// section of the asdfrc, mapping each tool name to the files its versions are
+// looked up in within each directory, in order
+func (c *Config) VersionFiles() (map[string][]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string][]string{}, err
+	}
+
+	return c.Settings.VersionFiles, nil
+}
+
+// Patches returns the patches defined in the [patches] section of the asdfrc,
+// mapping each tool name, or `<tool>@<version>` for the versions matching the
+// version, to the patches applied to its source before it is built. Each patch
+// is a path or URL optionally followed by `#sha256=<checksum>`. Paths are
+// absolute, relative paths in the asdfrc are relative to its directory.
+func (c *Config) Patches() (map[string][]string, error) {
+	err := c.loadSettings()
+	if err != nil {
+		return map[string][]string{}, err
+	}
+
+	return c.Settings.Patches, nil
+}
+
+// SettingError returns the error loading the key of the asdfrc, if any, see
+// Settings.KeyErrors
+func (c *Config) SettingError(key string) error {
+	err := c.loadSettings()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Settings.KeyErrors[key]; err != nil {
+		return fmt.Errorf("%s: %s: %w", c.ConfigFile, key, err)
+	}
+
+	return nil
+}
+
+// SettingsErrors returns the errors loading every key of the asdfrc, sorted by
+// key, see SettingError. The error loading the
+// asdfrc itself is returned by every setting instead.
+func (c *Config) SettingsErrors() []error {
+	if err := c.loadSettings(); err != nil {
+		return nil
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(c.Settings.KeyErrors)) {
+		errs = append(errs, c.SettingError(key))
+	}
+
+	return errs
+}
+
 // GetHook returns a hook command from config if it is there
 func (c *Config) GetHook(hook string) (string, error) {
 	err := c.loadSettings()
@@ -203,6 +926,11 @@ func (c *Config) loadSettings() error {
 		return nil
 	}
 
+	if c.ConfigFile == "" {
+		c.Settings = *defaultSettings()
+		return nil
+	}
+
 	settings, err := loadSettings(c.ConfigFile)
 
 	c.Settings = settings
@@ -229,12 +957,15 @@ func normalizePath(homeDir string, path string) string {
 func loadSettings(asdfrcPath string) (Settings, error) {
 	settings := defaultSettings()
 
-	// asdfrc is effectively formatted as ini
-	config, err := ini.Load(asdfrcPath)
+	// asdfrc is effectively formatted as ini. Inline comments need a space
+	// before the `#` so values such as `package.json#engines.node` are kept.
+	config, err := ini.LoadSources(ini.LoadOptions{SpaceBeforeInlineComment: true}, asdfrcPath)
 	if err != nil {
 		return *settings, err
 	}
 
+	settings.KeyErrors = expandValues(config)
+
 	mainConf := config.Section("")
 
 	settings.Raw = mainConf
@@ -245,6 +976,184 @@ func loadSettings(asdfrcPath string) (Settings, error) {
 	boolOverride(&settings.LegacyVersionFile, mainConf, "legacy_version_file")
 	boolOverride(&settings.AlwaysKeepDownload, mainConf, "always_keep_download")
 	boolOverride(&settings.DisablePluginShortNameRepository, mainConf, "disable_plugin_short_name_repository")
+	boolOverride(&settings.SystemFallback, mainConf, "system_fallback")
+	boolOverride(&settings.SubstitutionNotice, mainConf, "substitution_notice")
+	boolOverride(&settings.SanitizeEnv, mainConf, "sanitize_env")
+	boolOverride(&settings.LatestRemote, mainConf, "latest_remote")
+	boolOverride(&settings.EnvFiles, mainConf, "env_files")
+	boolOverride(&settings.WorktreeFallback, mainConf, "worktree_fallback")
+	boolOverride(&settings.Launchers, mainConf, "launchers")
+	boolOverride(&settings.VersionedShims, mainConf, "versioned_shims")
+	boolOverride(&settings.AuditLog, mainConf, "audit_log")
+
+	settings.SharedInstallDir = mainConf.Key("shared_install_dir").String()
+	settings.Locale = mainConf.Key("locale").String()
+	settings.NotifyWebhook = mainConf.Key("notify_webhook").String()
+
+	switch deprecatedVersions := strings.ToLower(mainConf.Key("deprecated_versions").String()); deprecatedVersions {
+	case "warn", "error", "ignore":
+		settings.DeprecatedVersions = deprecatedVersions
+	}
+
+	switch conflictingManagers := strings.ToLower(mainConf.Key("conflicting_managers").String()); conflictingManagers {
+	case "warn", "ignore":
+		settings.ConflictingManagers = conflictingManagers
+	}
+
+	switch installsBackend := strings.ToLower(mainConf.Key("installs_backend").String()); installsBackend {
+	case "directory", "index":
+		settings.InstallsBackend = installsBackend
+	}
+
+	switch resolutionCache := strings.ToLower(mainConf.Key("resolution_cache").String()); resolutionCache {
+	case "off", "memory", "disk":
+		settings.ResolutionCache = resolutionCache
+	}
+
+	switch hookFailurePolicy := strings.ToLower(mainConf.Key("hook_failure_policy").String()); hookFailurePolicy {
+	case "auto", "fail", "warn", "ignore":
+		settings.HookFailurePolicy = hookFailurePolicy
+	}
+
+	switch hookOutput := strings.ToLower(mainConf.Key("hook_output").String()); hookOutput {
+	case "inherit", "log":
+		settings.HookOutput = hookOutput
+	}
+
+	switch autoInstall := strings.ToLower(mainConf.Key("auto_install").String()); autoInstall {
+	case "no", "prompt", "yes":
+		settings.AutoInstall = autoInstall
+	case "false":
+		settings.AutoInstall = "no"
+	case "true":
+		settings.AutoInstall = "yes"
+	}
+
+	switch remoteFallback := strings.ToLower(mainConf.Key("remote_fallback").String()); remoteFallback {
+	case "no", "report", "install":
+		settings.RemoteFallback = remoteFallback
+	}
+
+	settings.SymlinkResolution = getSymlinkResolution(mainConf.Key("symlink_resolution").String())
+	settings.ToolVersionsPath = getToolVersionsPath(mainConf.Key("tool_versions_path").String())
+
+	if duration, err := mainConf.Key("list_all_cache_duration").Int(); err == nil && duration >= 0 {
+		settings.ListAllCacheDuration = duration
+	}
+
+	if days, err := mainConf.Key("maintain_download_retention").Int(); err == nil && days >= 0 {
+		settings.MaintainDownloadRetention = days
+	}
+
+	if budget, err := mainConf.Key("prompt_budget").Int(); err == nil && budget > 0 {
+		settings.PromptBudget = budget
+	}
+
+	if threshold, err := mainConf.Key("notify_threshold").Int(); err == nil && threshold >= 0 {
+		settings.NotifyThreshold = threshold
+	}
+
+	if key, err := mainConf.GetKey("exclude_installs"); err == nil {
+		settings.ExcludeInstalls = []string{}
+		for _, value := range strings.Fields(strings.ToLower(key.String())) {
+			if slices.Contains(excludeInstallsValues, value) {
+				settings.ExcludeInstalls = append(settings.ExcludeInstalls, value)
+			}
+		}
+	}
+
+	if key, err := mainConf.GetKey("maintain_tasks"); err == nil {
+		settings.MaintainTasks = []string{}
+		settings.MaintainTasks = append(settings.MaintainTasks, strings.Fields(strings.ToLower(key.String()))...)
+	}
+
+	if key, err := mainConf.GetKey("notify_channels"); err == nil {
+		for _, value := range strings.Fields(strings.ToLower(key.String())) {
+			if slices.Contains(notifyChannelValues, value) {
+				settings.NotifyChannels = append(settings.NotifyChannels, value)
+			}
+		}
+	}
+
+	if key, err := mainConf.GetKey("boundary_markers"); err == nil {
+		settings.BoundaryMarkers = strings.Fields(key.String())
+	}
+
+	if key, err := mainConf.GetKey("allow_prereleases"); err == nil {
+		settings.AllowPrereleases = strings.Fields(key.String())
+	}
+
+	if key, err := mainConf.GetKey("lint_rules"); err == nil {
+		settings.LintRules = []string{}
+		for _, value := range strings.Fields(strings.ToLower(key.String())) {
+			if slices.Contains(lintRulesValues, value) {
+				settings.LintRules = append(settings.LintRules, value)
+			}
+		}
+	}
+
+	for _, flag := range flags {
+		key, err := mainConf.GetKey(flag.Name)
+		if err != nil {
+			continue
+		}
+
+		value := strings.ToLower(key.String())
+		if !slices.Contains(flag.Values, value) {
+			settings.KeyErrors[flag.Name] = InvalidFlagValueError{flag: flag, value: key.String()}
+			continue
+		}
+		settings.Flags[flag.Name] = value
+	}
+
+	for _, key := range config.Section("groups").Keys() {
+		if tools := strings.Fields(key.String()); len(tools) > 0 {
+			settings.Groups[key.Name()] = tools
+		}
+	}
+
+	for _, key := range config.Section("version_files").Keys() {
+		if files := strings.Fields(key.String()); len(files) > 0 {
+			settings.VersionFiles[key.Name()] = files
+		}
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	for _, key := range config.Section("patches").Keys() {
+		for _, patch := range strings.Fields(key.String()) {
+			if !strings.Contains(patch, "://") {
+				file, checksum, hasChecksum := strings.Cut(patch, "#")
+				if file = normalizePath(homeDir, file); !filepath.IsAbs(file) {
+					file, _ = filepath.Abs(filepath.Join(filepath.Dir(asdfrcPath), file))
+				}
+				if patch = file; hasChecksum {
+					patch += "#" + checksum
+				}
+			}
+			settings.Patches[key.Name()] = append(settings.Patches[key.Name()], patch)
+		}
+	}
+
+	for _, key := range config.Section("policy").Keys() {
+		if constraints := strings.Fields(key.String()); len(constraints) > 0 {
+			settings.Policies[key.Name()] = constraints
+		}
+	}
+
+	for _, key := range config.Section("default_versions").Keys() {
+		if versions := strings.Fields(key.String()); len(versions) > 0 {
+			settings.DefaultVersions[key.Name()] = versions
+		}
+	}
+
+	for _, key := range config.Section("tool_aliases").Keys() {
+		if plugin := strings.TrimSpace(key.String()); plugin != "" && plugin != key.Name() {
+			settings.ToolAliases[key.Name()] = plugin
+		}
+	}
+
+	matchStrategies(config, settings.MatchStrategies, settings.MatchRanges)
+	execLimits(config, settings.ExecLimits)
 
 	concurrency := strings.ToLower(mainConf.Key("concurrency").String())
 	if concurrency != "" {
@@ -254,6 +1163,40 @@ func loadSettings(asdfrcPath string) (Settings, error) {
 	return *settings, nil
 }
 
+// expandValues replaces the template variables in every value of the asdfrc
+// but hook commands, which are run by a shell that has its own variables. Keys
+// whose values can't be expanded are deleted, so they keep their defaults, and
+// their errors are returned by key, see Settings.KeyErrors.
+func expandValues(config *ini.File) map[string]error {
+	errs := map[string]error{}
+	for _, section := range config.Sections() {
+		for _, key := range section.Keys() {
+			name := key.Name()
+			if section.Name() != ini.DefaultSection {
+				name = section.Name() + "." + name
+			} else if isHookKey(name) {
+				continue
+			}
+
+			value, err := Expand(key.Value())
+			if err != nil {
+				errs[name] = err
+				section.DeleteKey(key.Name())
+				continue
+			}
+			key.SetValue(value)
+		}
+	}
+
+	return errs
+}
+
+// isHookKey returns true if the key of the asdfrc is a hook command, run by
+// hook.Run
+func isHookKey(key string) bool {
+	return strings.HasPrefix(key, "pre_") || strings.HasPrefix(key, "post_") || key == "resolution_missing"
+}
+
 func boolOverride(field *bool, section *ini.Section, key string) {
 	lcYesOrNo := strings.ToLower(section.Key(key).String())
 
@@ -265,6 +1208,105 @@ func boolOverride(field *bool, section *ini.Section, key string) {
 	}
 }
 
+// matchStrategies adds the strategies set by the ignore_patch, ignore_minor and
+// ignore_version keys of the asdfrc to strategies, and the ranges limiting
+// them to ranges, followed by the strategies set in its [match] section, which
+// take precedence and aren't limited to a range
+func matchStrategies(config *ini.File, strategies, ranges map[string]string) {
+	for _, ignore := range ignoreKeys {
+		for tool, limit := range ParseIgnoreList(config.Section("").Key(ignore.key).String()) {
+			strategies[tool] = ignore.strategy
+			ranges[tool] = limit
+			if limit == "" {
+				delete(ranges, tool)
+			}
+		}
+	}
+
+	for _, key := range config.Section("match").Keys() {
+		if strategy := strings.ToLower(key.String()); slices.Contains(matchStrategyValues, strategy) {
+			strategies[key.Name()] = strategy
+			delete(ranges, key.Name())
+		}
+	}
+}
+
+// ParseIgnoreList parses the value of the ignore_patch, ignore_minor and
+// ignore_version keys of the asdfrc and of the ASDF_IGNORE_* environment
+// variables, listing tools, or `*` for all tools, separated by commas or
+// spaces. A tool may be followed by `=` and a range, a version constraint like
+// `20.*`, `18` or `>=3.11`, so its strategy only applies to versions set
+// within the range and only picks installed versions within it:
+//
+//	nodejs=20.*, python = >=3.11 ruby
+//
+// It returns the range of each tool, empty for tools listed without one. When
+// a tool is listed more than once the last entry wins. Entries without a tool
+// name are ignored.
+func ParseIgnoreList(value string) map[string]string {
+	value = strings.ReplaceAll(value, ",", " ")
+	value = ignoreRangeRegex.ReplaceAllString(ignoreEqualsRegex.ReplaceAllString(value, "="), "=$1")
+
+	tools := map[string]string{}
+	for _, entry := range strings.Fields(value) {
+		tool, limit, _ := strings.Cut(entry, "=")
+		if tool == "" || (tool != "*" && strings.ContainsAny(tool, "<>!*")) {
+			continue
+		}
+		tools[tool] = limit
+	}
+
+	return tools
+}
+
+// execLimits adds the attributes set by the `<tool>.<attribute>` keys of the
+// [exec] section of the asdfrc to limits
+func execLimits(config *ini.File, limits map[string]map[string]string) {
+	for _, key := range config.Section("exec").Keys() {
+		tool, attribute, ok := cutLast(key.Name(), ".")
+		if !ok || tool == "" || attribute == "" {
+			continue
+		}
+
+		if limits[tool] == nil {
+			limits[tool] = map[string]string{}
+		}
+		limits[tool][strings.ToLower(attribute)] = strings.TrimSpace(key.String())
+	}
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// getSymlinkResolution returns the symlink resolution mode set by
+// ASDF_SYMLINK_RESOLUTION, which takes precedence, or the given mode, falling
+// back to the default for unknown modes
+func getSymlinkResolution(mode string) string {
+	if modeFromEnv := os.Getenv("ASDF_SYMLINK_RESOLUTION"); modeFromEnv != "" {
+		mode = modeFromEnv
+	}
+
+	mode = strings.ToLower(mode)
+	if !slices.Contains(SymlinkResolutionModes, mode) {
+		return symlinkResolutionDefault
+	}
+	return mode
+}
+
+// getToolVersionsPath returns the path set by ASDF_TOOL_VERSIONS_PATH, which
+// takes precedence, or the given path
+func getToolVersionsPath(path string) string {
+	if pathFromEnv := os.Getenv("ASDF_TOOL_VERSIONS_PATH"); pathFromEnv != "" {
+		return pathFromEnv
+	}
+	return path
+}
+
 func getConcurrency(concurrency string) string {
 	concurrencyFromEnv := strings.ToLower(os.Getenv("ASDF_CONCURRENCY"))
 	if concurrencyFromEnv != "" {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -35,6 +36,53 @@ func TestLoadConfig(t *testing.T) {
 		assert.Equal(t, homeDir+"/some/other/dir", config.DataDir, "DataDir has the wrong value")
 		assert.True(t, strings.HasPrefix(config.ConfigFile, homeDir))
 	})
+
+	t.Run("Without HOME uses ASDF_DATA_DIR and skips asdfrc", func(t *testing.T) {
+		t.Setenv("HOME", "")
+		t.Setenv("XDG_CONFIG_HOME", "")
+		t.Setenv("ASDF_CONFIG_FILE", "")
+		t.Setenv("ASDF_DATA_DIR", "/opt/asdf")
+		config, err := LoadConfig()
+		assert.Nil(t, err)
+
+		assert.Equal(t, "", config.Home)
+		assert.Equal(t, "/opt/asdf", config.DataDir)
+		assert.Equal(t, "", config.ConfigFile)
+
+		concurrency, err := config.Concurrency()
+		assert.Nil(t, err)
+		assert.NotEmpty(t, concurrency)
+	})
+
+	t.Run("Without HOME uses XDG directories", func(t *testing.T) {
+		t.Setenv("HOME", "")
+		t.Setenv("ASDF_CONFIG_FILE", "")
+		t.Setenv("ASDF_DATA_DIR", "")
+		t.Setenv("XDG_DATA_HOME", "/var/lib/service")
+		t.Setenv("XDG_CONFIG_HOME", "/etc/service")
+		config, err := LoadConfig()
+		assert.Nil(t, err)
+
+		assert.Equal(t, "/var/lib/service/asdf", config.DataDir)
+		assert.Equal(t, "/etc/service/asdf/asdfrc", config.ConfigFile)
+	})
+
+	t.Run("Without HOME or data directory returns NoDataDirError", func(t *testing.T) {
+		t.Setenv("HOME", "")
+		t.Setenv("ASDF_DATA_DIR", "")
+		t.Setenv("XDG_DATA_HOME", "")
+		_, err := LoadConfig()
+		assert.IsType(t, NoDataDirError{}, err)
+		assert.ErrorContains(t, err, "set ASDF_DATA_DIR")
+	})
+
+	t.Run("Without HOME and ASDF_DATA_DIR containing a tilde returns HomeRequiredError", func(t *testing.T) {
+		t.Setenv("HOME", "")
+		t.Setenv("ASDF_DATA_DIR", "~/.asdf")
+		_, err := LoadConfig()
+		assert.IsType(t, HomeRequiredError{}, err)
+		assert.ErrorContains(t, err, "ASDF_DATA_DIR starts with ~ but HOME is not set")
+	})
 }
 
 func TestLoadSettings(t *testing.T) {
@@ -62,6 +110,49 @@ func TestLoadSettings(t *testing.T) {
 		assert.Zero(t, settings.PluginRepositoryLastCheckDuration.Every, "PluginRepositoryLastCheckDuration field has wrong value")
 		assert.True(t, settings.DisablePluginShortNameRepository, "DisablePluginShortNameRepository field has wrong value")
 		assert.Equal(t, "5", settings.Concurrency, "Concurrency field has wrong value")
+		assert.Equal(t, "/opt/asdf", settings.SharedInstallDir, "SharedInstallDir field has wrong value")
+		assert.Equal(t, "pt_BR.UTF-8", settings.Locale, "Locale field has wrong value")
+		assert.Equal(t, "error", settings.DeprecatedVersions, "DeprecatedVersions field has wrong value")
+		assert.Equal(t, "ignore", settings.ConflictingManagers, "ConflictingManagers field has wrong value")
+		assert.Equal(t, "index", settings.InstallsBackend, "InstallsBackend field has wrong value")
+		assert.Equal(t, "disk", settings.ResolutionCache, "ResolutionCache field has wrong value")
+		assert.Equal(t, "warn", settings.HookFailurePolicy, "HookFailurePolicy field has wrong value")
+		assert.Equal(t, "log", settings.HookOutput, "HookOutput field has wrong value")
+		assert.Equal(t, "prompt", settings.AutoInstall, "AutoInstall field has wrong value")
+		assert.Equal(t, "report", settings.RemoteFallback, "RemoteFallback field has wrong value")
+		assert.Zero(t, settings.ListAllCacheDuration, "ListAllCacheDuration field has wrong value")
+		assert.Equal(t, 50, settings.PromptBudget, "PromptBudget field has wrong value")
+		assert.Equal(t, "physical", settings.SymlinkResolution, "SymlinkResolution field has wrong value")
+		assert.Equal(t, "/ci/.tool-versions", settings.ToolVersionsPath, "ToolVersionsPath field has wrong value")
+		assert.True(t, settings.SystemFallback, "SystemFallback field has wrong value")
+		assert.True(t, settings.SubstitutionNotice, "SubstitutionNotice field has wrong value")
+		assert.True(t, settings.SanitizeEnv, "SanitizeEnv field has wrong value")
+		assert.True(t, settings.LatestRemote, "LatestRemote field has wrong value")
+		assert.True(t, settings.EnvFiles, "EnvFiles field has wrong value")
+		assert.True(t, settings.WorktreeFallback, "WorktreeFallback field has wrong value")
+		assert.True(t, settings.Launchers, "Launchers field has wrong value")
+		assert.True(t, settings.VersionedShims, "VersionedShims field has wrong value")
+		assert.True(t, settings.AuditLog, "AuditLog field has wrong value")
+		assert.Equal(t, []string{"quarantined"}, settings.ExcludeInstalls, "ExcludeInstalls field has wrong value")
+		assert.Equal(t, []string{"refresh", "tmp"}, settings.MaintainTasks, "MaintainTasks field has wrong value")
+		assert.Equal(t, 30, settings.MaintainDownloadRetention, "MaintainDownloadRetention field has wrong value")
+		assert.Equal(t, []string{"bell", "webhook"}, settings.NotifyChannels, "NotifyChannels field has wrong value")
+		assert.Equal(t, 300, settings.NotifyThreshold, "NotifyThreshold field has wrong value")
+		assert.Equal(t, "https://hooks.example.com/asdf", settings.NotifyWebhook, "NotifyWebhook field has wrong value")
+		assert.Equal(t, []string{"policy", "deprecated"}, settings.LintRules, "LintRules field has wrong value")
+		assert.Equal(t, []string{".git", ".hg"}, settings.BoundaryMarkers, "BoundaryMarkers field has wrong value")
+		assert.Equal(t, []string{"nodejs", "python"}, settings.AllowPrereleases, "AllowPrereleases field has wrong value")
+		assert.Equal(t, map[string][]string{"frontend": {"nodejs", "yarn", "pnpm"}}, settings.Groups, "Groups field has wrong value")
+		assert.Equal(t, map[string][]string{"nodejs": {".tool-versions", ".nvmrc", "package.json#engines.node"}}, settings.VersionFiles, "VersionFiles field has wrong value")
+		testdataDir, _ := filepath.Abs("testdata")
+		assert.Equal(t, map[string][]string{"python": {filepath.Join(testdataDir, "patches/fix.patch") + "#sha256=abc", "https://example.com/a.patch#sha256=def"}, "python@3.11": {"/opt/patches/b.patch"}}, settings.Patches, "Patches field has wrong value")
+		assert.Equal(t, map[string][]string{"nodejs": {">=18", "<21"}}, settings.Policies, "Policies field has wrong value")
+		assert.Equal(t, map[string][]string{"nodejs": {"20.11.0"}, "python": {"3.12.1", "system"}}, settings.DefaultVersions, "DefaultVersions field has wrong value")
+		assert.Equal(t, map[string]map[string]string{"bazel": {"umask": "022", "nofile": "65536"}, "nodejs": {"nice": "10"}}, settings.ExecLimits, "ExecLimits field has wrong value")
+		assert.Equal(t, map[string]string{"nodejs": "ignore-patch", "ruby": "ignore-minor", "golang": "latest"}, settings.MatchStrategies, "MatchStrategies field has wrong value")
+		assert.Equal(t, map[string]string{"ruby": "3.*"}, settings.MatchRanges, "MatchRanges field has wrong value")
+		assert.Equal(t, map[string]string{"node": "nodejs", "golang": "go"}, settings.ToolAliases, "ToolAliases field has wrong value")
+		assert.Equal(t, map[string]string{HomeFallbackFlag: "warn"}, settings.Flags, "Flags field has wrong value")
 	})
 
 	t.Run("ASDF_CONCURRENCY=99 takes precedence over asdfrc value", func(t *testing.T) {
@@ -73,6 +164,22 @@ func TestLoadSettings(t *testing.T) {
 		assert.Equal(t, "99", settings.Concurrency, "Concurrency field has wrong value")
 	})
 
+	t.Run("ASDF_SYMLINK_RESOLUTION takes precedence over asdfrc value", func(t *testing.T) {
+		t.Setenv("ASDF_SYMLINK_RESOLUTION", "both")
+		settings, err := loadSettings("testdata/asdfrc")
+		assert.Nil(t, err)
+
+		assert.Equal(t, "both", settings.SymlinkResolution, "SymlinkResolution field has wrong value")
+	})
+
+	t.Run("ASDF_TOOL_VERSIONS_PATH takes precedence over asdfrc value", func(t *testing.T) {
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", "ci")
+		settings, err := loadSettings("testdata/asdfrc")
+		assert.Nil(t, err)
+
+		assert.Equal(t, "ci", settings.ToolVersionsPath, "ToolVersionsPath field has wrong value")
+	})
+
 	t.Run("ASDF_CONCURRENCY=auto takes precedence over asdfrc value", func(t *testing.T) {
 		expectedConcurrency := strconv.Itoa(runtime.NumCPU())
 		t.Setenv("ASDF_CONCURRENCY", "auto")
@@ -94,6 +201,48 @@ func TestLoadSettings(t *testing.T) {
 		assert.Equal(t, settings.PluginRepositoryLastCheckDuration.Every, 60, "PluginRepositoryLastCheckDuration field has wrong value")
 		assert.False(t, settings.DisablePluginShortNameRepository, "DisablePluginShortNameRepository field has wrong value")
 		assert.Equal(t, expectedConcurrency, settings.Concurrency, "Concurrency field has wrong value")
+		assert.Empty(t, settings.SharedInstallDir, "SharedInstallDir field has wrong value")
+		assert.Empty(t, settings.Locale, "Locale field has wrong value")
+		assert.Equal(t, "warn", settings.DeprecatedVersions, "DeprecatedVersions field has wrong value")
+		assert.Equal(t, "warn", settings.ConflictingManagers, "ConflictingManagers field has wrong value")
+		assert.Equal(t, "directory", settings.InstallsBackend, "InstallsBackend field has wrong value")
+		assert.Equal(t, "off", settings.ResolutionCache, "ResolutionCache field has wrong value")
+		assert.Equal(t, "auto", settings.HookFailurePolicy, "HookFailurePolicy field has wrong value")
+		assert.Equal(t, "inherit", settings.HookOutput, "HookOutput field has wrong value")
+		assert.Equal(t, "no", settings.AutoInstall, "AutoInstall field has wrong value")
+		assert.Equal(t, "no", settings.RemoteFallback, "RemoteFallback field has wrong value")
+		assert.Equal(t, 60, settings.ListAllCacheDuration, "ListAllCacheDuration field has wrong value")
+		assert.Equal(t, 20, settings.PromptBudget, "PromptBudget field has wrong value")
+		assert.Equal(t, "logical", settings.SymlinkResolution, "SymlinkResolution field has wrong value")
+		assert.Empty(t, settings.ToolVersionsPath, "ToolVersionsPath field has wrong value")
+		assert.False(t, settings.SystemFallback, "SystemFallback field has wrong value")
+		assert.False(t, settings.SubstitutionNotice, "SubstitutionNotice field has wrong value")
+		assert.False(t, settings.SanitizeEnv, "SanitizeEnv field has wrong value")
+		assert.False(t, settings.LatestRemote, "LatestRemote field has wrong value")
+		assert.False(t, settings.EnvFiles, "EnvFiles field has wrong value")
+		assert.False(t, settings.WorktreeFallback, "WorktreeFallback field has wrong value")
+		assert.False(t, settings.Launchers, "Launchers field has wrong value")
+		assert.False(t, settings.VersionedShims, "VersionedShims field has wrong value")
+		assert.False(t, settings.AuditLog, "AuditLog field has wrong value")
+		assert.Equal(t, []string{"incomplete", "quarantined", "platform"}, settings.ExcludeInstalls, "ExcludeInstalls field has wrong value")
+		assert.Equal(t, []string{"refresh", "prune", "repack", "verify", "tmp"}, settings.MaintainTasks, "MaintainTasks field has wrong value")
+		assert.Zero(t, settings.MaintainDownloadRetention, "MaintainDownloadRetention field has wrong value")
+		assert.Empty(t, settings.NotifyChannels, "NotifyChannels field has wrong value")
+		assert.Equal(t, 60, settings.NotifyThreshold, "NotifyThreshold field has wrong value")
+		assert.Empty(t, settings.NotifyWebhook, "NotifyWebhook field has wrong value")
+		assert.Equal(t, []string{"missing-tool-versions", "unknown-plugin", "policy", "deprecated", "legacy-mismatch"}, settings.LintRules, "LintRules field has wrong value")
+		assert.Empty(t, settings.BoundaryMarkers, "BoundaryMarkers field has wrong value")
+		assert.Empty(t, settings.AllowPrereleases, "AllowPrereleases field has wrong value")
+		assert.Empty(t, settings.Groups, "Groups field has wrong value")
+		assert.Empty(t, settings.VersionFiles, "VersionFiles field has wrong value")
+		assert.Empty(t, settings.Patches, "Patches field has wrong value")
+		assert.Empty(t, settings.Policies, "Policies field has wrong value")
+		assert.Empty(t, settings.DefaultVersions, "DefaultVersions field has wrong value")
+		assert.Empty(t, settings.ExecLimits, "ExecLimits field has wrong value")
+		assert.Empty(t, settings.MatchStrategies, "MatchStrategies field has wrong value")
+		assert.Empty(t, settings.MatchRanges, "MatchRanges field has wrong value")
+		assert.Empty(t, settings.ToolAliases, "ToolAliases field has wrong value")
+		assert.Empty(t, settings.Flags, "Flags field has wrong value")
 	})
 }
 
@@ -129,6 +278,273 @@ func TestConfigMethods(t *testing.T) {
 		assert.True(t, DisablePluginShortNameRepository, "Expected DisablePluginShortNameRepository to be set")
 	})
 
+	t.Run("Returns SharedInstallDir from asdfrc file", func(t *testing.T) {
+		sharedDir, err := config.SharedInstallDir()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "/opt/asdf", sharedDir)
+	})
+
+	t.Run("Returns Locale from asdfrc file", func(t *testing.T) {
+		locale, err := config.Locale()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "pt_BR.UTF-8", locale)
+	})
+
+	t.Run("Returns DeprecatedVersions from asdfrc file", func(t *testing.T) {
+		deprecatedVersions, err := config.DeprecatedVersions()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "error", deprecatedVersions)
+	})
+
+	t.Run("Returns ConflictingManagers from asdfrc file", func(t *testing.T) {
+		conflictingManagers, err := config.ConflictingManagers()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "ignore", conflictingManagers)
+	})
+
+	t.Run("Returns InstallsBackend from asdfrc file", func(t *testing.T) {
+		installsBackend, err := config.InstallsBackend()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "index", installsBackend)
+	})
+
+	t.Run("Returns ResolutionCache from asdfrc file", func(t *testing.T) {
+		resolutionCache, err := config.ResolutionCache()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "disk", resolutionCache)
+	})
+
+	t.Run("Returns ListAllCacheDuration from asdfrc file", func(t *testing.T) {
+		duration, err := config.ListAllCacheDuration()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Zero(t, duration)
+	})
+
+	t.Run("Returns PromptBudget from asdfrc file", func(t *testing.T) {
+		budget, err := config.PromptBudget()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, 50, budget)
+	})
+
+	t.Run("Returns SymlinkResolution from asdfrc file", func(t *testing.T) {
+		mode, err := config.SymlinkResolution()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "physical", mode)
+	})
+
+	t.Run("Returns ToolVersionsPath from asdfrc file", func(t *testing.T) {
+		path, err := config.ToolVersionsPath()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "/ci/.tool-versions", path)
+	})
+
+	t.Run("Returns ToolVersionsPath relative to current directory from ASDF_TOOL_VERSIONS_PATH", func(t *testing.T) {
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", "ci")
+		conf := config
+		conf.Settings.Loaded = false
+		wd, err := os.Getwd()
+		assert.Nil(t, err)
+
+		path, err := conf.ToolVersionsPath()
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(wd, "ci"), path)
+	})
+
+	t.Run("Returns SystemFallback from asdfrc file", func(t *testing.T) {
+		systemFallback, err := config.SystemFallback()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, systemFallback)
+	})
+
+	t.Run("Returns SubstitutionNotice from asdfrc file", func(t *testing.T) {
+		substitutionNotice, err := config.SubstitutionNotice()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, substitutionNotice)
+	})
+
+	t.Run("Returns SanitizeEnv from asdfrc file", func(t *testing.T) {
+		sanitizeEnv, err := config.SanitizeEnv()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, sanitizeEnv)
+	})
+
+	t.Run("Returns LatestRemote from asdfrc file", func(t *testing.T) {
+		latestRemote, err := config.LatestRemote()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, latestRemote)
+	})
+
+	t.Run("Returns EnvFiles from asdfrc file", func(t *testing.T) {
+		envFiles, err := config.EnvFiles()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, envFiles)
+	})
+
+	t.Run("Returns WorktreeFallback from asdfrc file", func(t *testing.T) {
+		worktreeFallback, err := config.WorktreeFallback()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, worktreeFallback)
+	})
+
+	t.Run("Returns Launchers from asdfrc file", func(t *testing.T) {
+		launchers, err := config.Launchers()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, launchers, "Expected Launchers to be true")
+	})
+
+	t.Run("Returns HookFailurePolicy from asdfrc file", func(t *testing.T) {
+		hookFailurePolicy, err := config.HookFailurePolicy()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "warn", hookFailurePolicy)
+	})
+
+	t.Run("Returns HookOutput from asdfrc file", func(t *testing.T) {
+		hookOutput, err := config.HookOutput()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "log", hookOutput)
+	})
+
+	t.Run("Returns AutoInstall from asdfrc file", func(t *testing.T) {
+		autoInstall, err := config.AutoInstall()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "prompt", autoInstall)
+	})
+
+	t.Run("Returns RemoteFallback from asdfrc file", func(t *testing.T) {
+		remoteFallback, err := config.RemoteFallback()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "report", remoteFallback)
+	})
+
+	t.Run("Returns VersionedShims from asdfrc file", func(t *testing.T) {
+		versionedShims, err := config.VersionedShims()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, versionedShims)
+	})
+
+	t.Run("Returns AuditLog from asdfrc file", func(t *testing.T) {
+		auditLog, err := config.AuditLog()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.True(t, auditLog, "Expected AuditLog to be true")
+	})
+
+	t.Run("Returns ExcludeInstalls from asdfrc file", func(t *testing.T) {
+		excludeInstalls, err := config.ExcludeInstalls()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"quarantined"}, excludeInstalls)
+	})
+
+	t.Run("Returns notification settings from asdfrc file", func(t *testing.T) {
+		channels, err := config.NotifyChannels()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"bell", "webhook"}, channels)
+
+		threshold, err := config.NotifyThreshold()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, 300, threshold)
+
+		webhook, err := config.NotifyWebhook()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "https://hooks.example.com/asdf", webhook)
+	})
+
+	t.Run("Returns MaintainTasks from asdfrc file", func(t *testing.T) {
+		maintainTasks, err := config.MaintainTasks()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"refresh", "tmp"}, maintainTasks)
+	})
+
+	t.Run("Returns LintRules from asdfrc file", func(t *testing.T) {
+		lintRules, err := config.LintRules()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"policy", "deprecated"}, lintRules)
+	})
+
+	t.Run("Returns BoundaryMarkers from asdfrc file", func(t *testing.T) {
+		boundaryMarkers, err := config.BoundaryMarkers()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{".git", ".hg"}, boundaryMarkers)
+	})
+
+	t.Run("Returns AllowPrereleases from asdfrc file", func(t *testing.T) {
+		allowPrereleases, err := config.AllowPrereleases()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"nodejs", "python"}, allowPrereleases)
+	})
+
+	t.Run("Returns Groups from asdfrc file", func(t *testing.T) {
+		groups, err := config.Groups()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"nodejs", "yarn", "pnpm"}, groups["frontend"])
+	})
+
+	t.Run("Returns VersionFiles from asdfrc file", func(t *testing.T) {
+		versionFiles, err := config.VersionFiles()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{".tool-versions", ".nvmrc", "package.json#engines.node"}, versionFiles["nodejs"])
+	})
+
+	t.Run("Returns Patches from asdfrc file", func(t *testing.T) {
+		patches, err := config.Patches()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"/opt/patches/b.patch"}, patches["python@3.11"])
+	})
+
+	t.Run("Returns Policies from asdfrc file", func(t *testing.T) {
+		policies, err := config.Policies()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{">=18", "<21"}, policies["nodejs"])
+	})
+
+	t.Run("Returns DefaultVersions from asdfrc file", func(t *testing.T) {
+		defaultVersions, err := config.DefaultVersions()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, []string{"3.12.1", "system"}, defaultVersions["python"])
+	})
+
+	t.Run("Returns ToolAliases from asdfrc file", func(t *testing.T) {
+		aliases, err := config.ToolAliases()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "nodejs", aliases["node"])
+	})
+
+	t.Run("Returns ExecLimits from asdfrc file", func(t *testing.T) {
+		limits, err := config.ExecLimits()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, map[string]string{"nice": "10"}, limits["nodejs"])
+	})
+
+	t.Run("Returns MatchStrategies from asdfrc file", func(t *testing.T) {
+		strategies, err := config.MatchStrategies()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "ignore-patch", strategies["nodejs"])
+		assert.NotContains(t, strategies, "python")
+	})
+
+	t.Run("Returns MatchStrategies from ignore keys of asdfrc file", func(t *testing.T) {
+		strategies, err := config.MatchStrategies()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "ignore-minor", strategies["ruby"])
+		assert.Equal(t, "latest", strategies["golang"])
+	})
+
+	t.Run("Returns MatchRanges from ignore keys of asdfrc file", func(t *testing.T) {
+		ranges, err := config.MatchRanges()
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, map[string]string{"ruby": "3.*"}, ranges)
+	})
+
+	t.Run("Returns flag from asdfrc file", func(t *testing.T) {
+		homeFallback, err := config.Flag(HomeFallbackFlag)
+		assert.Nil(t, err, "Returned error when loading settings")
+		assert.Equal(t, "warn", homeFallback)
+	})
+
+	t.Run("Returns error for unknown flag", func(t *testing.T) {
+		_, err := config.Flag("experimental.unknown")
+		assert.EqualError(t, err, "unknown flag experimental.unknown")
+	})
+
 	t.Run("When file does not exist returns settings struct with defaults", func(t *testing.T) {
 		config := Config{ConfigFile: "non-existent"}
 
@@ -151,6 +567,166 @@ func TestConfigMethods(t *testing.T) {
 		shortName, err := config.DisablePluginShortNameRepository()
 		assert.Nil(t, err)
 		assert.False(t, shortName)
+
+		sharedDir, err := config.SharedInstallDir()
+		assert.Nil(t, err)
+		assert.Empty(t, sharedDir)
+
+		locale, err := config.Locale()
+		assert.Nil(t, err)
+		assert.Empty(t, locale)
+
+		deprecatedVersions, err := config.DeprecatedVersions()
+		assert.Nil(t, err)
+		assert.Equal(t, "warn", deprecatedVersions)
+
+		conflictingManagers, err := config.ConflictingManagers()
+		assert.Nil(t, err)
+		assert.Equal(t, "warn", conflictingManagers)
+
+		installsBackend, err := config.InstallsBackend()
+		assert.Nil(t, err)
+		assert.Equal(t, "directory", installsBackend)
+
+		resolutionCache, err := config.ResolutionCache()
+		assert.Nil(t, err)
+		assert.Equal(t, "off", resolutionCache)
+
+		hookFailurePolicy, err := config.HookFailurePolicy()
+		assert.Nil(t, err)
+		assert.Equal(t, "auto", hookFailurePolicy)
+
+		hookOutput, err := config.HookOutput()
+		assert.Nil(t, err)
+		assert.Equal(t, "inherit", hookOutput)
+
+		autoInstall, err := config.AutoInstall()
+		assert.Nil(t, err)
+		assert.Equal(t, "no", autoInstall)
+
+		remoteFallback, err := config.RemoteFallback()
+		assert.Nil(t, err)
+		assert.Equal(t, "no", remoteFallback)
+
+		duration, err := config.ListAllCacheDuration()
+		assert.Nil(t, err)
+		assert.Equal(t, 60, duration)
+
+		budget, err := config.PromptBudget()
+		assert.Nil(t, err)
+		assert.Equal(t, 20, budget)
+
+		mode, err := config.SymlinkResolution()
+		assert.Nil(t, err)
+		assert.Equal(t, "logical", mode)
+
+		path, err := config.ToolVersionsPath()
+		assert.Nil(t, err)
+		assert.Empty(t, path)
+
+		systemFallback, err := config.SystemFallback()
+		assert.Nil(t, err)
+		assert.False(t, systemFallback)
+
+		substitutionNotice, err := config.SubstitutionNotice()
+		assert.Nil(t, err)
+		assert.False(t, substitutionNotice)
+
+		sanitizeEnv, err := config.SanitizeEnv()
+		assert.Nil(t, err)
+		assert.False(t, sanitizeEnv)
+
+		latestRemote, err := config.LatestRemote()
+		assert.Nil(t, err)
+		assert.False(t, latestRemote)
+
+		envFiles, err := config.EnvFiles()
+		assert.Nil(t, err)
+		assert.False(t, envFiles)
+
+		worktreeFallback, err := config.WorktreeFallback()
+		assert.Nil(t, err)
+		assert.False(t, worktreeFallback)
+
+		launchers, err := config.Launchers()
+		assert.Nil(t, err)
+		assert.False(t, launchers)
+
+		versionedShims, err := config.VersionedShims()
+		assert.Nil(t, err)
+		assert.False(t, versionedShims)
+
+		auditLog, err := config.AuditLog()
+		assert.Nil(t, err)
+		assert.False(t, auditLog)
+
+		excludeInstalls, err := config.ExcludeInstalls()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"incomplete", "quarantined", "platform"}, excludeInstalls)
+
+		maintainTasks, err := config.MaintainTasks()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"refresh", "prune", "repack", "verify", "tmp"}, maintainTasks)
+
+		channels, err := config.NotifyChannels()
+		assert.Nil(t, err)
+		assert.Empty(t, channels)
+
+		threshold, err := config.NotifyThreshold()
+		assert.Nil(t, err)
+		assert.Equal(t, 60, threshold)
+
+		lintRules, err := config.LintRules()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"missing-tool-versions", "unknown-plugin", "policy", "deprecated", "legacy-mismatch"}, lintRules)
+
+		boundaryMarkers, err := config.BoundaryMarkers()
+		assert.Nil(t, err)
+		assert.Empty(t, boundaryMarkers)
+
+		allowPrereleases, err := config.AllowPrereleases()
+		assert.Nil(t, err)
+		assert.Empty(t, allowPrereleases)
+
+		groups, err := config.Groups()
+		assert.Nil(t, err)
+		assert.Empty(t, groups)
+
+		versionFiles, err := config.VersionFiles()
+		assert.Nil(t, err)
+		assert.Empty(t, versionFiles)
+
+		patches, err := config.Patches()
+		assert.Nil(t, err)
+		assert.Empty(t, patches)
+
+		policies, err := config.Policies()
+		assert.Nil(t, err)
+		assert.Empty(t, policies)
+
+		defaultVersions, err := config.DefaultVersions()
+		assert.Nil(t, err)
+		assert.Empty(t, defaultVersions)
+
+		aliases, err := config.ToolAliases()
+		assert.Nil(t, err)
+		assert.Empty(t, aliases)
+
+		limits, err := config.ExecLimits()
+		assert.Nil(t, err)
+		assert.Empty(t, limits)
+
+		strategies, err := config.MatchStrategies()
+		assert.Nil(t, err)
+		assert.Empty(t, strategies)
+
+		ranges, err := config.MatchRanges()
+		assert.Nil(t, err)
+		assert.Empty(t, ranges)
+
+		homeFallback, err := config.Flag(HomeFallbackFlag)
+		assert.Nil(t, err)
+		assert.Equal(t, "allow", homeFallback)
 	})
 }
 
@@ -193,3 +769,288 @@ func TestConfigGetHook(t *testing.T) {
 		assert.Empty(t, hookCmd)
 	})
 }
+
+func TestConfigForDirectory(t *testing.T) {
+	userDir := t.TempDir()
+	config := Config{ConfigFile: filepath.Join(userDir, ".asdfrc")}
+	assert.Nil(t, os.WriteFile(config.ConfigFile, []byte("legacy_version_file = yes\npre_asdf_plugin_add = echo user\n[match]\nnodejs = ignore-minor\nruby = range\n[exec]\nbazel.nofile = 4096\nbazel.nice = 5\n"), 0o666))
+
+	projectDir := filepath.Join(userDir, "project")
+	subDir := filepath.Join(projectDir, "sub")
+	assert.Nil(t, os.MkdirAll(subDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".asdfrc"), []byte("legacy_version_file = no\nignore_patch = python=3.12\npre_asdf_plugin_add = echo project\n[match]\nnodejs = exact\n[exec]\nbazel.nofile = 65536\n"), 0o666))
+
+	t.Run("returns user settings when no project asdfrc is found", func(t *testing.T) {
+		dirConfig, err := config.ForDirectory(t.TempDir())
+		assert.Nil(t, err)
+		assert.True(t, dirConfig.Settings.LegacyVersionFile)
+		assert.Empty(t, dirConfig.Settings.ProjectFiles)
+	})
+
+	t.Run("does not read user asdfrc as project asdfrc", func(t *testing.T) {
+		dirConfig, err := config.ForDirectory(userDir)
+		assert.Nil(t, err)
+		assert.Empty(t, dirConfig.Settings.ProjectFiles)
+	})
+
+	t.Run("applies project asdfrc to the directory and below", func(t *testing.T) {
+		dirConfig, err := config.ForDirectory(subDir)
+		assert.Nil(t, err)
+		assert.False(t, dirConfig.Settings.LegacyVersionFile)
+		assert.Equal(t, map[string]string{"nodejs": "exact", "ruby": "range", "python": "ignore-patch"}, dirConfig.Settings.MatchStrategies)
+		assert.Equal(t, map[string]string{"python": "3.12"}, dirConfig.Settings.MatchRanges)
+		assert.Equal(t, []string{filepath.Join(projectDir, ".asdfrc")}, dirConfig.Settings.ProjectFiles)
+	})
+
+	t.Run("nearer project asdfrc takes precedence", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(subDir, ".asdfrc"), []byte("legacy_version_file = yes\n"), 0o666))
+		t.Cleanup(func() { os.Remove(filepath.Join(subDir, ".asdfrc")) })
+
+		dirConfig, err := config.ForDirectory(subDir)
+		assert.Nil(t, err)
+		assert.True(t, dirConfig.Settings.LegacyVersionFile)
+		assert.Equal(t, "exact", dirConfig.Settings.MatchStrategies["nodejs"])
+		assert.Len(t, dirConfig.Settings.ProjectFiles, 2)
+	})
+
+	t.Run("ignores keys other than resolution settings, including the [exec] section", func(t *testing.T) {
+		dirConfig, err := config.ForDirectory(subDir)
+		assert.Nil(t, err)
+		hookCmd, err := dirConfig.GetHook("pre_asdf_plugin_add")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo user", hookCmd)
+		assert.Equal(t, map[string]map[string]string{"bazel": {"nofile": "4096", "nice": "5"}}, dirConfig.Settings.ExecLimits)
+	})
+
+	t.Run("does not change user settings", func(t *testing.T) {
+		assert.True(t, config.Settings.LegacyVersionFile)
+		assert.Equal(t, map[string]string{"nodejs": "ignore-minor", "ruby": "range"}, config.Settings.MatchStrategies)
+		assert.Empty(t, config.Settings.MatchRanges)
+		assert.Equal(t, map[string]map[string]string{"bazel": {"nofile": "4096", "nice": "5"}}, config.Settings.ExecLimits)
+	})
+}
+
+func TestParseIgnoreList(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected map[string]string
+	}{
+		{value: "", expected: map[string]string{}},
+		{value: "nodejs ruby", expected: map[string]string{"nodejs": "", "ruby": ""}},
+		{value: "nodejs,ruby, python", expected: map[string]string{"nodejs": "", "ruby": "", "python": ""}},
+		{value: "nodejs=20.*", expected: map[string]string{"nodejs": "20.*"}},
+		{value: "nodejs = 20.*, python=>=3.11 ruby", expected: map[string]string{"nodejs": "20.*", "python": ">=3.11", "ruby": ""}},
+		{value: "python = >=3.11", expected: map[string]string{"python": ">=3.11"}},
+		{value: "*=v1.x", expected: map[string]string{"*": "v1.x"}},
+		{value: "nodejs= ruby", expected: map[string]string{"nodejs": "", "ruby": ""}},
+		{value: "nodejs=18 nodejs=20", expected: map[string]string{"nodejs": "20"}},
+		{value: ",, =20 nodejs >=18", expected: map[string]string{"nodejs": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseIgnoreList(tt.value))
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("ASDF_TEST_MIRROR", "https://mirror.example.com")
+	t.Setenv("ASDF_TEST_EMPTY", "")
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "${env:ASDF_TEST_MIRROR}/plugins", expected: "https://mirror.example.com/plugins"},
+		{value: "/opt/asdf/${os}-${arch}", expected: "/opt/asdf/" + runtime.GOOS + "-" + runtime.GOARCH},
+		{value: "a${env:ASDF_TEST_EMPTY}b", expected: "ab"},
+		{value: "echo ${HOME} $@", expected: "echo ${HOME} $@"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			expanded, err := Expand(tt.value)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, expanded)
+		})
+	}
+
+	t.Run("returns error for undefined environment variable", func(t *testing.T) {
+		_, err := Expand("${env:ASDF_TEST_UNDEFINED}/plugins")
+		assert.ErrorIs(t, err, UndefinedVariableError{name: "env:ASDF_TEST_UNDEFINED"})
+		assert.ErrorContains(t, err, "undefined variable ${env:ASDF_TEST_UNDEFINED}")
+	})
+}
+
+func TestLoadSettingsAutoInstall(t *testing.T) {
+	asdfrc := t.TempDir() + "/asdfrc"
+
+	for value, expected := range map[string]string{"yes": "yes", "Prompt": "prompt", "true": "yes", "false": "no", "always": "no"} {
+		assert.Nil(t, os.WriteFile(asdfrc, []byte("auto_install = "+value+"\n"), 0o666))
+
+		settings, err := loadSettings(asdfrc)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, settings.AutoInstall, value)
+	}
+}
+
+func TestLoadSettingsKeepsUnknownMaintainTasks(t *testing.T) {
+	asdfrc := t.TempDir() + "/asdfrc"
+	assert.Nil(t, os.WriteFile(asdfrc, []byte("maintain_tasks = Refresh prnue\n"), 0o666))
+
+	settings, err := loadSettings(asdfrc)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"refresh", "prnue"}, settings.MaintainTasks)
+}
+
+func TestLoadSettingsRecordsInvalidFlagValues(t *testing.T) {
+	asdfrc := t.TempDir() + "/asdfrc"
+	assert.Nil(t, os.WriteFile(asdfrc, []byte("deprecate.home_fallback = Error\nexperimental.package_json = yes\n"), 0o666))
+
+	conf := Config{ConfigFile: asdfrc}
+	value, err := conf.Flag(HomeFallbackFlag)
+	assert.Nil(t, err)
+	assert.Equal(t, "error", value)
+
+	value, err = conf.Flag(PackageJSONFlag)
+	assert.Nil(t, err)
+	assert.Equal(t, "off", value)
+
+	errs := conf.SettingsErrors()
+	assert.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], `experimental.package_json: invalid value "yes", must be one of off, on, using off`)
+}
+
+func TestLoadSettingsExpandsTemplateVariables(t *testing.T) {
+	asdfrc := t.TempDir() + "/asdfrc"
+	t.Setenv("ASDF_TEST_SHARED", "/opt/shared")
+
+	t.Run("expands variables in values", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(asdfrc, []byte("shared_install_dir = ${env:ASDF_TEST_SHARED}/${os}\n\n[groups]\nweb = ${env:ASDF_TEST_SHARED}\n"), 0o666))
+
+		settings, err := loadSettings(asdfrc)
+		assert.Nil(t, err)
+		assert.Equal(t, "/opt/shared/"+runtime.GOOS, settings.SharedInstallDir)
+		assert.Equal(t, []string{"/opt/shared"}, settings.Groups["web"])
+	})
+
+	t.Run("returns error naming the key with an undefined variable", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(asdfrc, []byte("shared_install_dir = ${env:ASDF_TEST_UNDEFINED}\nlegacy_version_file = yes\n\n[groups]\nweb = ${env:ASDF_TEST_UNDEFINED}\n"), 0o666))
+
+		settings, err := loadSettings(asdfrc)
+		assert.Nil(t, err)
+		assert.True(t, settings.Loaded)
+		assert.True(t, settings.LegacyVersionFile)
+		assert.Empty(t, settings.SharedInstallDir)
+		assert.Empty(t, settings.Groups)
+
+		conf := Config{ConfigFile: asdfrc}
+		_, err = conf.SharedInstallDir()
+		assert.ErrorContains(t, err, asdfrc+": shared_install_dir: undefined variable ${env:ASDF_TEST_UNDEFINED}")
+		legacy, err := conf.LegacyVersionFile()
+		assert.Nil(t, err)
+		assert.True(t, legacy)
+		errs := conf.SettingsErrors()
+		assert.Len(t, errs, 2)
+		assert.ErrorContains(t, errs[0], asdfrc+": groups.web: undefined variable")
+	})
+
+	t.Run("audit log is an error rather than off when it can't be expanded", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(asdfrc, []byte("audit_log = ${env:ASDF_TEST_UNDEFINED}\n"), 0o666))
+
+		conf := Config{ConfigFile: asdfrc}
+		_, err := conf.AuditLog()
+		assert.ErrorContains(t, err, "audit_log: undefined variable")
+	})
+
+	t.Run("leaves hook commands as is", func(t *testing.T) {
+		hook := "pre_asdf_install = echo ${os} ${env:ASDF_TEST_UNDEFINED}"
+		assert.Nil(t, os.WriteFile(asdfrc, []byte(hook+"\nresolution_missing = echo ${arch}\n"), 0o666))
+
+		conf := Config{ConfigFile: asdfrc}
+		command, err := conf.GetHook("pre_asdf_install")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo ${os} ${env:ASDF_TEST_UNDEFINED}", command)
+		command, err = conf.GetHook("resolution_missing")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo ${arch}", command)
+		assert.Empty(t, conf.SettingsErrors())
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("creates asdfrc with values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "asdfrc")
+		err := Update(path, []Value{{Key: "concurrency", Value: "4"}, {Section: "tool_aliases", Key: "node", Value: "nodejs"}})
+		assert.Nil(t, err)
+
+		contents, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "concurrency = 4\n\n[tool_aliases]\nnode = nodejs\n", string(contents))
+	})
+
+	t.Run("replaces keys in place and adds keys to their section", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "asdfrc")
+		original := "# settings\nlegacy_version_file = no\n\n[default_versions]\nnodejs = 18.0.0 # old\n\n[match]\nnodejs = ignore-patch\n"
+		assert.Nil(t, os.WriteFile(path, []byte(original), 0o666))
+
+		err := Update(path, []Value{
+			{Key: "legacy_version_file", Value: "yes"},
+			{Key: "concurrency", Value: "4"},
+			{Section: "default_versions", Key: "nodejs", Value: "20.11.0"},
+			{Section: "default_versions", Key: "python", Value: "3.12.1"},
+			{Section: "tool_aliases", Key: "node", Value: "nodejs"},
+		})
+		assert.Nil(t, err)
+
+		contents, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "# settings\nlegacy_version_file = yes\nconcurrency = 4\n\n[default_versions]\nnodejs = 20.11.0\npython = 3.12.1\n\n[match]\nnodejs = ignore-patch\n\n[tool_aliases]\nnode = nodejs\n", string(contents))
+	})
+
+	t.Run("values are read back", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, Update(path, []Value{{Key: "concurrency", Value: "4"}, {Section: "tool_aliases", Key: "node", Value: "nodejs"}}))
+
+		values, err := Values(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "4", values[""]["concurrency"])
+		assert.Equal(t, "nodejs", values["tool_aliases"]["node"])
+	})
+
+	t.Run("writes through symlink and keeps file mode", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "dotfiles-asdfrc")
+		assert.Nil(t, os.WriteFile(target, []byte("concurrency = 2\n"), 0o600))
+		path := filepath.Join(dir, "asdfrc")
+		assert.Nil(t, os.Symlink(target, path))
+
+		assert.Nil(t, Update(path, []Value{{Key: "concurrency", Value: "4"}}))
+
+		info, err := os.Lstat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.ModeSymlink, info.Mode().Type())
+		info, err = os.Stat(target)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		contents, err := os.ReadFile(target)
+		assert.Nil(t, err)
+		assert.Equal(t, "concurrency = 4\n", string(contents))
+	})
+}
+
+func TestValues(t *testing.T) {
+	t.Run("returns no values when asdfrc does not exist", func(t *testing.T) {
+		values, err := Values(filepath.Join(t.TempDir(), "asdfrc"))
+		assert.Nil(t, err)
+		assert.Empty(t, values)
+	})
+
+	t.Run("returns values as written", func(t *testing.T) {
+		values, err := Values("testdata/asdfrc")
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", values[""]["legacy_version_file"])
+		assert.Equal(t, "20.11.0", values["default_versions"]["nodejs"])
+	})
+}
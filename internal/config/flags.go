@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Flag is a setting that lets users opt in to an experimental feature, or
+// out of a deprecated behavior, before the change becomes the default. Flags
+// are set in the asdfrc by name, e.g. `deprecate.home_fallback = warn`.
+type Flag struct {
+	Name        string
+	Description string
+	// Values are the values the flag accepts. The first is the default.
+	Values []string
+}
+
+// Default returns the value of the flag when it isn't set
+func (f Flag) Default() string {
+	return f.Values[0]
+}
+
+// HomeFallbackFlag controls whether versions set in the home directory are
+// used when no version is set in the directory tree
+const HomeFallbackFlag = "deprecate.home_fallback"
+
+// PackageJSONFlag controls whether the volta and packageManager fields of
+// package.json files set versions of node and its package managers
+const PackageJSONFlag = "experimental.package_json"
+
+// flags is the registry of all flags asdf understands. Flags are removed once
+// the change they guard is complete.
+var flags = []Flag{
+	{
+		Name:        HomeFallbackFlag,
+		Description: "Use versions from the home directory when none are set in the current directory tree",
+		Values:      []string{"allow", "warn", "error"},
+	},
+	{
+		Name:        PackageJSONFlag,
+		Description: "Use versions set by the volta and packageManager fields of package.json files",
+		Values:      []string{"off", "on"},
+	},
+}
+
+// UnknownFlagError is returned when a flag that isn't registered is looked up
+type UnknownFlagError struct {
+	name string
+}
+
+func (e UnknownFlagError) Error() string {
+	return fmt.Sprintf("unknown flag %s", e.name)
+}
+
+// InvalidFlagValueError is recorded for a flag set to a value it doesn't
+// accept, the flag keeps its default
+type InvalidFlagValueError struct {
+	flag  Flag
+	value string
+}
+
+func (e InvalidFlagValueError) Error() string {
+	return fmt.Sprintf("invalid value %q, must be one of %s, using %s", e.value, strings.Join(e.flag.Values, ", "), e.flag.Default())
+}
+
+// Flags returns all registered flags ordered by name
+func Flags() []Flag {
+	sorted := slices.Clone(flags)
+	slices.SortFunc(sorted, func(a, b Flag) int { return strings.Compare(a.Name, b.Name) })
+	return sorted
+}
+
+// Flag returns the value of the named flag, or its default when it isn't set
+// to one of the values it accepts. Invalid values are reported by
+// SettingsErrors.
+func (c *Config) Flag(name string) (string, error) {
+	index := slices.IndexFunc(flags, func(flag Flag) bool { return flag.Name == name })
+	if index < 0 {
+		return "", UnknownFlagError{name: name}
+	}
+
+	err := c.loadSettings()
+	if err != nil {
+		return flags[index].Default(), err
+	}
+
+	if value, ok := c.Settings.Flags[name]; ok {
+		return value, nil
+	}
+
+	return flags[index].Default(), nil
+}
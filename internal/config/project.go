@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/ini.v1"
+)
+
+// ProjectConfigFilename is the name of the asdfrc files a project can place in
+// its directories to override settings for the directory and those below it
+const ProjectConfigFilename = ".asdfrc"
+
+// ForDirectory returns the config with the settings set by the project asdfrc
+// files in the directory and its parents applied on top of the user's asdfrc.
+// A nearer file takes precedence over a farther one. Only settings affecting
+// how versions are resolved for the project can be set, legacy_version_file,
+// ignore_patch, ignore_minor, ignore_version and the [match] section. Every
+// other key is ignored, so checking out a repository can't add hooks, change
+// where asdf installs tools or how their executables are run. The user's
+// asdfrc is never read as a project file, even when it's in a parent of the
+// directory.
+func (c *Config) ForDirectory(directory string) (Config, error) {
+	if err := c.loadSettings(); err != nil {
+		return *c, err
+	}
+
+	files := projectConfigFiles(c.ConfigFile, directory)
+	if len(files) == 0 {
+		return *c, nil
+	}
+
+	conf := *c
+	conf.Settings.MatchStrategies = maps.Clone(conf.Settings.MatchStrategies)
+	if conf.Settings.MatchStrategies == nil {
+		conf.Settings.MatchStrategies = map[string]string{}
+	}
+	conf.Settings.MatchRanges = maps.Clone(conf.Settings.MatchRanges)
+	if conf.Settings.MatchRanges == nil {
+		conf.Settings.MatchRanges = map[string]string{}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		projectConf, err := ini.LoadSources(ini.LoadOptions{SpaceBeforeInlineComment: true}, files[i])
+		if err != nil {
+			return conf, fmt.Errorf("%s: %w", files[i], err)
+		}
+
+		boolOverride(&conf.Settings.LegacyVersionFile, projectConf.Section(""), "legacy_version_file")
+		matchStrategies(projectConf, conf.Settings.MatchStrategies, conf.Settings.MatchRanges)
+	}
+	conf.Settings.ProjectFiles = files
+
+	return conf, nil
+}
+
+// projectConfigFiles returns the project asdfrc files in the directory and its
+// parents, nearest first
+func projectConfigFiles(userConfigFile, directory string) (files []string) {
+	userConfig, err := os.Stat(userConfigFile)
+	if err != nil {
+		userConfig = nil
+	}
+
+	for dir := directory; ; dir = filepath.Dir(dir) {
+		file := filepath.Join(dir, ProjectConfigFilename)
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() && (userConfig == nil || !os.SameFile(info, userConfig)) {
+			files = append(files, file)
+		}
+
+		if filepath.Dir(dir) == dir {
+			return files
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// templateRegex matches the template variables expanded in config values.
+// Other `${...}` expressions, such as shell variables in hooks, are left as is.
+var templateRegex = regexp.MustCompile(`\$\{(env:[^}]*|arch|os)\}`)
+
+// UndefinedVariableError is returned when a value refers to an environment
+// variable that isn't set
+type UndefinedVariableError struct {
+	name string
+}
+
+func (e UndefinedVariableError) Error() string {
+	return fmt.Sprintf("undefined variable ${%s}", e.name)
+}
+
+// Expand replaces the template variables in a value. `${env:VAR}` is replaced
+// with the value of the environment variable VAR, `${os}` with the operating
+// system and `${arch}` with the architecture asdf runs on, as Go names them
+// (e.g. linux and amd64). Referring to an environment variable that isn't set
+// is an error, an empty variable is replaced with an empty string.
+func Expand(value string) (string, error) {
+	var err error
+	expanded := templateRegex.ReplaceAllStringFunc(value, func(match string) string {
+		name := match[2 : len(match)-1]
+		switch name {
+		case "os":
+			return runtime.GOOS
+		case "arch":
+			return runtime.GOARCH
+		}
+
+		variable, ok := os.LookupEnv(strings.TrimPrefix(name, "env:"))
+		if !ok && err == nil {
+			err = UndefinedVariableError{name: name}
+		}
+		return variable
+	})
+
+	if err != nil {
+		return value, err
+	}
+
+	return expanded, nil
+}
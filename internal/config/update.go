@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+var (
+	sectionLineRegex = regexp.MustCompile(`^\s*\[\s*([^\]]*?)\s*\]\s*$`)
+	keyLineRegex     = regexp.MustCompile(`^\s*([^=;#\s][^=]*?)\s*=`)
+)
+
+// Value is a key of the asdfrc and its value. Section is empty for keys before
+// the first section header.
+type Value struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+// Update sets the values in the asdfrc at path, creating it if it doesn't
+// exist. Keys already set are replaced in place, other keys are added at the
+// end of their section, which is added at the end of the file when it isn't
+// in it. Comments, blank lines and every other key are kept as they are. The
+// file is replaced at once, so it is never left partly written.
+func Update(path string, values []Value) error {
+	contents, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Keep the mode of the existing file and write through a symlink to it,
+	// e.g. an asdfrc kept with the rest of the dotfiles
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		if path, err = filepath.EvalSymlinks(path); err != nil {
+			return err
+		}
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.WriteString(updateContent(string(contents), values))
+	if err == nil {
+		err = tmpFile.Chmod(mode)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// Values returns the values set in the asdfrc at path by section and key, as
+// they are written in the file, before template variables are expanded. Keys
+// before the first section header are in the "" section. A file that doesn't
+// exist sets no values.
+func Values(path string) (map[string]map[string]string, error) {
+	values := map[string]map[string]string{}
+	file, err := ini.LoadSources(ini.LoadOptions{SpaceBeforeInlineComment: true}, path)
+	if err != nil {
+		if _, ok := err.(*fs.PathError); ok {
+			return values, nil
+		}
+		return values, err
+	}
+
+	for _, section := range file.Sections() {
+		name := section.Name()
+		if name == ini.DefaultSection {
+			name = ""
+		}
+
+		values[name] = map[string]string{}
+		for _, key := range section.Keys() {
+			values[name][key.Name()] = key.String()
+		}
+	}
+
+	return values, nil
+}
+
+func updateContent(content string, values []Value) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	for _, value := range values {
+		line := value.Key + " = " + value.Value
+		start, end, found := sectionRange(lines, value.Section)
+		if !found {
+			if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+				lines = append(lines, "")
+			}
+			if value.Section != "" {
+				lines = append(lines, "["+value.Section+"]")
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		index := slices.IndexFunc(lines[start:end], func(existing string) bool {
+			match := keyLineRegex.FindStringSubmatch(existing)
+			return match != nil && match[1] == value.Key
+		})
+		if index != -1 {
+			lines[start+index] = line
+			continue
+		}
+
+		// Added after the last non blank line of the section, so the blank
+		// line separating it from the next one stays in place
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		lines = slices.Insert(lines, end, line)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// sectionRange returns the range of lines following the header of the section,
+// up to the next header. The section before the first header is always found.
+func sectionRange(lines []string, section string) (start, end int, found bool) {
+	current := ""
+	found = section == ""
+	for i, line := range lines {
+		match := sectionLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		if found {
+			return start, i, true
+		}
+		current = match[1]
+		if current == section {
+			start, found = i+1, true
+		}
+	}
+
+	return start, len(lines), found
+}
@@ -0,0 +1,134 @@
+// Package conflicts detects other version managers, such as nvm or pyenv, whose
+// directories come before the asdf shims directory on PATH. Commands of tools
+// both manage are then run from the other manager rather than through asdf.
+package conflicts
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Manager is a version manager known to conflict with asdf
+type Manager struct {
+	Name string
+	// Tools are the names of the tools the manager manages, as provided by
+	// asdf plugins without their namespace
+	Tools []string
+	// RootVar is the environment variable set to the manager's root directory
+	RootVar string
+	// DefaultRoot is the root directory, relative to the home directory, used
+	// when RootVar isn't set
+	DefaultRoot string
+	// Dirs are the directories under the root the manager adds to PATH.
+	// Directories below them, such as the bin directory of a node version,
+	// match as well.
+	Dirs []string
+	// Init is the line in shell startup files that activates the manager
+	Init string
+}
+
+// Managers returns the version managers conflicts are detected for
+func Managers() []Manager {
+	return []Manager{
+		{Name: "nvm", Tools: []string{"nodejs"}, RootVar: "NVM_DIR", DefaultRoot: ".nvm", Dirs: []string{"versions/node"}, Init: `. "$NVM_DIR/nvm.sh"`},
+		{Name: "pyenv", Tools: []string{"python"}, RootVar: "PYENV_ROOT", DefaultRoot: ".pyenv", Dirs: []string{"shims"}, Init: `eval "$(pyenv init -)"`},
+		{Name: "rbenv", Tools: []string{"ruby"}, RootVar: "RBENV_ROOT", DefaultRoot: ".rbenv", Dirs: []string{"shims"}, Init: `eval "$(rbenv init -)"`},
+		{Name: "volta", Tools: []string{"nodejs", "yarn", "pnpm"}, RootVar: "VOLTA_HOME", DefaultRoot: ".volta", Dirs: []string{"bin"}, Init: `export PATH="$VOLTA_HOME/bin:$PATH"`},
+	}
+}
+
+// Conflict is a PATH entry of another version manager that comes before the
+// asdf shims directory for a tool asdf manages
+type Conflict struct {
+	Manager Manager
+	Tool    string
+	Entry   string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s puts %s ahead of asdf shims on PATH, %s commands run the %s version rather than the asdf one", c.Manager.Name, c.Entry, c.Tool, c.Manager.Name)
+}
+
+// Detect returns the conflicts for the tools in the PATH, looking up the root
+// directories of the managers with getenv. Tools are named without the
+// namespace of their plugin, see plugins.Plugin.ToolName. Only entries before the shims
+// directory are considered, or every entry when it isn't on PATH.
+func Detect(path, shimsDir string, tools []string, getenv func(string) string) (conflicts []Conflict) {
+	for _, entry := range filepath.SplitList(path) {
+		if filepath.Clean(entry) == filepath.Clean(shimsDir) {
+			break
+		}
+
+		for _, manager := range Managers() {
+			if !manager.owns(entry, getenv) {
+				continue
+			}
+
+			for _, tool := range manager.Tools {
+				if slices.Contains(tools, tool) {
+					conflicts = append(conflicts, Conflict{Manager: manager, Tool: tool, Entry: entry})
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func (m Manager) owns(entry string, getenv func(string) string) bool {
+	root := getenv(m.RootVar)
+	if root == "" {
+		home := getenv("HOME")
+		if home == "" {
+			return false
+		}
+		root = filepath.Join(home, m.DefaultRoot)
+	}
+
+	entry = filepath.Clean(entry)
+	for _, dir := range m.Dirs {
+		dir = filepath.Join(root, filepath.FromSlash(dir))
+		if entry == dir || strings.HasPrefix(entry, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteTakeover writes the shell changes needed for asdf to take over the tool
+// from the conflicting managers. Comments explain which lines to remove from
+// shell startup files, and the commands after them move the shims directory
+// ahead on PATH, so the output can also be evaluated by the current shell.
+func WriteTakeover(out io.Writer, tool, shimsDir string, conflicts []Conflict) error {
+	var managers []Manager
+	for _, conflict := range conflicts {
+		if conflict.Tool == tool && !slices.ContainsFunc(managers, func(m Manager) bool { return m.Name == conflict.Manager.Name }) {
+			managers = append(managers, conflict.Manager)
+		}
+	}
+
+	if len(managers) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, manager := range managers {
+		lines = append(lines,
+			fmt.Sprintf("# %s is ahead of asdf on PATH for %s. To stop using it, remove this line from", manager.Name, tool),
+			"# your shell startup files:",
+			"#   "+manager.Init,
+		)
+	}
+	lines = append(lines,
+		"# To keep it for other tools, add the following after it instead. Running it",
+		"# lets asdf win in the current shell.",
+		fmt.Sprintf("export PATH=%q", shimsDir+string(filepath.ListSeparator)+"$PATH"),
+	)
+
+	_, err := io.WriteString(out, strings.Join(lines, "\n")+"\n")
+	return err
+}
@@ -0,0 +1,78 @@
+package conflicts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const shimsDir = "/home/user/.asdf/shims"
+
+func getenv(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func TestDetect(t *testing.T) {
+	env := getenv(map[string]string{"HOME": "/home/user", "PYENV_ROOT": "/opt/pyenv"})
+
+	t.Run("returns entries of managers ahead of shims for managed tools", func(t *testing.T) {
+		path := "/home/user/.nvm/versions/node/v18.19.0/bin:/opt/pyenv/shims:" + shimsDir + ":/usr/bin"
+		conflicts := Detect(path, shimsDir, []string{"nodejs", "python"}, env)
+
+		assert.Len(t, conflicts, 2)
+		assert.Equal(t, "nvm", conflicts[0].Manager.Name)
+		assert.Equal(t, "nodejs", conflicts[0].Tool)
+		assert.Equal(t, "/home/user/.nvm/versions/node/v18.19.0/bin", conflicts[0].Entry)
+		assert.Equal(t, "pyenv", conflicts[1].Manager.Name)
+		assert.Equal(t, "python", conflicts[1].Tool)
+	})
+
+	t.Run("ignores entries after shims", func(t *testing.T) {
+		path := shimsDir + ":/home/user/.rbenv/shims"
+		assert.Empty(t, Detect(path, shimsDir, []string{"ruby"}, env))
+	})
+
+	t.Run("ignores tools asdf doesn't manage", func(t *testing.T) {
+		path := "/home/user/.rbenv/shims:" + shimsDir
+		assert.Empty(t, Detect(path, shimsDir, []string{"nodejs"}, env))
+	})
+
+	t.Run("uses root variable instead of default root", func(t *testing.T) {
+		path := "/home/user/.pyenv/shims:" + shimsDir
+		assert.Empty(t, Detect(path, shimsDir, []string{"python"}, env))
+	})
+
+	t.Run("considers every entry when shims aren't on PATH", func(t *testing.T) {
+		path := "/usr/bin:/home/user/.volta/bin"
+		conflicts := Detect(path, shimsDir, []string{"nodejs", "yarn"}, env)
+
+		assert.Len(t, conflicts, 2)
+		assert.Equal(t, "volta", conflicts[0].Manager.Name)
+		assert.Equal(t, "yarn", conflicts[1].Tool)
+	})
+}
+
+func TestWriteTakeover(t *testing.T) {
+	env := getenv(map[string]string{"HOME": "/home/user"})
+	path := "/home/user/.nvm/versions/node/v18.19.0/bin:/home/user/.volta/bin:" + shimsDir
+	conflicts := Detect(path, shimsDir, []string{"nodejs"}, env)
+
+	t.Run("writes lines to remove and PATH change", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, WriteTakeover(&out, "nodejs", shimsDir, conflicts))
+
+		assert.Contains(t, out.String(), "# nvm is ahead of asdf on PATH for nodejs.")
+		assert.Contains(t, out.String(), "#   . \"$NVM_DIR/nvm.sh\"\n")
+		assert.Contains(t, out.String(), "# volta is ahead of asdf on PATH for nodejs.")
+		assert.True(t, strings.HasSuffix(out.String(), "export PATH=\"/home/user/.asdf/shims:$PATH\"\n"))
+	})
+
+	t.Run("writes nothing without conflicts for the tool", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, WriteTakeover(&out, "python", shimsDir, conflicts))
+		assert.Empty(t, out.String())
+	})
+}
@@ -7,11 +7,30 @@ import (
 )
 
 const (
+	dataDirAudit     = "audit"
+	dataDirCache     = "cache"
 	dataDirDownloads = "downloads"
 	dataDirInstalls  = "installs"
+	dataDirLaunchers = "launchers"
+	dataDirLocks     = "locks"
+	dataDirLogs      = "logs"
+	dataDirMetadata  = "metadata"
 	dataDirPlugins   = "plugins"
+	dataDirTmp       = "tmp"
 )
 
+// AuditDirectory returns the directory the audit log of shim executions and its
+// rotated files are kept in
+func AuditDirectory(dataDir string) string {
+	return filepath.Join(dataDir, dataDirAudit)
+}
+
+// CacheDirectory returns the directory asdf caches data about a plugin in, such
+// as the output of its list-all callback
+func CacheDirectory(dataDir, pluginName string) string {
+	return filepath.Join(dataDir, dataDirCache, pluginName)
+}
+
 // DownloadDirectory returns the directory a plugin will be placing
 // downloads of version source code
 func DownloadDirectory(dataDir, pluginName string) string {
@@ -23,6 +42,29 @@ func InstallDirectory(dataDir, pluginName string) string {
 	return filepath.Join(dataDir, dataDirInstalls, pluginName)
 }
 
+// LaunchersDirectory returns the directory holding the versioned symlinks to
+// the executables of installed tools
+func LaunchersDirectory(dataDir string) string {
+	return filepath.Join(dataDir, dataDirLaunchers)
+}
+
+// LockDirectory returns the directory the lock files used to serialize changes
+// to the data directory are kept in
+func LockDirectory(dataDir string) string {
+	return filepath.Join(dataDir, dataDirLocks)
+}
+
+// LogsDirectory returns the directory the logs of hooks are kept in
+func LogsDirectory(dataDir string) string {
+	return filepath.Join(dataDir, dataDirLogs)
+}
+
+// MetadataDirectory returns the directory records about the installed versions
+// of a plugin are stored in
+func MetadataDirectory(dataDir, pluginName string) string {
+	return filepath.Join(dataDir, dataDirMetadata, pluginName)
+}
+
 // PluginsDirectory returns the path to the plugins directory in the data dir
 func PluginsDirectory(dataDir string) string {
 	return filepath.Join(dataDir, dataDirPlugins)
@@ -33,3 +75,10 @@ func PluginsDirectory(dataDir string) string {
 func PluginDirectory(dataDir, pluginName string) string {
 	return filepath.Join(dataDir, dataDirPlugins, pluginName)
 }
+
+// TmpDirectory returns the root of the temporary directories asdf creates for
+// plugin callbacks. Directories are removed when the callback succeeds and
+// kept for debugging when it fails.
+func TmpDirectory(dataDir string) string {
+	return filepath.Join(dataDir, dataDirTmp)
+}
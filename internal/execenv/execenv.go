@@ -3,11 +3,23 @@
 package execenv
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/asdf-vm/asdf/internal/callbackenv"
+	"github.com/asdf-vm/asdf/internal/config"
 	"github.com/asdf-vm/asdf/internal/execute"
+	"github.com/asdf-vm/asdf/internal/installs"
 	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/toolversions"
 )
 
 const execEnvCallbackName = "exec-env"
@@ -35,3 +47,376 @@ func Generate(plugin plugins.Plugin, callbackEnv map[string]string) (env map[str
 	str := stdout.String()
 	return execute.SliceToMap(strings.Split(str, "\x00")), err
 }
+
+// Environment holds the changes to the environment needed to use the tools
+// resolved for a directory without going through shims
+type Environment struct {
+	// Paths contains the directories to prepend to PATH, in order
+	Paths []string
+	// Vars contains the variables set by exec-env callbacks
+	Vars map[string]string
+	// ManPaths contains man page directories, only set when man pages are
+	// included
+	ManPaths []string
+	// DataDirs contains share directories with bash or fish completions, only
+	// set when completions are included
+	DataDirs []string
+	// FunctionPaths contains zsh completion function directories, only set when
+	// completions are included
+	FunctionPaths []string
+}
+
+const (
+	// IncludeMan adds the man page directories of installed versions to MANPATH
+	IncludeMan = "man"
+	// IncludeCompletions adds the shell completion directories of installed
+	// versions to XDG_DATA_DIRS and FPATH
+	IncludeCompletions = "completions"
+)
+
+// Includes returns the names of all optional parts of the environment
+func Includes() []string {
+	return []string{IncludeMan, IncludeCompletions}
+}
+
+// Directories inside an install that may contain man pages or completions. The
+// install's share directory is added to XDG_DATA_DIRS, which bash-completion
+// and fish search for completions, when it contains completionDirs.
+var (
+	manDirs         = []string{"share/man", "man"}
+	completionDirs  = []string{"bash-completion/completions", "fish/vendor_completions.d"}
+	functionDirs    = []string{"share/zsh/site-functions", "share/zsh/vendor-completions"}
+	defaultDataDirs = "/usr/local/share:/usr/share"
+)
+
+// ignoredVars are either set by Bash itself when sourcing exec-env or are only
+// meaningful to a single tool
+var ignoredVars = []string{"_", "SHLVL", "PWD", "OLDPWD", "PATH", "ASDF_INSTALL_TYPE", "ASDF_INSTALL_VERSION", "ASDF_INSTALL_PATH"}
+
+// ForDirectory returns the environment for the tool versions resolved in dir.
+// For each tool the first installed version is used. Tools without an
+// installed version or set to system are skipped. include selects optional
+// parts of the environment, see Includes.
+func ForDirectory(conf config.Config, dir string, include []string) (environment Environment, err error) {
+	environment.Vars = map[string]string{}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return environment, err
+	}
+
+	for _, result := range resolve.All(conf, allPlugins, dir) {
+		plugin, toolVersions := result.Plugin, result.Versions
+		if result.Err != nil {
+			return environment, result.Err
+		}
+
+		if !result.Found {
+			continue
+		}
+
+		for _, versionStr := range toolVersions.Versions {
+			version := toolversions.Parse(versionStr)
+			if version.IsSystem() {
+				break
+			}
+
+			if !installs.IsInstalled(conf, plugin, version) {
+				continue
+			}
+
+			toolEnvironment, err := ForTool(conf, plugin, version, include)
+			if err != nil {
+				return environment, err
+			}
+
+			environment.merge(toolEnvironment)
+			break
+		}
+	}
+
+	return environment, nil
+}
+
+// ForTool returns the environment for a single installed tool version
+func ForTool(conf config.Config, plugin plugins.Plugin, version toolversions.Version, include []string) (environment Environment, err error) {
+	environment.Vars = map[string]string{}
+
+	execPaths, err := shims.ExecutablePaths(conf, plugin, version)
+	if err != nil {
+		return environment, err
+	}
+
+	installPath := installs.InstallPath(conf, plugin, version)
+	environment.Include(installPath, include)
+
+	callbackEnv := callbackenv.ForVersion(conf, plugin, version).Map()
+
+	env, err := Generate(plugin, callbackEnv)
+	if _, ok := err.(plugins.NoCallbackError); ok {
+		environment.Paths = execPaths
+		return environment, nil
+	}
+
+	if err != nil {
+		return environment, err
+	}
+
+	// exec-env callbacks may prepend directories to PATH themselves
+	currentEnv := execute.CurrentEnv()
+	currentPath := currentEnv["PATH"]
+	if prefix, ok := strings.CutSuffix(env["PATH"], ":"+currentPath); ok && prefix != "" {
+		environment.Paths = append(environment.Paths, strings.Split(prefix, ":")...)
+	}
+	environment.Paths = append(environment.Paths, execPaths...)
+
+	for key, value := range env {
+		if slices.Contains(ignoredVars, key) {
+			continue
+		}
+
+		if current, ok := currentEnv[key]; !ok || current != value {
+			environment.Vars[key] = value
+		}
+	}
+
+	return environment, nil
+}
+
+// Include adds the optional directories selected by include that exist in
+// installPath to the environment
+func (e *Environment) Include(installPath string, include []string) {
+	if slices.Contains(include, IncludeMan) {
+		e.ManPaths = append(e.ManPaths, existingDirs(installPath, manDirs)...)
+	}
+
+	if slices.Contains(include, IncludeCompletions) {
+		shareDir := filepath.Join(installPath, "share")
+		if len(existingDirs(shareDir, completionDirs)) > 0 {
+			e.DataDirs = append(e.DataDirs, shareDir)
+		}
+		e.FunctionPaths = append(e.FunctionPaths, existingDirs(installPath, functionDirs)...)
+	}
+}
+
+func (e *Environment) merge(other Environment) {
+	e.Paths = append(e.Paths, other.Paths...)
+	e.ManPaths = append(e.ManPaths, other.ManPaths...)
+	e.DataDirs = append(e.DataDirs, other.DataDirs...)
+	e.FunctionPaths = append(e.FunctionPaths, other.FunctionPaths...)
+	for key, value := range other.Vars {
+		e.Vars[key] = value
+	}
+}
+
+// Variables returns every variable in the environment other than PATH,
+// including MANPATH, XDG_DATA_DIRS and FPATH when the environment contains
+// directories for them. Their directories are prepended to the values in
+// currentEnv.
+func (e Environment) Variables(currentEnv map[string]string) map[string]string {
+	vars := map[string]string{}
+	for key, value := range e.Vars {
+		vars[key] = value
+	}
+
+	if len(e.ManPaths) > 0 {
+		// A trailing colon makes man search its default path after these
+		// directories
+		vars["MANPATH"] = strings.Join(e.ManPaths, ":") + ":" + currentEnv["MANPATH"]
+	}
+
+	if len(e.DataDirs) > 0 {
+		current := currentEnv["XDG_DATA_DIRS"]
+		if current == "" {
+			current = defaultDataDirs
+		}
+		vars["XDG_DATA_DIRS"] = strings.Join(e.DataDirs, ":") + ":" + current
+	}
+
+	if len(e.FunctionPaths) > 0 {
+		vars["FPATH"] = strings.TrimSuffix(strings.Join(e.FunctionPaths, ":")+":"+currentEnv["FPATH"], ":")
+	}
+
+	return vars
+}
+
+// WriteDotenv writes the environment to out as a dotenv file, one KEY=value
+// pair per line. Directories are prepended to the values of PATH and other
+// list variables in currentEnv.
+func (e Environment) WriteDotenv(out io.Writer, currentEnv map[string]string) error {
+	path := strings.Join(append(slices.Clone(e.Paths), currentEnv["PATH"]), ":")
+	if _, err := fmt.Fprintf(out, "PATH=%s\n", quoteDotenv(strings.TrimSuffix(path, ":"))); err != nil {
+		return err
+	}
+
+	vars := e.Variables(currentEnv)
+	var keys []string
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(out, "%s=%s\n", key, quoteDotenv(vars[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func existingDirs(root string, dirs []string) (existing []string) {
+	for _, dir := range dirs {
+		if info, err := os.Stat(filepath.Join(root, dir)); err == nil && info.IsDir() {
+			existing = append(existing, filepath.Join(root, dir))
+		}
+	}
+	return existing
+}
+
+// quoteDotenv double quotes values containing anything other than characters
+// that are safe unquoted in every dotenv dialect
+func quoteDotenv(value string) string {
+	safe := func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/:._-+,@%", r)
+	}
+	if strings.IndexFunc(value, func(r rune) bool { return !safe(r) }) < 0 {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// InheritedVar is set by asdf exec in the environment of the executed tool. It
+// records the value each variable asdf changed had before, so a nested asdf
+// invocation run with ASDF_NO_INHERIT can undo the changes.
+const InheritedVar = "ASDF_EXEC_INHERITED"
+
+// Record returns env with InheritedVar set to the previous values of the
+// variables env changes in current. A variable that wasn't set is recorded as
+// null. Values recorded by an outer asdf exec are kept so the original
+// environment can be restored from any depth.
+func Record(env, current map[string]string) map[string]string {
+	previous := inherited(current)
+	for name, value := range env {
+		if _, recorded := previous[name]; recorded || name == InheritedVar {
+			continue
+		}
+
+		if old, ok := current[name]; !ok {
+			previous[name] = nil
+		} else if old != value {
+			previous[name] = &old
+		}
+	}
+
+	contents, err := json.Marshal(previous)
+	if err != nil {
+		return env
+	}
+
+	env[InheritedVar] = string(contents)
+	return env
+}
+
+// Restore returns the variables to set and unset in current to undo the
+// changes recorded by asdf exec invocations the current process is nested in
+func Restore(current map[string]string) (set map[string]string, unset []string) {
+	set = map[string]string{}
+	for name, value := range inherited(current) {
+		if value == nil {
+			unset = append(unset, name)
+		} else {
+			set[name] = *value
+		}
+	}
+	slices.Sort(unset)
+
+	return set, append(unset, InheritedVar)
+}
+
+func inherited(current map[string]string) map[string]*string {
+	previous := map[string]*string{}
+	if contents, ok := current[InheritedVar]; ok {
+		// An invalid value can only come from outside asdf and is ignored
+		_ = json.Unmarshal([]byte(contents), &previous)
+	}
+	return previous
+}
+
+// TraceVar enables tracing of the commands run by asdf exec, including those
+// run through shims. Set it to `stderr` to write traces to stderr, or to the
+// path of a file to append them to. `asdf exec --trace` sets it in the
+// environment of the command it runs, so tools the command runs through shims
+// are traced as well.
+const TraceVar = "ASDF_EXEC_TRACE"
+
+// Trace describes a command run by asdf exec
+type Trace struct {
+	Command    string
+	Tool       string
+	Version    string
+	Source     string
+	Executable string
+	Args       []string
+	// Env contains the variables set for the command that differ from the
+	// environment asdf was run in, other than PATH
+	Env  map[string]string
+	Path string
+}
+
+// NewTrace returns a trace of running executable with env, recording the
+// variables env changes in current
+func NewTrace(command, executable string, args []string, env, current map[string]string) Trace {
+	trace := Trace{Command: command, Executable: executable, Args: args, Env: map[string]string{}, Path: env["PATH"]}
+	for name, value := range env {
+		if old, ok := current[name]; name != "PATH" && (!ok || old != value) {
+			trace.Env[name] = value
+		}
+	}
+
+	return trace
+}
+
+// Write writes the trace to out, one line per detail, each prefixed with the
+// process ID so traces of concurrent commands written to the same file can be
+// told apart
+func (t Trace) Write(out io.Writer) error {
+	var b strings.Builder
+	prefix := fmt.Sprintf("asdf exec[%d]: ", os.Getpid())
+	line := func(format string, args ...any) {
+		b.WriteString(prefix)
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\n")
+	}
+
+	line("command %s", t.Command)
+	line("tool %s %s", t.Tool, t.Version)
+	line("source %s", t.Source)
+	line("exec %s", strings.Join(append([]string{t.Executable}, t.Args...), " "))
+
+	names := slices.Sorted(maps.Keys(t.Env))
+	for _, name := range names {
+		line("env %s=%s", name, t.Env[name])
+	}
+
+	line("PATH=%s", t.Path)
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// OpenTrace returns the writer traces are written to for a TraceVar value
+func OpenTrace(destination string, stderr io.Writer) (io.Writer, func() error, error) {
+	if destination == "stderr" {
+		return stderr, func() error { return nil }, nil
+	}
+
+	file, err := os.OpenFile(destination, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o666)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open trace file: %w", err)
+	}
+
+	return file, file.Close, nil
+}
@@ -1,9 +1,15 @@
 package execenv
 
 import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/execute"
+	"github.com/asdf-vm/asdf/internal/installtest"
 	"github.com/asdf-vm/asdf/internal/plugins"
 	"github.com/asdf-vm/asdf/internal/repotest"
 	"github.com/stretchr/testify/assert"
@@ -79,3 +85,160 @@ func TestGenerate(t *testing.T) {
 		assert.Equal(t, value, env["BAZ"])
 	})
 }
+
+func TestForDirectory(t *testing.T) {
+	testDataDir := t.TempDir()
+	currentDir := t.TempDir()
+	conf := config.Config{DataDir: testDataDir, DefaultToolVersionsFilename: ".tool-versions"}
+
+	_, err := repotest.InstallPlugin("dummy_plugin", testDataDir, testPluginName)
+	assert.Nil(t, err)
+	lua := plugins.New(conf, testPluginName)
+	assert.Nil(t, installtest.InstallOneVersion(conf, lua, "version", "1.0.0"))
+	assert.Nil(t, repotest.WritePluginCallback(lua.Dir, "exec-env", "#!/usr/bin/env bash\nexport LUA_HOME=$ASDF_INSTALL_PATH\nexport PATH=/opt/lua/bin:$PATH"))
+
+	_, err = repotest.InstallPlugin("dummy_plugin", testDataDir, testPluginName2)
+	assert.Nil(t, err)
+	ruby := plugins.New(conf, testPluginName2)
+
+	contents := "lua 2.0.0 1.0.0\nruby 1.0.0\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), []byte(contents), 0o666))
+
+	installPath := installtest.InstallPath(conf, lua, "1.0.0")
+
+	t.Run("returns paths and variables of installed versions", func(t *testing.T) {
+		environment, err := ForDirectory(conf, currentDir, []string{})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{"/opt/lua/bin", filepath.Join(installPath, "bin")}, environment.Paths)
+		assert.Equal(t, map[string]string{"LUA_HOME": installPath}, environment.Vars)
+		assert.NotContains(t, strings.Join(environment.Paths, ":"), ruby.Name)
+		assert.Empty(t, environment.ManPaths)
+	})
+
+	t.Run("returns man and completion directories when included", func(t *testing.T) {
+		for _, dir := range []string{"share/man/man1", "share/bash-completion/completions", "share/zsh/site-functions"} {
+			assert.Nil(t, os.MkdirAll(filepath.Join(installPath, dir), 0o777))
+		}
+
+		environment, err := ForDirectory(conf, currentDir, []string{IncludeMan, IncludeCompletions})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{filepath.Join(installPath, "share/man")}, environment.ManPaths)
+		assert.Equal(t, []string{filepath.Join(installPath, "share")}, environment.DataDirs)
+		assert.Equal(t, []string{filepath.Join(installPath, "share/zsh/site-functions")}, environment.FunctionPaths)
+	})
+}
+
+func TestVariables(t *testing.T) {
+	environment := Environment{
+		Vars:          map[string]string{"LUA_HOME": "/lua"},
+		ManPaths:      []string{"/lua/share/man"},
+		DataDirs:      []string{"/lua/share"},
+		FunctionPaths: []string{"/lua/share/zsh/site-functions"},
+	}
+
+	t.Run("keeps default search paths when variables are unset", func(t *testing.T) {
+		vars := environment.Variables(map[string]string{})
+		assert.Equal(t, map[string]string{
+			"LUA_HOME":      "/lua",
+			"MANPATH":       "/lua/share/man:",
+			"XDG_DATA_DIRS": "/lua/share:/usr/local/share:/usr/share",
+			"FPATH":         "/lua/share/zsh/site-functions",
+		}, vars)
+	})
+
+	t.Run("prepends directories to current values", func(t *testing.T) {
+		vars := environment.Variables(map[string]string{"MANPATH": "/usr/man", "XDG_DATA_DIRS": "/usr/share", "FPATH": "/usr/functions"})
+		assert.Equal(t, "/lua/share/man:/usr/man", vars["MANPATH"])
+		assert.Equal(t, "/lua/share:/usr/share", vars["XDG_DATA_DIRS"])
+		assert.Equal(t, "/lua/share/zsh/site-functions:/usr/functions", vars["FPATH"])
+	})
+}
+
+func TestWriteDotenv(t *testing.T) {
+	environment := Environment{
+		Paths: []string{"/asdf/installs/lua/1.0.0/bin"},
+		Vars:  map[string]string{"LUA_HOME": "/asdf/installs/lua/1.0.0", "GREETING": "hello \"world\"\n$HOME", "EMPTY": ""},
+	}
+
+	var out strings.Builder
+	assert.Nil(t, environment.WriteDotenv(&out, map[string]string{"PATH": "/usr/bin"}))
+	expected := "PATH=/asdf/installs/lua/1.0.0/bin:/usr/bin\nEMPTY=\nGREETING=\"hello \\\"world\\\"\\n\\$HOME\"\nLUA_HOME=/asdf/installs/lua/1.0.0\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRecordAndRestore(t *testing.T) {
+	t.Run("records previous values of changed variables", func(t *testing.T) {
+		current := map[string]string{"PATH": "/usr/bin", "HOME": "/home/user"}
+		env := Record(map[string]string{"PATH": "/tool/bin:/usr/bin", "HOME": "/home/user", "LUA_PATH": "/tool/lib"}, current)
+
+		assert.JSONEq(t, `{"PATH": "/usr/bin", "LUA_PATH": null}`, env[InheritedVar])
+	})
+
+	t.Run("restores environment from before outermost exec", func(t *testing.T) {
+		original := map[string]string{"PATH": "/usr/bin"}
+		outer := Record(map[string]string{"PATH": "/lua/bin:/usr/bin", "LUA_PATH": "/lua/lib"}, original)
+		outer = execute.MergeEnv(map[string]string{"PATH": "/usr/bin"}, outer)
+
+		inner := Record(map[string]string{"PATH": "/ruby/bin:/lua/bin:/usr/bin", "GEM_HOME": "/ruby/gems"}, maps.Clone(outer))
+		inner = execute.MergeEnv(maps.Clone(outer), inner)
+
+		set, unset := Restore(inner)
+		assert.Equal(t, map[string]string{"PATH": "/usr/bin"}, set)
+		assert.Equal(t, []string{"GEM_HOME", "LUA_PATH", InheritedVar}, unset)
+	})
+
+	t.Run("only unsets marker when not nested in asdf exec", func(t *testing.T) {
+		set, unset := Restore(map[string]string{"PATH": "/usr/bin"})
+		assert.Empty(t, set)
+		assert.Equal(t, []string{InheritedVar}, unset)
+	})
+}
+
+func TestTrace(t *testing.T) {
+	env := map[string]string{"PATH": "/install/bin:/usr/bin", "FOO": "bar", "HOME": "/home/user"}
+	current := map[string]string{"PATH": "/usr/bin", "HOME": "/home/user"}
+	trace := NewTrace("lua", "/install/bin/lua", []string{"-v"}, env, current)
+	trace.Tool = testPluginName
+	trace.Version = "1.0.0"
+	trace.Source = "/project/.tool-versions"
+
+	t.Run("NewTrace records only changed variables other than PATH", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"FOO": "bar"}, trace.Env)
+		assert.Equal(t, "/install/bin:/usr/bin", trace.Path)
+	})
+
+	t.Run("Write writes a line per detail", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, trace.Write(&out))
+		output := out.String()
+
+		assert.Contains(t, output, "]: tool lua 1.0.0\n")
+		assert.Contains(t, output, "]: source /project/.tool-versions\n")
+		assert.Contains(t, output, "]: exec /install/bin/lua -v\n")
+		assert.Contains(t, output, "]: env FOO=bar\n")
+		assert.Contains(t, output, "]: PATH=/install/bin:/usr/bin\n")
+	})
+
+	t.Run("OpenTrace appends to file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trace.log")
+		for range 2 {
+			out, closeTrace, err := OpenTrace(path, nil)
+			assert.Nil(t, err)
+			assert.Nil(t, trace.Write(out))
+			assert.Nil(t, closeTrace())
+		}
+
+		contents, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, strings.Count(string(contents), "]: command lua\n"))
+	})
+
+	t.Run("OpenTrace returns stderr for stderr", func(t *testing.T) {
+		var stderr strings.Builder
+		out, _, err := OpenTrace("stderr", &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, &stderr, out)
+	})
+}
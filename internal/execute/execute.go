@@ -4,13 +4,20 @@
 package execute
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// waitDelay is how long RunContext waits for the output of a cancelled command
+// to be closed, which processes it started and that outlive it may hold open
+const waitDelay = time.Second
+
 // Command represents a Bash command that can be executed by asdf
 type Command struct {
 	Command    string
@@ -36,6 +43,13 @@ func NewExpression(expression string, args []string) Command {
 
 // Run executes a Command with Bash and returns the error if there is one
 func (c Command) Run() error {
+	return c.RunContext(context.Background())
+}
+
+// RunContext executes a Command with Bash like Run, killing it and the
+// processes it started when the context is done before it exits. The error
+// of the context is returned in that case.
+func (c Command) RunContext(ctx context.Context) error {
 	var command string
 	if c.Expression != "" {
 		// Expressions need to be invoked inside a Bash function, so variables like
@@ -46,7 +60,18 @@ func (c Command) Run() error {
 		command = fmt.Sprintf("%s %s", c.Command, formatArgString(c.Args))
 	}
 
-	cmd := exec.Command("bash", "-c", command)
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+
+	// Commands that can't be cancelled run in the process group of asdf, so
+	// interactive ones can still read from the terminal. The others get their
+	// own group, so the processes they start are killed along with them.
+	if ctx.Done() != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = waitDelay
+	}
 
 	if len(c.Env) > 0 {
 		cmd.Env = MergeWithCurrentEnv(c.Env)
@@ -60,7 +85,12 @@ func (c Command) Run() error {
 	cmd.Stdout = c.Stdout
 	cmd.Stderr = c.Stderr
 
-	return cmd.Run()
+	err := cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
 }
 
 // MergeWithCurrentEnv merges the provided map into the current environment variables
@@ -1,11 +1,13 @@
 package execute
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -203,6 +205,47 @@ func TestRun_Expression(t *testing.T) {
 	})
 }
 
+func TestRunContext(t *testing.T) {
+	t.Run("runs command like Run when context is not done", func(t *testing.T) {
+		cmd := NewExpression("echo $1", []string{"value"})
+
+		var stdout strings.Builder
+		cmd.Stdout = &stdout
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		assert.Nil(t, cmd.RunContext(ctx))
+		assert.Equal(t, "value\n", stdout.String())
+	})
+
+	t.Run("kills command and the processes it started when context is done", func(t *testing.T) {
+		cmd := New("sleep 30 | cat", []string{})
+
+		var stdout strings.Builder
+		cmd.Stdout = &stdout
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		started := time.Now()
+		err := cmd.RunContext(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(started), 10*time.Second)
+	})
+
+	t.Run("does not run command when context is already done", func(t *testing.T) {
+		marker := t.TempDir() + "/ran"
+		cmd := New("touch "+marker, []string{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, cmd.RunContext(ctx), context.Canceled)
+		assert.NoFileExists(t, marker)
+	})
+}
+
 func TestMergeWithCurrentEnv(t *testing.T) {
 	t.Run("merge with current env", func(t *testing.T) {
 		path := os.Getenv("PATH")
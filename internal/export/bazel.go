@@ -0,0 +1,93 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+const (
+	bazelChecksumPrefix = "sha256:"
+	// bazelBuildFile makes every file of the tool available to the build
+	bazelBuildFile = `filegroup(name = "all", srcs = glob(["**"]), visibility = ["//visibility:public"])`
+)
+
+var bazelInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// bazelRepository is a repository rule declaring the toolchain of a single tool
+type bazelRepository struct {
+	Tool
+	name string
+	rule string
+}
+
+// writeBazel writes a repository rule for every tool, to be added to a
+// WORKSPACE or loaded from MODULE.bazel with use_repo_rule. A tool whose
+// provenance records a download URL and a sha256 checksum is fetched with
+// http_archive, so builds are hermetic and don't depend on asdf. Other tools
+// point at their install directory with new_local_repository. Tools that
+// aren't installed are skipped.
+func writeBazel(tools []Tool, out, warnings io.Writer) error {
+	var repositories []bazelRepository
+	for _, tool := range tools {
+		if toolversions.Parse(tool.Version).IsSystem() {
+			fmt.Fprintf(warnings, "warning: skipping %s, system version is not managed by asdf\n", tool.Name)
+			continue
+		}
+
+		repository := bazelRepository{Tool: tool, name: bazelRepositoryName(tool.Name)}
+		switch {
+		case tool.SourceURL != "" && strings.HasPrefix(tool.Checksum, bazelChecksumPrefix):
+			repository.rule = "http_archive"
+		case tool.InstallPath != "":
+			repository.rule = "new_local_repository"
+		default:
+			fmt.Fprintf(warnings, "warning: skipping %s, version %s is not installed\n", tool.Name, tool.Version)
+			continue
+		}
+		repositories = append(repositories, repository)
+	}
+
+	fmt.Fprintln(out, "# Generated by asdf export")
+	for _, rule := range []string{"http_archive", "new_local_repository"} {
+		if slices.ContainsFunc(repositories, func(repository bazelRepository) bool { return repository.rule == rule }) {
+			fmt.Fprintf(out, "load(\"@bazel_tools//tools/build_defs/repo:%s.bzl\", %q)\n", bazelRuleFile(rule), rule)
+		}
+	}
+
+	for _, repository := range repositories {
+		_, err := fmt.Fprintf(out, "\n# %s %s\n%s(\n    name = %q,\n", repository.Name, repository.Version, repository.rule, repository.name)
+		if err != nil {
+			return err
+		}
+
+		if repository.rule == "http_archive" {
+			fmt.Fprintf(out, "    urls = [%q],\n    sha256 = %q,\n", repository.SourceURL, strings.TrimPrefix(repository.Checksum, bazelChecksumPrefix))
+		} else {
+			fmt.Fprintf(out, "    path = %q,\n", repository.InstallPath)
+		}
+
+		if _, err := fmt.Fprintf(out, "    build_file_content = %q,\n)\n", bazelBuildFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bazelRepositoryName returns the name of the repository for the tool, which
+// may only contain letters, digits and underscores
+func bazelRepositoryName(toolName string) string {
+	return "asdf_" + bazelInvalidChars.ReplaceAllString(toolName, "_")
+}
+
+func bazelRuleFile(rule string) string {
+	if rule == "http_archive" {
+		return "http"
+	}
+	return "local"
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBazel(t *testing.T) {
+	t.Run("writes http_archive for tools with download URL and sha256 checksum", func(t *testing.T) {
+		tools := []Tool{{Name: "nodejs", Version: "20.11.1", InstallPath: "/asdf/installs/nodejs/20.11.1", SourceURL: "https://nodejs.org/dist/v20.11.1/node-v20.11.1-linux-x64.tar.gz", Checksum: "sha256:abc123"}}
+		var stdout, stderr strings.Builder
+		assert.Nil(t, Write("bazel", tools, &stdout, &stderr))
+
+		expected := `# Generated by asdf export
+load("@bazel_tools//tools/build_defs/repo:http.bzl", "http_archive")
+
+# nodejs 20.11.1
+http_archive(
+    name = "asdf_nodejs",
+    urls = ["https://nodejs.org/dist/v20.11.1/node-v20.11.1-linux-x64.tar.gz"],
+    sha256 = "abc123",
+    build_file_content = "filegroup(name = \"all\", srcs = glob([\"**\"]), visibility = [\"//visibility:public\"])",
+)
+`
+		assert.Equal(t, expected, stdout.String())
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("writes new_local_repository for installed tools without sha256 checksum", func(t *testing.T) {
+		tools := []Tool{{Name: "corp/python", Version: "3.12.1", InstallPath: "/asdf/installs/corp/python/3.12.1", SourceURL: "https://example.com/python.tar.gz", Checksum: "md5:abc"}}
+		var stdout, stderr strings.Builder
+		assert.Nil(t, Write("bazel", tools, &stdout, &stderr))
+
+		assert.Contains(t, stdout.String(), "load(\"@bazel_tools//tools/build_defs/repo:local.bzl\", \"new_local_repository\")\n")
+		assert.Contains(t, stdout.String(), "# corp/python 3.12.1\nnew_local_repository(\n    name = \"asdf_corp_python\",\n    path = \"/asdf/installs/corp/python/3.12.1\",\n")
+		assert.NotContains(t, stdout.String(), "http.bzl")
+	})
+
+	t.Run("skips system and tools that are not installed", func(t *testing.T) {
+		tools := []Tool{{Name: "golang", Version: "system"}, {Name: "ruby", Version: "3.3.0"}}
+		var stdout, stderr strings.Builder
+		assert.Nil(t, Write("bazel", tools, &stdout, &stderr))
+
+		assert.Equal(t, "# Generated by asdf export\n", stdout.String())
+		assert.Contains(t, stderr.String(), "warning: skipping golang, system version is not managed by asdf\n")
+		assert.Contains(t, stderr.String(), "warning: skipping ruby, version 3.3.0 is not installed\n")
+	})
+}
@@ -0,0 +1,145 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+)
+
+// dockerImages maps official image names to the tools they provide. Image tags
+// are expected to start with the tool version, e.g. golang:1.22-alpine.
+var dockerImages = map[string]string{
+	"elixir":              "elixir",
+	"erlang":              "erlang",
+	"golang":              "golang",
+	"hashicorp/terraform": "terraform",
+	"node":                "nodejs",
+	"python":              "python",
+	"ruby":                "ruby",
+	"rust":                "rust",
+}
+
+type travisLanguage struct {
+	tool string
+	key  string
+}
+
+// travisLanguages maps the language of a Travis CI config to the tool it
+// selects and the key listing the versions of that tool.
+var travisLanguages = map[string]travisLanguage{
+	"elixir":  {tool: "elixir", key: "elixir"},
+	"erlang":  {tool: "erlang", key: "otp_release"},
+	"go":      {tool: "golang", key: "go"},
+	"node_js": {tool: "nodejs", key: "node_js"},
+	"python":  {tool: "python", key: "python"},
+	"ruby":    {tool: "ruby", key: "rvm"},
+	"rust":    {tool: "rust", key: "rust"},
+}
+
+// readDockerfile returns a tool for every FROM instruction that uses a known
+// image with a versioned tag. Only the first version of each tool is kept.
+func readDockerfile(in io.Reader) (tools []Tool, err error) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+
+		image := fields[1]
+		if strings.HasPrefix(image, "--") && len(fields) > 2 {
+			image = fields[2]
+		}
+
+		tool, ok := dockerImageTool(image)
+		if ok && !slices.ContainsFunc(tools, func(t Tool) bool { return t.Name == tool.Name }) {
+			tools = append(tools, tool)
+		}
+	}
+
+	return tools, scanner.Err()
+}
+
+func dockerImageTool(image string) (Tool, bool) {
+	image, _, _ = strings.Cut(image, "@")
+	name, tag, ok := strings.Cut(image, ":")
+	if !ok || strings.Contains(image, "$") {
+		return Tool{}, false
+	}
+
+	// Drop the registry host, which is the only path component that can
+	// contain a dot or port, and the implicit namespace of official images.
+	if host, rest, found := strings.Cut(name, "/"); found && strings.ContainsAny(host, ".:") {
+		name = rest
+	}
+	name = strings.TrimPrefix(name, "library/")
+
+	tool, ok := dockerImages[name]
+	if !ok {
+		return Tool{}, false
+	}
+
+	version, _, _ := strings.Cut(tag, "-")
+	if !numeric(strings.ReplaceAll(version, ".", "")) {
+		return Tool{}, false
+	}
+
+	return Tool{Name: tool, Version: version}, true
+}
+
+// readTravis reads the language of a Travis CI config and the first version
+// listed for it. Only the top level scalar and list forms of the keys are
+// understood, which covers the configs Travis generates.
+func readTravis(in io.Reader) (tools []Tool, err error) {
+	values := map[string][]string{}
+	var current string
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") && current != "" && line != trimmed {
+			values[current] = append(values[current], unquote(strings.TrimPrefix(trimmed, "- ")))
+			continue
+		}
+
+		current = ""
+		if line != trimmed {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+
+		current = key
+		if value = strings.TrimSpace(value); value != "" {
+			values[key] = append(values[key], unquote(value))
+			current = ""
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return tools, err
+	}
+
+	for _, language := range values["language"] {
+		entry, ok := travisLanguages[language]
+		if !ok || len(values[entry.key]) == 0 {
+			continue
+		}
+		tools = append(tools, Tool{Name: entry.tool, Version: values[entry.key][0]})
+	}
+
+	return tools, nil
+}
+
+func unquote(value string) string {
+	return strings.Trim(value, `"'`)
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDockerfile(t *testing.T) {
+	t.Run("reads versions from official image tags", func(t *testing.T) {
+		dockerfile := `FROM golang:1.22-alpine AS build
+RUN go build ./...
+
+from --platform=linux/amd64 docker.io/library/node:20.11.1-bookworm-slim
+FROM python:3.12@sha256:abc123
+FROM golang:1.21
+`
+		tools, err := Read("dockerfile", strings.NewReader(dockerfile))
+		assert.Nil(t, err)
+		assert.Equal(t, []Tool{{Name: "golang", Version: "1.22"}, {Name: "nodejs", Version: "20.11.1"}, {Name: "python", Version: "3.12"}}, tools)
+	})
+
+	t.Run("skips unknown images and unversioned tags", func(t *testing.T) {
+		dockerfile := "FROM ubuntu:22.04\nFROM ruby:latest\nFROM rust\nFROM node:${NODE_VERSION}\nFROM build\n"
+		tools, err := Read("dockerfile", strings.NewReader(dockerfile))
+		assert.Nil(t, err)
+		assert.Empty(t, tools)
+	})
+}
+
+func TestReadTravis(t *testing.T) {
+	t.Run("reads first version listed for language", func(t *testing.T) {
+		config := `language: node_js
+node_js:
+  - "20"
+  - 18
+script: npm test
+`
+		tools, err := Read("travis", strings.NewReader(config))
+		assert.Nil(t, err)
+		assert.Equal(t, []Tool{{Name: "nodejs", Version: "20"}}, tools)
+	})
+
+	t.Run("reads scalar version", func(t *testing.T) {
+		tools, err := Read("travis", strings.NewReader("language: ruby\nrvm: 3.3.0\n"))
+		assert.Nil(t, err)
+		assert.Equal(t, []Tool{{Name: "ruby", Version: "3.3.0"}}, tools)
+	})
+
+	t.Run("returns nothing when language has no version", func(t *testing.T) {
+		tools, err := Read("travis", strings.NewReader("language: python\nscript: pytest\n"))
+		assert.Nil(t, err)
+		assert.Empty(t, tools)
+	})
+}
@@ -0,0 +1,244 @@
+// Package export converts the set of tools and versions resolved for a project
+// into the formats used by other provisioning systems, and reads them back from
+// those formats where possible. The conversion is an approximation, tools that
+// have no known equivalent are skipped with a warning.
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Tool represents a single tool and the version resolved for it
+type Tool struct {
+	Name    string
+	Version string
+	// InstallPath is the directory the version is installed in, empty when it
+	// isn't installed
+	InstallPath string
+	// SourceURL and Checksum are taken from the provenance record of the
+	// install, when the plugin recorded them
+	SourceURL string
+	Checksum  string
+}
+
+// UnknownFormatError is returned when an export is requested in a format that
+// is not supported
+type UnknownFormatError struct {
+	format    string
+	supported []string
+}
+
+func (e UnknownFormatError) Error() string {
+	return fmt.Sprintf("unknown format %s, supported formats: %s", e.format, strings.Join(e.supported, ", "))
+}
+
+type (
+	writerFunc func(tools []Tool, out, warnings io.Writer) error
+	readerFunc func(in io.Reader) ([]Tool, error)
+)
+
+var writers = map[string]writerFunc{
+	"bazel":    writeBazel,
+	"brewfile": writeBrewfile,
+	"nix":      writeNix,
+	"renovate": writeRenovate,
+}
+
+var readers = map[string]readerFunc{
+	"dockerfile": readDockerfile,
+	"renovate":   readRenovate,
+	"travis":     readTravis,
+}
+
+// Formats returns the names of all supported export formats
+func Formats() []string {
+	var formats []string
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// ImportFormats returns the names of all formats tools can be read from
+func ImportFormats() []string {
+	var formats []string
+	for format := range readers {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// Read parses the tools and versions contained in a document of the given
+// format.
+func Read(format string, in io.Reader) ([]Tool, error) {
+	reader, ok := readers[format]
+	if !ok {
+		return []Tool{}, UnknownFormatError{format: format, supported: ImportFormats()}
+	}
+
+	return reader(in)
+}
+
+// Write writes tools to out in the requested format. Warnings about tools that
+// could not be mapped are written to warnings.
+func Write(format string, tools []Tool, out, warnings io.Writer) error {
+	writer, ok := writers[format]
+	if !ok {
+		return UnknownFormatError{format: format, supported: Formats()}
+	}
+
+	return writer(tools, out, warnings)
+}
+
+// mapping holds the names a tool goes by in another ecosystem. Versioned names
+// may contain {major} and {minor} placeholders which are filled in from the
+// resolved version. When the version can't be split the unversioned name is
+// used instead.
+type mapping struct {
+	name      string
+	versioned string
+}
+
+var nixPackages = map[string]mapping{
+	"golang":    {name: "go"},
+	"java":      {name: "jdk", versioned: "jdk{major}"},
+	"nodejs":    {name: "nodejs", versioned: "nodejs_{major}"},
+	"python":    {name: "python3", versioned: "python{major}{minor}"},
+	"ruby":      {name: "ruby", versioned: "ruby_{major}_{minor}"},
+	"rust":      {name: "rustc"},
+	"terraform": {name: "terraform"},
+	"yarn":      {name: "yarn"},
+	"pnpm":      {name: "pnpm"},
+	"erlang":    {name: "erlang"},
+	"elixir":    {name: "elixir"},
+	"kubectl":   {name: "kubectl"},
+	"helm":      {name: "kubernetes-helm"},
+	"jq":        {name: "jq"},
+}
+
+var brewFormulas = map[string]mapping{
+	"golang":    {name: "go", versioned: "go@{major}.{minor}"},
+	"java":      {name: "openjdk", versioned: "openjdk@{major}"},
+	"nodejs":    {name: "node", versioned: "node@{major}"},
+	"python":    {name: "python", versioned: "python@{major}.{minor}"},
+	"ruby":      {name: "ruby", versioned: "ruby@{major}.{minor}"},
+	"rust":      {name: "rust"},
+	"terraform": {name: "terraform"},
+	"yarn":      {name: "yarn"},
+	"pnpm":      {name: "pnpm"},
+	"erlang":    {name: "erlang", versioned: "erlang@{major}"},
+	"elixir":    {name: "elixir"},
+	"kubectl":   {name: "kubernetes-cli"},
+	"helm":      {name: "helm"},
+	"jq":        {name: "jq"},
+}
+
+func writeNix(tools []Tool, out, warnings io.Writer) error {
+	var packages strings.Builder
+	for _, tool := range mapTools("nix", nixPackages, tools, warnings) {
+		fmt.Fprintf(&packages, "            pkgs.%s # %s %s\n", tool.mapped, tool.Name, tool.Version)
+	}
+
+	_, err := fmt.Fprintf(out, `{
+  description = "Development environment generated by asdf export";
+
+  inputs.nixpkgs.url = "github:NixOS/nixpkgs/nixos-unstable";
+  inputs.flake-utils.url = "github:numtide/flake-utils";
+
+  outputs = { self, nixpkgs, flake-utils }:
+    flake-utils.lib.eachDefaultSystem (system:
+      let pkgs = nixpkgs.legacyPackages.${system}; in {
+        devShells.default = pkgs.mkShell {
+          packages = [
+%s          ];
+        };
+      });
+}
+`, packages.String())
+	return err
+}
+
+func writeBrewfile(tools []Tool, out, warnings io.Writer) error {
+	_, err := fmt.Fprintln(out, "# Generated by asdf export")
+	if err != nil {
+		return err
+	}
+
+	for _, tool := range mapTools("brewfile", brewFormulas, tools, warnings) {
+		_, err = fmt.Fprintf(out, "brew %q # %s %s\n", tool.mapped, tool.Name, tool.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mappedTool struct {
+	Tool
+	mapped string
+}
+
+func mapTools(format string, table map[string]mapping, tools []Tool, warnings io.Writer) (mapped []mappedTool) {
+	for _, tool := range tools {
+		version := toolversions.Parse(tool.Version)
+		if version.IsSystem() {
+			fmt.Fprintf(warnings, "warning: skipping %s, system version is not managed by asdf\n", tool.Name)
+			continue
+		}
+
+		entry, ok := table[tool.Name]
+		if !ok {
+			fmt.Fprintf(warnings, "warning: no %s mapping for %s, skipping\n", format, tool.Name)
+			continue
+		}
+
+		name, exact := expand(entry, version)
+		if !exact {
+			fmt.Fprintf(warnings, "warning: %s %s approximated as %s\n", tool.Name, tool.Version, name)
+		}
+		mapped = append(mapped, mappedTool{Tool: tool, mapped: name})
+	}
+
+	return mapped
+}
+
+// expand returns the name to use for the version along with a boolean
+// indicating whether the name pins the version.
+func expand(entry mapping, version toolversions.Version) (string, bool) {
+	if entry.versioned == "" {
+		return entry.name, false
+	}
+
+	segments := strings.Split(strings.TrimPrefix(version.Value, "v"), ".")
+	needed := 1
+	if strings.Contains(entry.versioned, "{minor}") {
+		needed = 2
+	}
+
+	if version.Type != "version" || len(segments) < needed {
+		return entry.name, false
+	}
+
+	for _, segment := range segments[:needed] {
+		if !numeric(segment) {
+			return entry.name, false
+		}
+	}
+
+	name := strings.ReplaceAll(entry.versioned, "{major}", segments[0])
+	if needed > 1 {
+		name = strings.ReplaceAll(name, "{minor}", segments[1])
+	}
+	return name, true
+}
+
+func numeric(str string) bool {
+	return str != "" && strings.Trim(str, "0123456789") == ""
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	tools := []Tool{
+		{Name: "nodejs", Version: "20.11.1"},
+		{Name: "python", Version: "3.12.1"},
+		{Name: "golang", Version: "system"},
+		{Name: "unknown-tool", Version: "1.0.0"},
+	}
+
+	t.Run("returns error when format is unknown", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		err := Write("foobar", tools, &stdout, &stderr)
+		assert.ErrorContains(t, err, "unknown format foobar")
+		assert.Empty(t, stdout.String())
+	})
+
+	t.Run("writes brewfile with versioned formulas", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		err := Write("brewfile", tools, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		expected := "# Generated by asdf export\nbrew \"node@20\" # nodejs 20.11.1\nbrew \"python@3.12\" # python 3.12.1\n"
+		assert.Equal(t, expected, stdout.String())
+		assert.Contains(t, stderr.String(), "warning: skipping golang, system version is not managed by asdf\n")
+		assert.Contains(t, stderr.String(), "warning: no brewfile mapping for unknown-tool, skipping\n")
+	})
+
+	t.Run("returns error when brewfile can't be written", func(t *testing.T) {
+		var stderr strings.Builder
+		err := Write("brewfile", []Tool{{Name: "golang", Version: "system"}}, failingWriter{}, &stderr)
+		assert.ErrorIs(t, err, errWriteFailed)
+	})
+
+	t.Run("writes nix flake with mapped packages", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		err := Write("nix", tools, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		assert.Contains(t, stdout.String(), "pkgs.nodejs_20 # nodejs 20.11.1\n")
+		assert.Contains(t, stdout.String(), "pkgs.python312 # python 3.12.1\n")
+		assert.Contains(t, stdout.String(), "devShells.default = pkgs.mkShell")
+		assert.Contains(t, stderr.String(), "warning: no nix mapping for unknown-tool, skipping\n")
+	})
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		desc     string
+		entry    mapping
+		version  string
+		expected string
+		exact    bool
+	}{
+		{desc: "unversioned mapping", entry: mapping{name: "jq"}, version: "1.7", expected: "jq", exact: false},
+		{desc: "major only", entry: mapping{name: "node", versioned: "node@{major}"}, version: "20", expected: "node@20", exact: true},
+		{desc: "major and minor", entry: mapping{name: "go", versioned: "go@{major}.{minor}"}, version: "1.22.1", expected: "go@1.22", exact: true},
+		{desc: "missing minor", entry: mapping{name: "go", versioned: "go@{major}.{minor}"}, version: "1", expected: "go", exact: false},
+		{desc: "non numeric version", entry: mapping{name: "node", versioned: "node@{major}"}, version: "lts", expected: "node", exact: false},
+		{desc: "ref version", entry: mapping{name: "node", versioned: "node@{major}"}, version: "ref:v20.0.0", expected: "node", exact: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			name, exact := expand(tt.entry, toolversions.Parse(tt.version))
+			assert.Equal(t, tt.expected, name)
+			assert.Equal(t, tt.exact, exact)
+		})
+	}
+}
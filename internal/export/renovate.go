@@ -0,0 +1,81 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// renovateManifest is the normalized JSON document written by the renovate
+// format. It is meant to be consumed by Renovate or Dependabot custom managers
+// which propose updates to the versions listed in it.
+type renovateManifest struct {
+	Tools []renovateTool `json:"tools"`
+}
+
+type renovateTool struct {
+	Name         string `json:"name"`
+	CurrentValue string `json:"currentValue"`
+	Datasource   string `json:"datasource,omitempty"`
+	DepName      string `json:"depName,omitempty"`
+}
+
+type datasource struct {
+	datasource string
+	depName    string
+}
+
+var renovateDatasources = map[string]datasource{
+	"golang":    {datasource: "golang-version", depName: "go"},
+	"java":      {datasource: "java-version", depName: "java"},
+	"nodejs":    {datasource: "node-version", depName: "node"},
+	"python":    {datasource: "python-version", depName: "python"},
+	"ruby":      {datasource: "ruby-version", depName: "ruby"},
+	"rust":      {datasource: "github-tags", depName: "rust-lang/rust"},
+	"terraform": {datasource: "github-releases", depName: "hashicorp/terraform"},
+	"erlang":    {datasource: "github-tags", depName: "erlang/otp"},
+	"elixir":    {datasource: "github-tags", depName: "elixir-lang/elixir"},
+	"kubectl":   {datasource: "github-releases", depName: "kubernetes/kubernetes"},
+	"helm":      {datasource: "github-releases", depName: "helm/helm"},
+	"yarn":      {datasource: "npm", depName: "yarn"},
+	"pnpm":      {datasource: "npm", depName: "pnpm"},
+}
+
+func writeRenovate(tools []Tool, out, warnings io.Writer) error {
+	manifest := renovateManifest{Tools: []renovateTool{}}
+
+	for _, tool := range tools {
+		entry := renovateTool{Name: tool.Name, CurrentValue: tool.Version}
+		if source, ok := renovateDatasources[tool.Name]; ok {
+			entry.Datasource = source.datasource
+			entry.DepName = source.depName
+		} else {
+			fmt.Fprintf(warnings, "warning: no renovate datasource known for %s\n", tool.Name)
+		}
+		manifest.Tools = append(manifest.Tools, entry)
+	}
+
+	slices.SortFunc(manifest.Tools, func(a, b renovateTool) int { return strings.Compare(a.Name, b.Name) })
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
+
+func readRenovate(in io.Reader) (tools []Tool, err error) {
+	var manifest renovateManifest
+	if err := json.NewDecoder(in).Decode(&manifest); err != nil {
+		return tools, fmt.Errorf("unable to parse renovate manifest: %w", err)
+	}
+
+	for _, entry := range manifest.Tools {
+		if entry.Name == "" || entry.CurrentValue == "" {
+			return tools, fmt.Errorf("renovate manifest entry missing name or currentValue")
+		}
+		tools = append(tools, Tool{Name: entry.Name, Version: entry.CurrentValue})
+	}
+
+	return tools, nil
+}
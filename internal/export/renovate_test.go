@@ -0,0 +1,74 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenovate(t *testing.T) {
+	t.Run("writes normalized manifest sorted by tool name", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		tools := []Tool{{Name: "python", Version: "3.12.1"}, {Name: "custom", Version: "1.0"}, {Name: "nodejs", Version: "20.11.1"}}
+
+		err := Write("renovate", tools, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		expected := `{
+  "tools": [
+    {
+      "name": "custom",
+      "currentValue": "1.0"
+    },
+    {
+      "name": "nodejs",
+      "currentValue": "20.11.1",
+      "datasource": "node-version",
+      "depName": "node"
+    },
+    {
+      "name": "python",
+      "currentValue": "3.12.1",
+      "datasource": "python-version",
+      "depName": "python"
+    }
+  ]
+}
+`
+		assert.Equal(t, expected, stdout.String())
+		assert.Equal(t, "warning: no renovate datasource known for custom\n", stderr.String())
+	})
+
+	t.Run("writes empty tools list when no tools", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		err := Write("renovate", []Tool{}, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, "{\n  \"tools\": []\n}\n", stdout.String())
+	})
+
+	t.Run("reads back written manifest", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		tools := []Tool{{Name: "nodejs", Version: "20.11.1"}, {Name: "python", Version: "3.12.1"}}
+		assert.Nil(t, Write("renovate", tools, &stdout, &stderr))
+
+		readTools, err := Read("renovate", strings.NewReader(stdout.String()))
+		assert.Nil(t, err)
+		assert.Equal(t, tools, readTools)
+	})
+
+	t.Run("returns error when manifest is invalid", func(t *testing.T) {
+		_, err := Read("renovate", strings.NewReader("not json"))
+		assert.ErrorContains(t, err, "unable to parse renovate manifest")
+	})
+
+	t.Run("returns error when entry lacks version", func(t *testing.T) {
+		_, err := Read("renovate", strings.NewReader(`{"tools": [{"name": "nodejs"}]}`))
+		assert.EqualError(t, err, "renovate manifest entry missing name or currentValue")
+	})
+
+	t.Run("returns error when import format unknown", func(t *testing.T) {
+		_, err := Read("nix", strings.NewReader(""))
+		assert.EqualError(t, err, "unknown format nix, supported formats: dockerfile, renovate, travis")
+	})
+}
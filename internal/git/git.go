@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/asdf-vm/asdf/internal/execute"
@@ -15,6 +17,8 @@ import (
 // DefaultRemoteName for Git repositories in asdf
 const DefaultRemoteName = "origin"
 
+var commitHash = regexp.MustCompile("^[0-9a-f]{40}$")
+
 // Repoer is an interface for operations that can be applied to asdf plugins.
 // Right now we only support Git, but in the future we might have other
 // mechanisms to install and upgrade plugins. asdf doesn't require a plugin
@@ -70,6 +74,50 @@ func (r Repo) Head() (string, error) {
 	return strings.TrimSpace(stdout), nil
 }
 
+// Unborn returns true if the HEAD of the plugin's Git repository points at a
+// branch without a commit, as left by a clone killed before it checked one
+// out. The repository files are read rather than running Git, so a Git that
+// can't be run isn't mistaken for a broken repository.
+func (r Repo) Unborn() (bool, error) {
+	gitDir := filepath.Join(r.Directory, ".git")
+	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		return false, err
+	}
+
+	if _, err := os.Stat(filepath.Join(gitDir, "reftable")); err == nil {
+		return false, errors.New("unable to read refs stored in reftable format")
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return false, err
+	}
+
+	ref, symbolic := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !symbolic {
+		return false, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	packedRefs, err := os.ReadFile(filepath.Join(gitDir, "packed-refs"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(packedRefs), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == ref {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 // RemoteURL returns the URL of the default remote for the plugin's Git repository
 func (r Repo) RemoteURL() (string, error) {
 	err := repositoryExists(r.Directory)
@@ -86,6 +134,100 @@ func (r Repo) RemoteURL() (string, error) {
 	return stdout, err
 }
 
+// Repack packs loose objects and removes unreachable ones in the plugin's Git
+// repository
+func (r Repo) Repack() error {
+	err := repositoryExists(r.Directory)
+	if err != nil {
+		return err
+	}
+
+	_, stderr, err := exec([]string{"git", "-C", r.Directory, "gc", "--quiet"})
+	if err != nil {
+		return errors.New(stdErrToErrMsg(stderr))
+	}
+
+	return nil
+}
+
+// GC removes the data years of plugin updates leave behind in the plugin's Git
+// repository. Remote-tracking branches deleted from the remote are dropped, the
+// reflogs keeping old commits reachable are expired, and loose objects are
+// packed and unreachable ones removed. Dropping remote-tracking branches needs
+// the remote, it is skipped when the remote can't be reached.
+func (r Repo) GC() error {
+	err := repositoryExists(r.Directory)
+	if err != nil {
+		return err
+	}
+
+	_, _, _ = exec([]string{"git", "-C", r.Directory, "remote", "prune", DefaultRemoteName})
+
+	for _, command := range [][]string{
+		{"git", "-C", r.Directory, "reflog", "expire", "--expire=now", "--all"},
+		{"git", "-C", r.Directory, "gc", "--prune=now", "--quiet"},
+	} {
+		_, stderr, err := exec(command)
+		if err != nil {
+			return errors.New(stdErrToErrMsg(stderr))
+		}
+	}
+
+	return nil
+}
+
+// Toplevel returns the root of the working tree of the repository containing
+// the directory
+func (r Repo) Toplevel() (string, error) {
+	stdout, stderr, err := exec([]string{"git", "-C", r.Directory, "rev-parse", "--show-toplevel"})
+	if err != nil {
+		return "", errors.New(stdErrToErrMsg(stderr))
+	}
+
+	return strings.TrimSpace(stdout), nil
+}
+
+// Commit returns the hash of the commit the ref points to
+func (r Repo) Commit(ref string) (string, error) {
+	stdout, stderr, err := exec([]string{"git", "-C", r.Directory, "rev-parse", "--verify", "--quiet", ref + "^{commit}"})
+	if err != nil {
+		if msg := stdErrToErrMsg(stderr); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", fmt.Errorf("unknown revision %s", ref)
+	}
+
+	return strings.TrimSpace(stdout), nil
+}
+
+// Files returns the names of the files in a directory of the repository at
+// the commit. The directory is relative to the root of the repository, an
+// empty string or `.` lists the root.
+func (r Repo) Files(commit, directory string) ([]string, error) {
+	treeish := commit
+	if directory != "" && directory != "." {
+		treeish = commit + ":" + directory
+	}
+
+	stdout, stderr, err := exec([]string{"git", "-C", r.Directory, "ls-tree", "--name-only", treeish})
+	if err != nil {
+		return nil, errors.New(stdErrToErrMsg(stderr))
+	}
+
+	return strings.Split(strings.TrimSuffix(stdout, "\n"), "\n"), nil
+}
+
+// Show returns the contents of a file of the repository at the commit. The
+// path is relative to the root of the repository.
+func (r Repo) Show(commit, path string) (string, error) {
+	stdout, stderr, err := exec([]string{"git", "-C", r.Directory, "show", commit + ":" + path})
+	if err != nil {
+		return "", errors.New(stdErrToErrMsg(stderr))
+	}
+
+	return stdout, nil
+}
+
 // Update updates the plugin's Git repository to the ref if provided, or the
 // latest commit on the current branch
 func (r Repo) Update(ref string) (string, string, string, error) {
@@ -134,6 +276,36 @@ func (r Repo) Update(ref string) (string, string, string, error) {
 	return ref, oldHash, newHash, nil
 }
 
+// RemoteCommit returns the commit a branch or tag points to in the repository
+// at url. Refs that are already commit hashes are returned as is, since they
+// can't move.
+func RemoteCommit(url, ref string) (string, error) {
+	if commitHash.MatchString(ref) {
+		return ref, nil
+	}
+
+	stdout, stderr, err := exec([]string{"git", "ls-remote", url, ref, ref + "^{}"})
+	if err != nil {
+		return "", errors.New(stdErrToErrMsg(stderr))
+	}
+
+	commits := map[string]string{}
+	for _, line := range strings.Split(stdout, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			commits[fields[1]] = fields[0]
+		}
+	}
+
+	// Annotated tags are listed twice, the peeled ^{} entry is the commit
+	for _, name := range []string{"refs/heads/" + ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref, ref} {
+		if commit, ok := commits[name]; ok {
+			return commit, nil
+		}
+	}
+
+	return "", fmt.Errorf("ref %s not found in %s", ref, url)
+}
+
 func (r Repo) defaultRemote() (string, error) {
 	stdout, _, err := exec([]string{"git", "-C", r.Directory, "remote"})
 	if err != nil {
@@ -3,6 +3,7 @@ package git
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/asdf-vm/asdf/internal/repotest"
@@ -79,6 +80,57 @@ func TestRepoHead(t *testing.T) {
 	assert.NotZero(t, head)
 }
 
+func TestRepoUnborn(t *testing.T) {
+	t.Run("returns true when HEAD points at branch without commit", func(t *testing.T) {
+		directory := t.TempDir()
+		_, _, err := exec([]string{"git", "init", "--quiet", directory})
+		assert.Nil(t, err)
+
+		unborn, err := NewRepo(directory).Unborn()
+		assert.Nil(t, err)
+		assert.True(t, unborn)
+	})
+
+	t.Run("returns false when commit is checked out", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, NewRepo(directory).Clone(generateRepo(t), ""))
+
+		unborn, err := NewRepo(directory).Unborn()
+		assert.Nil(t, err)
+		assert.False(t, unborn)
+	})
+
+	t.Run("returns false when branch is in packed refs", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, NewRepo(directory).Clone(generateRepo(t), ""))
+		_, _, err := exec([]string{"git", "-C", directory, "pack-refs", "--all"})
+		assert.Nil(t, err)
+
+		unborn, err := NewRepo(directory).Unborn()
+		assert.Nil(t, err)
+		assert.False(t, unborn)
+	})
+
+	t.Run("returns false when Git can't be run", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, NewRepo(directory).Clone(generateRepo(t), ""))
+		t.Setenv("PATH", "")
+
+		unborn, err := NewRepo(directory).Unborn()
+		assert.Nil(t, err)
+		assert.False(t, unborn)
+	})
+
+	t.Run("returns error when HEAD can't be read", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, os.MkdirAll(filepath.Join(directory, ".git", "HEAD"), 0o777))
+
+		unborn, err := NewRepo(directory).Unborn()
+		assert.Error(t, err)
+		assert.False(t, unborn)
+	})
+}
+
 func TestRepoRemoteURL(t *testing.T) {
 	repoDir := generateRepo(t)
 	directory := t.TempDir()
@@ -210,6 +262,151 @@ func TestRepoUpdate(t *testing.T) {
 	})
 }
 
+func TestRemoteCommit(t *testing.T) {
+	repoDir := generateRepo(t)
+	head, err := getCurrentCommit(repoDir)
+	assert.Nil(t, err)
+
+	t.Run("returns commit branch points to", func(t *testing.T) {
+		branch, _, err := exec([]string{"git", "-C", repoDir, "rev-parse", "--abbrev-ref", "HEAD"})
+		assert.Nil(t, err)
+
+		commit, err := RemoteCommit(repoDir, strings.TrimSpace(branch))
+		assert.Nil(t, err)
+		assert.Equal(t, head, commit)
+	})
+
+	t.Run("returns commit annotated tag points to", func(t *testing.T) {
+		_, _, err := exec([]string{"git", "-C", repoDir, "-c", "user.name=asdf", "-c", "user.email=asdf@example.com", "tag", "-a", "v1.0.0", "-m", "v1.0.0"})
+		assert.Nil(t, err)
+
+		commit, err := RemoteCommit(repoDir, "v1.0.0")
+		assert.Nil(t, err)
+		assert.Equal(t, head, commit)
+	})
+
+	t.Run("returns commit hash as is", func(t *testing.T) {
+		commit, err := RemoteCommit("/nonexistent", head)
+		assert.Nil(t, err)
+		assert.Equal(t, head, commit)
+	})
+
+	t.Run("returns error when ref does not exist", func(t *testing.T) {
+		_, err := RemoteCommit(repoDir, "nonexistent")
+		assert.EqualError(t, err, "ref nonexistent not found in "+repoDir)
+	})
+}
+
+func TestRepoToplevel(t *testing.T) {
+	repoDir := generateRepo(t)
+
+	toplevel, err := NewRepo(filepath.Join(repoDir, "bin")).Toplevel()
+	assert.Nil(t, err)
+
+	expected, err := filepath.EvalSymlinks(repoDir)
+	assert.Nil(t, err)
+	actual, err := filepath.EvalSymlinks(toplevel)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestRepoGC(t *testing.T) {
+	t.Run("returns error when directory is not a repository", func(t *testing.T) {
+		err := NewRepo(t.TempDir()).GC()
+		assert.ErrorContains(t, err, "not a git repository")
+	})
+
+	t.Run("drops remote-tracking branches deleted from remote and packs objects", func(t *testing.T) {
+		repoDir := generateRepo(t)
+		_, _, err := exec([]string{"git", "-C", repoDir, "branch", "stale"})
+		assert.Nil(t, err)
+		directory := t.TempDir()
+		assert.Nil(t, NewRepo(directory).Clone(repoDir, ""))
+		_, _, err = exec([]string{"git", "-C", repoDir, "branch", "-D", "stale"})
+		assert.Nil(t, err)
+
+		assert.Nil(t, NewRepo(directory).GC())
+
+		refs, _, err := exec([]string{"git", "-C", directory, "branch", "--remotes"})
+		assert.Nil(t, err)
+		assert.NotContains(t, refs, "origin/stale")
+
+		loose, err := filepath.Glob(filepath.Join(directory, ".git", "objects", "??", "*"))
+		assert.Nil(t, err)
+		assert.Empty(t, loose)
+	})
+
+	t.Run("cleans up repository when remote can't be reached", func(t *testing.T) {
+		repoDir := generateRepo(t)
+		directory := t.TempDir()
+		assert.Nil(t, NewRepo(directory).Clone(repoDir, ""))
+		assert.Nil(t, os.RemoveAll(repoDir))
+
+		assert.Nil(t, NewRepo(directory).GC())
+	})
+}
+
+func TestRepoCommit(t *testing.T) {
+	repoDir := generateRepo(t)
+	repo := NewRepo(repoDir)
+
+	t.Run("returns commit ref points to", func(t *testing.T) {
+		expected, err := getCurrentCommit(repoDir)
+		assert.Nil(t, err)
+
+		commit, err := repo.Commit("HEAD")
+		assert.Nil(t, err)
+		assert.Equal(t, expected, commit)
+	})
+
+	t.Run("returns error when ref does not exist", func(t *testing.T) {
+		_, err := repo.Commit("non-existent")
+		assert.ErrorContains(t, err, "unknown revision non-existent")
+	})
+}
+
+func TestRepoFiles(t *testing.T) {
+	repoDir := generateRepo(t)
+	repo := NewRepo(repoDir)
+
+	t.Run("lists files in root of repository", func(t *testing.T) {
+		files, err := repo.Files("HEAD", ".")
+		assert.Nil(t, err)
+		assert.Contains(t, files, "LICENSE")
+		assert.Contains(t, files, "bin")
+	})
+
+	t.Run("lists files in directory of repository", func(t *testing.T) {
+		files, err := repo.Files("HEAD", "bin")
+		assert.Nil(t, err)
+		assert.Contains(t, files, "list-all")
+	})
+
+	t.Run("returns error when directory does not exist", func(t *testing.T) {
+		_, err := repo.Files("HEAD", "non-existent")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRepoShow(t *testing.T) {
+	repoDir := generateRepo(t)
+	repo := NewRepo(repoDir)
+
+	t.Run("returns contents of file at commit", func(t *testing.T) {
+		expected, err := os.ReadFile(filepath.Join(repoDir, "bin", "list-all"))
+		assert.Nil(t, err)
+
+		contents, err := repo.Show("HEAD", "bin/list-all")
+		assert.Nil(t, err)
+		assert.Equal(t, string(expected), contents)
+	})
+
+	t.Run("returns error when file does not exist", func(t *testing.T) {
+		_, err := repo.Show("HEAD", "non-existent")
+		assert.NotNil(t, err)
+	})
+}
+
 func getCurrentCommit(path string) (string, error) {
 	return getCommit(path, "HEAD")
 }
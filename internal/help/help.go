@@ -3,13 +3,17 @@
 package help
 
 import (
-	_ "embed"
+	"embed"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"strings"
 
+	"github.com/asdf-vm/asdf/internal/callbackenv"
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/messages"
 	"github.com/asdf-vm/asdf/internal/plugins"
 	"github.com/asdf-vm/asdf/internal/toolversions"
 )
@@ -17,11 +21,28 @@ import (
 //go:embed help.txt
 var helpText string
 
+// commonText lists the commands most people need, shown unless every command
+// is asked for
+//
+//go:embed common.txt
+var commonText string
+
+// examples holds the examples of asdf commands, in a file named after the
+// words of the command joined with underscores, such as plugin_add.txt. Each
+// example is a comment describing it followed by the command.
+//
+//go:embed examples/*.txt
+var examples embed.FS
+
 const quote = "\"Late but latest\"\n-- Rajinikanth"
 
-// Print help output to STDOUT
-func Print(asdfVersion string, plugins []plugins.Plugin) error {
-	return Write(asdfVersion, plugins, os.Stdout)
+// helpCallbacks are the plugin documentation callbacks in the order they are
+// rendered. Only help.overview is required.
+var helpCallbacks = []string{"help.overview", "help.deps", "help.config", "help.links"}
+
+// Print help output to STDOUT, only the common commands unless all is true
+func Print(asdfVersion string, plugins []plugins.Plugin, all bool) error {
+	return Write(asdfVersion, plugins, all, os.Stdout)
 }
 
 // PrintTool write tool help output to STDOUT
@@ -34,14 +55,20 @@ func PrintToolVersion(conf config.Config, toolName, toolVersion string) error {
 	return WriteToolVersionHelp(conf, toolName, toolVersion, os.Stdout, os.Stderr)
 }
 
-// Write help output to an io.Writer
-func Write(asdfVersion string, allPlugins []plugins.Plugin, writer io.Writer) error {
+// Write help output to an io.Writer. Only the common commands are listed
+// unless all is true, along with how to list every command.
+func Write(asdfVersion string, allPlugins []plugins.Plugin, all bool, writer io.Writer) error {
 	_, err := writer.Write([]byte(fmt.Sprintf("version: %s\n\n", asdfVersion)))
 	if err != nil {
 		return err
 	}
 
-	_, err = writer.Write([]byte(helpText))
+	text := messages.HelpTopic("common", commonText)
+	if all {
+		text = messages.Help(helpText)
+	}
+
+	_, err = writer.Write([]byte(text))
 	if err != nil {
 		return err
 	}
@@ -80,6 +107,28 @@ func Write(asdfVersion string, allPlugins []plugins.Plugin, writer io.Writer) er
 	return nil
 }
 
+// Examples returns the examples of an asdf command, given as its words such as
+// `plugin add`, and false when it has none
+func Examples(command string) (string, bool) {
+	contents, err := examples.ReadFile(path.Join("examples", strings.Join(strings.Fields(command), "_")+".txt"))
+	if err != nil {
+		return "", false
+	}
+
+	return string(contents), true
+}
+
+// WriteExamples writes the examples of an asdf command to an io.Writer
+func WriteExamples(command string, writer io.Writer) error {
+	text, ok := Examples(command)
+	if !ok {
+		return fmt.Errorf("no examples for asdf %s", command)
+	}
+
+	_, err := fmt.Fprintf(writer, "EXAMPLES\n%s", text)
+	return err
+}
+
 // WriteToolHelp output to an io.Writer
 func WriteToolHelp(conf config.Config, toolName string, writer io.Writer, errWriter io.Writer) error {
 	return writePluginHelp(conf, toolName, "", writer, errWriter)
@@ -92,48 +141,49 @@ func WriteToolVersionHelp(conf config.Config, toolName, toolVersion string, writ
 
 func writePluginHelp(conf config.Config, toolName, toolVersion string, writer io.Writer, errWriter io.Writer) error {
 	plugin := plugins.New(conf, toolName)
-	env := map[string]string{
-		"ASDF_INSTALL_PATH": plugin.Dir,
-	}
+	callbackEnv := callbackenv.Env{InstallPath: plugin.Dir}
 
 	if toolVersion != "" {
 		version := toolversions.Parse(toolVersion)
-		env["ASDF_INSTALL_VERSION"] = version.Value
-		env["ASDF_INSTALL_TYPE"] = version.Type
+		callbackEnv.InstallVersion = version.Value
+		callbackEnv.InstallType = version.Type
+		if installs.IsInstalled(conf, plugin, version) {
+			callbackEnv.InstallPath = installs.InstallPath(conf, plugin, version)
+		}
 	}
+	env := callbackEnv.Map()
 
 	if err := plugin.Exists(); err != nil {
 		errWriter.Write([]byte(fmt.Sprintf("No plugin named %s\n", plugin.Name)))
 		return err
 	}
 
-	err := plugin.RunCallback("help.overview", []string{}, env, writer, errWriter)
-	if _, ok := err.(plugins.NoCallbackError); ok {
-		// No such callback, print err msg
-		errWriter.Write([]byte(fmt.Sprintf("No documentation for plugin %s\n", plugin.Name)))
-		return err
-	}
-
-	if err != nil {
-		return err
-	}
-
-	err = plugin.RunCallback("help.deps", []string{}, env, writer, errWriter)
-	if _, ok := err.(plugins.NoCallbackError); !ok {
-		return err
-	}
+	var sections []string
+	for _, callback := range helpCallbacks {
+		var output strings.Builder
+		err := plugin.RunCallback(callback, []string{}, env, &output, errWriter)
+		if _, ok := err.(plugins.NoCallbackError); ok {
+			if callback == "help.overview" {
+				// No such callback, print err msg
+				errWriter.Write([]byte(fmt.Sprintf("No documentation for plugin %s\n", plugin.Name)))
+				return err
+			}
+			continue
+		}
 
-	err = plugin.RunCallback("help.config", []string{}, env, writer, errWriter)
-	if _, ok := err.(plugins.NoCallbackError); !ok {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	err = plugin.RunCallback("help.links", []string{}, env, writer, errWriter)
-	if _, ok := err.(plugins.NoCallbackError); !ok {
-		return err
+		if section := strings.TrimRight(output.String(), "\n"); section != "" {
+			sections = append(sections, section)
+		}
 	}
 
-	return nil
+	// Each callback renders its own section, separate them with a blank line so
+	// the combined output reads as a single document.
+	_, err := writer.Write([]byte(strings.Join(sections, "\n\n") + "\n"))
+	return err
 }
 
 func pluginExtensionCommands(plugins []plugins.Plugin) (string, error) {
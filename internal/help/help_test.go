@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -30,19 +31,98 @@ func TestWrite(t *testing.T) {
 	plugin := plugins.New(conf, testPluginName)
 	writeExtensionCommand(t, plugin, "", "")
 
-	var stdout strings.Builder
+	t.Run("lists common commands", func(t *testing.T) {
+		var stdout strings.Builder
+
+		err = Write(version, []plugins.Plugin{plugin}, false, &stdout)
+		assert.Nil(t, err)
+		output := stdout.String()
+
+		assert.Contains(t, output, "version: ")
+		assert.Contains(t, output, "COMMON COMMANDS\n")
+		assert.Contains(t, output, "asdf help --all")
+		assert.NotContains(t, output, "UTILS\n")
+		assert.Contains(t, output, "RESOURCES\n")
+		assert.Contains(t, output, "PLUGIN lua\n")
+	})
 
-	err = Write(version, []plugins.Plugin{plugin}, &stdout)
+	t.Run("lists every command when all is true", func(t *testing.T) {
+		var stdout strings.Builder
+
+		err = Write(version, []plugins.Plugin{plugin}, true, &stdout)
+		assert.Nil(t, err)
+		output := stdout.String()
+
+		// Simple format assertions
+		assert.Contains(t, output, "version: ")
+		assert.Contains(t, output, "MANAGE PLUGINS\n")
+		assert.Contains(t, output, "MANAGE TOOLS\n")
+		assert.Contains(t, output, "UTILS\n")
+		assert.Contains(t, output, "RESOURCES\n")
+		assert.Contains(t, output, "PLUGIN lua\n")
+	})
+}
+
+// TestExamples checks the examples of each command only run that command with
+// flags documented for it in the help text, so they don't drift from the
+// commands they show
+func TestExamples(t *testing.T) {
+	files, err := examples.ReadDir("examples")
 	assert.Nil(t, err)
-	output := stdout.String()
-
-	// Simple format assertions
-	assert.Contains(t, output, "version: ")
-	assert.Contains(t, output, "MANAGE PLUGINS\n")
-	assert.Contains(t, output, "MANAGE TOOLS\n")
-	assert.Contains(t, output, "UTILS\n")
-	assert.Contains(t, output, "RESOURCES\n")
-	assert.Contains(t, output, "PLUGIN lua\n")
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		command := strings.ReplaceAll(strings.TrimSuffix(file.Name(), ".txt"), "_", " ")
+		t.Run(command, func(t *testing.T) {
+			var usage []string
+			for _, line := range strings.Split(helpText, "\n") {
+				if strings.HasPrefix(line+" ", "asdf "+command+" ") {
+					usage = append(usage, strings.Fields(line)...)
+				}
+			}
+			assert.NotEmpty(t, usage, "asdf %s is not in help.txt", command)
+
+			text, ok := Examples(command)
+			assert.True(t, ok)
+
+			described := false
+			for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+				switch {
+				case line == "":
+					described = false
+				case strings.HasPrefix(line, "# "):
+					described = true
+				default:
+					assert.True(t, described, "example %q has no description", line)
+					assert.True(t, strings.HasPrefix(line+" ", "asdf "+command+" "), "example %q doesn't run asdf %s", line, command)
+
+					for _, word := range strings.Fields(line) {
+						flag, _, _ := strings.Cut(word, "=")
+						if strings.HasPrefix(flag, "-") {
+							assert.True(t, slices.ContainsFunc(usage, func(usageWord string) bool { return strings.Contains(usageWord, flag) }), "flag %s of example %q is not in help.txt", flag, line)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteExamples(t *testing.T) {
+	t.Run("writes examples of command", func(t *testing.T) {
+		var stdout strings.Builder
+
+		assert.Nil(t, WriteExamples("plugin add", &stdout))
+		assert.True(t, strings.HasPrefix(stdout.String(), "EXAMPLES\n# "))
+		assert.Contains(t, stdout.String(), "asdf plugin add nodejs\n")
+	})
+
+	t.Run("returns error when command has no examples", func(t *testing.T) {
+		var stdout strings.Builder
+
+		assert.Error(t, WriteExamples("non-existent", &stdout))
+		assert.Empty(t, stdout.String())
+	})
 }
 
 func TestWriteToolHelp(t *testing.T) {
@@ -60,6 +140,21 @@ func TestWriteToolHelp(t *testing.T) {
 		assert.Equal(t, stdout.String(), expected)
 	})
 
+	t.Run("when plugin implements several help callbacks separates each section", func(t *testing.T) {
+		var stdout strings.Builder
+		var stderr strings.Builder
+		plugin := installPlugin(t, conf, "dummy_plugin", "sectioned-plugin")
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "help.deps", "#!/usr/bin/env bash\necho 'Deps: none'\n"))
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "help.links", "#!/usr/bin/env bash\necho 'Links: none'\necho\n"))
+
+		err := WriteToolHelp(conf, plugin.Name, &stdout, &stderr)
+
+		assert.Nil(t, err)
+		assert.Empty(t, stderr.String())
+		expected := "Dummy plugin documentation\n\nDummy plugin is a plugin only used for unit tests\n\nDeps: none\n\nLinks: none\n"
+		assert.Equal(t, expected, stdout.String())
+	})
+
 	t.Run("when plugin does not have help.overview callback", func(t *testing.T) {
 		var stdout strings.Builder
 		var stderr strings.Builder
@@ -3,22 +3,118 @@
 package hook
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
 	"github.com/asdf-vm/asdf/internal/execute"
+	"github.com/asdf-vm/asdf/internal/lock"
 )
 
+const (
+	// LogFilename is the name of the log in the logs directory the output of
+	// hooks is written to when the hook_output setting is `log`. It is moved
+	// to a file with .1 appended once it grows past MaxLogSize.
+	LogFilename = "hooks.log"
+	// MaxLogSize is the size in bytes past which the log is rotated
+	MaxLogSize = 1024 * 1024
+)
+
+// Entry is the record of a hook run in the hook log
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Hook     string    `json:"hook"`
+	Args     []string  `json:"args"`
+	ExitCode int       `json:"exit_code"`
+	Stdout   string    `json:"stdout,omitempty"`
+	Stderr   string    `json:"stderr,omitempty"`
+}
+
+// FailedError is returned for a failing hook when the hook_failure_policy
+// setting is `fail`, or `auto` for hooks that aren't optional
+type FailedError struct {
+	Hook string
+	// Log is the path of the log the output of the hook was written to, empty
+	// when the output wasn't logged
+	Log string
+	Err error
+}
+
+func (e FailedError) Error() string {
+	if e.Log != "" {
+		return fmt.Sprintf("hook %s failed: %s, its output is in %s", e.Hook, e.Err, e.Log)
+	}
+	return fmt.Sprintf("hook %s failed: %s", e.Hook, e.Err)
+}
+
+func (e FailedError) Unwrap() error {
+	return e.Err
+}
+
 // Run gets a hook command from config and runs it with the provided arguments.
 // Output is sent to STDOUT and STDERR
 func Run(conf config.Config, hookName string, arguments []string) error {
 	return RunWithOutput(conf, hookName, arguments, os.Stdout, os.Stderr)
 }
 
+// RunOptional runs a hook like Run for commands that carried on when their
+// hooks failed before the hook_failure_policy setting was added, plugin add,
+// update and remove. A failing hook is ignored unless the setting is set.
+func RunOptional(conf config.Config, hookName string, arguments []string) error {
+	return run(context.Background(), conf, hookName, arguments, nil, os.Stdout, os.Stderr, true, true)
+}
+
 // RunWithOutput gets a hook command from config and runs it with the provided
 // arguments. Output is sent to the provided io.Writers.
 func RunWithOutput(config config.Config, hookName string, arguments []string, stdOut io.Writer, stdErr io.Writer) error {
+	return RunWithEnv(config, hookName, arguments, nil, stdOut, stdErr)
+}
+
+// RunWithEnv gets a hook command from config and runs it with the provided
+// arguments, adding env to the environment so hooks get the same context as
+// the plugin callbacks run alongside them. Output is sent to the provided
+// io.Writers, or to the hook log when the hook_output setting is `log`. A
+// failing hook returns a FailedError, prints a warning to stdErr or is
+// ignored, as set by the hook_failure_policy setting.
+func RunWithEnv(config config.Config, hookName string, arguments []string, env map[string]string, stdOut io.Writer, stdErr io.Writer) error {
+	return RunWithEnvContext(context.Background(), config, hookName, arguments, env, stdOut, stdErr)
+}
+
+// RunWithEnvContext runs a hook like RunWithEnv, killing it when the context is
+// done before it exits. The error of the context is returned in that case.
+func RunWithEnvContext(ctx context.Context, config config.Config, hookName string, arguments []string, env map[string]string, stdOut io.Writer, stdErr io.Writer) error {
+	return run(ctx, config, hookName, arguments, env, stdOut, stdErr, true, false)
+}
+
+// Capture runs a hook whose output is its result, like resolution_missing,
+// writing what it prints to STDOUT to stdOut even when the output of hooks is
+// logged. Only what it prints to STDERR is logged.
+func Capture(config config.Config, hookName string, arguments []string, stdOut io.Writer) error {
+	return CaptureContext(context.Background(), config, hookName, arguments, stdOut)
+}
+
+// CaptureContext runs a hook like Capture, killing it when the context is done
+// before it exits. The error of the context is returned in that case, whatever
+// the hook_failure_policy setting, as the caller gave up on the result.
+func CaptureContext(ctx context.Context, config config.Config, hookName string, arguments []string, stdOut io.Writer) error {
+	return run(ctx, config, hookName, arguments, nil, stdOut, os.Stderr, false, false)
+}
+
+// LogPath returns the path of the current hook log
+func LogPath(dataDir string) string {
+	return filepath.Join(data.LogsDirectory(dataDir), LogFilename)
+}
+
+func run(ctx context.Context, config config.Config, hookName string, arguments []string, env map[string]string, stdOut, stdErr io.Writer, logStdOut, optional bool) error {
 	hookCmd, err := config.GetHook(hookName)
 	if err != nil {
 		return err
@@ -28,10 +124,151 @@ func RunWithOutput(config config.Config, hookName string, arguments []string, st
 		return nil
 	}
 
+	policy, err := config.HookFailurePolicy()
+	if err != nil {
+		return err
+	}
+
+	output, err := config.HookOutput()
+	if err != nil {
+		return err
+	}
+
 	cmd := execute.NewExpression(hookCmd, arguments)
 
+	cmd.Env = env
 	cmd.Stdout = stdOut
 	cmd.Stderr = stdErr
 
-	return cmd.Run()
+	var loggedStdOut, loggedStdErr strings.Builder
+	if output == "log" {
+		if logStdOut {
+			cmd.Stdout = &loggedStdOut
+		}
+		cmd.Stderr = &loggedStdErr
+	}
+
+	err = cmd.RunContext(ctx)
+
+	logPath := ""
+	if output == "log" {
+		entry := Entry{
+			Time:     time.Now().UTC(),
+			Hook:     hookName,
+			Args:     arguments,
+			ExitCode: exitCode(err),
+			Stdout:   loggedStdOut.String(),
+			Stderr:   loggedStdErr.String(),
+		}
+		if logErr := appendEntry(config.DataDir, entry); logErr != nil {
+			warn(stdErr, "unable to write hook log: %s", logErr)
+		} else {
+			logPath = LogPath(config.DataDir)
+		}
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	failure := FailedError{Hook: hookName, Log: logPath, Err: err}
+	switch {
+	case policy == "ignore", policy == "auto" && optional:
+		return nil
+	case policy == "warn":
+		warn(stdErr, "%s", failure)
+		return nil
+	}
+
+	return failure
+}
+
+// ReadLog returns the entries of the current hook log, oldest first
+func ReadLog(dataDir string) (entries []Entry, err error) {
+	contents, err := os.ReadFile(LogPath(dataDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return entries, err
+	}
+
+	for i, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return entries, fmt.Errorf("invalid hook log entry on line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// appendEntry adds the entry to the hook log, moving the log to .1 first when
+// it has grown past MaxLogSize. Hooks of concurrent commands may finish at the
+// same time, so the log is locked while it is written.
+func appendEntry(dataDir string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	dir := data.LogsDirectory(dataDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	logLock, err := lock.Acquire(dataDir, lock.HookLog, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer logLock.Release()
+
+	logPath := LogPath(dataDir)
+	if info, err := os.Stat(logPath); err == nil && info.Size()+int64(len(line))+1 > MaxLogSize {
+		if err := os.Rename(logPath, logPath+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// exitCode returns the exit code of a hook that ran, or -1 if it couldn't be
+// run at all
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func warn(stdErr io.Writer, format string, args ...any) {
+	if stdErr == nil {
+		stdErr = os.Stderr
+	}
+	fmt.Fprintf(stdErr, "warning: "+format+"\n", args...)
 }
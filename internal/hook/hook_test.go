@@ -1,7 +1,12 @@
 package hook
 
 import (
+	"context"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/asdf-vm/asdf/internal/config"
@@ -25,7 +30,9 @@ func TestRun(t *testing.T) {
 		assert.Nil(t, err)
 
 		err = Run(config, "pre_asdf_plugin_add_test2", []string{"123"})
-		assert.Equal(t, 123, err.(*exec.ExitError).ExitCode())
+		var exitErr *exec.ExitError
+		assert.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, 123, exitErr.ExitCode())
 	})
 
 	t.Run("passes arguments to command", func(t *testing.T) {
@@ -33,7 +40,9 @@ func TestRun(t *testing.T) {
 		assert.Nil(t, err)
 
 		err = Run(config, "pre_asdf_plugin_add_test3", []string{"exit 123"})
-		assert.Equal(t, 123, err.(*exec.ExitError).ExitCode())
+		var exitErr *exec.ExitError
+		assert.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, 123, exitErr.ExitCode())
 	})
 
 	t.Run("does not return error when no such hook is defined in asdfrc", func(t *testing.T) {
@@ -44,3 +53,137 @@ func TestRun(t *testing.T) {
 		assert.Nil(t, err)
 	})
 }
+
+func TestRunWithEnv(t *testing.T) {
+	t.Setenv("ASDF_CONFIG_FILE", "testdata/asdfrc")
+
+	t.Run("adds variables to environment of command", func(t *testing.T) {
+		config, err := config.LoadConfig()
+		assert.Nil(t, err)
+
+		var stdout strings.Builder
+		err = RunWithEnv(config, "pre_asdf_plugin_add_test3", []string{"echo $ASDF_INSTALL_VERSION"}, map[string]string{"ASDF_INSTALL_VERSION": "1.2.3"}, &stdout, &stdout)
+		assert.Nil(t, err)
+		assert.Equal(t, "1.2.3\n", stdout.String())
+	})
+
+	t.Run("does not return error when no such hook is defined in asdfrc", func(t *testing.T) {
+		config, err := config.LoadConfig()
+		assert.Nil(t, err)
+
+		err = RunWithEnv(config, "nonexistent-hook", []string{}, map[string]string{"A": "b"}, nil, nil)
+		assert.Nil(t, err)
+	})
+}
+
+func TestRunWithEnvContext(t *testing.T) {
+	t.Setenv("ASDF_CONFIG_FILE", "testdata/asdfrc")
+
+	t.Run("returns error of context when it is done", func(t *testing.T) {
+		config, err := config.LoadConfig()
+		assert.Nil(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = RunWithEnvContext(ctx, config, "pre_asdf_plugin_add_test3", []string{"sleep 5"}, nil, io.Discard, io.Discard)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRunFailurePolicy(t *testing.T) {
+	writeConfig := func(t *testing.T, settings string) config.Config {
+		configFile := filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(configFile, []byte("failing_hook = echo failing $1 >&2; exit 3\n"+settings), 0o666))
+		return config.Config{DataDir: t.TempDir(), ConfigFile: configFile}
+	}
+
+	t.Run("returns error naming hook by default", func(t *testing.T) {
+		conf := writeConfig(t, "")
+
+		var stderr strings.Builder
+		err := RunWithOutput(conf, "failing_hook", []string{"1.0.0"}, &stderr, &stderr)
+		var failedErr FailedError
+		assert.ErrorAs(t, err, &failedErr)
+		assert.Equal(t, "failing_hook", failedErr.Hook)
+		assert.ErrorContains(t, err, "hook failing_hook failed: exit status 3")
+		assert.Equal(t, "failing 1.0.0\n", stderr.String())
+	})
+
+	t.Run("prints warning when policy is warn", func(t *testing.T) {
+		conf := writeConfig(t, "hook_failure_policy = warn\n")
+
+		var stderr strings.Builder
+		err := RunWithOutput(conf, "failing_hook", []string{"1.0.0"}, &stderr, &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, "failing 1.0.0\nwarning: hook failing_hook failed: exit status 3\n", stderr.String())
+	})
+
+	t.Run("ignores failure when policy is ignore", func(t *testing.T) {
+		conf := writeConfig(t, "hook_failure_policy = ignore\n")
+
+		var stderr strings.Builder
+		err := RunWithOutput(conf, "failing_hook", []string{"1.0.0"}, &stderr, &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, "failing 1.0.0\n", stderr.String())
+	})
+
+	t.Run("ignores failure of optional hook by default", func(t *testing.T) {
+		conf := writeConfig(t, "")
+		assert.Nil(t, RunOptional(conf, "failing_hook", []string{"1.0.0"}))
+	})
+
+	t.Run("returns error for optional hook when policy is fail", func(t *testing.T) {
+		conf := writeConfig(t, "hook_failure_policy = fail\n")
+		err := RunOptional(conf, "failing_hook", []string{"1.0.0"})
+		assert.ErrorContains(t, err, "hook failing_hook failed: exit status 3")
+	})
+}
+
+func TestRunHookOutput(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "asdfrc")
+	settings := "hook_output = log\nhook_failure_policy = warn\nnoisy_hook = echo out $1; echo err >&2\nfailing_hook = exit 3\n"
+	assert.Nil(t, os.WriteFile(configFile, []byte(settings), 0o666))
+	conf := config.Config{DataDir: t.TempDir(), ConfigFile: configFile}
+
+	t.Run("writes output to log instead of writers", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		err := RunWithOutput(conf, "noisy_hook", []string{"1.0.0"}, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.Empty(t, stdout.String())
+		assert.Empty(t, stderr.String())
+
+		entries, err := ReadLog(conf.DataDir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "noisy_hook", entries[0].Hook)
+		assert.Equal(t, []string{"1.0.0"}, entries[0].Args)
+		assert.Zero(t, entries[0].ExitCode)
+		assert.Equal(t, "out 1.0.0\n", entries[0].Stdout)
+		assert.Equal(t, "err\n", entries[0].Stderr)
+	})
+
+	t.Run("logs exit code and points warning at log", func(t *testing.T) {
+		var stderr strings.Builder
+		err := RunWithOutput(conf, "failing_hook", []string{}, &stderr, &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, "warning: hook failing_hook failed: exit status 3, its output is in "+LogPath(conf.DataDir)+"\n", stderr.String())
+
+		entries, err := ReadLog(conf.DataDir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, 3, entries[1].ExitCode)
+	})
+
+	t.Run("writes output of captured hook to writer", func(t *testing.T) {
+		var stdout strings.Builder
+		err := Capture(conf, "noisy_hook", []string{"2.0.0"}, &stdout)
+		assert.Nil(t, err)
+		assert.Equal(t, "out 2.0.0\n", stdout.String())
+
+		entries, err := ReadLog(conf.DataDir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 3)
+		assert.Empty(t, entries[2].Stdout)
+		assert.Equal(t, "err\n", entries[2].Stderr)
+	})
+}
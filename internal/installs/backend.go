@@ -0,0 +1,199 @@
+package installs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Names of the backends, matching the values of the installs_backend setting
+const (
+	// BackendDirectory lists the subdirectories of the install directory
+	BackendDirectory = "directory"
+	// BackendIndex reads an index file in the install directory that is kept
+	// up to date as versions are installed and uninstalled, so listing
+	// versions is a single read even with thousands of installs on a
+	// networked file system
+	BackendIndex = "index"
+)
+
+// IndexFilename is the name of the file in the install directory of a tool the
+// index backend stores the installed versions in. Removing it makes the index
+// backend rebuild it from the install directory.
+const IndexFilename = ".asdf-installs.json"
+
+// Backend keeps track of the versions installed in an install directory, the
+// directory holding one subdirectory per installed version of a tool. Install
+// directories are created and removed by asdf itself, the backend is told
+// about each change so it can keep any metadata it stores elsewhere in sync.
+// Alternative backends, such as a metadata index shared over the network,
+// implement this interface and are added to backends.
+type Backend interface {
+	// Versions returns the versions installed in the install directory, in
+	// any order
+	Versions(installDirectory string) ([]string, error)
+	// Added records that the version was installed in the install directory
+	Added(installDirectory, version string) error
+	// Removed records that the version was removed from the install
+	// directory
+	Removed(installDirectory, version string) error
+}
+
+var backends = map[string]Backend{
+	BackendDirectory: directoryBackend{},
+	BackendIndex:     indexBackend{},
+}
+
+// backend returns the backend selected by the installs_backend setting
+func backend(conf config.Config) Backend {
+	name, _ := conf.InstallsBackend()
+	if b, ok := backends[name]; ok {
+		return b
+	}
+
+	return backends[BackendDirectory]
+}
+
+// Added tells the configured backend a version was installed at the install
+// path
+func Added(conf config.Config, installPath string) error {
+	return backend(conf).Added(filepath.Dir(installPath), toolversions.VersionStringFromFSFormat(filepath.Base(installPath)))
+}
+
+// Removed tells the configured backend the version installed at the install
+// path was removed
+func Removed(conf config.Config, installPath string) error {
+	return backend(conf).Removed(filepath.Dir(installPath), toolversions.VersionStringFromFSFormat(filepath.Base(installPath)))
+}
+
+type directoryBackend struct{}
+
+func (directoryBackend) Versions(installDirectory string) (versions []string, err error) {
+	files, err := os.ReadDir(installDirectory)
+	if err != nil {
+		if _, ok := err.(*fs.PathError); ok {
+			return versions, nil
+		}
+
+		return versions, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		versions = append(versions, toolversions.VersionStringFromFSFormat(file.Name()))
+	}
+
+	return versions, err
+}
+
+func (directoryBackend) Added(string, string) error {
+	return nil
+}
+
+func (directoryBackend) Removed(string, string) error {
+	return nil
+}
+
+type indexBackend struct{}
+
+func (b indexBackend) Versions(installDirectory string) (versions []string, err error) {
+	contents, err := os.ReadFile(filepath.Join(installDirectory, IndexFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return b.rebuild(installDirectory)
+	}
+	if err != nil {
+		return versions, err
+	}
+
+	if err := json.Unmarshal(contents, &versions); err != nil {
+		return versions, fmt.Errorf("invalid installs index %s: %w", filepath.Join(installDirectory, IndexFilename), err)
+	}
+
+	return versions, nil
+}
+
+func (b indexBackend) Added(installDirectory, version string) error {
+	versions, err := b.Versions(installDirectory)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(versions, version) {
+		return nil
+	}
+
+	return writeIndex(installDirectory, append(versions, version))
+}
+
+func (b indexBackend) Removed(installDirectory, version string) error {
+	versions, err := b.Versions(installDirectory)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(versions, version) {
+		return nil
+	}
+
+	return writeIndex(installDirectory, slices.DeleteFunc(versions, func(v string) bool { return v == version }))
+}
+
+// rebuild lists the install directory and writes the versions found to the
+// index. Failing to write the index isn't an error, the install directory may
+// be read only, as a shared install directory often is.
+func (indexBackend) rebuild(installDirectory string) ([]string, error) {
+	versions, err := directoryBackend{}.Versions(installDirectory)
+	if err != nil || len(versions) == 0 {
+		return versions, err
+	}
+
+	_ = writeIndex(installDirectory, versions)
+	return versions, nil
+}
+
+func writeIndex(installDirectory string, versions []string) error {
+	versions = slices.Clone(versions)
+	slices.Sort(versions)
+	if versions == nil {
+		versions = []string{}
+	}
+
+	contents, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(installDirectory, 0o777); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial index
+	tmpFile, err := os.CreateTemp(installDirectory, IndexFilename+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(append(contents, '\n'))
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), 0o644)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filepath.Join(installDirectory, IndexFilename))
+}
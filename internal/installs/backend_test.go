@@ -0,0 +1,84 @@
+package installs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexBackend(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("installs_backend = index\n"), 0o666))
+	indexFile := filepath.Join(conf.DataDir, "installs", "lua", IndexFilename)
+
+	mockInstall(t, conf, plugin, "1.0.0")
+	mockInstall(t, conf, plugin, "ref-main")
+
+	t.Run("builds index from install directory when missing", func(t *testing.T) {
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "ref:main"}, installedVersions)
+		assert.FileExists(t, indexFile)
+	})
+
+	t.Run("lists versions from index without reading install directory", func(t *testing.T) {
+		assert.Nil(t, os.MkdirAll(filepath.Join(conf.DataDir, "installs", "lua", "3.0.0"), 0o777))
+
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "ref:main"}, installedVersions)
+	})
+
+	t.Run("Added adds version to index", func(t *testing.T) {
+		installPath := InstallPath(conf, plugin, toolversions.Version{Type: "version", Value: "2.0.0"})
+		assert.Nil(t, Added(conf, installPath))
+		assert.Nil(t, Added(conf, installPath))
+
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "ref:main"}, installedVersions)
+	})
+
+	t.Run("Removed removes version from index", func(t *testing.T) {
+		installPath := InstallPath(conf, plugin, toolversions.Version{Type: "ref", Value: "main"})
+		assert.Nil(t, Removed(conf, installPath))
+
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0"}, installedVersions)
+	})
+
+	t.Run("returns error when index is invalid", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(indexFile, []byte("{"), 0o666))
+
+		_, err := Installed(conf, plugin)
+		assert.ErrorContains(t, err, "invalid installs index")
+	})
+}
+
+func TestDirectoryBackend(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	mockInstall(t, conf, plugin, "1.0.0")
+	installPath := InstallPath(conf, plugin, toolversions.Version{Type: "version", Value: "1.0.0"})
+
+	t.Run("lists install directories and ignores other files", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(filepath.Dir(installPath), IndexFilename), []byte("[]\n"), 0o666))
+
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0"}, installedVersions)
+	})
+
+	t.Run("Added and Removed do nothing", func(t *testing.T) {
+		assert.Nil(t, Added(conf, installPath))
+		assert.Nil(t, Removed(conf, installPath))
+
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0"}, installedVersions)
+	})
+}
@@ -0,0 +1,152 @@
+package installs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/provenance"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+const (
+	incompleteFilename  = "incomplete"
+	quarantinedFilename = "quarantined"
+)
+
+// Reasons an install is excluded from the installed versions, matching the
+// values of the exclude_installs setting
+const (
+	// ReasonIncomplete is given for installs that were started but never
+	// finished, usually because asdf was interrupted
+	ReasonIncomplete = "incomplete"
+	// ReasonQuarantined is given for installs quarantined by a failed verify
+	ReasonQuarantined = "quarantined"
+	// ReasonPlatform is given for installs built for another OS or
+	// architecture, such as those in a data directory shared between machines
+	ReasonPlatform = "platform"
+)
+
+// Exclusion is an installed version left out of the installed versions and
+// why
+type Exclusion struct {
+	Version string
+	Reason  string
+	Detail  string
+}
+
+// Filter splits versions into those considered installed and those excluded
+// by the exclude_installs setting. Versions are checked for being incomplete,
+// quarantined and built for another platform, in that order, and the first
+// matching reason is given.
+func Filter(conf config.Config, plugin plugins.Plugin, versions []string) (kept []string, excluded []Exclusion) {
+	reasons, _ := conf.ExcludeInstalls()
+	if len(reasons) == 0 {
+		return versions, excluded
+	}
+
+	for _, versionStr := range versions {
+		exclusion, ok := check(conf, plugin, versionStr)
+		if ok && slices.Contains(reasons, exclusion.Reason) {
+			excluded = append(excluded, exclusion)
+			continue
+		}
+
+		kept = append(kept, versionStr)
+	}
+
+	return kept, excluded
+}
+
+// check returns why an installed version is not usable, if it isn't
+func check(conf config.Config, plugin plugins.Plugin, versionStr string) (Exclusion, bool) {
+	metadataDir := MetadataPath(conf, plugin, toolversions.Parse(versionStr))
+
+	if _, err := os.Stat(filepath.Join(metadataDir, incompleteFilename)); err == nil {
+		return Exclusion{Version: versionStr, Reason: ReasonIncomplete}, true
+	}
+
+	if contents, err := os.ReadFile(filepath.Join(metadataDir, quarantinedFilename)); err == nil {
+		return Exclusion{Version: versionStr, Reason: ReasonQuarantined, Detail: strings.TrimSpace(string(contents))}, true
+	}
+
+	record, err := provenance.Read(metadataDir, plugin.Name, versionStr)
+	if err == nil && record.Platform != "" && record.Platform != Platform() {
+		return Exclusion{Version: versionStr, Reason: ReasonPlatform, Detail: "built for " + record.Platform}, true
+	}
+
+	return Exclusion{}, false
+}
+
+// Platform returns the OS and architecture installs are recorded as built for
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// MarkIncomplete records that an install of the version has started. Until
+// MarkComplete is called the version is excluded as incomplete.
+func MarkIncomplete(conf config.Config, plugin plugins.Plugin, version toolversions.Version) error {
+	return writeMarker(MetadataPath(conf, plugin, version), incompleteFilename, "")
+}
+
+// MarkComplete records that an install of the version has finished
+func MarkComplete(conf config.Config, plugin plugins.Plugin, version toolversions.Version) error {
+	return removeMarker(MetadataPath(conf, plugin, version), incompleteFilename)
+}
+
+// Incomplete returns true if an install of the version was started but never
+// finished, whether or not the exclude_installs setting leaves it out
+func Incomplete(conf config.Config, plugin plugins.Plugin, version toolversions.Version) bool {
+	_, err := os.Stat(filepath.Join(MetadataPath(conf, plugin, version), incompleteFilename))
+	return err == nil
+}
+
+// Quarantine excludes an installed version until it is released, recording
+// the reason given
+func Quarantine(conf config.Config, plugin plugins.Plugin, version toolversions.Version, reason string) error {
+	return writeMarker(MetadataPath(conf, plugin, version), quarantinedFilename, reason+"\n")
+}
+
+// Release removes a version from quarantine. Versions that aren't quarantined
+// are left as they are.
+func Release(conf config.Config, plugin plugins.Plugin, version toolversions.Version) error {
+	return removeMarker(MetadataPath(conf, plugin, version), quarantinedFilename)
+}
+
+// Verify compares an installed version against the manifest written when it
+// was installed, see provenance.Verify. Unchanged versions are released from
+// quarantine and changed ones are quarantined. A provenance.NoManifestError is
+// returned for installs without a manifest.
+func Verify(conf config.Config, plugin plugins.Plugin, version toolversions.Version) (provenance.Differences, error) {
+	differences, err := provenance.Verify(MetadataPath(conf, plugin, version), InstallPath(conf, plugin, version))
+	if err != nil {
+		return differences, err
+	}
+
+	if differences.Empty() {
+		return differences, Release(conf, plugin, version)
+	}
+
+	return differences, Quarantine(conf, plugin, version, "files changed since install")
+}
+
+func writeMarker(metadataDir, name, contents string) error {
+	if err := os.MkdirAll(metadataDir, 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(metadataDir, name), []byte(contents), 0o666)
+}
+
+func removeMarker(metadataDir, name string) error {
+	err := os.Remove(filepath.Join(metadataDir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
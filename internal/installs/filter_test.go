@@ -0,0 +1,71 @@
+package installs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/provenance"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	for _, version := range []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"} {
+		mockInstall(t, conf, plugin, version)
+	}
+
+	assert.Nil(t, MarkIncomplete(conf, plugin, toolversions.Parse("1.0.0")))
+	assert.Nil(t, Quarantine(conf, plugin, toolversions.Parse("2.0.0"), "files changed since install"))
+	record := provenance.Record{Tool: testPluginName, Version: "3.0.0", Platform: "plan9/mips"}
+	assert.Nil(t, provenance.Write(MetadataPath(conf, plugin, toolversions.Parse("3.0.0")), record))
+
+	t.Run("excludes incomplete, quarantined and other platform installs by default", func(t *testing.T) {
+		kept, excluded := Filter(conf, plugin, []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"})
+		assert.Equal(t, []string{"4.0.0"}, kept)
+		assert.Equal(t, []Exclusion{
+			{Version: "1.0.0", Reason: ReasonIncomplete},
+			{Version: "2.0.0", Reason: ReasonQuarantined, Detail: "files changed since install"},
+			{Version: "3.0.0", Reason: ReasonPlatform, Detail: "built for plan9/mips"},
+		}, excluded)
+	})
+
+	t.Run("Installed leaves out excluded versions and All includes them", func(t *testing.T) {
+		installed, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"4.0.0"}, installed)
+
+		all, err := All(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"}, all)
+	})
+
+	t.Run("excludes only the installs set in exclude_installs", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("exclude_installs = quarantined\n"), 0o666))
+
+		kept, _ := Filter(conf, plugin, []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"})
+		assert.Equal(t, []string{"1.0.0", "3.0.0", "4.0.0"}, kept)
+	})
+
+	t.Run("excludes nothing when exclude_installs is none", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("exclude_installs = none\n"), 0o666))
+
+		kept, excluded := Filter(conf, plugin, []string{"1.0.0", "2.0.0"})
+		assert.Equal(t, []string{"1.0.0", "2.0.0"}, kept)
+		assert.Empty(t, excluded)
+	})
+
+	t.Run("MarkComplete and Release include versions again", func(t *testing.T) {
+		assert.Nil(t, MarkComplete(conf, plugin, toolversions.Parse("1.0.0")))
+		assert.Nil(t, Release(conf, plugin, toolversions.Parse("2.0.0")))
+		assert.Nil(t, Release(conf, plugin, toolversions.Parse("4.0.0")))
+
+		kept, _ := Filter(conf, plugin, []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"})
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "4.0.0"}, kept)
+	})
+}
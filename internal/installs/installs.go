@@ -7,43 +7,149 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/asdf-vm/asdf/internal/config"
 	"github.com/asdf-vm/asdf/internal/data"
 	"github.com/asdf-vm/asdf/internal/plugins"
 	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+	"golang.org/x/sys/unix"
 )
 
-// Installed returns a slice of all installed versions for a given plugin
+// Installed returns a slice of the installed versions for a given plugin from
+// oldest to newest, leaving out the installs excluded by the exclude_installs
+// setting. When a shared install directory is configured versions installed
+// there are included as well.
 func Installed(conf config.Config, plugin plugins.Plugin) (versions []string, err error) {
-	installDirectory := data.InstallDirectory(conf.DataDir, plugin.Name)
-	files, err := os.ReadDir(installDirectory)
+	versions, err = All(conf, plugin)
 	if err != nil {
-		if _, ok := err.(*fs.PathError); ok {
-			return versions, nil
-		}
+		return versions, err
+	}
 
+	versions, _ = Filter(conf, plugin, versions)
+	return versions, nil
+}
+
+// All returns a slice of every version with an install directory for a given
+// plugin from oldest to newest, with refs last, including those Installed
+// leaves out. Versions are listed by the backend selected with the
+// installs_backend setting.
+func All(conf config.Config, plugin plugins.Plugin) (versions []string, err error) {
+	installsBackend := backend(conf)
+	versions, err = installsBackend.Versions(data.InstallDirectory(conf.DataDir, plugin.Name))
+	if err != nil {
 		return versions, err
 	}
 
-	for _, file := range files {
-		if !file.IsDir() {
-			continue
+	if sharedDir, ok := sharedInstallDirectory(conf, plugin); ok {
+		sharedVersions, err := installsBackend.Versions(sharedDir)
+		if err != nil {
+			return versions, err
 		}
 
-		versions = append(versions, toolversions.VersionStringFromFSFormat(file.Name()))
+		for _, version := range sharedVersions {
+			if !slices.Contains(versions, version) {
+				versions = append(versions, version)
+			}
+		}
 	}
 
-	return versions, err
+	slices.SortFunc(versions, compareInstalled)
+	return versions, nil
 }
 
-// InstallPath returns the path to a tool installation
+// InstallPath returns the path to a tool installation. Installs in the current
+// users data directory take precedence over installs in the shared install
+// directory.
 func InstallPath(conf config.Config, plugin plugins.Plugin, version toolversions.Version) string {
 	if version.Type == "path" {
 		return version.Value
 	}
 
-	return filepath.Join(data.InstallDirectory(conf.DataDir, plugin.Name), toolversions.FormatForFS(version))
+	userPath := filepath.Join(data.InstallDirectory(conf.DataDir, plugin.Name), toolversions.FormatForFS(version))
+	if _, err := os.Stat(userPath); err == nil {
+		return userPath
+	}
+
+	if sharedDir, ok := sharedInstallDirectory(conf, plugin); ok {
+		sharedPath := filepath.Join(sharedDir, toolversions.FormatForFS(version))
+		if _, err := os.Stat(sharedPath); err == nil {
+			return sharedPath
+		}
+	}
+
+	return userPath
+}
+
+// InstallTarget returns the path a new install of the version should be
+// written to. New installs go into the shared install directory when one is
+// configured and the current user may write to it, otherwise they go into the
+// users data directory. The returned boolean is true for shared installs.
+func InstallTarget(conf config.Config, plugin plugins.Plugin, version toolversions.Version) (string, bool) {
+	root, err := conf.SharedInstallDir()
+	if err == nil && root != "" && version.Type != "path" && unix.Access(root, unix.W_OK) == nil {
+		return filepath.Join(data.InstallDirectory(root, plugin.Name), toolversions.FormatForFS(version)), true
+	}
+
+	return InstallPath(conf, plugin, version), false
+}
+
+// SetSharedPermissions makes an install in the shared install directory
+// readable by all users while ensuring only the owner can modify it.
+// Directories and executables become 0755, all other files 0644. Symlinks are
+// left untouched as changing them would change the files they point to.
+func SetSharedPermissions(installPath string) error {
+	return filepath.WalkDir(installPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := fs.FileMode(0o644)
+		if entry.IsDir() || info.Mode().Perm()&0o100 != 0 {
+			mode = 0o755
+		}
+
+		return os.Chmod(path, mode)
+	})
+}
+
+func sharedInstallDirectory(conf config.Config, plugin plugins.Plugin) (string, bool) {
+	root, err := conf.SharedInstallDir()
+	if err != nil || root == "" {
+		return "", false
+	}
+
+	return data.InstallDirectory(root, plugin.Name), true
+}
+
+// MetadataPath returns the directory records about an installed version are
+// stored in. Records are kept in the same root directory as the install, so
+// versions in the shared install directory have their records there too.
+func MetadataPath(conf config.Config, plugin plugins.Plugin, version toolversions.Version) string {
+	userPath := filepath.Join(data.MetadataDirectory(conf.DataDir, plugin.Name), toolversions.FormatForFS(version))
+	if _, err := os.Stat(filepath.Join(data.InstallDirectory(conf.DataDir, plugin.Name), toolversions.FormatForFS(version))); err == nil {
+		return userPath
+	}
+
+	root, err := conf.SharedInstallDir()
+	if err == nil && root != "" {
+		if _, err := os.Stat(filepath.Join(data.InstallDirectory(root, plugin.Name), toolversions.FormatForFS(version))); err == nil {
+			return filepath.Join(data.MetadataDirectory(root, plugin.Name), toolversions.FormatForFS(version))
+		}
+	}
+
+	return userPath
 }
 
 // DownloadPath returns the download path for a particular plugin and version
@@ -55,11 +161,35 @@ func DownloadPath(conf config.Config, plugin plugins.Plugin, version toolversion
 	return filepath.Join(data.DownloadDirectory(conf.DataDir, plugin.Name), toolversions.FormatForFS(version))
 }
 
-// IsInstalled checks if a specific version of a tool is installed
+// IsInstalled checks if a specific version of a tool is installed. Like the
+// versions returned by Installed, versions excluded by the exclude_installs
+// setting, such as installs that never finished, aren't installed.
 func IsInstalled(conf config.Config, plugin plugins.Plugin, version toolversions.Version) bool {
-	installDir := InstallPath(conf, plugin, version)
+	if !Exists(conf, plugin, version) {
+		return false
+	}
+
+	kept, _ := Filter(conf, plugin, []string{toolversions.Format(version)})
+	return len(kept) > 0
+}
 
-	// Check if version already installed
-	_, err := os.Stat(installDir)
+// Exists checks if the install directory of a version exists, whether or not
+// the version is excluded from the installed versions
+func Exists(conf config.Config, plugin plugins.Plugin, version toolversions.Version) bool {
+	_, err := os.Stat(InstallPath(conf, plugin, version))
 	return !os.IsNotExist(err)
 }
+
+// compareInstalled orders installed versions from oldest to newest, with refs
+// after all versions as they can't be compared to them
+func compareInstalled(a, b string) int {
+	aRef, bRef := strings.HasPrefix(a, "ref:"), strings.HasPrefix(b, "ref:")
+	if aRef != bRef {
+		if aRef {
+			return 1
+		}
+		return -1
+	}
+
+	return versionspec.CompareStrings(a, b)
+}
@@ -63,6 +63,16 @@ func TestInstalled(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, installedVersions, []string{"1.0.0"})
 	})
+
+	t.Run("returns versions from oldest to newest with refs last", func(t *testing.T) {
+		mockInstall(t, conf, plugin, "ref-main")
+		mockInstall(t, conf, plugin, "1.10.0")
+		mockInstall(t, conf, plugin, "1.9.0")
+
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "1.9.0", "1.10.0", "ref:main"}, installedVersions)
+	})
 }
 
 func TestIsInstalled(t *testing.T) {
@@ -77,9 +87,84 @@ func TestIsInstalled(t *testing.T) {
 		version := toolversions.Version{Type: "version", Value: "1.0.0"}
 		assert.True(t, IsInstalled(conf, plugin, version))
 	})
+	t.Run("returns false when install never finished", func(t *testing.T) {
+		version := toolversions.Version{Type: "version", Value: "2.0.0"}
+		installVersion(t, conf, plugin, "2.0.0")
+		assert.Nil(t, MarkIncomplete(conf, plugin, version))
+		assert.False(t, IsInstalled(conf, plugin, version))
+		assert.True(t, Exists(conf, plugin, version))
+	})
+}
+
+func TestSharedInstallDir(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	sharedDir := t.TempDir()
+	conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+	err := os.WriteFile(conf.ConfigFile, []byte("shared_install_dir = "+sharedDir+"\n"), 0o666)
+	assert.Nil(t, err)
+
+	sharedVersion := toolversions.Version{Type: "version", Value: "2.0.0"}
+	sharedPath := filepath.Join(sharedDir, "installs", "lua", "2.0.0")
+	assert.Nil(t, os.MkdirAll(sharedPath, 0o777))
+	mockInstall(t, conf, plugin, "1.0.0")
+
+	t.Run("Installed returns versions from data dir and shared dir", func(t *testing.T) {
+		installedVersions, err := Installed(conf, plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0"}, installedVersions)
+	})
+
+	t.Run("InstallPath returns shared path when only installed in shared dir", func(t *testing.T) {
+		assert.Equal(t, sharedPath, InstallPath(conf, plugin, sharedVersion))
+		assert.True(t, IsInstalled(conf, plugin, sharedVersion))
+	})
+
+	t.Run("InstallPath prefers data dir install over shared install", func(t *testing.T) {
+		assert.Nil(t, os.MkdirAll(filepath.Join(sharedDir, "installs", "lua", "1.0.0"), 0o777))
+		version := toolversions.Version{Type: "version", Value: "1.0.0"}
+		assert.Equal(t, filepath.Join(conf.DataDir, "installs", "lua", "1.0.0"), InstallPath(conf, plugin, version))
+	})
+
+	t.Run("InstallTarget returns shared path when shared dir is writable", func(t *testing.T) {
+		version := toolversions.Version{Type: "version", Value: "3.0.0"}
+		path, shared := InstallTarget(conf, plugin, version)
+		assert.True(t, shared)
+		assert.Equal(t, filepath.Join(sharedDir, "installs", "lua", "3.0.0"), path)
+	})
+
+	t.Run("InstallTarget returns data dir path when no shared dir", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		version := toolversions.Version{Type: "version", Value: "3.0.0"}
+		path, shared := InstallTarget(conf, plugin, version)
+		assert.False(t, shared)
+		assert.Equal(t, filepath.Join(conf.DataDir, "installs", "lua", "3.0.0"), path)
+	})
+}
+
+func TestSetSharedPermissions(t *testing.T) {
+	installPath := t.TempDir()
+	binDir := filepath.Join(installPath, "bin")
+	assert.Nil(t, os.MkdirAll(binDir, 0o700))
+	assert.Nil(t, os.WriteFile(filepath.Join(binDir, "lua"), []byte("echo"), 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(installPath, "README"), []byte("docs"), 0o666))
+	assert.Nil(t, os.Symlink("README", filepath.Join(installPath, "link")))
+
+	err := SetSharedPermissions(installPath)
+	assert.Nil(t, err)
+
+	assertMode(t, binDir, 0o755)
+	assertMode(t, filepath.Join(binDir, "lua"), 0o755)
+	assertMode(t, filepath.Join(installPath, "README"), 0o644)
 }
 
 // helper functions
+func assertMode(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, mode, info.Mode().Perm())
+}
+
 func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
 	t.Helper()
 	testDataDir := t.TempDir()
@@ -0,0 +1,149 @@
+// Package limits applies the umask, niceness and resource limits set for a tool
+// in the [exec] section of the asdfrc. They are applied to the asdf process
+// right before it execs an executable of the tool, which inherits them, so
+// build tools needing more file descriptors than the default don't need every
+// invocation wrapped in a script raising them.
+package limits
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	umaskAttribute = "umask"
+	niceAttribute  = "nice"
+	unlimited      = "unlimited"
+)
+
+// resources maps the names of resource limits, as used by limits.conf, to the
+// resources they limit
+var resources = map[string]int{
+	"core":    unix.RLIMIT_CORE,
+	"cpu":     unix.RLIMIT_CPU,
+	"data":    unix.RLIMIT_DATA,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"stack":   unix.RLIMIT_STACK,
+}
+
+// Limits are the process attributes set for a tool
+type Limits struct {
+	// Umask is the file mode creation mask, nil when not set
+	Umask *int
+	// Nice is the niceness, from -20 to 19, nil when not set
+	Nice *int
+	// Resources maps the names of resource limits to their soft limit
+	Resources map[string]uint64
+}
+
+// Attributes returns the names of the attributes that can be set for a tool,
+// in order
+func Attributes() []string {
+	return append([]string{umaskAttribute, niceAttribute}, slices.Sorted(maps.Keys(resources))...)
+}
+
+// ForTool returns the limits set for the tool in the [exec] section of the
+// user's asdfrc
+func ForTool(conf config.Config, tool string) (Limits, error) {
+	execLimits, err := conf.ExecLimits()
+	if err != nil {
+		return Limits{}, err
+	}
+
+	limits, err := Parse(execLimits[tool])
+	if err != nil {
+		return limits, fmt.Errorf("[exec] %s: %w", tool, err)
+	}
+	return limits, nil
+}
+
+// Parse returns the limits set by the attributes. Unknown attributes and
+// invalid values are errors, so a typo doesn't silently leave a limit unset.
+// Resource limits are numbers or `unlimited`, and the umask is octal.
+func Parse(attributes map[string]string) (Limits, error) {
+	limits := Limits{Resources: map[string]uint64{}}
+
+	for _, attribute := range slices.Sorted(maps.Keys(attributes)) {
+		value := attributes[attribute]
+
+		switch attribute {
+		case umaskAttribute:
+			umask, err := strconv.ParseUint(value, 8, 32)
+			if err != nil || umask > 0o777 {
+				return limits, fmt.Errorf("invalid umask %q, must be an octal mode such as 022", value)
+			}
+			mask := int(umask)
+			limits.Umask = &mask
+		case niceAttribute:
+			nice, err := strconv.Atoi(value)
+			if err != nil || nice < -20 || nice > 19 {
+				return limits, fmt.Errorf("invalid nice %q, must be a number from -20 to 19", value)
+			}
+			limits.Nice = &nice
+		default:
+			if _, ok := resources[attribute]; !ok {
+				return limits, fmt.Errorf("unknown attribute %s, must be one of %s", attribute, strings.Join(Attributes(), ", "))
+			}
+
+			if value == unlimited {
+				limits.Resources[attribute] = unix.RLIM_INFINITY
+				continue
+			}
+
+			limit, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return limits, fmt.Errorf("invalid %s limit %q, must be a number or %s", attribute, value, unlimited)
+			}
+			limits.Resources[attribute] = limit
+		}
+	}
+
+	return limits, nil
+}
+
+// Apply sets the limits on the current process. Only soft resource limits are
+// set, raising one past its hard limit is an error as raising hard limits
+// requires privileges. So is lowering the niceness for users without them.
+func (l Limits) Apply() error {
+	for _, name := range slices.Sorted(maps.Keys(l.Resources)) {
+		var rlimit unix.Rlimit
+		if err := unix.Getrlimit(resources[name], &rlimit); err != nil {
+			return fmt.Errorf("unable to get %s limit: %w", name, err)
+		}
+
+		if l.Resources[name] > uint64(rlimit.Max) {
+			return fmt.Errorf("%s limit %d is above the hard limit %d", name, l.Resources[name], rlimit.Max)
+		}
+
+		setLimit(&rlimit.Cur, l.Resources[name])
+		if err := unix.Setrlimit(resources[name], &rlimit); err != nil {
+			return fmt.Errorf("unable to set %s limit: %w", name, err)
+		}
+	}
+
+	if l.Nice != nil {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, *l.Nice); err != nil {
+			return fmt.Errorf("unable to set nice to %d: %w", *l.Nice, err)
+		}
+	}
+
+	if l.Umask != nil {
+		unix.Umask(*l.Umask)
+	}
+
+	return nil
+}
+
+// setLimit sets a field of unix.Rlimit, which is signed on some platforms
+func setLimit[T int64 | uint64](field *T, limit uint64) {
+	*field = T(limit)
+}
@@ -0,0 +1,93 @@
+package limits
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestParse(t *testing.T) {
+	umask, nice := 0o022, 10
+
+	tests := []struct {
+		desc       string
+		attributes map[string]string
+		expected   Limits
+		err        string
+	}{
+		{desc: "no attributes", attributes: nil, expected: Limits{Resources: map[string]uint64{}}},
+		{desc: "umask", attributes: map[string]string{"umask": "022"}, expected: Limits{Umask: &umask, Resources: map[string]uint64{}}},
+		{desc: "nice", attributes: map[string]string{"nice": "10"}, expected: Limits{Nice: &nice, Resources: map[string]uint64{}}},
+		{desc: "resource limits", attributes: map[string]string{"nofile": "65536", "core": "unlimited"}, expected: Limits{Resources: map[string]uint64{"nofile": 65536, "core": unix.RLIM_INFINITY}}},
+		{desc: "invalid umask", attributes: map[string]string{"umask": "0999"}, err: `invalid umask "0999"`},
+		{desc: "nice out of range", attributes: map[string]string{"nice": "20"}, err: `invalid nice "20"`},
+		{desc: "invalid resource limit", attributes: map[string]string{"nofile": "many"}, err: `invalid nofile limit "many"`},
+		{desc: "unknown attribute", attributes: map[string]string{"nofiles": "1024"}, err: "unknown attribute nofiles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			limits, err := Parse(tt.attributes)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, limits)
+		})
+	}
+}
+
+func TestForTool(t *testing.T) {
+	conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[exec]\nbazel.nofile = 4096\nnodejs.nice = high\n"), 0o666))
+
+	t.Run("returns limits set for tool", func(t *testing.T) {
+		limits, err := ForTool(conf, "bazel")
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]uint64{"nofile": 4096}, limits.Resources)
+	})
+
+	t.Run("returns no limits for tool without any", func(t *testing.T) {
+		limits, err := ForTool(conf, "python")
+		assert.Nil(t, err)
+		assert.Equal(t, Limits{Resources: map[string]uint64{}}, limits)
+	})
+
+	t.Run("returns error naming tool with invalid limit", func(t *testing.T) {
+		_, err := ForTool(conf, "nodejs")
+		assert.ErrorContains(t, err, "[exec] nodejs: invalid nice")
+	})
+}
+
+func TestApply(t *testing.T) {
+	var original unix.Rlimit
+	assert.Nil(t, unix.Getrlimit(unix.RLIMIT_NOFILE, &original))
+	t.Cleanup(func() { unix.Setrlimit(unix.RLIMIT_NOFILE, &original) })
+	originalUmask := unix.Umask(0o022)
+	t.Cleanup(func() { unix.Umask(originalUmask) })
+
+	t.Run("sets umask and soft resource limits", func(t *testing.T) {
+		umask := 0o077
+		assert.Nil(t, Limits{Umask: &umask, Resources: map[string]uint64{"nofile": 64}}.Apply())
+
+		var rlimit unix.Rlimit
+		assert.Nil(t, unix.Getrlimit(unix.RLIMIT_NOFILE, &rlimit))
+		assert.EqualValues(t, 64, rlimit.Cur)
+		assert.Equal(t, original.Max, rlimit.Max)
+		assert.Equal(t, 0o077, unix.Umask(0o077))
+	})
+
+	t.Run("returns error for limit above hard limit", func(t *testing.T) {
+		if uint64(original.Max) == unix.RLIM_INFINITY {
+			t.Skip("nofile hard limit is unlimited")
+		}
+
+		err := Limits{Resources: map[string]uint64{"nofile": uint64(original.Max) + 1}}.Apply()
+		assert.ErrorContains(t, err, "above the hard limit")
+	})
+}
@@ -0,0 +1,160 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// UnknownFormatError is returned when findings are requested in a format that
+// is not supported
+type UnknownFormatError struct {
+	format string
+}
+
+func (e UnknownFormatError) Error() string {
+	return fmt.Sprintf("unknown format %s, supported formats: %s", e.format, strings.Join(Formats(), ", "))
+}
+
+type writerFunc func(findings []Finding, out io.Writer) error
+
+var writers = map[string]writerFunc{
+	"json":  writeJSON,
+	"sarif": writeSARIF,
+	"text":  writeText,
+}
+
+// Formats returns the names of all supported output formats
+func Formats() []string {
+	var formats []string
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// Write writes the findings to out in the given format
+func Write(format string, findings []Finding, out io.Writer) error {
+	writer, ok := writers[format]
+	if !ok {
+		return UnknownFormatError{format: format}
+	}
+
+	return writer(findings, out)
+}
+
+func writeText(findings []Finding, out io.Writer) error {
+	for _, finding := range findings {
+		location := finding.File
+		if finding.Line > 0 {
+			location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+		}
+
+		if _, err := fmt.Fprintf(out, "%s: %s: %s (%s)\n", location, finding.Level, finding.Message, finding.Rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(findings []Finding, out io.Writer) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	return writeIndented(findings, out)
+}
+
+// The subset of SARIF 2.1.0 needed to report findings, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func writeSARIF(findings []Finding, out io.Writer) error {
+	driver := sarifDriver{Name: "asdf", InformationURI: "https://asdf-vm.com"}
+	for _, rule := range Rules() {
+		driver.Rules = append(driver.Rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}})
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	for _, finding := range findings {
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: finding.File}}
+		if finding.Line > 0 {
+			location.Region = &sarifRegion{StartLine: finding.Line}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    finding.Rule,
+			Level:     finding.Level,
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	return writeIndented(sarifLog{Schema: "https://json.schemastore.org/sarif-2.1.0.json", Version: "2.1.0", Runs: []sarifRun{run}}, out)
+}
+
+func writeIndented(value any, out io.Writer) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(append(contents, '\n'))
+	return err
+}
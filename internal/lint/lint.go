@@ -0,0 +1,202 @@
+// Package lint checks the tool versions declared in a repository against a set
+// of rules, so problems can be reported by `asdf lint` in CI and by code
+// review bots before they break someone's environment.
+package lint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+// Rules checked by Run, matching the values of the lint_rules setting
+const (
+	// RuleMissingToolVersions reports repositories without a .tool-versions
+	// file
+	RuleMissingToolVersions = "missing-tool-versions"
+	// RuleUnknownPlugin reports declared tools no plugin is added for
+	RuleUnknownPlugin = "unknown-plugin"
+	// RulePolicy reports versions that don't satisfy the constraints set for
+	// the tool in the [policy] section of the asdfrc
+	RulePolicy = "policy"
+	// RuleDeprecated reports versions the plugin marks as deprecated or end
+	// of life
+	RuleDeprecated = "deprecated"
+	// RuleLegacyMismatch reports legacy version files, such as .nvmrc, that
+	// set different versions than the .tool-versions file
+	RuleLegacyMismatch = "legacy-mismatch"
+)
+
+// Levels of findings
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Rule describes a rule
+type Rule struct {
+	ID          string
+	Level       string
+	Description string
+}
+
+// Rules returns every rule in the order they are checked
+func Rules() []Rule {
+	return []Rule{
+		{ID: RuleMissingToolVersions, Level: LevelWarning, Description: "The repository has no .tool-versions file"},
+		{ID: RuleUnknownPlugin, Level: LevelError, Description: "A declared tool has no plugin"},
+		{ID: RulePolicy, Level: LevelError, Description: "A declared version is outside the policy for the tool"},
+		{ID: RuleDeprecated, Level: LevelWarning, Description: "A declared version is deprecated or end of life"},
+		{ID: RuleLegacyMismatch, Level: LevelWarning, Description: "A legacy version file disagrees with the .tool-versions file"},
+	}
+}
+
+// Finding is a single problem found by a rule. File is relative to the
+// directory checked and Line is zero when the finding is about the file as a
+// whole.
+type Finding struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// declaration is a line of a .tool-versions file setting versions of a tool
+type declaration struct {
+	tool     string
+	versions []string
+	line     int
+}
+
+// Run checks the .tool-versions file in dir against the rules given, returning
+// the findings in the order of the lines they are about
+func Run(conf config.Config, dir string, rules []string) (findings []Finding, err error) {
+	filename := conf.DefaultToolVersionsFilename
+	contents, err := os.ReadFile(filepath.Join(dir, filename))
+	if errors.Is(err, fs.ErrNotExist) {
+		if slices.Contains(rules, RuleMissingToolVersions) {
+			findings = append(findings, finding(RuleMissingToolVersions, fmt.Sprintf("no %s file declaring the tools used", filename), filename, 0))
+		}
+		return findings, nil
+	}
+	if err != nil {
+		return findings, err
+	}
+
+	policies, err := conf.Policies()
+	if err != nil {
+		return findings, err
+	}
+
+	for _, decl := range declarations(string(contents)) {
+		plugin := plugins.New(conf, decl.tool)
+		if plugin.Exists() != nil {
+			if slices.Contains(rules, RuleUnknownPlugin) {
+				findings = append(findings, finding(RuleUnknownPlugin, fmt.Sprintf("no plugin for %s, add it with asdf plugin add %s", decl.tool, decl.tool), filename, decl.line))
+			}
+			continue
+		}
+
+		for _, version := range decl.versions {
+			if toolversions.Parse(version).Type != "version" || version == toolversions.Unmanaged {
+				continue
+			}
+
+			if constraints, ok := policies[decl.tool]; ok && slices.Contains(rules, RulePolicy) && !satisfiesAll(version, constraints) {
+				findings = append(findings, finding(RulePolicy, fmt.Sprintf("%s %s is outside the policy %s", decl.tool, version, strings.Join(constraints, " ")), filename, decl.line))
+			}
+
+			if slices.Contains(rules, RuleDeprecated) {
+				deprecation, found, err := plugin.Deprecation(version)
+				if err != nil {
+					return findings, err
+				}
+				if found {
+					message := fmt.Sprintf("%s %s is deprecated", decl.tool, version)
+					if deprecation.Message != "" {
+						message += ": " + deprecation.Message
+					}
+					findings = append(findings, finding(RuleDeprecated, message, filename, decl.line))
+				}
+			}
+		}
+
+		if slices.Contains(rules, RuleLegacyMismatch) {
+			mismatches, err := legacyMismatches(plugin, dir, filename, decl)
+			if err != nil {
+				return findings, err
+			}
+			findings = append(findings, mismatches...)
+		}
+	}
+
+	return findings, nil
+}
+
+func legacyMismatches(plugin plugins.Plugin, dir, filename string, decl declaration) (findings []Finding, err error) {
+	legacyFilenames, err := plugin.LegacyFilenames(context.Background())
+	if err != nil {
+		return findings, err
+	}
+
+	for _, legacyFilename := range legacyFilenames {
+		path := filepath.Join(dir, legacyFilename)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		versions, err := plugin.ParseLegacyVersionFile(context.Background(), path)
+		if err != nil {
+			return findings, err
+		}
+
+		versions = slices.DeleteFunc(versions, func(version string) bool { return version == "" })
+		if len(versions) > 0 && !slices.Equal(versions, decl.versions) {
+			message := fmt.Sprintf("%s sets %s %s but %s sets %s", legacyFilename, decl.tool, strings.Join(versions, " "), filename, strings.Join(decl.versions, " "))
+			findings = append(findings, finding(RuleLegacyMismatch, message, legacyFilename, 0))
+		}
+	}
+
+	return findings, nil
+}
+
+func satisfiesAll(version string, constraints []string) bool {
+	for _, constraint := range constraints {
+		if !versionspec.Satisfies(version, constraint) {
+			return false
+		}
+	}
+	return true
+}
+
+func declarations(contents string) (decls []declaration) {
+	for i, line := range strings.Split(contents, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if fields := strings.Fields(line); len(fields) > 1 {
+			decls = append(decls, declaration{tool: fields[0], versions: fields[1:], line: i + 1})
+		}
+	}
+	return decls
+}
+
+func finding(rule, message, file string, line int) Finding {
+	level := LevelWarning
+	for _, r := range Rules() {
+		if r.ID == rule {
+			level = r.Level
+		}
+	}
+
+	return Finding{Rule: rule, Level: level, Message: message, File: file, Line: line}
+}
@@ -0,0 +1,123 @@
+package lint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestRun(t *testing.T) {
+	conf := generateConfig(t)
+	allRules := []string{RuleMissingToolVersions, RuleUnknownPlugin, RulePolicy, RuleDeprecated, RuleLegacyMismatch}
+
+	t.Run("reports missing .tool-versions file", func(t *testing.T) {
+		findings, err := Run(conf, t.TempDir(), allRules)
+		assert.Nil(t, err)
+		assert.Equal(t, []Finding{{Rule: RuleMissingToolVersions, Level: LevelWarning, Message: "no .tool-versions file declaring the tools used", File: ".tool-versions"}}, findings)
+	})
+
+	t.Run("reports findings of every rule", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, ".tool-versions", "# tools\nnope 1.0.0\nlua 5.1.5 5.4.6\n")
+		writeFile(t, dir, ".dummy-version", "5.4.6\n")
+
+		findings, err := Run(conf, dir, allRules)
+		assert.Nil(t, err)
+		assert.Equal(t, []Finding{
+			{Rule: RuleUnknownPlugin, Level: LevelError, Message: "no plugin for nope, add it with asdf plugin add nope", File: ".tool-versions", Line: 2},
+			{Rule: RulePolicy, Level: LevelError, Message: "lua 5.1.5 is outside the policy >=5.3", File: ".tool-versions", Line: 3},
+			{Rule: RuleDeprecated, Level: LevelWarning, Message: "lua 5.1.5 is deprecated: end of life", File: ".tool-versions", Line: 3},
+			{Rule: RuleLegacyMismatch, Level: LevelWarning, Message: ".dummy-version sets lua 5.4.6 but .tool-versions sets 5.1.5 5.4.6", File: ".dummy-version"},
+		}, findings)
+	})
+
+	t.Run("only checks rules given", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, ".tool-versions", "nope 1.0.0\nlua 5.1.5\n")
+
+		findings, err := Run(conf, dir, []string{RulePolicy})
+		assert.Nil(t, err)
+		assert.Len(t, findings, 1)
+		assert.Equal(t, RulePolicy, findings[0].Rule)
+	})
+
+	t.Run("returns no findings for versions within policy", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, ".tool-versions", "lua 5.4.6 system\n")
+
+		findings, err := Run(conf, dir, allRules)
+		assert.Nil(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("returns no findings for unmanaged tools", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, ".tool-versions", "lua unmanaged\n")
+
+		findings, err := Run(conf, dir, allRules)
+		assert.Nil(t, err)
+		assert.Empty(t, findings)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	findings := []Finding{
+		{Rule: RuleUnknownPlugin, Level: LevelError, Message: "no plugin for nope", File: ".tool-versions", Line: 2},
+		{Rule: RuleLegacyMismatch, Level: LevelWarning, Message: ".nvmrc sets nodejs 18", File: ".nvmrc"},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, Write("text", findings, &out))
+		assert.Equal(t, ".tool-versions:2: error: no plugin for nope (unknown-plugin)\n.nvmrc: warning: .nvmrc sets nodejs 18 (legacy-mismatch)\n", out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, Write("json", nil, &out))
+		assert.Equal(t, "[]\n", out.String())
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, Write("sarif", findings, &out))
+
+		var log sarifLog
+		assert.Nil(t, json.Unmarshal([]byte(out.String()), &log))
+		assert.Equal(t, "2.1.0", log.Version)
+		assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(Rules()))
+		assert.Equal(t, "unknown-plugin", log.Runs[0].Results[0].RuleID)
+		assert.Equal(t, 2, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+		assert.Nil(t, log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region)
+	})
+
+	t.Run("returns error for unknown format", func(t *testing.T) {
+		err := Write("xml", findings, &strings.Builder{})
+		assert.ErrorContains(t, err, "unknown format xml, supported formats: json, sarif, text")
+	})
+}
+
+func generateConfig(t *testing.T) config.Config {
+	t.Helper()
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[policy]\nlua = >=5.3\n"), 0o666))
+
+	pluginDir, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	assert.Nil(t, repotest.WritePluginCallback(pluginDir, "list-deprecated", "#!/usr/bin/env bash\necho '5.1 end of life'\n"))
+
+	return conf
+}
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o666))
+}
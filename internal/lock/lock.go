@@ -0,0 +1,262 @@
+// Package lock provides advisory file locks in the asdf data directory so
+// commands run concurrently against the same ASDF_DATA_DIR, such as two CI jobs
+// sharing a cache, don't modify installs or plugins at the same time. Each lock
+// file records the process holding it so a waiting command can report who it's
+// waiting for.
+//
+// Locks are re-entrant for child processes: the holder exports a token naming
+// it in its environment, see TokenEnv, so commands run by plugin callbacks
+// while a lock is held, such as `asdf reshim` from an install script, take the
+// lock their parent holds rather than waiting for it forever.
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/data"
+)
+
+const (
+	// Installs is the lock held while tool versions are installed, uninstalled
+	// or reshimmed
+	Installs = "installs"
+	// Plugins is the lock held while plugins are added, removed or updated
+	Plugins = "plugins"
+	// HookLog is the lock held while an entry is added to the hook log
+	HookLog = "hooks"
+	// AuditLog is the lock held while a shim execution is added to the audit
+	// log
+	AuditLog = "audit"
+
+	lockFileExtension = ".lock"
+	pollInterval      = 100 * time.Millisecond
+)
+
+// getpid is replaced in tests to act as a child process of the holder
+var getpid = os.Getpid
+
+// Holder describes the process holding a lock
+type Holder struct {
+	PID      int       `json:"pid"`
+	Command  string    `json:"command"`
+	Acquired time.Time `json:"acquired"`
+	Token    string    `json:"token,omitempty"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("pid %d (%s) for %s", h.PID, h.Command, time.Since(h.Acquired).Round(time.Second))
+}
+
+// TimeoutError is returned when a lock couldn't be acquired before the timeout
+type TimeoutError struct {
+	name    string
+	timeout time.Duration
+	holder  Holder
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s lock held by %s", e.timeout, e.name, e.holder)
+}
+
+// HeldError is returned by TryAcquire when the lock is held by another process
+type HeldError struct {
+	name   string
+	holder Holder
+}
+
+func (e HeldError) Error() string {
+	return fmt.Sprintf("%s lock is held by %s", e.name, e.holder)
+}
+
+// Lock is a held lock
+type Lock struct {
+	file  *os.File
+	name  string
+	token string
+}
+
+// TokenEnv returns the name of the environment variable holding the token of
+// the lock with the given name, such as ASDF_INSTALLS_LOCK_TOKEN
+func TokenEnv(name string) string {
+	return "ASDF_" + strings.ToUpper(name) + "_LOCK_TOKEN"
+}
+
+// Acquire takes the lock with the given name, waiting for up to timeout for the
+// process holding it to release it. A timeout of zero waits indefinitely.
+// waiting is called once with the current holder if the lock is held by
+// another process. A lock held by a parent process whose token is set in the
+// environment is taken at once, releasing it leaves it held by the parent.
+func Acquire(dataDir, name string, timeout time.Duration, waiting func(Holder)) (*Lock, error) {
+	return acquire(dataDir, name, timeout, false, waiting)
+}
+
+// TryAcquire takes the lock with the given name like Acquire, returning a
+// HeldError rather than waiting when it is held by another process
+func TryAcquire(dataDir, name string) (*Lock, error) {
+	return acquire(dataDir, name, 0, true, nil)
+}
+
+func acquire(dataDir, name string, timeout time.Duration, try bool, waiting func(Holder)) (*Lock, error) {
+	dir := data.LockDirectory(dataDir)
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, name+lockFileExtension), os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	notified := false
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, err
+		}
+
+		holder, _ := readHolder(file.Name())
+		if token := os.Getenv(TokenEnv(name)); token != "" && token == holder.Token && holder.PID != getpid() {
+			file.Close()
+			return &Lock{name: name}, nil
+		}
+
+		if try {
+			file.Close()
+			return nil, HeldError{name: name, holder: holder}
+		}
+
+		if timeout > 0 && time.Since(start) >= timeout {
+			file.Close()
+			return nil, TimeoutError{name: name, timeout: timeout, holder: holder}
+		}
+
+		if !notified && waiting != nil {
+			waiting(holder)
+			notified = true
+		}
+
+		time.Sleep(pollInterval)
+	}
+
+	holder := Holder{PID: getpid(), Command: strings.Join(os.Args, " "), Acquired: time.Now(), Token: newToken()}
+	contents, err := json.Marshal(holder)
+	if err == nil {
+		if err = file.Truncate(0); err == nil {
+			_, err = file.WriteAt(contents, 0)
+		}
+	}
+	if err == nil {
+		err = os.Setenv(TokenEnv(name), holder.Token)
+	}
+
+	if err != nil {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+		return nil, err
+	}
+
+	return &Lock{file: file, name: name, token: holder.Token}, nil
+}
+
+// Release releases the lock. The holder recorded in the lock file is cleared
+// first, so a lock whose holder was killed before releasing it can be told
+// apart, see Status. Releasing a lock taken from a parent process does nothing.
+func (l *Lock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+
+	if os.Getenv(TokenEnv(l.name)) == l.token {
+		os.Unsetenv(TokenEnv(l.name))
+	}
+
+	err := l.file.Truncate(0)
+	if unlockErr := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err == nil {
+		err = unlockErr
+	}
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Status is the state of a single lock
+type Status struct {
+	Name string
+	Held bool
+	// Stale is true when the lock is free but its last holder exited without
+	// releasing it, usually because it was killed, leaving whatever it was
+	// doing unfinished
+	Stale  bool
+	Holder Holder
+}
+
+// List returns the status of every lock that has been taken in the data
+// directory, ordered by name. The holder of a free lock is only given when it
+// is stale.
+func List(dataDir string) (statuses []Status, err error) {
+	paths, err := filepath.Glob(filepath.Join(data.LockDirectory(dataDir), "*"+lockFileExtension))
+	if err != nil {
+		return statuses, err
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		status := Status{Name: strings.TrimSuffix(filepath.Base(path), lockFileExtension)}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return statuses, err
+		}
+
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+		if err == nil {
+			syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+			if holder, err := readHolder(path); err == nil {
+				status.Stale = true
+				status.Holder = holder
+			}
+		} else {
+			status.Held = true
+			status.Holder, _ = readHolder(path)
+		}
+		file.Close()
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// newToken returns a random token identifying a holder of a lock
+func newToken() string {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+func readHolder(path string) (holder Holder, err error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return holder, err
+	}
+
+	err = json.Unmarshal(contents, &holder)
+	return holder, err
+}
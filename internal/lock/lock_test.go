@@ -0,0 +1,167 @@
+package lock
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Run("acquires free lock", func(t *testing.T) {
+		lock, err := Acquire(t.TempDir(), Installs, 0, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, lock.Release())
+	})
+
+	t.Run("returns TimeoutError when lock is held", func(t *testing.T) {
+		dataDir := t.TempDir()
+		held, err := Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		defer held.Release()
+
+		var waitingFor Holder
+		_, err = Acquire(dataDir, Installs, 200*time.Millisecond, func(holder Holder) { waitingFor = holder })
+		assert.IsType(t, TimeoutError{}, err)
+		assert.ErrorContains(t, err, "timed out after 200ms waiting for installs lock held by pid")
+		assert.Equal(t, os.Getpid(), waitingFor.PID)
+	})
+
+	t.Run("acquires lock once it is released", func(t *testing.T) {
+		dataDir := t.TempDir()
+		held, err := Acquire(dataDir, Plugins, 0, nil)
+		assert.Nil(t, err)
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			held.Release()
+		}()
+
+		lock, err := Acquire(dataDir, Plugins, 5*time.Second, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, lock.Release())
+	})
+
+	t.Run("acquires lock held by parent process with its token", func(t *testing.T) {
+		dataDir := t.TempDir()
+		held, err := Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		defer held.Release()
+		assert.NotEmpty(t, os.Getenv(TokenEnv(Installs)))
+
+		// Acts as a child process, inheriting the environment of the holder
+		getpid = func() int { return os.Getpid() + 1 }
+		defer func() { getpid = os.Getpid }()
+
+		nested, err := Acquire(dataDir, Installs, 200*time.Millisecond, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, nested.Release())
+
+		statuses, err := List(dataDir)
+		assert.Nil(t, err)
+		assert.True(t, statuses[0].Held)
+	})
+
+	t.Run("waits for lock held by parent process with other token", func(t *testing.T) {
+		dataDir := t.TempDir()
+		held, err := Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		defer held.Release()
+
+		getpid = func() int { return os.Getpid() + 1 }
+		defer func() { getpid = os.Getpid }()
+		t.Setenv(TokenEnv(Installs), "other")
+
+		_, err = Acquire(dataDir, Installs, 200*time.Millisecond, nil)
+		assert.IsType(t, TimeoutError{}, err)
+	})
+
+	t.Run("waits for lock held by same process", func(t *testing.T) {
+		dataDir := t.TempDir()
+		held, err := Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		defer held.Release()
+
+		_, err = Acquire(dataDir, Installs, 200*time.Millisecond, nil)
+		assert.IsType(t, TimeoutError{}, err)
+	})
+
+	t.Run("clears token when lock is released", func(t *testing.T) {
+		held, err := Acquire(t.TempDir(), Plugins, 0, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, held.Release())
+		assert.Empty(t, os.Getenv(TokenEnv(Plugins)))
+	})
+}
+
+func TestTryAcquire(t *testing.T) {
+	dataDir := t.TempDir()
+	held, err := TryAcquire(dataDir, Installs)
+	assert.Nil(t, err)
+
+	_, err = TryAcquire(dataDir, Installs)
+	assert.IsType(t, HeldError{}, err)
+	assert.ErrorContains(t, err, "installs lock is held by pid")
+
+	assert.Nil(t, held.Release())
+	held, err = TryAcquire(dataDir, Installs)
+	assert.Nil(t, err)
+	assert.Nil(t, held.Release())
+}
+
+func TestTokenEnv(t *testing.T) {
+	assert.Equal(t, "ASDF_INSTALLS_LOCK_TOKEN", TokenEnv(Installs))
+}
+
+func TestList(t *testing.T) {
+	dataDir := t.TempDir()
+
+	t.Run("returns nothing when no locks have been taken", func(t *testing.T) {
+		statuses, err := List(dataDir)
+		assert.Nil(t, err)
+		assert.Empty(t, statuses)
+	})
+
+	t.Run("returns holder of held locks", func(t *testing.T) {
+		free, err := Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, free.Release())
+
+		held, err := Acquire(dataDir, Plugins, 0, nil)
+		assert.Nil(t, err)
+		defer held.Release()
+
+		statuses, err := List(dataDir)
+		assert.Nil(t, err)
+		assert.Len(t, statuses, 2)
+		assert.Equal(t, Status{Name: Installs}, statuses[0])
+		assert.Equal(t, Plugins, statuses[1].Name)
+		assert.True(t, statuses[1].Held)
+		assert.Equal(t, os.Getpid(), statuses[1].Holder.PID)
+	})
+
+	t.Run("returns holder of stale locks", func(t *testing.T) {
+		dataDir := t.TempDir()
+		held, err := Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		// Closing the file without releasing the lock leaves the holder
+		// recorded, as a killed process does
+		assert.Nil(t, held.file.Close())
+
+		statuses, err := List(dataDir)
+		assert.Nil(t, err)
+		assert.Len(t, statuses, 1)
+		assert.False(t, statuses[0].Held)
+		assert.True(t, statuses[0].Stale)
+		assert.Equal(t, os.Getpid(), statuses[0].Holder.PID)
+
+		held, err = Acquire(dataDir, Installs, 0, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, held.Release())
+
+		statuses, err = List(dataDir)
+		assert.Nil(t, err)
+		assert.Equal(t, []Status{{Name: Installs}}, statuses)
+	})
+}
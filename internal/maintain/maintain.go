@@ -0,0 +1,318 @@
+// Package maintain runs the housekeeping tasks of `asdf maintain`. It is meant
+// to be run on a schedule, from cron or launchd, and reports every action taken
+// so the output can be collected as JSON.
+package maintain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/provenance"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versions"
+)
+
+// Tasks run by Run, in the order they are run
+const (
+	// TaskRefresh rebuilds the list-all cache of every plugin
+	TaskRefresh = "refresh"
+	// TaskPrune removes kept downloads of versions that are no longer installed
+	// and, with the maintain_download_retention setting, downloads older than
+	// the retention period
+	TaskPrune = "prune"
+	// TaskRepack repacks the Git repositories of plugins
+	TaskRepack = "repack"
+	// TaskVerify verifies installs, quarantining those that changed
+	TaskVerify = "verify"
+	// TaskTmp removes temporary directories kept by failed installs
+	TaskTmp = "tmp"
+)
+
+// Results of actions
+const (
+	ResultDone        = "done"
+	ResultOK          = "ok"
+	ResultQuarantined = "quarantined"
+	ResultSkipped     = "skipped"
+	ResultFailed      = "failed"
+)
+
+// Tasks returns the names of all tasks in the order they are run
+func Tasks() []string {
+	return []string{TaskRefresh, TaskPrune, TaskRepack, TaskVerify, TaskTmp}
+}
+
+// Action is a single thing done by a task
+type Action struct {
+	Task   string `json:"task"`
+	Target string `json:"target,omitempty"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report lists the actions taken by a run
+type Report struct {
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Tasks    []string  `json:"tasks"`
+	Actions  []Action  `json:"actions"`
+	// Failed is true when any action failed
+	Failed bool `json:"failed"`
+}
+
+// UnknownTaskError is returned when asked to run a task that doesn't exist
+type UnknownTaskError struct {
+	task string
+}
+
+func (e UnknownTaskError) Error() string {
+	return fmt.Sprintf("unknown maintenance task %s", e.task)
+}
+
+// Run runs the given tasks against the data directory. Tasks are always run
+// in the order returned by Tasks. Failures of single actions are recorded in
+// the report rather than stopping the run, an error is only returned when the
+// tasks can't be run at all.
+func Run(conf config.Config, tasks []string) (report Report, err error) {
+	for _, task := range tasks {
+		if !slices.Contains(Tasks(), task) {
+			return report, UnknownTaskError{task: task}
+		}
+	}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return report, err
+	}
+
+	report = Report{Started: time.Now().UTC().Truncate(time.Second), Tasks: []string{}, Actions: []Action{}}
+	started := time.Now()
+
+	runners := map[string]func(config.Config, []plugins.Plugin) []Action{
+		TaskRefresh: refresh,
+		TaskPrune:   prune,
+		TaskRepack:  repack,
+		TaskVerify:  verify,
+		TaskTmp:     cleanTmp,
+	}
+
+	for _, task := range Tasks() {
+		if !slices.Contains(tasks, task) {
+			continue
+		}
+
+		report.Tasks = append(report.Tasks, task)
+		for _, action := range runners[task](conf, allPlugins) {
+			report.Actions = append(report.Actions, action)
+			report.Failed = report.Failed || action.Result == ResultFailed
+		}
+	}
+
+	report.Duration = time.Since(started).Round(time.Millisecond).String()
+	return report, nil
+}
+
+func refresh(conf config.Config, allPlugins []plugins.Plugin) (actions []Action) {
+	for _, plugin := range allPlugins {
+		_, err := versions.ListAll(conf, plugin, true, io.Discard)
+		if noCallback, ok := err.(plugins.NoCallbackError); ok {
+			actions = append(actions, Action{Task: TaskRefresh, Target: plugin.Name, Result: ResultSkipped, Detail: noCallback.Error()})
+			continue
+		}
+		actions = append(actions, result(TaskRefresh, plugin.Name, err))
+	}
+
+	return actions
+}
+
+func prune(conf config.Config, allPlugins []plugins.Plugin) (actions []Action) {
+	retention, err := conf.MaintainDownloadRetention()
+	if err != nil {
+		return []Action{result(TaskPrune, "", err)}
+	}
+	cutoff := time.Now().AddDate(0, 0, -retention)
+
+	for _, plugin := range allPlugins {
+		downloadDir := data.DownloadDirectory(conf.DataDir, plugin.Name)
+		entries, err := os.ReadDir(downloadDir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			actions = append(actions, result(TaskPrune, plugin.Name, err))
+			continue
+		}
+
+		installed, err := installs.All(conf, plugin)
+		if err != nil {
+			actions = append(actions, result(TaskPrune, plugin.Name, err))
+			continue
+		}
+
+		for _, entry := range entries {
+			version := toolversions.VersionStringFromFSFormat(entry.Name())
+			if slices.Contains(installed, version) && !expired(entry, retention, cutoff) {
+				continue
+			}
+
+			err := os.RemoveAll(filepath.Join(downloadDir, entry.Name()))
+			actions = append(actions, result(TaskPrune, fmt.Sprintf("%s %s download", plugin.Name, version), err))
+		}
+	}
+
+	return actions
+}
+
+// expired returns true when a download was last modified before the cutoff of
+// a retention period, downloads never expire without a retention period
+func expired(entry fs.DirEntry, retention int, cutoff time.Time) bool {
+	if retention == 0 {
+		return false
+	}
+
+	info, err := entry.Info()
+	return err == nil && info.ModTime().Before(cutoff)
+}
+
+func repack(_ config.Config, allPlugins []plugins.Plugin) (actions []Action) {
+	for _, plugin := range allPlugins {
+		// Plugins added from a local copy or an image aren't Git repositories
+		if _, err := os.Stat(filepath.Join(plugin.Dir, ".git")); err != nil {
+			continue
+		}
+
+		err := git.NewRepo(plugin.Dir).Repack()
+		actions = append(actions, result(TaskRepack, plugin.Name, err))
+	}
+
+	return actions
+}
+
+// GC cleans up the Git repository of every plugin, see git.Repo.GC, and
+// reports the space freed in the detail of each action. Unlike the repack task
+// it contacts the remote and drops history kept by reflogs, it is only run by
+// `asdf plugin gc`.
+func GC(allPlugins []plugins.Plugin) (actions []Action) {
+	for _, plugin := range allPlugins {
+		// Plugins added from a local copy or an image aren't Git repositories
+		gitDir := filepath.Join(plugin.Dir, ".git")
+		if _, err := os.Stat(gitDir); err != nil {
+			continue
+		}
+
+		before := dirSize(gitDir)
+		if err := git.NewRepo(plugin.Dir).GC(); err != nil {
+			actions = append(actions, result(TaskRepack, plugin.Name, err))
+			continue
+		}
+
+		freed := max(before-dirSize(gitDir), 0)
+		actions = append(actions, Action{Task: TaskRepack, Target: plugin.Name, Result: ResultDone, Detail: "freed " + FormatSize(freed)})
+	}
+
+	return actions
+}
+
+func verify(conf config.Config, allPlugins []plugins.Plugin) (actions []Action) {
+	for _, plugin := range allPlugins {
+		installed, err := installs.All(conf, plugin)
+		if err != nil {
+			actions = append(actions, result(TaskVerify, plugin.Name, err))
+			continue
+		}
+
+		for _, versionStr := range installed {
+			target := fmt.Sprintf("%s %s", plugin.Name, versionStr)
+			differences, err := installs.Verify(conf, plugin, toolversions.Parse(versionStr))
+			if _, ok := err.(provenance.NoManifestError); ok {
+				actions = append(actions, Action{Task: TaskVerify, Target: target, Result: ResultSkipped, Detail: err.Error()})
+				continue
+			}
+			if err != nil {
+				actions = append(actions, result(TaskVerify, target, err))
+				continue
+			}
+
+			if differences.Empty() {
+				actions = append(actions, Action{Task: TaskVerify, Target: target, Result: ResultOK})
+				continue
+			}
+
+			detail := fmt.Sprintf("%d modified, %d missing, %d added", len(differences.Modified), len(differences.Missing), len(differences.Added))
+			actions = append(actions, Action{Task: TaskVerify, Target: target, Result: ResultQuarantined, Detail: detail})
+		}
+	}
+
+	return actions
+}
+
+func cleanTmp(conf config.Config, _ []plugins.Plugin) (actions []Action) {
+	tmpDir := data.TmpDirectory(conf.DataDir)
+	entries, err := os.ReadDir(tmpDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return actions
+	}
+	if err != nil {
+		return []Action{result(TaskTmp, tmpDir, err)}
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(tmpDir, entry.Name())
+		actions = append(actions, result(TaskTmp, path, os.RemoveAll(path)))
+	}
+
+	return actions
+}
+
+// dirSize returns the total size of the files in the directory. Files that
+// can't be read are left out.
+func dirSize(directory string) (size int64) {
+	_ = filepath.WalkDir(directory, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
+
+// FormatSize formats a number of bytes with a binary unit, e.g. 1.5 MiB
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	suffixes := []string{"KiB", "MiB", "GiB"}
+	value := float64(bytes) / unit
+	i := 0
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
+
+func result(task, target string, err error) Action {
+	if err != nil {
+		return Action{Task: task, Target: target, Result: ResultFailed, Detail: err.Error()}
+	}
+
+	return Action{Task: task, Target: target, Result: ResultDone}
+}
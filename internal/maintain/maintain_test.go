@@ -0,0 +1,145 @@
+package maintain
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versions"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestRun(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	assert.Nil(t, versions.InstallOneVersion(conf, plugin, "1.0.0", true, io.Discard, io.Discard))
+
+	t.Run("returns error for unknown task", func(t *testing.T) {
+		_, err := Run(conf, []string{"defrag"})
+		assert.ErrorContains(t, err, "unknown maintenance task defrag")
+	})
+
+	t.Run("refresh rebuilds list-all cache", func(t *testing.T) {
+		report, err := Run(conf, []string{TaskRefresh})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskRefresh, Target: testPluginName, Result: ResultDone}}, report.Actions)
+		assert.False(t, report.Failed)
+	})
+
+	t.Run("prune removes downloads of versions that aren't installed", func(t *testing.T) {
+		downloadDir := data.DownloadDirectory(conf.DataDir, testPluginName)
+		assert.Nil(t, os.MkdirAll(filepath.Join(downloadDir, "2.0.0"), 0o777))
+
+		report, err := Run(conf, []string{TaskPrune})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskPrune, Target: "lua 2.0.0 download", Result: ResultDone}}, report.Actions)
+		assert.DirExists(t, filepath.Join(downloadDir, "1.0.0"))
+		assert.NoDirExists(t, filepath.Join(downloadDir, "2.0.0"))
+	})
+
+	t.Run("prune removes downloads older than maintain_download_retention", func(t *testing.T) {
+		downloadDir := filepath.Join(data.DownloadDirectory(conf.DataDir, testPluginName), "1.0.0")
+		assert.DirExists(t, downloadDir)
+		_, err := conf.MaintainDownloadRetention()
+		assert.Nil(t, err)
+		conf.Settings.Loaded = true
+		conf.Settings.MaintainDownloadRetention = 30
+		defer func() { conf.Settings.MaintainDownloadRetention = 0 }()
+
+		report, err := Run(conf, []string{TaskPrune})
+		assert.Nil(t, err)
+		assert.Empty(t, report.Actions)
+		assert.DirExists(t, downloadDir)
+
+		old := time.Now().AddDate(0, 0, -31)
+		assert.Nil(t, os.Chtimes(downloadDir, old, old))
+		report, err = Run(conf, []string{TaskPrune})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskPrune, Target: "lua 1.0.0 download", Result: ResultDone}}, report.Actions)
+		assert.NoDirExists(t, downloadDir)
+	})
+
+	t.Run("repack repacks plugin repositories", func(t *testing.T) {
+		report, err := Run(conf, []string{TaskRepack})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskRepack, Target: testPluginName, Result: ResultDone}}, report.Actions)
+	})
+
+	t.Run("verify quarantines changed installs and releases them once unchanged", func(t *testing.T) {
+		added := filepath.Join(installs.InstallPath(conf, plugin, toolversions.Parse("1.0.0")), "added")
+		assert.Nil(t, os.WriteFile(added, []byte("added"), 0o666))
+
+		report, err := Run(conf, []string{TaskVerify})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskVerify, Target: "lua 1.0.0", Result: ResultQuarantined, Detail: "0 modified, 0 missing, 1 added"}}, report.Actions)
+		installed, _ := installs.Installed(conf, plugin)
+		assert.Empty(t, installed)
+
+		assert.Nil(t, os.Remove(added))
+		report, err = Run(conf, []string{TaskVerify})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskVerify, Target: "lua 1.0.0", Result: ResultOK}}, report.Actions)
+		installed, _ = installs.Installed(conf, plugin)
+		assert.Equal(t, []string{"1.0.0"}, installed)
+	})
+
+	t.Run("tmp removes temporary directories", func(t *testing.T) {
+		tmpDir := filepath.Join(data.TmpDirectory(conf.DataDir), "lua-1.0.0-failed")
+		assert.Nil(t, os.MkdirAll(tmpDir, 0o777))
+
+		report, err := Run(conf, []string{TaskTmp})
+		assert.Nil(t, err)
+		assert.Equal(t, []Action{{Task: TaskTmp, Target: tmpDir, Result: ResultDone}}, report.Actions)
+		assert.NoDirExists(t, tmpDir)
+	})
+
+	t.Run("runs tasks in order", func(t *testing.T) {
+		report, err := Run(conf, []string{TaskTmp, TaskRefresh})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{TaskRefresh, TaskTmp}, report.Tasks)
+	})
+}
+
+func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
+	t.Helper()
+	conf, err := config.LoadConfig()
+	assert.Nil(t, err)
+	conf.DataDir = t.TempDir()
+
+	_, err = repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf, plugins.New(conf, testPluginName)
+}
+
+func TestGC(t *testing.T) {
+	_, plugin := generateConfig(t)
+
+	actions := GC([]plugins.Plugin{plugin})
+	assert.Len(t, actions, 1)
+	assert.Equal(t, testPluginName, actions[0].Target)
+	assert.Equal(t, ResultDone, actions[0].Result)
+	assert.Regexp(t, `^freed \d+(\.\d)? (B|KiB|MiB|GiB)$`, actions[0].Detail)
+}
+
+func TestFormatSize(t *testing.T) {
+	for bytes, want := range map[int64]string{
+		0:                         "0 B",
+		1023:                      "1023 B",
+		1536:                      "1.5 KiB",
+		5 * 1024 * 1024:           "5.0 MiB",
+		3 * 1024 * 1024 * 1024:    "3.0 GiB",
+		2048 * 1024 * 1024 * 1024: "2048.0 GiB",
+	} {
+		assert.Equal(t, want, FormatSize(bytes))
+	}
+}
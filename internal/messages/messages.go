@@ -0,0 +1,310 @@
+// Package messages holds the catalog of user facing messages printed by asdf
+// and selects the locale they are printed in, so messages can be translated
+// without changing the code printing them. Messages are format strings looked
+// up by key in locales/<locale>.ini, falling back to English. Help output is
+// translated by adding locales/help.<locale>.txt. Output meant for scripts,
+// such as versions, paths and tables, isn't translated.
+package messages
+
+import (
+	"embed"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/ini.v1"
+)
+
+// Keys of the messages in the catalog
+const (
+	ConfigLoadError                     = "config_load_error"
+	CurrentDirectoryError               = "current_directory_error"
+	PluginListError                     = "plugin_list_error"
+	NoSuchPlugin                        = "no_such_plugin"
+	SanitizeEnvError                    = "sanitize_env_error"
+	SanitizeEnvIgnored                  = "sanitize_env_ignored"
+	InvalidCommand                      = "invalid_command"
+	UsageComplete                       = "usage_complete"
+	NoPluginsInstalled                  = "no_plugins_installed"
+	NoVersionSetForTool                 = "no_version_set_for_tool"
+	DeprecatedCheckError                = "deprecated_check_error"
+	NoManagersAhead                     = "no_managers_ahead"
+	NoProblemsFound                     = "no_problems_found"
+	UsageEnv                            = "usage_env"
+	UnknownEnvFormat                    = "unknown_env_format"
+	EnvironmentError                    = "environment_error"
+	InvalidWith                         = "invalid_with"
+	UnknownInclude                      = "unknown_include"
+	UsageExport                         = "usage_export"
+	ResolveToolVersionsError            = "resolve_tool_versions_error"
+	UnknownFormat                       = "unknown_format"
+	LintRulesError                      = "lint_rules_error"
+	LintError                           = "lint_error"
+	UsageImport                         = "usage_import"
+	OpenError                           = "open_error"
+	NoToolsFound                        = "no_tools_found"
+	VersionFileWriteError               = "version_file_write_error"
+	UsageExec                           = "usage_exec"
+	AuditLogError                       = "audit_log_error"
+	LimitsError                         = "limits_error"
+	TakeoverWarning                     = "takeover_warning"
+	Warning                             = "warning"
+	SubstitutionNotice                  = "substitution_notice"
+	TraceWriteWarning                   = "trace_write_warning"
+	UsageExecEnvOnly                    = "usage_exec_env_only"
+	ProfileLoadError                    = "profile_load_error"
+	ProfilePlanError                    = "profile_plan_error"
+	NothingToChange                     = "nothing_to_change"
+	ProfileApplyError                   = "profile_apply_error"
+	UnknownBakeFormat                   = "unknown_bake_format"
+	ReadError                           = "read_error"
+	BakeError                           = "bake_error"
+	ImageLayoutError                    = "image_layout_error"
+	Baked                               = "baked"
+	RemoveError                         = "remove_error"
+	HomeDirectoryError                  = "home_directory_error"
+	WriteError                          = "write_error"
+	OtherManagersError                  = "other_managers_error"
+	ExtensionCommandError               = "extension_command_error"
+	ResolveCommandError                 = "resolve_command_error"
+	NoExecutableForVersion              = "no_executable_for_version"
+	NoVersionSetForCommand              = "no_version_set_for_command"
+	ConsiderAddingVersion               = "consider_adding_version"
+	NoPresetVersionInstalled            = "no_preset_version_installed"
+	InstallSuggestion                   = "install_suggestion"
+	OrAddVersion                        = "or_add_version"
+	ExecutableNotFound                  = "executable_not_found"
+	AutoInstallSkipped                  = "auto_install_skipped"
+	AutoInstallError                    = "auto_install_error"
+	RepositoryCleanupError              = "repository_cleanup_error"
+	NoPluginGiven                       = "no_plugin_given"
+	CallbackSupported                   = "callback_supported"
+	CallbackMissing                     = "callback_missing"
+	ConfigCheckError                    = "config_check_error"
+	ShortNameRepositoryDisabled         = "short_name_repository_disabled"
+	PluginIndexError                    = "plugin_index_error"
+	PluginListFailed                    = "plugin_list_failed"
+	Failed                              = "failed"
+	PluginUpdateError                   = "plugin_update_error"
+	PluginUpdated                       = "plugin_updated"
+	VersionFilesError                   = "version_files_error"
+	WorkspaceError                      = "workspace_error"
+	WorkspaceNotFound                   = "workspace_not_found"
+	GroupVersionsFromConfig             = "group_versions_from_config"
+	NoVersionsSpecified                 = "no_versions_specified"
+	InstallVersionError                 = "install_version_error"
+	ListPluginsError                    = "list_plugins_error"
+	RefreshError                        = "refresh_error"
+	InstallGroupError                   = "install_group_error"
+	InstallToolError                    = "install_tool_error"
+	FlagLoadError                       = "flag_load_error"
+	GroupsLoadError                     = "groups_load_error"
+	ListAllFailed                       = "list_all_failed"
+	NoCompatibleVersionsAvailable       = "no_compatible_versions_available"
+	NoCompatibleVersionsInstalled       = "no_compatible_versions_installed"
+	NoCompatibleVersionsInstalledFilter = "no_compatible_versions_installed_filter"
+	ListPluginsDueToError               = "list_plugins_due_to_error"
+	NoVersionsInstalled                 = "no_versions_installed"
+	LocksReadError                      = "locks_read_error"
+	NotificationError                   = "notification_error"
+	WaitingForLock                      = "waiting_for_lock"
+	LockAcquireError                    = "lock_acquire_error"
+	RecoverError                        = "recover_error"
+	NothingToRecover                    = "nothing_to_recover"
+	MaintainTasksError                  = "maintain_tasks_error"
+	MaintenanceError                    = "maintenance_error"
+	DataDirectoryStateError             = "data_directory_state_error"
+	DataDirectoryUpToDate               = "data_directory_up_to_date"
+	ToolAndVersionRequired              = "tool_and_version_required"
+	OverrideDirectoryError              = "override_directory_error"
+	OverrideSaveError                   = "override_save_error"
+	Overridden                          = "overridden"
+	OverridesReadError                  = "overrides_read_error"
+	NoToolSpecified                     = "no_tool_specified"
+	OverrideRemoveError                 = "override_remove_error"
+	NoOverride                          = "no_override"
+	UsageProvenance                     = "usage_provenance"
+	VersionNotInstalled                 = "version_not_installed"
+	Serving                             = "serving"
+	ServeError                          = "serve_error"
+	ToolsCheckError                     = "tools_check_error"
+	ReadyNotInstalled                   = "ready_not_installed"
+	ReadyMissingShims                   = "ready_missing_shims"
+	Ready                               = "ready"
+	ReshimError                         = "reshim_error"
+	ResolveError                        = "resolve_error"
+	UsageRetool                         = "usage_retool"
+	RetoolToolMismatch                  = "retool_tool_mismatch"
+	RetoolError                         = "retool_error"
+	RetoolWouldChange                   = "retool_would_change"
+	RetoolChanged                       = "retool_changed"
+	SummaryWriteError                   = "summary_write_error"
+	StampReadError                      = "stamp_read_error"
+	StampFileReadError                  = "stamp_file_read_error"
+	StampVerifyError                    = "stamp_verify_error"
+	UnknownStampFormat                  = "unknown_stamp_format"
+	StampError                          = "stamp_error"
+	StampWriteError                     = "stamp_write_error"
+	UsageShimversions                   = "usage_shimversions"
+	UsageWhich                          = "usage_which"
+	UnknownCommand                      = "unknown_command"
+	UnexpectedError                     = "unexpected_error"
+	ProjectRecordError                  = "project_record_error"
+	ProjectsCheckError                  = "projects_check_error"
+	ProjectsUsingVersion                = "projects_using_version"
+	ForceUninstallHint                  = "force_uninstall_hint"
+	InstalledVersionsError              = "installed_versions_error"
+	ToolVersionNotInstalled             = "tool_version_not_installed"
+	VerifySkipped                       = "verify_skipped"
+	VerifyOk                            = "verify_ok"
+	VerifyQuarantined                   = "verify_quarantined"
+	VerifyModified                      = "verify_modified"
+	VerifyMissing                       = "verify_missing"
+	VerifyAdded                         = "verify_added"
+	SystemVersionSelected               = "system_version_selected"
+	LatestVersionError                  = "latest_version_error"
+)
+
+// DefaultLocale is the locale messages missing from other locales are taken
+// from
+const DefaultLocale = "en"
+
+//go:embed locales
+var locales embed.FS
+
+// localeVariables are the environment variables the locale is taken from when
+// it isn't set in the asdfrc, in order of precedence
+var localeVariables = []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+
+var (
+	selected = DefaultLocale
+	// catalogs holds the messages of every locale loaded so far, guarded by
+	// catalogsMutex as messages may be looked up from several goroutines
+	catalogs      = map[string]map[string]string{}
+	catalogsMutex sync.Mutex
+)
+
+// SetLocale selects the locale messages are printed in. The setting comes from
+// the locale setting of the asdfrc, when empty the locale is taken from the
+// LC_ALL, LC_MESSAGES and LANG environment variables. Locales without a
+// catalog fall back to their language, e.g. pt_BR.UTF-8 to pt-br and then pt,
+// and then to English.
+func SetLocale(setting string) {
+	selected = Select(setting)
+}
+
+// Locale returns the selected locale
+func Locale() string {
+	return selected
+}
+
+// Select returns the locale with a catalog best matching the setting, or the
+// environment when the setting is empty
+func Select(setting string) string {
+	if setting == "" {
+		for _, variable := range localeVariables {
+			if setting = os.Getenv(variable); setting != "" {
+				break
+			}
+		}
+	}
+
+	for _, candidate := range candidates(setting) {
+		if _, ok := catalog(candidate); ok {
+			return candidate
+		}
+	}
+
+	return DefaultLocale
+}
+
+// Get returns the message for a key in the selected locale. Messages missing
+// from the selected locale are returned in English, unknown keys are returned
+// as is.
+func Get(key string) string {
+	if messages, ok := catalog(selected); ok {
+		if message, ok := messages[key]; ok {
+			return message
+		}
+	}
+
+	if message, ok := defaultCatalog()[key]; ok {
+		return message
+	}
+
+	return key
+}
+
+// Help returns the help output translated to the selected locale, or the
+// default text when there is no translation
+func Help(defaultText string) string {
+	return HelpTopic("help", defaultText)
+}
+
+// HelpTopic returns a part of the help output, such as the common commands,
+// translated to the selected locale like Help
+func HelpTopic(topic, defaultText string) string {
+	contents, err := locales.ReadFile("locales/" + topic + "." + selected + ".txt")
+	if err != nil {
+		return defaultText
+	}
+
+	return string(contents)
+}
+
+// candidates returns the locales to try for a setting such as pt_BR.UTF-8,
+// most specific first
+func candidates(setting string) []string {
+	setting, _, _ = strings.Cut(setting, ".")
+	setting, _, _ = strings.Cut(setting, "@")
+	setting = strings.ToLower(strings.ReplaceAll(setting, "_", "-"))
+	if setting == "" || setting == "c" || setting == "posix" {
+		return nil
+	}
+
+	language, _, _ := strings.Cut(setting, "-")
+	if language == setting {
+		return []string{setting}
+	}
+
+	return []string{setting, language}
+}
+
+func defaultCatalog() map[string]string {
+	messages, _ := catalog(DefaultLocale)
+	return messages
+}
+
+// catalog returns the messages of a locale, loading them from its file the
+// first time
+func catalog(locale string) (map[string]string, bool) {
+	catalogsMutex.Lock()
+	defer catalogsMutex.Unlock()
+
+	if messages, ok := catalogs[locale]; ok {
+		return messages, messages != nil
+	}
+
+	messages, err := load(locale)
+	if err != nil {
+		catalogs[locale] = nil
+		return nil, false
+	}
+
+	catalogs[locale] = messages
+	return messages, true
+}
+
+func load(locale string) (map[string]string, error) {
+	contents, err := locales.ReadFile("locales/" + locale + ".ini")
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.Section("").KeysHash(), nil
+}
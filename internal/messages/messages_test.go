@@ -0,0 +1,147 @@
+package messages
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	catalogs["pt-br"] = map[string]string{NoSuchPlugin: "Plugin inexistente: %s"}
+	catalogs["de"] = map[string]string{}
+	defer delete(catalogs, "pt-br")
+	defer delete(catalogs, "de")
+
+	tests := []struct {
+		setting  string
+		expected string
+	}{
+		{setting: "pt_BR.UTF-8", expected: "pt-br"},
+		{setting: "de_AT.UTF-8@euro", expected: "de"},
+		{setting: "fr_FR", expected: DefaultLocale},
+		{setting: "C", expected: DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setting, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Select(tt.setting))
+		})
+	}
+
+	t.Run("takes locale from environment when setting is empty", func(t *testing.T) {
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_MESSAGES", "de_DE.UTF-8")
+		t.Setenv("LANG", "pt_BR.UTF-8")
+		assert.Equal(t, "de", Select(""))
+	})
+}
+
+func TestGet(t *testing.T) {
+	catalogs["pt-br"] = map[string]string{NoSuchPlugin: "Plugin inexistente: %s"}
+	defer delete(catalogs, "pt-br")
+	defer SetLocale(DefaultLocale)
+
+	t.Run("returns message in selected locale", func(t *testing.T) {
+		SetLocale("pt_BR")
+		assert.Equal(t, "pt-br", Locale())
+		assert.Equal(t, "Plugin inexistente: %s", Get(NoSuchPlugin))
+	})
+
+	t.Run("returns English message when missing from selected locale", func(t *testing.T) {
+		SetLocale("pt_BR")
+		assert.Equal(t, "error loading config: %s", Get(ConfigLoadError))
+	})
+
+	t.Run("loads catalogs safely from several goroutines", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for _, locale := range []string{"de", "fr", "ja", DefaultLocale} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				catalog(locale)
+				Get(ConfigLoadError)
+			}()
+		}
+		wg.Wait()
+		assert.NotEmpty(t, defaultCatalog())
+	})
+
+	t.Run("returns key when unknown", func(t *testing.T) {
+		assert.Equal(t, "unknown_key", Get("unknown_key"))
+	})
+
+	t.Run("Help returns default text without translation", func(t *testing.T) {
+		assert.Equal(t, "usage", Help("usage"))
+	})
+
+	t.Run("HelpTopic returns default text without translation", func(t *testing.T) {
+		assert.Equal(t, "common commands", HelpTopic("common", "common commands"))
+	})
+}
+
+// TestCatalogs checks every key declared in messages.go is in the English
+// catalog, that every catalog only has keys from the English catalog and that
+// translations keep the format verbs of the English message
+func TestCatalogs(t *testing.T) {
+	verbRegex := regexp.MustCompile(`%[-+# 0]*[0-9]*[a-zA-Z%]`)
+	english := defaultCatalog()
+	keys := declaredKeys(t)
+	assert.NotEmpty(t, keys)
+	for _, key := range keys {
+		assert.Contains(t, english, key)
+	}
+
+	files, err := fs.Glob(locales, "locales/*.ini")
+	assert.Nil(t, err)
+	for _, file := range files {
+		locale := strings.TrimSuffix(strings.TrimPrefix(file, "locales/"), ".ini")
+		t.Run(locale, func(t *testing.T) {
+			messages, ok := catalog(locale)
+			assert.True(t, ok)
+			for key, message := range messages {
+				assert.Contains(t, english, key, fmt.Sprintf("unknown key %s", key))
+				assert.Equal(t, verbRegex.FindAllString(english[key], -1), verbRegex.FindAllString(message, -1), key)
+			}
+		})
+	}
+}
+
+// declaredKeys returns the values of the string constants declared in
+// messages.go other than DefaultLocale
+func declaredKeys(t *testing.T) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "messages.go", nil, 0)
+	assert.Nil(t, err)
+
+	var keys []string
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, name := range valueSpec.Names {
+				literal, ok := valueSpec.Values[i].(*ast.BasicLit)
+				if !ok || name.Name == "DefaultLocale" {
+					continue
+				}
+
+				key, err := strconv.Unquote(literal.Value)
+				assert.Nil(t, err)
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
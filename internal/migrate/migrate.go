@@ -0,0 +1,132 @@
+// Package migrate upgrades the contents of the asdf data directory between
+// asdf releases. The data directory records the version of the last migration
+// applied to it in a state file, every migration with a higher version is
+// pending and is run in order by Run.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/shims"
+)
+
+const stateFilename = ".asdf-state-version"
+
+// Migration is a single upgrade step for the asdf data directory. Migrations
+// must be safe to run more than once.
+type Migration struct {
+	Version     int
+	Description string
+	Run         func(conf config.Config, stdOut io.Writer, stdErr io.Writer) error
+}
+
+// migrations must be kept in ascending Version order. Never change the version
+// of a released migration, add a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "regenerate shims in the current shim format",
+		Run:         regenerateShims,
+	},
+	{
+		Version:     2,
+		Description: "remove plugin index checkout left behind by the Bash implementation",
+		Run:         removeLegacyPluginIndex,
+	},
+}
+
+// Latest returns the version the data directory will be at once all
+// migrations have been applied
+func Latest() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// StateVersion returns the version of the last migration applied to the data
+// directory. Data directories without a state file are at version 0.
+func StateVersion(dataDir string) (int, error) {
+	contents, err := os.ReadFile(filepath.Join(dataDir, stateFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid state version in %s: %w", stateFilename, err)
+	}
+
+	return version, nil
+}
+
+// Pending returns the migrations that have not yet been applied to the data
+// directory
+func Pending(dataDir string) (pending []Migration, err error) {
+	version, err := StateVersion(dataDir)
+	if err != nil {
+		return pending, err
+	}
+
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Run applies all pending migrations in order. The state file is updated
+// after each successful migration so a failed migration is retried on the
+// next run without re-running the ones before it.
+func Run(conf config.Config, stdOut io.Writer, stdErr io.Writer) (applied []Migration, err error) {
+	pending, err := Pending(conf.DataDir)
+	if err != nil {
+		return applied, err
+	}
+
+	for _, migration := range pending {
+		fmt.Fprintf(stdOut, "migrating data to version %d: %s\n", migration.Version, migration.Description)
+
+		err := migration.Run(conf, stdOut, stdErr)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+
+		err = writeStateVersion(conf.DataDir, migration.Version)
+		if err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+func writeStateVersion(dataDir string, version int) error {
+	err := os.MkdirAll(dataDir, 0o777)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dataDir, stateFilename), []byte(fmt.Sprintf("%d\n", version)), 0o666)
+}
+
+func regenerateShims(conf config.Config, stdOut io.Writer, stdErr io.Writer) error {
+	return shims.Regenerate(conf, stdOut, stdErr)
+}
+
+func removeLegacyPluginIndex(conf config.Config, _ io.Writer, _ io.Writer) error {
+	return os.RemoveAll(filepath.Join(conf.DataDir, "repository"))
+}
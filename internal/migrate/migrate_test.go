@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateVersion(t *testing.T) {
+	t.Run("returns 0 when state file does not exist", func(t *testing.T) {
+		version, err := StateVersion(t.TempDir())
+		assert.Nil(t, err)
+		assert.Equal(t, 0, version)
+	})
+
+	t.Run("returns version from state file", func(t *testing.T) {
+		dataDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(dataDir, stateFilename), []byte("1\n"), 0o666))
+
+		version, err := StateVersion(dataDir)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, version)
+	})
+
+	t.Run("returns error when state file is invalid", func(t *testing.T) {
+		dataDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(dataDir, stateFilename), []byte("foo"), 0o666))
+
+		_, err := StateVersion(dataDir)
+		assert.ErrorContains(t, err, "invalid state version")
+	})
+}
+
+func TestPending(t *testing.T) {
+	t.Run("returns all migrations for new data dir", func(t *testing.T) {
+		pending, err := Pending(t.TempDir())
+		assert.Nil(t, err)
+		assert.Len(t, pending, len(migrations))
+	})
+
+	t.Run("returns only migrations newer than state version", func(t *testing.T) {
+		dataDir := t.TempDir()
+		assert.Nil(t, writeStateVersion(dataDir, 1))
+
+		pending, err := Pending(dataDir)
+		assert.Nil(t, err)
+		assert.Len(t, pending, len(migrations)-1)
+		assert.Equal(t, 2, pending[0].Version)
+	})
+}
+
+func TestRun(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir()}
+	legacyIndex := filepath.Join(conf.DataDir, "repository")
+	assert.Nil(t, os.MkdirAll(legacyIndex, 0o777))
+
+	var stdout, stderr strings.Builder
+	applied, err := Run(conf, &stdout, &stderr)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(migrations))
+	assert.Contains(t, stdout.String(), "migrating data to version 1: regenerate shims in the current shim format\n")
+	assert.NoDirExists(t, legacyIndex)
+
+	version, err := StateVersion(conf.DataDir)
+	assert.Nil(t, err)
+	assert.Equal(t, Latest(), version)
+
+	t.Run("does nothing when already up to date", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		applied, err := Run(conf, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.Empty(t, applied)
+		assert.Empty(t, stdout.String())
+	})
+}
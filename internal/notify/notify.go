@@ -0,0 +1,148 @@
+// Package notify tells the user that a long operation, such as an install of a
+// tool built from source, has finished, through the channels set by the
+// notify_channels setting, so it can be left running without watching the
+// terminal.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+)
+
+// Channels notifications can be sent to
+const (
+	// ChannelBell rings the terminal bell
+	ChannelBell = "bell"
+	// ChannelDesktop shows a desktop notification with osascript on macOS
+	// and notify-send elsewhere
+	ChannelDesktop = "desktop"
+	// ChannelWebhook posts the Event as JSON to the URL set by the
+	// notify_webhook setting
+	ChannelWebhook = "webhook"
+
+	title          = "asdf"
+	webhookTimeout = 10 * time.Second
+)
+
+// Event describes a finished operation
+type Event struct {
+	Operation string `json:"operation"`
+	Directory string `json:"directory"`
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
+	// Duration is the number of whole seconds the operation took
+	Duration int `json:"duration_seconds"`
+}
+
+// Message returns a one line summary of the event
+func (e Event) Message() string {
+	duration := time.Duration(e.Duration) * time.Second
+	if e.Succeeded {
+		return fmt.Sprintf("%s finished in %s", e.Operation, duration)
+	}
+	return fmt.Sprintf("%s failed after %s: %s", e.Operation, duration, e.Error)
+}
+
+// Finished notifies every channel set by the notify_channels setting that the
+// operation started at the given time has finished, failing with opErr if not
+// nil. Operations that took less than the notify_threshold setting aren't
+// notified. The bell is written to stdErr. A channel that can't be notified
+// doesn't stop the others from being notified, the errors of all of them are
+// returned.
+func Finished(conf config.Config, operation string, started time.Time, opErr error, stdErr io.Writer) error {
+	channels, err := conf.NotifyChannels()
+	if err != nil || len(channels) == 0 {
+		return err
+	}
+
+	threshold, err := conf.NotifyThreshold()
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(started)
+	if elapsed < time.Duration(threshold)*time.Second {
+		return nil
+	}
+
+	event := Event{Operation: operation, Succeeded: opErr == nil, Duration: int(elapsed.Seconds())}
+	event.Directory, _ = os.Getwd()
+	if opErr != nil {
+		event.Error = opErr.Error()
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		var err error
+		switch channel {
+		case ChannelBell:
+			_, err = io.WriteString(stdErr, "\a")
+		case ChannelDesktop:
+			err = desktop(event)
+		case ChannelWebhook:
+			err = webhook(conf, event)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// desktop shows the event with the notifier of the platform. The title and
+// message are passed to osascript as arguments rather than in the script, so
+// they needn't be quoted.
+func desktop(event Event) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("osascript", "-e", "on run argv", "-e", "display notification (item 2 of argv) with title (item 1 of argv)", "-e", "end run", title, event.Message())
+	} else {
+		cmd = exec.Command("notify-send", "--app-name", title, title, event.Message())
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return err
+}
+
+func webhook(conf config.Config, event Event) error {
+	url, err := conf.NotifyWebhook()
+	if err != nil {
+		return err
+	}
+	if url == "" {
+		return errors.New("notify_webhook is not set")
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinished(t *testing.T) {
+	started := time.Now().Add(-90 * time.Second)
+	generateConfig := func(t *testing.T, settings string) config.Config {
+		conf := config.Config{DataDir: t.TempDir(), ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte(settings), 0o666))
+		return conf
+	}
+
+	t.Run("does nothing when no channel is set", func(t *testing.T) {
+		var stdErr strings.Builder
+		err := Finished(generateConfig(t, ""), "asdf install", started, nil, &stdErr)
+		assert.Nil(t, err)
+		assert.Empty(t, stdErr.String())
+	})
+
+	t.Run("rings bell when operation took longer than threshold", func(t *testing.T) {
+		var stdErr strings.Builder
+		err := Finished(generateConfig(t, "notify_channels = bell\n"), "asdf install", started, nil, &stdErr)
+		assert.Nil(t, err)
+		assert.Equal(t, "\a", stdErr.String())
+	})
+
+	t.Run("does not notify operations shorter than threshold", func(t *testing.T) {
+		var stdErr strings.Builder
+		err := Finished(generateConfig(t, "notify_channels = bell\nnotify_threshold = 300\n"), "asdf install", started, nil, &stdErr)
+		assert.Nil(t, err)
+		assert.Empty(t, stdErr.String())
+	})
+
+	t.Run("posts event to webhook", func(t *testing.T) {
+		var event Event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&event))
+		}))
+		defer server.Close()
+
+		conf := generateConfig(t, "notify_channels = webhook\nnotify_webhook = "+server.URL+"\n")
+		err := Finished(conf, "asdf install erlang 27.0", started, errors.New("build failed"), &strings.Builder{})
+		assert.Nil(t, err)
+		assert.Equal(t, "asdf install erlang 27.0", event.Operation)
+		assert.False(t, event.Succeeded)
+		assert.Equal(t, "build failed", event.Error)
+		assert.GreaterOrEqual(t, event.Duration, 90)
+	})
+
+	t.Run("returns error of webhook and notifies other channels", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		var stdErr strings.Builder
+		conf := generateConfig(t, "notify_channels = webhook bell\nnotify_webhook = "+server.URL+"\n")
+		err := Finished(conf, "asdf install", started, nil, &stdErr)
+		assert.ErrorContains(t, err, "webhook: "+server.URL+" returned 500 Internal Server Error")
+		assert.Equal(t, "\a", stdErr.String())
+	})
+
+	t.Run("returns error when webhook URL is not set", func(t *testing.T) {
+		err := Finished(generateConfig(t, "notify_channels = webhook\n"), "asdf install", started, nil, &strings.Builder{})
+		assert.ErrorContains(t, err, "notify_webhook is not set")
+	})
+
+	t.Run("shows desktop notification", func(t *testing.T) {
+		binDir := t.TempDir()
+		argsFile := filepath.Join(t.TempDir(), "args")
+		script := "#!/usr/bin/env bash\nprintf '%s\\n' \"$@\" > " + argsFile + "\n"
+		for _, name := range []string{"osascript", "notify-send"} {
+			assert.Nil(t, os.WriteFile(filepath.Join(binDir, name), []byte(script), 0o777))
+		}
+		t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+
+		err := Finished(generateConfig(t, "notify_channels = desktop\n"), "asdf install", started, nil, &strings.Builder{})
+		assert.Nil(t, err)
+		args, err := os.ReadFile(argsFile)
+		assert.Nil(t, err)
+		assert.Regexp(t, `\nasdf install finished in 1m3\ds\n$`, string(args))
+	})
+}
+
+func TestEventMessage(t *testing.T) {
+	assert.Equal(t, "asdf install finished in 21m4s", Event{Operation: "asdf install", Succeeded: true, Duration: 1264}.Message())
+	assert.Equal(t, "asdf install failed after 1m0s: build failed", Event{Operation: "asdf install", Error: "build failed", Duration: 60}.Message())
+}
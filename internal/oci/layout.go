@@ -0,0 +1,182 @@
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	configMediaType   = "application/vnd.oci.image.config.v1+json"
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	layerMediaType    = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// WriteLayout writes an OCI image layout to dest holding a single layer image
+// for the current platform. The layer contains the files under root at the
+// absolute path target, so the image can be added to other images or pushed to
+// a registry with tools that read image layouts.
+func WriteLayout(dest, root, target string) error {
+	blobs := filepath.Join(dest, "blobs", "sha256")
+	if err := os.MkdirAll(blobs, 0o777); err != nil {
+		return err
+	}
+
+	layer, diffID, err := writeLayer(blobs, root, strings.TrimPrefix(filepath.Clean(target), "/"))
+	if err != nil {
+		return fmt.Errorf("unable to write layer: %w", err)
+	}
+
+	config, err := writeJSONBlob(blobs, configMediaType, map[string]any{
+		"architecture": runtime.GOARCH,
+		"os":           runtime.GOOS,
+		"rootfs":       map[string]any{"type": "layers", "diff_ids": []string{diffID}},
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest, err := writeJSONBlob(blobs, manifestMediaType, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     manifestMediaType,
+		"config":        config,
+		"layers":        []layoutDescriptor{layer},
+	})
+	if err != nil {
+		return err
+	}
+	manifest.Platform = &platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}
+
+	index, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"manifests":     []layoutDescriptor{manifest},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dest, "index.json"), index, 0o666); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dest, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`), 0o666)
+}
+
+type layoutDescriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+// writeLayer writes a gzip compressed tar of root, with entries under prefix,
+// returning its descriptor and the digest of the uncompressed tar
+func writeLayer(blobs, root, prefix string) (layoutDescriptor, string, error) {
+	file, err := os.CreateTemp(blobs, "layer")
+	if err != nil {
+		return layoutDescriptor{}, "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	compressed := sha256.New()
+	uncompressed := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, compressed))
+	archive := tar.NewWriter(io.MultiWriter(gz, uncompressed))
+
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		contents, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer contents.Close()
+
+		_, err = io.Copy(archive, contents)
+		return err
+	})
+	if err != nil {
+		return layoutDescriptor{}, "", err
+	}
+
+	if err := archive.Close(); err != nil {
+		return layoutDescriptor{}, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return layoutDescriptor{}, "", err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return layoutDescriptor{}, "", err
+	}
+
+	sum := hex.EncodeToString(compressed.Sum(nil))
+	if err := os.Rename(file.Name(), filepath.Join(blobs, sum)); err != nil {
+		return layoutDescriptor{}, "", err
+	}
+
+	descriptor := layoutDescriptor{MediaType: layerMediaType, Digest: "sha256:" + sum, Size: info.Size()}
+	return descriptor, "sha256:" + hex.EncodeToString(uncompressed.Sum(nil)), nil
+}
+
+func writeJSONBlob(blobs, mediaType string, value any) (layoutDescriptor, error) {
+	contents, err := json.Marshal(value)
+	if err != nil {
+		return layoutDescriptor{}, err
+	}
+
+	sum := sha256.Sum256(contents)
+	digest := hex.EncodeToString(sum[:])
+	if err := os.WriteFile(filepath.Join(blobs, digest), contents, 0o666); err != nil {
+		return layoutDescriptor{}, err
+	}
+
+	return layoutDescriptor{MediaType: mediaType, Digest: "sha256:" + digest, Size: int64(len(contents))}, nil
+}
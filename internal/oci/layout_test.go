@@ -0,0 +1,55 @@
+package oci
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteLayout(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "shims"), 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "shims", "tool"), []byte("shim"), 0o755))
+	assert.Nil(t, os.Symlink("tool", filepath.Join(root, "shims", "alias")))
+
+	dest := t.TempDir()
+	assert.Nil(t, WriteLayout(dest, root, "/opt/asdf"))
+	assert.FileExists(t, filepath.Join(dest, "oci-layout"))
+
+	var index manifest
+	readJSON(t, filepath.Join(dest, "index.json"), &index)
+	assert.Len(t, index.Manifests, 1)
+	assert.Equal(t, runtime.GOARCH, index.Manifests[0].Platform.Architecture)
+
+	var image manifest
+	readJSON(t, blobPath(dest, index.Manifests[0].Digest), &image)
+	assert.Len(t, image.Layers, 1)
+
+	layer, err := os.Open(blobPath(dest, image.Layers[0].Digest))
+	assert.Nil(t, err)
+	defer layer.Close()
+	blob, err := verify(layer, image.Layers[0].Digest)
+	assert.Nil(t, err)
+
+	extracted := t.TempDir()
+	assert.Nil(t, extract(blob, extracted))
+	contents, err := os.ReadFile(filepath.Join(extracted, "opt", "asdf", "shims", "alias"))
+	assert.Nil(t, err)
+	assert.Equal(t, "shim", string(contents))
+}
+
+func readJSON(t *testing.T, path string, value any) {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(contents, value))
+}
+
+func blobPath(layout, digest string) string {
+	return filepath.Join(layout, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
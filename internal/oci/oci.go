@@ -0,0 +1,421 @@
+// Package oci pulls tools distributed as OCI container images. Only the parts
+// of the registry API needed to list tags and fetch a single image layer are
+// implemented, using anonymous token authentication when a registry asks for
+// it.
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+	manifestTypes     = "application/vnd.oci.image.index.v1+json, application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.list.v2+json, application/vnd.docker.distribution.manifest.v2+json"
+	whiteoutPrefix    = ".wh."
+)
+
+// Reference is an image repository in a registry, without a tag
+type Reference struct {
+	Registry   string
+	Repository string
+}
+
+// ParseReference parses an image reference such as ghcr.io/org/tool. Images
+// without a registry host are looked up on Docker Hub.
+func ParseReference(reference string) (Reference, error) {
+	if reference == "" || strings.ContainsAny(reference, "@ ") {
+		return Reference{}, fmt.Errorf("invalid image reference: %q", reference)
+	}
+
+	if strings.Contains(reference[strings.LastIndex(reference, "/")+1:], ":") {
+		return Reference{}, fmt.Errorf("image reference %s must not contain a tag, versions are used as tags", reference)
+	}
+
+	registry, repository, found := strings.Cut(reference, "/")
+	if !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry, repository = dockerHub, reference
+	}
+
+	if registry == dockerHub && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	return Reference{Registry: registry, Repository: repository}, nil
+}
+
+func (r Reference) String() string {
+	return r.Registry + "/" + r.Repository
+}
+
+func (r Reference) url(path string) string {
+	registry := r.Registry
+	if registry == dockerHub {
+		registry = dockerHubRegistry
+	}
+	return fmt.Sprintf("https://%s/v2/%s/%s", registry, r.Repository, path)
+}
+
+// Client talks to OCI registries
+type Client struct {
+	HTTP  *http.Client
+	token string
+}
+
+// NewClient returns a Client using the default HTTP client
+func NewClient() *Client {
+	return &Client{HTTP: http.DefaultClient}
+}
+
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+type manifest struct {
+	Manifests []descriptor `json:"manifests"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// Tags returns the tags of the image in the order the registry lists them
+func (c *Client) Tags(ref Reference) ([]string, error) {
+	resp, err := c.get(ref, ref.url("tags/list"), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unable to read tags of %s: %w", ref, err)
+	}
+
+	return list.Tags, nil
+}
+
+// Pull extracts the last layer of the image with the given tag into dest.
+// Tools distributed as images are usually copied onto a base image in the
+// final layer, so the layers of the base image are left out. Multi-platform
+// images are resolved to the image for the current architecture.
+func (c *Client) Pull(ref Reference, tag, dest string) error {
+	image, err := c.manifest(ref, tag)
+	if err != nil {
+		return err
+	}
+
+	if len(image.Manifests) > 0 {
+		digest := ""
+		for _, m := range image.Manifests {
+			if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == runtime.GOARCH {
+				digest = m.Digest
+				break
+			}
+		}
+
+		if digest == "" {
+			return fmt.Errorf("image %s:%s has no linux/%s variant", ref, tag, runtime.GOARCH)
+		}
+
+		image, err = c.manifest(ref, digest)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(image.Layers) == 0 {
+		return fmt.Errorf("image %s:%s has no layers", ref, tag)
+	}
+
+	layer := image.Layers[len(image.Layers)-1]
+	resp, err := c.get(ref, ref.url("blobs/"+layer.Digest), "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	blob, err := verify(resp.Body, layer.Digest)
+	if err != nil {
+		return fmt.Errorf("unable to download layer of %s:%s: %w", ref, tag, err)
+	}
+
+	return extract(blob, dest)
+}
+
+func (c *Client) manifest(ref Reference, tagOrDigest string) (image manifest, err error) {
+	resp, err := c.get(ref, ref.url("manifests/"+tagOrDigest), manifestTypes)
+	if err != nil {
+		return image, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&image); err != nil {
+		return image, fmt.Errorf("unable to read manifest of %s:%s: %w", ref, tagOrDigest, err)
+	}
+
+	return image, nil
+}
+
+// get requests the url, fetching an anonymous token and retrying once when
+// the registry requires authentication.
+func (c *Client) get(ref Reference, rawURL, accept string) (*http.Response, error) {
+	resp, err := c.do(rawURL, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		c.token, err = c.fetchToken(challenge)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authenticate with %s: %w", ref.Registry, err)
+		}
+
+		resp, err = c.do(rawURL, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", rawURL, resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) do(rawURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.HTTP.Do(req)
+}
+
+func (c *Client) fetchToken(challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge: %q", challenge)
+	}
+
+	values := url.Values{}
+	realm := ""
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else {
+			values.Set(key, value)
+		}
+	}
+
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge has no realm: %q", challenge)
+	}
+
+	resp, err := c.do(realm+"?"+values.Encode(), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", realm, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+// verify reads the blob and checks it matches its sha256 digest
+func verify(in io.Reader, digest string) ([]byte, error) {
+	algorithm, expected, _ := strings.Cut(digest, ":")
+	if algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported digest algorithm: %s", algorithm)
+	}
+
+	blob, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(blob)
+	if hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf("digest mismatch, expected %s", digest)
+	}
+
+	return blob, nil
+}
+
+// extract unpacks a layer, which may be gzip compressed, into dest. Entries
+// that would be written outside dest are rejected.
+func extract(blob []byte, dest string) error {
+	reader := bufio.NewReader(bytes.NewReader(blob))
+	var in io.Reader = reader
+	if magic, _ := reader.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	// Entries are resolved against the real destination, as links extracted
+	// earlier are followed by the entries written through them
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return err
+	}
+
+	archive := tar.NewReader(in)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read layer: %w", err)
+		}
+
+		name := filepath.Clean(strings.TrimPrefix(header.Name, "/"))
+		if name == "." || strings.HasPrefix(filepath.Base(name), whiteoutPrefix) {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("layer entry %s is outside the install directory", header.Name)
+		}
+
+		target, err := resolveWithin(root, filepath.Join(root, name))
+		if err != nil {
+			return fmt.Errorf("layer entry %s is outside the install directory", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o777); err != nil {
+			return err
+		}
+
+		// Later layers replace earlier entries, never write through them
+		if header.Typeflag != tar.TypeDir {
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o777)
+		case tar.TypeReg:
+			err = writeFile(target, archive, header.FileInfo().Mode().Perm())
+		case tar.TypeSymlink:
+			// Absolute links point at the host once extracted, so only
+			// relative links that stay within dest, from where the link
+			// really is, are allowed
+			if filepath.IsAbs(header.Linkname) || !within(root, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return fmt.Errorf("layer entry %s links outside the install directory", header.Name)
+			}
+			err = os.Symlink(header.Linkname, target)
+		case tar.TypeLink:
+			linkName := filepath.Clean(strings.TrimPrefix(header.Linkname, "/"))
+			source, linkErr := resolveWithin(root, filepath.Join(root, linkName))
+			if !filepath.IsLocal(linkName) || linkErr != nil {
+				return fmt.Errorf("layer entry %s links outside the install directory", header.Name)
+			}
+			err = os.Link(source, target)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// resolveWithin returns the path with the symlinks among its parents that
+// exist resolved, so writing to it can't follow a link out of root, which must
+// already be resolved. An error is returned when it's outside root.
+func resolveWithin(root, path string) (string, error) {
+	dir, rest := filepath.Dir(path), filepath.Base(path)
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		dir, rest = filepath.Dir(dir), filepath.Join(filepath.Base(dir), rest)
+	}
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+
+	resolved = filepath.Join(resolved, rest)
+	if !within(root, resolved) {
+		return "", fmt.Errorf("%s is outside %s", path, root)
+	}
+
+	return resolved, nil
+}
+
+// within returns true if the path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
+
+func writeFile(target string, in io.Reader, mode os.FileMode) error {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, in); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,231 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Reference
+	}{
+		{input: "ghcr.io/org/tool", want: Reference{Registry: "ghcr.io", Repository: "org/tool"}},
+		{input: "localhost:5000/tool", want: Reference{Registry: "localhost:5000", Repository: "tool"}},
+		{input: "localhost/tool", want: Reference{Registry: "localhost", Repository: "tool"}},
+		{input: "org/tool", want: Reference{Registry: "docker.io", Repository: "org/tool"}},
+		{input: "alpine", want: Reference{Registry: "docker.io", Repository: "library/alpine"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseReference(tt.input)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, input := range []string{"", "ghcr.io/org/tool:1.0.0", "ghcr.io/org/tool@sha256:abc"} {
+		t.Run("rejects "+input, func(t *testing.T) {
+			_, err := ParseReference(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	layer := buildLayer(t, map[string]string{"bin/tool": "#!/bin/sh\necho tool\n"})
+	base := buildLayer(t, map[string]string{"bin/sh": "base"})
+	server := newRegistry(t, map[string][]byte{"base": base, "tool": layer})
+	client := &Client{HTTP: server.Client()}
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "https://") + "/org/tool")
+	assert.Nil(t, err)
+
+	t.Run("Tags returns tags after authenticating", func(t *testing.T) {
+		tags, err := client.Tags(ref)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "latest"}, tags)
+	})
+
+	t.Run("Pull extracts the last layer of the image for the current platform", func(t *testing.T) {
+		dest := t.TempDir()
+		assert.Nil(t, client.Pull(ref, "1.0.0", dest))
+
+		contents, err := os.ReadFile(filepath.Join(dest, "bin", "tool"))
+		assert.Nil(t, err)
+		assert.Equal(t, "#!/bin/sh\necho tool\n", string(contents))
+		assert.NoFileExists(t, filepath.Join(dest, "bin", "sh"))
+
+		info, err := os.Stat(filepath.Join(dest, "bin", "tool"))
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	})
+
+	t.Run("Pull returns error for unknown tag", func(t *testing.T) {
+		err := client.Pull(ref, "9.9.9", t.TempDir())
+		assert.ErrorContains(t, err, "404")
+	})
+}
+
+func TestExtract(t *testing.T) {
+	t.Run("rejects entries outside the destination", func(t *testing.T) {
+		layer := buildLayer(t, map[string]string{"../escape": "oops"})
+		assert.ErrorContains(t, extract(layer, t.TempDir()), "outside the install directory")
+	})
+
+	t.Run("rejects absolute symlinks", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := tar.NewWriter(&buf)
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "bin/tool", Typeflag: tar.TypeSymlink, Linkname: "/usr/bin/tool"}))
+		assert.Nil(t, archive.Close())
+
+		assert.ErrorContains(t, extract(buf.Bytes(), t.TempDir()), "links outside the install directory")
+	})
+
+	t.Run("rejects symlinks chained outside the destination", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := tar.NewWriter(&buf)
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "d/a", Typeflag: tar.TypeSymlink, Linkname: ".."}))
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "d/a/b", Typeflag: tar.TypeSymlink, Linkname: "../.."}))
+		assert.Nil(t, archive.Close())
+
+		assert.ErrorContains(t, extract(buf.Bytes(), t.TempDir()), "links outside the install directory")
+	})
+
+	t.Run("rejects entries written through symlinks chained outside the destination", func(t *testing.T) {
+		parent := t.TempDir()
+		dest := filepath.Join(parent, "dest")
+		assert.Nil(t, os.Mkdir(dest, 0o777))
+
+		var buf bytes.Buffer
+		archive := tar.NewWriter(&buf)
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "d/a", Typeflag: tar.TypeSymlink, Linkname: "x/.."}))
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "d/x", Typeflag: tar.TypeSymlink, Linkname: ".."}))
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "d/a/escape", Mode: 0o644, Size: 4, Typeflag: tar.TypeReg}))
+		_, err := archive.Write([]byte("oops"))
+		assert.Nil(t, err)
+		assert.Nil(t, archive.Close())
+
+		assert.ErrorContains(t, extract(buf.Bytes(), dest), "layer entry d/a/escape is outside the install directory")
+		assert.NoFileExists(t, filepath.Join(parent, "escape"))
+	})
+
+	t.Run("extracts entries written through symlinks within the destination", func(t *testing.T) {
+		dest := t.TempDir()
+		var buf bytes.Buffer
+		archive := tar.NewWriter(&buf)
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "share/", Mode: 0o755, Typeflag: tar.TypeDir}))
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "lib/current", Typeflag: tar.TypeSymlink, Linkname: "../share"}))
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: "lib/current/tool", Mode: 0o644, Size: 4, Typeflag: tar.TypeReg}))
+		_, err := archive.Write([]byte("tool"))
+		assert.Nil(t, err)
+		assert.Nil(t, archive.Close())
+
+		assert.Nil(t, extract(buf.Bytes(), dest))
+		assert.FileExists(t, filepath.Join(dest, "share", "tool"))
+	})
+
+	t.Run("skips whiteout files", func(t *testing.T) {
+		dest := t.TempDir()
+		layer := buildLayer(t, map[string]string{"bin/.wh.old": "", "bin/tool": "tool"})
+		assert.Nil(t, extract(layer, dest))
+		assert.NoFileExists(t, filepath.Join(dest, "bin", ".wh.old"))
+		assert.FileExists(t, filepath.Join(dest, "bin", "tool"))
+	})
+}
+
+func TestVerify(t *testing.T) {
+	_, err := verify(strings.NewReader("blob"), digestOf([]byte("other")))
+	assert.ErrorContains(t, err, "digest mismatch")
+
+	blob, err := verify(strings.NewReader("blob"), digestOf([]byte("blob")))
+	assert.Nil(t, err)
+	assert.Equal(t, "blob", string(blob))
+}
+
+func buildLayer(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for name, contents := range files {
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := archive.Write([]byte(contents))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, archive.Close())
+	assert.Nil(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func digestOf(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// newRegistry serves a multi-platform image with the base and tool layers
+// under the 1.0.0 tag of org/tool, requiring a bearer token like public
+// registries do.
+func newRegistry(t *testing.T, layers map[string][]byte) *httptest.Server {
+	t.Helper()
+
+	blobs := map[string][]byte{}
+	for _, layer := range layers {
+		blobs[digestOf(layer)] = layer
+	}
+
+	image, err := json.Marshal(manifest{Layers: []descriptor{{Digest: digestOf(layers["base"])}, {Digest: digestOf(layers["tool"])}}})
+	assert.Nil(t, err)
+	index, err := json.Marshal(manifest{Manifests: []descriptor{
+		{Digest: "sha256:other", Platform: &platform{OS: "windows", Architecture: runtime.GOARCH}},
+		{Digest: digestOf(image), Platform: &platform{OS: "linux", Architecture: runtime.GOARCH}},
+	}})
+	assert.Nil(t, err)
+
+	manifests := map[string][]byte{"1.0.0": index, digestOf(image): image}
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"token": "secret"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:org/tool:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/v2/org/tool/")
+		switch {
+		case path == "tags/list":
+			fmt.Fprint(w, `{"name": "org/tool", "tags": ["1.0.0", "latest"]}`)
+		case strings.HasPrefix(path, "manifests/") && manifests[strings.TrimPrefix(path, "manifests/")] != nil:
+			w.Write(manifests[strings.TrimPrefix(path, "manifests/")])
+		case strings.HasPrefix(path, "blobs/") && blobs[strings.TrimPrefix(path, "blobs/")] != nil:
+			w.Write(blobs[strings.TrimPrefix(path, "blobs/")])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
@@ -0,0 +1,139 @@
+// Package overrides manages per-directory tool version overrides. Overrides are
+// stored in a file in the asdf data directory rather than in the directory they
+// apply to, so versions can be changed for a repository without modifying it.
+// An override applies to its directory and every directory below it.
+package overrides
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Filename is the name of the file in the data directory overrides are stored
+// in
+const Filename = "overrides.json"
+
+// Override represents the versions set for a tool in a directory
+type Override struct {
+	Directory string   `json:"directory"`
+	Tool      string   `json:"tool"`
+	Versions  []string `json:"versions"`
+}
+
+// List returns all overrides, ordered by directory and then tool name
+func List(dataDir string) (overrides []Override, err error) {
+	contents, err := os.ReadFile(path(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return overrides, nil
+	}
+
+	if err != nil {
+		return overrides, err
+	}
+
+	if err := json.Unmarshal(contents, &overrides); err != nil {
+		return overrides, fmt.Errorf("invalid overrides file %s: %w", path(dataDir), err)
+	}
+
+	return overrides, nil
+}
+
+// Set stores an override for the tool in the directory, replacing any existing
+// override for the same tool and directory
+func Set(dataDir, directory, tool string, versions []string) error {
+	overrides, err := List(dataDir)
+	if err != nil {
+		return err
+	}
+
+	directory = filepath.Clean(directory)
+	overrides = slices.DeleteFunc(overrides, func(o Override) bool {
+		return o.Directory == directory && o.Tool == tool
+	})
+	overrides = append(overrides, Override{Directory: directory, Tool: tool, Versions: versions})
+
+	return write(dataDir, overrides)
+}
+
+// Remove deletes the override for the tool in the directory. The boolean
+// returned indicates whether an override existed.
+func Remove(dataDir, directory, tool string) (bool, error) {
+	overrides, err := List(dataDir)
+	if err != nil {
+		return false, err
+	}
+
+	directory = filepath.Clean(directory)
+	remaining := slices.DeleteFunc(slices.Clone(overrides), func(o Override) bool {
+		return o.Directory == directory && o.Tool == tool
+	})
+
+	if len(remaining) == len(overrides) {
+		return false, nil
+	}
+
+	return true, write(dataDir, remaining)
+}
+
+// Find returns the override for the tool that applies to the directory. When
+// overrides are set for several parent directories the closest one wins.
+func Find(dataDir, directory, tool string) (override Override, found bool, err error) {
+	overrides, err := List(dataDir)
+	if err != nil {
+		return override, false, err
+	}
+
+	directory = filepath.Clean(directory)
+	for _, o := range overrides {
+		if o.Tool != tool || !contains(o.Directory, directory) {
+			continue
+		}
+
+		if !found || len(o.Directory) > len(override.Directory) {
+			override, found = o, true
+		}
+	}
+
+	return override, found, nil
+}
+
+func contains(parent, directory string) bool {
+	if parent == directory || parent == string(filepath.Separator) {
+		return true
+	}
+	return strings.HasPrefix(directory, parent+string(filepath.Separator))
+}
+
+func write(dataDir string, overrides []Override) error {
+	slices.SortFunc(overrides, func(a, b Override) int {
+		if c := strings.Compare(a.Directory, b.Directory); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tool, b.Tool)
+	})
+
+	if overrides == nil {
+		overrides = []Override{}
+	}
+
+	contents, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dataDir, 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path(dataDir), append(contents, '\n'), 0o666)
+}
+
+func path(dataDir string) string {
+	return filepath.Join(dataDir, Filename)
+}
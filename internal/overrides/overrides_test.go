@@ -0,0 +1,93 @@
+package overrides
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	t.Run("returns no overrides when file does not exist", func(t *testing.T) {
+		overrides, err := List(t.TempDir())
+		assert.Nil(t, err)
+		assert.Empty(t, overrides)
+	})
+
+	t.Run("returns error when file is invalid", func(t *testing.T) {
+		dataDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(dataDir, Filename), []byte("not json"), 0o666))
+
+		_, err := List(dataDir)
+		assert.ErrorContains(t, err, "invalid overrides file")
+	})
+}
+
+func TestSet(t *testing.T) {
+	dataDir := t.TempDir()
+
+	assert.Nil(t, Set(dataDir, "/repo/b", "nodejs", []string{"20.0.0"}))
+	assert.Nil(t, Set(dataDir, "/repo/a/", "python", []string{"3.12.0", "system"}))
+	assert.Nil(t, Set(dataDir, "/repo/b", "nodejs", []string{"22.0.0"}))
+
+	overrides, err := List(dataDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []Override{
+		{Directory: "/repo/a", Tool: "python", Versions: []string{"3.12.0", "system"}},
+		{Directory: "/repo/b", Tool: "nodejs", Versions: []string{"22.0.0"}},
+	}, overrides)
+}
+
+func TestRemove(t *testing.T) {
+	dataDir := t.TempDir()
+	assert.Nil(t, Set(dataDir, "/repo", "nodejs", []string{"20.0.0"}))
+
+	t.Run("returns false when override does not exist", func(t *testing.T) {
+		removed, err := Remove(dataDir, "/repo", "python")
+		assert.Nil(t, err)
+		assert.False(t, removed)
+	})
+
+	t.Run("removes override", func(t *testing.T) {
+		removed, err := Remove(dataDir, "/repo", "nodejs")
+		assert.Nil(t, err)
+		assert.True(t, removed)
+
+		overrides, err := List(dataDir)
+		assert.Nil(t, err)
+		assert.Empty(t, overrides)
+	})
+}
+
+func TestFind(t *testing.T) {
+	dataDir := t.TempDir()
+	assert.Nil(t, Set(dataDir, "/repo", "nodejs", []string{"20.0.0"}))
+	assert.Nil(t, Set(dataDir, "/repo/pkg", "nodejs", []string{"22.0.0"}))
+	assert.Nil(t, Set(dataDir, "/repo", "python", []string{"3.12.0"}))
+
+	tests := []struct {
+		desc      string
+		directory string
+		tool      string
+		expected  []string
+		found     bool
+	}{
+		{desc: "exact directory", directory: "/repo", tool: "nodejs", expected: []string{"20.0.0"}, found: true},
+		{desc: "subdirectory", directory: "/repo/src/lib", tool: "nodejs", expected: []string{"20.0.0"}, found: true},
+		{desc: "closest directory wins", directory: "/repo/pkg/app", tool: "nodejs", expected: []string{"22.0.0"}, found: true},
+		{desc: "directory with shared prefix", directory: "/repository", tool: "nodejs", found: false},
+		{desc: "other tool", directory: "/repo", tool: "ruby", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			override, found, err := Find(dataDir, tt.directory, tt.tool)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.found, found)
+			if tt.found {
+				assert.Equal(t, tt.expected, override.Versions)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Package patches collects the patches set in the [patches] section of the
+// asdfrc for a tool version into a directory before it is installed, so teams
+// carrying local patches to the source of a tool don't need to fork its
+// plugin. The directory is passed to the download and install callbacks as
+// ASDF_PATCHES_DIR, plugins apply the patches in it in lexical order.
+package patches
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+// DirName is the name of the directory patches are collected in, within the
+// temporary directory of the install
+const DirName = "patches"
+
+const checksumPrefix = "sha256="
+
+// client is the HTTP client patches are downloaded with
+var client = http.DefaultClient
+
+// Patch is a patch to apply to the source of a tool
+type Patch struct {
+	// Source is the path or URL of the patch
+	Source string
+	// Checksum is the expected hex encoded SHA-256 of the patch, required for
+	// URLs
+	Checksum string
+}
+
+// ChecksumError is returned when a patch doesn't match its checksum
+type ChecksumError struct {
+	source   string
+	expected string
+	actual   string
+}
+
+func (e ChecksumError) Error() string {
+	return fmt.Sprintf("patch %s has checksum sha256:%s, expected sha256:%s", e.source, e.actual, e.expected)
+}
+
+// Parse parses a patch set in the asdfrc, a path or URL optionally followed by
+// `#sha256=<checksum>`
+func Parse(spec string) (Patch, error) {
+	source, fragment, hasFragment := strings.Cut(spec, "#")
+	patch := Patch{Source: source}
+
+	if hasFragment {
+		if !strings.HasPrefix(fragment, checksumPrefix) {
+			return patch, fmt.Errorf("invalid checksum for patch %s, expected #sha256=<checksum>", source)
+		}
+		patch.Checksum = strings.ToLower(strings.TrimPrefix(fragment, checksumPrefix))
+	}
+
+	if isURL(source) && patch.Checksum == "" {
+		return patch, fmt.Errorf("patch %s is downloaded and needs a checksum, add #sha256=<checksum>", source)
+	}
+
+	return patch, nil
+}
+
+// ForVersion returns the patches for a version of a tool. Patches set for the
+// tool come first, followed by those set for `<tool>@<version>` keys matching
+// the version, from the least to the most specific key. A key's version
+// matches itself and every version that starts with it followed by a dot.
+func ForVersion(conf config.Config, toolName, version string) (patches []Patch, err error) {
+	configured, err := conf.Patches()
+	if err != nil {
+		return patches, err
+	}
+
+	var versionKeys []string
+	for key := range configured {
+		tool, keyVersion, ok := strings.Cut(key, "@")
+		if ok && tool == toolName && versionspec.Satisfies(version, keyVersion) {
+			versionKeys = append(versionKeys, key)
+		}
+	}
+	slices.SortFunc(versionKeys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+	})
+
+	for _, key := range append([]string{toolName}, versionKeys...) {
+		for _, spec := range configured[key] {
+			patch, err := Parse(spec)
+			if err != nil {
+				return patches, err
+			}
+			patches = append(patches, patch)
+		}
+	}
+
+	return patches, nil
+}
+
+// Collect copies or downloads the patches for a version of a tool into the
+// patches directory in dir, numbering them so their lexical order is the order
+// they are set in. The path of the directory is returned, or an empty string
+// when no patches are set for the version. Every patch with a checksum is
+// verified.
+func Collect(conf config.Config, toolName, version, dir string) (string, error) {
+	patches, err := ForVersion(conf, toolName, version)
+	if err != nil || len(patches) == 0 {
+		return "", err
+	}
+
+	patchesDir := filepath.Join(dir, DirName)
+	if err := os.MkdirAll(patchesDir, 0o777); err != nil {
+		return "", err
+	}
+
+	for i, patch := range patches {
+		dest := filepath.Join(patchesDir, fmt.Sprintf("%04d-%s", i+1, baseName(patch.Source)))
+		if err := fetch(patch, dest); err != nil {
+			return "", err
+		}
+	}
+
+	return patchesDir, nil
+}
+
+func fetch(patch Patch, dest string) error {
+	var source io.ReadCloser
+	if isURL(patch.Source) {
+		resp, err := client.Get(patch.Source)
+		if err != nil {
+			return fmt.Errorf("unable to download patch %s: %w", patch.Source, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("unable to download patch %s: %s", patch.Source, resp.Status)
+		}
+		source = resp.Body
+	} else {
+		file, err := os.Open(patch.Source)
+		if err != nil {
+			return fmt.Errorf("unable to read patch: %w", err)
+		}
+		source = file
+	}
+	defer source.Close()
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), source)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); patch.Checksum != "" && actual != patch.Checksum {
+		return ChecksumError{source: patch.Source, expected: patch.Checksum, actual: actual}
+	}
+
+	return nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// baseName returns the file name of a path or the last segment of a URL path
+func baseName(source string) string {
+	if isURL(source) {
+		source, _, _ = strings.Cut(source, "?")
+		return path.Base(source)
+	}
+	return filepath.Base(source)
+}
@@ -0,0 +1,111 @@
+package patches
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const patchContents = "--- a/configure\n+++ b/configure\n"
+
+func checksum(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParse(t *testing.T) {
+	t.Run("parses path without checksum", func(t *testing.T) {
+		patch, err := Parse("/patches/fix.patch")
+		assert.Nil(t, err)
+		assert.Equal(t, Patch{Source: "/patches/fix.patch"}, patch)
+	})
+
+	t.Run("parses URL with checksum", func(t *testing.T) {
+		patch, err := Parse("https://example.com/fix.patch#sha256=ABC")
+		assert.Nil(t, err)
+		assert.Equal(t, Patch{Source: "https://example.com/fix.patch", Checksum: "abc"}, patch)
+	})
+
+	t.Run("returns error for URL without checksum", func(t *testing.T) {
+		_, err := Parse("https://example.com/fix.patch")
+		assert.ErrorContains(t, err, "needs a checksum")
+	})
+
+	t.Run("returns error for unknown checksum", func(t *testing.T) {
+		_, err := Parse("/patches/fix.patch#md5=abc")
+		assert.ErrorContains(t, err, "invalid checksum")
+	})
+}
+
+func TestForVersion(t *testing.T) {
+	conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	asdfrc := "[patches]\npython = /p/all.patch\npython@3.11.2 = /p/patch-release.patch\npython@3.11 = /p/minor.patch\npython@3.12 = /p/other.patch\nruby = /p/ruby.patch\n"
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte(asdfrc), 0o666))
+
+	patches, err := ForVersion(conf, "python", "3.11.2")
+	assert.Nil(t, err)
+	assert.Equal(t, []Patch{{Source: "/p/all.patch"}, {Source: "/p/minor.patch"}, {Source: "/p/patch-release.patch"}}, patches)
+
+	patches, err = ForVersion(conf, "nodejs", "20.0.0")
+	assert.Nil(t, err)
+	assert.Empty(t, patches)
+}
+
+func TestCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/remote.patch" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(patchContents))
+	}))
+	defer server.Close()
+
+	patchDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(patchDir, "local.patch"), []byte(patchContents), 0o666))
+
+	collect := func(t *testing.T, patches string) (string, error) {
+		t.Helper()
+		conf := config.Config{ConfigFile: filepath.Join(patchDir, "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[patches]\npython = "+patches+"\n"), 0o666))
+		return Collect(conf, "python", "3.12.1", t.TempDir())
+	}
+
+	t.Run("collects patches in order", func(t *testing.T) {
+		dir, err := collect(t, "local.patch "+server.URL+"/remote.patch#sha256="+checksum(patchContents))
+		assert.Nil(t, err)
+
+		entries, err := os.ReadDir(dir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "0001-local.patch", entries[0].Name())
+		assert.Equal(t, "0002-remote.patch", entries[1].Name())
+
+		contents, err := os.ReadFile(filepath.Join(dir, "0002-remote.patch"))
+		assert.Nil(t, err)
+		assert.Equal(t, patchContents, string(contents))
+	})
+
+	t.Run("returns empty directory when there are no patches", func(t *testing.T) {
+		dir, err := Collect(config.Config{}, "python", "3.12.1", t.TempDir())
+		assert.Nil(t, err)
+		assert.Empty(t, dir)
+	})
+
+	t.Run("returns ChecksumError when patch doesn't match checksum", func(t *testing.T) {
+		_, err := collect(t, "local.patch#sha256="+checksum("other"))
+		assert.IsType(t, ChecksumError{}, err)
+	})
+
+	t.Run("returns error when patch can't be downloaded", func(t *testing.T) {
+		_, err := collect(t, server.URL+"/missing.patch#sha256=abc")
+		assert.ErrorContains(t, err, "404")
+	})
+}
@@ -0,0 +1,65 @@
+package plugins
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Capability is an optional callback a plugin may implement, the feature it
+// provides and what asdf does when the plugin doesn't implement it
+type Capability struct {
+	Callback string
+	Feature  string
+	Fallback string
+}
+
+// capabilities lists the optional callbacks in the order they are documented
+var capabilities = []Capability{
+	{Callback: "download", Feature: "downloading separately from installing", Fallback: "bin/install downloads the version itself"},
+	{Callback: "latest-stable", Feature: "looking up the latest stable version", Fallback: "the latest version is picked from bin/list-all"},
+	{Callback: "resolve-alias", Feature: "version aliases such as lts", Fallback: "versions are used as they are set"},
+	{Callback: "list-legacy-filenames", Feature: "legacy version files", Fallback: "only .tool-versions files are read"},
+	{Callback: "parse-legacy-file", Feature: "parsing legacy version files", Fallback: "the contents of the file are used as the version"},
+	{Callback: "list-bin-paths", Feature: "custom executable directories", Fallback: "executables are looked up in bin"},
+	{Callback: "exec-env", Feature: "setting environment variables for executables", Fallback: "executables run in the current environment"},
+	{Callback: "exec-path", Feature: "custom executable paths", Fallback: "the executable found in the executable directories is run"},
+	{Callback: "uninstall", Feature: "custom uninstall steps", Fallback: "the install directory is deleted"},
+	{Callback: "pre-uninstall", Feature: "cleaning up before a version is uninstalled", Fallback: "state kept outside the install directory is left behind"},
+	{Callback: "post-uninstall", Feature: "cleaning up after a version is uninstalled", Fallback: "state kept outside the install directory is left behind"},
+	{Callback: "list-deprecated", Feature: "deprecation notices", Fallback: "no versions are reported as deprecated"},
+	{Callback: "complete", Feature: "shell completion of tool arguments", Fallback: "shells complete file names"},
+	{Callback: "help.overview", Feature: "asdf help documentation", Fallback: "asdf help prints no documentation"},
+}
+
+// requiredCallbacks are the callbacks every plugin must implement
+var requiredCallbacks = []string{"list-all", "install"}
+
+// Capabilities returns the optional callbacks plugins may implement
+func Capabilities() []Capability {
+	return slices.Clone(capabilities)
+}
+
+// Supports returns true if the plugin implements the callback
+func (p Plugin) Supports(callback string) bool {
+	_, err := p.CallbackPath(callback)
+	if err != nil {
+		_, _, ok := p.imageCallback(callback)
+		return ok
+	}
+	return true
+}
+
+// Explain describes the missing callback in terms of the feature the plugin
+// doesn't support, for use in messages shown to users
+func (e NoCallbackError) Explain() string {
+	if slices.Contains(requiredCallbacks, e.callback) {
+		return fmt.Sprintf("plugin %s is missing the required bin/%s callback, it may be incomplete or not an asdf plugin", e.plugin, e.callback)
+	}
+
+	index := slices.IndexFunc(capabilities, func(c Capability) bool { return c.Callback == e.callback })
+	if index < 0 {
+		return e.Error()
+	}
+
+	return fmt.Sprintf("plugin %s doesn't support %s as it has no bin/%s callback", e.plugin, capabilities[index].Feature, e.callback)
+}
@@ -0,0 +1,109 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/oci"
+)
+
+// ImagePrefix marks a plugin URL as an OCI image reference, such as
+// image:ghcr.io/org/tool. Image plugins have no repository, asdf itself lists
+// the image tags as versions and installs a version by extracting the last
+// layer of the image with that tag.
+const ImagePrefix = "image:"
+
+const imageFilename = "image"
+
+// imageBinPaths are the directories executables are looked up in when an image
+// plugin has no list-bin-paths callback. Tools are usually copied into one of
+// these or the root of the image.
+var imageBinPaths = []string{".", "bin", "usr/local/bin", "usr/bin"}
+
+type imageCallback func(ref oci.Reference, environment map[string]string, stdOut io.Writer) error
+
+// imageCallbacks are the callbacks asdf implements for image plugins. Scripts
+// added to the plugin's bin directory take precedence.
+var imageCallbacks = map[string]imageCallback{
+	"list-all":       imageListAll,
+	"install":        imageInstall,
+	"list-bin-paths": imageListBinPaths,
+}
+
+// Image returns the reference of the image the plugin installs versions from,
+// if it is an image plugin
+func (p Plugin) Image() (string, bool) {
+	contents, err := os.ReadFile(filepath.Join(p.Dir, imageFilename))
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(string(contents)), true
+}
+
+func (p Plugin) imageCallback(name string) (oci.Reference, imageCallback, bool) {
+	callback, ok := imageCallbacks[name]
+	if !ok {
+		return oci.Reference{}, nil, false
+	}
+
+	image, ok := p.Image()
+	if !ok {
+		return oci.Reference{}, nil, false
+	}
+
+	ref, err := oci.ParseReference(image)
+	if err != nil {
+		return oci.Reference{}, nil, false
+	}
+
+	return ref, callback, true
+}
+
+func addImage(plugin Plugin, image string) error {
+	if _, err := oci.ParseReference(image); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(plugin.Dir, 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(plugin.Dir, imageFilename), []byte(image+"\n"), 0o666)
+}
+
+func imageListAll(ref oci.Reference, _ map[string]string, stdOut io.Writer) error {
+	tags, err := oci.NewClient().Tags(ref)
+	if err != nil {
+		return err
+	}
+
+	var versions []string
+	for _, tag := range tags {
+		if versionTag(tag) {
+			versions = append(versions, tag)
+		}
+	}
+
+	_, err = fmt.Fprintln(stdOut, strings.Join(versions, " "))
+	return err
+}
+
+// versionTag returns true for tags that look like versions, leaving out
+// floating tags like latest and signature or attestation tags
+func versionTag(tag string) bool {
+	tag = strings.TrimPrefix(tag, "v")
+	return tag != "" && tag[0] >= '0' && tag[0] <= '9'
+}
+
+func imageInstall(ref oci.Reference, environment map[string]string, _ io.Writer) error {
+	return oci.NewClient().Pull(ref, environment["ASDF_INSTALL_VERSION"], environment["ASDF_INSTALL_PATH"])
+}
+
+func imageListBinPaths(_ oci.Reference, _ map[string]string, stdOut io.Writer) error {
+	_, err := fmt.Fprintln(stdOut, strings.Join(imageBinPaths, " "))
+	return err
+}
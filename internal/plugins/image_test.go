@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddImage(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir()}
+
+	t.Run("creates image plugin without cloning a repository", func(t *testing.T) {
+		err := Add(conf, "tool", "image:ghcr.io/org/tool", "")
+		assert.Nil(t, err)
+
+		image, ok := New(conf, "tool").Image()
+		assert.True(t, ok)
+		assert.Equal(t, "ghcr.io/org/tool", image)
+	})
+
+	t.Run("returns error when image reference includes a tag", func(t *testing.T) {
+		err := Add(conf, "tagged", "image:ghcr.io/org/tool:1.0.0", "")
+		assert.ErrorContains(t, err, "must not contain a tag")
+	})
+
+	t.Run("lists image reference as the plugin URL", func(t *testing.T) {
+		plugins, err := List(conf, true, true)
+		assert.Nil(t, err)
+		assert.Equal(t, []Plugin{{Name: "tool", Dir: New(conf, "tool").Dir, URL: "image:ghcr.io/org/tool"}}, plugins)
+	})
+
+	t.Run("returns error when updating image plugin", func(t *testing.T) {
+		_, err := New(conf, "tool").Update(conf, "", io.Discard, io.Discard)
+		assert.ErrorContains(t, err, "can't be updated")
+	})
+}
+
+func TestImageCallbacks(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir()}
+	assert.Nil(t, Add(conf, "tool", "image:ghcr.io/org/tool", ""))
+	plugin := New(conf, "tool")
+
+	t.Run("supports builtin callbacks", func(t *testing.T) {
+		assert.True(t, plugin.Supports("list-all"))
+		assert.True(t, plugin.Supports("install"))
+		assert.False(t, plugin.Supports("exec-env"))
+	})
+
+	t.Run("list-bin-paths prints default image directories", func(t *testing.T) {
+		var stdout strings.Builder
+		err := plugin.RunCallback("list-bin-paths", []string{}, map[string]string{}, &stdout, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, ". bin usr/local/bin usr/bin\n", stdout.String())
+	})
+
+	t.Run("scripts in plugin take precedence over builtin callbacks", func(t *testing.T) {
+		assert.Nil(t, os.MkdirAll(filepath.Join(plugin.Dir, "bin"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "list-bin-paths"), []byte("#!/usr/bin/env bash\necho opt/bin\n"), 0o777))
+
+		var stdout strings.Builder
+		err := plugin.RunCallback("list-bin-paths", []string{}, map[string]string{}, &stdout, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, "opt/bin\n", stdout.String())
+	})
+}
+
+func TestVersionTag(t *testing.T) {
+	for _, tag := range []string{"1.4.2", "v1.4.2", "2024.01"} {
+		assert.True(t, versionTag(tag), tag)
+	}
+
+	for _, tag := range []string{"latest", "v", "sha256-abc.sig", ""} {
+		assert.False(t, versionTag(tag), tag)
+	}
+}
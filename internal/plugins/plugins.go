@@ -2,6 +2,7 @@
 package plugins
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -80,7 +81,7 @@ func (e NoCommandError) Error() string {
 
 const (
 	dataDirPlugins         = "plugins"
-	invalidPluginNameMsg   = "%s is invalid. Name may only contain lowercase letters, numbers, '_', and '-'"
+	invalidPluginNameMsg   = "%s is invalid. Name may only contain lowercase letters, numbers, '_', and '-', optionally prefixed with a namespace and '/'"
 	pluginAlreadyExistsMsg = "Plugin named %s already added"
 	pluginMissingMsg       = "Plugin named %s not installed"
 	hasNoCallbackMsg       = "Plugin named %s does not have a callback named %s"
@@ -98,19 +99,46 @@ type Plugin struct {
 	URL  string
 }
 
+// Namespace returns the namespace of the plugin, or an empty string for
+// plugins without one
+func (p Plugin) Namespace() string {
+	namespace, _, _ := strings.Cut(p.Name, "/")
+	if namespace == p.Name {
+		return ""
+	}
+	return namespace
+}
+
+// ToolName returns the name of the tool the plugin provides, which is its name
+// without the namespace. Plugins in different namespaces may provide the same
+// tool.
+func (p Plugin) ToolName() string {
+	return p.Name[strings.LastIndex(p.Name, "/")+1:]
+}
+
 // New takes config and a plugin name and returns a Plugin struct. It is
-// intended for functions that need to quickly initialize a plugin.
+// intended for functions that need to quickly initialize a plugin. A name that
+// isn't the name of an installed plugin is looked up in the [tool_aliases]
+// section of the asdfrc, so `node` returns the nodejs plugin when it is set as
+// an alias of it. The asdfrc is only read in that case.
 func New(config config.Config, name string) Plugin {
 	pluginsDir := data.PluginDirectory(config.DataDir, name)
+	if _, err := os.Stat(pluginsDir); err != nil {
+		if aliases, _ := config.ToolAliases(); aliases[name] != "" {
+			name = aliases[name]
+			pluginsDir = data.PluginDirectory(config.DataDir, name)
+		}
+	}
+
 	return Plugin{Dir: pluginsDir, Name: name}
 }
 
 // LegacyFilenames returns a slice of filenames if the plugin contains the
 // list-legacy-filenames callback.
-func (p Plugin) LegacyFilenames() (filenames []string, err error) {
+func (p Plugin) LegacyFilenames(ctx context.Context) (filenames []string, err error) {
 	var stdOut strings.Builder
 	var stdErr strings.Builder
-	err = p.RunCallback("list-legacy-filenames", []string{}, map[string]string{}, &stdOut, &stdErr)
+	err = p.RunCallbackContext(ctx, "list-legacy-filenames", []string{}, map[string]string{}, &stdOut, &stdErr)
 	if err != nil {
 		_, ok := err.(NoCallbackError)
 		if ok {
@@ -126,11 +154,51 @@ func (p Plugin) LegacyFilenames() (filenames []string, err error) {
 	return filenames, nil
 }
 
+// Deprecation describes a tool version the plugin marks as deprecated or end
+// of life
+type Deprecation struct {
+	Version string
+	Message string
+}
+
+// Deprecation returns the deprecation notice for a version if the plugin
+// contains the list-deprecated callback and the callback reports the version.
+// Each line printed by the callback contains a version followed by an optional
+// message. A listed version also matches every version that starts with it
+// followed by a dot, so `16` marks all 16.x.y versions as deprecated. When more
+// than one line matches the longest version wins.
+func (p Plugin) Deprecation(version string) (deprecation Deprecation, found bool, err error) {
+	var stdOut strings.Builder
+	var stdErr strings.Builder
+	err = p.RunCallback("list-deprecated", []string{}, map[string]string{}, &stdOut, &stdErr)
+	if err != nil {
+		if _, ok := err.(NoCallbackError); ok {
+			return deprecation, false, nil
+		}
+
+		return deprecation, false, err
+	}
+
+	for _, line := range strings.Split(stdOut.String(), "\n") {
+		listed, message, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if listed == "" || (listed != version && !strings.HasPrefix(version, listed+".")) {
+			continue
+		}
+
+		if !found || len(listed) > len(deprecation.Version) {
+			deprecation = Deprecation{Version: listed, Message: strings.TrimSpace(message)}
+			found = true
+		}
+	}
+
+	return deprecation, found, nil
+}
+
 // ParseLegacyVersionFile takes a file and uses the parse-legacy-file callback
 // script to parse it if the script is present. Otherwise just reads the file
 // directly. In either case the returned string is split on spaces and a slice
 // of versions is returned.
-func (p Plugin) ParseLegacyVersionFile(path string) (versions []string, err error) {
+func (p Plugin) ParseLegacyVersionFile(ctx context.Context, path string) (versions []string, err error) {
 	parseLegacyFileName := "parse-legacy-file"
 	parseCallbackPath := filepath.Join(p.Dir, "bin", parseLegacyFileName)
 
@@ -140,7 +208,7 @@ func (p Plugin) ParseLegacyVersionFile(path string) (versions []string, err erro
 		var stdOut strings.Builder
 		var stdErr strings.Builder
 
-		err = p.RunCallback(parseLegacyFileName, []string{path}, map[string]string{}, &stdOut, &stdErr)
+		err = p.RunCallbackContext(ctx, parseLegacyFileName, []string{path}, map[string]string{}, &stdOut, &stdErr)
 		if err != nil {
 			return versions, err
 		}
@@ -169,7 +237,7 @@ func (p Plugin) Exists() error {
 		return err
 	}
 
-	if !exists {
+	if !exists || isNamespaceDir(p.Dir) {
 		return PluginMissing{plugin: p.Name}
 	}
 
@@ -178,7 +246,22 @@ func (p Plugin) Exists() error {
 
 // RunCallback invokes a callback with the given name if it exists for the plugin
 func (p Plugin) RunCallback(name string, arguments []string, environment map[string]string, stdOut io.Writer, errOut io.Writer) error {
+	return p.RunCallbackContext(context.Background(), name, arguments, environment, stdOut, errOut)
+}
+
+// RunCallbackContext invokes a callback like RunCallback, killing it when the
+// context is done before it exits, so callers can bound how long a plugin may
+// take
+func (p Plugin) RunCallbackContext(ctx context.Context, name string, arguments []string, environment map[string]string, stdOut io.Writer, errOut io.Writer) error {
 	callback, err := p.CallbackPath(name)
+	if _, ok := err.(NoCallbackError); ok {
+		if ref, imageCallback, ok := p.imageCallback(name); ok {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return imageCallback(ref, environment, stdOut)
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -189,7 +272,7 @@ func (p Plugin) RunCallback(name string, arguments []string, environment map[str
 	cmd.Stdout = stdOut
 	cmd.Stderr = errOut
 
-	return cmd.Run()
+	return cmd.RunContext(ctx)
 }
 
 // CallbackPath returns the full file path to a callback script
@@ -270,10 +353,18 @@ func (p Plugin) Update(conf config.Config, ref string, out, errout io.Writer) (s
 		return "", fmt.Errorf("no such plugin: %s", p.Name)
 	}
 
+	if image, ok := p.Image(); ok {
+		return "", fmt.Errorf("plugin %s installs versions from image %s and can't be updated", p.Name, image)
+	}
+
 	repo := git.NewRepo(p.Dir)
 
-	hook.Run(conf, "pre_asdf_plugin_update", []string{p.Name})
-	hook.Run(conf, fmt.Sprintf("pre_asdf_plugin_update_%s", p.Name), []string{p.Name})
+	if err := hook.RunOptional(conf, "pre_asdf_plugin_update", []string{p.Name}); err != nil {
+		return "", err
+	}
+	if err := hook.RunOptional(conf, fmt.Sprintf("pre_asdf_plugin_update_%s", p.Name), []string{p.Name}); err != nil {
+		return "", err
+	}
 
 	newRef, oldSHA, newSHA, err := repo.Update(ref)
 	if err != nil {
@@ -292,10 +383,11 @@ func (p Plugin) Update(conf config.Config, ref string, out, errout io.Writer) (s
 		return newRef, err
 	}
 
-	hook.Run(conf, "post_asdf_plugin_update", []string{p.Name})
-	hook.Run(conf, fmt.Sprintf("post_asdf_plugin_update_%s", p.Name), []string{})
-
-	return newRef, nil
+	if err := hook.RunOptional(conf, "post_asdf_plugin_update", []string{p.Name}); err != nil {
+		return newRef, err
+	}
+	err = hook.RunOptional(conf, fmt.Sprintf("post_asdf_plugin_update_%s", p.Name), []string{})
+	return newRef, err
 }
 
 // List takes config and flags for what to return and builds a list of plugins
@@ -311,55 +403,100 @@ func List(config config.Config, urls, refs bool) (plugins []Plugin, err error) {
 		return plugins, err
 	}
 
+	var names []string
 	for _, file := range files {
-		if file.IsDir() {
-			if refs || urls {
-				var url string
-				var refString string
-				location := filepath.Join(pluginsDir, file.Name())
-				repo := git.NewRepo(location)
-
-				// TODO: Improve these error messages
+		if !file.IsDir() {
+			continue
+		}
+
+		if !isNamespaceDir(filepath.Join(pluginsDir, file.Name())) {
+			names = append(names, file.Name())
+			continue
+		}
+
+		namespaced, err := os.ReadDir(filepath.Join(pluginsDir, file.Name()))
+		if err != nil {
+			return plugins, err
+		}
+		for _, entry := range namespaced {
+			name := file.Name() + "/" + entry.Name()
+			if entry.IsDir() && isPluginDir(filepath.Join(pluginsDir, name)) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		location := filepath.Join(pluginsDir, name)
+		if image, ok := (Plugin{Dir: location}).Image(); ok && (refs || urls) {
+			plugins = append(plugins, Plugin{Name: name, Dir: location, URL: ImagePrefix + image})
+		} else if refs || urls {
+			var url string
+			var refString string
+			repo := git.NewRepo(location)
+
+			if refs {
+				refString, err = repo.Head()
 				if err != nil {
 					return plugins, err
 				}
+			}
 
-				if refs {
-					refString, err = repo.Head()
-					if err != nil {
-						return plugins, err
-					}
-				}
-
-				if urls {
-					url, err = repo.RemoteURL()
-					if err != nil {
-						return plugins, err
-					}
+			if urls {
+				url, err = repo.RemoteURL()
+				if err != nil {
+					return plugins, err
 				}
-
-				plugins = append(plugins, Plugin{
-					Name: file.Name(),
-					Dir:  location,
-					URL:  url,
-					Ref:  refString,
-				})
-			} else {
-				plugins = append(plugins, Plugin{
-					Name: file.Name(),
-					Dir:  filepath.Join(pluginsDir, file.Name()),
-				})
 			}
+
+			plugins = append(plugins, Plugin{
+				Name: name,
+				Dir:  location,
+				URL:  url,
+				Ref:  refString,
+			})
+		} else {
+			plugins = append(plugins, Plugin{
+				Name: name,
+				Dir:  location,
+			})
 		}
 	}
 
 	return plugins, nil
 }
 
+// isPluginDir returns true for directories containing a plugin, which either
+// have a bin directory with its callbacks or are image plugins
+func isPluginDir(dir string) bool {
+	if _, ok := (Plugin{Dir: dir}).Image(); ok {
+		return true
+	}
+
+	exists, _ := directoryExists(filepath.Join(dir, "bin"))
+	return exists
+}
+
+// isNamespaceDir returns true for directories holding namespaced plugins
+func isNamespaceDir(dir string) bool {
+	if isPluginDir(dir) {
+		return false
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.IsDir() && isPluginDir(filepath.Join(dir, entry.Name())) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Add takes plugin name and Git URL and installs the plugin if it isn't
 // already installed
 func Add(config config.Config, pluginName, pluginURL, ref string) error {
-	err := validatePluginName(pluginName)
+	err := ValidateName(pluginName)
 	if err != nil {
 		return err
 	}
@@ -369,11 +506,21 @@ func Add(config config.Config, pluginName, pluginURL, ref string) error {
 		return fmt.Errorf("unable to check if plugin already exists: %w", err)
 	}
 
+	if exists && isNamespaceDir(data.PluginDirectory(config.DataDir, pluginName)) {
+		return fmt.Errorf("%s is a namespace of other plugins", pluginName)
+	}
+
 	if exists {
 		return NewPluginAlreadyExists(pluginName)
 	}
 
-	plugin := New(config, pluginName)
+	// Not New, the plugin is added under the name given even when it is set
+	// as an alias of another plugin
+	plugin := Plugin{Dir: data.PluginDirectory(config.DataDir, pluginName), Name: pluginName}
+
+	if namespace := plugin.Namespace(); namespace != "" && isPluginDir(data.PluginDirectory(config.DataDir, namespace)) {
+		return fmt.Errorf("namespace %s is already the name of a plugin", namespace)
+	}
 
 	if pluginURL == "" {
 		// Ignore error here as the default value is fine
@@ -402,10 +549,18 @@ func Add(config config.Config, pluginName, pluginURL, ref string) error {
 	plugin.URL = pluginURL
 
 	// Run pre hooks
-	hook.Run(config, "pre_asdf_plugin_add", []string{plugin.Name})
-	hook.Run(config, fmt.Sprintf("pre_asdf_plugin_add_%s", plugin.Name), []string{})
+	if err := hook.RunOptional(config, "pre_asdf_plugin_add", []string{plugin.Name}); err != nil {
+		return err
+	}
+	if err := hook.RunOptional(config, fmt.Sprintf("pre_asdf_plugin_add_%s", plugin.Name), []string{}); err != nil {
+		return err
+	}
 
-	err = git.NewRepo(plugin.Dir).Clone(plugin.URL, ref)
+	if image, ok := strings.CutPrefix(plugin.URL, ImagePrefix); ok {
+		err = addImage(plugin, image)
+	} else {
+		err = git.NewRepo(plugin.Dir).Clone(plugin.URL, ref)
+	}
 	if err != nil {
 		return err
 	}
@@ -419,15 +574,15 @@ func Add(config config.Config, pluginName, pluginURL, ref string) error {
 	plugin.RunCallback("post-plugin-add", []string{}, env, os.Stdout, os.Stderr)
 
 	// Run post hooks
-	hook.Run(config, "post_asdf_plugin_add", []string{plugin.Name})
-	hook.Run(config, fmt.Sprintf("post_asdf_plugin_add_%s", plugin.Name), []string{})
-
-	return nil
+	if err := hook.RunOptional(config, "post_asdf_plugin_add", []string{plugin.Name}); err != nil {
+		return err
+	}
+	return hook.RunOptional(config, fmt.Sprintf("post_asdf_plugin_add_%s", plugin.Name), []string{})
 }
 
 // Remove uninstalls a plugin by removing it from the file system if installed
 func Remove(config config.Config, pluginName string, stdout, stderr io.Writer) error {
-	err := validatePluginName(pluginName)
+	err := ValidateName(pluginName)
 	if err != nil {
 		return err
 	}
@@ -439,12 +594,16 @@ func Remove(config config.Config, pluginName string, stdout, stderr io.Writer) e
 		return fmt.Errorf("unable to check if plugin exists: %w", err)
 	}
 
-	if !exists {
+	if !exists || isNamespaceDir(plugin.Dir) {
 		return fmt.Errorf("No such plugin: %s", pluginName)
 	}
 
-	hook.Run(config, "pre_asdf_plugin_remove", []string{plugin.Name})
-	hook.Run(config, fmt.Sprintf("pre_asdf_plugin_remove_%s", plugin.Name), []string{})
+	if err := hook.RunOptional(config, "pre_asdf_plugin_remove", []string{plugin.Name}); err != nil {
+		return err
+	}
+	if err := hook.RunOptional(config, fmt.Sprintf("pre_asdf_plugin_remove_%s", plugin.Name), []string{}); err != nil {
+		return err
+	}
 
 	env := map[string]string{
 		"ASDF_PLUGIN_PATH":       plugin.Dir,
@@ -460,6 +619,14 @@ func Remove(config config.Config, pluginName string, stdout, stderr io.Writer) e
 	err2 := os.RemoveAll(pluginDir)
 	err3 := os.RemoveAll(installDir)
 
+	// Namespaces are removed with their last plugin, removing a directory
+	// fails if it isn't empty
+	if plugin.Namespace() != "" {
+		for _, dir := range []string{downloadDir, pluginDir, installDir} {
+			_ = os.Remove(filepath.Dir(dir))
+		}
+	}
+
 	if err != nil {
 		return err
 	}
@@ -468,10 +635,16 @@ func Remove(config config.Config, pluginName string, stdout, stderr io.Writer) e
 		return err2
 	}
 
-	hook.Run(config, "post_asdf_plugin_remove", []string{plugin.Name})
-	hook.Run(config, fmt.Sprintf("post_asdf_plugin_remove_%s", plugin.Name), []string{})
+	hookErr := hook.RunOptional(config, "post_asdf_plugin_remove", []string{plugin.Name})
+	if err := hook.RunOptional(config, fmt.Sprintf("post_asdf_plugin_remove_%s", plugin.Name), []string{}); hookErr == nil {
+		hookErr = err
+	}
+
+	if err3 != nil {
+		return err3
+	}
 
-	return err3
+	return hookErr
 }
 
 // PluginExists returns a boolean indicating whether or not a plugin with the
@@ -494,8 +667,11 @@ func directoryExists(dir string) (bool, error) {
 	return fileInfo.IsDir(), nil
 }
 
-func validatePluginName(name string) error {
-	match, err := regexp.MatchString("^[[:lower:][:digit:]_-]+$", name)
+// ValidateName returns an error if name isn't a valid plugin name. Names are
+// made of lowercase letters, numbers, '_' and '-', and may be prefixed with a
+// namespace made of the same characters and a '/', for example corp/nodejs.
+func ValidateName(name string) error {
+	match, err := regexp.MatchString("^([[:lower:][:digit:]_-]+/)?[[:lower:][:digit:]_-]+$", name)
 	if err != nil {
 		return err
 	}
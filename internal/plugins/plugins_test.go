@@ -1,11 +1,14 @@
 package plugins
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/asdf-vm/asdf/internal/config"
 	"github.com/asdf-vm/asdf/internal/data"
@@ -79,6 +82,26 @@ func TestNew(t *testing.T) {
 		assert.Equal(t, "test-plugin", plugin.Name)
 		assert.Equal(t, filepath.Join(testDataDir, "plugins", "test-plugin"), plugin.Dir)
 	})
+
+	t.Run("returns plugin of alias set in tool_aliases", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[tool_aliases]\nnode = nodejs\n"), 0o666))
+
+		plugin := New(conf, "node")
+		assert.Equal(t, "nodejs", plugin.Name)
+		assert.Equal(t, filepath.Join(testDataDir, "plugins", "nodejs"), plugin.Dir)
+	})
+
+	t.Run("returns installed plugin named like alias", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[tool_aliases]\nnode = nodejs\n"), 0o666))
+		assert.Nil(t, os.MkdirAll(filepath.Join(testDataDir, "plugins", "node"), 0o777))
+
+		plugin := New(conf, "node")
+		assert.Equal(t, "node", plugin.Name)
+	})
 }
 
 func TestAdd(t *testing.T) {
@@ -153,6 +176,20 @@ func TestAdd(t *testing.T) {
 		assert.Equal(t, 12, len(entries))
 	})
 
+	t.Run("when plugin name is an alias installs plugin under that name", func(t *testing.T) {
+		testDataDir := t.TempDir()
+		conf := config.Config{DataDir: testDataDir, ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[tool_aliases]\nnode = "+testPluginName+"\n"), 0o666))
+		pluginPath, err := repotest.GeneratePlugin("dummy_plugin", testDataDir, testPluginName)
+		assert.Nil(t, err)
+
+		err = Add(conf, "node", pluginPath, "")
+		assert.Nil(t, err)
+
+		assert.DirExists(t, data.PluginDirectory(testDataDir, "node"))
+		assert.NoDirExists(t, data.PluginDirectory(testDataDir, testPluginName))
+	})
+
 	t.Run("when parameters are valid creates plugin download dir", func(t *testing.T) {
 		testDataDir := t.TempDir()
 		conf := config.Config{DataDir: testDataDir}
@@ -225,6 +262,53 @@ func TestRemove(t *testing.T) {
 	})
 }
 
+func TestNamespacedPlugins(t *testing.T) {
+	testDataDir := t.TempDir()
+	conf := config.Config{DataDir: testDataDir}
+	forkName := "corp/" + testPluginName
+
+	repoPath, err := repotest.GeneratePlugin("dummy_plugin", testDataDir, testPluginName)
+	assert.Nil(t, err)
+	assert.Nil(t, Add(conf, testPluginName, repoPath, ""))
+	assert.Nil(t, Add(conf, forkName, repoPath, ""))
+
+	t.Run("Namespace and ToolName split the plugin name", func(t *testing.T) {
+		fork := New(conf, forkName)
+		assert.Equal(t, "corp", fork.Namespace())
+		assert.Equal(t, testPluginName, fork.ToolName())
+		assert.Empty(t, New(conf, testPluginName).Namespace())
+		assert.Equal(t, testPluginName, New(conf, testPluginName).ToolName())
+	})
+
+	t.Run("List returns plugins in namespaces", func(t *testing.T) {
+		plugins, err := List(conf, true, false)
+		assert.Nil(t, err)
+		assert.Len(t, plugins, 2)
+		assert.Equal(t, forkName, plugins[0].Name)
+		assert.Equal(t, filepath.Join(testDataDir, "plugins", "corp", testPluginName), plugins[0].Dir)
+		assert.NotZero(t, plugins[0].URL)
+		assert.Equal(t, testPluginName, plugins[1].Name)
+	})
+
+	t.Run("namespace is not a plugin", func(t *testing.T) {
+		assert.Equal(t, PluginMissing{plugin: "corp"}, New(conf, "corp").Exists())
+		assert.ErrorContains(t, Add(conf, "corp", repoPath, ""), "corp is a namespace of other plugins")
+		assert.ErrorContains(t, Remove(conf, "corp", io.Discard, io.Discard), "No such plugin")
+	})
+
+	t.Run("Add returns error when namespace is a plugin", func(t *testing.T) {
+		err := Add(conf, testPluginName+"/fork", repoPath, "")
+		assert.ErrorContains(t, err, "namespace lua is already the name of a plugin")
+	})
+
+	t.Run("Remove removes namespace with its last plugin", func(t *testing.T) {
+		assert.Nil(t, Remove(conf, forkName, io.Discard, io.Discard))
+		assert.NoDirExists(t, filepath.Join(testDataDir, "plugins", "corp"))
+		assert.NoDirExists(t, filepath.Join(testDataDir, "downloads", "corp"))
+		assert.DirExists(t, New(conf, testPluginName).Dir)
+	})
+}
+
 func TestUpdate(t *testing.T) {
 	testDataDir := t.TempDir()
 	conf := config.Config{DataDir: testDataDir}
@@ -377,17 +461,22 @@ func TestPluginExists(t *testing.T) {
 	})
 }
 
-func TestValidatePluginName(t *testing.T) {
+func TestValidateName(t *testing.T) {
 	t.Run("returns no error when plugin name is valid", func(t *testing.T) {
-		err := validatePluginName(testPluginName)
+		err := ValidateName(testPluginName)
 		assert.Nil(t, err)
 	})
 
-	invalids := []string{"plugin^name", "plugin%name", "plugin name", "PLUGIN_NAME"}
+	t.Run("returns no error when plugin name has a namespace", func(t *testing.T) {
+		err := ValidateName("corp/" + testPluginName)
+		assert.Nil(t, err)
+	})
+
+	invalids := []string{"plugin^name", "plugin%name", "plugin name", "PLUGIN_NAME", "/plugin", "corp/", "a/b/c", "../plugin"}
 
 	for _, invalid := range invalids {
 		t.Run(invalid, func(t *testing.T) {
-			err := validatePluginName(invalid)
+			err := ValidateName(invalid)
 
 			if err == nil {
 				t.Error("Expected an error")
@@ -548,7 +637,7 @@ func TestLegacyFilenames(t *testing.T) {
 	plugin := New(conf, testPluginName)
 
 	t.Run("returns list of filenames when list-legacy-filenames callback is present", func(t *testing.T) {
-		filenames, err := plugin.LegacyFilenames()
+		filenames, err := plugin.LegacyFilenames(context.Background())
 		assert.Nil(t, err)
 		assert.Equal(t, filenames, []string{".dummy-version", ".dummyrc"})
 	})
@@ -559,12 +648,50 @@ func TestLegacyFilenames(t *testing.T) {
 		assert.Nil(t, err)
 		plugin := New(conf, testPluginName)
 
-		filenames, err := plugin.LegacyFilenames()
+		filenames, err := plugin.LegacyFilenames(context.Background())
 		assert.Nil(t, err)
 		assert.Equal(t, filenames, []string{})
 	})
 }
 
+func TestDeprecation(t *testing.T) {
+	testDataDir := t.TempDir()
+	conf := config.Config{DataDir: testDataDir}
+	_, err := repotest.InstallPlugin("dummy_plugin", testDataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := New(conf, testPluginName)
+
+	t.Run("returns not found when list-deprecated callback not present", func(t *testing.T) {
+		_, found, err := plugin.Deprecation("1.0.0")
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	script := "#!/usr/bin/env bash\necho '1 end of life since 2020'\necho '1.1 use 2.0 instead'\necho '2.0.0'\n"
+	assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "list-deprecated", script))
+
+	tests := []struct {
+		version  string
+		found    bool
+		expected Deprecation
+	}{
+		{version: "1.0.0", found: true, expected: Deprecation{Version: "1", Message: "end of life since 2020"}},
+		{version: "1.1.3", found: true, expected: Deprecation{Version: "1.1", Message: "use 2.0 instead"}},
+		{version: "2.0.0", found: true, expected: Deprecation{Version: "2.0.0"}},
+		{version: "2.0.1", found: false},
+		{version: "10.0.0", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			deprecation, found, err := plugin.Deprecation(tt.version)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, deprecation)
+		})
+	}
+}
+
 func TestParseLegacyVersionFile(t *testing.T) {
 	testDataDir := t.TempDir()
 	conf := config.Config{DataDir: testDataDir}
@@ -584,22 +711,33 @@ func TestParseLegacyVersionFile(t *testing.T) {
 		assert.Nil(t, err)
 		plugin := New(conf, testPluginName)
 
-		versions, err := plugin.ParseLegacyVersionFile(path)
+		versions, err := plugin.ParseLegacyVersionFile(context.Background(), path)
 		assert.Nil(t, err)
 		assert.Equal(t, versions, []string{"dummy-1.2.3"})
 	})
 
 	t.Run("returns file contents parsed by parse-legacy-file callback when it is present", func(t *testing.T) {
-		versions, err := plugin.ParseLegacyVersionFile(path)
+		versions, err := plugin.ParseLegacyVersionFile(context.Background(), path)
 		assert.Nil(t, err)
 		assert.Equal(t, versions, []string{"1.2.3"})
 	})
 
 	t.Run("returns error when passed file that doesn't exist", func(t *testing.T) {
-		versions, err := plugin.ParseLegacyVersionFile("non-existent-file")
+		versions, err := plugin.ParseLegacyVersionFile(context.Background(), "non-existent-file")
 		assert.Error(t, err)
 		assert.Empty(t, versions)
 	})
+
+	t.Run("returns error of context when parse-legacy-file callback does not exit in time", func(t *testing.T) {
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "parse-legacy-file", "#!/usr/bin/env bash\nsleep 30\n"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		versions, err := plugin.ParseLegacyVersionFile(ctx, path)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, versions)
+	})
 }
 
 func touchFile(name string) error {
@@ -609,3 +747,31 @@ func touchFile(name string) error {
 	}
 	return file.Close()
 }
+
+func TestSupports(t *testing.T) {
+	testDataDir := t.TempDir()
+	conf := config.Config{DataDir: testDataDir}
+	_, err := repotest.InstallPlugin("dummy_plugin", testDataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := New(conf, testPluginName)
+
+	assert.True(t, plugin.Supports("list-all"))
+	assert.False(t, plugin.Supports("exec-env"))
+}
+
+func TestNoCallbackErrorExplain(t *testing.T) {
+	t.Run("explains missing optional callback", func(t *testing.T) {
+		err := NoCallbackError{callback: "exec-env", plugin: "lua"}
+		assert.Equal(t, "plugin lua doesn't support setting environment variables for executables as it has no bin/exec-env callback", err.Explain())
+	})
+
+	t.Run("explains missing required callback", func(t *testing.T) {
+		err := NoCallbackError{callback: "list-all", plugin: "lua"}
+		assert.Equal(t, "plugin lua is missing the required bin/list-all callback, it may be incomplete or not an asdf plugin", err.Explain())
+	})
+
+	t.Run("returns error message for unknown callback", func(t *testing.T) {
+		err := NoCallbackError{callback: "other", plugin: "lua"}
+		assert.Equal(t, err.Error(), err.Explain())
+	})
+}
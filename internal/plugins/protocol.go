@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Callbacks print the data asdf reads from them, such as versions, to stdout
+// and everything meant for the user, such as progress and warnings, to
+// stderr. Some plugins print log lines to stdout as well, which would end up
+// in the data, so the output of callbacks listing versions is checked for
+// lines that can't be versions.
+var (
+	// versionToken matches anything that may be a version, which never
+	// contains spaces, quotes or brackets
+	versionToken = regexp.MustCompile(`^[[:alnum:]][[:alnum:]._+~:@/-]*$`)
+	// logPrefix matches prefixes commonly used by log lines
+	logPrefix = regexp.MustCompile(`^(\[|==>|->|\*|#|(?i:info|warn|warning|error|debug|note)\b)`)
+)
+
+// SplitVersionOutput separates the versions printed to stdout by a callback
+// listing versions, such as list-all and latest-stable, from diagnostics that
+// should have been printed to stderr. A whole line is diagnostic when it
+// starts like a log line, contains words ending like a label, a clause or a
+// progress message, or is made of more than one word none of which contains a digit.
+// Otherwise only the words that can't be versions are left out, so a single
+// odd word doesn't drop every version printed on the same line.
+func SplitVersionOutput(output string) (versions string, diagnostics []string) {
+	var kept []string
+	for _, line := range strings.Split(output, "\n") {
+		if diagnosticLine(line) {
+			diagnostics = append(diagnostics, strings.TrimSpace(line))
+			continue
+		}
+
+		fields := strings.Fields(line)
+		words := slices.DeleteFunc(slices.Clone(fields), func(field string) bool { return !versionToken.MatchString(field) })
+		if len(words) == len(fields) {
+			kept = append(kept, line)
+			continue
+		}
+
+		for _, field := range fields {
+			if !versionToken.MatchString(field) {
+				diagnostics = append(diagnostics, field)
+			}
+		}
+		if len(words) > 0 {
+			kept = append(kept, strings.Join(words, " "))
+		}
+	}
+
+	return strings.Join(kept, "\n"), diagnostics
+}
+
+// VersionOutput returns the versions printed by the callback to stdout,
+// leaving out diagnostics. When there are any a warning quoting the first is
+// written to stdErr, so the plugin can be fixed.
+func (p Plugin) VersionOutput(callback, output string, stdErr io.Writer) string {
+	versions, diagnostics := SplitVersionOutput(output)
+	if len(diagnostics) > 0 {
+		fmt.Fprintf(stdErr, "warning: %s %s printed %d lines or words that aren't versions to stdout, ignoring them. Plugins must print diagnostics to stderr: %q\n", p.Name, callback, len(diagnostics), diagnostics[0])
+	}
+
+	return versions
+}
+
+func diagnosticLine(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	if logPrefix.MatchString(fields[0]) {
+		return true
+	}
+
+	digits := false
+	for _, field := range fields {
+		if strings.HasSuffix(field, "...") || strings.ContainsAny(field[len(field)-1:], ":,;!?") {
+			return true
+		}
+
+		digits = digits || strings.ContainsAny(field, "0123456789")
+	}
+
+	return len(fields) > 1 && !digits
+}
@@ -0,0 +1,76 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitVersionOutput(t *testing.T) {
+	tests := []struct {
+		desc        string
+		output      string
+		versions    string
+		diagnostics []string
+	}{
+		{
+			desc:     "keeps versions on one line",
+			output:   "1.0.0 1.1.0 2.0.0-rc1\n",
+			versions: "1.0.0 1.1.0 2.0.0-rc1\n",
+		},
+		{
+			desc:     "keeps versions on many lines and single words",
+			output:   "1.0.0\nlatest\nref:v1.2\njdk-21+35\nC",
+			versions: "1.0.0\nlatest\nref:v1.2\njdk-21+35\nC",
+		},
+		{
+			desc:        "leaves out lines ending like a progress message",
+			output:      "Fetching versions from github...\n1.0.0 2.0.0",
+			versions:    "1.0.0 2.0.0",
+			diagnostics: []string{"Fetching versions from github..."},
+		},
+		{
+			desc:        "leaves out lines starting like log lines",
+			output:      "[INFO] using cache\n==> done\n1.0.0\nwarning: 2.0.0 is yanked",
+			versions:    "1.0.0",
+			diagnostics: []string{"[INFO] using cache", "==> done", "warning: 2.0.0 is yanked"},
+		},
+		{
+			desc:        "leaves out words that can't be versions and keeps versions on the same line",
+			output:      "1.0.0 1.1.0 (deprecated) 2.0.0 \"3.0.0\"\n",
+			versions:    "1.0.0 1.1.0 2.0.0\n",
+			diagnostics: []string{"(deprecated)", `"3.0.0"`},
+		},
+		{
+			desc:        "leaves out sentences without versions",
+			output:      "using the cached list\n1.0.0",
+			versions:    "1.0.0",
+			diagnostics: []string{"using the cached list"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			versions, diagnostics := SplitVersionOutput(tt.output)
+			assert.Equal(t, tt.versions, versions)
+			assert.Equal(t, tt.diagnostics, diagnostics)
+		})
+	}
+}
+
+func TestVersionOutput(t *testing.T) {
+	plugin := Plugin{Name: "lua"}
+
+	t.Run("writes nothing when output only contains versions", func(t *testing.T) {
+		var stderr strings.Builder
+		assert.Equal(t, "1.0.0 2.0.0", plugin.VersionOutput("list-all", "1.0.0 2.0.0", &stderr))
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("warns about lines that aren't versions", func(t *testing.T) {
+		var stderr strings.Builder
+		assert.Equal(t, "1.0.0", plugin.VersionOutput("latest-stable", "Resolving latest...\n1.0.0", &stderr))
+		assert.Equal(t, "warning: lua latest-stable printed 1 lines or words that aren't versions to stdout, ignoring them. Plugins must print diagnostics to stderr: \"Resolving latest...\"\n", stderr.String())
+	})
+}
@@ -0,0 +1,266 @@
+// Package profile converges the machine to a profile, a YAML file declaring the
+// plugins to add and the refs they are pinned to, the default versions of
+// tools, asdfrc settings and tool aliases, so every machine of a team or fleet
+// can be set up the same way from one file. Only what differs from the profile
+// is changed, applying it again changes nothing.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of changes, in the order they are applied
+const (
+	// KindConfig sets a key of the asdfrc
+	KindConfig = "config"
+	// KindAlias sets a key of the [tool_aliases] section of the asdfrc
+	KindAlias = "alias"
+	// KindVersion sets a key of the [default_versions] section of the asdfrc
+	KindVersion = "version"
+	// KindPlugin adds a plugin, or updates it to the ref it is pinned to
+	KindPlugin = "plugin"
+	// KindInstall installs a default version
+	KindInstall = "install"
+)
+
+const (
+	aliasesSection  = "tool_aliases"
+	versionsSection = "default_versions"
+	shortHeadLength = 7
+)
+
+// Plugin is a plugin of a profile. The URL defaults to the one of the
+// short-name repository and the ref to the default branch of the plugin.
+type Plugin struct {
+	URL string `yaml:"url"`
+	Ref string `yaml:"ref"`
+}
+
+// Profile describes the state of a machine
+type Profile struct {
+	Plugins map[string]Plugin `yaml:"plugins"`
+	// Versions maps tool names to their default versions, separated by
+	// spaces, see the [default_versions] section of the asdfrc
+	Versions map[string]string `yaml:"versions"`
+	// Config maps keys of the asdfrc, outside of any section, to values
+	Config map[string]string `yaml:"config"`
+	// Aliases maps tool aliases to plugin names, see the [tool_aliases]
+	// section of the asdfrc
+	Aliases map[string]string `yaml:"aliases"`
+}
+
+// Change is a difference between the machine and a profile. From is empty
+// when the profile adds something.
+type Change struct {
+	Kind   string
+	Target string
+	From   string
+	To     string
+}
+
+// String formats the change as a line of a diff
+func (c Change) String() string {
+	if c.From == "" {
+		return strings.TrimSpace(fmt.Sprintf("+ %s %s %s", c.Kind, c.Target, c.To))
+	}
+
+	return fmt.Sprintf("~ %s %s %s -> %s", c.Kind, c.Target, c.From, c.To)
+}
+
+// Load reads the profile at path. Unknown fields are an error, so a misspelled
+// field isn't silently ignored.
+func Load(path string) (profile Profile, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return profile, err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&profile); err != nil && !errors.Is(err, io.EOF) {
+		return profile, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name := range profile.Plugins {
+		if err := plugins.ValidateName(name); err != nil {
+			return profile, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	for tool, versions := range profile.Versions {
+		if len(strings.Fields(versions)) == 0 {
+			return profile, fmt.Errorf("%s: no version set for %s", path, tool)
+		}
+	}
+
+	return profile, nil
+}
+
+// Plan returns the changes applying the profile makes to the machine, in the
+// order they are applied. Plugins that are installed but not in the profile,
+// and settings the profile doesn't set, are left as they are. Installed
+// plugins are only updated when they aren't at the ref the profile pins them
+// to, the URL they were added from isn't changed.
+func Plan(conf config.Config, profile Profile) (changes []Change, err error) {
+	values, err := config.Values(conf.ConfigFile)
+	if err != nil {
+		return changes, err
+	}
+
+	changes = append(changes, valueChanges(KindConfig, values[""], profile.Config)...)
+	changes = append(changes, valueChanges(KindAlias, values[aliasesSection], profile.Aliases)...)
+	changes = append(changes, valueChanges(KindVersion, values[versionsSection], normalizeVersions(profile.Versions))...)
+
+	for _, name := range slices.Sorted(maps.Keys(profile.Plugins)) {
+		pinned := profile.Plugins[name]
+		if plugins.New(conf, name).Exists() != nil {
+			changes = append(changes, Change{Kind: KindPlugin, Target: name, To: strings.TrimSpace(pinned.URL + " " + pinned.Ref)})
+			continue
+		}
+
+		if pinned.Ref == "" {
+			continue
+		}
+
+		repo := git.NewRepo(plugins.New(conf, name).Dir)
+		head, err := repo.Head()
+		if err != nil {
+			return changes, fmt.Errorf("unable to get ref of plugin %s: %w", name, err)
+		}
+
+		// A ref missing from the clone was pushed since it was last updated
+		if commit, err := repo.Commit(pinned.Ref); err != nil || commit != head {
+			changes = append(changes, Change{Kind: KindPlugin, Target: name, From: head[:min(len(head), shortHeadLength)], To: pinned.Ref})
+		}
+	}
+
+	for _, tool := range slices.Sorted(maps.Keys(profile.Versions)) {
+		plugin := plugins.New(conf, tool)
+		for _, version := range strings.Fields(profile.Versions[tool]) {
+			if installable(version) && !installs.IsInstalled(conf, plugin, toolversions.Parse(version)) {
+				changes = append(changes, Change{Kind: KindInstall, Target: plugin.Name, To: version})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// Apply makes the changes planned for the profile, see Plan, printing each one
+// to stdOut before it is made. The asdfrc is written first, so plugins are
+// added and versions installed with the settings of the profile. It stops at
+// the first change that fails, returning the changes made until then.
+func Apply(conf config.Config, profile Profile, stdOut, stdErr io.Writer) (applied []Change, err error) {
+	changes, err := Plan(conf, profile)
+	if err != nil {
+		return applied, err
+	}
+
+	var values []config.Value
+	for _, change := range changes {
+		switch change.Kind {
+		case KindConfig:
+			values = append(values, config.Value{Key: change.Target, Value: change.To})
+		case KindAlias:
+			values = append(values, config.Value{Section: aliasesSection, Key: change.Target, Value: change.To})
+		case KindVersion:
+			values = append(values, config.Value{Section: versionsSection, Key: change.Target, Value: change.To})
+		}
+	}
+
+	if len(values) > 0 {
+		for _, change := range changes[:len(values)] {
+			fmt.Fprintln(stdOut, change)
+		}
+		if err := config.Update(conf.ConfigFile, values); err != nil {
+			return applied, err
+		}
+		applied = append(applied, changes[:len(values)]...)
+
+		// Settings loaded before the asdfrc was written are stale
+		conf.Settings = config.Settings{}
+	}
+
+	for _, change := range changes[len(values):] {
+		fmt.Fprintln(stdOut, change)
+
+		switch {
+		case change.Kind == KindPlugin && change.From == "":
+			pinned := profile.Plugins[change.Target]
+			err = plugins.Add(conf, change.Target, pinned.URL, pinned.Ref)
+		case change.Kind == KindPlugin:
+			_, err = plugins.New(conf, change.Target).Update(conf, change.To, stdOut, stdErr)
+		case change.Kind == KindInstall:
+			err = versions.InstallOneVersion(conf, plugins.New(conf, change.Target), change.To, false, stdOut, stdErr)
+		}
+		if err != nil {
+			return applied, fmt.Errorf("%s %s: %w", change.Kind, change.Target, err)
+		}
+
+		applied = append(applied, change)
+	}
+
+	return applied, nil
+}
+
+// valueChanges returns the changes setting the values of the profile that
+// differ from the current ones, sorted by key
+func valueChanges(kind string, current, values map[string]string) (changes []Change) {
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		value, set := current[key]
+		if set && value == values[key] {
+			continue
+		}
+
+		change := Change{Kind: kind, Target: key, To: values[key]}
+		if set {
+			change.From = value
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// normalizeVersions separates versions by single spaces, as they are compared
+// to the versions in the asdfrc as written
+func normalizeVersions(toolVersions map[string]string) map[string]string {
+	normalized := map[string]string{}
+	for tool, versions := range toolVersions {
+		normalized[tool] = strings.Join(strings.Fields(versions), " ")
+	}
+
+	return normalized
+}
+
+// installable returns true if the version names a single version or ref to
+// install. Versions resolved against the installed ones or the versions
+// available, such as `latest`, `18.x` or `>=18`, and system and path versions,
+// aren't installed by a profile.
+func installable(version string) bool {
+	parsed := toolversions.Parse(version)
+	switch {
+	case parsed.Type == "ref":
+		return true
+	case parsed.Type != "version", strings.HasPrefix(version, "latest"), versionspec.IsWildcard(version):
+		return false
+	default:
+		return strings.TrimLeft(version, "!=<>") == version
+	}
+}
@@ -0,0 +1,146 @@
+package profile
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestLoad(t *testing.T) {
+	write := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "profile.yaml")
+		assert.Nil(t, os.WriteFile(path, []byte(contents), 0o666))
+		return path
+	}
+
+	t.Run("returns profile", func(t *testing.T) {
+		profile, err := Load(write(t, "plugins:\n  python:\n    url: https://example.com/asdf-python.git\n    ref: v1.2.0\n  lua: {}\nversions:\n  python: 3.10 system\nconfig:\n  legacy_version_file: yes\n  concurrency: 4\naliases:\n  py: python\n"))
+		assert.Nil(t, err)
+		assert.Equal(t, Profile{
+			Plugins:  map[string]Plugin{"python": {URL: "https://example.com/asdf-python.git", Ref: "v1.2.0"}, "lua": {}},
+			Versions: map[string]string{"python": "3.10 system"},
+			Config:   map[string]string{"legacy_version_file": "yes", "concurrency": "4"},
+			Aliases:  map[string]string{"py": "python"},
+		}, profile)
+	})
+
+	t.Run("returns empty profile for empty file", func(t *testing.T) {
+		profile, err := Load(write(t, ""))
+		assert.Nil(t, err)
+		assert.Equal(t, Profile{}, profile)
+	})
+
+	t.Run("returns error for unknown field", func(t *testing.T) {
+		_, err := Load(write(t, "plugin:\n  lua: {}\n"))
+		assert.ErrorContains(t, err, "field plugin not found")
+	})
+
+	t.Run("returns error for invalid plugin name", func(t *testing.T) {
+		_, err := Load(write(t, "plugins:\n  Lua: {}\n"))
+		assert.ErrorContains(t, err, "Lua is invalid")
+	})
+
+	t.Run("returns error for tool without versions", func(t *testing.T) {
+		_, err := Load(write(t, "versions:\n  lua: \"\"\n"))
+		assert.ErrorContains(t, err, "no version set for lua")
+	})
+}
+
+func TestApply(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("# mine\nconcurrency = 2\n"), 0o666))
+	repoPath, err := repotest.GeneratePlugin("dummy_plugin", t.TempDir(), testPluginName)
+	assert.Nil(t, err)
+
+	profile := Profile{
+		Plugins:  map[string]Plugin{testPluginName: {URL: repoPath}},
+		Versions: map[string]string{testPluginName: "1.0.0  system"},
+		Config:   map[string]string{"concurrency": "4", "legacy_version_file": "yes"},
+		Aliases:  map[string]string{"dummy": testPluginName},
+	}
+
+	t.Run("plans changes to make", func(t *testing.T) {
+		changes, err := Plan(conf, profile)
+		assert.Nil(t, err)
+		assert.Equal(t, []Change{
+			{Kind: KindConfig, Target: "concurrency", From: "2", To: "4"},
+			{Kind: KindConfig, Target: "legacy_version_file", To: "yes"},
+			{Kind: KindAlias, Target: "dummy", To: testPluginName},
+			{Kind: KindVersion, Target: testPluginName, To: "1.0.0 system"},
+			{Kind: KindPlugin, Target: testPluginName, To: repoPath},
+			{Kind: KindInstall, Target: testPluginName, To: "1.0.0"},
+		}, changes)
+	})
+
+	t.Run("makes changes and prints them", func(t *testing.T) {
+		var stdOut strings.Builder
+		applied, err := Apply(conf, profile, &stdOut, io.Discard)
+		assert.Nil(t, err)
+		assert.Len(t, applied, 6)
+		assert.Contains(t, stdOut.String(), "~ config concurrency 2 -> 4\n+ config legacy_version_file yes\n")
+
+		contents, err := os.ReadFile(conf.ConfigFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "# mine\nconcurrency = 4\nlegacy_version_file = yes\n\n[tool_aliases]\ndummy = lua\n\n[default_versions]\nlua = 1.0.0 system\n", string(contents))
+		assert.DirExists(t, filepath.Join(conf.DataDir, "installs", testPluginName, "1.0.0"))
+	})
+
+	t.Run("makes no changes when applied again", func(t *testing.T) {
+		applied, err := Apply(conf, profile, io.Discard, io.Discard)
+		assert.Nil(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("updates plugin to pinned ref", func(t *testing.T) {
+		assert.Nil(t, exec.Command("git", "-C", repoPath, "tag", "v1", "HEAD~1").Run())
+		profile := profile
+		profile.Plugins = map[string]Plugin{testPluginName: {URL: repoPath, Ref: "v1"}}
+		repo := git.NewRepo(plugins.New(conf, testPluginName).Dir)
+		head, err := repo.Head()
+		assert.Nil(t, err)
+
+		changes, err := Plan(conf, profile)
+		assert.Nil(t, err)
+		assert.Equal(t, []Change{{Kind: KindPlugin, Target: testPluginName, From: head[:7], To: "v1"}}, changes)
+
+		_, err = Apply(conf, profile, io.Discard, io.Discard)
+		assert.Nil(t, err)
+		changes, err = Plan(conf, profile)
+		assert.Nil(t, err)
+		assert.Empty(t, changes)
+	})
+}
+
+func TestChangeString(t *testing.T) {
+	assert.Equal(t, "+ plugin lua", Change{Kind: KindPlugin, Target: "lua"}.String())
+	assert.Equal(t, "+ install lua 1.0.0", Change{Kind: KindInstall, Target: "lua", To: "1.0.0"}.String())
+	assert.Equal(t, "~ config concurrency 2 -> 4", Change{Kind: KindConfig, Target: "concurrency", From: "2", To: "4"}.String())
+}
+
+func TestInstallable(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"1.0.0":       true,
+		"ref:v1":      true,
+		"system":      false,
+		"path:/opt":   false,
+		"latest":      false,
+		"latest:1.2":  false,
+		"18.x":        false,
+		">=18":        false,
+		"!=1.5.3":     false,
+		"3.12.1-beta": true,
+	} {
+		assert.Equal(t, expected, installable(version), version)
+	}
+}
@@ -0,0 +1,131 @@
+// Package projects keeps an index of the project directories asdf has installed
+// or set versions in. The index is used to find the projects still referencing
+// a version before it is uninstalled.
+package projects
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+)
+
+// Filename is the name of the file in the data directory the index is stored
+// in
+const Filename = "projects.json"
+
+// List returns the directories of all known projects, in order
+func List(dataDir string) (directories []string, err error) {
+	contents, err := os.ReadFile(path(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return directories, nil
+	}
+
+	if err != nil {
+		return directories, err
+	}
+
+	if err := json.Unmarshal(contents, &directories); err != nil {
+		return directories, fmt.Errorf("invalid projects file %s: %w", path(dataDir), err)
+	}
+
+	return directories, nil
+}
+
+// Record adds the project the directory belongs to to the index. The project
+// is the closest directory at or above it containing a .tool-versions file.
+// Nothing is recorded when there is none, the .tool-versions file of the home
+// directory sets default versions rather than those of a project.
+func Record(conf config.Config, directory string) error {
+	project, found := projectDir(conf, filepath.Clean(directory))
+	if !found {
+		return nil
+	}
+
+	directories, err := List(conf.DataDir)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(directories, project) {
+		return nil
+	}
+
+	return write(conf.DataDir, append(directories, project))
+}
+
+// Using returns the known projects whose version files set the version of the
+// tool. Projects whose directory no longer exists are removed from the index.
+func Using(conf config.Config, plugin plugins.Plugin, version string) (using []string, err error) {
+	directories, err := List(conf.DataDir)
+	if err != nil {
+		return using, err
+	}
+
+	var existing []string
+	for _, directory := range directories {
+		if _, err := os.Stat(directory); err != nil {
+			continue
+		}
+		existing = append(existing, directory)
+
+		versions, found, err := resolve.InDirectory(conf, plugin, directory)
+		if err != nil {
+			return using, fmt.Errorf("unable to resolve %s in %s: %w", plugin.Name, directory, err)
+		}
+
+		if found && slices.Contains(versions.Versions, version) {
+			using = append(using, directory)
+		}
+	}
+
+	if len(existing) < len(directories) {
+		return using, write(conf.DataDir, existing)
+	}
+
+	return using, nil
+}
+
+func projectDir(conf config.Config, directory string) (string, bool) {
+	homeDir, _ := os.UserHomeDir()
+	for dir := directory; dir != homeDir; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, conf.DefaultToolVersionsFilename)); err == nil {
+			return dir, true
+		}
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return "", false
+}
+
+func write(dataDir string, directories []string) error {
+	if directories == nil {
+		directories = []string{}
+	}
+	slices.Sort(directories)
+
+	contents, err := json.MarshalIndent(directories, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dataDir, 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path(dataDir), append(contents, '\n'), 0o666)
+}
+
+func path(dataDir string) string {
+	return filepath.Join(dataDir, Filename)
+}
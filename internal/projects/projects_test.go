@@ -0,0 +1,104 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestRecord(t *testing.T) {
+	conf, _ := generateConfig(t)
+
+	t.Run("records closest directory containing a .tool-versions file", func(t *testing.T) {
+		project := t.TempDir()
+		subdir := filepath.Join(project, "src", "lib")
+		assert.Nil(t, os.MkdirAll(subdir, 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(project, ".tool-versions"), []byte("lua 1.0.0\n"), 0o666))
+
+		assert.Nil(t, Record(conf, subdir))
+		assert.Nil(t, Record(conf, project))
+
+		directories, err := List(conf.DataDir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{project}, directories)
+	})
+
+	t.Run("records nothing when no .tool-versions file is found", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, Record(conf, directory))
+
+		directories, err := List(conf.DataDir)
+		assert.Nil(t, err)
+		assert.NotContains(t, directories, directory)
+	})
+
+	t.Run("records nothing when only home directory has a .tool-versions file", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		assert.Nil(t, os.WriteFile(filepath.Join(home, ".tool-versions"), []byte("lua 1.0.0\n"), 0o666))
+		directory := filepath.Join(home, "scratch")
+		assert.Nil(t, os.MkdirAll(directory, 0o777))
+
+		assert.Nil(t, Record(conf, directory))
+		assert.Nil(t, Record(conf, home))
+
+		directories, err := List(conf.DataDir)
+		assert.Nil(t, err)
+		assert.NotContains(t, directories, home)
+		assert.NotContains(t, directories, directory)
+	})
+}
+
+func TestUsing(t *testing.T) {
+	conf, plugin := generateConfig(t)
+
+	using := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(using, ".tool-versions"), []byte("lua 2.0.0 1.0.0\n"), 0o666))
+	other := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(other, ".tool-versions"), []byte("lua 2.0.0\n"), 0o666))
+	removed := filepath.Join(t.TempDir(), "removed")
+	assert.Nil(t, os.MkdirAll(removed, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(removed, ".tool-versions"), []byte("lua 1.0.0\n"), 0o666))
+
+	for _, directory := range []string{using, other, removed} {
+		assert.Nil(t, Record(conf, directory))
+	}
+	assert.Nil(t, os.RemoveAll(removed))
+
+	t.Run("returns projects setting the version", func(t *testing.T) {
+		projects, err := Using(conf, plugin, "1.0.0")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{using}, projects)
+	})
+
+	t.Run("ignores versions set in the environment", func(t *testing.T) {
+		t.Setenv("ASDF_LUA_VERSION", "3.0.0")
+		projects, err := Using(conf, plugin, "3.0.0")
+		assert.Nil(t, err)
+		assert.Empty(t, projects)
+	})
+
+	t.Run("removes projects that no longer exist from the index", func(t *testing.T) {
+		directories, err := List(conf.DataDir)
+		assert.Nil(t, err)
+		assert.NotContains(t, directories, removed)
+		assert.Len(t, directories, 2)
+	})
+}
+
+func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
+	t.Helper()
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf, plugins.New(conf, testPluginName)
+}
@@ -0,0 +1,173 @@
+package provenance
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const manifestFilename = "manifest.sha256"
+
+// NoManifestError is returned when verifying a version installed without a
+// manifest of file hashes
+type NoManifestError struct{}
+
+func (e NoManifestError) Error() string {
+	return "no manifest recorded"
+}
+
+// Differences lists the files in an install that don't match its manifest.
+// Paths are relative to the install directory.
+type Differences struct {
+	Modified []string
+	Missing  []string
+	Added    []string
+}
+
+// Empty returns true if the install matches its manifest
+func (d Differences) Empty() bool {
+	return len(d.Modified) == 0 && len(d.Missing) == 0 && len(d.Added) == 0
+}
+
+// WriteManifest hashes every file in installDir and stores the hashes in the
+// metadata directory. The format is the same as the output of sha256sum.
+// Symlinks are recorded by target rather than followed.
+func WriteManifest(metadataDir, installDir string) error {
+	hashes, err := hashTree(installDir)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for path := range hashes {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	var contents strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&contents, "%s  %s\n", hashes[path], path)
+	}
+
+	if err := os.MkdirAll(metadataDir, 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(metadataDir, manifestFilename), []byte(contents.String()), 0o666)
+}
+
+// Verify re-hashes the files in installDir and compares them with the manifest
+// stored in the metadata directory
+func Verify(metadataDir, installDir string) (differences Differences, err error) {
+	recorded, err := readManifest(filepath.Join(metadataDir, manifestFilename))
+	if err != nil {
+		return differences, err
+	}
+
+	current, err := hashTree(installDir)
+	if err != nil {
+		return differences, err
+	}
+
+	for path, hash := range recorded {
+		currentHash, ok := current[path]
+		switch {
+		case !ok:
+			differences.Missing = append(differences.Missing, path)
+		case currentHash != hash:
+			differences.Modified = append(differences.Modified, path)
+		}
+	}
+
+	for path := range current {
+		if _, ok := recorded[path]; !ok {
+			differences.Added = append(differences.Added, path)
+		}
+	}
+
+	slices.Sort(differences.Modified)
+	slices.Sort(differences.Missing)
+	slices.Sort(differences.Added)
+	return differences, nil
+}
+
+func readManifest(manifestPath string) (map[string]string, error) {
+	file, err := os.Open(manifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, NoManifestError{}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, path, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			return nil, fmt.Errorf("invalid line in manifest %s: %q", manifestPath, scanner.Text())
+		}
+		hashes[path] = hash
+	}
+
+	return hashes, scanner.Err()
+}
+
+func hashTree(root string) (map[string]string, error) {
+	hashes := map[string]string{}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256([]byte("symlink:" + target))
+			hashes[relative] = hex.EncodeToString(sum[:])
+			return nil
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		hashes[relative] = hash
+		return nil
+	})
+
+	return hashes, err
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
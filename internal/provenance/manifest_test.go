@@ -0,0 +1,55 @@
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	setup := func(t *testing.T) (string, string) {
+		t.Helper()
+		metadataDir := t.TempDir()
+		installDir := t.TempDir()
+
+		assert.Nil(t, os.MkdirAll(filepath.Join(installDir, "bin"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(installDir, "bin", "lua"), []byte("#!/bin/sh\n"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(installDir, "README"), []byte("readme"), 0o666))
+		assert.Nil(t, os.Symlink("bin/lua", filepath.Join(installDir, "lua")))
+		assert.Nil(t, WriteManifest(metadataDir, installDir))
+		return metadataDir, installDir
+	}
+
+	t.Run("returns NoManifestError when no manifest recorded", func(t *testing.T) {
+		_, err := Verify(t.TempDir(), t.TempDir())
+		assert.IsType(t, NoManifestError{}, err)
+	})
+
+	t.Run("returns no differences for untouched install", func(t *testing.T) {
+		metadataDir, installDir := setup(t)
+
+		differences, err := Verify(metadataDir, installDir)
+		assert.Nil(t, err)
+		assert.True(t, differences.Empty())
+	})
+
+	t.Run("reports modified, missing and added files", func(t *testing.T) {
+		metadataDir, installDir := setup(t)
+		assert.Nil(t, os.WriteFile(filepath.Join(installDir, "bin", "lua"), []byte("#!/bin/sh\nexit 1\n"), 0o777))
+		assert.Nil(t, os.Remove(filepath.Join(installDir, "README")))
+		assert.Nil(t, os.WriteFile(filepath.Join(installDir, "bin", "luac"), []byte(""), 0o777))
+		assert.Nil(t, os.Remove(filepath.Join(installDir, "lua")))
+		assert.Nil(t, os.Symlink("bin/luac", filepath.Join(installDir, "lua")))
+
+		differences, err := Verify(metadataDir, installDir)
+		assert.Nil(t, err)
+		assert.Equal(t, Differences{
+			Modified: []string{"bin/lua", "lua"},
+			Missing:  []string{"README"},
+			Added:    []string{"bin/luac"},
+		}, differences)
+		assert.False(t, differences.Empty())
+	})
+}
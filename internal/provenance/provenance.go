@@ -0,0 +1,136 @@
+// Package provenance records where an installed tool version came from and how
+// it was built, along with a manifest of the hashes of the installed files so
+// changes to the install can be detected later. Both are written for every
+// version installed by asdf and kept in the version's metadata directory until
+// it is uninstalled.
+package provenance
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	recordFilename = "provenance.json"
+	// PluginFilename is the name of the file plugins may write details about the
+	// install to. The full path is passed to the download and install callbacks
+	// in ASDF_PROVENANCE_FILE.
+	PluginFilename = ".asdf-provenance"
+)
+
+// NoRecordError is returned when no provenance record exists for a version,
+// typically because it was installed by an older asdf release
+type NoRecordError struct {
+	tool    string
+	version string
+}
+
+func (e NoRecordError) Error() string {
+	return fmt.Sprintf("no provenance recorded for %s %s", e.tool, e.version)
+}
+
+// Record describes the install of a single tool version
+type Record struct {
+	Tool        string    `json:"tool"`
+	Version     string    `json:"version"`
+	PluginURL   string    `json:"plugin_url,omitempty"`
+	PluginRef   string    `json:"plugin_ref,omitempty"`
+	SourceURL   string    `json:"source_url,omitempty"`
+	Commit      string    `json:"commit,omitempty"`
+	Checksum    string    `json:"checksum,omitempty"`
+	BuildFlags  string    `json:"build_flags,omitempty"`
+	Platform    string    `json:"platform,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+	Duration    string    `json:"duration"`
+}
+
+// Fields returns the names and values of the fields of the record in display
+// order
+func (r Record) Fields() [][2]string {
+	return [][2]string{
+		{"tool", r.Tool},
+		{"version", r.Version},
+		{"plugin_url", r.PluginURL},
+		{"plugin_ref", r.PluginRef},
+		{"source_url", r.SourceURL},
+		{"commit", r.Commit},
+		{"checksum", r.Checksum},
+		{"build_flags", r.BuildFlags},
+		{"platform", r.Platform},
+		{"installed_at", r.InstalledAt.Format(time.RFC3339)},
+		{"duration", r.Duration},
+	}
+}
+
+// Write stores the record in the metadata directory
+func Write(metadataDir string, record Record) error {
+	contents, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(metadataDir, 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(metadataDir, recordFilename), append(contents, '\n'), 0o666)
+}
+
+// Read returns the record stored in the metadata directory
+func Read(metadataDir, tool, version string) (record Record, err error) {
+	contents, err := os.ReadFile(filepath.Join(metadataDir, recordFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return record, NoRecordError{tool: tool, version: version}
+	}
+
+	if err != nil {
+		return record, err
+	}
+
+	err = json.Unmarshal(contents, &record)
+	return record, err
+}
+
+// ReadPluginFile adds the details a plugin wrote to the file at path to the
+// record. The file contains one `key=value` pair per line, the supported keys
+// are source_url, commit, checksum and build_flags. A missing file is not an
+// error.
+func ReadPluginFile(path string, record *Record) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "source_url":
+			record.SourceURL = strings.TrimSpace(value)
+		case "commit":
+			record.Commit = strings.TrimSpace(value)
+		case "checksum":
+			record.Checksum = strings.TrimSpace(value)
+		case "build_flags":
+			record.BuildFlags = strings.TrimSpace(value)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,61 @@
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	metadataDir := filepath.Join(t.TempDir(), "lua", "1.0.0")
+
+	t.Run("returns NoRecordError when no record exists", func(t *testing.T) {
+		_, err := Read(metadataDir, "lua", "1.0.0")
+		assert.IsType(t, NoRecordError{}, err)
+		assert.ErrorContains(t, err, "no provenance recorded for lua 1.0.0")
+	})
+
+	t.Run("reads back written record", func(t *testing.T) {
+		record := Record{
+			Tool:        "lua",
+			Version:     "1.0.0",
+			PluginURL:   "https://github.com/asdf-vm/asdf-lua.git",
+			PluginRef:   "abc123",
+			SourceURL:   "https://www.lua.org/ftp/lua-1.0.0.tar.gz",
+			InstalledAt: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			Duration:    "1m2s",
+		}
+		assert.Nil(t, Write(metadataDir, record))
+
+		read, err := Read(metadataDir, "lua", "1.0.0")
+		assert.Nil(t, err)
+		assert.Equal(t, record, read)
+	})
+}
+
+func TestReadPluginFile(t *testing.T) {
+	t.Run("ignores missing file", func(t *testing.T) {
+		record := Record{Tool: "lua"}
+		assert.Nil(t, ReadPluginFile(filepath.Join(t.TempDir(), PluginFilename), &record))
+		assert.Equal(t, Record{Tool: "lua"}, record)
+	})
+
+	t.Run("sets supported keys", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), PluginFilename)
+		contents := "source_url=https://example.com/lua.tar.gz?a=b\ncommit=abc123\nchecksum = sha256:abc\nbuild_flags=--with-readline\ntool=other\ninvalid line\n"
+		assert.Nil(t, os.WriteFile(path, []byte(contents), 0o666))
+
+		record := Record{Tool: "lua"}
+		assert.Nil(t, ReadPluginFile(path, &record))
+		assert.Equal(t, Record{
+			Tool:       "lua",
+			SourceURL:  "https://example.com/lua.tar.gz?a=b",
+			Commit:     "abc123",
+			Checksum:   "sha256:abc",
+			BuildFlags: "--with-readline",
+		}, record)
+	})
+}
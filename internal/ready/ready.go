@@ -0,0 +1,74 @@
+// Package ready checks whether every tool version declared for a directory is
+// installed and reachable through shims, so setup scripts such as a
+// devcontainer postCreateCommand can wait for the environment to be usable.
+package ready
+
+import (
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Tool is the state of a single declared tool version
+type Tool struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Installed    bool     `json:"installed"`
+	MissingShims []string `json:"missing_shims,omitempty"`
+}
+
+// Ready returns true if the version is installed and all its shims exist
+func (t Tool) Ready() bool {
+	return t.Installed && len(t.MissingShims) == 0
+}
+
+// Status is the state of every tool version declared for a directory
+type Status struct {
+	Ready bool   `json:"ready"`
+	Tools []Tool `json:"tools"`
+}
+
+// Check returns the status of every tool version set for dir. System and path
+// versions aren't managed by asdf and are always considered ready.
+func Check(conf config.Config, dir string) (status Status, err error) {
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return status, err
+	}
+
+	status.Ready = true
+	status.Tools = []Tool{}
+	for _, plugin := range allPlugins {
+		toolVersions, found, err := resolve.Version(conf, plugin, dir)
+		if err != nil {
+			return status, err
+		}
+
+		if !found {
+			continue
+		}
+
+		for _, versionStr := range toolVersions.Versions {
+			version := toolversions.Parse(versionStr)
+			if version.IsSystem() || version.Type == "path" {
+				continue
+			}
+
+			tool := Tool{Name: plugin.Name, Version: versionStr, Installed: installs.IsInstalled(conf, plugin, version)}
+			if tool.Installed {
+				tool.MissingShims, err = shims.Missing(conf, plugin, version)
+				if err != nil {
+					return status, err
+				}
+			}
+
+			status.Ready = status.Ready && tool.Ready()
+			status.Tools = append(status.Tools, tool)
+		}
+	}
+
+	return status, nil
+}
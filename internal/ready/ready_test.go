@@ -0,0 +1,65 @@
+package ready
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installtest"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestCheck(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ".tool-versions"), []byte("lua 1.0.0 system\n"), 0o666)
+	assert.Nil(t, err)
+
+	t.Run("returns not ready when version is not installed", func(t *testing.T) {
+		status, err := Check(conf, dir)
+		assert.Nil(t, err)
+		assert.False(t, status.Ready)
+		assert.Equal(t, []Tool{{Name: testPluginName, Version: "1.0.0"}}, status.Tools)
+	})
+
+	t.Run("returns not ready when shims are missing", func(t *testing.T) {
+		err := installtest.InstallOneVersion(conf, plugin, "version", "1.0.0")
+		assert.Nil(t, err)
+		assert.Nil(t, shims.RemoveAll(conf))
+
+		status, err := Check(conf, dir)
+		assert.Nil(t, err)
+		assert.False(t, status.Ready)
+		assert.True(t, status.Tools[0].Installed)
+		assert.NotEmpty(t, status.Tools[0].MissingShims)
+	})
+
+	t.Run("returns ready once reshimmed", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		assert.Nil(t, shims.GenerateAll(conf, &stdout, &stderr))
+
+		status, err := Check(conf, dir)
+		assert.Nil(t, err)
+		assert.True(t, status.Ready)
+		assert.Equal(t, []Tool{{Name: testPluginName, Version: "1.0.0", Installed: true}}, status.Tools)
+	})
+}
+
+func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
+	t.Helper()
+	conf, err := config.LoadConfig()
+	assert.Nil(t, err)
+	conf.DataDir = t.TempDir()
+
+	_, err = repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf, plugins.New(conf, testPluginName)
+}
@@ -0,0 +1,282 @@
+// Package recovery implements `asdf recover`, which finds what commands that
+// were killed partway left behind in the data directory, such as installs that
+// never finished or plugins that were never fully cloned, and repairs or
+// removes it. Every action taken is reported.
+package recovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/lock"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Checks run by Run, in the order they are run
+const (
+	// CheckLocks finds locks whose holder exited without releasing them
+	CheckLocks = "locks"
+	// CheckPlugins finds plugins whose clone was interrupted
+	CheckPlugins = "plugins"
+	// CheckInstalls finds installs that never finished and installs indexes
+	// that can't be read
+	CheckInstalls = "installs"
+	// CheckStaging finds temporary directories and files of interrupted
+	// installs and reshims
+	CheckStaging = "staging"
+	// CheckShims finds shims that weren't fully written
+	CheckShims = "shims"
+)
+
+// Results of actions
+const (
+	ResultRemoved  = "removed"
+	ResultRepaired = "repaired"
+	ResultFailed   = "failed"
+)
+
+// Action is a single thing repaired or removed
+type Action struct {
+	Check  string
+	Target string
+	Result string
+	Detail string
+}
+
+// Report lists the actions taken by a run
+type Report struct {
+	Actions []Action
+	// Failed is true when any action failed
+	Failed bool
+}
+
+func (r *Report) add(actions ...Action) {
+	for _, action := range actions {
+		r.Actions = append(r.Actions, action)
+		r.Failed = r.Failed || action.Result == ResultFailed
+	}
+}
+
+// Run recovers the data directory. The plugins and installs locks are taken
+// with acquire for the whole run, so nothing another command is working on is
+// mistaken for something left behind. Failures of single actions are recorded
+// in the report rather than stopping the run, an error is only returned when
+// recovery can't be run at all.
+func Run(conf config.Config, acquire func(name string) (*lock.Lock, error)) (report Report, err error) {
+	// Locks are listed before they are taken, taking them replaces the
+	// holder recorded by a process that was killed. Releasing them at the
+	// end of the run clears the record.
+	statuses, err := lock.List(conf.DataDir)
+	if err != nil {
+		return report, err
+	}
+
+	for _, name := range []string{lock.Plugins, lock.Installs} {
+		heldLock, err := acquire(name)
+		if err != nil {
+			return report, err
+		}
+		defer heldLock.Release()
+	}
+
+	report.add(staleLocks(statuses)...)
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return report, err
+	}
+
+	pluginActions, allPlugins := interruptedClones(allPlugins)
+	report.add(pluginActions...)
+
+	installActions, removedInstalls := incompleteInstalls(conf, allPlugins)
+	report.add(installActions...)
+	report.add(staging(conf)...)
+	report.add(damagedShims(conf, removedInstalls)...)
+
+	return report, nil
+}
+
+func staleLocks(statuses []lock.Status) (actions []Action) {
+	for _, status := range statuses {
+		if !status.Stale {
+			continue
+		}
+
+		detail := fmt.Sprintf("pid %d (%s) exited without releasing it %s ago", status.Holder.PID, status.Holder.Command, time.Since(status.Holder.Acquired).Round(time.Second))
+		actions = append(actions, Action{Check: CheckLocks, Target: status.Name, Result: ResultRepaired, Detail: detail})
+	}
+
+	return actions
+}
+
+// interruptedClones removes the plugins whose Git clone never finished, so the
+// plugin can be added again, returning the plugins that are left. A clone
+// killed partway leaves a repository whose HEAD points at a branch without a
+// commit, or an empty directory if it was killed before the repository was
+// created. Plugins whose repository can't be checked are kept and the failure
+// reported.
+func interruptedClones(allPlugins []plugins.Plugin) (actions []Action, kept []plugins.Plugin) {
+	for _, plugin := range allPlugins {
+		detail := ""
+		if entries, err := os.ReadDir(plugin.Dir); err == nil && len(entries) == 0 {
+			detail = "empty plugin directory"
+		} else if unborn, err := git.NewRepo(plugin.Dir).Unborn(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			actions = append(actions, Action{Check: CheckPlugins, Target: plugin.Name, Result: ResultFailed, Detail: err.Error()})
+		} else if unborn {
+			detail = "interrupted clone, no commit is checked out"
+		}
+
+		if detail == "" {
+			kept = append(kept, plugin)
+			continue
+		}
+
+		actions = append(actions, removal(CheckPlugins, plugin.Name, detail, os.RemoveAll(plugin.Dir)))
+	}
+
+	return actions, kept
+}
+
+// incompleteInstalls removes the installs that were started but never
+// finished, along with their metadata and partial download, and repairs the
+// installs indexes of the installs_backend setting left unreadable or partly
+// written. removed is true when any install was removed.
+func incompleteInstalls(conf config.Config, allPlugins []plugins.Plugin) (actions []Action, removed bool) {
+	for _, plugin := range allPlugins {
+		installDir := data.InstallDirectory(conf.DataDir, plugin.Name)
+		actions = append(actions, removeMatches(CheckInstalls, filepath.Join(installDir, installs.IndexFilename+".*"), "partly written installs index")...)
+
+		versions, err := installs.All(conf, plugin)
+		if err != nil {
+			indexAction, invalid := repairIndex(installDir)
+			if !invalid {
+				actions = append(actions, Action{Check: CheckInstalls, Target: plugin.Name, Result: ResultFailed, Detail: err.Error()})
+				continue
+			}
+
+			actions = append(actions, indexAction)
+			if indexAction.Result == ResultFailed {
+				continue
+			}
+			if versions, err = installs.All(conf, plugin); err != nil {
+				actions = append(actions, Action{Check: CheckInstalls, Target: plugin.Name, Result: ResultFailed, Detail: err.Error()})
+				continue
+			}
+		}
+
+		for _, versionStr := range versions {
+			version := toolversions.Parse(versionStr)
+			if !installs.Incomplete(conf, plugin, version) {
+				continue
+			}
+
+			installPath := installs.InstallPath(conf, plugin, version)
+			err := os.RemoveAll(installPath)
+			if err == nil {
+				err = installs.Removed(conf, installPath)
+			}
+			if err == nil {
+				err = os.RemoveAll(installs.MetadataPath(conf, plugin, version))
+			}
+			if err == nil {
+				err = os.RemoveAll(installs.DownloadPath(conf, plugin, version))
+			}
+
+			actions = append(actions, removal(CheckInstalls, fmt.Sprintf("%s %s", plugin.Name, versionStr), "incomplete install", err))
+			removed = removed || err == nil
+		}
+	}
+
+	return actions, removed
+}
+
+// repairIndex removes the installs index of the directory if it isn't valid
+// JSON, it is rebuilt from the install directory the next time it is read.
+// invalid is false when the index is valid or can't be read.
+func repairIndex(installDir string) (action Action, invalid bool) {
+	index := filepath.Join(installDir, installs.IndexFilename)
+	contents, err := os.ReadFile(index)
+	if err != nil || json.Valid(contents) {
+		return Action{}, false
+	}
+
+	return result(CheckInstalls, index, ResultRepaired, "unreadable installs index rebuilt", os.Remove(index)), true
+}
+
+// staging removes the temporary directories of installs, see `asdf cache clean
+// --tmp`, and the staging directories and temporary files of reshims
+func staging(conf config.Config) (actions []Action) {
+	actions = append(actions, removeMatches(CheckStaging, filepath.Join(data.TmpDirectory(conf.DataDir), "*"), "temporary directory of install")...)
+
+	paths, err := shims.Leftovers(conf)
+	if err != nil {
+		return append(actions, result(CheckStaging, shims.Directory(conf), ResultRemoved, "", err))
+	}
+
+	for _, path := range paths {
+		actions = append(actions, removal(CheckStaging, path, "left by interrupted reshim", os.RemoveAll(path)))
+	}
+
+	return actions
+}
+
+// damagedShims regenerates the shims when any of them wasn't fully written, or
+// incomplete installs were removed, whose shims would be left pointing at them
+func damagedShims(conf config.Config, removedInstalls bool) (actions []Action) {
+	names, err := shims.Damaged(conf)
+	if err != nil {
+		return []Action{result(CheckShims, shims.Directory(conf), ResultRepaired, "", err)}
+	}
+
+	if len(names) == 0 && !removedInstalls {
+		return actions
+	}
+
+	err = shims.Regenerate(conf, io.Discard, io.Discard)
+	for _, name := range names {
+		actions = append(actions, result(CheckShims, name, ResultRepaired, "partly written shim regenerated", err))
+	}
+	if len(names) == 0 {
+		actions = append(actions, result(CheckShims, shims.Directory(conf), ResultRepaired, "shims regenerated without removed installs", err))
+	}
+
+	return actions
+}
+
+func removeMatches(check, pattern, detail string) (actions []Action) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return []Action{result(check, pattern, ResultRemoved, "", err)}
+	}
+
+	for _, path := range paths {
+		actions = append(actions, removal(check, path, detail, os.RemoveAll(path)))
+	}
+
+	return actions
+}
+
+func removal(check, target, detail string, err error) Action {
+	return result(check, target, ResultRemoved, detail, err)
+}
+
+func result(check, target, done, detail string, err error) Action {
+	if err != nil {
+		return Action{Check: check, Target: target, Result: ResultFailed, Detail: err.Error()}
+	}
+
+	return Action{Check: check, Target: target, Result: done, Detail: detail}
+}
@@ -0,0 +1,186 @@
+package recovery
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/lock"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/asdf-vm/asdf/internal/shims"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versions"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestRun(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	assert.Nil(t, versions.InstallOneVersion(conf, plugin, "1.0.0", false, io.Discard, io.Discard))
+	run := func() Report {
+		report, err := Run(conf, acquire(conf))
+		assert.Nil(t, err)
+		return report
+	}
+
+	t.Run("reports nothing when nothing was left behind", func(t *testing.T) {
+		report := run()
+		assert.Empty(t, report.Actions)
+		assert.False(t, report.Failed)
+	})
+
+	t.Run("clears locks left by processes that exited without releasing them", func(t *testing.T) {
+		lockFile := filepath.Join(data.LockDirectory(conf.DataDir), lock.Installs+".lock")
+		assert.Nil(t, os.WriteFile(lockFile, []byte(`{"pid":4242,"command":"asdf install","acquired":"2026-01-05T03:00:00Z"}`), 0o666))
+
+		report := run()
+		assert.Len(t, report.Actions, 1)
+		assert.Equal(t, Action{Check: CheckLocks, Target: lock.Installs, Result: ResultRepaired}, Action{Check: report.Actions[0].Check, Target: report.Actions[0].Target, Result: report.Actions[0].Result})
+		assert.Contains(t, report.Actions[0].Detail, "pid 4242 (asdf install) exited without releasing it")
+
+		statuses, err := lock.List(conf.DataDir)
+		assert.Nil(t, err)
+		for _, status := range statuses {
+			assert.False(t, status.Stale)
+		}
+	})
+
+	t.Run("removes incomplete installs and regenerates shims", func(t *testing.T) {
+		assert.Nil(t, versions.InstallOneVersion(conf, plugin, "2.0.0", false, io.Discard, io.Discard))
+		version := toolversions.Parse("2.0.0")
+		assert.Nil(t, installs.MarkIncomplete(conf, plugin, version))
+
+		report := run()
+		assert.Equal(t, []Action{
+			{Check: CheckInstalls, Target: "lua 2.0.0", Result: ResultRemoved, Detail: "incomplete install"},
+			{Check: CheckShims, Target: shims.Directory(conf), Result: ResultRepaired, Detail: "shims regenerated without removed installs"},
+		}, report.Actions)
+		assert.NoDirExists(t, installs.InstallPath(conf, plugin, version))
+		assert.NoDirExists(t, installs.MetadataPath(conf, plugin, version))
+
+		shimVersions, err := shims.GetToolsAndVersionsFromShimFile(shims.Path(conf, "dummy"))
+		assert.Nil(t, err)
+		assert.Equal(t, []toolversions.ToolVersions{{Name: testPluginName, Versions: []string{"1.0.0"}}}, shimVersions)
+	})
+
+	t.Run("regenerates partly written shims", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(shims.Path(conf, "dummy"), []byte("#!/usr/bin/env bash\n# asdf-plugin: lua 1.0.0\nexec asdf"), 0o755))
+
+		report := run()
+		assert.Equal(t, []Action{{Check: CheckShims, Target: "dummy", Result: ResultRepaired, Detail: "partly written shim regenerated"}}, report.Actions)
+		damaged, err := shims.Damaged(conf)
+		assert.Nil(t, err)
+		assert.Empty(t, damaged)
+	})
+
+	t.Run("removes temporary directories and files of installs and reshims", func(t *testing.T) {
+		tmpDir := filepath.Join(data.TmpDirectory(conf.DataDir), "lua-2.0.0-123")
+		stagingDir := filepath.Join(conf.DataDir, ".shims-123")
+		tmpShim := filepath.Join(shims.Directory(conf), ".dummy.123")
+		assert.Nil(t, os.MkdirAll(tmpDir, 0o777))
+		assert.Nil(t, os.MkdirAll(stagingDir, 0o777))
+		assert.Nil(t, os.WriteFile(tmpShim, []byte("#!/usr/bin/env bash\n"), 0o755))
+
+		report := run()
+		assert.Equal(t, []Action{
+			{Check: CheckStaging, Target: tmpDir, Result: ResultRemoved, Detail: "temporary directory of install"},
+			{Check: CheckStaging, Target: stagingDir, Result: ResultRemoved, Detail: "left by interrupted reshim"},
+			{Check: CheckStaging, Target: tmpShim, Result: ResultRemoved, Detail: "left by interrupted reshim"},
+		}, report.Actions)
+		assert.NoDirExists(t, tmpDir)
+		assert.NoDirExists(t, stagingDir)
+		assert.NoFileExists(t, tmpShim)
+	})
+
+	t.Run("removes plugins whose clone was interrupted", func(t *testing.T) {
+		emptyDir := data.PluginDirectory(conf.DataDir, "empty")
+		assert.Nil(t, os.MkdirAll(emptyDir, 0o777))
+		cloneDir := data.PluginDirectory(conf.DataDir, "clone")
+		assert.Nil(t, exec.Command("git", "init", "--quiet", cloneDir).Run())
+
+		report := run()
+		assert.Equal(t, []Action{
+			{Check: CheckPlugins, Target: "clone", Result: ResultRemoved, Detail: "interrupted clone, no commit is checked out"},
+			{Check: CheckPlugins, Target: "empty", Result: ResultRemoved, Detail: "empty plugin directory"},
+		}, report.Actions)
+		assert.NoDirExists(t, emptyDir)
+		assert.NoDirExists(t, cloneDir)
+		assert.DirExists(t, plugin.Dir)
+	})
+
+	t.Run("keeps plugins whose repository can't be checked", func(t *testing.T) {
+		brokenDir := data.PluginDirectory(conf.DataDir, "broken")
+		assert.Nil(t, os.MkdirAll(filepath.Join(brokenDir, ".git", "HEAD"), 0o777))
+		defer os.RemoveAll(brokenDir)
+		t.Setenv("PATH", "")
+
+		report := run()
+		assert.Len(t, report.Actions, 1)
+		assert.Equal(t, "broken", report.Actions[0].Target)
+		assert.Equal(t, ResultFailed, report.Actions[0].Result)
+		assert.True(t, report.Failed)
+		assert.DirExists(t, brokenDir)
+		assert.DirExists(t, plugin.Dir)
+	})
+
+	t.Run("returns error when lock can't be taken", func(t *testing.T) {
+		heldLock, err := lock.Acquire(conf.DataDir, lock.Installs, 0, nil)
+		assert.Nil(t, err)
+		defer heldLock.Release()
+
+		_, err = Run(conf, func(name string) (*lock.Lock, error) {
+			return lock.Acquire(conf.DataDir, name, 1, nil)
+		})
+		assert.ErrorAs(t, err, &lock.TimeoutError{})
+	})
+}
+
+func TestRunInstallsIndex(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("installs_backend = index\n"), 0o666))
+	conf.Settings = config.Settings{}
+	assert.Nil(t, versions.InstallOneVersion(conf, plugin, "1.0.0", false, io.Discard, io.Discard))
+
+	installDir := data.InstallDirectory(conf.DataDir, testPluginName)
+	index := filepath.Join(installDir, installs.IndexFilename)
+	partial := index + ".123"
+	assert.Nil(t, os.WriteFile(index, []byte(`["1.0`), 0o666))
+	assert.Nil(t, os.WriteFile(partial, []byte(`["1.0`), 0o666))
+
+	report, err := Run(conf, acquire(conf))
+	assert.Nil(t, err)
+	assert.Equal(t, []Action{
+		{Check: CheckInstalls, Target: partial, Result: ResultRemoved, Detail: "partly written installs index"},
+		{Check: CheckInstalls, Target: index, Result: ResultRepaired, Detail: "unreadable installs index rebuilt"},
+	}, report.Actions)
+
+	installed, err := installs.Installed(conf, plugin)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0"}, installed)
+}
+
+func acquire(conf config.Config) func(string) (*lock.Lock, error) {
+	return func(name string) (*lock.Lock, error) {
+		return lock.Acquire(conf.DataDir, name, 0, nil)
+	}
+}
+
+func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
+	t.Helper()
+	conf, err := config.LoadConfig()
+	assert.Nil(t, err)
+	conf.DataDir = t.TempDir()
+
+	_, err = repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf, plugins.New(conf, testPluginName)
+}
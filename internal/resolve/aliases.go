@@ -0,0 +1,169 @@
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+const (
+	// resolveAliasCallback is the plugin callback resolving an alias, such as
+	// `lts` or `stable`, to a version
+	resolveAliasCallback = "resolve-alias"
+	// aliasCacheFilename is the name of the file in the cache directory of a
+	// plugin the resolved aliases are stored in
+	aliasCacheFilename = "aliases.json"
+)
+
+// aliasEntry is an alias as resolved by the plugin. Version is empty when the
+// plugin didn't resolve it, so names that aren't aliases aren't looked up on
+// every resolution either.
+type aliasEntry struct {
+	Version  string    `json:"version"`
+	Resolved time.Time `json:"resolved"`
+}
+
+// expand replaces aliases and then `latest` and wildcard versions, see
+// expandAliases and expandInstalled
+func expand(ctx context.Context, conf config.Config, plugin plugins.Plugin, versions ToolVersions) ToolVersions {
+	versions, _ = replaceAliases(ctx, conf, plugin, versions, true)
+	return expandInstalled(conf, plugin, versions)
+}
+
+// expandCached expands the versions like expand does, but only from the aliases
+// already cached. ok is false when an alias isn't cached or its cache entry is
+// out of date, resolving it would run the plugin's resolve-alias callback.
+func expandCached(conf config.Config, plugin plugins.Plugin, versions ToolVersions) (expanded ToolVersions, ok bool) {
+	versions, ok = replaceAliases(context.Background(), conf, plugin, versions, false)
+	if !ok {
+		return versions, false
+	}
+	return expandInstalled(conf, plugin, versions), true
+}
+
+// expandAliases replaces aliases, such as `nodejs lts` or `java stable`, with
+// the version the plugin's resolve-alias callback resolves them to, so a
+// project can track a release channel instead of a version. Versions starting
+// with a letter that aren't installed are looked up. Resolved aliases are
+// cached for list_all_cache_duration minutes, as channels move about as often
+// as new versions are released. A stale alias is still used when the callback
+// fails, so a project keeps working offline, and aliases that can't be
+// resolved at all are left as they are.
+func expandAliases(ctx context.Context, conf config.Config, plugin plugins.Plugin, versions ToolVersions) ToolVersions {
+	versions, _ = replaceAliases(ctx, conf, plugin, versions, true)
+	return versions
+}
+
+// replaceAliases replaces aliases as expandAliases does. When lookup is false
+// the resolve-alias callback is never run, ok is then false if an alias would
+// have been looked up.
+func replaceAliases(ctx context.Context, conf config.Config, plugin plugins.Plugin, versions ToolVersions, lookup bool) (replaced ToolVersions, ok bool) {
+	if _, err := plugin.CallbackPath(resolveAliasCallback); err != nil {
+		return versions, true
+	}
+
+	if !slices.ContainsFunc(versions.Versions, func(version string) bool { return isAlias(conf, plugin, version) }) {
+		return versions, true
+	}
+
+	duration, err := conf.ListAllCacheDuration()
+	if err != nil {
+		return versions, true
+	}
+
+	file := aliasCacheFile(conf, plugin)
+	entries := map[string]aliasEntry{}
+	if contents, err := os.ReadFile(file); err == nil {
+		// An invalid cache is replaced once an alias is resolved
+		_ = json.Unmarshal(contents, &entries)
+	}
+
+	changed := false
+	expanded := make([]string, 0, len(versions.Versions))
+	for _, version := range versions.Versions {
+		if !isAlias(conf, plugin, version) {
+			expanded = append(expanded, version)
+			continue
+		}
+
+		entry, cached := entries[version]
+		if !cached || time.Since(entry.Resolved) >= time.Duration(duration)*time.Minute {
+			if !lookup {
+				return versions, false
+			}
+			if resolved, err := resolveAlias(ctx, plugin, version); err == nil {
+				entry = aliasEntry{Version: resolved, Resolved: time.Now()}
+				entries[version] = entry
+				changed = true
+			}
+		}
+
+		if entry.Version != "" {
+			version = entry.Version
+		}
+		expanded = append(expanded, version)
+	}
+
+	if changed && duration > 0 {
+		// Failing to write the cache only makes the next resolution slower
+		_ = writeCache(file, entries)
+	}
+
+	if !slices.Equal(expanded, versions.Versions) {
+		versions.Requested = versions.Versions
+		versions.Versions = expanded
+	}
+
+	return versions, true
+}
+
+// isAlias returns true if the version may be an alias, which is the case for
+// versions starting with a letter that aren't installed. Versions of tools with
+// named distributions, like `temurin-21.0.1`, are only looked up until they're
+// installed.
+func isAlias(conf config.Config, plugin plugins.Plugin, version string) bool {
+	parsed := toolversions.Parse(version)
+	if parsed.Type != "version" || parsed.IsSystem() || isLatest(version) || versionspec.IsWildcard(version) || parsed.Value == "" {
+		return false
+	}
+
+	first := []rune(parsed.Value)[0]
+	if !unicode.IsLetter(first) || (first == 'v' && len(parsed.Value) > 1 && unicode.IsDigit([]rune(parsed.Value)[1])) {
+		return false
+	}
+
+	return !installs.IsInstalled(conf, plugin, parsed)
+}
+
+// resolveAlias runs the resolve-alias callback of the plugin with the alias,
+// which prints the version the alias currently points at. Nothing is printed
+// for names that aren't aliases.
+func resolveAlias(ctx context.Context, plugin plugins.Plugin, alias string) (string, error) {
+	var stdOut strings.Builder
+	if err := plugin.RunCallbackContext(ctx, resolveAliasCallback, []string{alias}, map[string]string{}, &stdOut, io.Discard); err != nil {
+		return "", err
+	}
+
+	versions := strings.Fields(plugin.VersionOutput(resolveAliasCallback, stdOut.String(), io.Discard))
+	if len(versions) == 0 {
+		return "", nil
+	}
+	return versions[len(versions)-1], nil
+}
+
+func aliasCacheFile(conf config.Config, plugin plugins.Plugin) string {
+	return filepath.Join(data.CacheDirectory(conf.DataDir, plugin.Name), aliasCacheFilename)
+}
@@ -0,0 +1,85 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionAliases(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("list_all_cache_duration = 60\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := "#!/usr/bin/env bash\necho \"$1\" >> " + calls + "\ncase \"$1\" in\n  lts) echo 20.1.0 ;;\n  broken) exit 1 ;;\nesac\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", resolveAliasCallback), []byte(script), 0o777))
+	callCount := func() int {
+		contents, _ := os.ReadFile(calls)
+		return len(strings.Fields(string(contents)))
+	}
+
+	currentDir := t.TempDir()
+	setVersions := func(versions string) {
+		assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), []byte(testPluginName+" "+versions+"\n"), 0o666))
+	}
+
+	t.Run("resolves alias with plugin callback", func(t *testing.T) {
+		setVersions("lts 1.0.0")
+
+		versions, found, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"20.1.0", "1.0.0"}, versions.Versions)
+		assert.Equal(t, []string{"lts", "1.0.0"}, versions.Requested)
+		assert.Equal(t, 1, callCount())
+	})
+
+	t.Run("uses cached alias until it expires", func(t *testing.T) {
+		versions, _, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"20.1.0", "1.0.0"}, versions.Versions)
+		assert.Equal(t, 1, callCount())
+	})
+
+	t.Run("leaves names the plugin does not resolve as they are", func(t *testing.T) {
+		setVersions("nightly")
+
+		versions, _, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"nightly"}, versions.Versions)
+		assert.Nil(t, versions.Requested)
+
+		_, _, err = Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, callCount())
+	})
+
+	t.Run("uses stale alias when callback fails", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(aliasCacheFile(conf, plugin), []byte(`{"broken":{"version":"3.0.0","resolved":"2000-01-01T00:00:00Z"}}`), 0o666))
+		setVersions("broken")
+
+		versions, _, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"3.0.0"}, versions.Versions)
+		assert.Equal(t, 3, callCount())
+	})
+
+	t.Run("does not look up versions or installed versions", func(t *testing.T) {
+		assert.Nil(t, os.MkdirAll(filepath.Join(conf.DataDir, "installs", testPluginName, "stable"), 0o777))
+		setVersions("stable v1.2.3 1.2.3 system latest:1")
+
+		versions, _, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"stable", "v1.2.3", "1.2.3", "system", "latest:1"}, versions.Versions)
+		assert.Equal(t, 3, callCount())
+	})
+}
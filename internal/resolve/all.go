@@ -0,0 +1,69 @@
+package resolve
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+// Result is the outcome of resolving a single tool, see All
+type Result struct {
+	Plugin   plugins.Plugin
+	Versions ToolVersions
+	Found    bool
+	Err      error
+}
+
+// All resolves every plugin in the directory like Version does, returning the
+// results in the order of the plugins. Tools are resolved concurrently by a
+// pool of workers and the .tool-versions files in the directory tree are read
+// once and shared between them, rather than read again for each tool as
+// calling Version for every plugin does.
+func All(conf config.Config, plugins []plugins.Plugin, directory string) []Result {
+	return AllContext(context.Background(), conf, plugins, directory)
+}
+
+// AllContext resolves every plugin in the directory like All, passing the
+// context to the plugin callbacks and hooks run, see VersionContext
+func AllContext(ctx context.Context, conf config.Config, plugins []plugins.Plugin, directory string) []Result {
+	results := make([]Result, len(plugins))
+	for i, plugin := range plugins {
+		results[i].Plugin = plugin
+	}
+
+	conf, err := conf.ForDirectory(directory)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	reads := newFileReads()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(plugins)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := &results[i]
+				result.Versions, result.Found, result.Err = resolveWithHooks(ctx, conf, result.Plugin, directory, reads)
+				if ctxErr := ctx.Err(); result.Err == nil && ctxErr != nil {
+					result.Found, result.Err = false, ctxErr
+				}
+			}
+		}()
+	}
+
+	for i := range plugins {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,106 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: "testdata/asdfrc"}
+	var allPlugins []plugins.Plugin
+	for _, name := range []string{"first", "second", "third", "fourth"} {
+		_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, name)
+		assert.Nil(t, err)
+		allPlugins = append(allPlugins, plugins.New(conf, name))
+	}
+
+	parentDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte("first 1.0.0\nsecond 2.0.0\n"), 0o666))
+	projectDir := filepath.Join(parentDir, "project")
+	assert.Nil(t, os.MkdirAll(projectDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte("second 3.0.0 system\nthird unmanaged\n"), 0o666))
+
+	t.Run("returns the same results as Version for every plugin in order", func(t *testing.T) {
+		results := All(conf, allPlugins, projectDir)
+		assert.Len(t, results, len(allPlugins))
+
+		for i, result := range results {
+			assert.Equal(t, allPlugins[i], result.Plugin)
+
+			versions, found, err := Version(conf, allPlugins[i], projectDir)
+			assert.Equal(t, err, result.Err)
+			assert.Equal(t, found, result.Found, result.Plugin.Name)
+			assert.Equal(t, versions, result.Versions, result.Plugin.Name)
+		}
+
+		assert.Equal(t, []string{"1.0.0"}, results[0].Versions.Versions)
+		assert.Equal(t, parentDir, results[0].Versions.Directory)
+		assert.Equal(t, []string{"3.0.0", "system"}, results[1].Versions.Versions)
+		assert.Equal(t, []string{"system"}, results[2].Versions.Versions)
+		assert.False(t, results[3].Found)
+	})
+
+	t.Run("returns version from environment", func(t *testing.T) {
+		t.Setenv(VariableVersionName("first"), "5.0.0")
+
+		results := All(conf, allPlugins, projectDir)
+		assert.Equal(t, []string{"5.0.0"}, results[0].Versions.Versions)
+	})
+
+	t.Run("returns error of failing post resolve hook for its tool", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("post_asdf_resolve = test \"$1\" != second\n"), 0o666))
+
+		results := All(conf, allPlugins, projectDir)
+		assert.Nil(t, results[0].Err)
+		assert.ErrorContains(t, results[1].Err, "failed to run post_asdf_resolve hook")
+		assert.False(t, results[1].Found)
+	})
+}
+
+func TestFileReads(t *testing.T) {
+	directory := t.TempDir()
+	filepath := filepath.Join(directory, ".tool-versions")
+	assert.Nil(t, os.WriteFile(filepath, []byte("# asdf:root\nlua 5.4.6\n"), 0o666))
+
+	t.Run("reads each file once", func(t *testing.T) {
+		reads := newFileReads()
+		versions, found, err := reads.toolVersions(filepath, []string{"lua"})
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"5.4.6"}, versions)
+
+		assert.Nil(t, os.WriteFile(filepath, []byte("lua 1.0.0\n"), 0o666))
+		defer os.WriteFile(filepath, []byte("# asdf:root\nlua 5.4.6\n"), 0o666)
+
+		versions, _, _ = reads.toolVersions(filepath, []string{"lua"})
+		assert.Equal(t, []string{"5.4.6"}, versions)
+		root, err := reads.isRoot(filepath)
+		assert.Nil(t, err)
+		assert.True(t, root)
+	})
+
+	t.Run("reads files every time when nil", func(t *testing.T) {
+		var reads *fileReads
+		root, err := reads.isRoot(filepath)
+		assert.Nil(t, err)
+		assert.True(t, root)
+
+		_, found, err := reads.toolVersions(filepath, []string{"ruby"})
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns not found when file does not exist", func(t *testing.T) {
+		_, found, err := newFileReads().toolVersions(filepath+"-missing", []string{"lua"})
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+}
@@ -0,0 +1,274 @@
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+// Values of the resolution_cache setting enabling the cache
+const (
+	cacheMemory = "memory"
+	cacheDisk   = "disk"
+)
+
+// cacheFilename is the name of the file in the cache directory of a plugin the
+// disk cache is stored in
+const cacheFilename = "resolve.json"
+
+// cacheLimit is the number of directories cached per tool, the least recently
+// stored are dropped first
+const cacheLimit = 256
+
+// cacheEntry is the result of walking up the directory tree from a directory
+// for a tool, along with the state of every file and directory the walk
+// looked at. An entry is only used while all of them are unchanged.
+type cacheEntry struct {
+	Filename string            `json:"filename"`
+	Legacy   bool              `json:"legacy"`
+	Package  bool              `json:"package_json"`
+	Markers  []string          `json:"markers"`
+	Chain    []string          `json:"version_files"`
+	Aliases  map[string]string `json:"tool_aliases"`
+	Versions ToolVersions      `json:"versions"`
+	Found    bool              `json:"found"`
+	Top      bool              `json:"top"`
+	Files    []cachedFile      `json:"files"`
+	Stored   time.Time         `json:"stored"`
+}
+
+// cachedFile is the state of a file or directory when an entry was stored.
+// Creating or removing a version file changes the modification time of its
+// directory, and editing it changes its own.
+type cachedFile struct {
+	Path    string `json:"path"`
+	Exists  bool   `json:"exists"`
+	ModTime int64  `json:"mod_time,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+}
+
+// memoryCache holds the entries of every tool used by this process, keyed by
+// the path of the disk cache of the tool and then by directory. Entries loaded from and stored to the disk
+// cache are kept here too.
+var (
+	memoryCache = map[string]map[string]cacheEntry{}
+	cacheMutex  sync.Mutex
+)
+
+// findVersionsInTree returns the result of walkTree, from the cache selected by
+// the resolution_cache setting when possible. Walking the directory tree and
+// reading every version file in it on each shim invocation is slow in deep
+// monorepos, a cached result only needs a stat of each file it depends on.
+func findVersionsInTree(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, reads *fileReads) (versions ToolVersions, found, top bool, err error) {
+	mode, _ := conf.ResolutionCache()
+	if mode != cacheMemory && mode != cacheDisk {
+		return walkTree(ctx, conf, plugin, directory, reads, nil)
+	}
+
+	settings, err := cacheSettings(conf, plugin)
+	if err != nil {
+		return versions, false, false, err
+	}
+
+	// The lock isn't held while walking so tools resolved concurrently, see
+	// All, don't wait on each other
+	cacheMutex.Lock()
+	entry, ok := cachedEntries(conf, plugin, mode)[directory]
+	cacheMutex.Unlock()
+	if ok && entry.sameSettings(settings) && unchanged(entry.Files) {
+		return entry.Versions, entry.Found, entry.Top, nil
+	}
+
+	names := []string{"", conf.DefaultToolVersionsFilename, WorkspaceFilename}
+	for _, entry := range settings.Chain {
+		names = append(names, versionFileName(entry))
+	}
+	if settings.Legacy && settings.Chain == nil {
+		legacyFilenames, err := plugin.LegacyFilenames(ctx)
+		if err != nil {
+			return versions, false, false, err
+		}
+		names = append(names, legacyFilenames...)
+	}
+	if settings.Package && settings.Chain == nil {
+		names = append(names, packageJSONFilename)
+	}
+
+	var files []cachedFile
+	record := func(directory string) error {
+		for _, name := range names {
+			file, err := statFile(path.Join(directory, name))
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+		}
+		return nil
+	}
+
+	versions, found, top, err = walkTree(ctx, conf, plugin, directory, reads, record)
+	if err != nil {
+		return versions, found, top, err
+	}
+
+	settings.Versions = versions
+	settings.Found = found
+	settings.Top = top
+	settings.Files = files
+	settings.Stored = time.Now()
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	entries := cachedEntries(conf, plugin, mode)
+	entries[directory] = settings
+	prune(entries)
+
+	if mode == cacheDisk {
+		// The cache only saves time, failing to write it isn't an error
+		_ = writeCache(cacheFile(conf, plugin), entries)
+	}
+
+	return versions, found, top, nil
+}
+
+// cacheSettings returns an entry holding the settings the walk for the tool
+// depends on. A cached entry is only used while they are unchanged.
+func cacheSettings(conf config.Config, plugin plugins.Plugin) (settings cacheEntry, err error) {
+	settings.Filename = conf.DefaultToolVersionsFilename
+
+	settings.Legacy, err = conf.LegacyVersionFile()
+	if err != nil {
+		return settings, err
+	}
+
+	settings.Package, err = packageJSONEnabled(conf)
+	if err != nil {
+		return settings, err
+	}
+
+	settings.Markers, err = conf.BoundaryMarkers()
+	if err != nil {
+		return settings, err
+	}
+
+	// Aliases set the names the tool is looked for under, see toolNames
+	settings.Aliases, err = conf.ToolAliases()
+	if err != nil {
+		return settings, err
+	}
+
+	settings.Chain, err = versionFiles(conf, plugin)
+	return settings, err
+}
+
+// sameSettings returns true if the entry was stored with the settings
+func (e cacheEntry) sameSettings(settings cacheEntry) bool {
+	return e.Filename == settings.Filename && e.Legacy == settings.Legacy && e.Package == settings.Package && slices.Equal(e.Markers, settings.Markers) && slices.Equal(e.Chain, settings.Chain) && maps.Equal(e.Aliases, settings.Aliases)
+}
+
+// cachedEntries returns the cached entries of a tool, reading them from the disk
+// cache the first time they are needed when it is enabled
+func cachedEntries(conf config.Config, plugin plugins.Plugin, mode string) map[string]cacheEntry {
+	file := cacheFile(conf, plugin)
+	if entries, ok := memoryCache[file]; ok {
+		return entries
+	}
+
+	entries := map[string]cacheEntry{}
+	if mode == cacheDisk {
+		if contents, err := os.ReadFile(file); err == nil {
+			// An invalid cache is replaced the next time an entry is stored
+			_ = json.Unmarshal(contents, &entries)
+		}
+	}
+
+	memoryCache[file] = entries
+	return entries
+}
+
+func cacheFile(conf config.Config, plugin plugins.Plugin) string {
+	return filepath.Join(data.CacheDirectory(conf.DataDir, plugin.Name), cacheFilename)
+}
+
+// writeCache writes the entries to a temporary file first so concurrent shims
+// never read a partial cache
+func writeCache(file string, entries any) error {
+	contents, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(contents)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), file)
+}
+
+// prune drops the least recently stored entries over the limit
+func prune(entries map[string]cacheEntry) {
+	if len(entries) <= cacheLimit {
+		return
+	}
+
+	directories := make([]string, 0, len(entries))
+	for directory := range entries {
+		directories = append(directories, directory)
+	}
+	slices.SortFunc(directories, func(a, b string) int { return entries[a].Stored.Compare(entries[b].Stored) })
+
+	for _, directory := range directories[:len(entries)-cacheLimit] {
+		delete(entries, directory)
+	}
+}
+
+func statFile(filePath string) (cachedFile, error) {
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cachedFile{Path: filePath}, nil
+	}
+	if err != nil {
+		return cachedFile{}, err
+	}
+
+	return cachedFile{Path: filePath, Exists: true, ModTime: info.ModTime().UnixNano(), Size: info.Size()}, nil
+}
+
+// unchanged returns true if every file is in the state it was recorded in
+func unchanged(files []cachedFile) bool {
+	for _, file := range files {
+		current, err := statFile(file.Path)
+		if err != nil || current != file {
+			return false
+		}
+	}
+
+	return true
+}
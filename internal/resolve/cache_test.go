@@ -0,0 +1,89 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindVersionsInTreeCache(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("resolution_cache = disk\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	parent := t.TempDir()
+	directory := filepath.Join(parent, "a", "b")
+	assert.Nil(t, os.MkdirAll(directory, 0o777))
+	versionFile := filepath.Join(parent, ".tool-versions")
+	assert.Nil(t, os.WriteFile(versionFile, []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+	resolve := func(t *testing.T) []string {
+		t.Helper()
+		versions, found, err := Version(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		return versions.Versions
+	}
+
+	t.Run("stores result in disk cache", func(t *testing.T) {
+		assert.Equal(t, []string{"1.0.0"}, resolve(t))
+		assert.FileExists(t, cacheFile(conf, plugin))
+	})
+
+	t.Run("returns cached result when nothing changed", func(t *testing.T) {
+		entries := cachedEntries(conf, plugin, cacheDisk)
+		entry := entries[directory]
+		entry.Versions.Versions = []string{"cached"}
+		entries[directory] = entry
+
+		assert.Equal(t, []string{"cached"}, resolve(t))
+	})
+
+	t.Run("invalidates result when version file changes", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(versionFile, []byte(testPluginName+" 2.0.0 3.0.0\n"), 0o666))
+		assert.Equal(t, []string{"2.0.0", "3.0.0"}, resolve(t))
+	})
+
+	t.Run("invalidates result when version file is added closer to directory", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(parent, "a", ".tool-versions"), []byte(testPluginName+" 4.0.0\n"), 0o666))
+		assert.Equal(t, []string{"4.0.0"}, resolve(t))
+	})
+
+	t.Run("reads entries stored by another process", func(t *testing.T) {
+		delete(memoryCache, cacheFile(conf, plugin))
+
+		entries := cachedEntries(conf, plugin, cacheDisk)
+		assert.Equal(t, []string{"4.0.0"}, entries[directory].Versions.Versions)
+		assert.True(t, unchanged(entries[directory].Files))
+	})
+
+	t.Run("invalidates result when tool aliases change", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte("alias-of-tool 5.0.0\n"), 0o666))
+		assert.Equal(t, []string{"4.0.0"}, resolve(t))
+
+		asdfrc := "resolution_cache = disk\n\n[tool_aliases]\nalias-of-tool = " + testPluginName + "\n"
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte(asdfrc), 0o666))
+		assert.Equal(t, []string{"5.0.0"}, resolve(t))
+	})
+}
+
+func TestPrune(t *testing.T) {
+	entries := map[string]cacheEntry{}
+	for i := range cacheLimit + 2 {
+		entries[filepath.Join("/", string(rune('a'+i%26)), string(rune('a'+i/26)))] = cacheEntry{Stored: time.Unix(int64(i), 0)}
+	}
+
+	prune(entries)
+	assert.Len(t, entries, cacheLimit)
+	assert.NotContains(t, entries, "/a/a")
+	assert.NotContains(t, entries, "/b/a")
+	assert.Contains(t, entries, "/c/a")
+}
@@ -0,0 +1,27 @@
+package resolve
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+// findDefaultVersions looks up the versions set for the tool in the
+// [default_versions] section of the asdfrc. They are used when no version is
+// set for the tool in the environment, an override or a version file, before
+// falling back to the versions set in the home directory.
+func findDefaultVersions(conf config.Config, plugin plugins.Plugin) (versions ToolVersions, found bool, err error) {
+	defaultVersions, err := conf.DefaultVersions()
+	if err != nil {
+		return versions, false, err
+	}
+
+	toolVersions, ok := defaultVersions[plugin.Name]
+	if !ok {
+		return versions, false, nil
+	}
+
+	return ToolVersions{Versions: slices.Clone(toolVersions), Directory: filepath.Dir(conf.ConfigFile), Source: filepath.Base(conf.ConfigFile)}, true, nil
+}
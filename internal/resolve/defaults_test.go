@@ -0,0 +1,81 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionDefaultVersions(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), ".asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[default_versions]\n"+testPluginName+" = 2.0.0 system\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	assert.Nil(t, os.WriteFile(filepath.Join(homeDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+
+	t.Run("returns default versions when no version is set", func(t *testing.T) {
+		versions, found, err := Version(conf, plugin, t.TempDir())
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0", "system"}, versions.Versions)
+		assert.Equal(t, ".asdfrc", versions.Source)
+		assert.Equal(t, filepath.Dir(conf.ConfigFile), versions.Directory)
+	})
+
+	t.Run("returns versions set in version file before default versions", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+		versions, found, err := Version(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+	})
+
+	t.Run("returns default versions in project marked as root", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte("# asdf:root\nother 1.0.0\n"), 0o666))
+
+		versions, found, err := Version(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0", "system"}, versions.Versions)
+	})
+
+	t.Run("returns versions set in home directory for tools without default", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[default_versions]\nother = 2.0.0\n"), 0o666))
+
+		versions, found, err := Version(conf, plugin, t.TempDir())
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.2.3"}, versions.Versions)
+		assert.Equal(t, homeDir, versions.Directory)
+	})
+
+	t.Run("returns fresh default versions from cache", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("resolution_cache = disk\n[default_versions]\n"+testPluginName+" = 2.0.0\n"), 0o666))
+		directory := t.TempDir()
+
+		_, _, err := Version(conf, plugin, directory)
+		assert.Nil(t, err)
+
+		versions, found, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.True(t, fresh)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+	})
+}
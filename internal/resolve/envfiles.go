@@ -0,0 +1,97 @@
+package resolve
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+)
+
+// envFilenames are the files, nearest directory first and in this order within
+// a directory, that versions are looked up in when the env_files setting is
+// enabled. .asdf.env lets a project pin versions without sharing them with
+// other tools reading .env.
+var envFilenames = []string{".asdf.env", ".env"}
+
+// findVersionsInEnvFiles looks up the ASDF_<TOOL>_VERSION assignment of the
+// tool in the env files of each directory and its parents, stopping where
+// walkTree stops. Versions set this way take precedence over everything but
+// the environment, as if the files had been loaded into it by a tool like
+// direnv.
+func findVersionsInEnvFiles(conf config.Config, pluginName string, directories []string) (versions ToolVersions, found bool, err error) {
+	enabled, err := conf.EnvFiles()
+	if err != nil || !enabled {
+		return versions, false, err
+	}
+
+	name := VariableVersionName(pluginName)
+	for _, directory := range directories {
+		for {
+			for _, filename := range envFilenames {
+				content, err := os.ReadFile(path.Join(directory, filename))
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					return versions, false, err
+				}
+
+				if value, ok := envFileValue(string(content), name); ok && strings.TrimSpace(value) != "" {
+					return ToolVersions{Versions: parseVersion(value), Directory: directory, Source: filename}, true, nil
+				}
+			}
+
+			root, err := isRootDir(conf, directory, nil)
+			if err != nil {
+				return versions, false, err
+			}
+
+			marker, err := boundaryMarker(conf, directory)
+			if err != nil {
+				return versions, false, err
+			}
+
+			nextDir := path.Dir(directory)
+			if root || marker != "" || nextDir == directory {
+				break
+			}
+			directory = nextDir
+		}
+	}
+
+	return versions, false, nil
+}
+
+// envFileValue returns the value assigned to the variable in the content of an
+// env file. Assignments may start with `export`, values may be quoted and
+// unquoted values end at a ` #` comment. The last assignment wins, as it does
+// when the file is sourced by a shell.
+func envFileValue(content, name string) (value string, found bool) {
+	for _, line := range readEnvLines(content) {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != name {
+			continue
+		}
+
+		rawValue = strings.TrimSpace(rawValue)
+		if len(rawValue) >= 2 && (rawValue[0] == '"' || rawValue[0] == '\'') && rawValue[len(rawValue)-1] == rawValue[0] {
+			value = rawValue[1 : len(rawValue)-1]
+		} else {
+			value, _, _ = strings.Cut(rawValue, " #")
+			value = strings.TrimSpace(value)
+		}
+		found = true
+	}
+
+	return value, found
+}
+
+func readEnvLines(content string) []string {
+	return strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+}
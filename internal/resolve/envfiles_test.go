@@ -0,0 +1,112 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/overrides"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionEnvFiles(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("env_files = yes\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	projectDir := t.TempDir()
+	subDir := filepath.Join(projectDir, "subdir")
+	assert.Nil(t, os.MkdirAll(subDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(subDir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+	assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".env"), []byte("OTHER=1\nexport "+VariableVersionName(testPluginName)+"=\"2.0.0 1.0.0\"\n"), 0o666))
+
+	t.Run("returns version from env file in parent directory before .tool-versions file", func(t *testing.T) {
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0", "1.0.0"}, versions.Versions)
+		assert.Equal(t, ".env", versions.Source)
+		assert.Equal(t, projectDir, versions.Directory)
+	})
+
+	t.Run("returns version from .asdf.env before .env", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".asdf.env"), []byte(VariableVersionName(testPluginName)+"=3.0.0 # pinned\n"), 0o666))
+		defer os.Remove(filepath.Join(projectDir, ".asdf.env"))
+
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"3.0.0"}, versions.Versions)
+		assert.Equal(t, ".asdf.env", versions.Source)
+	})
+
+	t.Run("returns version from env file before override", func(t *testing.T) {
+		assert.Nil(t, overrides.Set(conf.DataDir, subDir, testPluginName, []string{"4.0.0"}))
+		defer overrides.Remove(conf.DataDir, subDir, testPluginName)
+
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, ".env", versions.Source)
+	})
+
+	t.Run("returns version from environment before env file", func(t *testing.T) {
+		t.Setenv(VariableVersionName(testPluginName), "5.0.0")
+
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"5.0.0"}, versions.Versions)
+	})
+
+	t.Run("does not search parent directories of a .tool-versions file marked as root", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(subDir, ".tool-versions"), []byte("# asdf:root\n"+testPluginName+" 1.0.0\n"), 0o666))
+		defer os.WriteFile(filepath.Join(subDir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666)
+
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		assert.Equal(t, ".tool-versions", versions.Source)
+	})
+
+	t.Run("ignores env files when env_files is not set", func(t *testing.T) {
+		conf := config.Config{DataDir: conf.DataDir, DefaultToolVersionsFilename: ".tool-versions", ConfigFile: "testdata/asdfrc"}
+
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+	})
+}
+
+func TestEnvFileValue(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content string
+		value   string
+		found   bool
+	}{
+		{desc: "unset", content: "OTHER=1\n# ASDF_LUA_VERSION=1.0.0\n", value: "", found: false},
+		{desc: "unquoted", content: "ASDF_LUA_VERSION=1.0.0\n", value: "1.0.0", found: true},
+		{desc: "exported with spaces", content: "export  ASDF_LUA_VERSION = 1.0.0 \n", value: "1.0.0", found: true},
+		{desc: "double quoted", content: "ASDF_LUA_VERSION=\"1.0.0 system\"\n", value: "1.0.0 system", found: true},
+		{desc: "single quoted", content: "ASDF_LUA_VERSION='1.0.0'\r\n", value: "1.0.0", found: true},
+		{desc: "comment after unquoted value", content: "ASDF_LUA_VERSION=1.0.0 # pinned\n", value: "1.0.0", found: true},
+		{desc: "last assignment wins", content: "ASDF_LUA_VERSION=1.0.0\nASDF_LUA_VERSION=2.0.0\n", value: "2.0.0", found: true},
+		{desc: "prefix of another variable", content: "ASDF_LUA_VERSION_FILE=1.0.0\n", value: "", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			value, found := envFileValue(tt.content, "ASDF_LUA_VERSION")
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.value, value)
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/overrides"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Candidate is a place Version looked for the versions of a tool
+type Candidate struct {
+	// Source is the environment variable, file or hook the versions could
+	// come from
+	Source string
+	// Versions are the versions the candidate sets, if any
+	Versions []string
+	// Accepted is true for the candidate the versions are taken from
+	Accepted bool
+	// Reason explains why the candidate was accepted or skipped
+	Reason string
+}
+
+// Explain resolves the tool like Version does, also returning every candidate
+// considered in order along with why it was accepted or skipped, for debugging
+// why a version is picked. The resolution cache is bypassed so the candidates
+// reflect the files on disk.
+func Explain(conf config.Config, plugin plugins.Plugin, directory string) (candidates []Candidate, versions ToolVersions, found bool, err error) {
+	ctx := context.Background()
+	explain := func(candidate Candidate) {
+		candidates = append(candidates, candidate)
+	}
+
+	conf, err = conf.ForDirectory(directory)
+	if err != nil {
+		return candidates, versions, false, err
+	}
+
+	versions, found, err = explainVersions(ctx, conf, plugin, directory, explain)
+	if found && err == nil {
+		versions = expandAliases(ctx, conf, plugin, versions)
+		if versions.Requested != nil {
+			explain(Candidate{Source: resolveAliasCallback, Versions: versions.Versions, Accepted: true, Reason: fmt.Sprintf("resolved aliases in %v with the plugin", versions.Requested)})
+		}
+
+		aliased := versions.Versions
+		versions = expandInstalled(conf, plugin, versions)
+		if !slices.Equal(aliased, versions.Versions) {
+			explain(Candidate{Source: "latest", Versions: versions.Versions, Accepted: true, Reason: fmt.Sprintf("replaced %v with the newest installed versions", aliased)})
+		}
+	}
+
+	return candidates, versions, found, err
+}
+
+func explainVersions(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, explain func(Candidate)) (versions ToolVersions, found bool, err error) {
+	envVersions, envVariableName, found := findVersionsInEnv(plugin.Name)
+	if found {
+		explain(Candidate{Source: envVariableName, Versions: envVersions, Accepted: true, Reason: "environment variable is set"})
+		return ToolVersions{Versions: envVersions, Source: envVariableName}, true, nil
+	}
+	explain(Candidate{Source: envVariableName, Reason: "environment variable is not set"})
+
+	versions, set, found, err := findVersionsInToolVersionsPath(ctx, conf, plugin, nil)
+	if set {
+		toolVersionsPath, _ := conf.ToolVersionsPath()
+		if found {
+			explain(Candidate{Source: toolVersionsPath, Versions: versions.Versions, Accepted: true, Reason: "tool_versions_path is set"})
+		} else if err == nil {
+			explain(Candidate{Source: toolVersionsPath, Reason: "tool_versions_path is set, the directory and its parents aren't searched"})
+		}
+		return versions, found, err
+	}
+
+	directories, err := searchDirectories(conf, directory)
+	if err != nil {
+		return versions, false, err
+	}
+
+	if enabled, _ := conf.EnvFiles(); enabled {
+		versions, found, err = findVersionsInEnvFiles(conf, plugin.Name, directories)
+		if err != nil {
+			return versions, false, err
+		}
+		if found {
+			explain(Candidate{Source: filepath.Join(versions.Directory, versions.Source), Versions: versions.Versions, Accepted: true, Reason: fmt.Sprintf("%s is set in env file", envVariableName)})
+			return versions, true, nil
+		}
+		explain(Candidate{Source: strings.Join(envFilenames, ", "), Reason: fmt.Sprintf("%s is not set in env files of the directory or its parents", envVariableName)})
+	}
+
+	overridesFile := filepath.Join(conf.DataDir, overrides.Filename)
+	for _, dir := range directories {
+		override, found, err := overrides.Find(conf.DataDir, dir, plugin.Name)
+		if err != nil {
+			return versions, false, err
+		}
+		if found {
+			explain(Candidate{Source: overridesFile, Versions: override.Versions, Accepted: true, Reason: fmt.Sprintf("override set for %s", override.Directory)})
+			return ToolVersions{Versions: override.Versions, Directory: conf.DataDir, Source: overrides.Filename}, true, nil
+		}
+	}
+	explain(Candidate{Source: overridesFile, Reason: "no override set for the directory or its parents"})
+
+	top := true
+	for _, dir := range directories {
+		dirVersions, dirFound, dirTop, err := explainTree(ctx, conf, plugin, dir, explain)
+		if err != nil || dirFound {
+			return dirVersions, dirFound, err
+		}
+		top = top && dirTop
+	}
+
+	versions, found, err = explainWorktree(ctx, conf, plugin, directories, explain)
+	if err != nil || found {
+		return versions, found, err
+	}
+
+	versions, found, err = findDefaultVersions(conf, plugin)
+	if err != nil {
+		return versions, false, err
+	}
+	if found {
+		explain(Candidate{Source: conf.ConfigFile, Versions: versions.Versions, Accepted: true, Reason: "default version set in [default_versions]"})
+		return versions, true, nil
+	}
+	explain(Candidate{Source: conf.ConfigFile, Reason: "no default version set in [default_versions]"})
+
+	if !top {
+		return explainMissing(ctx, conf, plugin, directory, explain)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return explainMissing(ctx, conf, plugin, directory, explain)
+	}
+
+	fallback, err := conf.Flag(config.HomeFallbackFlag)
+	if err != nil {
+		return versions, false, err
+	}
+
+	var homeCandidates []Candidate
+	versions, found, err = explainDir(ctx, conf, plugin, homeDir, func(candidate Candidate) {
+		homeCandidates = append(homeCandidates, candidate)
+	})
+	if err != nil {
+		return versions, false, err
+	}
+
+	for _, candidate := range homeCandidates {
+		if candidate.Accepted {
+			candidate.Reason = fmt.Sprintf("%s, home directory fallback (%s = %s)", candidate.Reason, config.HomeFallbackFlag, fallback)
+			if fallback == "error" {
+				candidate.Accepted = false
+				explain(candidate)
+				return versions, false, HomeFallbackError{toolName: plugin.Name, homeDir: homeDir}
+			}
+		}
+		explain(candidate)
+	}
+
+	if found {
+		return versions, true, nil
+	}
+
+	return explainMissing(ctx, conf, plugin, directory, explain)
+}
+
+// explainWorktree mirrors findVersionsInWorktree, explaining the version files
+// of the main worktree
+func explainWorktree(ctx context.Context, conf config.Config, plugin plugins.Plugin, directories []string, explain func(Candidate)) (versions ToolVersions, found bool, err error) {
+	enabled, err := conf.WorktreeFallback()
+	if err != nil || !enabled {
+		return versions, false, err
+	}
+
+	for _, dir := range directories {
+		mainDir, ok := mainWorktree(dir)
+		if !ok {
+			continue
+		}
+
+		versions, found, err = explainDir(ctx, conf, plugin, mainDir, func(candidate Candidate) {
+			candidate.Reason = fmt.Sprintf("%s, main worktree fallback (%s = yes)", candidate.Reason, worktreeSetting)
+			explain(candidate)
+		})
+		if err != nil || found {
+			return versions, found, err
+		}
+	}
+
+	return versions, false, nil
+}
+
+// explainTree mirrors walkTree, explaining each directory from the directory up
+// to `/`, a root .tool-versions file or a boundary marker
+func explainTree(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, explain func(Candidate)) (versions ToolVersions, found, top bool, err error) {
+	for dir := directory; ; dir = path.Dir(dir) {
+		versions, found, err = findVersionsInWorkspace(conf, plugin, dir, directory, nil)
+		if err != nil {
+			return versions, false, false, err
+		}
+		if found {
+			explain(Candidate{Source: path.Join(dir, WorkspaceFilename), Versions: versions.Versions, Accepted: true, Reason: fmt.Sprintf("sets %s for subproject", plugin.Name)})
+			return versions, true, false, nil
+		}
+
+		versions, found, err = explainDir(ctx, conf, plugin, dir, explain)
+		if err != nil || found {
+			return versions, found, false, err
+		}
+
+		root, err := isRootDir(conf, dir, nil)
+		if err != nil {
+			return versions, false, false, err
+		}
+		if root {
+			explain(Candidate{Source: path.Join(dir, conf.DefaultToolVersionsFilename), Reason: "marked as project root, parent directories are not searched"})
+			return versions, false, false, nil
+		}
+
+		marker, err := boundaryMarker(conf, dir)
+		if err != nil {
+			return versions, false, false, err
+		}
+		if marker != "" {
+			explain(Candidate{Source: path.Join(dir, marker), Reason: "boundary marker, parent directories are not searched"})
+			return versions, false, false, nil
+		}
+
+		if path.Dir(dir) == dir {
+			return versions, false, true, nil
+		}
+	}
+}
+
+// explainDir mirrors findVersionsInDir, explaining each version file of the
+// directory it looks at
+func explainDir(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, explain func(Candidate)) (versions ToolVersions, found bool, err error) {
+	files, err := versionFiles(conf, plugin)
+	if err != nil {
+		return versions, false, err
+	}
+
+	for _, entry := range files {
+		versions, found, err = findVersionsInVersionFile(ctx, conf, plugin, directory, entry, nil)
+		if err != nil {
+			return versions, false, err
+		}
+		if found {
+			explain(Candidate{Source: path.Join(directory, entry), Versions: versions.Versions, Accepted: true, Reason: fmt.Sprintf("sets %s, from version_files", plugin.Name)})
+			return versions, true, nil
+		}
+		explain(Candidate{Source: path.Join(directory, entry), Reason: fmt.Sprintf("missing or does not set %s, from version_files", plugin.Name)})
+	}
+	if files != nil {
+		return versions, false, nil
+	}
+
+	toolVersionsFile := path.Join(directory, conf.DefaultToolVersionsFilename)
+	if _, err := os.Stat(toolVersionsFile); err != nil {
+		explain(Candidate{Source: toolVersionsFile, Reason: "file does not exist"})
+	} else {
+		names, err := toolNames(conf, plugin)
+		if err != nil {
+			return versions, false, err
+		}
+		toolVersions, found, err := fileToolVersions(readFile(toolVersionsFile), names)
+		if err != nil {
+			return versions, false, err
+		}
+		if slices.Equal(toolVersions, []string{toolversions.Unmanaged}) {
+			toolVersions = []string{toolversions.System}
+		}
+		if found {
+			explain(Candidate{Source: toolVersionsFile, Versions: toolVersions, Accepted: true, Reason: fmt.Sprintf("sets %s", plugin.Name)})
+			return ToolVersions{Versions: toolVersions, Source: conf.DefaultToolVersionsFilename, Directory: directory}, true, nil
+		}
+		explain(Candidate{Source: toolVersionsFile, Reason: fmt.Sprintf("does not set %s", plugin.Name)})
+	}
+
+	legacyFiles, err := conf.LegacyVersionFile()
+	if err != nil {
+		return versions, false, err
+	}
+
+	if legacyFiles {
+		legacyFilenames, err := plugin.LegacyFilenames(ctx)
+		if err != nil {
+			return versions, false, err
+		}
+
+		for _, filename := range legacyFilenames {
+			legacyFile := path.Join(directory, filename)
+			if _, err := os.Stat(legacyFile); err != nil {
+				explain(Candidate{Source: legacyFile, Reason: "legacy file does not exist"})
+				continue
+			}
+
+			// Like findVersionsInLegacyFile only the first legacy file found
+			// is used, even when it sets no version
+			versions, found, err = findVersionsInLegacyFile(ctx, plugin, directory)
+			if err != nil {
+				return versions, false, err
+			}
+			if found {
+				explain(Candidate{Source: legacyFile, Versions: versions.Versions, Accepted: true, Reason: "legacy file sets a version"})
+				return versions, true, nil
+			}
+			explain(Candidate{Source: legacyFile, Reason: "legacy file sets no version"})
+			break
+		}
+	}
+
+	if _, ok := voltaKeys[plugin.Name]; ok {
+		if enabled, err := packageJSONEnabled(conf); err == nil && enabled {
+			packageFile := path.Join(directory, packageJSONFilename)
+			versions, found, err = findVersionsInPackageJSON(conf, plugin, directory)
+			if err != nil {
+				return versions, false, err
+			}
+			if found {
+				explain(Candidate{Source: packageFile, Versions: versions.Versions, Accepted: true, Reason: fmt.Sprintf("sets %s", plugin.Name)})
+				return versions, true, nil
+			}
+			explain(Candidate{Source: packageFile, Reason: fmt.Sprintf("missing or does not set %s", plugin.Name)})
+		}
+	}
+
+	return versions, false, nil
+}
+
+// explainMissing explains the resolution_missing hook, which is run when no
+// version is set
+func explainMissing(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, explain func(Candidate)) (versions ToolVersions, found bool, err error) {
+	versions, found, err = resolutionMissing(ctx, conf, plugin, directory)
+	source := resolutionMissingSource
+	switch {
+	case err != nil:
+		explain(Candidate{Source: source, Reason: err.Error()})
+	case found:
+		explain(Candidate{Source: source, Versions: versions.Versions, Accepted: true, Reason: "hook printed versions"})
+	default:
+		explain(Candidate{Source: source, Reason: "hook not set or printed no versions"})
+	}
+
+	return versions, found, err
+}
@@ -0,0 +1,68 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	parent := t.TempDir()
+	directory := filepath.Join(parent, "child")
+	assert.Nil(t, os.MkdirAll(directory, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte("other 1.0.0\n"), 0o666))
+	assert.Nil(t, os.WriteFile(filepath.Join(parent, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+
+	t.Run("explains each candidate up to the accepted version file", func(t *testing.T) {
+		candidates, versions, found, err := Explain(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.2.3"}, versions.Versions)
+
+		assert.Len(t, candidates, 4)
+		assert.Equal(t, Candidate{Source: VariableVersionName(testPluginName), Reason: "environment variable is not set"}, candidates[0])
+		assert.Equal(t, filepath.Join(conf.DataDir, "overrides.json"), candidates[1].Source)
+		assert.Equal(t, Candidate{Source: filepath.Join(directory, ".tool-versions"), Reason: "does not set " + testPluginName}, candidates[2])
+		assert.Equal(t, Candidate{Source: filepath.Join(parent, ".tool-versions"), Versions: []string{"1.2.3"}, Accepted: true, Reason: "sets " + testPluginName}, candidates[3])
+	})
+
+	t.Run("accepts environment variable first", func(t *testing.T) {
+		t.Setenv(VariableVersionName(testPluginName), "2.0.0")
+
+		candidates, versions, found, err := Explain(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+		assert.Len(t, candidates, 1)
+		assert.True(t, candidates[0].Accepted)
+	})
+
+	t.Run("stops at project root", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte("# asdf:root\nother 1.0.0\n"), 0o666))
+		t.Cleanup(func() { os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte("other 1.0.0\n"), 0o666) })
+
+		candidates, _, found, err := Explain(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+		assert.Contains(t, candidates, Candidate{Source: filepath.Join(directory, ".tool-versions"), Reason: "marked as project root, parent directories are not searched"})
+		assert.Equal(t, "resolution_missing hook", candidates[len(candidates)-1].Source)
+	})
+
+	t.Run("agrees with Version", func(t *testing.T) {
+		_, explained, _, err := Explain(conf, plugin, directory)
+		assert.Nil(t, err)
+		resolved, _, err := Version(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.Equal(t, resolved, explained)
+	})
+}
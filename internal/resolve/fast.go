@@ -0,0 +1,106 @@
+package resolve
+
+import (
+	"context"
+	"slices"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/overrides"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+// Cached resolves the tool like Version does, but only from the environment,
+// env files when enabled, overrides and the disk resolution cache, for shell
+// prompts that can't afford to walk the directory tree for version files or
+// run plugin callbacks and hooks on every render. fresh is false when the cache
+// can't answer, because the directory has no entry, the entry is out of date,
+// an alias set isn't in the alias cache or resolving would run the home
+// directory fallback or the resolution_missing hook.
+func Cached(conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found, fresh bool, err error) {
+	envVersions, envVariableName, found := findVersionsInEnv(plugin.Name)
+	if found {
+		return ToolVersions{Versions: envVersions, Source: envVariableName}, true, true, nil
+	}
+
+	conf, err = conf.ForDirectory(directory)
+	if err != nil {
+		return versions, false, false, err
+	}
+
+	versions, set, found, err := findVersionsInToolVersionsPath(context.Background(), conf, plugin, nil)
+	if set {
+		return versions, found, err == nil, err
+	}
+
+	directories, err := searchDirectories(conf, directory)
+	if err != nil {
+		return versions, false, false, err
+	}
+
+	versions, found, err = findVersionsInEnvFiles(conf, plugin.Name, directories)
+	if err != nil || found {
+		return versions, found, err == nil, err
+	}
+
+	for _, dir := range directories {
+		override, found, err := overrides.Find(conf.DataDir, dir, plugin.Name)
+		if err != nil {
+			return versions, false, false, err
+		}
+		if found {
+			return ToolVersions{Versions: override.Versions, Directory: conf.DataDir, Source: overrides.Filename}, true, true, nil
+		}
+	}
+
+	settings, err := cacheSettings(conf, plugin)
+	if err != nil {
+		return versions, false, false, err
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	entries := cachedEntries(conf, plugin, cacheDisk)
+	top := true
+	for _, dir := range directories {
+		entry, ok := entries[dir]
+		if !ok || !entry.sameSettings(settings) || !unchanged(entry.Files) {
+			return versions, false, false, nil
+		}
+
+		if entry.Found {
+			versions, fresh := expandCached(conf, plugin, entry.Versions)
+			return versions, true, fresh, nil
+		}
+		top = top && entry.Top
+	}
+
+	// The main worktree isn't cached, resolving would read its version files
+	worktreeFallback, err := conf.WorktreeFallback()
+	if err != nil {
+		return versions, false, false, err
+	}
+	if worktreeFallback && slices.ContainsFunc(directories, inLinkedWorktree) {
+		return versions, false, false, nil
+	}
+
+	versions, found, err = findDefaultVersions(conf, plugin)
+	if err != nil {
+		return versions, false, false, err
+	}
+	if found {
+		versions, fresh := expandCached(conf, plugin, versions)
+		return versions, true, fresh, nil
+	}
+
+	if top {
+		return versions, false, false, nil
+	}
+
+	missingHook, err := conf.GetHook(resolutionMissingHook)
+	if err != nil || missingHook != "" {
+		return versions, false, false, err
+	}
+
+	return versions, false, true, nil
+}
@@ -0,0 +1,111 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCached(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("resolution_cache = disk\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	directory := t.TempDir()
+	versionFile := filepath.Join(directory, ".tool-versions")
+	assert.Nil(t, os.WriteFile(versionFile, []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+	t.Run("returns stale when cache is cold", func(t *testing.T) {
+		_, found, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+		assert.False(t, fresh)
+	})
+
+	t.Run("returns version from env without cache", func(t *testing.T) {
+		t.Setenv(VariableVersionName(testPluginName), "2.0.0")
+
+		versions, found, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.True(t, fresh)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+	})
+
+	t.Run("returns versions stored by Version", func(t *testing.T) {
+		_, _, err := Version(conf, plugin, directory)
+		assert.Nil(t, err)
+		delete(memoryCache, cacheFile(conf, plugin))
+
+		versions, found, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.True(t, fresh)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		assert.Equal(t, directory, versions.Directory)
+	})
+
+	t.Run("returns stale when version file changed", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(versionFile, []byte(testPluginName+" 3.0.0 4.0.0\n"), 0o666))
+
+		_, found, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+		assert.False(t, fresh)
+	})
+
+	t.Run("returns stale when no version set up to the root directory", func(t *testing.T) {
+		empty := t.TempDir()
+		_, found, err := Version(conf, plugin, empty)
+		assert.Nil(t, err)
+		assert.False(t, found)
+
+		_, found, fresh, err := Cached(conf, plugin, empty)
+		assert.Nil(t, err)
+		assert.False(t, found)
+		assert.False(t, fresh)
+	})
+}
+
+func TestCachedAliases(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("resolution_cache = disk\nlist_all_cache_duration = 60\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := "#!/usr/bin/env bash\necho \"$1\" >> " + calls + "\necho 20.1.0\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", resolveAliasCallback), []byte(script), 0o777))
+
+	directory := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte(testPluginName+" lts\n"), 0o666))
+	_, _, err = Version(conf, plugin, directory)
+	assert.Nil(t, err)
+	delete(memoryCache, cacheFile(conf, plugin))
+
+	t.Run("returns alias resolved from alias cache", func(t *testing.T) {
+		versions, found, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.True(t, fresh)
+		assert.Equal(t, []string{"20.1.0"}, versions.Versions)
+	})
+
+	t.Run("returns stale without running callback when alias cache is cold", func(t *testing.T) {
+		assert.Nil(t, os.Remove(aliasCacheFile(conf, plugin)))
+		assert.Nil(t, os.Remove(calls))
+
+		_, _, fresh, err := Cached(conf, plugin, directory)
+		assert.Nil(t, err)
+		assert.False(t, fresh)
+		assert.NoFileExists(t, calls)
+	})
+}
@@ -0,0 +1,205 @@
+package resolve
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+// Names of the match strategies, matching the values of the [match] section of
+// the asdfrc
+const (
+	// StrategyExact uses the versions as they are set
+	StrategyExact = "exact"
+	// StrategyIgnorePatch uses the newest installed version with the same
+	// major and minor version as a version set
+	StrategyIgnorePatch = "ignore-patch"
+	// StrategyIgnoreMinor uses the newest installed version with the same
+	// major version as a version set
+	StrategyIgnoreMinor = "ignore-minor"
+	// StrategyRange treats the versions set as constraints, like `18` or
+	// `>=18.2`, and uses the newest installed version satisfying one of them
+	StrategyRange = "range"
+	// StrategyLatest uses the newest installed version whatever is set
+	StrategyLatest = "latest"
+)
+
+// MatchStrategy picks the installed version used for the versions set for a
+// tool. Strategies are selected per tool in the [match] section of the asdfrc,
+// or with the ASDF_IGNORE_* environment variables, see Strategy.
+type MatchStrategy interface {
+	// Match returns the installed version to use for the versions set, or an
+	// empty string to use the versions as they are set. Installed versions
+	// are ordered from newest to oldest.
+	Match(installed, versions []string) string
+}
+
+var strategies = map[string]MatchStrategy{
+	StrategyExact:       exactStrategy{},
+	StrategyIgnorePatch: releaseStrategy{segments: 2},
+	StrategyIgnoreMinor: releaseStrategy{segments: 1},
+	StrategyRange:       rangeStrategy{},
+	StrategyLatest:      latestStrategy{},
+}
+
+// ignoreVariables map the environment variables listing tools, or `*` for all
+// tools, to the strategy they select, in order of precedence. Tools are listed
+// as the ignore keys of the asdfrc list them, see config.ParseIgnoreList.
+var ignoreVariables = []struct {
+	name     string
+	strategy string
+}{
+	{name: "ASDF_IGNORE_VERSION", strategy: StrategyLatest},
+	{name: "ASDF_IGNORE_MINOR", strategy: StrategyIgnoreMinor},
+	{name: "ASDF_IGNORE_PATCH", strategy: StrategyIgnorePatch},
+}
+
+// Strategy returns the match strategy of a tool. ASDF_IGNORE_VERSION,
+// ASDF_IGNORE_MINOR and ASDF_IGNORE_PATCH listing the tool, or `*`, select the
+// latest, ignore-minor and ignore-patch strategies and take precedence over
+// the asdfrc. In the asdfrc a strategy set for the tool takes precedence over
+// one set for `*`. Tools without a strategy use exact. A strategy selected for
+// a tool listed with a range, like `nodejs=20.*`, is limited to it, see
+// limitedStrategy.
+func Strategy(conf config.Config, toolName string) MatchStrategy {
+	for _, variable := range ignoreVariables {
+		tools := config.ParseIgnoreList(os.Getenv(variable.name))
+		for _, name := range []string{toolName, "*"} {
+			if limit, ok := tools[name]; ok {
+				return limited(strategies[variable.strategy], limit)
+			}
+		}
+	}
+
+	configured, _ := conf.MatchStrategies()
+	ranges, _ := conf.MatchRanges()
+	for _, name := range []string{toolName, "*"} {
+		if strategy, ok := strategies[configured[name]]; ok {
+			return limited(strategy, ranges[name])
+		}
+	}
+
+	return strategies[StrategyExact]
+}
+
+// IsConstraint returns true if a version set for a tool selects among versions
+// rather than naming one, as constraints with an operator like `>=18` do, and
+// as every version does for tools using the range strategy. Exclusions,
+// wildcard, `latest`, system, path and ref versions aren't constraints.
+func IsConstraint(conf config.Config, toolName, version string) bool {
+	if toolversions.Parse(version).Type != "version" || version == "" || isLatest(version) ||
+		versionspec.IsExclusion(version) || versionspec.IsWildcard(version) {
+		return false
+	}
+
+	if strings.TrimLeft(version, "=<>") != version {
+		return true
+	}
+
+	_, ok := Strategy(conf, toolName).(rangeStrategy)
+	return ok
+}
+
+// AllowsPrereleases returns true if the pre-release versions of a tool, like
+// `1.2.0-rc1`, may be picked for `latest`, wildcard versions and the match
+// strategies, because allow_prereleases in the asdfrc lists the tool or `*`.
+func AllowsPrereleases(conf config.Config, toolName string) bool {
+	tools, _ := conf.AllowPrereleases()
+	return slices.Contains(tools, toolName) || slices.Contains(tools, "*")
+}
+
+// limited returns the strategy limited to the range, or the strategy itself
+// when the range is empty
+func limited(strategy MatchStrategy, limit string) MatchStrategy {
+	if limit == "" {
+		return strategy
+	}
+
+	return limitedStrategy{strategy: strategy, limit: limit}
+}
+
+// limitedStrategy only relaxes the matching of versions set within a range,
+// picking an installed version within it. Versions set outside of the range
+// are used as they are. Constraints set for the range strategy, like `>=18`,
+// count as within the range, only the installed versions are limited.
+type limitedStrategy struct {
+	strategy MatchStrategy
+	limit    string
+}
+
+func (s limitedStrategy) Match(installed, versions []string) string {
+	within := func(version string) bool { return versionspec.Satisfies(version, s.limit) }
+	wantedWithin := func(version string) bool {
+		return strings.TrimLeft(version, "=<>") != version || within(version)
+	}
+
+	wanted := slices.DeleteFunc(slices.Clone(versions), versionspec.IsExclusion)
+	if len(wanted) > 0 && !slices.ContainsFunc(wanted, wantedWithin) {
+		return ""
+	}
+
+	limitedInstalled := slices.DeleteFunc(slices.Clone(installed), func(version string) bool { return !within(version) })
+	limitedVersions := slices.DeleteFunc(slices.Clone(versions), func(version string) bool {
+		return !versionspec.IsExclusion(version) && !wantedWithin(version)
+	})
+	return s.strategy.Match(limitedInstalled, limitedVersions)
+}
+
+type exactStrategy struct{}
+
+func (exactStrategy) Match([]string, []string) string {
+	return ""
+}
+
+type releaseStrategy struct {
+	segments int
+}
+
+func (s releaseStrategy) Match(installed, versions []string) string {
+	return newestInstalled(installed, versions, func(installed, version string) bool {
+		return versionspec.SameRelease(installed, version, s.segments)
+	})
+}
+
+type rangeStrategy struct{}
+
+func (rangeStrategy) Match(installed, versions []string) string {
+	return newestInstalled(installed, versions, versionspec.Satisfies)
+}
+
+type latestStrategy struct{}
+
+func (latestStrategy) Match(installed, versions []string) string {
+	return newestInstalled(installed, slices.DeleteFunc(slices.Clone(versions), func(version string) bool {
+		return !versionspec.IsExclusion(version)
+	}), nil)
+}
+
+// newestInstalled returns the first installed version matching any of the
+// versions set that isn't ruled out by an exclusion among them, such as
+// `!=1.5.3`. When only exclusions are set every other version matches.
+func newestInstalled(installed, versions []string, matches func(installed, version string) bool) string {
+	wanted := slices.DeleteFunc(slices.Clone(versions), versionspec.IsExclusion)
+
+	for _, installedVersion := range installed {
+		if versionspec.Excluded(installedVersion, versions) {
+			continue
+		}
+
+		if len(wanted) == 0 {
+			return installedVersion
+		}
+
+		for _, version := range wanted {
+			if matches(installedVersion, version) {
+				return installedVersion
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,149 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategy(t *testing.T) {
+	conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[match]\nlua = range\n"), 0o666))
+
+	t.Run("returns strategy set for tool in asdfrc", func(t *testing.T) {
+		assert.Equal(t, rangeStrategy{}, Strategy(conf, "lua"))
+	})
+
+	t.Run("returns exact strategy for tool without strategy", func(t *testing.T) {
+		assert.Equal(t, exactStrategy{}, Strategy(conf, "ruby"))
+	})
+
+	t.Run("ASDF_IGNORE_PATCH takes precedence over asdfrc", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_PATCH", "ruby lua")
+		assert.Equal(t, releaseStrategy{segments: 2}, Strategy(conf, "lua"))
+	})
+
+	t.Run("ASDF_IGNORE_VERSION takes precedence over ASDF_IGNORE_MINOR", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_MINOR", "*")
+		t.Setenv("ASDF_IGNORE_VERSION", "lua")
+		assert.Equal(t, latestStrategy{}, Strategy(conf, "lua"))
+		assert.Equal(t, releaseStrategy{segments: 1}, Strategy(conf, "ruby"))
+	})
+
+	t.Run("returns strategy set for all tools when tool has none", func(t *testing.T) {
+		conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("ignore_patch = *\nignore_version = ruby\n[match]\nlua = range\n"), 0o666))
+
+		assert.Equal(t, rangeStrategy{}, Strategy(conf, "lua"))
+		assert.Equal(t, latestStrategy{}, Strategy(conf, "ruby"))
+		assert.Equal(t, releaseStrategy{segments: 2}, Strategy(conf, "python"))
+	})
+
+	t.Run("accepts tools separated by commas in ASDF_IGNORE_PATCH", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_PATCH", "ruby,lua")
+		assert.Equal(t, releaseStrategy{segments: 2}, Strategy(conf, "lua"))
+	})
+
+	t.Run("limits strategy to range set in ASDF_IGNORE_MINOR", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_MINOR", "ruby, lua = 5.*")
+		assert.Equal(t, limitedStrategy{strategy: releaseStrategy{segments: 1}, limit: "5.*"}, Strategy(conf, "lua"))
+		assert.Equal(t, releaseStrategy{segments: 1}, Strategy(conf, "ruby"))
+	})
+
+	t.Run("limits strategy to range set in asdfrc", func(t *testing.T) {
+		conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("ignore_patch = *=1.*\nignore_version = ruby=3.*\n[match]\nlua = range\n"), 0o666))
+
+		assert.Equal(t, rangeStrategy{}, Strategy(conf, "lua"))
+		assert.Equal(t, limitedStrategy{strategy: latestStrategy{}, limit: "3.*"}, Strategy(conf, "ruby"))
+		assert.Equal(t, limitedStrategy{strategy: releaseStrategy{segments: 2}, limit: "1.*"}, Strategy(conf, "python"))
+	})
+}
+
+func TestIsConstraint(t *testing.T) {
+	conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[match]\nlua = range\n"), 0o666))
+
+	for version, expected := range map[string]bool{
+		">=18":     true,
+		"<2":       true,
+		"=1.2":     true,
+		"18":       false,
+		"!=1.5.3":  false,
+		"18.x":     false,
+		"latest":   false,
+		"latest:1": false,
+		"system":   false,
+		"ref:v1":   false,
+	} {
+		assert.Equal(t, expected, IsConstraint(conf, "ruby", version), version)
+	}
+
+	assert.True(t, IsConstraint(conf, "lua", "5.4"))
+	assert.False(t, IsConstraint(conf, "lua", "path:/opt/lua"))
+}
+
+func TestLimitedStrategy(t *testing.T) {
+	installed := []string{"22.1.0", "20.11.1", "20.9.0", "18.19.0"}
+
+	tests := []struct {
+		strategy string
+		limit    string
+		versions []string
+		expected string
+	}{
+		{strategy: StrategyLatest, limit: "20.*", versions: []string{"20.9.0"}, expected: "20.11.1"},
+		{strategy: StrategyLatest, limit: "20.*", versions: []string{"18.0.0"}, expected: ""},
+		{strategy: StrategyLatest, limit: "20", versions: []string{"20.0.0", "!=20.11.1"}, expected: "20.9.0"},
+		{strategy: StrategyLatest, limit: "20.*", versions: []string{"!=20.11.1"}, expected: "20.9.0"},
+		{strategy: StrategyIgnoreMinor, limit: ">=20", versions: []string{"20.0.0"}, expected: "20.11.1"},
+		{strategy: StrategyIgnoreMinor, limit: "<20", versions: []string{"20.0.0"}, expected: ""},
+		{strategy: StrategyIgnoreMinor, limit: "<=20.9", versions: []string{"20.0.0"}, expected: "20.9.0"},
+		{strategy: StrategyRange, limit: "20.*", versions: []string{">=18"}, expected: "20.11.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy+" "+tt.limit+" "+strings.Join(tt.versions, " "), func(t *testing.T) {
+			strategy := limitedStrategy{strategy: strategies[tt.strategy], limit: tt.limit}
+			assert.Equal(t, tt.expected, strategy.Match(installed, tt.versions))
+		})
+	}
+}
+
+func TestMatchStrategies(t *testing.T) {
+	installed := []string{"2.1.0", "1.10.0", "1.9.2", "1.9.0"}
+
+	tests := []struct {
+		strategy string
+		versions []string
+		expected string
+	}{
+		{strategy: StrategyExact, versions: []string{"1.9.0"}, expected: ""},
+		{strategy: StrategyIgnorePatch, versions: []string{"1.9.0"}, expected: "1.9.2"},
+		{strategy: StrategyIgnorePatch, versions: []string{"1.1.0"}, expected: ""},
+		{strategy: StrategyIgnoreMinor, versions: []string{"1.0.0"}, expected: "1.10.0"},
+		{strategy: StrategyRange, versions: []string{"1.9"}, expected: "1.9.2"},
+		{strategy: StrategyRange, versions: []string{"<2", ">=3"}, expected: "1.10.0"},
+		{strategy: StrategyRange, versions: []string{"3"}, expected: ""},
+		{strategy: StrategyLatest, versions: []string{"1.0.0"}, expected: "2.1.0"},
+		{strategy: StrategyRange, versions: []string{">=1.9", "!=2.1.0", "!=1.10"}, expected: "1.9.2"},
+		{strategy: StrategyRange, versions: []string{"!=2"}, expected: "1.10.0"},
+		{strategy: StrategyIgnorePatch, versions: []string{"1.9.0", "!=1.9.2"}, expected: "1.9.0"},
+		{strategy: StrategyLatest, versions: []string{"1.0.0", "!=2.1.0"}, expected: "1.10.0"},
+		{strategy: StrategyRange, versions: []string{">=2", "!=2.1.0"}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy+" "+strings.Join(tt.versions, " "), func(t *testing.T) {
+			assert.Equal(t, tt.expected, strategies[tt.strategy].Match(installed, tt.versions))
+		})
+	}
+
+	t.Run("latest returns empty string without installed versions", func(t *testing.T) {
+		assert.Equal(t, "", strategies[StrategyLatest].Match(nil, []string{"1.0.0"}))
+	})
+}
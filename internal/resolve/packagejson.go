@@ -0,0 +1,82 @@
+package resolve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+const packageJSONFilename = "package.json"
+
+// voltaKeys maps the names of the tools package.json files can set versions
+// of to their key in the volta field
+var voltaKeys = map[string]string{
+	"nodejs": "node",
+	"node":   "node",
+	"npm":    "npm",
+	"yarn":   "yarn",
+	"pnpm":   "pnpm",
+}
+
+type packageJSON struct {
+	PackageManager string         `json:"packageManager"`
+	Volta          map[string]any `json:"volta"`
+}
+
+// packageJSONEnabled returns true if the experimental.package_json flag is on
+func packageJSONEnabled(conf config.Config) (bool, error) {
+	value, err := conf.Flag(config.PackageJSONFlag)
+	return value == "on", err
+}
+
+// findVersionsInPackageJSON looks up the version of node, npm, yarn or pnpm set
+// by the package.json file in the directory, when the experimental.package_json
+// flag is on. For package managers the packageManager field used by Corepack,
+// e.g. `pnpm@9.1.0+sha512.abc`, takes precedence over the volta field. A
+// package.json without either field doesn't set a version, so in a workspace
+// the search continues up to the package.json of the workspace root.
+func findVersionsInPackageJSON(conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+	key, ok := voltaKeys[plugin.Name]
+	if !ok {
+		return versions, false, nil
+	}
+
+	if enabled, err := packageJSONEnabled(conf); err != nil || !enabled {
+		return versions, false, err
+	}
+
+	file := path.Join(directory, packageJSONFilename)
+	contents, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return versions, false, nil
+	}
+	if err != nil {
+		return versions, false, err
+	}
+
+	var manifest packageJSON
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return versions, false, fmt.Errorf("unable to parse %s: %w", file, err)
+	}
+
+	version := ""
+	if name, value, ok := strings.Cut(manifest.PackageManager, "@"); ok && name == key {
+		version, _, _ = strings.Cut(value, "+")
+	}
+	if version == "" {
+		version, _ = manifest.Volta[key].(string)
+	}
+
+	if version = strings.TrimSpace(version); version == "" {
+		return versions, false, nil
+	}
+
+	return ToolVersions{Versions: []string{version}, Source: packageJSONFilename, Directory: directory}, true, nil
+}
@@ -0,0 +1,90 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindVersionsInPackageJSON(t *testing.T) {
+	conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("experimental.package_json = on\n"), 0o666))
+
+	writePackageJSON := func(t *testing.T, contents string) string {
+		t.Helper()
+		directory := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, packageJSONFilename), []byte(contents), 0o666))
+		return directory
+	}
+
+	t.Run("returns node version from volta field for nodejs", func(t *testing.T) {
+		directory := writePackageJSON(t, `{"volta": {"node": "20.11.1", "npm": "10.2.4"}}`)
+		versions, found, err := findVersionsInPackageJSON(conf, plugins.New(conf, "nodejs"), directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"20.11.1"}, versions.Versions)
+		assert.Equal(t, packageJSONFilename, versions.Source)
+	})
+
+	t.Run("prefers packageManager field and strips its hash", func(t *testing.T) {
+		directory := writePackageJSON(t, `{"packageManager": "pnpm@9.1.0+sha512.abc", "volta": {"pnpm": "8.0.0"}}`)
+		versions, found, err := findVersionsInPackageJSON(conf, plugins.New(conf, "pnpm"), directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"9.1.0"}, versions.Versions)
+	})
+
+	t.Run("ignores packageManager field of another package manager", func(t *testing.T) {
+		directory := writePackageJSON(t, `{"packageManager": "yarn@4.1.0"}`)
+		_, found, err := findVersionsInPackageJSON(conf, plugins.New(conf, "pnpm"), directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("ignores tools package.json files can't set", func(t *testing.T) {
+		directory := writePackageJSON(t, `{"volta": {"python": "3.12.1"}}`)
+		_, found, err := findVersionsInPackageJSON(conf, plugins.New(conf, "python"), directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns error when package.json is invalid", func(t *testing.T) {
+		directory := writePackageJSON(t, `{"volta": `)
+		_, found, err := findVersionsInPackageJSON(conf, plugins.New(conf, "nodejs"), directory)
+		assert.ErrorContains(t, err, "unable to parse")
+		assert.False(t, found)
+	})
+
+	t.Run("ignores package.json when flag is off", func(t *testing.T) {
+		conf := config.Config{ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		directory := writePackageJSON(t, `{"volta": {"node": "20.11.1"}}`)
+		_, found, err := findVersionsInPackageJSON(conf, plugins.New(conf, "nodejs"), directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestVersionFromWorkspacePackageJSON(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("experimental.package_json = on\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, "nodejs")
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, "nodejs")
+
+	root := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(root, packageJSONFilename), []byte(`{"workspaces": ["packages/*"], "volta": {"node": "20.11.1"}}`), 0o666))
+	member := filepath.Join(root, "packages", "app")
+	assert.Nil(t, os.MkdirAll(member, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(member, packageJSONFilename), []byte(`{"name": "app"}`), 0o666))
+
+	versions, found, err := Version(conf, plugin, member)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"20.11.1"}, versions.Versions)
+	assert.Equal(t, root, versions.Directory)
+}
@@ -0,0 +1,89 @@
+package resolve
+
+import (
+	"os"
+	"sync"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// fileReads holds the contents of the .tool-versions files read while
+// resolving several tools in the same directory, see All, so each file is read
+// once rather than once per tool and again to check for the root marker. A nil
+// *fileReads reads the files every time.
+type fileReads struct {
+	mutex sync.Mutex
+	files map[string]fileRead
+}
+
+type fileRead struct {
+	contents string
+	exists   bool
+	err      error
+}
+
+func newFileReads() *fileReads {
+	return &fileReads{files: map[string]fileRead{}}
+}
+
+// toolVersions returns the versions set for the tool in the .tool-versions
+// file, if it exists, under the first of its names set in the file, see
+// toolNames
+func (r *fileReads) toolVersions(filepath string, toolNames []string) (versions []string, found bool, err error) {
+	return fileToolVersions(r.read(filepath), toolNames)
+}
+
+// fileToolVersions returns the versions set for the tool in the read
+// .tool-versions file, see toolVersions
+func fileToolVersions(file fileRead, toolNames []string) (versions []string, found bool, err error) {
+	if !file.exists || file.err != nil {
+		return versions, false, file.err
+	}
+
+	for _, toolName := range toolNames {
+		if versions, found = toolversions.FindToolVersionsInContent(file.contents, toolName); found {
+			return versions, true, nil
+		}
+	}
+	return versions, false, nil
+}
+
+// isRoot returns true if the .tool-versions file exists and contains the
+// root marker
+func (r *fileReads) isRoot(filepath string) (bool, error) {
+	file := r.read(filepath)
+	if !file.exists || file.err != nil {
+		return false, file.err
+	}
+
+	return toolversions.IsRootContent(file.contents), nil
+}
+
+func (r *fileReads) read(filepath string) fileRead {
+	if r == nil {
+		return readFile(filepath)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	file, ok := r.files[filepath]
+	if !ok {
+		file = readFile(filepath)
+		r.files[filepath] = file
+	}
+	return file
+}
+
+// readFile reads the file, which doesn't exist when it can't be stat'ed
+func readFile(filepath string) fileRead {
+	contents, err := os.ReadFile(filepath)
+	if err != nil {
+		if _, statErr := os.Stat(filepath); statErr != nil {
+			return fileRead{}
+		}
+		return fileRead{exists: true, err: err}
+	}
+
+	return fileRead{contents: string(contents), exists: true}
+}
@@ -4,16 +4,28 @@
 package resolve
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"slices"
 	"strings"
 
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/hook"
 	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/overrides"
 	"github.com/asdf-vm/asdf/internal/plugins"
 	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+const (
+	resolutionMissingHook   = "resolution_missing"
+	resolutionMissingSource = resolutionMissingHook + " hook"
+	preResolveHook          = "pre_asdf_resolve"
+	postResolveHook         = "post_asdf_resolve"
 )
 
 // ToolVersions represents a tool along with versions specified for it
@@ -21,99 +33,457 @@ type ToolVersions struct {
 	Versions  []string
 	Directory string
 	Source    string
+	// Requested holds the versions as set, before aliases were resolved and
+	// `latest` and `latest:<prefix>` versions were replaced with installed
+	// versions in Versions. It is nil when no version was replaced.
+	Requested []string
 }
 
 // Version takes a plugin and a directory and resolves the tool to one or more
 // versions.
 func Version(conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+	return VersionContext(context.Background(), conf, plugin, directory)
+}
+
+// VersionContext resolves the tool like Version, killing the plugin callbacks
+// and hooks run along the way, such as parse-legacy-file or the
+// resolution_missing hook, when the context is done. The error of the context
+// is returned in that case.
+func VersionContext(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+	conf, err = conf.ForDirectory(directory)
+	if err != nil {
+		return versions, false, err
+	}
+
+	versions, found, err = resolveWithHooks(ctx, conf, plugin, directory, nil)
+
+	// Failing callbacks like resolve-alias are skipped over, what was resolved
+	// without them isn't the result when they were killed
+	if ctxErr := ctx.Err(); err == nil && ctxErr != nil {
+		return versions, false, ctxErr
+	}
+
+	return versions, found, err
+}
+
+func findVersions(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, reads *fileReads) (versions ToolVersions, found bool, err error) {
 	version, envVariableName, found := findVersionsInEnv(plugin.Name)
 	if found {
 		return ToolVersions{Versions: version, Source: envVariableName}, true, nil
 	}
 
-	for !found {
-		versions, found, err = findVersionsInDir(conf, plugin, directory)
+	versions, set, found, err := findVersionsInToolVersionsPath(ctx, conf, plugin, reads)
+	if set {
+		return versions, found, err
+	}
+
+	directories, err := searchDirectories(conf, directory)
+	if err != nil {
+		return versions, false, err
+	}
+
+	versions, found, err = findVersionsInEnvFiles(conf, plugin.Name, directories)
+	if err != nil || found {
+		return versions, found, err
+	}
+
+	for _, dir := range directories {
+		override, found, err := overrides.Find(conf.DataDir, dir, plugin.Name)
+		if err != nil || found {
+			return ToolVersions{Versions: override.Versions, Directory: conf.DataDir, Source: overrides.Filename}, found, err
+		}
+	}
+
+	// The home directory fallback only applies when every search reached `/`
+	top := true
+	for _, dir := range directories {
+		dirVersions, dirFound, dirTop, err := findVersionsInTree(ctx, conf, plugin, dir, reads)
 		if err != nil {
-			return versions, false, err
+			return dirVersions, false, err
+		}
+		if dirFound {
+			return dirVersions, true, nil
+		}
+		top = top && dirTop
+	}
+
+	versions, found, err = findVersionsInWorktree(ctx, conf, plugin, directories, reads)
+	if err != nil || found {
+		return versions, found, err
+	}
+
+	versions, found, err = findDefaultVersions(conf, plugin)
+	if err != nil || found {
+		return versions, found, err
+	}
+
+	// If no version was found up to `/` try the current users home directory.
+	// I'd like to eventually remove this feature.
+	if top {
+		if homeDir, osErr := os.UserHomeDir(); osErr == nil {
+			versions, found, err = findVersionsInHome(ctx, conf, plugin, homeDir, reads)
+		}
+	}
+
+	if !found && err == nil {
+		return resolutionMissing(ctx, conf, plugin, directory)
+	}
+
+	return versions, found, err
+}
+
+// walkTree looks up the versions set in the directory and its parents, stopping
+// at a root .tool-versions file. top is true when versions weren't found and
+// the search reached `/`. The files and directories the result depends on are
+// passed to record, if given, so the result can be cached.
+func walkTree(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, reads *fileReads, record func(directory string) error) (versions ToolVersions, found, top bool, err error) {
+	start := directory
+	for {
+		if record != nil {
+			if err := record(directory); err != nil {
+				return versions, false, false, err
+			}
+		}
+
+		// The workspace manifest of a monorepo root sets the versions of the
+		// subprojects below it, before the version files of the root
+		versions, found, err = findVersionsInWorkspace(conf, plugin, directory, start, reads)
+		if err != nil || found {
+			return versions, found, false, err
+		}
+
+		versions, found, err = findVersionsInDir(ctx, conf, plugin, directory, reads)
+		if err != nil || found {
+			return versions, found, false, err
+		}
+
+		// A root .tool-versions file isolates the project from versions set
+		// in parent directories, including the home directory.
+		root, err := isRootDir(conf, directory, reads)
+		if err != nil || root {
+			return versions, false, false, err
+		}
+
+		// So does a boundary marker, such as the .git directory of a
+		// repository
+		marker, err := boundaryMarker(conf, directory)
+		if err != nil || marker != "" {
+			return versions, false, false, err
 		}
 
 		nextDir := path.Dir(directory)
 		// If current dir and next dir are the same it means we've reached `/` and
 		// have no more parent directories to search.
 		if nextDir == directory {
-			// If no version found, try current users home directory. I'd like to
-			// eventually remove this feature.
-			homeDir, osErr := os.UserHomeDir()
-			if osErr != nil {
-				break
-			}
-
-			versions, found, err = findVersionsInDir(conf, plugin, homeDir)
-			break
+			return versions, false, true, nil
 		}
 		directory = nextDir
 	}
+}
+
+// InDirectory resolves the tool to the versions set by the version files in
+// the directory alone. Unlike Version it doesn't look at the environment,
+// overrides, parent directories or the resolution_missing hook.
+func InDirectory(conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+	conf, err = conf.ForDirectory(directory)
+	if err != nil {
+		return versions, false, err
+	}
+
+	ctx := context.Background()
+	versions, found, err = findVersionsInDir(ctx, conf, plugin, directory, nil)
+	if found && err == nil {
+		versions = expand(ctx, conf, plugin, versions)
+	}
 
 	return versions, found, err
 }
 
-// FindBestMatchingVersion returns the best matching version for a plugin based on
-// the installed versions and the versions specified in the plugin's configuration.
-// It considers the environment variables ASDF_IGNORE_PATCH, ASDF_IGNORE_MINOR, ASDF_IGNORE_VERSION
-// These variables allow users to ignore .tool-versions constraints.
-// The best matching version is determined by the following rules:
-// If ASDF_IGNORE_VERSION is set, returns always the latest installed version of the plugin.
-// If ASDF_IGNORE_PATCH is set, returns the latest installed version that matches the major.minor version.
-// If ASDF_IGNORE_MINOR is set, returns the latest installed version that matches the major version.
-// You can set these environment variables to "*" to use the ignore rule for all plugins.
-// Example:
-// ASDF_IGNORE_PATCH=* # ignores all patch versions
-// ASDF_IGNORE_MINOR=nodejs golang # ignores all minor/patch versions for nodejs and golang
+// expandInstalled replaces `latest` and `latest:<prefix>` versions with the
+// newest installed stable version starting with the prefix, or with a digit
+// when no prefix is given, and wildcard versions like `18.x` or `3.12.*` with
+// the newest installed stable version matching them that no exclusion among
+// the versions set rules out. Pre-release versions are matched too for tools
+// listed in allow_prereleases. Versions without an installed match are left
+// as they are, so `asdf install` can install the newest matching version
+// available.
+func expandInstalled(conf config.Config, plugin plugins.Plugin, versions ToolVersions) ToolVersions {
+	if !slices.ContainsFunc(versions.Versions, func(version string) bool { return isLatest(version) || versionspec.IsWildcard(version) }) {
+		return versions
+	}
+
+	installed, err := installs.Installed(conf, plugin)
+	if err != nil {
+		return versions
+	}
+	versionspec.Sort(installed)
+	prereleases := AllowsPrereleases(conf, plugin.Name)
+
+	expanded := make([]string, 0, len(versions.Versions))
+	for _, version := range versions.Versions {
+		switch {
+		case isLatest(version):
+			if match, ok := newestMatching(installed, toolversions.ParseFromCliArg(version).Value, prereleases); ok {
+				version = match
+			}
+		case versionspec.IsWildcard(version):
+			if match, ok := newestWildcardMatch(installed, version, versions.Versions, prereleases); ok {
+				version = match
+			}
+		}
+		expanded = append(expanded, version)
+	}
+
+	if !slices.Equal(expanded, versions.Versions) {
+		if versions.Requested == nil {
+			versions.Requested = versions.Versions
+		}
+		versions.Versions = expanded
+	}
+
+	return versions
+}
+
+func isLatest(version string) bool {
+	return toolversions.ParseFromCliArg(version).Type == "latest"
+}
+
+// newestMatching returns the last stable version of the sorted versions that
+// starts with the prefix, or the last version when prereleases is true
+func newestMatching(sorted []string, prefix string, prereleases bool) (string, bool) {
+	for i := len(sorted) - 1; i >= 0; i-- {
+		parsed := versionspec.Parse(sorted[i])
+		if parsed.Prerelease() && !prereleases {
+			continue
+		}
+
+		matches := strings.HasPrefix(sorted[i], prefix)
+		if prefix == "" {
+			matches = parsed.Prefix == "" && len(parsed.Release) > 0
+		}
+
+		if matches {
+			return sorted[i], true
+		}
+	}
+
+	return "", false
+}
+
+// newestWildcardMatch returns the last stable version of the sorted versions,
+// or the last version when prereleases is true, that matches the wildcard and
+// isn't excluded by the versions set
+func newestWildcardMatch(sorted []string, wildcard string, versions []string, prereleases bool) (string, bool) {
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if versionspec.MatchesWildcard(sorted[i], wildcard) && (prereleases || !versionspec.Parse(sorted[i]).Prerelease()) && !versionspec.Excluded(sorted[i], versions) {
+			return sorted[i], true
+		}
+	}
+
+	return "", false
+}
+
+// HomeFallbackError is returned when a version is only set in the home
+// directory and the deprecate.home_fallback flag is set to error
+type HomeFallbackError struct {
+	toolName string
+	homeDir  string
+}
+
+func (e HomeFallbackError) Error() string {
+	return fmt.Sprintf("%s version is only set in %s, outside the current directory tree, and %s is set to error", e.toolName, e.homeDir, config.HomeFallbackFlag)
+}
+
+// findVersionsInHome looks up versions set in the home directory for
+// directories outside it. This fallback is deprecated and controlled by the
+// deprecate.home_fallback flag.
+func findVersionsInHome(ctx context.Context, conf config.Config, plugin plugins.Plugin, homeDir string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	versions, found, err = findVersionsInDir(ctx, conf, plugin, homeDir, reads)
+	if !found || err != nil {
+		return versions, found, err
+	}
+
+	fallback, err := conf.Flag(config.HomeFallbackFlag)
+	if err != nil {
+		return versions, false, err
+	}
+
+	switch fallback {
+	case "warn":
+		fmt.Fprintf(os.Stderr, "warning: %s version resolved from %s outside the current directory tree, this fallback is deprecated, set a version in [default_versions] instead (%s)\n", plugin.Name, homeDir, config.HomeFallbackFlag)
+	case "error":
+		return versions, false, HomeFallbackError{toolName: plugin.Name, homeDir: homeDir}
+	}
+
+	return versions, true, nil
+}
+
+// resolveWithHooks resolves the tool, see findVersions, expanding the versions
+// found. The pre_asdf_resolve hook runs before and the post_asdf_resolve hook
+// after, unless resolving fails, so organizations can check versions against
+// their policies or log them. Either hook failing fails the resolution, unless
+// the hook_failure_policy setting is `warn` or `ignore`.
+func resolveWithHooks(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	if err := resolveHook(ctx, conf, preResolveHook, plugin, directory, versions); err != nil {
+		return versions, false, err
+	}
+
+	versions, found, err = findVersions(ctx, conf, plugin, directory, reads)
+	if err != nil {
+		return versions, found, err
+	}
+	if found {
+		versions = expand(ctx, conf, plugin, versions)
+	}
+
+	if err := resolveHook(ctx, conf, postResolveHook, plugin, directory, versions); err != nil {
+		return versions, false, err
+	}
+
+	return versions, found, nil
+}
+
+// resolveHook runs a resolve hook, passing it the tool name, the directory and
+// the versions resolved, if any, and their source in ASDF_RESOLVE_SOURCE, see
+// sourcePath. What the hook prints goes to STDERR, so it can't end up in the
+// output of the command resolving the tool.
+func resolveHook(ctx context.Context, conf config.Config, hookName string, plugin plugins.Plugin, directory string, versions ToolVersions) error {
+	env := map[string]string{}
+	if versions.Source != "" {
+		env["ASDF_RESOLVE_SOURCE"] = sourcePath(versions)
+	}
+
+	err := hook.RunWithEnvContext(ctx, conf, hookName, append([]string{plugin.Name, directory}, versions.Versions...), env, os.Stderr, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to run %s hook: %w", hookName, err)
+	}
+
+	return nil
+}
+
+// sourcePath returns the path of the file the versions were resolved from, or
+// the name of their source when they don't come from a file, either the
+// environment variable or the resolution_missing hook
+func sourcePath(versions ToolVersions) string {
+	if versions.Directory == "" || versions.Source == resolutionMissingSource {
+		return versions.Source
+	}
+
+	return filepath.Join(versions.Directory, versions.Source)
+}
+
+// resolutionMissing runs the resolution_missing hook, if set, with the tool name
+// and directory no version could be resolved in. Versions printed by the hook
+// are used as the resolved versions, allowing fallbacks that can't be expressed
+// in version files, such as querying an internal service.
+func resolutionMissing(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+	var stdOut strings.Builder
+	err = hook.CaptureContext(ctx, conf, resolutionMissingHook, []string{plugin.Name, directory}, &stdOut)
+	if err != nil {
+		return versions, false, fmt.Errorf("failed to run %s hook: %w", resolutionMissingHook, err)
+	}
+
+	resolved := strings.Fields(stdOut.String())
+	if len(resolved) == 0 {
+		return versions, false, nil
+	}
+
+	return ToolVersions{Versions: resolved, Directory: directory, Source: resolutionMissingSource}, true, nil
+}
+
+// FindBestMatchingVersion returns the installed version to use for the
+// versions set for a tool, as picked by the match strategy of the tool, see
+// Strategy. An empty string is returned when the versions set should be used
+// as they are, either because the strategy is exact or because no installed
+// version matches. Installed pre-release versions are only picked when they
+// are set, or for tools listed in allow_prereleases, see AllowsPrereleases.
 func FindBestMatchingVersion(conf config.Config, plugin plugins.Plugin, versions []string) string {
 	availableVersions, err := installs.Installed(conf, plugin)
 	if err != nil {
 		return ""
 	}
-	ignorePatches := strings.Split(os.Getenv("ASDF_IGNORE_PATCH"), " ")
-	ignoreMinors := strings.Split(os.Getenv("ASDF_IGNORE_MINOR"), " ")
-	ignoreVersions := strings.Split(os.Getenv("ASDF_IGNORE_VERSION"), " ")
-	slices.SortFunc(availableVersions, func(a, b string) int { return -strings.Compare(a, b) })
-	if slices.Contains(ignoreVersions, plugin.Name) || slices.Contains(ignoreVersions, "*") {
-		return availableVersions[0]
+
+	if !AllowsPrereleases(conf, plugin.Name) {
+		availableVersions = slices.DeleteFunc(availableVersions, func(version string) bool {
+			return versionspec.Parse(version).Prerelease() && !slices.Contains(versions, version)
+		})
 	}
-	if len(ignorePatches) == 0 && len(ignoreMinors) == 0 {
+	if len(availableVersions) == 0 {
 		return ""
 	}
-	slices.SortFunc(versions, func(a, b string) int { return -strings.Compare(a, b) })
-	for _, version := range availableVersions {
-		if slices.Contains(ignorePatches, plugin.Name) || slices.Contains(ignorePatches, "*") {
-			majorMinor := strings.Join(strings.Split(version, ".")[:2], ".")
-			for _, v := range versions {
-				if strings.HasPrefix(v, majorMinor) {
-					return version
-				}
-			}
+
+	slices.SortFunc(availableVersions, func(a, b string) int { return -versionspec.CompareStrings(a, b) })
+	return Strategy(conf, plugin.Name).Match(availableVersions, versions)
+}
+
+// isRootDir returns true if the directory contains a .tool-versions file marked
+// as a project root
+func isRootDir(conf config.Config, directory string, reads *fileReads) (bool, error) {
+	return reads.isRoot(path.Join(directory, conf.DefaultToolVersionsFilename))
+}
+
+// boundaryMarker returns the first of the boundary_markers set in the asdfrc
+// found in the directory, or an empty string when there is none. Markers can
+// be files or directories, as .git is a file in worktrees and submodules.
+func boundaryMarker(conf config.Config, directory string) (string, error) {
+	markers, err := conf.BoundaryMarkers()
+	if err != nil {
+		return "", err
+	}
+
+	for _, marker := range markers {
+		if _, err := os.Lstat(path.Join(directory, marker)); err == nil {
+			return marker, nil
 		}
-		if slices.Contains(ignoreMinors, plugin.Name) || slices.Contains(ignoreMinors, "*") {
-			major := strings.Split(version, ".")[0]
-			for _, v := range versions {
-				if strings.HasPrefix(v, major) {
-					return version
-				}
-			}
+	}
+
+	return "", nil
+}
+
+// toolNames returns the names the tool can be set under in .tool-versions
+// files, the name of its plugin followed by those of the [tool_aliases] of the
+// asdfrc referring to it, so files written for other version managers, which
+// may call the tool `node` rather than `nodejs`, set it too
+func toolNames(conf config.Config, plugin plugins.Plugin) ([]string, error) {
+	aliases, err := conf.ToolAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{plugin.Name}
+	for alias, name := range aliases {
+		if name == plugin.Name {
+			names = append(names, alias)
 		}
 	}
-	return ""
+	slices.Sort(names[1:])
+
+	return names, nil
 }
 
-func findVersionsInDir(conf config.Config, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+func findVersionsInDir(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	files, err := versionFiles(conf, plugin)
+	if err != nil {
+		return versions, false, err
+	}
+
+	if files != nil {
+		return findVersionsInVersionFiles(ctx, conf, plugin, directory, files, reads)
+	}
+
+	names, err := toolNames(conf, plugin)
+	if err != nil {
+		return versions, false, err
+	}
+
 	filepath := path.Join(directory, conf.DefaultToolVersionsFilename)
 
-	if _, err = os.Stat(filepath); err == nil {
-		versions, found, err := toolversions.FindToolVersions(filepath, plugin.Name)
-		if found || err != nil {
-			return ToolVersions{Versions: versions, Source: conf.DefaultToolVersionsFilename, Directory: directory}, found, err
-		}
+	toolVersions, found, err := reads.toolVersions(filepath, names)
+	if slices.Equal(toolVersions, []string{toolversions.Unmanaged}) {
+		toolVersions = []string{toolversions.System}
+	}
+	if found || err != nil {
+		return ToolVersions{Versions: toolVersions, Source: conf.DefaultToolVersionsFilename, Directory: directory}, found, err
 	}
 
 	legacyFiles, err := conf.LegacyVersionFile()
@@ -122,21 +492,21 @@ func findVersionsInDir(conf config.Config, plugin plugins.Plugin, directory stri
 	}
 
 	if legacyFiles {
-		versions, found, err := findVersionsInLegacyFile(plugin, directory)
+		versions, found, err := findVersionsInLegacyFile(ctx, plugin, directory)
 
 		if found || err != nil {
 			return versions, found, err
 		}
 	}
 
-	return versions, found, nil
+	return findVersionsInPackageJSON(conf, plugin, directory)
 }
 
 // findVersionsInEnv returns the version from the environment if present
 func findVersionsInEnv(pluginName string) ([]string, string, bool) {
-	envVariableName := variableVersionName(pluginName)
+	envVariableName := VariableVersionName(pluginName)
 	versionString := os.Getenv(envVariableName)
-	if versionString == "" {
+	if strings.TrimSpace(versionString) == "" {
 		return []string{}, envVariableName, false
 	}
 	return parseVersion(versionString), envVariableName, true
@@ -146,10 +516,10 @@ func findVersionsInEnv(pluginName string) ([]string, string, bool) {
 // the specified plugin has a list-legacy-filenames callback script. If the
 // callback script exists asdf will look for files with the given name in the
 // current and extract the version from them.
-func findVersionsInLegacyFile(plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
+func findVersionsInLegacyFile(ctx context.Context, plugin plugins.Plugin, directory string) (versions ToolVersions, found bool, err error) {
 	var legacyFileNames []string
 
-	legacyFileNames, err = plugin.LegacyFilenames()
+	legacyFileNames, err = plugin.LegacyFilenames(ctx)
 	if err != nil {
 		return versions, false, err
 	}
@@ -157,7 +527,11 @@ func findVersionsInLegacyFile(plugin plugins.Plugin, directory string) (versions
 	for _, filename := range legacyFileNames {
 		filepath := path.Join(directory, filename)
 		if _, err := os.Stat(filepath); err == nil {
-			versionsSlice, err := plugin.ParseLegacyVersionFile(filepath)
+			versionsSlice, err := plugin.ParseLegacyVersionFile(ctx, filepath)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				// Returning not found would be cached, see findVersionsInTree
+				return versions, false, ctxErr
+			}
 
 			if len(versionsSlice) == 0 || (len(versionsSlice) == 1 && versionsSlice[0] == "") {
 				return versions, false, nil
@@ -181,6 +555,16 @@ func parseVersion(rawVersions string) []string {
 	return versions
 }
 
-func variableVersionName(toolName string) string {
-	return fmt.Sprintf("ASDF_%s_VERSION", strings.ToUpper(toolName))
+// VariableVersionName returns the name of the environment variable that sets
+// the versions of the tool, taking precedence over version files
+func VariableVersionName(toolName string) string {
+	// A namespace separator isn't valid in variable names
+	return fmt.Sprintf("ASDF_%s_VERSION", strings.ToUpper(strings.ReplaceAll(toolName, "/", "__")))
+}
+
+// VariableResolvedVersionName returns the name of the environment variable
+// `asdf exec` sets to the version of the tool it runs, which differs from the
+// versions set when an installed version is substituted for them
+func VariableResolvedVersionName(toolName string) string {
+	return fmt.Sprintf("ASDF_%s_RESOLVED_VERSION", strings.ToUpper(strings.ReplaceAll(toolName, "/", "__")))
 }
@@ -1,13 +1,16 @@
 package resolve
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/overrides"
 	"github.com/asdf-vm/asdf/internal/plugins"
 	"github.com/asdf-vm/asdf/internal/repotest"
 	"github.com/stretchr/testify/assert"
@@ -30,6 +33,139 @@ func TestVersion(t *testing.T) {
 		assert.Empty(t, toolVersion.Versions)
 	})
 
+	t.Run("returns version set in home directory when deprecate.home_fallback allows it", func(t *testing.T) {
+		homeDir := t.TempDir()
+		t.Setenv("HOME", homeDir)
+		assert.Nil(t, os.WriteFile(filepath.Join(homeDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, t.TempDir())
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.2.3"}, toolVersion.Versions)
+		assert.Equal(t, homeDir, toolVersion.Directory)
+	})
+
+	t.Run("returns HomeFallbackError when deprecate.home_fallback is error", func(t *testing.T) {
+		homeDir := t.TempDir()
+		t.Setenv("HOME", homeDir)
+		assert.Nil(t, os.WriteFile(filepath.Join(homeDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+		flagConf := conf
+		flagConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(flagConf.ConfigFile, []byte("deprecate.home_fallback = error\n"), 0o666))
+
+		_, found, err := Version(flagConf, plugin, t.TempDir())
+		assert.IsType(t, HomeFallbackError{}, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns versions printed by resolution_missing hook when no version found", func(t *testing.T) {
+		directory := t.TempDir()
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte("resolution_missing = echo 1.0.0 $1-$2\n"), 0o666))
+
+		toolVersion, found, err := Version(hookConf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0", testPluginName + "-" + directory}, toolVersion.Versions)
+		assert.Equal(t, "resolution_missing hook", toolVersion.Source)
+	})
+
+	t.Run("returns not found when resolution_missing hook prints nothing", func(t *testing.T) {
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte("resolution_missing = true\n"), 0o666))
+
+		_, found, err := Version(hookConf, plugin, t.TempDir())
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns error when resolution_missing hook fails", func(t *testing.T) {
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte("resolution_missing = exit 1\n"), 0o666))
+
+		_, found, err := Version(hookConf, plugin, t.TempDir())
+		assert.ErrorContains(t, err, "failed to run resolution_missing hook")
+		assert.False(t, found)
+	})
+
+	t.Run("runs resolve hooks with tool, directory and versions resolved", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte(testPluginName+" 1.0.0 system\n"), 0o666))
+		logFile := filepath.Join(t.TempDir(), "log")
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		hooks := "pre_asdf_resolve = echo pre $@ >> " + logFile + "\npost_asdf_resolve = echo post $@ $ASDF_RESOLVE_SOURCE >> " + logFile + "\n"
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte(hooks), 0o666))
+
+		toolVersion, found, err := Version(hookConf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0", "system"}, toolVersion.Versions)
+
+		contents, err := os.ReadFile(logFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "pre "+testPluginName+" "+directory+"\npost "+testPluginName+" "+directory+" 1.0.0 system "+filepath.Join(directory, ".tool-versions")+"\n", string(contents))
+	})
+
+	t.Run("passes resolution_missing hook as resolve source", func(t *testing.T) {
+		directory := t.TempDir()
+		logFile := filepath.Join(t.TempDir(), "log")
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		hooks := "resolution_missing = echo 1.0.0\npost_asdf_resolve = echo $ASDF_RESOLVE_SOURCE >> " + logFile + "\n"
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte(hooks), 0o666))
+
+		_, found, err := Version(hookConf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+
+		contents, err := os.ReadFile(logFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "resolution_missing hook\n", string(contents))
+	})
+
+	t.Run("runs post resolve hook without versions when none is set", func(t *testing.T) {
+		directory := t.TempDir()
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte("post_asdf_resolve = test $# -eq 2\n"), 0o666))
+
+		_, found, err := Version(hookConf, plugin, directory)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns error when resolve hooks fail", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+		for _, hookName := range []string{"pre_asdf_resolve", "post_asdf_resolve"} {
+			hookConf := conf
+			hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+			assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte(hookName+" = exit 1\n"), 0o666))
+
+			_, found, err := Version(hookConf, plugin, directory)
+			assert.ErrorContains(t, err, "failed to run "+hookName+" hook")
+			assert.False(t, found)
+		}
+	})
+
+	t.Run("returns versions when resolve hook fails and hook_failure_policy is ignore", func(t *testing.T) {
+		directory := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), ".asdfrc")
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte("hook_failure_policy = ignore\npost_asdf_resolve = exit 1\n"), 0o666))
+
+		toolVersion, found, err := Version(hookConf, plugin, directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, toolVersion.Versions)
+	})
+
 	t.Run("returns single version from .tool-versions file", func(t *testing.T) {
 		// write a version file
 		data := []byte(fmt.Sprintf("%s 1.2.3", testPluginName))
@@ -56,6 +192,22 @@ func TestVersion(t *testing.T) {
 		assert.Equal(t, toolVersion.Versions, []string{"2.3.4"})
 	})
 
+	t.Run("returns version from override before .tool-versions file", func(t *testing.T) {
+		data := []byte(fmt.Sprintf("%s 1.2.3", testPluginName))
+		err = os.WriteFile(filepath.Join(currentDir, ".tool-versions"), data, 0o666)
+		assert.Nil(t, err)
+
+		err = overrides.Set(testDataDir, currentDir, testPluginName, []string{"3.4.5"})
+		assert.Nil(t, err)
+		defer overrides.Remove(testDataDir, currentDir, testPluginName)
+
+		toolVersion, found, err := Version(conf, plugin, filepath.Join(currentDir, "subdir"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"3.4.5"}, toolVersion.Versions)
+		assert.Equal(t, overrides.Filename, toolVersion.Source)
+	})
+
 	t.Run("returns single version from .tool-versions file in parent directory", func(t *testing.T) {
 		// write a version file
 		data := []byte(fmt.Sprintf("%s 1.2.3", testPluginName))
@@ -70,6 +222,231 @@ func TestVersion(t *testing.T) {
 		assert.True(t, found)
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3"})
 	})
+
+	t.Run("does not search parent directories of a .tool-versions file marked as root", func(t *testing.T) {
+		homeDir := t.TempDir()
+		t.Setenv("HOME", homeDir)
+		assert.Nil(t, os.WriteFile(filepath.Join(homeDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+
+		parentDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+		projectDir := filepath.Join(parentDir, "project")
+		assert.Nil(t, os.MkdirAll(filepath.Join(projectDir, "subdir"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte("# asdf:root\nother 1.0.0\n"), 0o666))
+
+		_, found, err := Version(conf, plugin, filepath.Join(projectDir, "subdir"))
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("does not search parent directories of a boundary marker", func(t *testing.T) {
+		conf := config.Config{DataDir: testDataDir, DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("boundary_markers = .git\n"), 0o666))
+		homeDir := t.TempDir()
+		t.Setenv("HOME", homeDir)
+		assert.Nil(t, os.WriteFile(filepath.Join(homeDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+
+		parentDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+		repoDir := filepath.Join(parentDir, "repo")
+		assert.Nil(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o777))
+		assert.Nil(t, os.MkdirAll(filepath.Join(repoDir, "subdir"), 0o777))
+
+		_, found, err := Version(conf, plugin, filepath.Join(repoDir, "subdir"))
+		assert.Nil(t, err)
+		assert.False(t, found)
+
+		assert.Nil(t, os.WriteFile(filepath.Join(repoDir, ".tool-versions"), []byte(testPluginName+" 2.0.0\n"), 0o666))
+		toolVersion, found, err := Version(conf, plugin, filepath.Join(repoDir, "subdir"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, toolVersion.Versions)
+	})
+
+	t.Run("searches parent directories of a boundary marker when none are set", func(t *testing.T) {
+		parentDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+		repoDir := filepath.Join(parentDir, "repo")
+		assert.Nil(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o777))
+
+		toolVersion, found, err := Version(conf, plugin, repoDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.2.3"}, toolVersion.Versions)
+	})
+
+	t.Run("returns version from .tool-versions file marked as root", func(t *testing.T) {
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte("# asdf:root\n"+testPluginName+" 2.0.0\n"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, toolVersion.Versions)
+	})
+
+	t.Run("returns newest installed version matching latest versions", func(t *testing.T) {
+		for _, version := range []string{"1.21.9", "1.22.1", "1.22.10", "1.23.0-rc1", "1.23.0-rc2"} {
+			assert.Nil(t, os.MkdirAll(filepath.Join(testDataDir, "installs", testPluginName, version), 0o777))
+		}
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(testPluginName+" latest:1.22 latest latest:3\n"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.22.10", "1.22.10", "latest:3"}, toolVersion.Versions)
+		assert.Equal(t, []string{"latest:1.22", "latest", "latest:3"}, toolVersion.Requested)
+	})
+
+	t.Run("returns newest installed stable version matching wildcard versions", func(t *testing.T) {
+		for _, version := range []string{"1.9.2", "1.10.0", "1.22.2", "1.23.0-rc1", "2.0.0"} {
+			assert.Nil(t, os.MkdirAll(filepath.Join(testDataDir, "installs", testPluginName, version), 0o777))
+		}
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(testPluginName+" 1.x 1.22.* !=1.22.10 3.x\n"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.22.2", "1.22.2", "!=1.22.10", "3.x"}, toolVersion.Versions)
+		assert.Equal(t, []string{"1.x", "1.22.*", "!=1.22.10", "3.x"}, toolVersion.Requested)
+	})
+
+	t.Run("returns newest installed prerelease matching latest and wildcard versions when tool allows prereleases", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("allow_prereleases = "+testPluginName+"\n"), 0o666))
+		for _, version := range []string{"1.22.2", "1.23.0-rc1", "1.23.0-rc2"} {
+			assert.Nil(t, os.MkdirAll(filepath.Join(testDataDir, "installs", testPluginName, version), 0o777))
+		}
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(testPluginName+" latest:1 1.x\n"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.23.0-rc2", "1.23.0-rc2"}, toolVersion.Versions)
+	})
+
+	t.Run("uses legacy_version_file setting of project asdfrc", func(t *testing.T) {
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".dummy-version"), []byte("1.2.3"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.2.3"}, toolVersion.Versions)
+
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".asdfrc"), []byte("legacy_version_file = no\n"), 0o666))
+		_, found, err = Version(conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns system for tool marked unmanaged instead of version in parent directory", func(t *testing.T) {
+		parentDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte(testPluginName+" 1.2.3\n"), 0o666))
+		projectDir := filepath.Join(parentDir, "project")
+		assert.Nil(t, os.MkdirAll(filepath.Join(projectDir, "subdir"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(testPluginName+" unmanaged\n"), 0o666))
+
+		toolVersion, found, err := Version(conf, plugin, filepath.Join(projectDir, "subdir"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"system"}, toolVersion.Versions)
+		assert.Equal(t, projectDir, toolVersion.Directory)
+	})
+}
+
+func TestVersionContext(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: "testdata/asdfrc"}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	projectDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".dummy-version"), []byte("1.2.3"), 0o666))
+
+	t.Run("resolves versions like Version when context is not done", func(t *testing.T) {
+		toolVersion, found, err := VersionContext(context.Background(), conf, plugin, projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.2.3"}, toolVersion.Versions)
+	})
+
+	t.Run("returns error of context when parse-legacy-file callback does not exit in time", func(t *testing.T) {
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "parse-legacy-file", "#!/usr/bin/env bash\nsleep 30\n"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		started := time.Now()
+		_, found, err := VersionContext(ctx, conf, plugin, projectDir)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, found)
+		assert.Less(t, time.Since(started), 10*time.Second)
+	})
+}
+
+func TestFindBestMatchingVersion(t *testing.T) {
+	testDataDir := t.TempDir()
+	conf := config.Config{DataDir: testDataDir, DefaultToolVersionsFilename: ".tool-versions", ConfigFile: "testdata/asdfrc"}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	for _, version := range []string{"1.1.0", "1.2.0", "2.0.0"} {
+		err := os.MkdirAll(filepath.Join(testDataDir, "installs", testPluginName, version), 0o777)
+		assert.Nil(t, err)
+	}
+
+	t.Run("returns latest installed version when ASDF_IGNORE_VERSION is set", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_VERSION", testPluginName)
+		assert.Equal(t, "2.0.0", FindBestMatchingVersion(conf, plugin, []string{"1.0.0"}))
+	})
+
+	t.Run("returns latest installed version matching major.minor when ASDF_IGNORE_PATCH is set", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_PATCH", "*")
+		assert.Equal(t, "1.1.0", FindBestMatchingVersion(conf, plugin, []string{"1.1.5"}))
+	})
+
+	t.Run("compares version segments numerically", func(t *testing.T) {
+		for _, version := range []string{"1.9.0", "1.10.0"} {
+			assert.Nil(t, os.MkdirAll(filepath.Join(testDataDir, "installs", testPluginName, version), 0o777))
+		}
+		defer func() {
+			for _, version := range []string{"1.9.0", "1.10.0"} {
+				assert.Nil(t, os.RemoveAll(filepath.Join(testDataDir, "installs", testPluginName, version)))
+			}
+		}()
+
+		t.Setenv("ASDF_IGNORE_PATCH", "*")
+		assert.Equal(t, "1.10.0", FindBestMatchingVersion(conf, plugin, []string{"1.10.2"}))
+		assert.Equal(t, "1.1.0", FindBestMatchingVersion(conf, plugin, []string{"v1.1.9"}))
+	})
+
+	t.Run("does not reorder the versions passed in", func(t *testing.T) {
+		t.Setenv("ASDF_IGNORE_MINOR", testPluginName)
+		versions := []string{"1.0.0", "3.0.0"}
+		assert.Equal(t, "1.2.0", FindBestMatchingVersion(conf, plugin, versions))
+		assert.Equal(t, []string{"1.0.0", "3.0.0"}, versions)
+	})
+
+	t.Run("skips prereleases unless set or tool allows prereleases", func(t *testing.T) {
+		prerelease := filepath.Join(testDataDir, "installs", testPluginName, "2.1.0-rc1")
+		assert.Nil(t, os.MkdirAll(prerelease, 0o777))
+		defer os.RemoveAll(prerelease)
+
+		t.Setenv("ASDF_IGNORE_MINOR", "*")
+		assert.Equal(t, "2.0.0", FindBestMatchingVersion(conf, plugin, []string{"2.0.5"}))
+		assert.Equal(t, "2.1.0-rc1", FindBestMatchingVersion(conf, plugin, []string{"2.1.0-rc1"}))
+
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("allow_prereleases = *\n"), 0o666))
+		assert.Equal(t, "2.1.0-rc1", FindBestMatchingVersion(conf, plugin, []string{"2.0.5"}))
+	})
 }
 
 func TestFindVersionsInDir(t *testing.T) {
@@ -82,7 +459,7 @@ func TestFindVersionsInDir(t *testing.T) {
 	t.Run("when no versions set returns found false", func(t *testing.T) {
 		currentDir := t.TempDir()
 
-		versions, found, err := findVersionsInDir(conf, plugin, currentDir)
+		versions, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
 
 		assert.Empty(t, versions)
 		assert.False(t, found)
@@ -95,7 +472,7 @@ func TestFindVersionsInDir(t *testing.T) {
 		data := []byte(fmt.Sprintf("%s 1.2.3", testPluginName))
 		err = os.WriteFile(filepath.Join(currentDir, ".tool-versions"), data, 0o666)
 
-		toolVersion, found, err := findVersionsInDir(conf, plugin, currentDir)
+		toolVersion, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
 
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3"})
 		assert.True(t, found)
@@ -108,7 +485,7 @@ func TestFindVersionsInDir(t *testing.T) {
 		data := []byte(fmt.Sprintf("%s 1.2.3 2.3.4", testPluginName))
 		err = os.WriteFile(filepath.Join(currentDir, ".tool-versions"), data, 0o666)
 
-		toolVersion, found, err := findVersionsInDir(conf, plugin, currentDir)
+		toolVersion, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
 
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3", "2.3.4"})
 		assert.True(t, found)
@@ -122,13 +499,36 @@ func TestFindVersionsInDir(t *testing.T) {
 		data := []byte(fmt.Sprintf("%s 1.2.3 2.3.4", testPluginName))
 		err = os.WriteFile(filepath.Join(currentDir, "custom-file"), data, 0o666)
 
-		toolVersion, found, err := findVersionsInDir(conf, plugin, currentDir)
+		toolVersion, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
 
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3", "2.3.4"})
 		assert.True(t, found)
 		assert.Nil(t, err)
 	})
 
+	t.Run("when version is set under alias of tool returns found true and version", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[tool_aliases]\ntest = "+testPluginName+"\n"), 0o666))
+		currentDir := t.TempDir()
+
+		data := []byte("test 1.2.3\n")
+		assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), data, 0o666))
+
+		toolVersion, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
+		assert.Equal(t, []string{"1.2.3"}, toolVersion.Versions)
+		assert.True(t, found)
+		assert.Nil(t, err)
+
+		data = []byte(fmt.Sprintf("test 1.2.3\n%s 2.3.4\n", testPluginName))
+		assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), data, 0o666))
+
+		toolVersion, found, err = findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
+		assert.Equal(t, []string{"2.3.4"}, toolVersion.Versions)
+		assert.True(t, found)
+		assert.Nil(t, err)
+	})
+
 	t.Run("when .tool-version exists and legacy file support is on looks up version in .tool-versions", func(t *testing.T) {
 		currentDir := t.TempDir()
 
@@ -140,7 +540,7 @@ func TestFindVersionsInDir(t *testing.T) {
 		err = os.WriteFile(filepath.Join(currentDir, ".dummy-version"), data, 0o666)
 		assert.NoError(t, err)
 
-		toolVersion, found, err := findVersionsInDir(conf, plugin, currentDir)
+		toolVersion, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3"})
 		assert.True(t, found)
 		assert.NoError(t, err)
@@ -153,7 +553,7 @@ func TestFindVersionsInDir(t *testing.T) {
 		err = os.WriteFile(filepath.Join(currentDir, ".dummy-version"), data, 0o666)
 		assert.NoError(t, err)
 
-		toolVersion, found, err := findVersionsInDir(conf, plugin, currentDir)
+		toolVersion, found, err := findVersionsInDir(context.Background(), conf, plugin, currentDir, nil)
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3", "2.3.4"})
 		assert.True(t, found)
 		assert.NoError(t, err)
@@ -172,14 +572,14 @@ func TestFindVersionsLegacyFiles(t *testing.T) {
 		_, err := repotest.InstallPlugin("dummy_plugin_no_download", conf.DataDir, pluginName)
 		assert.Nil(t, err)
 		plugin := plugins.New(conf, pluginName)
-		toolVersion, found, err := findVersionsInLegacyFile(plugin, t.TempDir())
+		toolVersion, found, err := findVersionsInLegacyFile(context.Background(), plugin, t.TempDir())
 		assert.Empty(t, toolVersion.Versions)
 		assert.False(t, found)
 		assert.Nil(t, err)
 	})
 
 	t.Run("when given tool that has a list-legacy-filenames callback but file not found returns empty versions list", func(t *testing.T) {
-		toolVersion, found, err := findVersionsInLegacyFile(plugin, t.TempDir())
+		toolVersion, found, err := findVersionsInLegacyFile(context.Background(), plugin, t.TempDir())
 		assert.Empty(t, toolVersion.Versions)
 		assert.False(t, found)
 		assert.Nil(t, err)
@@ -192,7 +592,7 @@ func TestFindVersionsLegacyFiles(t *testing.T) {
 		err = os.WriteFile(filepath.Join(currentDir, ".dummy-version"), data, 0o666)
 		assert.Nil(t, err)
 
-		toolVersion, found, err := findVersionsInLegacyFile(plugin, currentDir)
+		toolVersion, found, err := findVersionsInLegacyFile(context.Background(), plugin, currentDir)
 		assert.Equal(t, toolVersion.Versions, []string{"1.2.3"})
 		assert.True(t, found)
 		assert.Nil(t, err)
@@ -225,6 +625,12 @@ func TestFindVersionsInEnv(t *testing.T) {
 		assert.Equal(t, envVariableName, "ASDF_LUA_VERSION")
 		os.Unsetenv("ASDF_LUA_VERSION")
 	})
+
+	t.Run("when env variable is set to whitespace returns not found", func(t *testing.T) {
+		t.Setenv("ASDF_LUA_VERSION", "  ")
+		_, _, found := findVersionsInEnv("lua")
+		assert.False(t, found)
+	})
 }
 
 func TestVariableVersionName(t *testing.T) {
@@ -244,11 +650,20 @@ func TestVariableVersionName(t *testing.T) {
 			input:  "foo-bar",
 			output: "ASDF_FOO-BAR_VERSION",
 		},
+		{
+			input:  "corp/nodejs",
+			output: "ASDF_CORP__NODEJS_VERSION",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("input: %s, output: %s", tt.input, tt.output), func(t *testing.T) {
-			assert.Equal(t, tt.output, variableVersionName(tt.input))
+			assert.Equal(t, tt.output, VariableVersionName(tt.input))
 		})
 	}
 }
+
+func TestVariableResolvedVersionName(t *testing.T) {
+	assert.Equal(t, "ASDF_RUBY_RESOLVED_VERSION", VariableResolvedVersionName("ruby"))
+	assert.Equal(t, "ASDF_CORP__NODEJS_RESOLVED_VERSION", VariableResolvedVersionName("corp/nodejs"))
+}
@@ -0,0 +1,89 @@
+package resolve
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// IgnoredVariable is an ASDF_<TOOL>_VERSION variable removed from the
+// environment by SanitizeEnv
+type IgnoredVariable struct {
+	Name   string
+	Value  string
+	Reason string
+}
+
+func (i IgnoredVariable) String() string {
+	return fmt.Sprintf("%s=%q, %s", i.Name, i.Value, i.Reason)
+}
+
+// SanitizeEnv checks the ASDF_<TOOL>_VERSION variables of the tools with a
+// plugin added before versions are resolved, as a variable exported long ago
+// by a shell takes precedence over every version file. Whitespace in values is
+// normalized. Variables without any versions, or only setting versions that
+// aren't installed, are removed from the environment of the process and
+// returned.
+func SanitizeEnv(conf config.Config) (ignored []IgnoredVariable, err error) {
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return ignored, err
+	}
+
+	for _, plugin := range allPlugins {
+		name := VariableVersionName(plugin.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		versions := strings.Fields(value)
+		reason := ""
+		switch {
+		case len(versions) == 0:
+			reason = "no versions set"
+		case !anyUsable(conf, plugin, versions):
+			reason = "no version set is installed"
+		}
+
+		if reason != "" {
+			ignored = append(ignored, IgnoredVariable{Name: name, Value: value, Reason: reason})
+			if err := os.Unsetenv(name); err != nil {
+				return ignored, err
+			}
+			continue
+		}
+
+		if normalized := strings.Join(versions, " "); normalized != value {
+			if err := os.Setenv(name, normalized); err != nil {
+				return ignored, err
+			}
+		}
+	}
+
+	return ignored, nil
+}
+
+// anyUsable returns true if any of the versions can be used without installing
+// anything first
+func anyUsable(conf config.Config, plugin plugins.Plugin, versions []string) bool {
+	for _, versionStr := range versions {
+		version := toolversions.Parse(versionStr)
+		switch version.Type {
+		case "version", "ref":
+			if installs.IsInstalled(conf, plugin, version) {
+				return true
+			}
+		default:
+			// system and path versions don't need an install of their own
+			return true
+		}
+	}
+
+	return false
+}
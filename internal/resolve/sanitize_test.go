@@ -0,0 +1,58 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeEnv(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: "testdata/asdfrc"}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(filepath.Join(conf.DataDir, "installs", testPluginName, "1.0.0"), 0o777))
+	variable := VariableVersionName(testPluginName)
+
+	t.Run("normalizes whitespace in versions", func(t *testing.T) {
+		t.Setenv(variable, " 1.0.0\t2.0.0\n")
+
+		ignored, err := SanitizeEnv(conf)
+		assert.Nil(t, err)
+		assert.Empty(t, ignored)
+		assert.Equal(t, "1.0.0 2.0.0", os.Getenv(variable))
+	})
+
+	t.Run("keeps system and path versions", func(t *testing.T) {
+		t.Setenv(variable, "2.0.0 system")
+
+		ignored, err := SanitizeEnv(conf)
+		assert.Nil(t, err)
+		assert.Empty(t, ignored)
+		assert.Equal(t, "2.0.0 system", os.Getenv(variable))
+	})
+
+	t.Run("removes variable without versions", func(t *testing.T) {
+		t.Setenv(variable, "  ")
+
+		ignored, err := SanitizeEnv(conf)
+		assert.Nil(t, err)
+		assert.Equal(t, []IgnoredVariable{{Name: variable, Value: "  ", Reason: "no versions set"}}, ignored)
+		_, ok := os.LookupEnv(variable)
+		assert.False(t, ok)
+	})
+
+	t.Run("removes variable setting only versions that aren't installed", func(t *testing.T) {
+		t.Setenv(variable, "2.0.0 3.0.0")
+
+		ignored, err := SanitizeEnv(conf)
+		assert.Nil(t, err)
+		assert.Len(t, ignored, 1)
+		assert.Equal(t, `ASDF_TEST-PLUGIN_VERSION="2.0.0 3.0.0", no version set is installed`, ignored[0].String())
+		_, ok := os.LookupEnv(variable)
+		assert.False(t, ok)
+	})
+}
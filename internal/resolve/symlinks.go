@@ -0,0 +1,41 @@
+package resolve
+
+import (
+	"path/filepath"
+
+	"github.com/asdf-vm/asdf/internal/config"
+)
+
+// Values of the symlink_resolution setting
+const (
+	symlinksPhysical = "physical"
+	symlinksBoth     = "both"
+)
+
+// searchDirectories returns the directories whose parents are searched for
+// versions, in order, as selected by the symlink_resolution setting. The
+// working directory of a shell that changed into a symlink is the path of the
+// symlink, so its parents are those of the symlink rather than of the
+// directory it points to. A directory whose symlinks can't be resolved is
+// searched as it is.
+func searchDirectories(conf config.Config, directory string) ([]string, error) {
+	mode, err := conf.SymlinkResolution()
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != symlinksPhysical && mode != symlinksBoth {
+		return []string{directory}, nil
+	}
+
+	physical, err := filepath.EvalSymlinks(directory)
+	if err != nil || physical == directory {
+		return []string{directory}, nil
+	}
+
+	if mode == symlinksBoth {
+		return []string{physical, directory}, nil
+	}
+
+	return []string{physical}, nil
+}
@@ -0,0 +1,70 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionSymlinks(t *testing.T) {
+	dataDir := t.TempDir()
+	_, err := repotest.InstallPlugin("dummy_plugin", dataDir, testPluginName)
+	assert.Nil(t, err)
+
+	// links/project is a symlink to real/a/project, each tree sets a
+	// different version above the project
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	assert.Nil(t, err)
+	realDir := filepath.Join(base, "real", "a")
+	assert.Nil(t, os.MkdirAll(filepath.Join(realDir, "project"), 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(realDir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+	linksDir := filepath.Join(base, "links")
+	assert.Nil(t, os.MkdirAll(linksDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(linksDir, ".tool-versions"), []byte(testPluginName+" 2.0.0\n"), 0o666))
+	assert.Nil(t, os.Symlink(filepath.Join(realDir, "project"), filepath.Join(linksDir, "project")))
+	directory := filepath.Join(linksDir, "project")
+
+	resolveWith := func(t *testing.T, mode string) ToolVersions {
+		t.Helper()
+		conf := config.Config{DataDir: dataDir, DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("symlink_resolution = "+mode+"\n"), 0o666))
+
+		versions, found, err := Version(conf, plugins.New(conf, testPluginName), directory)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		return versions
+	}
+
+	t.Run("searches parents of the symlink when logical", func(t *testing.T) {
+		versions := resolveWith(t, "logical")
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+		assert.Equal(t, linksDir, versions.Directory)
+	})
+
+	t.Run("searches parents of the symlink target when physical", func(t *testing.T) {
+		versions := resolveWith(t, "physical")
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		assert.Equal(t, realDir, versions.Directory)
+	})
+
+	t.Run("ASDF_SYMLINK_RESOLUTION takes precedence over asdfrc", func(t *testing.T) {
+		t.Setenv("ASDF_SYMLINK_RESOLUTION", "physical")
+		versions := resolveWith(t, "logical")
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+	})
+
+	t.Run("searches physical then logical parents when both", func(t *testing.T) {
+		versions := resolveWith(t, "both")
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+
+		assert.Nil(t, os.Remove(filepath.Join(realDir, ".tool-versions")))
+		versions = resolveWith(t, "both")
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+		assert.Equal(t, linksDir, versions.Directory)
+	})
+}
@@ -0,0 +1,120 @@
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// versionFiles returns the version files set for the tool in the
+// [version_files] section of the asdfrc, or nil when the default lookup of the
+// .tool-versions file followed by legacy version files is used
+func versionFiles(conf config.Config, plugin plugins.Plugin) ([]string, error) {
+	files, err := conf.VersionFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	return files[plugin.Name], nil
+}
+
+// versionFileName returns the name of the file a [version_files] entry refers
+// to, without the JSON field
+func versionFileName(entry string) string {
+	filename, _, _ := strings.Cut(entry, "#")
+	return filename
+}
+
+// findVersionsInVersionFiles looks up the versions of the tool in the version
+// files of the directory in the order they are set in the [version_files]
+// section of the asdfrc, using the first file setting a version. The
+// .tool-versions file is read as usual. An entry of the form
+// `<file>#<field>`, such as `package.json#engines.node`, reads the version from
+// a field of a JSON file, with nested fields separated by dots. Any other file
+// is read like a legacy version file, through the parse-legacy-file callback
+// of the plugin when it has one.
+func findVersionsInVersionFiles(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory string, files []string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	for _, entry := range files {
+		versions, found, err = findVersionsInVersionFile(ctx, conf, plugin, directory, entry, reads)
+		if found || err != nil {
+			return versions, found, err
+		}
+	}
+
+	return versions, false, nil
+}
+
+func findVersionsInVersionFile(ctx context.Context, conf config.Config, plugin plugins.Plugin, directory, entry string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	filename, field, isJSON := strings.Cut(entry, "#")
+	filepath := path.Join(directory, filename)
+	if _, err := os.Stat(filepath); err != nil {
+		return versions, false, nil
+	}
+
+	var fileVersions []string
+	switch {
+	case isJSON:
+		fileVersions, err = jsonFieldVersions(filepath, field)
+	case filename == conf.DefaultToolVersionsFilename:
+		var names []string
+		if names, err = toolNames(conf, plugin); err == nil {
+			fileVersions, _, err = reads.toolVersions(filepath, names)
+		}
+		if slices.Equal(fileVersions, []string{toolversions.Unmanaged}) {
+			fileVersions = []string{toolversions.System}
+		}
+	default:
+		fileVersions, err = plugin.ParseLegacyVersionFile(ctx, filepath)
+		fileVersions = slices.DeleteFunc(fileVersions, func(version string) bool { return version == "" })
+	}
+
+	if err != nil || len(fileVersions) == 0 {
+		return versions, false, err
+	}
+
+	return ToolVersions{Versions: fileVersions, Source: entry, Directory: directory}, true, nil
+}
+
+// jsonFieldVersions returns the versions set by a string field of a JSON file,
+// such as `engines.node` in a package.json file. A single constraint like
+// `>=18` is returned as it is, but ranges combining several, like `>=18 <21`
+// or `18 || 20`, can't be expressed as asdf versions and set none.
+func jsonFieldVersions(filepath, field string) ([]string, error) {
+	contents, err := os.ReadFile(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal(contents, &value); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", filepath, err)
+	}
+
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		value = object[key]
+	}
+
+	version, _ := value.(string)
+	versions := strings.Fields(version)
+	if len(versions) > 1 && slices.ContainsFunc(versions, func(version string) bool { return strings.ContainsAny(version[:1], "=<>|^~") }) {
+		return nil, nil
+	}
+	return versions, nil
+}
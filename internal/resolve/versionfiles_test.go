@@ -0,0 +1,88 @@
+package resolve
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindVersionsInVersionFiles(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("[version_files]\n"+testPluginName+" = .nvmrc .tool-versions package.json#engines.node\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	write := func(t *testing.T, directory, name, contents string) {
+		t.Helper()
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, name), []byte(contents), 0o666))
+	}
+
+	t.Run("uses first file in order that sets a version", func(t *testing.T) {
+		directory := t.TempDir()
+		write(t, directory, ".tool-versions", testPluginName+" 1.0.0\n")
+		write(t, directory, ".nvmrc", "2.0.0\n")
+
+		versions, found, err := findVersionsInDir(context.Background(), conf, plugin, directory, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, ToolVersions{Versions: []string{"2.0.0"}, Source: ".nvmrc", Directory: directory}, versions)
+	})
+
+	t.Run("falls through files that don't set a version", func(t *testing.T) {
+		directory := t.TempDir()
+		write(t, directory, ".tool-versions", "other 1.0.0\n")
+		write(t, directory, "package.json", `{"engines": {"node": ">=18"}}`)
+
+		versions, found, err := findVersionsInDir(context.Background(), conf, plugin, directory, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, ToolVersions{Versions: []string{">=18"}, Source: "package.json#engines.node", Directory: directory}, versions)
+	})
+
+	t.Run("ignores ranges combining several constraints", func(t *testing.T) {
+		for _, value := range []string{">=18 <21", "18 || 20"} {
+			directory := t.TempDir()
+			write(t, directory, "package.json", `{"engines": {"node": "`+value+`"}}`)
+
+			_, found, err := findVersionsInDir(context.Background(), conf, plugin, directory, nil)
+			assert.Nil(t, err)
+			assert.False(t, found, value)
+		}
+	})
+
+	t.Run("ignores JSON files without the field", func(t *testing.T) {
+		directory := t.TempDir()
+		write(t, directory, "package.json", `{"engines": "node"}`)
+
+		_, found, err := findVersionsInDir(context.Background(), conf, plugin, directory, nil)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("returns error for invalid JSON file", func(t *testing.T) {
+		directory := t.TempDir()
+		write(t, directory, "package.json", `{"engines": `)
+
+		_, found, err := findVersionsInDir(context.Background(), conf, plugin, directory, nil)
+		assert.ErrorContains(t, err, "unable to parse")
+		assert.False(t, found)
+	})
+
+	t.Run("leaves other tools to the default lookup", func(t *testing.T) {
+		directory := t.TempDir()
+		write(t, directory, ".tool-versions", "other 1.0.0\n")
+		write(t, directory, ".nvmrc", "2.0.0\n")
+
+		versions, found, err := findVersionsInDir(context.Background(), conf, plugins.New(conf, "other"), directory, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+	})
+}
@@ -0,0 +1,51 @@
+package resolve
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// findVersionsInToolVersionsPath looks up the versions of the tool in the path
+// set by ASDF_TOOL_VERSIONS_PATH or the tool_versions_path setting, for CI and
+// scripted builds that must resolve the same versions whatever directory they
+// run in. The path is either a file in the .tool-versions format or a
+// directory, whose version files are read as they would be in the directory
+// tree. Either way the current directory, its parents, env files, overrides
+// and the home directory aren't looked at. set is false when no path is set.
+func findVersionsInToolVersionsPath(ctx context.Context, conf config.Config, plugin plugins.Plugin, reads *fileReads) (versions ToolVersions, set, found bool, err error) {
+	toolVersionsPath, err := conf.ToolVersionsPath()
+	if err != nil || toolVersionsPath == "" {
+		return versions, err != nil, false, err
+	}
+
+	info, err := os.Stat(toolVersionsPath)
+	if err != nil {
+		return versions, true, false, err
+	}
+
+	if info.IsDir() {
+		versions, found, err = findVersionsInDir(ctx, conf, plugin, toolVersionsPath, reads)
+		return versions, true, found, err
+	}
+
+	names, err := toolNames(conf, plugin)
+	if err != nil {
+		return versions, true, false, err
+	}
+
+	toolVersions, found, err := reads.toolVersions(toolVersionsPath, names)
+	if slices.Equal(toolVersions, []string{toolversions.Unmanaged}) {
+		toolVersions = []string{toolversions.System}
+	}
+	if !found || err != nil {
+		return versions, true, false, err
+	}
+
+	return ToolVersions{Versions: toolVersions, Source: filepath.Base(toolVersionsPath), Directory: filepath.Dir(toolVersionsPath)}, true, true, nil
+}
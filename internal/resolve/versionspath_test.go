@@ -0,0 +1,90 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/overrides"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionToolVersionsPath(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: "testdata/asdfrc"}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+
+	currentDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+	ciDir := t.TempDir()
+	ciFile := filepath.Join(ciDir, "ci.tool-versions")
+	assert.Nil(t, os.WriteFile(ciFile, []byte(testPluginName+" 2.0.0\n"), 0o666))
+	assert.Nil(t, os.WriteFile(filepath.Join(ciDir, ".tool-versions"), []byte(testPluginName+" 3.0.0\n"), 0o666))
+
+	t.Run("returns versions from file set by ASDF_TOOL_VERSIONS_PATH", func(t *testing.T) {
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", ciFile)
+
+		versions, found, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+		assert.Equal(t, "ci.tool-versions", versions.Source)
+		assert.Equal(t, ciDir, versions.Directory)
+	})
+
+	t.Run("returns versions from version files of directory set by ASDF_TOOL_VERSIONS_PATH", func(t *testing.T) {
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", ciDir)
+
+		versions, found, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"3.0.0"}, versions.Versions)
+		assert.Equal(t, ".tool-versions", versions.Source)
+	})
+
+	t.Run("returns versions from tool_versions_path setting", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("tool_versions_path = "+ciFile+"\n"), 0o666))
+
+		versions, found, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+	})
+
+	t.Run("does not search current directory or overrides when tool is not in path", func(t *testing.T) {
+		otherFile := filepath.Join(t.TempDir(), ".tool-versions")
+		assert.Nil(t, os.WriteFile(otherFile, []byte("other 1.0.0\n"), 0o666))
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", otherFile)
+		assert.Nil(t, overrides.Set(conf.DataDir, currentDir, testPluginName, []string{"4.0.0"}))
+		defer overrides.Remove(conf.DataDir, currentDir, testPluginName)
+
+		versions, found, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.False(t, found)
+		assert.Empty(t, versions.Versions)
+	})
+
+	t.Run("returns version from environment before path", func(t *testing.T) {
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", ciFile)
+		t.Setenv(VariableVersionName(testPluginName), "5.0.0")
+
+		versions, found, err := Version(conf, plugin, currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"5.0.0"}, versions.Versions)
+	})
+
+	t.Run("returns error when path does not exist", func(t *testing.T) {
+		t.Setenv("ASDF_TOOL_VERSIONS_PATH", filepath.Join(ciDir, "missing"))
+
+		_, found, err := Version(conf, plugin, currentDir)
+		assert.False(t, found)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,139 @@
+package resolve
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// WorkspaceFilename is the name of the workspace manifest of a monorepo, placed
+// at its root. It sets the versions of the subprojects of the repository in
+// sections named after their directories, relative to the root, so they don't
+// each need a .tool-versions file:
+//
+//	[services/api]
+//	nodejs 20.11.0
+//
+//	[packages/*]
+//	nodejs 18.19.0
+//
+// Sections hold lines in the .tool-versions format. Their names may contain
+// the patterns of path.Match, a `*` matching a single directory.
+const WorkspaceFilename = ".tool-workspace"
+
+// workspaceSection is a section of a workspace manifest
+type workspaceSection struct {
+	pattern string
+	content string
+}
+
+// parseWorkspace returns the sections of the workspace manifest, in the order
+// they are in. Lines before the first section are ignored.
+func parseWorkspace(content string) (sections []workspaceSection) {
+	var lines []string
+	flush := func() {
+		if len(sections) > 0 {
+			sections[len(sections)-1].content = strings.Join(lines, "\n")
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			flush()
+			pattern := path.Clean(strings.TrimSpace(strings.Trim(trimmed, "[]")))
+			sections = append(sections, workspaceSection{pattern: pattern})
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return sections
+}
+
+// findVersionsInWorkspace looks up the versions set for the start directory by
+// the workspace manifest in root, if there is one. The section of the nearest
+// of start and its parents below root setting the tool is used, sections are
+// tried in the order they are in for each directory.
+func findVersionsInWorkspace(conf config.Config, plugin plugins.Plugin, root, start string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	rel, err := filepath.Rel(root, start)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return versions, false, nil
+	}
+
+	file := reads.read(path.Join(root, WorkspaceFilename))
+	if !file.exists || file.err != nil {
+		return versions, false, file.err
+	}
+
+	names, err := toolNames(conf, plugin)
+	if err != nil {
+		return versions, false, err
+	}
+
+	sections := parseWorkspace(file.contents)
+	for dir := filepath.ToSlash(rel); dir != "."; dir = path.Dir(dir) {
+		for _, section := range sections {
+			if matched, _ := path.Match(section.pattern, dir); !matched {
+				continue
+			}
+
+			for _, name := range names {
+				toolVersions, found := toolversions.FindToolVersionsInContent(section.content, name)
+				if !found {
+					continue
+				}
+
+				if slices.Equal(toolVersions, []string{toolversions.Unmanaged}) {
+					toolVersions = []string{toolversions.System}
+				}
+				return ToolVersions{Versions: toolVersions, Source: WorkspaceFilename, Directory: root}, true, nil
+			}
+		}
+	}
+
+	return versions, false, nil
+}
+
+// Workspace returns the root of the workspace the directory is in, the nearest
+// of the directory and its parents holding a workspace manifest, along with
+// the directories of its subprojects that exist, see WorkspaceFilename. The
+// root is the first of the directories. found is false when the directory
+// isn't in a workspace.
+func Workspace(directory string) (root string, directories []string, found bool, err error) {
+	for dir := directory; ; dir = filepath.Dir(dir) {
+		contents, err := os.ReadFile(filepath.Join(dir, WorkspaceFilename))
+		if err == nil {
+			root = dir
+			directories = append(directories, root)
+			for _, section := range parseWorkspace(string(contents)) {
+				matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(section.pattern)))
+				if err != nil {
+					return root, directories, true, err
+				}
+
+				for _, match := range matches {
+					if info, err := os.Stat(match); err == nil && info.IsDir() && !slices.Contains(directories, match) {
+						directories = append(directories, match)
+					}
+				}
+			}
+			return root, directories, true, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, false, err
+		}
+
+		if filepath.Dir(dir) == dir {
+			return "", nil, false, nil
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionWorkspace(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+	t.Setenv("HOME", t.TempDir())
+
+	root := generateWorkspace(t, "[services/api]\n"+testPluginName+" 2.0.0\n\n[packages/*]\n# shared by every package\n"+testPluginName+" 3.0.0\n")
+	assert.Nil(t, os.WriteFile(filepath.Join(root, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+	t.Run("returns versions set for subproject", func(t *testing.T) {
+		versions, found, err := Version(conf, plugin, filepath.Join(root, "services", "api", "src"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+		assert.Equal(t, WorkspaceFilename, versions.Source)
+		assert.Equal(t, root, versions.Directory)
+	})
+
+	t.Run("returns versions set for subprojects matching pattern", func(t *testing.T) {
+		versions, found, err := Version(conf, plugin, filepath.Join(root, "packages", "web"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"3.0.0"}, versions.Versions)
+	})
+
+	t.Run("returns versions set in root for other directories", func(t *testing.T) {
+		versions, found, err := Version(conf, plugin, filepath.Join(root, "services", "worker"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		assert.Equal(t, ".tool-versions", versions.Source)
+	})
+
+	t.Run("returns versions set in subproject version file before workspace", func(t *testing.T) {
+		versionsFile := filepath.Join(root, "services", "api", ".tool-versions")
+		assert.Nil(t, os.WriteFile(versionsFile, []byte(testPluginName+" 4.0.0\n"), 0o666))
+		defer os.Remove(versionsFile)
+
+		versions, found, err := Version(conf, plugin, filepath.Join(root, "services", "api", "src"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"4.0.0"}, versions.Versions)
+	})
+
+	t.Run("returns changed versions when resolution is cached", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("resolution_cache = memory\n"), 0o666))
+		dir := filepath.Join(root, "services", "api")
+
+		versions, _, err := Version(conf, plugin, dir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+
+		assert.Nil(t, os.WriteFile(filepath.Join(root, WorkspaceFilename), []byte("[services/api]\n"+testPluginName+" 2.1.0 \n"), 0o666))
+		versions, _, err = Version(conf, plugin, dir)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2.1.0"}, versions.Versions)
+	})
+
+	t.Run("explains versions set for subproject", func(t *testing.T) {
+		candidates, versions, found, err := Explain(conf, plugin, filepath.Join(root, "services", "api"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.1.0"}, versions.Versions)
+		last := candidates[len(candidates)-1]
+		assert.True(t, last.Accepted)
+		assert.Equal(t, filepath.Join(root, WorkspaceFilename), last.Source)
+	})
+}
+
+func TestWorkspace(t *testing.T) {
+	root := generateWorkspace(t, "[services/api]\n[packages/*]\n[missing]\n")
+
+	t.Run("returns root and subproject directories", func(t *testing.T) {
+		workspaceRoot, directories, found, err := Workspace(filepath.Join(root, "packages", "web"))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, root, workspaceRoot)
+		assert.Equal(t, []string{
+			root,
+			filepath.Join(root, "services", "api"),
+			filepath.Join(root, "packages", "ui"),
+			filepath.Join(root, "packages", "web"),
+		}, directories)
+	})
+
+	t.Run("returns false outside workspace", func(t *testing.T) {
+		_, _, found, err := Workspace(t.TempDir())
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestParseWorkspace(t *testing.T) {
+	sections := parseWorkspace("ignored 1.0.0\n[ services/api/ ]\nnodejs 20.11.0\n\n[packages/*]\nnodejs 18.19.0\n")
+	assert.Equal(t, []workspaceSection{
+		{pattern: "services/api", content: "nodejs 20.11.0\n"},
+		{pattern: "packages/*", content: "nodejs 18.19.0\n"},
+	}, sections)
+}
+
+// generateWorkspace creates a workspace with the manifest and the services/api,
+// services/worker, packages/ui and packages/web directories, returning its root
+func generateWorkspace(t *testing.T, manifest string) string {
+	root := t.TempDir()
+	for _, dir := range []string{"services/api/src", "services/worker", "packages/ui", "packages/web"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(root, dir), 0o777))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(root, WorkspaceFilename), []byte(manifest), 0o666))
+	return root
+}
@@ -0,0 +1,109 @@
+package resolve
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+const (
+	gitDirName      = ".git"
+	gitDirPrefix    = "gitdir:"
+	commonDirFile   = "commondir"
+	worktreeSetting = "worktree_fallback"
+)
+
+// findVersionsInWorktree looks up the versions of the tool in the root version
+// files of the main worktree when the directory is in a linked git worktree and
+// the worktree_fallback setting is enabled, so versions pinned in files only
+// the primary checkout has, such as an untracked .tool-versions, still apply to
+// its other worktrees. It is the last place looked at before the default
+// versions and the home directory.
+func findVersionsInWorktree(ctx context.Context, conf config.Config, plugin plugins.Plugin, directories []string, reads *fileReads) (versions ToolVersions, found bool, err error) {
+	enabled, err := conf.WorktreeFallback()
+	if err != nil || !enabled {
+		return versions, false, err
+	}
+
+	for _, dir := range directories {
+		mainDir, ok := mainWorktree(dir)
+		if !ok {
+			continue
+		}
+
+		versions, found, err = findVersionsInDir(ctx, conf, plugin, mainDir, reads)
+		if err != nil || found {
+			return versions, found, err
+		}
+	}
+
+	return versions, false, nil
+}
+
+// mainWorktree returns the root directory of the main worktree of the linked
+// git worktree the directory is in. ok is false when the directory isn't in a
+// linked worktree, or the main worktree is a bare repository. Only the files
+// git writes for the worktree are read, git itself isn't run.
+func mainWorktree(directory string) (mainDir string, ok bool) {
+	for dir := directory; ; dir = filepath.Dir(dir) {
+		info, err := os.Stat(filepath.Join(dir, gitDirName))
+		if err == nil {
+			// A .git directory is the main worktree or a repository
+			// without linked worktrees, a .git file points to the
+			// repository of a linked worktree or a submodule
+			if info.IsDir() {
+				return "", false
+			}
+			return mainWorktreeOf(dir)
+		}
+
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+func inLinkedWorktree(directory string) bool {
+	_, ok := mainWorktree(directory)
+	return ok
+}
+
+// mainWorktreeOf reads the .git file of the worktree in the directory. The
+// directory it points to has a commondir file with the path of the repository
+// shared by all worktrees, which submodules don't have.
+func mainWorktreeOf(directory string) (string, bool) {
+	contents, err := os.ReadFile(filepath.Join(directory, gitDirName))
+	if err != nil {
+		return "", false
+	}
+
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(contents)), gitDirPrefix)
+	if !ok {
+		return "", false
+	}
+	gitDir = absolutePath(directory, strings.TrimSpace(gitDir))
+
+	commonDir, err := os.ReadFile(filepath.Join(gitDir, commonDirFile))
+	if err != nil {
+		return "", false
+	}
+
+	repository := absolutePath(gitDir, strings.TrimSpace(string(commonDir)))
+	if filepath.Base(repository) != gitDirName {
+		return "", false
+	}
+
+	mainDir := filepath.Dir(repository)
+	return mainDir, mainDir != directory
+}
+
+func absolutePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
@@ -0,0 +1,122 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionWorktreeFallback(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("worktree_fallback = yes\n"), 0o666))
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+	t.Setenv("HOME", t.TempDir())
+
+	mainDir, worktreeDir := generateWorktree(t)
+	assert.Nil(t, os.WriteFile(filepath.Join(mainDir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+	subDir := filepath.Join(worktreeDir, "sub")
+	assert.Nil(t, os.MkdirAll(subDir, 0o777))
+
+	t.Run("returns versions set in main worktree", func(t *testing.T) {
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		assert.Equal(t, ".tool-versions", versions.Source)
+		assert.Equal(t, mainDir, versions.Directory)
+	})
+
+	t.Run("returns versions set in linked worktree before main worktree", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(worktreeDir, ".tool-versions"), []byte(testPluginName+" 2.0.0\n"), 0o666))
+		defer os.Remove(filepath.Join(worktreeDir, ".tool-versions"))
+
+		versions, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.0.0"}, versions.Versions)
+		assert.Equal(t, worktreeDir, versions.Directory)
+	})
+
+	t.Run("does not return versions set in main worktree when setting is off", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+
+		_, found, err := Version(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("explains main worktree fallback", func(t *testing.T) {
+		candidates, versions, found, err := Explain(conf, plugin, subDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		last := candidates[len(candidates)-1]
+		assert.True(t, last.Accepted)
+		assert.Equal(t, filepath.Join(mainDir, ".tool-versions"), last.Source)
+		assert.Contains(t, last.Reason, "main worktree fallback")
+	})
+}
+
+func TestMainWorktree(t *testing.T) {
+	mainDir, worktreeDir := generateWorktree(t)
+
+	t.Run("returns main worktree of linked worktree", func(t *testing.T) {
+		dir, ok := mainWorktree(filepath.Join(worktreeDir, "sub", "dir"))
+		assert.True(t, ok)
+		assert.Equal(t, mainDir, dir)
+	})
+
+	t.Run("returns false in main worktree", func(t *testing.T) {
+		_, ok := mainWorktree(mainDir)
+		assert.False(t, ok)
+	})
+
+	t.Run("returns false outside repository", func(t *testing.T) {
+		_, ok := mainWorktree(t.TempDir())
+		assert.False(t, ok)
+	})
+
+	t.Run("returns false in submodule", func(t *testing.T) {
+		submoduleDir := filepath.Join(mainDir, "submodule")
+		assert.Nil(t, os.MkdirAll(filepath.Join(mainDir, ".git", "modules", "submodule"), 0o777))
+		assert.Nil(t, os.MkdirAll(submoduleDir, 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(submoduleDir, ".git"), []byte("gitdir: ../.git/modules/submodule\n"), 0o666))
+
+		_, ok := mainWorktree(submoduleDir)
+		assert.False(t, ok)
+	})
+
+	t.Run("returns false for worktree of bare repository", func(t *testing.T) {
+		bareDir := filepath.Join(t.TempDir(), "repo.git")
+		gitDir := filepath.Join(bareDir, "worktrees", "feature")
+		assert.Nil(t, os.MkdirAll(gitDir, 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(gitDir, "commondir"), []byte("../..\n"), 0o666))
+		dir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, ".git"), []byte("gitdir: "+gitDir+"\n"), 0o666))
+
+		_, ok := mainWorktree(dir)
+		assert.False(t, ok)
+	})
+}
+
+// generateWorktree writes the files git writes for a repository with a linked
+// worktree, returning the directories of both worktrees
+func generateWorktree(t *testing.T) (mainDir, worktreeDir string) {
+	mainDir = filepath.Join(t.TempDir(), "main")
+	gitDir := filepath.Join(mainDir, ".git", "worktrees", "feature")
+	assert.Nil(t, os.MkdirAll(gitDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(gitDir, "commondir"), []byte("../..\n"), 0o666))
+
+	worktreeDir = filepath.Join(t.TempDir(), "feature")
+	assert.Nil(t, os.MkdirAll(worktreeDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(worktreeDir, ".git"), []byte("gitdir: "+gitDir+"\n"), 0o666))
+	return mainDir, worktreeDir
+}
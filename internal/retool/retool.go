@@ -0,0 +1,138 @@
+// Package retool moves every project under a set of directories from one
+// version of a tool to another, for platform teams rolling out an upgrade
+// across many repositories with `asdf retool`.
+package retool
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+// skipDirs are directories never searched for version files, as they hold
+// dependencies or version control data rather than projects
+var skipDirs = []string{".git", ".hg", ".svn", "node_modules", "vendor"}
+
+// Change is a version file that pins the old version of the tool
+type Change struct {
+	Path   string
+	Before []string
+	After  []string
+}
+
+// ParseSpec parses a `<tool>@<version>` argument
+func ParseSpec(spec string) (tool, version string, err error) {
+	tool, version, ok := strings.Cut(spec, "@")
+	if !ok || tool == "" || version == "" {
+		return "", "", fmt.Errorf("invalid tool version %q, expected <tool>@<version>", spec)
+	}
+
+	return tool, version, nil
+}
+
+// Run finds the version files named filename under the roots setting the tool
+// to the from version, and replaces it with the to version. A version matches
+// from when it equals it or starts with it followed by a dot, so `18` matches
+// 18.19.0. Files are only rewritten when write is true, the changes are
+// returned either way.
+func Run(roots []string, filename, tool, from, to string, write bool) (changes []Change, err error) {
+	replace := func(version string) string {
+		if versionspec.Satisfies(version, from) {
+			return to
+		}
+		return version
+	}
+
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() {
+				if path != root && slices.Contains(skipDirs, entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if entry.Name() != filename || !entry.Type().IsRegular() {
+				return nil
+			}
+
+			change, changed, err := retoolFile(path, tool, replace, write)
+			if err != nil {
+				return err
+			}
+			if changed {
+				changes = append(changes, change)
+			}
+			return nil
+		})
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+func retoolFile(path, tool string, replace func(string) string, write bool) (change Change, changed bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return change, false, err
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return change, false, err
+	}
+
+	updated, changed := toolversions.ReplaceVersions(string(contents), tool, replace)
+	if !changed {
+		return change, false, nil
+	}
+
+	before, _, _ := toolversions.FindToolVersions(path, tool)
+	change = Change{Path: path, Before: before}
+	for _, version := range before {
+		change.After = append(change.After, replace(version))
+	}
+
+	if write {
+		if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+			return change, false, err
+		}
+	}
+
+	return change, true, nil
+}
+
+// WriteSummary writes a Markdown summary of the changes, suitable for the
+// description of a pull request
+func WriteSummary(w io.Writer, tool, from, to string, changes []Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintf(w, "# Move %s from %s to %s\n\nNo version files set %s to %s.\n", tool, from, to, tool, from)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "# Move %s from %s to %s\n\n%d version files changed:\n\n", tool, from, to, len(changes)); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		_, err := fmt.Fprintf(w, "- `%s`: %s → %s\n", change.Path, strings.Join(change.Before, " "), strings.Join(change.After, " "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package retool
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSpec(t *testing.T) {
+	tool, version, err := ParseSpec("nodejs@18")
+	assert.Nil(t, err)
+	assert.Equal(t, "nodejs", tool)
+	assert.Equal(t, "18", version)
+
+	for _, spec := range []string{"nodejs", "@18", "nodejs@"} {
+		_, _, err := ParseSpec(spec)
+		assert.ErrorContains(t, err, "invalid tool version", spec)
+	}
+}
+
+func TestRun(t *testing.T) {
+	setup := func(t *testing.T) string {
+		t.Helper()
+		root := t.TempDir()
+		files := map[string]string{
+			"api/.tool-versions":                  "nodejs 18.19.0 # pinned\nruby 3.3.0\n",
+			"web/.tool-versions":                  "nodejs 20.11.1\n",
+			"web/legacy/.tool-versions":           "nodejs   18 system\n",
+			"web/node_modules/dep/.tool-versions": "nodejs 18.0.0\n",
+			"other/.tool-versions":                "nodejs 180.0.0\n",
+		}
+		for name, contents := range files {
+			path := filepath.Join(root, name)
+			assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o777))
+			assert.Nil(t, os.WriteFile(path, []byte(contents), 0o666))
+		}
+		return root
+	}
+
+	read := func(t *testing.T, path string) string {
+		t.Helper()
+		contents, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		return string(contents)
+	}
+
+	t.Run("rewrites files pinning the old version", func(t *testing.T) {
+		root := setup(t)
+
+		changes, err := Run([]string{root}, ".tool-versions", "nodejs", "18", "20.11.1", true)
+		assert.Nil(t, err)
+		assert.Equal(t, []Change{
+			{Path: filepath.Join(root, "api/.tool-versions"), Before: []string{"18.19.0"}, After: []string{"20.11.1"}},
+			{Path: filepath.Join(root, "web/legacy/.tool-versions"), Before: []string{"18", "system"}, After: []string{"20.11.1", "system"}},
+		}, changes)
+
+		assert.Equal(t, "nodejs 20.11.1 # pinned\nruby 3.3.0\n", read(t, filepath.Join(root, "api/.tool-versions")))
+		assert.Equal(t, "nodejs   20.11.1 system\n", read(t, filepath.Join(root, "web/legacy/.tool-versions")))
+		assert.Equal(t, "nodejs 18.0.0\n", read(t, filepath.Join(root, "web/node_modules/dep/.tool-versions")))
+		assert.Equal(t, "nodejs 180.0.0\n", read(t, filepath.Join(root, "other/.tool-versions")))
+	})
+
+	t.Run("leaves files unchanged when not writing", func(t *testing.T) {
+		root := setup(t)
+
+		changes, err := Run([]string{root}, ".tool-versions", "nodejs", "18", "20.11.1", false)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 2)
+		assert.Equal(t, "nodejs 18.19.0 # pinned\nruby 3.3.0\n", read(t, filepath.Join(root, "api/.tool-versions")))
+	})
+
+	t.Run("returns error for missing root", func(t *testing.T) {
+		_, err := Run([]string{filepath.Join(t.TempDir(), "missing")}, ".tool-versions", "nodejs", "18", "20", true)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestWriteSummary(t *testing.T) {
+	var output strings.Builder
+	changes := []Change{{Path: "/src/api/.tool-versions", Before: []string{"18.19.0"}, After: []string{"20.11.1"}}}
+
+	assert.Nil(t, WriteSummary(&output, "nodejs", "18", "20.11.1", changes))
+	assert.Equal(t, "# Move nodejs from 18 to 20.11.1\n\n1 version files changed:\n\n- `/src/api/.tool-versions`: 18.19.0 → 20.11.1\n", output.String())
+}
@@ -0,0 +1,200 @@
+// Package revision resolves versions as they were set at a past revision of a
+// Git repository. The version files committed at the revision are written to a
+// temporary directory that mirrors the layout of the working tree, so the
+// regular resolution logic can run against it unchanged. This allows old builds
+// to be reproduced, for example when bisecting a build failure, without
+// checking out the revision.
+package revision
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/plugins"
+)
+
+// Snapshot is a temporary directory containing the version files of a
+// repository at a revision
+type Snapshot struct {
+	// Ref is the Git ref the snapshot was taken at, as given by the user
+	Ref string
+	// Commit is the hash of the commit Ref pointed to
+	Commit string
+	// Root is the root of the working tree of the repository
+	Root string
+	// Dir is the temporary directory the working tree is mirrored in
+	Dir string
+}
+
+// Checkout writes the version files of the repository containing the
+// directory, as they were at ref, into a new snapshot. Version files in the
+// directories above the root of the repository are copied from the file system
+// as they are now, since they aren't part of the history of the repository.
+// The snapshot must be removed with Remove once no longer needed.
+func Checkout(conf config.Config, directory, ref string) (snapshot Snapshot, err error) {
+	directory, err = filepath.EvalSymlinks(directory)
+	if err != nil {
+		return snapshot, err
+	}
+
+	repo := git.NewRepo(directory)
+	root, err := repo.Toplevel()
+	if err != nil {
+		return snapshot, err
+	}
+
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return snapshot, err
+	}
+
+	// ls-tree lists paths relative to the directory it's run in
+	repo = git.NewRepo(root)
+
+	commit, err := repo.Commit(ref)
+	if err != nil {
+		return snapshot, err
+	}
+
+	filenames, err := versionFilenames(conf)
+	if err != nil {
+		return snapshot, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "asdf-revision-")
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot = Snapshot{Ref: ref, Commit: commit, Root: root, Dir: tmpDir}
+	if err := snapshot.write(repo, directory, filenames); err != nil {
+		snapshot.Remove()
+		return Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// Path returns the path in the snapshot mirroring a path in the working tree
+func (s Snapshot) Path(original string) string {
+	return filepath.Join(s.Dir, original)
+}
+
+// Original returns the path in the working tree a path in the snapshot
+// mirrors. Paths outside the snapshot are returned unchanged.
+func (s Snapshot) Original(snapshotPath string) string {
+	rel, err := filepath.Rel(s.Dir, snapshotPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return snapshotPath
+	}
+
+	return filepath.Join(string(filepath.Separator), rel)
+}
+
+// Remove deletes the snapshot
+func (s Snapshot) Remove() error {
+	return os.RemoveAll(s.Dir)
+}
+
+func (s Snapshot) write(repo git.Repo, directory string, filenames []string) error {
+	rel, err := filepath.Rel(s.Root, directory)
+	if err != nil {
+		return err
+	}
+
+	// Directories of the repository from the root down to the directory,
+	// relative to the root
+	repoDirs := []string{"."}
+	if rel != "." {
+		for i, segment := range strings.Split(filepath.ToSlash(rel), "/") {
+			repoDirs = append(repoDirs, path.Join(repoDirs[i], segment))
+		}
+	}
+
+	for _, repoDir := range repoDirs {
+		names, err := repo.Files(s.Commit, repoDir)
+		if err != nil {
+			// The directory didn't exist at the revision, so neither did any
+			// of the directories below it
+			break
+		}
+
+		for _, name := range names {
+			if !slices.Contains(filenames, name) {
+				continue
+			}
+
+			contents, err := repo.Show(s.Commit, path.Join(repoDir, name))
+			if err != nil {
+				return err
+			}
+
+			if err := writeFile(s.Path(filepath.Join(s.Root, repoDir, name)), []byte(contents)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for dir := filepath.Dir(s.Root); ; dir = filepath.Dir(dir) {
+		for _, filename := range filenames {
+			contents, err := os.ReadFile(filepath.Join(dir, filename))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := writeFile(s.Path(filepath.Join(dir, filename)), contents); err != nil {
+				return err
+			}
+		}
+
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	return os.MkdirAll(s.Path(directory), 0o777)
+}
+
+// versionFilenames returns the names of the files versions may be set in
+func versionFilenames(conf config.Config) ([]string, error) {
+	filenames := []string{conf.DefaultToolVersionsFilename}
+
+	legacyFiles, err := conf.LegacyVersionFile()
+	if err != nil || !legacyFiles {
+		return filenames, err
+	}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return filenames, err
+	}
+
+	for _, plugin := range allPlugins {
+		legacyFilenames, err := plugin.LegacyFilenames(context.Background())
+		if err != nil {
+			return filenames, err
+		}
+		filenames = append(filenames, legacyFilenames...)
+	}
+
+	return filenames, nil
+}
+
+func writeFile(filename string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, contents, 0o666)
+}
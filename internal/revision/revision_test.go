@@ -0,0 +1,120 @@
+package revision
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestCheckout(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	parent, err := filepath.EvalSymlinks(t.TempDir())
+	assert.Nil(t, err)
+	root := filepath.Join(parent, "repo")
+	subdir := filepath.Join(root, "src", "lib")
+	assert.Nil(t, os.MkdirAll(subdir, 0o777))
+	writeTestFile(t, parent, ".tool-versions", "lua 0.1.0\n")
+
+	runGit(t, root, "init")
+	writeTestFile(t, root, ".tool-versions", "lua 1.0.0\n")
+	writeTestFile(t, root, "README", "readme\n")
+	commit(t, root, "first")
+	writeTestFile(t, root, ".tool-versions", "lua 2.0.0\n")
+	writeTestFile(t, filepath.Join(root, "src"), ".tool-versions", "lua 2.1.0\n")
+	commit(t, root, "second")
+
+	t.Run("resolves versions set at the revision", func(t *testing.T) {
+		snapshot, err := Checkout(conf, subdir, "HEAD~1")
+		assert.Nil(t, err)
+		defer snapshot.Remove()
+
+		versions, found, err := resolve.Version(conf, plugin, snapshot.Path(subdir))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"1.0.0"}, versions.Versions)
+		assert.Equal(t, root, snapshot.Original(versions.Directory))
+		assert.Equal(t, root, snapshot.Root)
+		assert.NoFileExists(t, snapshot.Path(filepath.Join(root, "README")))
+	})
+
+	t.Run("resolves versions set in subdirectories at the revision", func(t *testing.T) {
+		snapshot, err := Checkout(conf, subdir, "HEAD")
+		assert.Nil(t, err)
+		defer snapshot.Remove()
+
+		versions, found, err := resolve.Version(conf, plugin, snapshot.Path(subdir))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"2.1.0"}, versions.Versions)
+	})
+
+	t.Run("copies version files above the repository from the file system", func(t *testing.T) {
+		assert.Nil(t, os.Remove(filepath.Join(root, ".tool-versions")))
+		runGit(t, root, "rm", "-q", "--cached", ".tool-versions")
+		commit(t, root, "third")
+
+		snapshot, err := Checkout(conf, root, "HEAD")
+		assert.Nil(t, err)
+		defer snapshot.Remove()
+
+		versions, found, err := resolve.Version(conf, plugin, snapshot.Path(root))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"0.1.0"}, versions.Versions)
+		assert.Equal(t, parent, snapshot.Original(versions.Directory))
+	})
+
+	t.Run("returns error when ref does not exist", func(t *testing.T) {
+		_, err := Checkout(conf, root, "non-existent")
+		assert.ErrorContains(t, err, "unknown revision non-existent")
+	})
+
+	t.Run("returns error when directory is not in a repository", func(t *testing.T) {
+		_, err := Checkout(conf, t.TempDir(), "HEAD")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestOriginal(t *testing.T) {
+	snapshot := Snapshot{Dir: "/tmp/asdf-revision-123"}
+
+	assert.Equal(t, "/home/user/project", snapshot.Original(snapshot.Path("/home/user/project")))
+	assert.Equal(t, "/home/user/.asdf", snapshot.Original("/home/user/.asdf"))
+}
+
+func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
+	t.Helper()
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf, plugins.New(conf, testPluginName)
+}
+
+func commit(t *testing.T, dir, message string) {
+	t.Helper()
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.name=asdf", "-c", "user.email=asdf@example.com", "commit", "-q", "-m", message)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	assert.Nil(t, err)
+	return string(output)
+}
+
+func writeTestFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o666))
+}
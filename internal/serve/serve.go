@@ -0,0 +1,201 @@
+// Package serve exposes read-only asdf queries over HTTP, so build executors
+// sharing a data directory can ask one long running asdf process instead of
+// each starting the CLI.
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// Resolved is the versions of a tool set for a directory and where they are
+// set
+type Resolved struct {
+	Tool      string   `json:"tool"`
+	Versions  []string `json:"versions"`
+	Source    string   `json:"source"`
+	Directory string   `json:"directory,omitempty"`
+}
+
+// Artifact is the location of a tool version in the data directory
+type Artifact struct {
+	Tool         string `json:"tool"`
+	Version      string `json:"version"`
+	Installed    bool   `json:"installed"`
+	InstallPath  string `json:"install_path"`
+	DownloadPath string `json:"download_path,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer returns a server serving Handler on the address, with timeouts so
+// slow or idle clients can't hold connections open
+func NewServer(conf config.Config, address string) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           Handler(conf),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// Handler returns a handler serving the read-only endpoints:
+//
+//	GET /resolve?dir=<path>[&tool=<name>]     versions set for a directory
+//	GET /installed[?tool=<name>]              installed versions of tools
+//	GET /artifact?tool=<name>&version=<ver>   install and download paths
+func Handler(conf config.Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /resolve", func(w http.ResponseWriter, r *http.Request) {
+		dir := r.URL.Query().Get("dir")
+		if !filepath.IsAbs(dir) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "dir must be an absolute path"})
+			return
+		}
+
+		selected, err := selectPlugins(conf, r.URL.Query().Get("tool"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		resolved := []Resolved{}
+		for _, plugin := range selected {
+			versions, found, err := resolve.VersionContext(r.Context(), conf, plugin, dir)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			if found {
+				resolved = append(resolved, Resolved{Tool: plugin.Name, Versions: versions.Versions, Source: versions.Source, Directory: versions.Directory})
+			}
+		}
+
+		writeJSON(w, http.StatusOK, resolved)
+	})
+
+	mux.HandleFunc("GET /installed", func(w http.ResponseWriter, r *http.Request) {
+		selected, err := selectPlugins(conf, r.URL.Query().Get("tool"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		installed := map[string][]string{}
+		for _, plugin := range selected {
+			versions, err := installs.Installed(conf, plugin)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			installed[plugin.Name] = versions
+		}
+
+		writeJSON(w, http.StatusOK, installed)
+	})
+
+	mux.HandleFunc("GET /artifact", func(w http.ResponseWriter, r *http.Request) {
+		tool, versionStr := r.URL.Query().Get("tool"), r.URL.Query().Get("version")
+		if tool == "" || versionStr == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "tool and version must be given"})
+			return
+		}
+
+		selected, err := selectPlugins(conf, tool)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		// Versions come from requests too, path versions and separators would
+		// tell whether any path exists
+		version := toolversions.Parse(versionStr)
+		if version.Type == "path" || strings.ContainsAny(versionStr, `/\`) || strings.Contains(versionStr, "..") {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid version: %q", versionStr)})
+			return
+		}
+
+		plugin := selected[0]
+		artifact := Artifact{
+			Tool:        plugin.Name,
+			Version:     versionStr,
+			Installed:   installs.IsInstalled(conf, plugin, version),
+			InstallPath: installs.InstallPath(conf, plugin, version),
+		}
+
+		if downloadPath := installs.DownloadPath(conf, plugin, version); exists(downloadPath) {
+			artifact.DownloadPath = downloadPath
+		}
+
+		writeJSON(w, http.StatusOK, artifact)
+	})
+
+	return mux
+}
+
+// selectPlugins returns the named plugin, or every installed plugin when no
+// name is given
+func selectPlugins(conf config.Config, name string) ([]plugins.Plugin, error) {
+	if name == "" {
+		return plugins.List(conf, false, false)
+	}
+
+	// Tool names come from requests, don't let them reach outside the data dir
+	if plugins.ValidateName(name) != nil {
+		return nil, invalidToolError{name: name}
+	}
+
+	plugin := plugins.New(conf, name)
+	if err := plugin.Exists(); err != nil {
+		return nil, err
+	}
+
+	return []plugins.Plugin{plugin}, nil
+}
+
+type invalidToolError struct {
+	name string
+}
+
+func (e invalidToolError) Error() string {
+	return fmt.Sprintf("invalid tool name: %q", e.name)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch err.(type) {
+	case plugins.PluginMissing:
+		status = http.StatusNotFound
+	case invalidToolError:
+		status = http.StatusBadRequest
+	}
+
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
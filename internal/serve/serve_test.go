@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installtest"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestHandler(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	assert.Nil(t, installtest.InstallOneVersion(conf, plugin, "version", "1.0.0"))
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, ".tool-versions"), []byte("lua 1.0.0\n"), 0o666))
+	handler := Handler(conf)
+
+	t.Run("resolve returns versions set for directory", func(t *testing.T) {
+		var resolved []Resolved
+		status := get(t, handler, "/resolve?dir="+url.QueryEscape(filepath.Join(dir, "subdir")), &resolved)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []Resolved{{Tool: testPluginName, Versions: []string{"1.0.0"}, Source: ".tool-versions", Directory: dir}}, resolved)
+	})
+
+	t.Run("resolve returns bad request for relative directory", func(t *testing.T) {
+		status := get(t, handler, "/resolve?dir=project", nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("installed returns installed versions of each tool", func(t *testing.T) {
+		var installed map[string][]string
+		status := get(t, handler, "/installed", &installed)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, map[string][]string{testPluginName: {"1.0.0"}}, installed)
+	})
+
+	t.Run("installed returns not found for unknown tool", func(t *testing.T) {
+		status := get(t, handler, "/installed?tool=unknown", nil)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("installed returns bad request for tool names outside the data dir", func(t *testing.T) {
+		status := get(t, handler, "/installed?tool="+url.QueryEscape("../installs"), nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("artifact returns install path of version", func(t *testing.T) {
+		var artifact Artifact
+		status := get(t, handler, "/artifact?tool=lua&version=1.0.0", &artifact)
+		assert.Equal(t, http.StatusOK, status)
+		assert.True(t, artifact.Installed)
+		assert.Equal(t, filepath.Join(conf.DataDir, "installs", testPluginName, "1.0.0"), artifact.InstallPath)
+	})
+
+	t.Run("artifact returns bad request without version", func(t *testing.T) {
+		status := get(t, handler, "/artifact?tool=lua", nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("artifact returns bad request for path versions", func(t *testing.T) {
+		for _, version := range []string{"path:" + dir, "../../lua/1.0.0", "1.0.0/..", `1\0`} {
+			status := get(t, handler, "/artifact?tool=lua&version="+url.QueryEscape(version), nil)
+			assert.Equal(t, http.StatusBadRequest, status, version)
+		}
+	})
+
+	t.Run("rejects requests that aren't reads", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/installed", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+}
+
+func get(t *testing.T, handler http.Handler, target string, value any) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	if value != nil {
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), value))
+	}
+
+	return recorder.Code
+}
+
+func generateConfig(t *testing.T) (config.Config, plugins.Plugin) {
+	t.Helper()
+	conf, err := config.LoadConfig()
+	assert.Nil(t, err)
+	conf.DataDir = t.TempDir()
+
+	_, err = repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+
+	return conf, plugins.New(conf, testPluginName)
+}
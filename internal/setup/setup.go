@@ -0,0 +1,202 @@
+// Package setup contains the steps of the `asdf setup` first-run wizard:
+// detecting the user's shell and writing the lines activating asdf to its RC
+// file, finding versions set by other version managers so they can be imported,
+// and checking the resulting installation for problems.
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// marker is written above the activation lines so they are only written once
+const marker = "# Added by asdf setup"
+
+// UnsupportedShellError is returned when asdf setup can't configure the user's
+// shell
+type UnsupportedShellError struct {
+	shell string
+}
+
+func (e UnsupportedShellError) Error() string {
+	return fmt.Sprintf("unable to configure %s automatically, see https://asdf-vm.com/guide/getting-started.html", e.shell)
+}
+
+// Shell is a shell asdf setup can write activation lines for
+type Shell struct {
+	Name   string
+	RCFile string
+	Lines  []string
+}
+
+// DetectShell returns the shell at shellPath, usually the value of $SHELL, and
+// the lines needed to add the shims in dataDir to the PATH in it
+func DetectShell(shellPath, home, dataDir string) (Shell, error) {
+	name := filepath.Base(shellPath)
+
+	var dataDirLine string
+	if dataDir != filepath.Join(home, ".asdf") {
+		dataDirLine = fmt.Sprintf("export ASDF_DATA_DIR=%q", dataDir)
+	}
+
+	switch name {
+	case "bash", "zsh":
+		rcFile := filepath.Join(home, "."+name+"rc")
+		if zdotdir := os.Getenv("ZDOTDIR"); name == "zsh" && zdotdir != "" {
+			rcFile = filepath.Join(zdotdir, ".zshrc")
+		}
+
+		lines := []string{`export PATH="${ASDF_DATA_DIR:-$HOME/.asdf}/shims:$PATH"`}
+		if dataDirLine != "" {
+			lines = slices.Insert(lines, 0, dataDirLine)
+		}
+		return Shell{Name: name, RCFile: rcFile, Lines: lines}, nil
+	case "fish":
+		shims := `"$HOME/.asdf/shims"`
+		if dataDirLine != "" {
+			shims = fmt.Sprintf("%q", filepath.Join(dataDir, "shims"))
+		}
+		lines := []string{
+			fmt.Sprintf("if not contains %s $PATH", shims),
+			fmt.Sprintf("    set -gx --prepend PATH %s", shims),
+			"end",
+		}
+		if dataDirLine != "" {
+			lines = slices.Insert(lines, 0, fmt.Sprintf("set -gx ASDF_DATA_DIR %q", dataDir))
+		}
+		return Shell{Name: name, RCFile: filepath.Join(home, ".config", "fish", "config.fish"), Lines: lines}, nil
+	default:
+		return Shell{Name: name}, UnsupportedShellError{shell: name}
+	}
+}
+
+// Activated returns true if the shell's RC file already contains the
+// activation lines, either written by asdf setup or added by hand
+func (s Shell) Activated() (bool, error) {
+	contents, err := os.ReadFile(s.RCFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if strings.Contains(string(contents), marker) {
+		return true, nil
+	}
+
+	for _, line := range s.Lines {
+		if strings.Contains(line, "shims") && strings.Contains(string(contents), strings.TrimSpace(line)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Activate appends the activation lines to the shell's RC file
+func (s Shell) Activate() error {
+	if err := os.MkdirAll(filepath.Dir(s.RCFile), 0o777); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.RCFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "\n%s\n%s\n", marker, strings.Join(s.Lines, "\n"))
+	return err
+}
+
+// Existing is a tool version set by another version manager
+type Existing struct {
+	Manager string
+	toolversions.ToolVersions
+}
+
+// ExistingVersions returns the default versions set with nvm and pyenv, which
+// are read from $NVM_DIR/alias/default and $PYENV_ROOT/version. Aliases such as
+// lts/* that can't be used as asdf versions are skipped.
+func ExistingVersions(home string) (existing []Existing, err error) {
+	nvmDir := os.Getenv("NVM_DIR")
+	if nvmDir == "" {
+		nvmDir = filepath.Join(home, ".nvm")
+	}
+
+	nvmVersions, err := readVersionFile(filepath.Join(nvmDir, "alias", "default"))
+	if err != nil {
+		return existing, err
+	}
+
+	if len(nvmVersions) > 0 {
+		version := strings.TrimPrefix(nvmVersions[0], "v")
+		if version != "" && version[0] >= '0' && version[0] <= '9' {
+			existing = append(existing, Existing{Manager: "nvm", ToolVersions: toolversions.ToolVersions{Name: "nodejs", Versions: []string{version}}})
+		}
+	}
+
+	pyenvRoot := os.Getenv("PYENV_ROOT")
+	if pyenvRoot == "" {
+		pyenvRoot = filepath.Join(home, ".pyenv")
+	}
+
+	pyenvVersions, err := readVersionFile(filepath.Join(pyenvRoot, "version"))
+	if err != nil {
+		return existing, err
+	}
+
+	if len(pyenvVersions) > 0 {
+		existing = append(existing, Existing{Manager: "pyenv", ToolVersions: toolversions.ToolVersions{Name: "python", Versions: pyenvVersions}})
+	}
+
+	return existing, nil
+}
+
+func readVersionFile(path string) (versions []string, err error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return versions, nil
+	}
+
+	if err != nil {
+		return versions, err
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			versions = append(versions, line)
+		}
+	}
+
+	return versions, nil
+}
+
+// Check returns the problems found with the asdf installation using dataDir
+// when run with pathEnv as the PATH
+func Check(dataDir, pathEnv string) (problems []string) {
+	shims := filepath.Join(dataDir, "shims")
+	if !slices.Contains(filepath.SplitList(pathEnv), shims) {
+		problems = append(problems, fmt.Sprintf("shims directory %s is not on PATH", shims))
+	}
+
+	if err := os.MkdirAll(dataDir, 0o777); err != nil {
+		problems = append(problems, fmt.Sprintf("data directory %s can't be created: %s", dataDir, err))
+	} else if file, err := os.CreateTemp(dataDir, ".setup-check-"); err != nil {
+		problems = append(problems, fmt.Sprintf("data directory %s is not writable: %s", dataDir, err))
+	} else {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	return problems
+}
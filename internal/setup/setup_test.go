@@ -0,0 +1,134 @@
+package setup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectShell(t *testing.T) {
+	t.Setenv("ZDOTDIR", "")
+
+	t.Run("returns bash RC file and PATH line", func(t *testing.T) {
+		shell, err := DetectShell("/bin/bash", "/home/user", "/home/user/.asdf")
+		assert.Nil(t, err)
+		assert.Equal(t, Shell{
+			Name:   "bash",
+			RCFile: "/home/user/.bashrc",
+			Lines:  []string{`export PATH="${ASDF_DATA_DIR:-$HOME/.asdf}/shims:$PATH"`},
+		}, shell)
+	})
+
+	t.Run("exports custom data directory", func(t *testing.T) {
+		shell, err := DetectShell("/usr/bin/zsh", "/home/user", "/data/asdf")
+		assert.Nil(t, err)
+		assert.Equal(t, "/home/user/.zshrc", shell.RCFile)
+		assert.Equal(t, []string{`export ASDF_DATA_DIR="/data/asdf"`, `export PATH="${ASDF_DATA_DIR:-$HOME/.asdf}/shims:$PATH"`}, shell.Lines)
+	})
+
+	t.Run("uses ZDOTDIR for zsh when set", func(t *testing.T) {
+		t.Setenv("ZDOTDIR", "/home/user/.config/zsh")
+		shell, err := DetectShell("/usr/bin/zsh", "/home/user", "/home/user/.asdf")
+		assert.Nil(t, err)
+		assert.Equal(t, "/home/user/.config/zsh/.zshrc", shell.RCFile)
+	})
+
+	t.Run("returns fish config file", func(t *testing.T) {
+		shell, err := DetectShell("/usr/bin/fish", "/home/user", "/home/user/.asdf")
+		assert.Nil(t, err)
+		assert.Equal(t, "/home/user/.config/fish/config.fish", shell.RCFile)
+		assert.Equal(t, `    set -gx --prepend PATH "$HOME/.asdf/shims"`, shell.Lines[1])
+	})
+
+	t.Run("returns UnsupportedShellError for other shells", func(t *testing.T) {
+		_, err := DetectShell("/usr/bin/elvish", "/home/user", "/home/user/.asdf")
+		assert.IsType(t, UnsupportedShellError{}, err)
+	})
+}
+
+func TestActivate(t *testing.T) {
+	home := t.TempDir()
+	shell, err := DetectShell("/bin/bash", home, filepath.Join(home, ".asdf"))
+	assert.Nil(t, err)
+
+	t.Run("returns false when RC file is missing", func(t *testing.T) {
+		activated, err := shell.Activated()
+		assert.Nil(t, err)
+		assert.False(t, activated)
+	})
+
+	t.Run("appends activation lines to RC file", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(shell.RCFile, []byte("alias ll='ls -l'\n"), 0o666))
+		assert.Nil(t, shell.Activate())
+
+		contents, err := os.ReadFile(shell.RCFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "alias ll='ls -l'\n\n# Added by asdf setup\nexport PATH=\"${ASDF_DATA_DIR:-$HOME/.asdf}/shims:$PATH\"\n", string(contents))
+
+		activated, err := shell.Activated()
+		assert.Nil(t, err)
+		assert.True(t, activated)
+	})
+
+	t.Run("returns true when lines were added by hand", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(shell.RCFile, []byte("  export PATH=\"${ASDF_DATA_DIR:-$HOME/.asdf}/shims:$PATH\"\n"), 0o666))
+
+		activated, err := shell.Activated()
+		assert.Nil(t, err)
+		assert.True(t, activated)
+	})
+}
+
+func TestExistingVersions(t *testing.T) {
+	t.Setenv("NVM_DIR", "")
+	t.Setenv("PYENV_ROOT", "")
+
+	t.Run("returns nothing when no other managers are used", func(t *testing.T) {
+		existing, err := ExistingVersions(t.TempDir())
+		assert.Nil(t, err)
+		assert.Empty(t, existing)
+	})
+
+	t.Run("returns nvm and pyenv default versions", func(t *testing.T) {
+		home := t.TempDir()
+		assert.Nil(t, os.MkdirAll(filepath.Join(home, ".nvm", "alias"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(home, ".nvm", "alias", "default"), []byte("v18.17.0\n"), 0o666))
+		assert.Nil(t, os.MkdirAll(filepath.Join(home, ".pyenv"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(home, ".pyenv", "version"), []byte("3.12.1\n2.7.18\n"), 0o666))
+
+		existing, err := ExistingVersions(home)
+		assert.Nil(t, err)
+		assert.Equal(t, []Existing{
+			{Manager: "nvm", ToolVersions: toolversions.ToolVersions{Name: "nodejs", Versions: []string{"18.17.0"}}},
+			{Manager: "pyenv", ToolVersions: toolversions.ToolVersions{Name: "python", Versions: []string{"3.12.1", "2.7.18"}}},
+		}, existing)
+	})
+
+	t.Run("skips nvm aliases", func(t *testing.T) {
+		nvmDir := t.TempDir()
+		t.Setenv("NVM_DIR", nvmDir)
+		assert.Nil(t, os.MkdirAll(filepath.Join(nvmDir, "alias"), 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(nvmDir, "alias", "default"), []byte("lts/*\n"), 0o666))
+
+		existing, err := ExistingVersions(t.TempDir())
+		assert.Nil(t, err)
+		assert.Empty(t, existing)
+	})
+}
+
+func TestCheck(t *testing.T) {
+	dataDir := t.TempDir()
+
+	t.Run("returns no problems when shims are on PATH", func(t *testing.T) {
+		problems := Check(dataDir, "/usr/bin"+string(os.PathListSeparator)+filepath.Join(dataDir, "shims"))
+		assert.Empty(t, problems)
+	})
+
+	t.Run("returns problem when shims are not on PATH", func(t *testing.T) {
+		problems := Check(dataDir, "/usr/bin")
+		assert.Equal(t, []string{"shims directory " + filepath.Join(dataDir, "shims") + " is not on PATH"}, problems)
+	})
+}
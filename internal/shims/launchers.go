@@ -0,0 +1,145 @@
+package shims
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+// LauncherPath returns the path of the launcher for an executable of a version
+func LauncherPath(conf config.Config, executableName, version string) string {
+	return filepath.Join(data.LaunchersDirectory(conf.DataDir), executableName+"@"+version)
+}
+
+// GenerateLaunchers brings the launchers directory up to date when the
+// launchers setting is enabled. Shims resolve the version to run from the
+// current directory and need the shims directory on PATH, neither of which
+// holds in shebangs run by cron or systemd. Launchers are symlinks to the
+// executables of installed versions instead, so they can be used as
+// interpreters with an absolute path. Each executable of an installed version
+// gets a launcher named <executable>@<version>, e.g. python@3.12.1, and one
+// named <executable>@<major>.<minor>, e.g. python@3.12, pointing at the newest
+// stable version installed in that release line. Launchers of versions no
+// longer installed are removed.
+func GenerateLaunchers(conf config.Config) error {
+	if enabled, err := conf.Launchers(); err != nil || !enabled {
+		return err
+	}
+
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return err
+	}
+
+	targets := map[string]string{}
+	for _, plugin := range allPlugins {
+		if err := launcherTargets(conf, plugin, targets); err != nil {
+			return err
+		}
+	}
+
+	return writeLaunchers(data.LaunchersDirectory(conf.DataDir), targets)
+}
+
+// launcherTargets adds the launchers of every installed version of a tool to
+// targets, mapping launcher names to the executables they point at. Versions
+// are visited from oldest to newest so the newest version of each release line
+// is kept.
+func launcherTargets(conf config.Config, plugin plugins.Plugin, targets map[string]string) error {
+	installedVersions, err := installs.Installed(conf, plugin)
+	if err != nil {
+		return err
+	}
+
+	for _, versionStr := range installedVersions {
+		version := toolversions.Parse(versionStr)
+		if version.Type != "version" {
+			continue
+		}
+
+		executables, err := ToolExecutables(conf, plugin, version)
+		if err != nil {
+			// A broken install doesn't stop launchers being generated for the
+			// other versions
+			continue
+		}
+
+		releaseLine := launcherReleaseLine(version.Value)
+		seen := map[string]bool{}
+		for _, executable := range executables {
+			name := filepath.Base(executable)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			targets[name+"@"+version.Value] = executable
+			if releaseLine != "" {
+				targets[name+"@"+releaseLine] = executable
+			}
+		}
+	}
+
+	return nil
+}
+
+// launcherReleaseLine returns the major and minor version of a stable version,
+// or an empty string for pre-releases and versions without a minor version
+func launcherReleaseLine(version string) string {
+	parsed := versionspec.Parse(version)
+	if len(parsed.Release) < 2 || parsed.Prerelease() {
+		return ""
+	}
+
+	return parsed.Prefix + strings.Join(parsed.Release[:2], ".")
+}
+
+// writeLaunchers makes the launchers directory hold exactly the given
+// launchers. Each symlink is replaced with a rename, so a script started
+// during a reshim never finds a launcher missing.
+func writeLaunchers(directory string, targets map[string]string) error {
+	if err := os.MkdirAll(directory, 0o777); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if _, ok := targets[entry.Name()]; !ok {
+			if err := os.Remove(filepath.Join(directory, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	for name, target := range targets {
+		path := filepath.Join(directory, name)
+		if current, err := os.Readlink(path); err == nil && current == target {
+			continue
+		}
+
+		tmpPath := filepath.Join(directory, "."+name+".tmp")
+		_ = os.Remove(tmpPath)
+		if err := os.Symlink(target, tmpPath); err != nil {
+			return err
+		}
+
+		if err := os.Rename(tmpPath, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
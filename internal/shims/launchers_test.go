@@ -0,0 +1,62 @@
+package shims
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateLaunchers(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("launchers = yes\n"), 0o666))
+	for _, version := range []string{"1.1.0", "1.1.2", "1.2.0-rc1", "2.0.0"} {
+		installVersion(t, conf, plugin, version)
+	}
+	executable := func(version string) string {
+		return filepath.Join(installs.InstallPath(conf, plugin, toolversions.Version{Type: "version", Value: version}), "bin", "dummy")
+	}
+
+	t.Run("creates launcher for every version and release line", func(t *testing.T) {
+		assert.Nil(t, GenerateLaunchers(conf))
+
+		for launcher, version := range map[string]string{
+			"1.1.0":     "1.1.0",
+			"1.1.2":     "1.1.2",
+			"1.1":       "1.1.2",
+			"1.2.0-rc1": "1.2.0-rc1",
+			"2.0.0":     "2.0.0",
+			"2.0":       "2.0.0",
+		} {
+			target, err := os.Readlink(LauncherPath(conf, "dummy", launcher))
+			assert.Nil(t, err)
+			assert.Equal(t, executable(version), target)
+		}
+		assert.NoFileExists(t, LauncherPath(conf, "dummy", "1.2"))
+	})
+
+	t.Run("updates release line launchers and removes launchers of uninstalled versions", func(t *testing.T) {
+		assert.Nil(t, os.RemoveAll(filepath.Dir(filepath.Dir(executable("1.1.2")))))
+		assert.Nil(t, GenerateLaunchers(conf))
+
+		target, err := os.Readlink(LauncherPath(conf, "dummy", "1.1"))
+		assert.Nil(t, err)
+		assert.Equal(t, executable("1.1.0"), target)
+		_, err = os.Lstat(LauncherPath(conf, "dummy", "1.1.2"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("does nothing when launchers are disabled", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("launchers = no\n"), 0o666))
+		conf.Settings.Loaded = false
+		installVersion(t, conf, plugin, "3.0.0")
+
+		assert.Nil(t, GenerateLaunchers(conf))
+		_, err := os.Lstat(LauncherPath(conf, "dummy", "3.0.0"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
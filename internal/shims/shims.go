@@ -2,19 +2,26 @@
 package shims
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"sync"
 
+	"github.com/asdf-vm/asdf/internal/callbackenv"
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
 	"github.com/asdf-vm/asdf/internal/hook"
 	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/overrides"
 	"github.com/asdf-vm/asdf/internal/paths"
 	"github.com/asdf-vm/asdf/internal/plugins"
 	"github.com/asdf-vm/asdf/internal/resolve"
@@ -22,7 +29,10 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-const shimDirName = "shims"
+const (
+	shimDirName      = "shims"
+	binPathsCallback = "list-bin-paths"
+)
 
 // UnknownCommandError is an error returned when a shim is not found
 type UnknownCommandError struct {
@@ -54,21 +64,84 @@ func (e NoExecutableForPluginError) Error() string {
 	return fmt.Sprintf("No %s executable found for %s %s", e.shim, strings.Join(e.tools, ", "), strings.Join(e.versions, ", "))
 }
 
+type pluginToolVersions struct {
+	plugin       plugins.Plugin
+	toolVersions resolve.ToolVersions
+	// resolved holds the versions as resolved, before they're narrowed to
+	// the versions the shim was generated for
+	resolved resolve.ToolVersions
+}
+
+// preferClosest keeps a single plugin for each tool provided by plugins in
+// more than one namespace, the one whose version was set closest to the
+// directory, so a project can use a fork of a tool set elsewhere
+func preferClosest(candidates []pluginToolVersions) (kept []pluginToolVersions) {
+	for _, candidate := range candidates {
+		index := slices.IndexFunc(kept, func(existing pluginToolVersions) bool {
+			return existing.plugin.ToolName() == candidate.plugin.ToolName()
+		})
+
+		if index < 0 {
+			kept = append(kept, candidate)
+		} else if resolutionDepth(candidate.toolVersions) > resolutionDepth(kept[index].toolVersions) {
+			kept[index] = candidate
+		}
+	}
+
+	return kept
+}
+
+// resolutionDepth returns how close to the directory versions were set.
+// Environment variables are closest, followed by directory overrides and then
+// by files in deeper directories.
+func resolutionDepth(toolVersions resolve.ToolVersions) int {
+	switch {
+	case toolVersions.Directory == "":
+		return math.MaxInt
+	case toolVersions.Source == overrides.Filename:
+		return math.MaxInt - 1
+	}
+
+	return strings.Count(filepath.Clean(toolVersions.Directory), string(filepath.Separator))
+}
+
 // FindExecutable takes a shim name and a current directory and returns the path
 // to the executable that the shim resolves to.
 func FindExecutable(conf config.Config, shimName, currentDirectory string) (path string, plugin plugins.Plugin, version string, found bool, err error) {
+	path, plugin, version, _, found, err = FindResolvedExecutable(conf, shimName, currentDirectory)
+	return path, plugin, version, found, err
+}
+
+// FindResolvedExecutable returns the executable the shim resolves to like
+// FindExecutable, along with the versions resolved for its tool, so callers
+// don't resolve them again and run the resolve hooks twice. The resolved
+// versions are empty for shim templates and system fallbacks, which don't
+// come from versions set.
+func FindResolvedExecutable(conf config.Config, shimName, currentDirectory string) (path string, plugin plugins.Plugin, version string, resolved resolve.ToolVersions, found bool, err error) {
 	shimPath := Path(conf, shimName)
 
 	if _, err := os.Stat(shimPath); err != nil {
-		return "", plugins.Plugin{}, "", false, UnknownCommandError{shim: shimName}
+		return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, UnknownCommandError{shim: shimName}
+	}
+
+	// Match strategies can be set by project asdfrc files
+	conf, err = conf.ForDirectory(currentDirectory)
+	if err != nil {
+		return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, err
 	}
 
 	toolVersions, err := GetToolsAndVersionsFromShimFile(shimPath)
 	if err != nil {
-		return "", plugins.Plugin{}, "", false, err
+		return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, err
 	}
 
-	existingPluginToolVersions := make(map[plugins.Plugin]resolve.ToolVersions)
+	if executableName, _, ok := splitVersionedShim(shimName, toolVersions); ok {
+		return findVersionedExecutable(conf, shimName, executableName, toolVersions, currentDirectory)
+	}
+
+	// A slice rather than a map so plugins are always checked in the order they
+	// are listed in the shim, which keeps the selected executable deterministic.
+	var existingPluginToolVersions []pluginToolVersions
 
 	// loop over tools and check if the plugin for them still exists
 	for _, shimToolVersion := range toolVersions {
@@ -77,22 +150,23 @@ func FindExecutable(conf config.Config, shimName, currentDirectory string) (path
 			// If a shim template is found, we can return it before looping through versions
 			shimTemplate, err := plugin.ShimTemplatePath(shimName)
 			if err == nil {
-				return shimTemplate, plugin, "", true, nil
+				return shimTemplate, plugin, "", resolve.ToolVersions{}, true, nil
 			}
 
 			versions, found, err := resolve.Version(conf, plugin, currentDirectory)
 			if err != nil {
-				return "", plugins.Plugin{}, "", false, nil
+				return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, err
 			}
 
 			if found {
+				resolved := versions
 				tempVersions := toolversions.Intersect(versions.Versions, shimToolVersion.Versions)
 				if len(tempVersions) == 0 {
 					tempVersions = []string{resolve.FindBestMatchingVersion(conf, plugin, versions.Versions)}
 				}
 
-				if slices.Contains(versions.Versions, "system") {
-					tempVersions = append(tempVersions, "system")
+				if slices.Contains(versions.Versions, toolversions.System) {
+					tempVersions = append(tempVersions, toolversions.System)
 				}
 
 				parsedVersions := toolversions.ParseSlice(versions.Versions)
@@ -104,22 +178,35 @@ func FindExecutable(conf config.Config, shimName, currentDirectory string) (path
 
 				versions.Versions = tempVersions
 				if len(versions.Versions) > 0 {
-					existingPluginToolVersions[plugin] = versions
+					existingPluginToolVersions = append(existingPluginToolVersions, pluginToolVersions{plugin: plugin, toolVersions: versions, resolved: resolved})
 				}
 			}
 		}
 	}
 
+	existingPluginToolVersions = preferClosest(existingPluginToolVersions)
+
 	if len(existingPluginToolVersions) == 0 {
-		return "", plugins.Plugin{}, "", false, NoVersionSetError{shim: shimName}
+		if fallback, _ := conf.SystemFallback(); fallback {
+			if executablePath, found := SystemExecutableOnPath(conf, shimName); found {
+				plugin := plugins.Plugin{}
+				if len(toolVersions) > 0 {
+					plugin = plugins.New(conf, toolVersions[0].Name)
+				}
+				return executablePath, plugin, toolversions.System, resolve.ToolVersions{}, true, nil
+			}
+		}
+
+		return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, NoVersionSetError{shim: shimName}
 	}
 
-	for plugin, toolVersions := range existingPluginToolVersions {
-		for _, version := range toolVersions.Versions {
+	for _, existing := range existingPluginToolVersions {
+		plugin := existing.plugin
+		for _, version := range existing.toolVersions.Versions {
 			parsedVersion := toolversions.Parse(version)
-			if parsedVersion.Type == "system" {
+			if parsedVersion.IsSystem() {
 				if executablePath, found := SystemExecutableOnPath(conf, shimName); found {
-					return executablePath, plugin, version, true, nil
+					return executablePath, plugin, version, existing.resolved, true, nil
 				}
 
 				break
@@ -128,7 +215,7 @@ func FindExecutable(conf config.Config, shimName, currentDirectory string) (path
 			if parsedVersion.Type == "path" {
 				executablePath, err := GetExecutablePath(conf, plugin, shimName, parsedVersion)
 				if err == nil {
-					return executablePath, plugin, version, true, nil
+					return executablePath, plugin, version, existing.resolved, true, nil
 				}
 
 				break
@@ -136,19 +223,19 @@ func FindExecutable(conf config.Config, shimName, currentDirectory string) (path
 
 			executablePath, err := GetExecutablePath(conf, plugin, shimName, parsedVersion)
 			if err == nil {
-				return executablePath, plugin, version, true, nil
+				return executablePath, plugin, version, existing.resolved, true, nil
 			}
 		}
 	}
 
 	tools := []string{}
 	versions := []string{}
-	for plugin, toolVersions := range existingPluginToolVersions {
-		tools = append(tools, plugin.Name)
-		versions = append(versions, toolVersions.Versions...)
+	for _, existing := range existingPluginToolVersions {
+		tools = append(tools, existing.plugin.Name)
+		versions = append(versions, existing.toolVersions.Versions...)
 	}
 
-	return "", plugins.Plugin{}, "", false, NoExecutableForPluginError{shim: shimName, tools: tools, versions: versions}
+	return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, NoExecutableForPluginError{shim: shimName, tools: tools, versions: versions}
 }
 
 // SystemExecutableOnPath returns the path to the system executable if found,
@@ -159,6 +246,50 @@ func SystemExecutableOnPath(conf config.Config, executableName string) (string,
 	return executablePath, err == nil
 }
 
+// SystemExecutable returns the path of the system version of the tool, the
+// first executable found on PATH outside the shims directory named after the
+// tool or after one of its shims. Tools are often named differently from
+// their executables, nodejs runs node, so the shims of the installed versions
+// tell which executables to look for.
+func SystemExecutable(conf config.Config, plugin plugins.Plugin) (string, bool) {
+	names, err := Names(conf, plugin)
+	if err != nil {
+		names = nil
+	}
+
+	for _, name := range append([]string{plugin.Name}, names...) {
+		if executablePath, found := SystemExecutableOnPath(conf, name); found {
+			return executablePath, true
+		}
+	}
+
+	return "", false
+}
+
+// Names returns the sorted names of the shims the tool has executables in
+func Names(conf config.Config, plugin plugins.Plugin) (names []string, err error) {
+	entries, err := os.ReadDir(Directory(conf))
+	if errors.Is(err, fs.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return names, err
+	}
+
+	for _, entry := range entries {
+		toolVersions, err := GetToolsAndVersionsFromShimFile(Path(conf, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		if slices.ContainsFunc(toolVersions, func(toolVersion toolversions.ToolVersions) bool { return toolVersion.Name == plugin.Name }) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
 // ExecutableOnPath returns the path to an executable if one is found on the
 // provided paths. `path` must be in the same format as the `PATH` environment
 // variable.
@@ -178,9 +309,12 @@ func GetExecutablePath(conf config.Config, plugin plugins.Plugin, shimName strin
 
 	executable := ""
 
+	// Use the first match, like PATH lookup in the environment the executable
+	// is run in, so the order of directories from list-bin-paths is respected
 	for _, executablePath := range executables {
 		if filepath.Base(executablePath) == shimName {
 			executable = executablePath
+			break
 		}
 	}
 
@@ -216,7 +350,7 @@ func getCustomExecutablePath(conf config.Config, plugin plugins.Plugin, shimName
 	var stdErr strings.Builder
 
 	installPath := installs.InstallPath(conf, plugin, version)
-	env := map[string]string{"ASDF_INSTALL_TYPE": "version"}
+	env := callbackenv.ForVersion(conf, plugin, version).Map()
 
 	relativePath, err := filepath.Rel(installPath, executablePath)
 	if err != nil {
@@ -252,54 +386,228 @@ func RemoveAll(conf config.Config) error {
 }
 
 // GenerateAll generates shims for all executables of every version of every
-// plugin.
+// plugin, and launchers when they are enabled.
 func GenerateAll(conf config.Config, stdOut io.Writer, stdErr io.Writer) error {
 	plugins, err := plugins.List(conf, false, false)
 	if err != nil {
 		return err
 	}
 
+	var targets []target
+	for _, plugin := range plugins {
+		pluginTargets, err := installedTargets(conf, plugin)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, pluginTargets...)
+	}
+
+	generate(conf, Directory(conf), targets, stdOut, stdErr)
+	return GenerateLaunchers(conf)
+}
+
+// Regenerate rebuilds the shims of every version of every plugin from scratch,
+// dropping shims of executables that no longer exist, like RemoveAll followed
+// by GenerateAll but without the window in which shims are missing. The new
+// shims are written to a staging directory and then swapped in, see swap, so
+// processes running shims while a build reshims always find them.
+func Regenerate(conf config.Config, stdOut io.Writer, stdErr io.Writer) error {
+	plugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return err
+	}
+
+	var targets []target
 	for _, plugin := range plugins {
-		err := GenerateForPluginVersions(conf, plugin, stdOut, stdErr)
+		pluginTargets, err := installedTargets(conf, plugin)
 		if err != nil {
 			return err
 		}
+		targets = append(targets, pluginTargets...)
+	}
+
+	// The staging directory is on the same filesystem as the shims directory
+	// so shims can be renamed into it
+	staging, err := os.MkdirTemp(conf.DataDir, "."+shimDirName+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	generate(conf, staging, targets, stdOut, stdErr)
+	if err := swap(staging, Directory(conf)); err != nil {
+		return err
+	}
+
+	return GenerateLaunchers(conf)
+}
+
+// swap renames every shim in the staging directory over the shim of the same
+// name in the shims directory, then removes the shims that weren't staged. A
+// rename replaces a file atomically, so a shim that is kept is never missing
+// or partly written, even for a moment.
+func swap(staging, shimDir string) error {
+	if err := os.MkdirAll(shimDir, 0o777); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+
+	staged := map[string]bool{}
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(staging, entry.Name()), filepath.Join(shimDir, entry.Name())); err != nil {
+			return err
+		}
+		staged[entry.Name()] = true
+	}
+
+	entries, err = os.ReadDir(shimDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !staged[entry.Name()] {
+			os.RemoveAll(filepath.Join(shimDir, entry.Name()))
+		}
 	}
 
 	return nil
 }
 
 // GenerateForPluginVersions generates all shims for all installed versions of
-// a tool.
+// a tool, and launchers when they are enabled.
 func GenerateForPluginVersions(conf config.Config, plugin plugins.Plugin, stdOut io.Writer, stdErr io.Writer) error {
-	installedVersions, err := installs.Installed(conf, plugin)
+	targets, err := installedTargets(conf, plugin)
 	if err != nil {
 		return err
 	}
 
-	for _, version := range installedVersions {
-		parsedVersion := toolversions.Parse(version)
-		GenerateForVersion(conf, plugin, parsedVersion, stdOut, stdErr)
-	}
-	return nil
+	generate(conf, Directory(conf), targets, stdOut, stdErr)
+	return GenerateLaunchers(conf)
 }
 
 // GenerateForVersion loops over all the executable files found for a tool and
-// generates a shim for each one
+// generates a shim for each one, then brings launchers up to date when they are
+// enabled
 func GenerateForVersion(conf config.Config, plugin plugins.Plugin, version toolversions.Version, stdOut io.Writer, stdErr io.Writer) error {
-	err := hook.RunWithOutput(conf, fmt.Sprintf("pre_asdf_reshim_%s", plugin.Name), []string{toolversions.Format(version)}, stdOut, stdErr)
+	if err := generateForVersion(conf, Directory(conf), target{plugin: plugin, version: version}, nil, stdOut, stdErr); err != nil {
+		return err
+	}
+
+	return GenerateLaunchers(conf)
+}
+
+// target is an installed version of a tool shims are generated for
+type target struct {
+	plugin  plugins.Plugin
+	version toolversions.Version
+}
+
+// scan holds the executables found in a version before its shims are written
+type scan struct {
+	executables []string
+	err         error
+}
+
+func installedTargets(conf config.Config, plugin plugins.Plugin) (targets []target, err error) {
+	installedVersions, err := installs.Installed(conf, plugin)
+	if err != nil {
+		return targets, err
+	}
+
+	for _, version := range installedVersions {
+		targets = append(targets, target{plugin: plugin, version: toolversions.Parse(version)})
+	}
+	return targets, nil
+}
+
+// generate writes the shims for every target to shimDir. Install directories are scanned
+// for executables in parallel, as with hundreds of versions installed scanning
+// them one at a time dominates the time taken. Shims are written and hooks are
+// run in order afterwards.
+func generate(conf config.Config, shimDir string, targets []target, stdOut io.Writer, stdErr io.Writer) {
+	scans := scanExecutables(conf, targets)
+	for i, target := range targets {
+		// Errors for a single version don't stop shims being generated for the
+		// others
+		_ = generateForVersion(conf, shimDir, target, scans[i], stdOut, stdErr)
+	}
+}
+
+// scanExecutables finds the executables of each target using a pool of
+// workers. Targets with a pre-reshim hook are skipped, as the hook may change
+// the install, and are scanned once the hook has run.
+func scanExecutables(conf config.Config, targets []target) []*scan {
+	scans := make([]*scan, len(targets))
+
+	var toScan []int
+	for i, target := range targets {
+		if hookCmd, _ := conf.GetHook(preReshimHook(target.plugin)); hookCmd == "" {
+			toScan = append(toScan, i)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(toScan)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				executables, err := ToolExecutables(conf, targets[i].plugin, targets[i].version)
+				scans[i] = &scan{executables: executables, err: err}
+			}
+		}()
+	}
+
+	for _, i := range toScan {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return scans
+}
+
+func generateForVersion(conf config.Config, shimDir string, target target, scanned *scan, stdOut io.Writer, stdErr io.Writer) error {
+	plugin, version := target.plugin, target.version
+	err := hook.RunWithOutput(conf, preReshimHook(plugin), []string{toolversions.Format(version)}, stdOut, stdErr)
 	if err != nil {
 		return err
 	}
-	executables, err := ToolExecutables(conf, plugin, version)
+
+	if scanned == nil {
+		executables, err := ToolExecutables(conf, plugin, version)
+		scanned = &scan{executables: executables, err: err}
+	}
+
+	if scanned.err != nil {
+		return scanned.err
+	}
+
+	if err := os.MkdirAll(shimDir, 0o777); err != nil {
+		return err
+	}
+
+	versioned, err := conf.VersionedShims()
 	if err != nil {
 		return err
 	}
 
-	for _, executablePath := range executables {
-		err := Write(conf, plugin, version, executablePath)
-		if err != nil {
-			return err
+	for _, executablePath := range scanned.executables {
+		shimNames := []string{filepath.Base(executablePath)}
+		if versioned {
+			shimNames = append(shimNames, versionedShimNames(filepath.Base(executablePath), version)...)
+		}
+
+		for _, shimName := range shimNames {
+			if err := write(shimDir, shimName, plugin, version); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -310,6 +618,10 @@ func GenerateForVersion(conf config.Config, plugin plugins.Plugin, version toolv
 	return nil
 }
 
+func preReshimHook(plugin plugins.Plugin) string {
+	return fmt.Sprintf("pre_asdf_reshim_%s", plugin.Name)
+}
+
 // Write generates a shim script and writes it to disk
 func Write(conf config.Config, plugin plugins.Plugin, version toolversions.Version, executablePath string) error {
 	err := ensureShimDirExists(conf)
@@ -317,8 +629,11 @@ func Write(conf config.Config, plugin plugins.Plugin, version toolversions.Versi
 		return err
 	}
 
-	shimName := filepath.Base(executablePath)
-	shimPath := Path(conf, shimName)
+	return write(Directory(conf), filepath.Base(executablePath), plugin, version)
+}
+
+func write(shimDir, shimName string, plugin plugins.Plugin, version toolversions.Version) error {
+	shimPath := filepath.Join(shimDir, shimName)
 	versions := []toolversions.ToolVersions{{Name: plugin.Name, Versions: []string{toolversions.Format(version)}}}
 
 	if _, err := os.Stat(shimPath); err == nil {
@@ -329,7 +644,109 @@ func Write(conf config.Config, plugin plugins.Plugin, version toolversions.Versi
 		versions = toolversions.Unique(append(versions, oldVersions...))
 	}
 
-	return os.WriteFile(shimPath, []byte(encode(shimName, versions)), 0o777)
+	return writeFile(shimPath, []byte(encode(shimName, versions)))
+}
+
+// writeFile writes the shim to a temporary file first and renames it into
+// place, so processes running the shim concurrently never read a partial one
+func writeFile(shimPath string, contents []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(shimPath), "."+filepath.Base(shimPath)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(contents)
+	if err == nil {
+		err = tmpFile.Chmod(0o755)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), shimPath)
+}
+
+// Missing returns the names of the executables of a tool version that have no
+// shim, or whose shim doesn't list the version. These are fixed by a reshim.
+func Missing(conf config.Config, plugin plugins.Plugin, version toolversions.Version) (names []string, err error) {
+	executables, err := ToolExecutables(conf, plugin, version)
+	if err != nil {
+		return names, err
+	}
+
+	formatted := toolversions.Format(version)
+	for _, executable := range executables {
+		shimName := filepath.Base(executable)
+		toolVersions, err := GetToolsAndVersionsFromShimFile(Path(conf, shimName))
+		if err != nil && !os.IsNotExist(err) {
+			return names, err
+		}
+
+		if !slices.ContainsFunc(toolVersions, func(tool toolversions.ToolVersions) bool {
+			return tool.Name == plugin.Name && slices.Contains(tool.Versions, formatted)
+		}) {
+			names = append(names, shimName)
+		}
+	}
+
+	return names, nil
+}
+
+// Damaged returns the names of the shims that don't hold what a reshim would
+// write for the versions they list, such as shims cut short by a reshim that
+// was killed. These are fixed by regenerating the shims.
+func Damaged(conf config.Config) (names []string, err error) {
+	entries, err := os.ReadDir(Directory(conf))
+	if errors.Is(err, fs.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return names, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		contents, err := os.ReadFile(filepath.Join(Directory(conf), entry.Name()))
+		if err != nil {
+			return names, err
+		}
+
+		versions := parse(string(contents))
+		if len(versions) == 0 || encode(entry.Name(), versions) != string(contents) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// Leftovers returns the paths of the staging directories and temporary files
+// left behind by reshims that were interrupted, see Regenerate and writeFile.
+// Shims and launchers are never hidden files, so every hidden file in their
+// directories is one.
+func Leftovers(conf config.Config) (paths []string, err error) {
+	patterns := []string{
+		filepath.Join(conf.DataDir, "."+shimDirName+"-*"),
+		filepath.Join(Directory(conf), ".*"),
+		filepath.Join(data.LaunchersDirectory(conf.DataDir), ".*"),
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	return paths, nil
 }
 
 // Path returns the path for a shim script
@@ -376,7 +793,7 @@ func ToolExecutables(conf config.Config, plugin plugins.Plugin, version toolvers
 // ExecutablePaths returns a slice of absolute directory paths that tool
 // executables are contained in.
 func ExecutablePaths(conf config.Config, plugin plugins.Plugin, version toolversions.Version) (paths []string, err error) {
-	dirs, err := ExecutableDirs(plugin)
+	dirs, err := cachedExecutableDirs(conf, plugin, version)
 	if err != nil {
 		return []string{}, err
 	}
@@ -396,7 +813,7 @@ func ExecutableDirs(plugin plugins.Plugin) ([]string, error) {
 	var stdOut strings.Builder
 	var stdErr strings.Builder
 
-	err := plugin.RunCallback("list-bin-paths", []string{}, map[string]string{}, &stdOut, &stdErr)
+	err := plugin.RunCallback(binPathsCallback, []string{}, map[string]string{}, &stdOut, &stdErr)
 	if err != nil {
 		if _, ok := err.(plugins.NoCallbackError); ok {
 			// assume all executables are located in /bin directory
@@ -417,6 +834,41 @@ func ExecutableDirs(plugin plugins.Plugin) ([]string, error) {
 	return dirs, nil
 }
 
+// cachedExecutableDirs returns the output of ExecutableDirs for a version,
+// caching it in the plugin's cache directory so the list-bin-paths callback
+// isn't run every time a version is reshimmed or executed. The cache is ignored
+// once the callback is modified, such as by a plugin update.
+func cachedExecutableDirs(conf config.Config, plugin plugins.Plugin, version toolversions.Version) ([]string, error) {
+	callback, err := plugin.CallbackPath(binPathsCallback)
+	if err != nil {
+		return ExecutableDirs(plugin)
+	}
+
+	callbackInfo, err := os.Stat(callback)
+	if err != nil {
+		return ExecutableDirs(plugin)
+	}
+
+	cacheFile := filepath.Join(data.CacheDirectory(conf.DataDir, plugin.Name), binPathsCallback, toolversions.FormatForFS(version))
+	if cacheInfo, err := os.Stat(cacheFile); err == nil && cacheInfo.ModTime().After(callbackInfo.ModTime()) {
+		if contents, err := os.ReadFile(cacheFile); err == nil {
+			return strings.Split(string(contents), "\n"), nil
+		}
+	}
+
+	dirs, err := ExecutableDirs(plugin)
+	if err != nil {
+		return dirs, err
+	}
+
+	// Failing to write the cache only makes the next lookup slower
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o777); err == nil {
+		_ = os.WriteFile(cacheFile, []byte(strings.Join(dirs, "\n")), 0o666)
+	}
+
+	return dirs, nil
+}
+
 func parse(contents string) (versions []toolversions.ToolVersions) {
 	lines := strings.Split(contents, "\n")
 
@@ -450,9 +902,27 @@ func encode(shimName string, toolVersions []toolversions.ToolVersions) string {
 	return content
 }
 
+// dirsToPaths joins the directories output by list-bin-paths to the install
+// root. Directories containing glob patterns, such as libexec/*/bin, are
+// expanded to every matching directory in lexical order.
 func dirsToPaths(dirs []string, root string) (paths []string) {
 	for _, dir := range dirs {
-		paths = append(paths, filepath.Join(root, dir))
+		path := filepath.Join(root, dir)
+		if !strings.ContainsAny(dir, "*?[") {
+			paths = append(paths, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			continue
+		}
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				paths = append(paths, match)
+			}
+		}
 	}
 
 	return paths
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/asdf-vm/asdf/internal/config"
 	"github.com/asdf-vm/asdf/internal/installs"
@@ -45,6 +46,22 @@ func TestFindExecutable(t *testing.T) {
 		assert.Equal(t, err.(NoVersionSetError).Error(), "no versions set for dummy")
 	})
 
+	t.Run("returns executable on PATH when no version is set and system_fallback is enabled", func(t *testing.T) {
+		fallbackConf := conf
+		fallbackConf.Settings = config.Settings{Loaded: true, SystemFallback: true}
+		systemDir := t.TempDir()
+		systemDummy := filepath.Join(systemDir, "dummy")
+		assert.Nil(t, os.WriteFile(systemDummy, []byte("#!/usr/bin/env bash\n"), 0o777))
+		t.Setenv("PATH", systemDir+":"+os.Getenv("PATH"))
+
+		executable, gotPlugin, version, found, err := FindExecutable(fallbackConf, "dummy", currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, systemDummy, executable)
+		assert.Equal(t, "system", version)
+		assert.Equal(t, plugin.Name, gotPlugin.Name)
+	})
+
 	t.Run("returns string containing path to executable when found", func(t *testing.T) {
 		// write a version file
 		data := []byte("lua 1.1.0")
@@ -59,6 +76,26 @@ func TestFindExecutable(t *testing.T) {
 		assert.Nil(t, err)
 	})
 
+	t.Run("returns versions resolved along with executable", func(t *testing.T) {
+		executable, _, version, resolved, found, err := FindResolvedExecutable(conf, "dummy", currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "dummy", filepath.Base(executable))
+		assert.Equal(t, "1.1.0", version)
+		assert.Equal(t, []string{"1.1.0"}, resolved.Versions)
+		assert.Equal(t, currentDir, resolved.Directory)
+	})
+
+	t.Run("returns error when resolve hook fails", func(t *testing.T) {
+		hookConf := conf
+		hookConf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(hookConf.ConfigFile, []byte("pre_asdf_resolve = echo refused >&2; exit 1\n"), 0o666))
+
+		_, _, _, found, err := FindExecutable(hookConf, "dummy", currentDir)
+		assert.False(t, found)
+		assert.ErrorContains(t, err, "failed to run pre_asdf_resolve hook")
+	})
+
 	t.Run("returns path to executable with first version when multiple versions are set", func(t *testing.T) {
 		// write a version file
 		data := []byte("lua 1.1.0 3.0.0 2.0.0")
@@ -136,6 +173,51 @@ func TestFindExecutable(t *testing.T) {
 	})
 }
 
+func TestFindExecutable_Namespaced(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	fork := installPlugin(t, conf, "dummy_plugin", "corp/"+testPluginName)
+	installVersion(t, conf, plugin, "1.1.0")
+	installVersion(t, conf, fork, "1.1.0-corp1")
+	stdout, stderr := buildOutputs()
+	assert.Nil(t, GenerateAll(conf, &stdout, &stderr))
+
+	parentDir := t.TempDir()
+	projectDir := filepath.Join(parentDir, "project")
+	assert.Nil(t, os.MkdirAll(projectDir, 0o777))
+	assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte("lua 1.1.0\n"), 0o666))
+
+	t.Run("returns executable of the plugin set for the directory", func(t *testing.T) {
+		executable, gotPlugin, version, found, err := FindExecutable(conf, "dummy", parentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, plugin.Name, gotPlugin.Name)
+		assert.Equal(t, "1.1.0", version)
+		assert.Equal(t, filepath.Join(conf.DataDir, "installs", "lua", "1.1.0", "bin", "dummy"), executable)
+	})
+
+	t.Run("returns executable of the fork set closer to the directory", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte("corp/lua 1.1.0-corp1\n"), 0o666))
+
+		executable, gotPlugin, version, found, err := FindExecutable(conf, "dummy", projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, fork.Name, gotPlugin.Name)
+		assert.Equal(t, "1.1.0-corp1", version)
+		assert.Equal(t, filepath.Join(conf.DataDir, "installs", "corp", "lua", "1.1.0-corp1", "bin", "dummy"), executable)
+	})
+
+	t.Run("returns executable of the plugin set closer to the directory than the fork", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(parentDir, ".tool-versions"), []byte("corp/lua 1.1.0-corp1\n"), 0o666))
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte("lua 1.1.0\n"), 0o666))
+
+		_, gotPlugin, version, found, err := FindExecutable(conf, "dummy", projectDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, plugin.Name, gotPlugin.Name)
+		assert.Equal(t, "1.1.0", version)
+	})
+}
+
 func TestFindExecutable_Ref(t *testing.T) {
 	version := "ref:v1.1.0"
 	conf, plugin := generateConfig(t)
@@ -257,6 +339,56 @@ func TestGenerateAll(t *testing.T) {
 	})
 }
 
+func TestRegenerate(t *testing.T) {
+	version := "1.1.0"
+	conf, plugin := generateConfig(t)
+	installVersion(t, conf, plugin, version)
+	executables, err := ToolExecutables(conf, plugin, toolversions.Version{Type: "version", Value: version})
+	assert.Nil(t, err)
+	stdout, stderr := buildOutputs()
+
+	t.Run("replaces shims and removes shims of executables that no longer exist", func(t *testing.T) {
+		assert.Nil(t, ensureShimDirExists(conf))
+		stalePath := Path(conf, "stale")
+		assert.Nil(t, os.WriteFile(stalePath, []byte("#!/usr/bin/env bash\n# asdf-plugin: lua 0.1.0\n"), 0o777))
+		assert.Nil(t, os.WriteFile(Path(conf, "dummy"), []byte("#!/usr/bin/env bash\n# asdf-plugin: lua 0.1.0\n"), 0o777))
+
+		assert.Nil(t, Regenerate(conf, &stdout, &stderr))
+
+		_, err := os.Stat(stalePath)
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+
+		for _, executable := range executables {
+			shimName := filepath.Base(executable)
+			shimPath := Path(conf, shimName)
+			assert.Nil(t, unix.Access(shimPath, unix.X_OK))
+
+			content, err := os.ReadFile(shimPath)
+			assert.Nil(t, err)
+			want := fmt.Sprintf("#!/usr/bin/env bash\n# asdf-plugin: lua 1.1.0\nexec asdf exec \"%s\" \"$@\"", shimName)
+			assert.Equal(t, want, string(content))
+		}
+	})
+
+	t.Run("leaves no staging directory or temporary files behind", func(t *testing.T) {
+		assert.Nil(t, Regenerate(conf, &stdout, &stderr))
+
+		staging, err := filepath.Glob(filepath.Join(conf.DataDir, ".shims-*"))
+		assert.Nil(t, err)
+		assert.Empty(t, staging)
+
+		temporary, err := filepath.Glob(filepath.Join(Directory(conf), ".*"))
+		assert.Nil(t, err)
+		assert.Empty(t, temporary)
+	})
+
+	t.Run("creates shims directory when it does not exist", func(t *testing.T) {
+		assert.Nil(t, os.RemoveAll(Directory(conf)))
+		assert.Nil(t, Regenerate(conf, &stdout, &stderr))
+		assert.Nil(t, unix.Access(Path(conf, "dummy"), unix.X_OK))
+	})
+}
+
 func TestGenerateForPluginVersions(t *testing.T) {
 	t.Setenv("ASDF_CONFIG_FILE", "testdata/asdfrc")
 	version := "1.1.0"
@@ -330,6 +462,35 @@ func TestGenerateForVersion(t *testing.T) {
 	})
 }
 
+func TestSystemExecutable(t *testing.T) {
+	version := toolversions.Version{Type: "version", Value: "1.1.0"}
+	conf, plugin := generateConfig(t)
+	installVersion(t, conf, plugin, version.Value)
+	stdout, stderr := buildOutputs()
+	assert.Nil(t, GenerateForVersion(conf, plugin, version, &stdout, &stderr))
+
+	names, err := Names(conf, plugin)
+	assert.Nil(t, err)
+	assert.Contains(t, names, "dummy")
+
+	t.Run("returns false when no executable of the tool is on PATH", func(t *testing.T) {
+		t.Setenv("PATH", Directory(conf))
+		_, found := SystemExecutable(conf, plugin)
+		assert.False(t, found)
+	})
+
+	t.Run("returns executable named after a shim of the tool on PATH outside the shims directory", func(t *testing.T) {
+		systemDir := t.TempDir()
+		executable := filepath.Join(systemDir, "dummy")
+		assert.Nil(t, os.WriteFile(executable, []byte("#!/usr/bin/env bash\n"), 0o755))
+		t.Setenv("PATH", Directory(conf)+":"+systemDir)
+
+		path, found := SystemExecutable(conf, plugin)
+		assert.True(t, found)
+		assert.Equal(t, executable, path)
+	})
+}
+
 func TestWrite(t *testing.T) {
 	version := toolversions.Version{Type: "version", Value: "1.1.0"}
 	version2 := toolversions.Version{Type: "version", Value: "2.0.0"}
@@ -485,6 +646,65 @@ func TestExecutablePaths(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, "plugins/lua/shims", relativePath)
 	})
+
+	t.Run("caches output of list-bin-paths until callback changes", func(t *testing.T) {
+		version := toolversions.Version{Type: "version", Value: "1.2.3"}
+		counter := filepath.Join(t.TempDir(), "calls")
+		callback := filepath.Join(plugin.Dir, "bin", "list-bin-paths")
+		data := []byte(fmt.Sprintf("echo called >> %q\necho 'foo'", counter))
+		assert.Nil(t, os.WriteFile(callback, data, 0o777))
+
+		for range 3 {
+			executables, err := ExecutablePaths(conf, plugin, version)
+			assert.Nil(t, err)
+			assert.Equal(t, "foo", filepath.Base(executables[len(executables)-1]))
+		}
+
+		calls, err := os.ReadFile(counter)
+		assert.Nil(t, err)
+		assert.Equal(t, "called\n", string(calls))
+
+		data = []byte("echo 'baz'")
+		assert.Nil(t, os.WriteFile(callback, data, 0o777))
+		future := time.Now().Add(time.Minute)
+		assert.Nil(t, os.Chtimes(callback, future, future))
+
+		executables, err := ExecutablePaths(conf, plugin, version)
+		assert.Nil(t, err)
+		assert.Equal(t, "baz", filepath.Base(executables[len(executables)-1]))
+	})
+}
+
+func TestExecutablePathsGlobs(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	installVersion(t, conf, plugin, "1.2.3")
+	version := toolversions.Version{Type: "version", Value: "1.2.3"}
+	installPath := installs.InstallPath(conf, plugin, version)
+
+	for _, jdk := range []string{"jdk21", "jdk17"} {
+		dir := filepath.Join(installPath, "libexec", jdk, "bin")
+		assert.Nil(t, os.MkdirAll(dir, 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, "java"), []byte("#!/usr/bin/env bash\n"), 0o777))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(installPath, "libexec", "README"), []byte{}, 0o666))
+	assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "list-bin-paths", "#!/usr/bin/env bash\necho 'libexec/*/bin libexec/*'\n"))
+
+	t.Run("expands glob patterns to matching directories", func(t *testing.T) {
+		paths, err := ExecutablePaths(conf, plugin, version)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(installPath, "libexec", "jdk17", "bin"),
+			filepath.Join(installPath, "libexec", "jdk21", "bin"),
+			filepath.Join(installPath, "libexec", "jdk17"),
+			filepath.Join(installPath, "libexec", "jdk21"),
+		}, paths)
+	})
+
+	t.Run("resolves executable to first matching directory", func(t *testing.T) {
+		path, err := GetExecutablePath(conf, plugin, "java", version)
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(installPath, "libexec", "jdk17", "bin", "java"), path)
+	})
 }
 
 func TestExecutableDirs(t *testing.T) {
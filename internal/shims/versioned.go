@@ -0,0 +1,116 @@
+package shims
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
+)
+
+// versionedShimNames returns the names of the versioned shims of an executable
+// of a version, generated when the versioned_shims setting is enabled. Like
+// launchers they are named <executable>@<version>, e.g. python@3.12.1, and
+// <executable>@<major>.<minor>, e.g. python@3.12. Versions that aren't plain
+// version numbers, like refs and paths, don't get versioned shims.
+func versionedShimNames(executableName string, version toolversions.Version) []string {
+	if version.Type != "version" {
+		return nil
+	}
+
+	names := []string{executableName + "@" + version.Value}
+	if releaseLine := launcherReleaseLine(version.Value); releaseLine != "" && releaseLine != version.Value {
+		names = append(names, executableName+"@"+releaseLine)
+	}
+	return names
+}
+
+// splitVersionedShim splits the name of a versioned shim into the name of the
+// executable and the version suffix. Executables may have an @ in their name,
+// so a shim is only taken to be versioned when every version it was generated
+// for matches the suffix.
+func splitVersionedShim(shimName string, shimToolVersions []toolversions.ToolVersions) (executableName, suffix string, ok bool) {
+	index := strings.LastIndex(shimName, "@")
+	if index <= 0 || index == len(shimName)-1 {
+		return "", "", false
+	}
+
+	executableName, suffix = shimName[:index], shimName[index+1:]
+	for _, shimToolVersion := range shimToolVersions {
+		for _, version := range shimToolVersion.Versions {
+			if version != suffix && launcherReleaseLine(version) != suffix {
+				return "", "", false
+			}
+		}
+	}
+
+	return executableName, suffix, true
+}
+
+// findVersionedExecutable returns the executable a versioned shim runs. The
+// first version set for the directory that the shim was generated for wins,
+// so `python@3.11` runs 3.11.9 in a directory listing `python 3.12.1 3.11.9`.
+// When none of the versions set match, the newest installed version the shim
+// was generated for runs instead of failing.
+func findVersionedExecutable(conf config.Config, shimName, executableName string, shimToolVersions []toolversions.ToolVersions, currentDirectory string) (path string, plugin plugins.Plugin, version string, resolved resolve.ToolVersions, found bool, err error) {
+	type candidate struct {
+		plugin    plugins.Plugin
+		resolved  resolve.ToolVersions
+		declared  []string
+		installed []string
+	}
+
+	var candidates []candidate
+	for _, shimToolVersion := range shimToolVersions {
+		plugin := plugins.New(conf, shimToolVersion.Name)
+		if plugin.Exists() != nil {
+			continue
+		}
+
+		versions, found, err := resolve.Version(conf, plugin, currentDirectory)
+		if err != nil {
+			return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, err
+		}
+
+		installed := slices.Clone(shimToolVersion.Versions)
+		versionspec.Sort(installed)
+		slices.Reverse(installed)
+
+		candidate := candidate{plugin: plugin, installed: installed}
+		if found {
+			candidate.resolved = versions
+			candidate.declared = toolversions.Intersect(versions.Versions, shimToolVersion.Versions)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	if len(candidates) == 0 {
+		return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, NoVersionSetError{shim: shimName}
+	}
+
+	// Versions set for the directory are tried for every tool before falling
+	// back to the newest installed version of any
+	tools := []string{}
+	versions := []string{}
+	for _, fallback := range []bool{false, true} {
+		for _, candidate := range candidates {
+			tried := candidate.declared
+			if fallback {
+				tried = candidate.installed
+				tools = append(tools, candidate.plugin.Name)
+				versions = append(versions, tried...)
+			}
+
+			for _, version := range tried {
+				if path, err := GetExecutablePath(conf, candidate.plugin, executableName, toolversions.Parse(version)); err == nil {
+					return path, candidate.plugin, version, candidate.resolved, true, nil
+				}
+			}
+		}
+	}
+
+	return "", plugins.Plugin{}, "", resolve.ToolVersions{}, false, NoExecutableForPluginError{shim: shimName, tools: tools, versions: versions}
+}
@@ -0,0 +1,122 @@
+package shims
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionedShims(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+	assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("versioned_shims = yes\n"), 0o666))
+	for _, version := range []string{"1.1.0", "1.1.2", "1.2.0-rc1", "2.0.0"} {
+		installVersion(t, conf, plugin, version)
+	}
+	stdout, stderr := buildOutputs()
+	assert.Nil(t, Regenerate(conf, &stdout, &stderr))
+	currentDir := t.TempDir()
+
+	t.Run("generates shim for every version and release line", func(t *testing.T) {
+		for shimName, versions := range map[string][]string{
+			"dummy":           {"1.1.0", "1.1.2", "1.2.0-rc1", "2.0.0"},
+			"dummy@1.1.0":     {"1.1.0"},
+			"dummy@1.1.2":     {"1.1.2"},
+			"dummy@1.1":       {"1.1.0", "1.1.2"},
+			"dummy@1.2.0-rc1": {"1.2.0-rc1"},
+			"dummy@2.0":       {"2.0.0"},
+		} {
+			toolVersions, err := GetToolsAndVersionsFromShimFile(Path(conf, shimName))
+			assert.Nil(t, err)
+			assert.Len(t, toolVersions, 1)
+			assert.ElementsMatch(t, versions, toolVersions[0].Versions, shimName)
+		}
+		assert.NoFileExists(t, Path(conf, "dummy@1.2"))
+	})
+
+	t.Run("runs first version set for the directory matching the suffix", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), []byte("lua 2.0.0 1.1.0\n"), 0o666))
+
+		executable, gotPlugin, version, found, err := FindExecutable(conf, "dummy@1.1", currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, plugin, gotPlugin)
+		assert.Equal(t, "1.1.0", version)
+		assert.Equal(t, "dummy", filepath.Base(executable))
+		assert.Equal(t, "1.1.0", filepath.Base(filepath.Dir(filepath.Dir(executable))))
+
+		_, _, version, _, err = FindExecutable(conf, "dummy", currentDir)
+		assert.Nil(t, err)
+		assert.Equal(t, "2.0.0", version)
+	})
+
+	t.Run("runs newest installed version matching the suffix when none set match", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filepath.Join(currentDir, ".tool-versions"), []byte("lua 2.0.0\n"), 0o666))
+
+		_, _, version, found, err := FindExecutable(conf, "dummy@1.1", currentDir)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "1.1.2", version)
+
+		_, _, version, found, err = FindExecutable(conf, "dummy@1.1", t.TempDir())
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "1.1.2", version)
+	})
+
+	t.Run("returns error for version without shim", func(t *testing.T) {
+		_, _, _, found, err := FindExecutable(conf, "dummy@3.0", currentDir)
+		assert.False(t, found)
+		assert.IsType(t, UnknownCommandError{}, err)
+	})
+
+	t.Run("removes shims of uninstalled versions on reshim", func(t *testing.T) {
+		installPath := filepath.Join(conf.DataDir, "installs", testPluginName, "1.1.2")
+		assert.Nil(t, os.RemoveAll(installPath))
+		assert.Nil(t, Regenerate(conf, &stdout, &stderr))
+
+		assert.NoFileExists(t, Path(conf, "dummy@1.1.2"))
+		toolVersions, err := GetToolsAndVersionsFromShimFile(Path(conf, "dummy@1.1"))
+		assert.Nil(t, err)
+		assert.Equal(t, []toolversions.ToolVersions{{Name: testPluginName, Versions: []string{"1.1.0"}}}, toolVersions)
+	})
+
+	t.Run("generates no versioned shims when versioned_shims is not set", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte(""), 0o666))
+		conf := conf
+		conf.Settings.Loaded = false
+		assert.Nil(t, Regenerate(conf, &stdout, &stderr))
+
+		assert.FileExists(t, Path(conf, "dummy"))
+		assert.NoFileExists(t, Path(conf, "dummy@1.1"))
+	})
+}
+
+func TestSplitVersionedShim(t *testing.T) {
+	shimToolVersions := []toolversions.ToolVersions{{Name: "python", Versions: []string{"3.11.4", "3.11.9"}}}
+
+	tests := []struct {
+		desc           string
+		shimName       string
+		toolVersions   []toolversions.ToolVersions
+		executableName string
+		ok             bool
+	}{
+		{desc: "release line", shimName: "python@3.11", toolVersions: shimToolVersions, executableName: "python", ok: true},
+		{desc: "version", shimName: "python@3.11.4", toolVersions: []toolversions.ToolVersions{{Name: "python", Versions: []string{"3.11.4"}}}, executableName: "python", ok: true},
+		{desc: "no suffix", shimName: "python", toolVersions: shimToolVersions, ok: false},
+		{desc: "empty suffix", shimName: "python@", toolVersions: shimToolVersions, ok: false},
+		{desc: "executable with @ in name", shimName: "tool@edge", toolVersions: shimToolVersions, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			executableName, _, ok := splitVersionedShim(tt.shimName, tt.toolVersions)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.executableName, executableName)
+		})
+	}
+}
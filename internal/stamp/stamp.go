@@ -0,0 +1,367 @@
+// Package stamp records the tools and exact versions resolved for a build, the
+// plugins that installed them and a hash of the version files they were set in,
+// in a small file meant to be embedded in build artifacts. A stamp can later be
+// verified against the environment it is read in, to trace an artifact back to
+// the toolchain that produced it or to check a rebuild uses the same one.
+package stamp
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/git"
+	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/provenance"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+const (
+	// FormatJSON writes stamps as a JSON document
+	FormatJSON = "json"
+	// FormatEnv writes stamps as `KEY=value` lines, which can be sourced by
+	// shells or passed to tools reading env files
+	FormatEnv = "env"
+
+	envPrefix = "ASDF_STAMP_"
+)
+
+// Tool is a tool resolved for the build
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Source is the version file the version was set in, relative to the
+	// directory stamped, or where else it was set, such as an environment
+	// variable
+	Source    string `json:"source,omitempty"`
+	PluginURL string `json:"plugin_url,omitempty"`
+	PluginRef string `json:"plugin_ref,omitempty"`
+}
+
+// Stamp describes the tools resolved for a build
+type Stamp struct {
+	Platform string `json:"platform"`
+	// VersionFilesHash is the hex encoded SHA-256 of the version files the
+	// versions were set in, empty when none were set in files
+	VersionFilesHash string `json:"version_files_sha256,omitempty"`
+	Tools            []Tool `json:"tools"`
+}
+
+// New stamps the tools resolved in the directory. The version of a tool is the
+// installed version used for the versions set, or the first version set when
+// none is installed. The plugin URL and ref are the ones recorded when the
+// version was installed, or the current ones of the plugin.
+func New(conf config.Config, dir string) (stamp Stamp, err error) {
+	allPlugins, err := plugins.List(conf, false, false)
+	if err != nil {
+		return stamp, err
+	}
+
+	stamp = Stamp{Platform: installs.Platform(), Tools: []Tool{}}
+	var files []string
+	for _, result := range resolve.All(conf, allPlugins, dir) {
+		if result.Err != nil {
+			return stamp, fmt.Errorf("unable to resolve %s: %w", result.Plugin.Name, result.Err)
+		}
+
+		if !result.Found || len(result.Versions.Versions) == 0 {
+			continue
+		}
+
+		version := resolve.FindBestMatchingVersion(conf, result.Plugin, result.Versions.Versions)
+		if version == "" {
+			version = result.Versions.Versions[0]
+		}
+
+		tool := Tool{Name: result.Plugin.Name, Version: version, Source: result.Versions.Source}
+		if file := filepath.Join(result.Versions.Directory, result.Versions.Source); result.Versions.Directory != "" && isFile(file) {
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+			tool.Source = relativePath(dir, file)
+		}
+
+		tool.PluginURL, tool.PluginRef = pluginSource(conf, result.Plugin, version)
+		stamp.Tools = append(stamp.Tools, tool)
+	}
+
+	stamp.VersionFilesHash, err = hashFiles(dir, files)
+	return stamp, err
+}
+
+// Verify returns the differences between the stamp and the tools resolved in
+// the directory, see Diff
+func Verify(conf config.Config, stamp Stamp, dir string) ([]string, error) {
+	current, err := New(conf, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return Diff(stamp, current), nil
+}
+
+// Diff returns a line describing each difference between a stamp and the
+// current one, empty when they match
+func Diff(stamped, current Stamp) (differences []string) {
+	if stamped.Platform != current.Platform {
+		differences = append(differences, fmt.Sprintf("platform: stamped %s, current %s", stamped.Platform, current.Platform))
+	}
+
+	if stamped.VersionFilesHash != current.VersionFilesHash {
+		differences = append(differences, fmt.Sprintf("version files: stamped sha256 %s, current %s", orNone(stamped.VersionFilesHash), orNone(current.VersionFilesHash)))
+	}
+
+	for _, tool := range stamped.Tools {
+		index := slices.IndexFunc(current.Tools, func(currentTool Tool) bool { return currentTool.Name == tool.Name })
+		if index < 0 {
+			differences = append(differences, fmt.Sprintf("%s: stamped %s, not set", tool.Name, tool.Version))
+			continue
+		}
+
+		currentTool := current.Tools[index]
+		if tool.Version != currentTool.Version {
+			differences = append(differences, fmt.Sprintf("%s: stamped %s, current %s", tool.Name, tool.Version, currentTool.Version))
+		}
+
+		if tool.PluginRef != currentTool.PluginRef {
+			differences = append(differences, fmt.Sprintf("%s: stamped plugin ref %s, current %s", tool.Name, orNone(tool.PluginRef), orNone(currentTool.PluginRef)))
+		}
+	}
+
+	for _, tool := range current.Tools {
+		if !slices.ContainsFunc(stamped.Tools, func(stampedTool Tool) bool { return stampedTool.Name == tool.Name }) {
+			differences = append(differences, fmt.Sprintf("%s: current %s, not stamped", tool.Name, tool.Version))
+		}
+	}
+
+	return differences
+}
+
+// Write writes the stamp in the format
+func Write(stamp Stamp, format string, out io.Writer) error {
+	switch format {
+	case FormatJSON:
+		contents, err := json.MarshalIndent(stamp, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", contents)
+		return err
+	case FormatEnv:
+		return writeEnv(stamp, out)
+	}
+
+	return fmt.Errorf("unknown stamp format %s, supported formats: %s, %s", format, FormatJSON, FormatEnv)
+}
+
+// Read parses a stamp written in any format
+func Read(in io.Reader) (stamp Stamp, err error) {
+	contents, err := io.ReadAll(in)
+	if err != nil {
+		return stamp, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(contents)), "{") {
+		err = json.Unmarshal(contents, &stamp)
+		return stamp, err
+	}
+
+	return readEnv(string(contents))
+}
+
+// writeEnv writes the platform, the version files hash and the names of the
+// tools, followed by the version, source and plugin of each tool in variables
+// named after the tool. Empty values are left out. Tools whose names map to
+// the same variables, such as foo-bar and foo_bar, can't be written.
+func writeEnv(stamp Stamp, out io.Writer) error {
+	names := make([]string, 0, len(stamp.Tools))
+	prefixes := map[string]string{}
+	for _, tool := range stamp.Tools {
+		if other, ok := prefixes[toolPrefix(tool.Name)]; ok {
+			return fmt.Errorf("tools %s and %s have the same variable names in %s stamps, use the %s format", other, tool.Name, FormatEnv, FormatJSON)
+		}
+		prefixes[toolPrefix(tool.Name)] = tool.Name
+		names = append(names, tool.Name)
+	}
+
+	lines := [][2]string{
+		{"PLATFORM", stamp.Platform},
+		{"VERSION_FILES_SHA256", stamp.VersionFilesHash},
+		{"TOOLS", strings.Join(names, " ")},
+	}
+	for _, tool := range stamp.Tools {
+		for _, field := range toolFields(&tool) {
+			lines = append(lines, [2]string{toolPrefix(tool.Name) + field.suffix, *field.value})
+		}
+	}
+
+	for _, line := range lines {
+		if line[1] == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "%s%s=%s\n", envPrefix, line[0], quote(line[1])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readEnv(contents string) (stamp Stamp, err error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return stamp, fmt.Errorf("invalid stamp line %q", line)
+		}
+		values[strings.TrimPrefix(key, envPrefix)], err = unquote(value)
+		if err != nil {
+			return stamp, fmt.Errorf("invalid stamp line %q: %w", line, err)
+		}
+	}
+
+	stamp = Stamp{Platform: values["PLATFORM"], VersionFilesHash: values["VERSION_FILES_SHA256"], Tools: []Tool{}}
+	for _, name := range strings.Fields(values["TOOLS"]) {
+		tool := Tool{Name: name}
+		for _, field := range toolFields(&tool) {
+			*field.value = values[toolPrefix(name)+field.suffix]
+		}
+		stamp.Tools = append(stamp.Tools, tool)
+	}
+
+	return stamp, scanner.Err()
+}
+
+type toolField struct {
+	suffix string
+	value  *string
+}
+
+func toolFields(tool *Tool) []toolField {
+	return []toolField{
+		{suffix: "_VERSION", value: &tool.Version},
+		{suffix: "_SOURCE", value: &tool.Source},
+		{suffix: "_PLUGIN_URL", value: &tool.PluginURL},
+		{suffix: "_PLUGIN_REF", value: &tool.PluginRef},
+	}
+}
+
+// toolPrefix returns the part of the variable names of the tool following
+// ASDF_STAMP_, which like ASDF_<TOOL>_VERSION variables is the upper case name
+func toolPrefix(name string) string {
+	return strings.ToUpper(strings.NewReplacer("/", "__", "-", "_").Replace(name))
+}
+
+// quote single quotes the value so shells read it back unchanged, closing the
+// quotes around the single quotes it contains
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// unquote reverses quote, reading the value as a shell would. Unquoted
+// characters and backslash escapes outside quotes are kept as they are.
+func unquote(value string) (string, error) {
+	var unquoted strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\'':
+			end := strings.IndexByte(value[i+1:], '\'')
+			if end < 0 {
+				return "", errors.New("unterminated quote")
+			}
+			unquoted.WriteString(value[i+1 : i+1+end])
+			i += end + 1
+		case '\\':
+			if i+1 < len(value) {
+				i++
+			}
+			unquoted.WriteByte(value[i])
+		default:
+			unquoted.WriteByte(value[i])
+		}
+	}
+
+	return unquoted.String(), nil
+}
+
+// pluginSource returns the URL and ref of the plugin recorded when the version
+// was installed, falling back to the current ones of the plugin
+func pluginSource(conf config.Config, plugin plugins.Plugin, version string) (url, ref string) {
+	parsed := toolversions.Parse(version)
+	if parsed.Type == "version" {
+		record, err := provenance.Read(installs.MetadataPath(conf, plugin, parsed), plugin.Name, version)
+		if err == nil && record.PluginRef != "" {
+			return record.PluginURL, record.PluginRef
+		}
+	}
+
+	if image, ok := plugin.Image(); ok {
+		return plugins.ImagePrefix + image, ""
+	}
+
+	repo := git.NewRepo(plugin.Dir)
+	ref, _ = repo.Head()
+	url, _ = repo.RemoteURL()
+	return strings.TrimSpace(url), ref
+}
+
+// hashFiles returns the SHA-256 of the path of each file relative to the
+// directory and its contents, in order of the relative paths, so the hash
+// doesn't depend on where a project is checked out
+func hashFiles(dir string, files []string) (string, error) {
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	slices.SortFunc(files, func(a, b string) int { return strings.Compare(relativePath(dir, a), relativePath(dir, b)) })
+	hash := sha256.New()
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		hash.Write([]byte(relativePath(dir, file)))
+		hash.Write([]byte{0})
+		hash.Write(contents)
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func relativePath(dir, file string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil {
+		return rel
+	}
+	return file
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
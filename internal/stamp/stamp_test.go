@@ -0,0 +1,129 @@
+package stamp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installtest"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginName = "lua"
+
+func TestNew(t *testing.T) {
+	conf := config.Config{DataDir: t.TempDir(), DefaultToolVersionsFilename: ".tool-versions", ConfigFile: filepath.Join(t.TempDir(), "asdfrc")}
+	_, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, testPluginName)
+	assert.Nil(t, err)
+	plugin := plugins.New(conf, testPluginName)
+	assert.Nil(t, installtest.InstallOneVersion(conf, plugin, "version", "1.0.0"))
+
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+	stamp, err := New(conf, dir)
+	assert.Nil(t, err)
+
+	t.Run("records tools with their source and plugin", func(t *testing.T) {
+		assert.Len(t, stamp.Tools, 1)
+		assert.Equal(t, testPluginName, stamp.Tools[0].Name)
+		assert.Equal(t, "1.0.0", stamp.Tools[0].Version)
+		assert.Equal(t, ".tool-versions", stamp.Tools[0].Source)
+		assert.Len(t, stamp.Tools[0].PluginRef, 40)
+		assert.Len(t, stamp.VersionFilesHash, 64)
+	})
+
+	t.Run("hash does not depend on where project is", func(t *testing.T) {
+		otherDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(otherDir, ".tool-versions"), []byte(testPluginName+" 1.0.0\n"), 0o666))
+
+		differences, err := Verify(conf, stamp, otherDir)
+		assert.Nil(t, err)
+		assert.Empty(t, differences)
+	})
+
+	t.Run("Verify returns differences when versions change", func(t *testing.T) {
+		otherDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(otherDir, ".tool-versions"), []byte(testPluginName+" 2.0.0\n"), 0o666))
+
+		differences, err := Verify(conf, stamp, otherDir)
+		assert.Nil(t, err)
+		assert.Len(t, differences, 2)
+		assert.Contains(t, differences, "lua: stamped 1.0.0, current 2.0.0")
+	})
+}
+
+func TestDiff(t *testing.T) {
+	stamped := Stamp{Platform: "linux/amd64", VersionFilesHash: "abc", Tools: []Tool{
+		{Name: "nodejs", Version: "20.11.0", PluginRef: "1111"},
+		{Name: "python", Version: "3.12.1"},
+	}}
+
+	t.Run("returns no differences for same stamp", func(t *testing.T) {
+		assert.Empty(t, Diff(stamped, stamped))
+	})
+
+	t.Run("returns every difference", func(t *testing.T) {
+		current := Stamp{Platform: "darwin/arm64", Tools: []Tool{
+			{Name: "nodejs", Version: "20.12.0", PluginRef: "2222"},
+			{Name: "ruby", Version: "3.3.0"},
+		}}
+
+		assert.Equal(t, []string{
+			"platform: stamped linux/amd64, current darwin/arm64",
+			"version files: stamped sha256 abc, current none",
+			"nodejs: stamped 20.11.0, current 20.12.0",
+			"nodejs: stamped plugin ref 1111, current 2222",
+			"python: stamped 3.12.1, not set",
+			"ruby: current 3.3.0, not stamped",
+		}, Diff(stamped, current))
+	})
+}
+
+func TestWriteRead(t *testing.T) {
+	stamp := Stamp{Platform: "linux/amd64", VersionFilesHash: "abc", Tools: []Tool{
+		{Name: "nodejs", Version: "20.11.0", Source: ".tool-versions", PluginURL: "https://github.com/asdf-vm/asdf-nodejs.git", PluginRef: "1111"},
+		{Name: "acme/my-tool", Version: "1.0.0", Source: "resolution_missing hook"},
+		{Name: "ruby", Version: "3.3.0", Source: `it's "$HOME"/.ruby-version \n`},
+	}}
+
+	for _, format := range []string{FormatJSON, FormatEnv} {
+		t.Run("reads stamp written as "+format, func(t *testing.T) {
+			var out strings.Builder
+			assert.Nil(t, Write(stamp, format, &out))
+
+			read, err := Read(strings.NewReader(out.String()))
+			assert.Nil(t, err)
+			assert.Equal(t, stamp, read)
+		})
+	}
+
+	t.Run("writes variables named after tools", func(t *testing.T) {
+		var out strings.Builder
+		assert.Nil(t, Write(stamp, FormatEnv, &out))
+		assert.Contains(t, out.String(), "ASDF_STAMP_TOOLS='nodejs acme/my-tool ruby'\n")
+		assert.Contains(t, out.String(), "ASDF_STAMP_ACME__MY_TOOL_SOURCE='resolution_missing hook'\n")
+		assert.Contains(t, out.String(), "ASDF_STAMP_NODEJS_VERSION='20.11.0'\n")
+		assert.Contains(t, out.String(), `ASDF_STAMP_RUBY_SOURCE='it'\''s "$HOME"/.ruby-version \n'`+"\n")
+		assert.NotContains(t, out.String(), "ASDF_STAMP_ACME__MY_TOOL_PLUGIN_REF")
+	})
+
+	t.Run("returns error when tools have the same variable names", func(t *testing.T) {
+		stamp := Stamp{Tools: []Tool{{Name: "foo-bar", Version: "1.0.0"}, {Name: "foo_bar", Version: "2.0.0"}}}
+		err := Write(stamp, FormatEnv, &strings.Builder{})
+		assert.ErrorContains(t, err, "tools foo-bar and foo_bar have the same variable names")
+	})
+
+	t.Run("returns error for unterminated quote", func(t *testing.T) {
+		_, err := Read(strings.NewReader("ASDF_STAMP_PLATFORM='linux/amd64\n"))
+		assert.ErrorContains(t, err, "unterminated quote")
+	})
+
+	t.Run("returns error for unknown format", func(t *testing.T) {
+		assert.ErrorContains(t, Write(stamp, "yaml", &strings.Builder{}), "unknown stamp format yaml")
+	})
+}
@@ -10,12 +10,38 @@ import (
 	"strings"
 )
 
+// RootMarker is a comment that, on a line of its own, marks a .tool-versions
+// file as the root of a project. Versions aren't looked up in parent
+// directories of a root file.
+const RootMarker = "asdf:root"
+
+// RequiresMarker starts a comment that, at the end of the line of a tool,
+// lists the tools that must be installed before it, separated by spaces.
+const RequiresMarker = "asdf:requires"
+
+// Unmanaged is a version that, as the only version set for a tool in a
+// .tool-versions file, opts the directory out of versions inherited from
+// parent directories and the home directory. The tool is used from the system
+// PATH, as if the version was `system`.
+const Unmanaged = "unmanaged"
+
+// System is the version that uses the tool installed outside asdf, found on
+// PATH once the asdf shims directory is removed from it. It is both the
+// keyword set in version files and the Type of the parsed version.
+const System = "system"
+
 // Version struct represents a single version in asdf.
 type Version struct {
 	Type  string // Must be one of: version, ref, path, system, latest
 	Value string // Any string
 }
 
+// IsSystem returns true if the version is the system version, which isn't
+// installed by asdf and has no install directory
+func (v Version) IsSystem() bool {
+	return v.Type == System
+}
+
 // ToolVersions represents a tool along with versions specified for it
 type ToolVersions struct {
 	Name     string
@@ -74,6 +100,51 @@ func updateContentWithToolVersions(content string, toolVersions []ToolVersions)
 	return output.String()
 }
 
+// ReplaceVersions passes each version set for the tool in the content of a tool
+// versions file to replace, substituting the version returned. Unlike
+// WriteToolVersionsToFile everything else in the content, such as spacing,
+// blank lines and comments, is left as it is. changed is true if any version
+// was substituted.
+func ReplaceVersions(content, toolName string, replace func(version string) string) (updated string, changed bool) {
+	lines := readLines(content)
+	for i, line := range lines {
+		preComment, comment, hasComment := strings.Cut(line, "#")
+
+		var output strings.Builder
+		tokenIndex := 0
+		isTool := false
+		for j, field := range strings.Split(preComment, " ") {
+			if j > 0 {
+				output.WriteString(" ")
+			}
+
+			token := strings.TrimSpace(field)
+			if token == "" {
+				output.WriteString(field)
+				continue
+			}
+
+			if tokenIndex == 0 {
+				isTool = token == toolName
+			} else if isTool {
+				if replacement := replace(token); replacement != token {
+					field = strings.Replace(field, token, replacement, 1)
+					changed = true
+				}
+			}
+			output.WriteString(field)
+			tokenIndex++
+		}
+
+		if hasComment {
+			output.WriteString("#" + comment)
+		}
+		lines[i] = output.String()
+	}
+
+	return strings.Join(lines, "\n"), changed
+}
+
 // FindToolVersions looks up a tool version in a tool versions file and if found
 // returns a slice of versions for it.
 func FindToolVersions(filepath, toolName string) (versions []string, found bool, err error) {
@@ -82,7 +153,7 @@ func FindToolVersions(filepath, toolName string) (versions []string, found bool,
 		return versions, false, err
 	}
 
-	versions, found = findToolVersionsInContent(string(content), toolName)
+	versions, found = FindToolVersionsInContent(string(content), toolName)
 	return versions, found, nil
 }
 
@@ -98,6 +169,27 @@ func GetAllToolsAndVersions(filepath string) (toolVersions []ToolVersions, err e
 	return toolVersions, nil
 }
 
+// FindRequirements returns the tools listed after the RequiresMarker in the
+// comment on the line of the tool in a tool versions file
+func FindRequirements(filepath, toolName string) (tools []string, err error) {
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return tools, err
+	}
+
+	return findRequirementsInContent(string(content), toolName), nil
+}
+
+// IsRoot returns true if the .tool-versions file contains the RootMarker
+func IsRoot(filepath string) (bool, error) {
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return false, err
+	}
+
+	return IsRootContent(string(content)), nil
+}
+
 // Intersect takes two slices of versions and returns a new slice containing
 // only the versions found in both.
 func Intersect(versions1 []string, versions2 []string) (versions []string) {
@@ -167,8 +259,8 @@ func Parse(version string) Version {
 		}
 	}
 
-	if version == "system" {
-		return Version{Type: "system"}
+	if version == System {
+		return Version{Type: System}
 	}
 
 	return Version{Type: "version", Value: version}
@@ -185,8 +277,8 @@ func ParseSlice(versions []string) (parsedVersions []Version) {
 // Format takes a Version struct and formats it as a string
 func Format(version Version) string {
 	switch version.Type {
-	case "system":
-		return "system"
+	case System:
+		return System
 	case "path":
 		return fmt.Sprintf("path:%s", version.Value)
 	case "ref":
@@ -220,7 +312,9 @@ func readLines(content string) (lines []string) {
 	return strings.Split(content, "\n")
 }
 
-func findToolVersionsInContent(content, toolName string) (versions []string, found bool) {
+// FindToolVersionsInContent looks up a tool version in the content of a tool
+// versions file, see FindToolVersions
+func FindToolVersionsInContent(content, toolName string) (versions []string, found bool) {
 	toolVersions := getAllToolsAndVersionsInContent(content)
 	for _, tool := range toolVersions {
 		if tool.Name == toolName {
@@ -243,6 +337,35 @@ func getAllToolsAndVersionsInContent(content string) (toolVersions []ToolVersion
 	return toolVersions
 }
 
+func findRequirementsInContent(content, toolName string) (tools []string) {
+	for _, line := range readLines(content) {
+		tokens, comment := parseLine(line)
+		if len(tokens) < 2 || tokens[0] != toolName {
+			continue
+		}
+
+		fields := strings.Fields(comment)
+		if len(fields) > 0 && fields[0] == RequiresMarker {
+			tools = append(tools, fields[1:]...)
+		}
+	}
+
+	return tools
+}
+
+// IsRootContent returns true if the content of a tool versions file contains
+// the RootMarker, see IsRoot
+func IsRootContent(content string) bool {
+	for _, line := range readLines(content) {
+		tokens, comment := parseLine(line)
+		if len(tokens) == 0 && strings.TrimSpace(comment) == RootMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
 // parseLine receives a single line from a file and parses it into a list of
 // tokens and a comment. A comment may occur anywhere on the line and is started
 // by a `#` character.
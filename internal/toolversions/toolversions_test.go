@@ -51,6 +51,54 @@ func TestFindToolVersions(t *testing.T) {
 	})
 }
 
+func TestFindRequirements(t *testing.T) {
+	t.Run("returns error when non-existent file", func(t *testing.T) {
+		_, err := FindRequirements("non-existent-file", "yarn")
+		assert.Error(t, err)
+	})
+
+	t.Run("returns tools listed in comment on line of tool", func(t *testing.T) {
+		toolVersionsPath := filepath.Join(t.TempDir(), ".tool-versions")
+		assert.Nil(t, os.WriteFile(toolVersionsPath, []byte("nodejs 20.0.0\nyarn 1.22.0 # asdf:requires nodejs python\n"), 0o666))
+
+		tools, err := FindRequirements(toolVersionsPath, "yarn")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"nodejs", "python"}, tools)
+	})
+}
+
+func TestFindRequirementsInContent(t *testing.T) {
+	assert.Empty(t, findRequirementsInContent("", "yarn"))
+	assert.Empty(t, findRequirementsInContent("yarn 1.22.0 # needs nodejs", "yarn"))
+	assert.Empty(t, findRequirementsInContent("# asdf:requires nodejs\nyarn 1.22.0", "yarn"))
+	assert.Empty(t, findRequirementsInContent("poetry 1.8.0 # asdf:requires python", "yarn"))
+	assert.Equal(t, []string{"nodejs"}, findRequirementsInContent("yarn 1.22.0   #asdf:requires   nodejs ", "yarn"))
+}
+
+func TestIsRoot(t *testing.T) {
+	t.Run("returns error when non-existent file", func(t *testing.T) {
+		root, err := IsRoot("non-existent-file")
+		assert.Error(t, err)
+		assert.False(t, root)
+	})
+
+	t.Run("returns true when file contains root marker", func(t *testing.T) {
+		toolVersionsPath := filepath.Join(t.TempDir(), ".tool-versions")
+		assert.Nil(t, os.WriteFile(toolVersionsPath, []byte("ruby 2.0.0\n  #  asdf:root\n"), 0o666))
+
+		root, err := IsRoot(toolVersionsPath)
+		assert.Nil(t, err)
+		assert.True(t, root)
+	})
+}
+
+func TestIsRootContent(t *testing.T) {
+	assert.False(t, IsRootContent(""))
+	assert.False(t, IsRootContent("ruby 2.0.0 # asdf:root"))
+	assert.False(t, IsRootContent("# asdf:rooted"))
+	assert.True(t, IsRootContent("# asdf:root\nruby 2.0.0"))
+}
+
 func TestWriteToolVersionsToFile(t *testing.T) {
 	toolVersions := ToolVersions{Name: "lua", Versions: []string{"1.2.3"}}
 
@@ -152,6 +200,29 @@ func TestUpdateContentWithToolVersions(t *testing.T) {
 	}
 }
 
+func TestReplaceVersions(t *testing.T) {
+	bump := func(version string) string {
+		if version == "18.19.0" {
+			return "20.11.1"
+		}
+		return version
+	}
+
+	t.Run("replaces matching versions keeping formatting", func(t *testing.T) {
+		content := "# tools\nnodejs  18.19.0   system # pinned\n\nnodejs-lts 18.19.0\nruby 18.19.0\n"
+		updated, changed := ReplaceVersions(content, "nodejs", bump)
+		assert.True(t, changed)
+		assert.Equal(t, "# tools\nnodejs  20.11.1   system # pinned\n\nnodejs-lts 18.19.0\nruby 18.19.0\n", updated)
+	})
+
+	t.Run("leaves content unchanged when no version matches", func(t *testing.T) {
+		content := "nodejs 16.0.0 # 18.19.0\n"
+		updated, changed := ReplaceVersions(content, "nodejs", bump)
+		assert.False(t, changed)
+		assert.Equal(t, content, updated)
+	})
+}
+
 func TestIntersect(t *testing.T) {
 	tests := []struct {
 		desc   string
@@ -232,19 +303,19 @@ func TestUnique(t *testing.T) {
 
 func TestFindToolVersionsInContent(t *testing.T) {
 	t.Run("returns empty list with found false when empty content", func(t *testing.T) {
-		versions, found := findToolVersionsInContent("", "ruby")
+		versions, found := FindToolVersionsInContent("", "ruby")
 		assert.False(t, found)
 		assert.Empty(t, versions)
 	})
 
 	t.Run("returns empty list with found false when tool not found", func(t *testing.T) {
-		versions, found := findToolVersionsInContent("lua 5.4.5", "ruby")
+		versions, found := FindToolVersionsInContent("lua 5.4.5", "ruby")
 		assert.False(t, found)
 		assert.Empty(t, versions)
 	})
 
 	t.Run("returns list of versions with found true when tool found", func(t *testing.T) {
-		versions, found := findToolVersionsInContent("lua 5.4.5 5.4.6\nruby 2.0.0", "lua")
+		versions, found := FindToolVersionsInContent("lua 5.4.5 5.4.6\nruby 2.0.0", "lua")
 		assert.True(t, found)
 		assert.Equal(t, []string{"5.4.5", "5.4.6"}, versions)
 	})
@@ -314,6 +385,12 @@ func TestParse(t *testing.T) {
 	})
 }
 
+func TestIsSystem(t *testing.T) {
+	assert.True(t, Parse("system").IsSystem())
+	assert.False(t, Parse("1.2.3").IsSystem())
+	assert.False(t, Parse("path:/usr").IsSystem())
+}
+
 func TestParseFromCliArg(t *testing.T) {
 	t.Run("when passed 'latest' returns struct with type of 'latest'", func(t *testing.T) {
 		version := ParseFromCliArg("latest")
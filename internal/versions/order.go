@@ -0,0 +1,128 @@
+package versions
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/resolve"
+	"github.com/asdf-vm/asdf/internal/toolversions"
+)
+
+// RequirementCycleError is returned when tools require each other, directly or
+// through other tools, so none of them can be installed first.
+type RequirementCycleError struct {
+	toolNames []string
+}
+
+func (e RequirementCycleError) Error() string {
+	return fmt.Sprintf("tools require each other: %s", strings.Join(e.toolNames, ", "))
+}
+
+// RequirementFailedError is returned for a tool that isn't installed because a
+// tool it requires failed to install.
+type RequirementFailedError struct {
+	toolName    string
+	requirement string
+}
+
+func (e RequirementFailedError) Error() string {
+	return fmt.Sprintf("not installing %s because %s, which it requires, failed to install", e.toolName, e.requirement)
+}
+
+// Resolution holds the versions of a tool resolved for a directory, so they're
+// resolved once, and the resolve hooks run once, when tools are both ordered
+// and installed, see Resolve
+type Resolution struct {
+	Versions resolve.ToolVersions
+	Found    bool
+	Err      error
+}
+
+// Resolve resolves the versions of every tool for the directory, by tool name.
+// A tool whose plugin doesn't exist isn't resolved, the error is kept instead.
+func Resolve(ctx context.Context, conf config.Config, tools []plugins.Plugin, dir string) map[string]Resolution {
+	resolutions := map[string]Resolution{}
+	for _, plugin := range tools {
+		if err := plugin.Exists(); err != nil {
+			resolutions[plugin.Name] = Resolution{Err: err}
+			continue
+		}
+
+		versions, found, err := resolve.VersionContext(ctx, conf, plugin, dir)
+		resolutions[plugin.Name] = Resolution{Versions: versions, Found: found, Err: err}
+	}
+
+	return resolutions
+}
+
+// Requirements returns the tools that must be installed before the tool. They
+// are listed after the asdf:requires marker in the comment on the line of the
+// tool in the .tool-versions file its versions were resolved from. A tool
+// whose version is set anywhere else has no requirements.
+func Requirements(conf config.Config, plugin plugins.Plugin, resolution Resolution) ([]string, error) {
+	versions := resolution.Versions
+	if resolution.Err != nil || !resolution.Found || versions.Source != conf.DefaultToolVersionsFilename {
+		return nil, resolution.Err
+	}
+
+	return toolversions.FindRequirements(filepath.Join(versions.Directory, versions.Source), plugin.Name)
+}
+
+// InstallOrder sorts the tools so that each tool comes after the tools it
+// requires, keeping the given order otherwise. Requirements on tools that
+// aren't in tools are ignored. The requirements of each tool are returned
+// along with the order. A RequirementCycleError is returned when they can't be
+// ordered. The versions of the tools are resolved with Resolve.
+func InstallOrder(conf config.Config, tools []plugins.Plugin, resolutions map[string]Resolution) (ordered []plugins.Plugin, requirements map[string][]string, err error) {
+	requirements = map[string][]string{}
+	names := map[string]bool{}
+	for _, plugin := range tools {
+		names[plugin.Name] = true
+	}
+
+	for _, plugin := range tools {
+		// Installing the tool reports the same error resolving its version
+		required, _ := Requirements(conf, plugin, resolutions[plugin.Name])
+		for _, name := range required {
+			if names[name] && name != plugin.Name && !slices.Contains(requirements[plugin.Name], name) {
+				requirements[plugin.Name] = append(requirements[plugin.Name], name)
+			}
+		}
+	}
+
+	placed := map[string]bool{}
+	for len(ordered) < len(tools) {
+		next := slices.IndexFunc(tools, func(plugin plugins.Plugin) bool {
+			if placed[plugin.Name] {
+				return false
+			}
+
+			for _, name := range requirements[plugin.Name] {
+				if !placed[name] {
+					return false
+				}
+			}
+			return true
+		})
+
+		if next == -1 {
+			var remaining []string
+			for _, plugin := range tools {
+				if !placed[plugin.Name] {
+					remaining = append(remaining, plugin.Name)
+				}
+			}
+			return nil, nil, RequirementCycleError{toolNames: remaining}
+		}
+
+		placed[tools[next].Name] = true
+		ordered = append(ordered, tools[next])
+	}
+
+	return ordered, requirements, nil
+}
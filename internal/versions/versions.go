@@ -3,30 +3,55 @@
 package versions
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/asdf-vm/asdf/internal/callbackenv"
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/data"
+	"github.com/asdf-vm/asdf/internal/git"
 	"github.com/asdf-vm/asdf/internal/hook"
 	"github.com/asdf-vm/asdf/internal/installs"
+	"github.com/asdf-vm/asdf/internal/patches"
 	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/provenance"
 	"github.com/asdf-vm/asdf/internal/resolve"
 	"github.com/asdf-vm/asdf/internal/shims"
 	"github.com/asdf-vm/asdf/internal/toolversions"
+	"github.com/asdf-vm/asdf/internal/versionspec"
 )
 
 const (
-	systemVersion           = "system"
 	latestVersion           = "latest"
 	latestFilterRegex       = "(?i)(^Available versions:|-src|-dev|-latest|-stm|[-\\.]rc|-milestone|-alpha|-beta|[-\\.]pre|-next|(a|b|c)[0-9]+|snapshot|master|main)"
 	numericStartFilterRegex = "^\\s*[0-9]"
 	noLatestVersionErrMsg   = "no latest version found"
+	listAllCacheFilename    = "list-all"
 )
 
+// CollationEnv returns the environment variables passed to callbacks whose
+// output order asdf relies on. It forces any `sort` run by the plugin to
+// compare bytes rather than follow the users locale, so the same versions are
+// listed in the same order on every machine. Only the collation is changed, a
+// locale set with LC_ALL, which takes precedence over LC_COLLATE, is passed as
+// LANG instead so messages and the character set stay the same.
+func CollationEnv() map[string]string {
+	env := map[string]string{"LC_COLLATE": "C"}
+	if locale := os.Getenv("LC_ALL"); locale != "" {
+		env["LC_ALL"] = ""
+		env["LANG"] = locale
+	}
+	return env
+}
+
 // UninstallableVersionError is an error returned if someone tries to install the
 // system version.
 type UninstallableVersionError struct {
@@ -62,11 +87,73 @@ func (e VersionAlreadyInstalledError) Error() string {
 	return fmt.Sprintf("version %s of %s is already installed", e.version.Value, e.toolName)
 }
 
+// UnsatisfiedConstraintError is returned by `asdf install` for a constraint no
+// installed version satisfies when the remote_fallback setting is `report`,
+// naming the newest version available satisfying it
+type UnsatisfiedConstraintError struct {
+	toolName   string
+	constraint string
+	candidate  string
+}
+
+func (e UnsatisfiedConstraintError) Error() string {
+	return fmt.Sprintf("no installed version of %s satisfies %s, the newest available is %s, install it with `asdf install %s %s`", e.toolName, e.constraint, e.candidate, e.toolName, e.candidate)
+}
+
+// DeprecatedVersionError is returned when a version the plugin marks as
+// deprecated is used and the deprecated_versions setting is set to error.
+type DeprecatedVersionError struct {
+	toolName    string
+	deprecation plugins.Deprecation
+	version     string
+}
+
+func (e DeprecatedVersionError) Error() string {
+	return deprecationMessage(e.toolName, e.version, e.deprecation)
+}
+
+// CheckDeprecated checks whether the plugin marks a version as deprecated. A
+// warning is written to stdErr when it is, unless the deprecated_versions
+// setting is set to ignore. When the setting is set to error a
+// DeprecatedVersionError is returned instead.
+func CheckDeprecated(conf config.Config, plugin plugins.Plugin, version string, stdErr io.Writer) error {
+	action, err := conf.DeprecatedVersions()
+	if err != nil || action == "ignore" {
+		return err
+	}
+
+	deprecation, found, err := plugin.Deprecation(version)
+	if err != nil || !found {
+		return err
+	}
+
+	if action == "error" {
+		return DeprecatedVersionError{toolName: plugin.Name, deprecation: deprecation, version: version}
+	}
+
+	fmt.Fprintf(stdErr, "warning: %s\n", deprecationMessage(plugin.Name, version, deprecation))
+	return nil
+}
+
+func deprecationMessage(toolName, version string, deprecation plugins.Deprecation) string {
+	if deprecation.Message == "" {
+		return fmt.Sprintf("%s %s is deprecated", toolName, version)
+	}
+	return fmt.Sprintf("%s %s is deprecated: %s", toolName, version, deprecation.Message)
+}
+
 // InstallAll installs all specified versions of every tool for the current
 // directory. Typically this will just be a single version, if not already
 // installed, but it may be multiple versions if multiple versions for the tool
 // are specified in the .tool-versions file.
 func InstallAll(conf config.Config, dir string, stdOut io.Writer, stdErr io.Writer) (failures []error) {
+	return InstallAllContext(context.Background(), conf, dir, stdOut, stdErr)
+}
+
+// InstallAllContext installs all specified versions of every tool like
+// InstallAll, see InstallContext. Tools left when the context is done aren't
+// installed, the error of the context is returned for them.
+func InstallAllContext(ctx context.Context, conf config.Config, dir string, stdOut io.Writer, stdErr io.Writer) (failures []error) {
 	plugins, err := plugins.List(conf, false, false)
 	if err != nil {
 		return []error{fmt.Errorf("unable to list plugins: %w", err)}
@@ -74,40 +161,111 @@ func InstallAll(conf config.Config, dir string, stdOut io.Writer, stdErr io.Writ
 
 	// Ideally we should install these in the order they are specified in the
 	// closest .tool-versions file, but for now that is too complicated to
-	// implement.
-	for _, plugin := range plugins {
-		err := Install(conf, plugin, dir, stdOut, stdErr)
+	// implement. Tools are only moved after the tools they require.
+	resolutions := Resolve(ctx, conf, plugins, dir)
+	ordered, requirements, err := InstallOrder(conf, plugins, resolutions)
+	if err != nil {
+		return []error{err}
+	}
+
+	failed := map[string]bool{}
+	for _, plugin := range ordered {
+		if err := ctx.Err(); err != nil {
+			return append(failures, err)
+		}
+
+		if index := slices.IndexFunc(requirements[plugin.Name], func(name string) bool { return failed[name] }); index != -1 {
+			failed[plugin.Name] = true
+			failures = append(failures, RequirementFailedError{toolName: plugin.Name, requirement: requirements[plugin.Name][index]})
+			continue
+		}
+
+		err := InstallResolved(ctx, conf, plugin, dir, resolutions[plugin.Name], stdOut, stdErr)
 		if err != nil {
 			failures = append(failures, err)
+			failed[plugin.Name] = installFailed(err)
 		}
 	}
 
 	return failures
 }
 
+// installFailed returns true if the error returned by Install means the tool
+// isn't installed. Tools that are already installed or have no version set
+// don't stop the tools requiring them from being installed.
+func installFailed(err error) bool {
+	var vaiErr VersionAlreadyInstalledError
+	if errors.As(err, &vaiErr) {
+		return false
+	}
+
+	_, noVersion := err.(NoVersionSetError)
+	return !noVersion
+}
+
 // Install installs all specified versions of a tool for the current directory.
 // Typically this will just be a single version, if not already installed, but
 // it may be multiple versions if multiple versions for the tool are specified
 // in the .tool-versions file.
 func Install(conf config.Config, plugin plugins.Plugin, dir string, stdOut io.Writer, stdErr io.Writer) error {
-	err := plugin.Exists()
-	if err != nil {
-		return err
-	}
+	return InstallContext(context.Background(), conf, plugin, dir, stdOut, stdErr)
+}
 
-	versions, found, err := resolve.Version(conf, plugin, dir)
-	if err != nil {
-		return err
+// InstallContext installs all specified versions of a tool like Install,
+// killing the plugin callbacks resolving and installing them when the context
+// is done. The error of the context is returned in that case.
+func InstallContext(ctx context.Context, conf config.Config, plugin plugins.Plugin, dir string, stdOut io.Writer, stdErr io.Writer) error {
+	return InstallResolved(ctx, conf, plugin, dir, Resolve(ctx, conf, []plugins.Plugin{plugin}, dir)[plugin.Name], stdOut, stdErr)
+}
+
+// InstallResolved installs the versions of a tool already resolved for the
+// directory like InstallContext, see Resolve
+func InstallResolved(ctx context.Context, conf config.Config, plugin plugins.Plugin, dir string, resolution Resolution, stdOut io.Writer, stdErr io.Writer) (err error) {
+	versions, found := resolution.Versions, resolution.Found
+	if resolution.Err != nil {
+		return resolution.Err
 	}
 
 	if !found || len(versions.Versions) == 0 {
 		return NoVersionSetError{toolName: plugin.Name}
 	}
 
-	for _, version := range versions.Versions {
-		iErr := InstallOneVersion(conf, plugin, version, false, stdOut, stdErr)
+	// latest and wildcard versions are installed as the newest version
+	// available rather than the newest installed one. Resolved aliases are
+	// installed as resolved.
+	requested := versions.Versions
+	if remote, _ := conf.LatestRemote(); remote && versions.Requested != nil {
+		requested = slices.Clone(versions.Versions)
+		for i, version := range versions.Requested {
+			if toolversions.ParseFromCliArg(version).Type == latestVersion || versionspec.IsWildcard(version) {
+				requested[i] = version
+			}
+		}
+	}
+
+	origin := callbackenv.OriginOf(dir, versions)
+	for _, version := range requested {
+		// Exclusions like `!=1.5.3` only rule out installed versions when a
+		// match strategy picks one, there is nothing to install
+		if versionspec.IsExclusion(version) {
+			continue
+		}
+
+		// Wildcards without an installed match, or all of them with
+		// latest_remote, install the newest matching version available that
+		// the exclusions don't rule out
+		if versionspec.IsWildcard(version) {
+			match, wErr := Wildcard(conf, plugin, version, requested)
+			if wErr != nil {
+				return wErr
+			}
+			version = match
+		}
+
+		iErr := installOneVersion(ctx, conf, plugin, version, requested, false, origin, stdOut, stdErr)
 		var vaiErr VersionAlreadyInstalledError
-		if errors.As(iErr, &vaiErr) {
+		var ucErr UnsatisfiedConstraintError
+		if errors.As(iErr, &vaiErr) || errors.As(iErr, &ucErr) {
 			err = errors.Join(err, iErr)
 		} else if iErr != nil {
 			return iErr
@@ -128,7 +286,7 @@ func InstallVersion(conf config.Config, plugin plugins.Plugin, version toolversi
 
 	resolvedVersion := ""
 	if version.Type == latestVersion {
-		resolvedVersion, err = Latest(plugin, version.Value)
+		resolvedVersion, err = Latest(conf, plugin, version.Value)
 		if err != nil {
 			return err
 		}
@@ -139,13 +297,40 @@ func InstallVersion(conf config.Config, plugin plugins.Plugin, version toolversi
 
 // InstallOneVersion installs a specific version of a specific tool
 func InstallOneVersion(conf config.Config, plugin plugins.Plugin, versionStr string, keepDownload bool, stdOut io.Writer, stdErr io.Writer) error {
-	err := plugin.Exists()
+	return InstallOneVersionContext(context.Background(), conf, plugin, versionStr, keepDownload, stdOut, stdErr)
+}
+
+// InstallOneVersionContext installs a specific version of a specific tool like
+// InstallOneVersion, killing the download and install callbacks when the
+// context is done. The error of the context is returned in that case.
+func InstallOneVersionContext(ctx context.Context, conf config.Config, plugin plugins.Plugin, versionStr string, keepDownload bool, stdOut io.Writer, stdErr io.Writer) error {
+	return installOneVersion(ctx, conf, plugin, versionStr, nil, keepDownload, callbackenv.Origin{}, stdOut, stdErr)
+}
+
+// installOneVersion installs the version, resolving `latest`, wildcards and
+// constraints first. Exclusions among the constraints rule out the versions
+// they match.
+func installOneVersion(ctx context.Context, conf config.Config, plugin plugins.Plugin, versionStr string, constraints []string, keepDownload bool, origin callbackenv.Origin, stdOut io.Writer, stdErr io.Writer) (err error) {
+	err = plugin.Exists()
 	if err != nil {
 		return err
 	}
 
-	if versionStr == systemVersion {
-		return UninstallableVersionError{toolName: plugin.Name, versionType: systemVersion}
+	if versionStr == toolversions.System {
+		return UninstallableVersionError{toolName: plugin.Name, versionType: toolversions.System}
+	}
+
+	// latest versions set in version files are installed as the newest
+	// matching version available
+	if latest := toolversions.ParseFromCliArg(versionStr); latest.Type == latestVersion {
+		versionStr, err = Latest(conf, plugin, latest.Value)
+	} else if versionspec.IsWildcard(versionStr) {
+		versionStr, err = Wildcard(conf, plugin, versionStr, constraints)
+	} else {
+		versionStr, err = remoteFallback(conf, plugin, versionStr, constraints)
+	}
+	if err != nil {
+		return err
 	}
 
 	version := toolversions.Parse(versionStr)
@@ -153,22 +338,81 @@ func InstallOneVersion(conf config.Config, plugin plugins.Plugin, versionStr str
 	if version.Type == "path" {
 		return UninstallableVersionError{toolName: plugin.Name, versionType: "path"}
 	}
-	downloadDir := installs.DownloadPath(conf, plugin, version)
-	installDir := installs.InstallPath(conf, plugin, version)
-
 	if installs.IsInstalled(conf, plugin, version) {
 		return VersionAlreadyInstalledError{version: version, toolName: plugin.Name}
 	}
 
-	concurrency, _ := conf.Concurrency()
-	env := map[string]string{
-		"ASDF_INSTALL_TYPE":    version.Type,
-		"ASDF_INSTALL_VERSION": version.Value,
-		"ASDF_INSTALL_PATH":    installDir,
-		"ASDF_DOWNLOAD_PATH":   downloadDir,
-		"ASDF_CONCURRENCY":     concurrency,
+	if err := removeExcluded(conf, plugin, version); err != nil {
+		return fmt.Errorf("unable to remove excluded install: %w", err)
 	}
 
+	if err := CheckDeprecated(conf, plugin, version.Value, stdErr); err != nil {
+		return err
+	}
+
+	installDir, shared := installs.InstallTarget(conf, plugin, version)
+	return build(ctx, conf, plugin, version, installDir, shared, false, keepDownload, origin, stdOut, stdErr)
+}
+
+// build runs the download and install callbacks of the version with installDir
+// as its install path. When replace is true the version is already installed
+// at installDir and stays usable while it is built again, the new build is made
+// in a staging directory and only renamed over the previous one once it has
+// succeeded.
+func build(ctx context.Context, conf config.Config, plugin plugins.Plugin, version toolversions.Version, installDir string, shared, replace, keepDownload bool, origin callbackenv.Origin, stdOut io.Writer, stdErr io.Writer) (err error) {
+	downloadDir := installs.DownloadPath(conf, plugin, version)
+	provenanceFile := filepath.Join(downloadDir, provenance.PluginFilename)
+	started := time.Now()
+
+	tmpDir, err := makeTmpDir(conf.DataDir, plugin, version)
+	if err != nil {
+		return fmt.Errorf("unable to create temporary dir: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			fmt.Fprintf(stdErr, "temporary files kept for debugging in %s\n", tmpDir)
+			return
+		}
+
+		if rmErr := os.RemoveAll(tmpDir); rmErr != nil {
+			fmt.Fprintf(stdErr, "failed to clean up '%s' due to %s\n", tmpDir, rmErr)
+		}
+	}()
+
+	patchesDir, err := patches.Collect(conf, plugin.Name, version.Value, tmpDir)
+	if err != nil {
+		return fmt.Errorf("unable to collect patches: %w", err)
+	}
+
+	buildDir := installDir
+	if replace {
+		// The staging directory is in the data directory holding the install,
+		// so the build can be renamed over it
+		root := conf.DataDir
+		if shared {
+			root, _ = conf.SharedInstallDir()
+		}
+		staging, err := makeTmpDir(root, plugin, version)
+		if err != nil {
+			return fmt.Errorf("unable to create staging dir: %w", err)
+		}
+		defer os.RemoveAll(staging)
+		buildDir = filepath.Join(staging, filepath.Base(installDir))
+	}
+
+	concurrency, _ := conf.Concurrency()
+	env := callbackenv.Env{
+		InstallType:    version.Type,
+		InstallVersion: version.Value,
+		InstallPath:    buildDir,
+		DownloadPath:   downloadDir,
+		Concurrency:    concurrency,
+		ProvenanceFile: provenanceFile,
+		TmpDir:         tmpDir,
+		PatchesDir:     patchesDir,
+		Origin:         origin,
+	}.Map()
+
 	err = os.MkdirAll(downloadDir, 0o777)
 	if err != nil {
 		return fmt.Errorf("unable to create download dir: %w", err)
@@ -179,7 +423,7 @@ func InstallOneVersion(conf config.Config, plugin plugins.Plugin, versionStr str
 		return fmt.Errorf("failed to run pre-download hook: %w", err)
 	}
 
-	err = plugin.RunCallback("download", []string{}, env, stdOut, stdErr)
+	err = plugin.RunCallbackContext(ctx, "download", []string{}, env, stdOut, stdErr)
 	if _, ok := err.(plugins.NoCallbackError); err != nil && !ok {
 		return fmt.Errorf("failed to run download callback: %w", err)
 	}
@@ -189,19 +433,56 @@ func InstallOneVersion(conf config.Config, plugin plugins.Plugin, versionStr str
 		return fmt.Errorf("failed to run pre-install hook: %w", err)
 	}
 
-	err = os.MkdirAll(installDir, 0o777)
+	err = os.MkdirAll(buildDir, 0o777)
 	if err != nil {
 		return fmt.Errorf("unable to create install dir: %w", err)
 	}
 
-	err = plugin.RunCallback("install", []string{}, env, stdOut, stdErr)
+	if !replace {
+		err = installs.MarkIncomplete(conf, plugin, version)
+		if err != nil {
+			return fmt.Errorf("unable to mark install incomplete: %w", err)
+		}
+
+		err = installs.Added(conf, installDir)
+		if err != nil {
+			return fmt.Errorf("unable to record install: %w", err)
+		}
+	}
+
+	err = plugin.RunCallbackContext(ctx, "install", []string{}, env, stdOut, stdErr)
 	if err != nil {
+		if replace {
+			return fmt.Errorf("failed to run install callback: %w", err)
+		}
+
 		if rmErr := os.RemoveAll(installDir); rmErr != nil {
 			fmt.Fprintf(stdErr, "failed to clean up '%s' due to %s\n", installDir, rmErr)
+		} else if rmErr := installs.Removed(conf, installDir); rmErr != nil {
+			fmt.Fprintf(stdErr, "failed to remove '%s' from installs due to %s\n", installDir, rmErr)
 		}
 		return fmt.Errorf("failed to run install callback: %w", err)
 	}
 
+	if shared {
+		err = installs.SetSharedPermissions(buildDir)
+		if err != nil {
+			return fmt.Errorf("unable to set permissions on shared install: %w", err)
+		}
+	}
+
+	if replace {
+		err = replaceInstall(conf, plugin, version, buildDir, installDir, stdOut, stdErr)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = installs.MarkComplete(conf, plugin, version)
+		if err != nil {
+			return fmt.Errorf("unable to mark install complete: %w", err)
+		}
+	}
+
 	// Reshim
 	err = shims.GenerateAll(conf, stdOut, stdErr)
 	if err != nil {
@@ -213,6 +494,11 @@ func InstallOneVersion(conf config.Config, plugin plugins.Plugin, versionStr str
 		return fmt.Errorf("failed to run post-install hook: %w", err)
 	}
 
+	err = recordProvenance(conf, plugin, version, installDir, provenanceFile, started)
+	if err != nil {
+		fmt.Fprintf(stdErr, "warning: unable to record provenance of %s %s: %s\n", plugin.Name, version.Value, err)
+	}
+
 	// delete download dir
 	keep, err := conf.AlwaysKeepDownload()
 	if err != nil {
@@ -231,26 +517,91 @@ func InstallOneVersion(conf config.Config, plugin plugins.Plugin, versionStr str
 	return nil
 }
 
+// makeTmpDir creates a temporary directory for the install of a version under
+// the asdf managed temporary root of the data directory, so files left behind
+// by plugins that crash can be found and removed with `asdf cache clean --tmp`
+func makeTmpDir(dataDir string, plugin plugins.Plugin, version toolversions.Version) (string, error) {
+	root := data.TmpDirectory(dataDir)
+	if err := os.MkdirAll(root, 0o777); err != nil {
+		return "", err
+	}
+
+	return os.MkdirTemp(root, fmt.Sprintf("%s-%s-", strings.ReplaceAll(plugin.Name, "/", "-"), strings.ReplaceAll(version.Value, "/", "-")))
+}
+
+// remoteFallback resolves a constraint no installed version satisfies as set
+// by remote_fallback. With `report` an UnsatisfiedConstraintError naming the
+// newest version available satisfying it is returned, with `install` that
+// version is returned to be installed. A VersionAlreadyInstalledError is
+// returned when an installed version satisfies it. Other versions, and every
+// version with `no`, are returned as they are.
+func remoteFallback(conf config.Config, plugin plugins.Plugin, version string, constraints []string) (string, error) {
+	fallback, _ := conf.RemoteFallback()
+	if fallback == "no" || !resolve.IsConstraint(conf, plugin.Name, version) {
+		return version, nil
+	}
+
+	if match, ok := installedMatch(conf, plugin, version, constraints); ok {
+		return "", VersionAlreadyInstalledError{toolName: plugin.Name, version: toolversions.Parse(match)}
+	}
+
+	candidate, err := Constraint(conf, plugin, version, constraints)
+	if err != nil {
+		return "", err
+	}
+
+	// Versions naming an available version, as every version does for tools
+	// using the range strategy, are installed as they are set
+	if fallback == "report" && candidate != version {
+		return "", UnsatisfiedConstraintError{toolName: plugin.Name, constraint: version, candidate: candidate}
+	}
+
+	return candidate, nil
+}
+
+// installedMatch returns the newest installed version satisfying the
+// constraint that no exclusion among the constraints rules out
+func installedMatch(conf config.Config, plugin plugins.Plugin, constraint string, constraints []string) (string, bool) {
+	installed, err := installs.Installed(conf, plugin)
+	if err != nil {
+		return "", false
+	}
+
+	installed = slices.DeleteFunc(installed, func(version string) bool {
+		return !versionspec.Satisfies(version, constraint) || versionspec.Excluded(version, constraints)
+	})
+	if len(installed) == 0 {
+		return "", false
+	}
+
+	return slices.MaxFunc(installed, versionspec.CompareStrings), true
+}
+
 // Latest invokes the plugin's latest-stable callback if it exists and returns
 // the version it returns. If the callback is missing it invokes the list-all
-// callback and returns the last version matching the query, if a query is
-// provided.
-func Latest(plugin plugins.Plugin, query string) (version string, err error) {
+// callback and returns the newest version matching the query, if a query is
+// provided. For tools listed in allow_prereleases the latest-stable callback
+// is skipped, as it only returns stable versions, and the newest version
+// listed is returned even when it is a pre-release.
+func Latest(conf config.Config, plugin plugins.Plugin, query string) (version string, err error) {
 	var stdOut strings.Builder
 	var stdErr strings.Builder
 
-	err = plugin.RunCallback("latest-stable", []string{query}, map[string]string{}, &stdOut, &stdErr)
-	if err == nil {
-		versions := parseVersions(stdOut.String())
-		if len(versions) < 1 {
-			return version, errors.New(noLatestVersionErrMsg)
+	prereleases := resolve.AllowsPrereleases(conf, plugin.Name)
+	if !prereleases {
+		err = plugin.RunCallback("latest-stable", []string{query}, CollationEnv(), &stdOut, &stdErr)
+		if err == nil {
+			versions := parseVersions(plugin.VersionOutput("latest-stable", stdOut.String(), os.Stderr))
+			if len(versions) < 1 {
+				return version, errors.New(noLatestVersionErrMsg)
+			}
+			return versions[len(versions)-1], nil
 		}
-		return versions[len(versions)-1], nil
-	}
 
-	// Fallback to list-all if latest-stable fails
-	if _, ok := err.(plugins.NoCallbackError); !ok {
-		return version, err
+		// Fallback to list-all if latest-stable fails
+		if _, ok := err.(plugins.NoCallbackError); !ok {
+			return version, err
+		}
 	}
 
 	allVersions, err := AllVersions(plugin)
@@ -258,7 +609,7 @@ func Latest(plugin plugins.Plugin, query string) (version string, err error) {
 		return version, err
 	}
 
-	versions := filterByRegex(allVersions, latestFilterRegex, false)
+	versions := filterLatest(allVersions, prereleases)
 
 	// If no query specified by user default to selecting version with numeric start
 	if query == "" {
@@ -271,7 +622,48 @@ func Latest(plugin plugins.Plugin, query string) (version string, err error) {
 		return version, errors.New(noLatestVersionErrMsg)
 	}
 
-	return versions[len(versions)-1], nil
+	return slices.MaxFunc(versions, versionspec.CompareStrings), nil
+}
+
+// Wildcard returns the newest stable version available matching a wildcard
+// version like `18.x` or `3.12.*`, as listed by the plugin's list-all callback,
+// skipping versions ruled out by exclusions among the constraints. Pre-release
+// versions match too for tools listed in allow_prereleases.
+func Wildcard(conf config.Config, plugin plugins.Plugin, wildcard string, constraints []string) (string, error) {
+	return newestAvailable(conf, plugin, wildcard, constraints, func(version string) bool {
+		return versionspec.MatchesWildcard(version, wildcard)
+	})
+}
+
+// Constraint returns the newest stable version available satisfying a
+// constraint like `>=18`, or `18` for tools using the range strategy, as
+// listed by the plugin's list-all callback, skipping versions ruled out by
+// exclusions among the constraints. Pre-release versions satisfy it too for
+// tools listed in allow_prereleases.
+func Constraint(conf config.Config, plugin plugins.Plugin, constraint string, constraints []string) (string, error) {
+	return newestAvailable(conf, plugin, constraint, constraints, func(version string) bool {
+		return versionspec.Satisfies(version, constraint)
+	})
+}
+
+// newestAvailable returns the newest version listed by the plugin's list-all
+// callback, cached as ListAll does, that matches, isn't a pre-release, unless the tool allows them,
+// and isn't ruled out by exclusions among the constraints
+func newestAvailable(conf config.Config, plugin plugins.Plugin, spec string, constraints []string, matches func(version string) bool) (string, error) {
+	allVersions, err := ListAll(conf, plugin, false, io.Discard)
+	if err != nil {
+		return "", err
+	}
+
+	prereleases := resolve.AllowsPrereleases(conf, plugin.Name)
+	versions := slices.DeleteFunc(filterLatest(allVersions, prereleases), func(version string) bool {
+		return !matches(version) || (!prereleases && versionspec.Parse(version).Prerelease()) || versionspec.Excluded(version, constraints)
+	})
+	if len(versions) < 1 {
+		return "", fmt.Errorf("no version of %s matches %s", plugin.Name, spec)
+	}
+
+	return slices.MaxFunc(versions, versionspec.CompareStrings), nil
 }
 
 // AllVersions returns a slice of all available versions for the tool managed by
@@ -280,12 +672,12 @@ func AllVersions(plugin plugins.Plugin) (versions []string, err error) {
 	var stdout strings.Builder
 	var stderr strings.Builder
 
-	err = plugin.RunCallback("list-all", []string{}, map[string]string{}, &stdout, &stderr)
+	err = plugin.RunCallback("list-all", []string{}, CollationEnv(), &stdout, &stderr)
 	if err != nil {
 		return versions, err
 	}
 
-	versions = parseVersions(stdout.String())
+	versions = parseVersions(plugin.VersionOutput("list-all", stdout.String(), os.Stderr))
 
 	return versions, err
 }
@@ -294,39 +686,99 @@ func AllVersions(plugin plugins.Plugin) (versions []string, err error) {
 // post-uninstall hooks if set, and runs the plugin's uninstall callback if
 // defined.
 func Uninstall(conf config.Config, plugin plugins.Plugin, rawVersion string, stdout, stderr io.Writer) error {
+	return uninstall(conf, plugin, rawVersion, callbackenv.UninstallRequested, stdout, stderr)
+}
+
+// removeExcluded removes what is left of an install of the version excluded by
+// the exclude_installs setting, such as one that never finished, so it's
+// installed again from scratch rather than on top of it
+func removeExcluded(conf config.Config, plugin plugins.Plugin, version toolversions.Version) error {
+	if !installs.Exists(conf, plugin, version) {
+		return nil
+	}
+
+	installDir := installs.InstallPath(conf, plugin, version)
+	metadataDir := installs.MetadataPath(conf, plugin, version)
+	if err := os.RemoveAll(installDir); err != nil {
+		return err
+	}
+
+	if err := installs.Removed(conf, installDir); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(metadataDir)
+}
+
+// uninstall removes the version, running in order the pre_asdf_uninstall hook,
+// the plugin's pre-uninstall and uninstall callbacks, and once the version is
+// removed the plugin's post-uninstall callback and the post_asdf_uninstall
+// hook. The pre and post-uninstall callbacks let plugins clean up state kept
+// outside the install directory, such as caches or registrations, which is
+// otherwise left behind. Callbacks and hooks all get the environment of the
+// version along with the reason it is uninstalled.
+func uninstall(conf config.Config, plugin plugins.Plugin, rawVersion, reason string, stdout, stderr io.Writer) error {
 	version := toolversions.ParseFromCliArg(rawVersion)
 
 	if version.Type == "latest" {
 		return errors.New("'latest' is a special version value that cannot be used for uninstall command")
 	}
 
-	if !installs.IsInstalled(conf, plugin, version) {
+	if !installs.Exists(conf, plugin, version) {
 		return errors.New("No such version")
 	}
 
-	err := hook.RunWithOutput(conf, fmt.Sprintf("pre_asdf_uninstall_%s", plugin.Name), []string{version.Value}, stdout, stderr)
+	installDir := installs.InstallPath(conf, plugin, version)
+	metadataDir := installs.MetadataPath(conf, plugin, version)
+	return runUninstall(conf, plugin, version, installDir, reason, func() error {
+		if err := os.RemoveAll(installDir); err != nil {
+			return err
+		}
+
+		if err := installs.Removed(conf, installDir); err != nil {
+			return err
+		}
+
+		return os.RemoveAll(metadataDir)
+	}, stdout, stderr)
+}
+
+// runUninstall runs the uninstall hooks and callbacks of the version installed
+// at installDir for the reason, calling remove once the uninstall callback has
+// run to remove the install
+func runUninstall(conf config.Config, plugin plugins.Plugin, version toolversions.Version, installDir, reason string, remove func() error, stdout, stderr io.Writer) error {
+	callbackEnv := callbackenv.ForVersion(conf, plugin, version)
+	callbackEnv.InstallPath = installDir
+	callbackEnv.UninstallReason = reason
+	env := callbackEnv.Map()
+
+	err := hook.RunWithEnv(conf, fmt.Sprintf("pre_asdf_uninstall_%s", plugin.Name), []string{version.Value}, env, stdout, stderr)
 	if err != nil {
 		return err
 	}
 
-	// invoke uninstall callback if available
-	installDir := installs.InstallPath(conf, plugin, version)
-	env := map[string]string{
-		"ASDF_INSTALL_TYPE":    version.Type,
-		"ASDF_INSTALL_VERSION": version.Value,
-		"ASDF_INSTALL_PATH":    installDir,
+	err = plugin.RunCallback("pre-uninstall", []string{}, env, stdout, stderr)
+	if _, ok := err.(plugins.NoCallbackError); !ok && err != nil {
+		return err
 	}
+
+	// invoke uninstall callback if available
 	err = plugin.RunCallback("uninstall", []string{}, env, stdout, stderr)
 	if _, ok := err.(plugins.NoCallbackError); !ok && err != nil {
 		return err
 	}
 
-	err = os.RemoveAll(installDir)
+	err = remove()
 	if err != nil {
 		return err
 	}
 
-	err = hook.RunWithOutput(conf, fmt.Sprintf("post_asdf_uninstall_%s", plugin.Name), []string{version.Value}, stdout, stderr)
+	err = plugin.RunCallback("post-uninstall", []string{}, env, stdout, stderr)
+	if _, ok := err.(plugins.NoCallbackError); !ok && err != nil {
+		return fmt.Errorf("%s %s was uninstalled but its post-uninstall callback failed: %w", plugin.Name, version.Value, err)
+	}
+
+	err = hook.RunWithEnv(conf, fmt.Sprintf("post_asdf_uninstall_%s", plugin.Name), []string{version.Value}, env, stdout, stderr)
 	if err != nil {
 		return err
 	}
@@ -334,6 +786,100 @@ func Uninstall(conf config.Config, plugin plugins.Plugin, rawVersion string, std
 	return nil
 }
 
+// replaceInstall renames the new build of a version over its install
+// directory. The previous install is moved aside first and then uninstalled
+// with the rebuild reason, so plugins can clean up what they kept for it.
+func replaceInstall(conf config.Config, plugin plugins.Plugin, version toolversions.Version, buildDir, installDir string, stdout, stderr io.Writer) error {
+	previousDir := buildDir + "-previous"
+	if err := os.Rename(installDir, previousDir); err != nil {
+		return fmt.Errorf("unable to move previous install aside: %w", err)
+	}
+
+	if err := os.Rename(buildDir, installDir); err != nil {
+		if rbErr := os.Rename(previousDir, installDir); rbErr != nil {
+			fmt.Fprintf(stderr, "failed to restore '%s' due to %s\n", installDir, rbErr)
+		}
+		return fmt.Errorf("unable to replace install dir: %w", err)
+	}
+
+	err := runUninstall(conf, plugin, version, previousDir, callbackenv.UninstallRebuild, func() error {
+		return os.RemoveAll(previousDir)
+	}, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("%s %s was rebuilt but removing the previous install failed: %w", plugin.Name, version.Value, err)
+	}
+
+	return nil
+}
+
+// UntrackedRefError is returned when a ref version was installed without the
+// plugin recording the repository and commit it was built from
+type UntrackedRefError struct {
+	toolName string
+	ref      string
+}
+
+func (e UntrackedRefError) Error() string {
+	return fmt.Sprintf("unable to check %s ref:%s for updates, the plugin did not record source_url and commit", e.toolName, e.ref)
+}
+
+// RefStatus holds the commit an installed ref version was built from and the
+// commit the ref points to upstream
+type RefStatus struct {
+	Installed string
+	Upstream  string
+}
+
+// Stale returns true when the ref has moved since the version was installed
+func (s RefStatus) Stale() bool {
+	return s.Installed != s.Upstream
+}
+
+// CheckRef compares the commit an installed ref version was built from with
+// the commit the ref currently points to in the repository it came from
+func CheckRef(conf config.Config, plugin plugins.Plugin, version toolversions.Version) (status RefStatus, err error) {
+	record, err := provenance.Read(installs.MetadataPath(conf, plugin, version), plugin.Name, toolversions.Format(version))
+	if _, ok := err.(provenance.NoRecordError); ok || (err == nil && (record.SourceURL == "" || record.Commit == "")) {
+		return status, UntrackedRefError{toolName: plugin.Name, ref: version.Value}
+	}
+
+	if err != nil {
+		return status, err
+	}
+
+	upstream, err := git.RemoteCommit(record.SourceURL, version.Value)
+	if err != nil {
+		return status, err
+	}
+
+	return RefStatus{Installed: record.Commit, Upstream: upstream}, nil
+}
+
+// RefreshRef installs a ref version, rebuilding it if it is already installed
+// and the ref has moved upstream. The installed version is only replaced once
+// the rebuild has succeeded. It returns true if the version was rebuilt.
+// Versions that aren't refs are ignored.
+func RefreshRef(conf config.Config, plugin plugins.Plugin, versionStr string, stdOut io.Writer, stdErr io.Writer) (bool, error) {
+	version := toolversions.Parse(versionStr)
+	if version.Type != "ref" {
+		return false, nil
+	}
+
+	if !installs.IsInstalled(conf, plugin, version) {
+		return false, InstallOneVersion(conf, plugin, versionStr, false, stdOut, stdErr)
+	}
+
+	status, err := CheckRef(conf, plugin, version)
+	if err != nil || !status.Stale() {
+		return false, err
+	}
+
+	fmt.Fprintf(stdErr, "%s ref:%s moved from %s to %s, rebuilding\n", plugin.Name, version.Value, status.Installed, status.Upstream)
+	installDir := installs.InstallPath(conf, plugin, version)
+	shared := filepath.Dir(installDir) != data.InstallDirectory(conf.DataDir, plugin.Name)
+	return true, build(context.Background(), conf, plugin, version, installDir, shared, true, false, callbackenv.Origin{}, stdOut, stdErr)
+}
+
 func filterByExactMatch(allVersions []string, pattern string) (versions []string) {
 	for _, version := range allVersions {
 		if strings.HasPrefix(version, pattern) {
@@ -344,6 +890,20 @@ func filterByExactMatch(allVersions []string, pattern string) (versions []string
 	return versions
 }
 
+// filterLatest drops the versions `latest` and wildcard versions never resolve
+// to, such as development builds and pre-release versions. Versions parsed as
+// pre-releases are kept when prereleases is true.
+func filterLatest(allVersions []string, prereleases bool) (versions []string) {
+	regex := regexp.MustCompile(latestFilterRegex)
+	for _, version := range allVersions {
+		if !regex.MatchString(version) || (prereleases && versionspec.Parse(version).Prerelease()) {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
+
 func filterByRegex(allVersions []string, pattern string, keepMatch bool) (versions []string) {
 	regex, _ := regexp.Compile(pattern)
 	for _, version := range allVersions {
@@ -356,15 +916,137 @@ func filterByRegex(allVersions []string, pattern string, keepMatch bool) (versio
 	return versions
 }
 
-// future refactoring opportunity: this function is an exact copy of
-// resolve.parseVersion
-func parseVersions(rawVersions string) []string {
-	var versions []string
-	for _, version := range strings.Split(rawVersions, " ") {
-		version = strings.TrimSpace(version)
-		if len(version) > 0 {
-			versions = append(versions, version)
+func recordProvenance(conf config.Config, plugin plugins.Plugin, version toolversions.Version, installDir, pluginFile string, started time.Time) error {
+	record := provenance.Record{
+		Tool:        plugin.Name,
+		Version:     toolversions.Format(version),
+		Platform:    installs.Platform(),
+		InstalledAt: started.UTC().Truncate(time.Second),
+		Duration:    time.Since(started).Round(time.Millisecond).String(),
+	}
+
+	// Plugins that aren't Git repositories have no URL or ref to record
+	repo := git.NewRepo(plugin.Dir)
+	record.PluginRef, _ = repo.Head()
+	if url, err := repo.RemoteURL(); err == nil {
+		record.PluginURL = strings.TrimSpace(url)
+	}
+
+	if err := provenance.ReadPluginFile(pluginFile, &record); err != nil {
+		return err
+	}
+
+	metadataDir := installs.MetadataPath(conf, plugin, version)
+	if err := provenance.WriteManifest(metadataDir, installDir); err != nil {
+		return err
+	}
+
+	return provenance.Write(metadataDir, record)
+}
+
+// ListAll returns the versions reported by the plugin's list-all callback,
+// caching them in the data directory for list_all_cache_duration minutes so
+// repeated listings don't hit the network.
+//
+// Once the cache expires the callback is run with ASDF_LIST_ALL_SINCE set to
+// the newest cached version. Plugins supporting incremental listing print only
+// versions released after it, which are appended to the cache. Plugins that
+// ignore the variable print every version, and their output replaces the
+// cache, see mergeListing. When refresh is true the cache is ignored and
+// rebuilt from a full listing. Output written to stderr by the callback is
+// copied to stdErr.
+func ListAll(conf config.Config, plugin plugins.Plugin, refresh bool, stdErr io.Writer) (versions []string, err error) {
+	duration, err := conf.ListAllCacheDuration()
+	if err != nil {
+		return versions, err
+	}
+
+	cacheFile := filepath.Join(data.CacheDirectory(conf.DataDir, plugin.Name), listAllCacheFilename)
+	cached, modified, cacheErr := readListAllCache(cacheFile)
+	if duration == 0 || refresh || cacheErr != nil || len(cached) == 0 {
+		cached = []string{}
+	} else if time.Since(modified) < time.Duration(duration)*time.Minute {
+		return cached, nil
+	}
+
+	env := CollationEnv()
+	since := ""
+	if len(cached) > 0 {
+		since = cached[len(cached)-1]
+		env["ASDF_LIST_ALL_SINCE"] = since
+	}
+
+	var stdout strings.Builder
+	err = plugin.RunCallback("list-all", []string{}, env, &stdout, stdErr)
+	if err != nil {
+		return versions, err
+	}
+
+	versions = parseVersions(plugin.VersionOutput("list-all", stdout.String(), stdErr))
+	if since != "" {
+		versions = mergeListing(cached, versions)
+	}
+
+	if duration > 0 {
+		// Failing to write the cache only makes the next listing slower
+		_ = writeListAllCache(cacheFile, versions)
+	}
+
+	return versions, nil
+}
+
+// mergeListing merges the versions listed with ASDF_LIST_ALL_SINCE set into the
+// cached versions. A listing without any cached version only has versions
+// released since, which are appended. Any other listing is a full one, even
+// when the version it was asked to start from was removed upstream, and
+// replaces the cached versions. Each version is only kept once.
+func mergeListing(cached, listed []string) []string {
+	seen := map[string]bool{}
+	for _, version := range cached {
+		seen[version] = true
+	}
+
+	var merged []string
+	if !slices.ContainsFunc(listed, func(version string) bool { return seen[version] }) {
+		merged = slices.Clone(cached)
+	} else {
+		clear(seen)
+	}
+
+	for _, version := range listed {
+		if !seen[version] {
+			seen[version] = true
+			merged = append(merged, version)
 		}
 	}
-	return versions
+
+	return merged
+}
+
+func readListAllCache(cacheFile string) (versions []string, modified time.Time, err error) {
+	info, err := os.Stat(cacheFile)
+	if err != nil {
+		return versions, modified, err
+	}
+
+	contents, err := os.ReadFile(cacheFile)
+	if err != nil {
+		return versions, modified, err
+	}
+
+	return strings.Fields(string(contents)), info.ModTime(), nil
+}
+
+func writeListAllCache(cacheFile string, versions []string) error {
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(cacheFile, []byte(strings.Join(versions, "\n")+"\n"), 0o666)
+}
+
+// parseVersions splits callback output into versions, which may be separated
+// by spaces or newlines
+func parseVersions(rawVersions string) []string {
+	return strings.Fields(rawVersions)
 }
@@ -1,14 +1,20 @@
 package versions
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/asdf-vm/asdf/internal/config"
+	"github.com/asdf-vm/asdf/internal/installs"
 	"github.com/asdf-vm/asdf/internal/plugins"
+	"github.com/asdf-vm/asdf/internal/provenance"
 	"github.com/asdf-vm/asdf/internal/repotest"
 	"github.com/asdf-vm/asdf/internal/toolversions"
 	"github.com/stretchr/testify/assert"
@@ -72,6 +78,75 @@ func TestInstallAll(t *testing.T) {
 	})
 }
 
+func TestInstallAllRequirements(t *testing.T) {
+	t.Run("installs required tools first", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		currentDir := t.TempDir()
+		secondPlugin := installPlugin(t, conf, "dummy_plugin", "another")
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		hooks := "post_asdf_install_testlua = echo installed testlua\npost_asdf_install_another = echo installed another\n"
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte(hooks), 0o666))
+
+		content := fmt.Sprintf("%s 1.0.0 # asdf:requires %s\n%s 1.0.0\n", secondPlugin.Name, plugin.Name, plugin.Name)
+		writeVersionFile(t, currentDir, content)
+
+		err := InstallAll(conf, currentDir, &stdout, &stderr)
+		assert.Empty(t, err)
+
+		assert.Equal(t, "installed testlua\ninstalled another\n", stdout.String())
+		assertVersionInstalled(t, conf.DataDir, secondPlugin.Name, "1.0.0")
+	})
+
+	t.Run("skips tools requiring a tool that failed to install", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		currentDir := t.TempDir()
+		secondPlugin := installPlugin(t, conf, "dummy_plugin", "another")
+
+		content := fmt.Sprintf("%s 1.0.0 # asdf:requires %s\n%s other-dummy\n", secondPlugin.Name, plugin.Name, plugin.Name)
+		writeVersionFile(t, currentDir, content)
+
+		err := InstallAll(conf, currentDir, &stdout, &stderr)
+		assert.Len(t, err, 2)
+		assert.ErrorContains(t, err[1], "not installing another because testlua, which it requires, failed to install")
+
+		assertNotInstalled(t, conf.DataDir, secondPlugin.Name, "1.0.0")
+	})
+
+	t.Run("installs nothing when tools require each other", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		currentDir := t.TempDir()
+		secondPlugin := installPlugin(t, conf, "dummy_plugin", "another")
+
+		content := fmt.Sprintf("%s 1.0.0 # asdf:requires %s\n%s 1.0.0 # asdf:requires %s\n", secondPlugin.Name, plugin.Name, plugin.Name, secondPlugin.Name)
+		writeVersionFile(t, currentDir, content)
+
+		err := InstallAll(conf, currentDir, &stdout, &stderr)
+		assert.Len(t, err, 1)
+		assert.ErrorContains(t, err[0], "tools require each other: another, testlua")
+
+		assertNotInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+	})
+}
+
+func TestInstallOrder(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	currentDir := t.TempDir()
+	secondPlugin := installPlugin(t, conf, "dummy_plugin", "another")
+	thirdPlugin := installPlugin(t, conf, "dummy_plugin", "third")
+
+	content := fmt.Sprintf("%s 1.0.0 # asdf:requires %s missing\n%s 1.0.0\n%s 1.0.0 # asdf:requires %s\n", plugin.Name, thirdPlugin.Name, thirdPlugin.Name, secondPlugin.Name, plugin.Name)
+	writeVersionFile(t, currentDir, content)
+
+	tools := []plugins.Plugin{secondPlugin, plugin, thirdPlugin}
+	ordered, requirements, err := InstallOrder(conf, tools, Resolve(context.Background(), conf, tools, currentDir))
+	assert.Nil(t, err)
+	assert.Equal(t, []plugins.Plugin{thirdPlugin, plugin, secondPlugin}, ordered)
+	assert.Equal(t, map[string][]string{plugin.Name: {thirdPlugin.Name}, secondPlugin.Name: {plugin.Name}}, requirements)
+}
+
 func TestInstall(t *testing.T) {
 	conf, plugin := generateConfig(t)
 	stdout, stderr := buildOutputs()
@@ -90,6 +165,132 @@ func TestInstall(t *testing.T) {
 		assertVersionInstalled(t, conf.DataDir, plugin.Name, version)
 	})
 
+	t.Run("skips exclusions of versions set for current directory", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" 1.0.0 !=1.0.1"), 0o666))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+		entries, err := os.ReadDir(filepath.Join(conf.DataDir, "installs", plugin.Name))
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("passes project directory and version source to install callback", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		subDir := filepath.Join(projectDir, "sub")
+		assert.Nil(t, os.MkdirAll(subDir, 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" 1.0.0"), 0o666))
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "install", "#!/usr/bin/env bash\nmkdir -p \"$ASDF_INSTALL_PATH\"\necho \"$ASDF_PROJECT_DIR $ASDF_VERSION_SOURCE\" > \"$ASDF_INSTALL_PATH/origin\"\n"))
+
+		err := Install(conf, plugin, subDir, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		origin, err := os.ReadFile(filepath.Join(conf.DataDir, "installs", plugin.Name, "1.0.0", "origin"))
+		assert.Nil(t, err)
+		assert.Equal(t, subDir+" "+filepath.Join(projectDir, ".tool-versions")+"\n", string(origin))
+	})
+
+	t.Run("installs newest version matching latest version specified for current directory", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" latest:1\n"), 0o666))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.1.0")
+	})
+
+	t.Run("only checks for newer latest version when latest_remote is set", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" latest:1\n"), 0o666))
+		assert.Nil(t, InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.ErrorAs(t, err, &VersionAlreadyInstalledError{})
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "installs", plugin.Name, "1.1.0"))
+
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("latest_remote = yes\n"), 0o666))
+		err = Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.1.0")
+	})
+
+	t.Run("installs newest version matching wildcard version specified for current directory", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" 1.x !=1.1.0\n"), 0o666))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "installs", plugin.Name, "1.1.0"))
+	})
+
+	t.Run("does not install wildcard version matched by installed version", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" 1.*\n"), 0o666))
+		assert.Nil(t, InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.ErrorAs(t, err, &VersionAlreadyInstalledError{})
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "installs", plugin.Name, "1.1.0"))
+	})
+
+	t.Run("returns error when no version matches wildcard", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" 3.x\n"), 0o666))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.ErrorContains(t, err, "no version of "+plugin.Name+" matches 3.x")
+	})
+
+	t.Run("installs newest version satisfying constraint when remote_fallback is install", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" >=1.0.0 !=2.0.0\n"), 0o666))
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("remote_fallback = install\n"), 0o666))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.1.0")
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "installs", plugin.Name, "2.0.0"))
+
+		err = Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.ErrorAs(t, err, &VersionAlreadyInstalledError{})
+	})
+
+	t.Run("reports newest version satisfying constraint when remote_fallback is report", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		projectDir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(projectDir, ".tool-versions"), []byte(plugin.Name+" >=1.0.0\n"), 0o666))
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("remote_fallback = report\n"), 0o666))
+
+		err := Install(conf, plugin, projectDir, &stdout, &stderr)
+		assert.ErrorAs(t, err, &UnsatisfiedConstraintError{})
+		assert.ErrorContains(t, err, "the newest available is 2.0.0, install it with `asdf install "+plugin.Name+" 2.0.0`")
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "installs", plugin.Name))
+	})
+
 	t.Run("returns error when plugin doesn't exist", func(t *testing.T) {
 		conf, _ := generateConfig(t)
 		stdout, stderr := buildOutputs()
@@ -234,6 +435,97 @@ func TestInstallOneVersion(t *testing.T) {
 		assert.ErrorAs(t, err, &eerr)
 	})
 
+	t.Run("installs again version whose install never finished", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		version := toolversions.Version{Type: "version", Value: "1.0.0"}
+		installDir := installs.InstallPath(conf, plugin, version)
+		assert.Nil(t, os.MkdirAll(installDir, 0o777))
+		assert.Nil(t, os.WriteFile(filepath.Join(installDir, "leftover"), []byte{}, 0o666))
+		assert.Nil(t, installs.MarkIncomplete(conf, plugin, version))
+
+		err := InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+		assert.NoFileExists(t, filepath.Join(installDir, "leftover"))
+		assert.False(t, installs.Incomplete(conf, plugin, version))
+	})
+
+	t.Run("installs newest version satisfying constraint when remote_fallback is install", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("remote_fallback = install\n"), 0o666))
+
+		err := InstallOneVersion(conf, plugin, "<2", false, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.1.0")
+	})
+
+	t.Run("reports newest version satisfying constraint when remote_fallback is report", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("remote_fallback = report\n[match]\n"+plugin.Name+" = range\n"), 0o666))
+
+		err := InstallOneVersion(conf, plugin, "<2", false, &stdout, &stderr)
+		assert.ErrorAs(t, err, &UnsatisfiedConstraintError{})
+		assert.ErrorContains(t, err, "the newest available is 1.1.0")
+		assertNotInstalled(t, conf.DataDir, plugin.Name, "1.1.0")
+
+		err = InstallOneVersion(conf, plugin, "1.1.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.1.0")
+	})
+
+	t.Run("warns when version is deprecated", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "list-deprecated", "#!/usr/bin/env bash\necho '1 end of life'\n"))
+
+		err := InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, "warning: testlua 1.0.0 is deprecated: end of life\n", stderr.String())
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+	})
+
+	t.Run("returns error when version is deprecated and deprecated_versions is error", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.Settings = config.Settings{Loaded: true, DeprecatedVersions: "error"}
+		stdout, stderr := buildOutputs()
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "list-deprecated", "#!/usr/bin/env bash\necho '1.0.0'\n"))
+
+		err := InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.ErrorContains(t, err, "testlua 1.0.0 is deprecated")
+		assert.IsType(t, DeprecatedVersionError{}, err)
+		assertNotInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+	})
+
+	t.Run("records provenance of installed version", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+
+		installScript := filepath.Join(plugin.Dir, "bin", "install")
+		f, err := os.OpenFile(installScript, os.O_APPEND|os.O_WRONLY, 0o777)
+		assert.Nil(t, err)
+		_, err = f.WriteString("\necho source_url=https://example.com/lua.tar.gz > \"$ASDF_PROVENANCE_FILE\"")
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+
+		err = InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		version := toolversions.Version{Type: "version", Value: "1.0.0"}
+		record, err := provenance.Read(installs.MetadataPath(conf, plugin, version), plugin.Name, "1.0.0")
+		assert.Nil(t, err)
+		assert.Equal(t, "testlua", record.Tool)
+		assert.Equal(t, "1.0.0", record.Version)
+		assert.Equal(t, "https://example.com/lua.tar.gz", record.SourceURL)
+		assert.NotEmpty(t, record.PluginRef)
+		assert.NotEmpty(t, record.Duration)
+		assert.False(t, record.InstalledAt.IsZero())
+	})
+
 	t.Run("creates download directory", func(t *testing.T) {
 		conf, plugin := generateConfig(t)
 		stdout, stderr := buildOutputs()
@@ -278,6 +570,47 @@ func TestInstallOneVersion(t *testing.T) {
 		assert.True(t, os.IsNotExist(err))
 	})
 
+	t.Run("removes temporary directory when install succeeds", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "install", "#!/usr/bin/env bash\ntouch \"$TMPDIR/build.log\"\n"))
+
+		err := InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		entries, err := os.ReadDir(filepath.Join(conf.DataDir, "tmp"))
+		assert.Nil(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("keeps temporary directory when install fails", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "install", "#!/usr/bin/env bash\ntouch \"$TMPDIR/build.log\"\nexit 1\n"))
+
+		err := InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Error(t, err)
+
+		logs, err := filepath.Glob(filepath.Join(conf.DataDir, "tmp", "testlua-1.0.0-*", "build.log"))
+		assert.Nil(t, err)
+		assert.Len(t, logs, 1)
+		assert.Contains(t, stderr.String(), "temporary files kept for debugging in "+filepath.Dir(logs[0]))
+	})
+
+	t.Run("kills install callback and removes install directory when context is done", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "install", "#!/usr/bin/env bash\nmkdir -p \"$ASDF_INSTALL_PATH\"\nsleep 30\n"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		err := InstallOneVersionContext(ctx, conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.0.0")))
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "installs", plugin.Name, "1.0.0"))
+	})
+
 	t.Run("runs pre-download, pre-install and post-install hooks when installation successful", func(t *testing.T) {
 		conf, plugin := generateConfig(t)
 		stdout, stderr := buildOutputs()
@@ -334,7 +667,7 @@ func TestLatest(t *testing.T) {
 		assert.Nil(t, err)
 		plugin := plugins.New(conf, pluginName)
 
-		version, err := Latest(plugin, "")
+		version, err := Latest(conf, plugin, "")
 		assert.Nil(t, err)
 		assert.Equal(t, "2.0.0", version)
 	})
@@ -350,25 +683,25 @@ func TestLatest(t *testing.T) {
 		err = os.WriteFile(latestScript, []byte("#!/usr/bin/env bash\necho 1.2.3-dev"), 0o777)
 		assert.Nil(t, err)
 
-		version, err := Latest(plugin, "")
+		version, err := Latest(conf, plugin, "")
 		assert.Nil(t, err)
 		assert.Equal(t, "1.2.3-dev", version)
 	})
 
 	t.Run("when given query matching no versions return empty slice of versions", func(t *testing.T) {
-		version, err := Latest(plugin, "impossible-to-satisfy-query")
+		version, err := Latest(conf, plugin, "impossible-to-satisfy-query")
 		assert.Error(t, err, "no latest version found")
 		assert.Equal(t, version, "")
 	})
 
 	t.Run("when given no query returns latest version of plugin", func(t *testing.T) {
-		version, err := Latest(plugin, "")
+		version, err := Latest(conf, plugin, "")
 		assert.Nil(t, err)
 		assert.Equal(t, "5.1.0", version)
 	})
 
 	t.Run("when given no query returns latest version of plugin", func(t *testing.T) {
-		version, err := Latest(plugin, "4")
+		version, err := Latest(conf, plugin, "4")
 		assert.Nil(t, err)
 		assert.Equal(t, "4.0.0", version)
 	})
@@ -387,10 +720,108 @@ func TestLatest(t *testing.T) {
 		err = os.Remove(latestScript)
 		assert.Nil(t, err)
 
-		version, err := Latest(plugin, "")
+		version, err := Latest(conf, plugin, "")
 		assert.Nil(t, err)
 		assert.Equal(t, "3.4.5", version)
 	})
+
+	t.Run("when list-all output is unsorted returns newest version", func(t *testing.T) {
+		pluginName := "latest-unsorted"
+		pluginDir, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, pluginName)
+		assert.Nil(t, err)
+		plugin := plugins.New(conf, pluginName)
+
+		listAllScript := filepath.Join(pluginDir, "bin", "list-all")
+		err = os.WriteFile(listAllScript, []byte("#!/usr/bin/env bash\necho 1.10.0 1.9.0 1.2.0"), 0o777)
+		assert.Nil(t, err)
+		assert.Nil(t, os.Remove(filepath.Join(pluginDir, "bin", "latest-stable")))
+
+		version, err := Latest(conf, plugin, "1")
+		assert.Nil(t, err)
+		assert.Equal(t, "1.10.0", version)
+	})
+
+	t.Run("when tool allows prereleases skips latest-stable callback and returns newest version listed", func(t *testing.T) {
+		pluginName := "latest-prereleases"
+		pluginDir, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, pluginName)
+		assert.Nil(t, err)
+		plugin := plugins.New(conf, pluginName)
+		assert.Nil(t, os.WriteFile(filepath.Join(pluginDir, "bin", "list-all"), []byte("#!/usr/bin/env bash\necho 1.1.0 1.2.0-rc1 1.2.0-dev"), 0o777))
+
+		version, err := Latest(conf, plugin, "")
+		assert.Nil(t, err)
+		assert.Equal(t, "2.0.0", version)
+
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("allow_prereleases = "+pluginName+"\n"), 0o666))
+
+		version, err = Latest(conf, plugin, "")
+		assert.Nil(t, err)
+		assert.Equal(t, "1.2.0-rc1", version)
+	})
+}
+
+func TestWildcard(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "list-all"), []byte("#!/usr/bin/env bash\necho 1.1.0 1.2.0-rc1 1.1.1 2.0.0"), 0o777))
+
+	t.Run("returns newest stable version matching wildcard", func(t *testing.T) {
+		version, err := Wildcard(conf, plugin, "1.x", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "1.1.1", version)
+	})
+
+	t.Run("skips versions excluded by constraints", func(t *testing.T) {
+		version, err := Wildcard(conf, plugin, "1.x", []string{"1.x", "!=1.1.1"})
+		assert.Nil(t, err)
+		assert.Equal(t, "1.1.0", version)
+	})
+
+	t.Run("returns newest prerelease matching wildcard when tool allows prereleases", func(t *testing.T) {
+		conf := conf
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("allow_prereleases = *\n"), 0o666))
+
+		version, err := Wildcard(conf, plugin, "1.x", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "1.2.0-rc1", version)
+	})
+}
+
+func TestConstraint(t *testing.T) {
+	conf, plugin := generateConfig(t)
+	assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "list-all"), []byte("#!/usr/bin/env bash\necho 1.1.0 1.2.0-rc1 1.1.1 2.0.0"), 0o777))
+
+	t.Run("returns newest stable version satisfying constraint", func(t *testing.T) {
+		version, err := Constraint(conf, plugin, "<2", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "1.1.1", version)
+	})
+
+	t.Run("skips versions excluded by constraints", func(t *testing.T) {
+		version, err := Constraint(conf, plugin, ">=1.0", []string{">=1.0", "!=2.0.0"})
+		assert.Nil(t, err)
+		assert.Equal(t, "1.1.1", version)
+	})
+
+	t.Run("returns error when no version satisfies constraint", func(t *testing.T) {
+		_, err := Constraint(conf, plugin, ">2.0.0", nil)
+		assert.ErrorContains(t, err, "no version of "+plugin.Name+" matches >2.0.0")
+	})
+
+	t.Run("uses cached list-all output", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.ConfigFile = filepath.Join(t.TempDir(), "asdfrc")
+		assert.Nil(t, os.WriteFile(conf.ConfigFile, []byte("list_all_cache_duration = 60\n"), 0o666))
+		_, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "list-all"), []byte("#!/usr/bin/env bash\nexit 1"), 0o777))
+
+		version, err := Constraint(conf, plugin, ">=1.0", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "2.0.0", version)
+	})
 }
 
 func TestLatestWithSamples(t *testing.T) {
@@ -424,12 +855,127 @@ func TestLatestWithSamples(t *testing.T) {
 		assert.Nil(t, err)
 
 		plugin := plugins.New(conf, pluginName)
-		version, err := Latest(plugin, "")
+		version, err := Latest(conf, plugin, "")
 		assert.Nil(t, err)
 		assert.Equal(t, tt.expectedOutput, version)
 	}
 }
 
+func TestListAll(t *testing.T) {
+	listAll := func(t *testing.T, conf config.Config, plugin plugins.Plugin, script string) {
+		t.Helper()
+		assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "list-all", "#!/usr/bin/env bash\n"+script+"\n"))
+	}
+
+	expireCache := func(t *testing.T, conf config.Config, plugin plugins.Plugin) {
+		t.Helper()
+		old := time.Now().Add(-2 * time.Hour)
+		cacheFile := filepath.Join(conf.DataDir, "cache", plugin.Name, "list-all")
+		assert.Nil(t, os.Chtimes(cacheFile, old, old))
+	}
+
+	t.Run("leaves out log lines printed to stdout and warns about them", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		listAll(t, conf, plugin, "echo 'Fetching versions from GitHub...'\necho 1.0.0 2.0.0\necho 3.0.0\necho 'Found 3 versions, done'")
+
+		var stderr strings.Builder
+		versions, err := ListAll(conf, plugin, true, &stderr)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "3.0.0"}, versions)
+		assert.Contains(t, stderr.String(), "warning: testlua list-all printed 2 lines or words that aren't versions to stdout")
+	})
+
+	t.Run("returns cached versions until cache expires", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.Settings = config.Settings{Loaded: true, ListAllCacheDuration: 60}
+		listAll(t, conf, plugin, "echo 1.0.0 2.0.0")
+
+		versions, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0"}, versions)
+
+		listAll(t, conf, plugin, "echo 1.0.0 2.0.0 3.0.0")
+		versions, err = ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0"}, versions)
+
+		expireCache(t, conf, plugin)
+		versions, err = ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "3.0.0"}, versions)
+	})
+
+	t.Run("ignores cache when refreshing", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.Settings = config.Settings{Loaded: true, ListAllCacheDuration: 60}
+		listAll(t, conf, plugin, "echo 1.0.0")
+		_, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+
+		listAll(t, conf, plugin, `[ -z "$ASDF_LIST_ALL_SINCE" ] && echo 2.0.0`)
+		versions, err := ListAll(conf, plugin, true, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2.0.0"}, versions)
+	})
+
+	t.Run("appends versions from incremental listing to expired cache", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.Settings = config.Settings{Loaded: true, ListAllCacheDuration: 60}
+		listAll(t, conf, plugin, "echo 1.0.0 2.0.0")
+		_, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+
+		listAll(t, conf, plugin, `[ "$ASDF_LIST_ALL_SINCE" = 2.0.0 ] && echo 2.1.0 3.0.0`)
+		expireCache(t, conf, plugin)
+		versions, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "2.1.0", "3.0.0"}, versions)
+
+		// Nothing new since 3.0.0
+		listAll(t, conf, plugin, "true")
+		expireCache(t, conf, plugin)
+		versions, err = ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "2.1.0", "3.0.0"}, versions)
+	})
+
+	t.Run("replaces expired cache when newest cached version disappears upstream", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.Settings = config.Settings{Loaded: true, ListAllCacheDuration: 60}
+		listAll(t, conf, plugin, "echo 1.0.0 2.0.0 2.1.0")
+		_, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+
+		// A plugin ignoring ASDF_LIST_ALL_SINCE after 2.1.0 was pulled
+		listAll(t, conf, plugin, "echo 1.0.0 2.0.0 2.0.1")
+		expireCache(t, conf, plugin)
+		versions, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0", "2.0.0", "2.0.1"}, versions)
+	})
+
+	t.Run("does not cache when cache duration is zero", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		conf.Settings = config.Settings{Loaded: true, ListAllCacheDuration: 0}
+		listAll(t, conf, plugin, "echo 1.0.0")
+
+		versions, err := ListAll(conf, plugin, false, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1.0.0"}, versions)
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "cache"))
+	})
+
+	t.Run("returns error and callback output when list-all fails", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		listAll(t, conf, plugin, "echo oops >&2; exit 1")
+
+		var stderr strings.Builder
+		_, err := ListAll(conf, plugin, false, &stderr)
+		assert.Error(t, err)
+		assert.Equal(t, "oops\n", stderr.String())
+	})
+}
+
 func TestAllVersions(t *testing.T) {
 	pluginName := "list-all-test"
 	conf, _ := generateConfig(t)
@@ -443,6 +989,45 @@ func TestAllVersions(t *testing.T) {
 		assert.Equal(t, versions, []string{"1.0.0", "1.1.0", "2.0.0"})
 	})
 
+	t.Run("returns versions in the same order regardless of the users locale", func(t *testing.T) {
+		pluginName := "list-all-locale"
+		pluginDir, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, pluginName)
+		assert.Nil(t, err)
+		plugin := plugins.New(conf, pluginName)
+
+		// Byte order puts '-' before '_', most UTF-8 collations ignore punctuation
+		// and put 1.0.0_a first.
+		script := "#!/usr/bin/env bash\nprintf '%s\\n' 1.0.0_a 1.0.0-b 1.0.0 | sort | tr '\\n' ' '\n"
+		err = os.WriteFile(filepath.Join(pluginDir, "bin", "list-all"), []byte(script), 0o777)
+		assert.Nil(t, err)
+
+		for _, locale := range []string{"C", "en_US.UTF-8", "tr_TR.UTF-8", "sv_SE.UTF-8"} {
+			t.Setenv("LC_ALL", locale)
+			t.Setenv("LC_COLLATE", locale)
+			t.Setenv("LANG", locale)
+
+			versions, err := AllVersions(plugin)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"1.0.0", "1.0.0-b", "1.0.0_a"}, versions, "locale %s", locale)
+		}
+	})
+
+	t.Run("runs callback with C collation", func(t *testing.T) {
+		pluginName := "list-all-collation"
+		pluginDir, err := repotest.InstallPlugin("dummy_plugin", conf.DataDir, pluginName)
+		assert.Nil(t, err)
+		plugin := plugins.New(conf, pluginName)
+		t.Setenv("LC_ALL", "en_US.UTF-8")
+
+		script := "#!/usr/bin/env bash\necho \"$LC_COLLATE ${LC_ALL:-unset} $LANG\"\n"
+		err = os.WriteFile(filepath.Join(pluginDir, "bin", "list-all"), []byte(script), 0o777)
+		assert.Nil(t, err)
+
+		versions, err := AllVersions(plugin)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"C", "unset", "en_US.UTF-8"}, versions)
+	})
+
 	t.Run("returns error when callback missing", func(t *testing.T) {
 		pluginName = "list-all-fail"
 		_, err := repotest.InstallPlugin("dummy_plugin_no_download", conf.DataDir, pluginName)
@@ -482,6 +1067,7 @@ func TestUninstall(t *testing.T) {
 		err := Uninstall(conf, plugin, "1.0.0", &stdout, &stderr)
 		assert.Nil(t, err)
 		assertNotInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+		assert.NoDirExists(t, filepath.Join(conf.DataDir, "metadata", plugin.Name, "1.0.0"))
 	})
 
 	t.Run("runs pre and post-uninstall hooks", func(t *testing.T) {
@@ -509,9 +1095,132 @@ func TestUninstall(t *testing.T) {
 		want := "pre_asdf_uninstall_test 1.0.0\ncustom uninstall\npost_asdf_uninstall_test 1.0.0\n"
 		assert.Equal(t, want, stdout.String())
 	})
+
+	t.Run("invokes pre and post-uninstall callbacks with context when present", func(t *testing.T) {
+		stdout, stderr := buildOutputs()
+		err = InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		preUninstall := []byte("#!/usr/bin/env bash\necho pre-uninstall $ASDF_INSTALL_VERSION $ASDF_UNINSTALL_REASON\ntest -d \"$ASDF_INSTALL_PATH\" && echo installed\n")
+		assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "pre-uninstall"), preUninstall, 0o755))
+		postUninstall := []byte("#!/usr/bin/env bash\necho post-uninstall $ASDF_INSTALL_VERSION $ASDF_UNINSTALL_REASON\ntest -d \"$ASDF_INSTALL_PATH\" || echo removed\n")
+		assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "post-uninstall"), postUninstall, 0o755))
+		t.Cleanup(func() {
+			os.Remove(filepath.Join(plugin.Dir, "bin", "pre-uninstall"))
+			os.Remove(filepath.Join(plugin.Dir, "bin", "post-uninstall"))
+		})
+
+		err = Uninstall(conf, plugin, "1.0.0", &stdout, &stderr)
+		assert.Nil(t, err)
+		want := "pre_asdf_uninstall_test 1.0.0\npre-uninstall 1.0.0 uninstall\ninstalled\ncustom uninstall\npost-uninstall 1.0.0 uninstall\nremoved\npost_asdf_uninstall_test 1.0.0\n"
+		assert.Equal(t, want, stdout.String())
+	})
+
+	t.Run("does not uninstall when pre-uninstall callback fails", func(t *testing.T) {
+		stdout, stderr := buildOutputs()
+		err = InstallOneVersion(conf, plugin, "1.0.0", false, &stdout, &stderr)
+		assert.Nil(t, err)
+
+		assert.Nil(t, os.WriteFile(filepath.Join(plugin.Dir, "bin", "pre-uninstall"), []byte("#!/usr/bin/env bash\nexit 1\n"), 0o755))
+		t.Cleanup(func() { os.Remove(filepath.Join(plugin.Dir, "bin", "pre-uninstall")) })
+
+		err = Uninstall(conf, plugin, "1.0.0", &stdout, &stderr)
+		assert.NotNil(t, err)
+		assertVersionInstalled(t, conf.DataDir, plugin.Name, "1.0.0")
+	})
 }
 
 // Helper functions
+func TestRefreshRef(t *testing.T) {
+	upstream, err := repotest.GeneratePlugin("dummy_plugin", t.TempDir(), "upstream")
+	assert.Nil(t, err)
+	branch := strings.TrimSpace(runGit(t, upstream, "rev-parse", "--abbrev-ref", "HEAD"))
+
+	conf, plugin := generateConfig(t)
+	failFile := filepath.Join(t.TempDir(), "fail")
+	script := fmt.Sprintf(`#!/usr/bin/env bash
+mkdir -p "$ASDF_INSTALL_PATH"
+touch "$ASDF_INSTALL_PATH/built"
+[ -e %s ] && exit 1
+echo "source_url=%s" >>"$ASDF_PROVENANCE_FILE"
+echo "commit=$(git ls-remote %s "refs/heads/$ASDF_INSTALL_VERSION" | cut -f1)" >>"$ASDF_PROVENANCE_FILE"
+`, failFile, upstream, upstream)
+	assert.Nil(t, repotest.WritePluginCallback(plugin.Dir, "install", script))
+	version := "ref:" + branch
+
+	t.Run("installs ref that is not installed", func(t *testing.T) {
+		stdout, stderr := buildOutputs()
+		rebuilt, err := RefreshRef(conf, plugin, version, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.False(t, rebuilt)
+		assert.True(t, installs.IsInstalled(conf, plugin, toolversions.Parse(version)))
+	})
+
+	t.Run("does nothing when ref has not moved", func(t *testing.T) {
+		stdout, stderr := buildOutputs()
+		rebuilt, err := RefreshRef(conf, plugin, version, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.False(t, rebuilt)
+	})
+
+	t.Run("keeps installed version when rebuild fails", func(t *testing.T) {
+		runGit(t, upstream, "-c", "user.name=asdf", "-c", "user.email=asdf@example.com", "commit", "--allow-empty", "-m", "update")
+		assert.Nil(t, os.WriteFile(failFile, []byte{}, 0o666))
+		defer os.Remove(failFile)
+		installDir := installs.InstallPath(conf, plugin, toolversions.Parse(version))
+		before, err := os.Stat(filepath.Join(installDir, "built"))
+		assert.Nil(t, err)
+
+		stdout, stderr := buildOutputs()
+		_, err = RefreshRef(conf, plugin, version, &stdout, &stderr)
+		assert.ErrorContains(t, err, "failed to run install callback")
+
+		after, err := os.Stat(filepath.Join(installDir, "built"))
+		assert.Nil(t, err)
+		assert.True(t, os.SameFile(before, after))
+		assert.True(t, installs.IsInstalled(conf, plugin, toolversions.Parse(version)))
+	})
+
+	t.Run("rebuilds when ref has moved upstream", func(t *testing.T) {
+		runGit(t, upstream, "-c", "user.name=asdf", "-c", "user.email=asdf@example.com", "commit", "--allow-empty", "-m", "update")
+		head := strings.TrimSpace(runGit(t, upstream, "rev-parse", "HEAD"))
+
+		stdout, stderr := buildOutputs()
+		rebuilt, err := RefreshRef(conf, plugin, version, &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.True(t, rebuilt)
+		assert.Contains(t, stderr.String(), "rebuilding")
+
+		status, err := CheckRef(conf, plugin, toolversions.Parse(version))
+		assert.Nil(t, err)
+		assert.Equal(t, RefStatus{Installed: head, Upstream: head}, status)
+	})
+
+	t.Run("ignores versions that are not refs", func(t *testing.T) {
+		stdout, stderr := buildOutputs()
+		rebuilt, err := RefreshRef(conf, plugin, "1.0.0", &stdout, &stderr)
+		assert.Nil(t, err)
+		assert.False(t, rebuilt)
+		assert.False(t, installs.IsInstalled(conf, plugin, toolversions.Parse("1.0.0")))
+	})
+
+	t.Run("returns UntrackedRefError when commit was not recorded", func(t *testing.T) {
+		conf, plugin := generateConfig(t)
+		stdout, stderr := buildOutputs()
+		assert.Nil(t, InstallOneVersion(conf, plugin, "ref:main", false, &stdout, &stderr))
+
+		_, err := RefreshRef(conf, plugin, "ref:main", &stdout, &stderr)
+		assert.IsType(t, UntrackedRefError{}, err)
+	})
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	assert.Nil(t, err)
+	return string(output)
+}
+
 func buildOutputs() (strings.Builder, strings.Builder) {
 	var stdout strings.Builder
 	var stderr strings.Builder
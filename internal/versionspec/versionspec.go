@@ -0,0 +1,323 @@
+// Package versionspec parses and compares the version strings used by tools
+// managed with asdf. Plugins return versions in many shapes, semver
+// (1.2.3-rc.1+build), dates (2024.01.15), patchlevels (2.6.0-p0, 1.9.3p551) and
+// vendor prefixes (temurin-21.0.1, v1.22.0), so parsing never fails. Any string
+// is split into a prefix, numeric release segments and a suffix, and formatting
+// a parsed version always returns the original string.
+package versionspec
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// exclusionOperator starts a constraint excluding versions, so a known bad
+// release can be skipped while tracking a range, e.g. `>=1.5 !=1.5.3`
+const exclusionOperator = "!="
+
+// prereleaseRegex matches suffixes marking a release that comes before the
+// release with the same numeric segments.
+var prereleaseRegex = regexp.MustCompile(`(?i)^[-._]?((alpha|beta|rc|pre|preview|dev|snapshot|milestone)([-._]?[0-9a-z.]*)?|(a|b|c|m)[0-9]+)$`)
+
+// wildcardRegex matches the placeholder segments ending a wildcard version,
+// like `.x` in `18.x` or `.*.*` in `1.*.*`
+var wildcardRegex = regexp.MustCompile(`^(\.[xX*])+$`)
+
+// Version is a parsed version string
+type Version struct {
+	// Prefix contains everything before the first digit, e.g. `v` or
+	// `temurin-`. Strings without digits are stored entirely in Prefix.
+	Prefix string
+	// Release contains the dot separated numeric segments following the
+	// prefix, stored as strings so arbitrarily long numbers are preserved.
+	Release []string
+	// Suffix contains everything after the release segments, including any
+	// leading separator and build metadata, e.g. `-rc.1+build.5` or `p551`.
+	Suffix string
+}
+
+// Parse splits a version string into its components. It accepts any string.
+func Parse(raw string) Version {
+	start := strings.IndexAny(raw, "0123456789")
+	if start < 0 {
+		return Version{Prefix: raw}
+	}
+
+	version := Version{Prefix: raw[:start]}
+	rest := raw[start:]
+
+	for {
+		end := digitsEnd(rest)
+		version.Release = append(version.Release, rest[:end])
+		rest = rest[end:]
+
+		if len(rest) < 2 || rest[0] != '.' || !isDigit(rest[1]) {
+			break
+		}
+		rest = rest[1:]
+	}
+
+	version.Suffix = rest
+	return version
+}
+
+// String formats the version, returning the string it was parsed from
+func (v Version) String() string {
+	return v.Prefix + strings.Join(v.Release, ".") + v.Suffix
+}
+
+// Prerelease returns true if the suffix marks an alpha, beta, release
+// candidate or other pre-release version.
+func (v Version) Prerelease() bool {
+	suffix, _, _ := strings.Cut(v.Suffix, "+")
+	return len(v.Release) > 0 && prereleaseRegex.MatchString(suffix)
+}
+
+// Build returns the build metadata following a `+` in the suffix
+func (v Version) Build() string {
+	_, build, _ := strings.Cut(v.Suffix, "+")
+	return build
+}
+
+// Compare returns a negative number when a is older than b, a positive number
+// when a is newer than b, and zero only when both were parsed from the same
+// string. Versions are ordered by:
+//
+//  1. strings without any digits, like `system` or `lts`, before versions
+//  2. prefix, ignoring a leading `v`, so different distributions don't mix
+//  3. numeric release segments, missing segments count as zero
+//  4. pre-releases before releases before releases with other suffixes, like
+//     patchlevels
+//  5. the suffix and build metadata, comparing digit runs numerically
+//  6. the original string, byte by byte
+func Compare(a, b Version) int {
+	if c := min(len(a.Release), 1) - min(len(b.Release), 1); c != 0 {
+		return c
+	}
+
+	if c := strings.Compare(normalizePrefix(a.Prefix), normalizePrefix(b.Prefix)); c != 0 {
+		return c
+	}
+
+	if c := compareRelease(a.Release, b.Release); c != 0 {
+		return c
+	}
+
+	if c := suffixClass(a) - suffixClass(b); c != 0 {
+		return c
+	}
+
+	aSuffix, aBuild, _ := strings.Cut(a.Suffix, "+")
+	bSuffix, bBuild, _ := strings.Cut(b.Suffix, "+")
+	if c := compareNatural(aSuffix, bSuffix); c != 0 {
+		return c
+	}
+
+	if c := compareNatural(aBuild, bBuild); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.String(), b.String())
+}
+
+// CompareStrings parses and compares two version strings
+func CompareStrings(a, b string) int {
+	return Compare(Parse(a), Parse(b))
+}
+
+// Sort sorts a slice of version strings from oldest to newest
+func Sort(versions []string) {
+	slices.SortFunc(versions, CompareStrings)
+}
+
+// SameRelease returns true if both version strings have the same prefix,
+// ignoring a leading `v`, and the same leading numeric release segments, so
+// `1.2.3` and `v1.2.9` share the 1.2 release while `1.2.3` and `1.20.0` don't.
+// Missing segments count as zero.
+func SameRelease(a, b string, segments int) bool {
+	aVersion, bVersion := Parse(a), Parse(b)
+	if len(aVersion.Release) == 0 || len(bVersion.Release) == 0 {
+		return false
+	}
+
+	if normalizePrefix(aVersion.Prefix) != normalizePrefix(bVersion.Prefix) {
+		return false
+	}
+
+	for i := 0; i < segments; i++ {
+		if compareDigits(segment(aVersion.Release, i), segment(bVersion.Release, i)) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Satisfies returns true if the version string satisfies the constraint. A
+// constraint is a version optionally preceded by an operator, one of `=`, `!=`,
+// `>`, `>=`, `<` and `<=`. A constraint version matches itself and every
+// version that starts with it followed by a dot, so `18` matches 18.x.y
+// versions, `>18` only matches versions after every 18.x.y, `<=18` also
+// matches them and `!=18.2` matches none of the 18.2.x versions. Wildcard
+// versions match as MatchesWildcard does, so `!=18.2.*` works like `!=18.2`.
+func Satisfies(raw, constraint string) bool {
+	operator := constraint[:len(constraint)-len(strings.TrimLeft(constraint, "!=<>"))]
+	target := strings.TrimPrefix(constraint, operator)
+	matches := raw == target || strings.HasPrefix(raw, target+".") || MatchesWildcard(raw, target)
+	compared := CompareStrings(raw, target)
+
+	switch operator {
+	case "", "=":
+		return matches
+	case exclusionOperator:
+		return !matches
+	case ">":
+		return !matches && compared > 0
+	case ">=":
+		return matches || compared > 0
+	case "<":
+		return !matches && compared < 0
+	case "<=":
+		return matches || compared < 0
+	default:
+		return false
+	}
+}
+
+// IsWildcard returns true if the version ends with placeholder segments in
+// place of numbers, `x`, `X` or `*`, such as `18.x`, `3.12.*` or `v1.x.x`
+func IsWildcard(raw string) bool {
+	version := Parse(raw)
+	return len(version.Release) > 0 && wildcardRegex.MatchString(version.Suffix)
+}
+
+// MatchesWildcard returns true if the version string matches the wildcard
+// version, having the same prefix, ignoring a leading `v`, and the same
+// numeric segments before the placeholders, which match any segments. `18.x`
+// matches 18, 18.2 and 18.2.1 but not 180.1.0. Pre-releases match too, callers
+// picking a version to use skip them.
+func MatchesWildcard(raw, wildcard string) bool {
+	if !IsWildcard(wildcard) {
+		return false
+	}
+
+	fixed := len(Parse(wildcard).Release)
+	return len(Parse(raw).Release) >= fixed && SameRelease(raw, wildcard, fixed)
+}
+
+// IsExclusion returns true if the constraint excludes versions, like `!=1.5.3`,
+// rather than selecting them
+func IsExclusion(constraint string) bool {
+	return strings.HasPrefix(constraint, exclusionOperator)
+}
+
+// Excluded returns true if an exclusion among the constraints rules the version
+// out. Other constraints are ignored.
+func Excluded(raw string, constraints []string) bool {
+	for _, constraint := range constraints {
+		if IsExclusion(constraint) && !Satisfies(raw, constraint) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizePrefix(prefix string) string {
+	if prefix == "v" || prefix == "V" {
+		return ""
+	}
+	return prefix
+}
+
+func compareRelease(a, b []string) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		if c := compareDigits(segment(a, i), segment(b, i)); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func segment(release []string, index int) string {
+	if index < len(release) {
+		return release[index]
+	}
+	return "0"
+}
+
+func suffixClass(v Version) int {
+	switch {
+	case v.Prerelease():
+		return 0
+	case v.Suffix == "" || strings.HasPrefix(v.Suffix, "+"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// compareNatural compares strings by splitting them into runs of digits and
+// runs of other characters. Digit runs are compared numerically, other runs
+// byte by byte, and digit runs sort before other runs.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		aToken, aDigits := nextToken(a)
+		bToken, bDigits := nextToken(b)
+		a, b = a[len(aToken):], b[len(bToken):]
+
+		var c int
+		switch {
+		case aDigits && bDigits:
+			c = compareDigits(aToken, bToken)
+		case aDigits:
+			c = -1
+		case bDigits:
+			c = 1
+		default:
+			c = strings.Compare(aToken, bToken)
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return len(a) - len(b)
+}
+
+func nextToken(str string) (string, bool) {
+	if isDigit(str[0]) {
+		return str[:digitsEnd(str)], true
+	}
+
+	end := strings.IndexAny(str, "0123456789")
+	if end < 0 {
+		end = len(str)
+	}
+	return str[:end], false
+}
+
+// compareDigits numerically compares two strings of digits of any length
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func digitsEnd(str string) int {
+	for i := 0; i < len(str); i++ {
+		if !isDigit(str[i]) {
+			return i
+		}
+	}
+	return len(str)
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
@@ -0,0 +1,305 @@
+package versionspec
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// samples covers the version formats seen in real plugins
+var samples = []string{
+	"", "system", "lts", "latest", "1", "1.0", "1.0.0", "v1.0.0", "1.2.3-rc1",
+	"1.2.3-rc.2", "1.2.3-beta.1", "1.2.3-alpha", "1.2.3+build.5", "1.2.3-rc.1+build.5",
+	"2.6.0-p0", "1.9.3-p551", "1.9.3p551", "3.13.0a1", "3.13.0b2", "3.13.2t",
+	"1.18.2-otp-27", "2024.01.15", "20240115", "2024-01-15", "temurin-21.0.1+12",
+	"zulu-17.44.53", "openjdk-21", "1.10.0", "1.9.0", "01.2", "1..2", "1.", ".1",
+	"jruby-9.4.5.0", "ref:v1.0.0", "18446744073709551616.1",
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Version
+	}{
+		{input: "", expected: Version{}},
+		{input: "system", expected: Version{Prefix: "system"}},
+		{input: "1.2.3", expected: Version{Release: []string{"1", "2", "3"}}},
+		{input: "v1.22.0", expected: Version{Prefix: "v", Release: []string{"1", "22", "0"}}},
+		{input: "1.2.3-rc.1+build.5", expected: Version{Release: []string{"1", "2", "3"}, Suffix: "-rc.1+build.5"}},
+		{input: "1.9.3p551", expected: Version{Release: []string{"1", "9", "3"}, Suffix: "p551"}},
+		{input: "2024.01.15", expected: Version{Release: []string{"2024", "01", "15"}}},
+		{input: "temurin-21.0.1+12", expected: Version{Prefix: "temurin-", Release: []string{"21", "0", "1"}, Suffix: "+12"}},
+		{input: "1..2", expected: Version{Release: []string{"1"}, Suffix: "..2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Parse(tt.input))
+		})
+	}
+}
+
+func TestPrerelease(t *testing.T) {
+	prereleases := []string{"1.2.3-rc1", "1.2.3-rc.2", "1.2.3-beta.1", "1.2.3-alpha", "3.13.0a1", "3.13.0b2", "1.0.0-dev", "1.0.0-SNAPSHOT", "1.2.3-rc.1+build.5"}
+	for _, version := range prereleases {
+		assert.True(t, Parse(version).Prerelease(), version)
+	}
+
+	releases := []string{"1.2.3", "1.2.3+build.5", "2.6.0-p0", "1.9.3p551", "3.13.2t", "1.18.2-otp-27", "system", "temurin-21.0.1+12"}
+	for _, version := range releases {
+		assert.False(t, Parse(version).Prerelease(), version)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	assert.Equal(t, "build.5", Parse("1.2.3-rc.1+build.5").Build())
+	assert.Equal(t, "", Parse("1.2.3").Build())
+}
+
+func TestCompare(t *testing.T) {
+	ordered := [][]string{
+		{"1.9.0", "1.10.0"},
+		{"1.2", "1.2.0"},
+		{"1.2.3-alpha", "1.2.3-beta.1"},
+		{"1.2.3-rc.2", "1.2.3-rc.10"},
+		{"1.2.3-rc1", "1.2.3"},
+		{"1.2.3", "1.2.3+build.5"},
+		{"1.2.3", "1.2.3-p1"},
+		{"1.9.3-p99", "1.9.3-p551"},
+		{"3.13.0a1", "3.13.0b2"},
+		{"3.13.0b2", "3.13.0"},
+		{"1.0.0", "v1.0.0"},
+		{"v1.0.0", "1.0.1"},
+		{"2023.12.31", "2024.01.15"},
+		{"system", "1.0.0"},
+		{"18446744073709551615", "18446744073709551616"},
+		{"openjdk-21", "temurin-17"},
+	}
+
+	for _, pair := range ordered {
+		t.Run(strings.Join(pair, " < "), func(t *testing.T) {
+			assert.Negative(t, CompareStrings(pair[0], pair[1]))
+			assert.Positive(t, CompareStrings(pair[1], pair[0]))
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	versions := []string{"1.10.0", "1.2.0", "1.9.0-rc1", "1.9.0", "v1.0.0"}
+	Sort(versions)
+	assert.Equal(t, []string{"v1.0.0", "1.2.0", "1.9.0-rc1", "1.9.0", "1.10.0"}, versions)
+}
+
+func TestSameRelease(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		segments int
+		expected bool
+	}{
+		{a: "1.2.3", b: "1.2.9", segments: 2, expected: true},
+		{a: "1.2.3", b: "1.20.0", segments: 2, expected: false},
+		{a: "v1.2.3", b: "1.2.0", segments: 2, expected: true},
+		{a: "1.10.0", b: "1.1.0", segments: 1, expected: true},
+		{a: "10.0.0", b: "1.0.0", segments: 1, expected: false},
+		{a: "2", b: "2.0.5", segments: 2, expected: true},
+		{a: "temurin-21.0.1", b: "zulu-21.0.1", segments: 1, expected: false},
+		{a: "system", b: "system", segments: 1, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SameRelease(tt.a, tt.b, tt.segments))
+		})
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		expected   bool
+	}{
+		{version: "18.19.0", constraint: "18", expected: true},
+		{version: "18.19.0", constraint: "=18.19.0", expected: true},
+		{version: "180.0.0", constraint: "18", expected: false},
+		{version: "20.11.0", constraint: ">=18", expected: true},
+		{version: "18.0.0", constraint: ">=18", expected: true},
+		{version: "16.20.2", constraint: ">=18", expected: false},
+		{version: "18.19.0", constraint: ">18", expected: false},
+		{version: "19.0.0", constraint: ">18", expected: true},
+		{version: "21.0.1", constraint: "<21", expected: false},
+		{version: "20.11.0", constraint: "<21", expected: true},
+		{version: "21.7.3", constraint: "<=21", expected: true},
+		{version: "22.0.0", constraint: "<=21", expected: false},
+		{version: "1.10.0", constraint: ">1.9", expected: true},
+		{version: "18.0.0", constraint: "=>18", expected: false},
+		{version: "1.5.3", constraint: "!=1.5.3", expected: false},
+		{version: "1.5.4", constraint: "!=1.5.3", expected: true},
+		{version: "1.5.4", constraint: "!=1.5", expected: false},
+		{version: "1.50.0", constraint: "!=1.5", expected: true},
+		{version: "1.5.3", constraint: "!1.5.3", expected: false},
+		{version: "18.19.0", constraint: "18.x", expected: true},
+		{version: "18.2.1", constraint: "!=18.2.*", expected: false},
+		{version: "18.3.0", constraint: "!=18.2.*", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.constraint, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Satisfies(tt.version, tt.constraint))
+		})
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	constraints := []string{">=1.5", "!=1.5.3", "!=1.6"}
+
+	assert.True(t, Excluded("1.5.3", constraints))
+	assert.True(t, Excluded("1.6.2", constraints))
+	assert.False(t, Excluded("1.5.4", constraints))
+	assert.False(t, Excluded("1.4.0", constraints), "only exclusions rule versions out")
+	assert.True(t, IsExclusion("!=1.5.3"))
+	assert.False(t, IsExclusion("<=1.5.3"))
+}
+
+func TestIsWildcard(t *testing.T) {
+	for _, version := range []string{"18.x", "3.12.*", "v1.x.x", "1.X", "temurin-21.*"} {
+		assert.True(t, IsWildcard(version), version)
+	}
+
+	for _, version := range []string{"18", "x", "*", "1.x.3", "1.2.3-rc.1", "18.xy", "lts"} {
+		assert.False(t, IsWildcard(version), version)
+	}
+}
+
+func TestMatchesWildcard(t *testing.T) {
+	tests := []struct {
+		version  string
+		wildcard string
+		expected bool
+	}{
+		{version: "18.19.0", wildcard: "18.x", expected: true},
+		{version: "18", wildcard: "18.x", expected: true},
+		{version: "180.1.0", wildcard: "18.x", expected: false},
+		{version: "17.9.9", wildcard: "18.x", expected: false},
+		{version: "3.12.10", wildcard: "3.12.*", expected: true},
+		{version: "3.1.2", wildcard: "3.12.*", expected: false},
+		{version: "3", wildcard: "3.12.*", expected: false},
+		{version: "v1.22.0", wildcard: "1.x.x", expected: true},
+		{version: "temurin-21.0.1", wildcard: "temurin-21.*", expected: true},
+		{version: "zulu-21.0.1", wildcard: "temurin-21.*", expected: false},
+		{version: "18.3.0-rc.1", wildcard: "18.x", expected: true},
+		{version: "18.19.0", wildcard: "18", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.wildcard, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MatchesWildcard(tt.version, tt.wildcard))
+		})
+	}
+}
+
+func TestRoundTripProperty(t *testing.T) {
+	for _, version := range append(samples, randomVersions(500)...) {
+		assert.Equal(t, version, Parse(version).String())
+	}
+}
+
+func TestCompareProperties(t *testing.T) {
+	versions := append(slices.Clone(samples), randomVersions(60)...)
+
+	for _, a := range versions {
+		assert.Zero(t, CompareStrings(a, a), "reflexive for %q", a)
+
+		for _, b := range versions {
+			ab, ba := CompareStrings(a, b), CompareStrings(b, a)
+			assert.Equal(t, sign(ab), -sign(ba), "antisymmetric for %q and %q", a, b)
+			assert.Equal(t, a == b, ab == 0, "zero only when equal for %q and %q", a, b)
+
+			for _, c := range versions {
+				if ab <= 0 && CompareStrings(b, c) <= 0 {
+					assert.LessOrEqual(t, CompareStrings(a, c), 0, "transitive for %q, %q and %q", a, b, c)
+				}
+			}
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, version := range samples {
+		f.Add(version)
+	}
+
+	f.Fuzz(func(t *testing.T, version string) {
+		if formatted := Parse(version).String(); formatted != version {
+			t.Errorf("round trip failed for %q, got %q", version, formatted)
+		}
+	})
+}
+
+func FuzzCompare(f *testing.F) {
+	f.Add("1.2.3", "1.10.0", "1.2.3-rc1")
+	f.Add("v1.0.0", "1.0.0", "1.0.0+build")
+	f.Add("2.6.0-p0", "2.6.0", "2.6.0-rc1")
+	f.Add("1.9.3p551", "01.9.3", "1.9.3-p551")
+
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		ab, ba := CompareStrings(a, b), CompareStrings(b, a)
+		if sign(ab) != -sign(ba) {
+			t.Errorf("not antisymmetric for %q and %q", a, b)
+		}
+
+		if (ab == 0) != (a == b) {
+			t.Errorf("zero result for different versions %q and %q", a, b)
+		}
+
+		if ab <= 0 && CompareStrings(b, c) <= 0 && CompareStrings(a, c) > 0 {
+			t.Errorf("not transitive for %q, %q and %q", a, b, c)
+		}
+	})
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// randomVersions generates version like strings from a fixed seed so failures
+// are reproducible.
+func randomVersions(count int) (versions []string) {
+	random := rand.New(rand.NewSource(42))
+	prefixes := []string{"", "", "v", "jdk-", "temurin-"}
+	suffixes := []string{"", "", "-rc", "-rc.", "-beta.", "a", "p", "-p", "+build.", "-otp-", "t", ".", "_"}
+
+	for i := 0; i < count; i++ {
+		var version strings.Builder
+		version.WriteString(prefixes[random.Intn(len(prefixes))])
+
+		segments := random.Intn(4)
+		for j := 0; j < segments; j++ {
+			if j > 0 {
+				version.WriteString(".")
+			}
+			if random.Intn(5) == 0 {
+				version.WriteString("0")
+			}
+			version.WriteString(strings.Repeat("1", random.Intn(2)) + string(rune('0'+random.Intn(10))))
+		}
+
+		version.WriteString(suffixes[random.Intn(len(suffixes))])
+		if random.Intn(2) == 0 {
+			version.WriteString(string(rune('0' + random.Intn(10))))
+		}
+		versions = append(versions, version.String())
+	}
+
+	return versions
+}
@@ -0,0 +1,53 @@
+#!/usr/bin/env bats
+
+load test_helpers
+
+setup() {
+  setup_asdf_dir
+  install_dummy_plugin
+  run asdf install dummy 1.0
+
+  PROJECT_DIR="$HOME/project"
+  mkdir -p "$PROJECT_DIR"
+  echo 'dummy 1.0' >>"$PROJECT_DIR/.tool-versions"
+}
+
+teardown() {
+  clean_asdf_dir
+}
+
+@test "complete prints nothing when plugin has no complete callback" {
+  cd "$PROJECT_DIR"
+
+  run asdf complete dummy ""
+  [ "$status" -eq 0 ]
+  [ "$output" = "" ]
+}
+
+@test "complete prints candidates from complete callback starting with the current word" {
+  cd "$PROJECT_DIR"
+
+  cat >"$ASDF_DIR/plugins/dummy/bin/complete" <<'SCRIPT'
+#!/usr/bin/env bash
+echo "$ASDF_INSTALL_VERSION-$*"
+echo plan
+echo apply
+SCRIPT
+  chmod +x "$ASDF_DIR/plugins/dummy/bin/complete"
+
+  run asdf complete dummy p
+  [ "$status" -eq 0 ]
+  [ "$output" = "plan" ]
+
+  run asdf complete dummy --flag ""
+  [ "$status" -eq 0 ]
+  [ "${lines[0]}" = "1.0-dummy --flag " ]
+}
+
+@test "complete prints nothing for unknown command" {
+  cd "$PROJECT_DIR"
+
+  run asdf complete sunny ""
+  [ "$status" -eq 0 ]
+  [ "$output" = "" ]
+}
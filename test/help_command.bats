@@ -72,6 +72,19 @@ EOF
 
   run asdf help
 
+  [ "$status" -eq 0 ]
+  [[ $output =~ $'version: '[0-9]* ]]
+  [[ $output == *$'COMMON COMMANDS\n'* ]]
+  [[ $output == *'asdf help --all'* ]]
+  [[ $output != *$'UTILS\n'* ]]
+  [[ $output == *$'"Late but latest"\n-- Rajinikanth' ]]
+}
+
+@test "help --all should show every command" {
+  cd "$PROJECT_DIR"
+
+  run asdf help --all
+
   [ "$status" -eq 0 ]
   [[ $output =~ $'version: '[0-9]* ]]
   [[ $output == *$'MANAGE PLUGINS\n'* ]]
@@ -79,3 +92,13 @@ EOF
   [[ $output == *$'UTILS\n'* ]]
   [[ $output == *$'"Late but latest"\n-- Rajinikanth' ]]
 }
+
+@test "help should show examples of an asdf command" {
+  cd "$PROJECT_DIR"
+
+  run asdf help install
+
+  [ "$status" -eq 0 ]
+  [[ $output == $'EXAMPLES\n'* ]]
+  [[ $output == *$'\nasdf install nodejs 20.11.1\n'* ]]
+}
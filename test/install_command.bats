@@ -37,6 +37,20 @@ teardown() {
   [ "$(cat "$ASDF_DIR/installs/dummy/1.2.0/version")" = "1.2.0" ]
 }
 
+@test "install_command --at installs the versions set at a git revision" {
+  cd "$PROJECT_DIR"
+  git init -q
+  echo 'dummy 1.0.0' >".tool-versions"
+  git add .tool-versions
+  git -c user.name=asdf -c user.email=asdf@example.com commit -q -m first
+  echo 'dummy 1.1.0' >".tool-versions"
+
+  run asdf install --at HEAD
+  [ "$status" -eq 0 ]
+  [ "$(cat "$ASDF_DIR/installs/dummy/1.0.0/version")" = "1.0.0" ]
+  [ ! -d "$ASDF_DIR/installs/dummy/1.1.0" ]
+}
+
 @test "install_command with only name installs the version in .tool-versions" {
   cd "$PROJECT_DIR"
   echo -n 'dummy 1.2.0' >".tool-versions"
@@ -378,3 +392,13 @@ EOM
   [ "$status" -eq 0 ]
   [ "$(cat "$ASDF_DIR/installs/legacy-dummy/1.0.0/version")" = "1.0.0" ]
 }
+
+@test "install_command runs asdf reshim from install callback while holding installs lock" {
+  cat <<'EOF2' >>"$ASDF_DIR/plugins/dummy/bin/install"
+asdf reshim dummy "$ASDF_INSTALL_VERSION"
+EOF2
+
+  run timeout 30 asdf install dummy 1.1.0
+  [ "$status" -eq 0 ]
+  [ -f "$ASDF_DIR/shims/dummy" ]
+}
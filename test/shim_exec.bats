@@ -57,6 +57,48 @@ teardown() {
   [ "$status" -eq 0 ]
 }
 
+@test "asdf exec --env-only prints executable and environment as JSON instead of running it" {
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+
+  run asdf exec --env-only dummy world hello
+  [ "$status" -eq 0 ]
+  echo "$output" | grep "\"executable\": \"$(asdf_data_dir)/installs/dummy/1.0/bin/dummy\""
+  echo "$output" | grep "\"ASDF_INSTALL_VERSION\": \"1.0\""
+  echo "$output" | grep -v "This is Dummy"
+}
+
+@test "asdf exec --trace writes executable, source and environment to stderr before running it" {
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+
+  run asdf exec --trace dummy world hello
+  [ "$status" -eq 0 ]
+  echo "$output" | grep "source $PROJECT_DIR/.tool-versions"
+  echo "$output" | grep "exec $(asdf_data_dir)/installs/dummy/1.0/bin/dummy world hello"
+  echo "$output" | grep "env ASDF_INSTALL_VERSION=1.0"
+  echo "$output" | grep "This is Dummy 1.0! hello world"
+}
+
+@test "shims append traces to the file set in ASDF_EXEC_TRACE" {
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+
+  ASDF_EXEC_TRACE="$PROJECT_DIR/trace.log" run dummy world hello
+  [ "$status" -eq 0 ]
+  [ "$output" = "This is Dummy 1.0! hello world" ]
+  grep "exec $(asdf_data_dir)/installs/dummy/1.0/bin/dummy world hello" "$PROJECT_DIR/trace.log"
+}
+
+@test "nested asdf env with ASDF_NO_INHERIT undoes PATH changes of outer asdf env" {
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+
+  run asdf env dummy env ASDF_NO_INHERIT=yes asdf env dummy printenv PATH
+  [ "$status" -eq 0 ]
+  [ "$(echo "$output" | grep -o "installs/dummy/1.0/bin" | wc -l)" -eq 1 ]
+}
+
 @test "shim exec should pass all arguments to executable" {
   echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
   run asdf install
@@ -474,3 +516,59 @@ EOM
   [ "$output" = "This is Dummy 1.0! hello world" ]
   [ "$status" -eq 0 ]
 }
+
+@test "asdf exec --with overlays a version over the current one" {
+  run asdf install dummy 1.0
+  run asdf install dummy 1.1
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+
+  run asdf exec --with dummy=1.1 dummy world hello
+  [ "$output" = "This is Dummy 1.1! hello world" ]
+  [ "$status" -eq 0 ]
+
+  run asdf exec dummy world hello
+  [ "$output" = "This is Dummy 1.0! hello world" ]
+}
+
+@test "asdf exec --with fails for unknown tools" {
+  run asdf install dummy 1.0
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+
+  run asdf exec --with nope=1.0 dummy
+  [ "$output" = "No such plugin: nope" ]
+  [ "$status" -eq 1 ]
+}
+
+@test "asdf exec prints a notice when substituting an installed version if substitution_notice is set" {
+  run asdf install dummy 1.1
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  echo "substitution_notice = yes" >"$HOME/.asdfrc"
+
+  ASDF_IGNORE_VERSION=dummy run asdf exec dummy world hello
+  [ "$status" -eq 0 ]
+  [ "${lines[0]}" = "notice: using dummy 1.1 instead of 1.0 set in $PROJECT_DIR/.tool-versions" ]
+  [ "${lines[1]}" = "This is Dummy 1.1! hello world" ]
+}
+
+@test "asdf exec installs missing version when auto_install is yes" {
+  echo "auto_install = yes" >"$HOME/.asdfrc"
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+  echo "dummy 1.1" >"$PROJECT_DIR/.tool-versions"
+
+  run asdf exec dummy world hello
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"This is Dummy 1.1! hello world"* ]]
+  [ -f "$ASDF_DIR/installs/dummy/1.1/version" ]
+}
+
+@test "asdf env doesn't install missing version when auto_install is yes" {
+  echo "auto_install = yes" >"$HOME/.asdfrc"
+  echo "dummy 1.0" >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+  echo "dummy 1.1" >"$PROJECT_DIR/.tool-versions"
+
+  run asdf env dummy
+  [ "$status" -ne 0 ]
+  [ ! -d "$ASDF_DIR/installs/dummy/1.1" ]
+}
@@ -105,3 +105,21 @@ EOM
   run asdf uninstall dummy 1.0.0
   [ "$output" = "removed dummy 1.0.0" ]
 }
+
+@test "uninstall_command should refuse to remove a version used by a known project" {
+  cd "$PROJECT_DIR"
+  echo 'dummy 1.1.0' >"$PROJECT_DIR/.tool-versions"
+  run asdf install
+  [ "$status" -eq 0 ]
+  cd "$HOME"
+
+  run asdf uninstall dummy 1.1.0
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"dummy 1.1.0 is used by these projects:"* ]]
+  [[ "$output" == *"$PROJECT_DIR"* ]]
+  [ -f "$ASDF_DIR/installs/dummy/1.1.0/version" ]
+
+  run asdf uninstall --force dummy 1.1.0
+  [ "$status" -eq 0 ]
+  [ ! -f "$ASDF_DIR/installs/dummy/1.1.0/version" ]
+}